@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+
+	core "github.com/forkbombeu/avdctl/internal/avd"
+	"github.com/spf13/cobra"
+)
+
+func newAndroidAccessibilityCommand(env core.Env) *cobra.Command {
+	var name, serial string
+	cmd := &cobra.Command{
+		Use:   "accessibility COMPONENT",
+		Short: "Enable an accessibility service (package/.Class) and wait until it is active",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			resolved, err := resolveAndroidSerial(env, name, serial)
+			if err != nil {
+				return err
+			}
+			if err := core.EnableAccessibilityService(env, resolved, args[0]); err != nil {
+				return err
+			}
+			fmt.Printf("Accessibility service %s active on %s\n", args[0], resolved)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&name, "name", "", "running AVD name")
+	cmd.Flags().StringVar(&serial, "serial", "", "emulator serial (e.g. emulator-5580)")
+	return cmd
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+
+	core "github.com/forkbombeu/avdctl/internal/avd"
+	"github.com/spf13/cobra"
+)
+
+func newAndroidAdoptCommand(env core.Env) *cobra.Command {
+	var name, serial string
+	var asJSON, list bool
+	cmd := &cobra.Command{
+		Use:   "adopt",
+		Short: "Register an emulator started outside avdctl (e.g. by Android Studio) so ps and cleanup know it",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if list {
+				out := core.ListAdopted(env)
+				if asJSON {
+					return encodeJSON(out)
+				}
+				for _, inst := range out {
+					fmt.Printf("%-14s %-18s pid=%-7d %s\n", inst.Serial, inst.Name, inst.PID, inst.AVDPath)
+				}
+				return nil
+			}
+			resolved, err := resolveAndroidSerial(env, name, serial)
+			if err != nil {
+				return err
+			}
+			inst, err := core.Adopt(env, resolved)
+			if err != nil {
+				return err
+			}
+			if asJSON {
+				return encodeJSON(inst)
+			}
+			fmt.Printf("Adopted %s on %s (pid %d)\n", inst.Name, inst.Serial, inst.PID)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&name, "name", "", "running AVD name")
+	cmd.Flags().StringVar(&serial, "serial", "", "emulator serial (e.g. emulator-5580)")
+	cmd.Flags().BoolVar(&list, "list", false, "list adopted emulators that are still running")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "output JSON")
+	return cmd
+}
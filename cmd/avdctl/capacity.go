@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	core "github.com/forkbombeu/avdctl/internal/avd"
+	"github.com/spf13/cobra"
+)
+
+func newAndroidCapacityCommand(env core.Env) *cobra.Command {
+	var golden string
+	var asJSON bool
+	cmd := &cobra.Command{
+		Use:   "capacity",
+		Short: "Estimate how many more clones of a golden this host can run, and the bottleneck",
+		Long: `Estimates the need of one more instance from the usage avdctl recorded when stopping
+clones of the golden (peak qemu memory, average CPU, disk) and from the clones running now,
+and divides what the host has left by it. At least one clone must have run.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if golden == "" {
+				return fmt.Errorf("--golden is required")
+			}
+			report, err := core.Capacity(env, golden)
+			if err != nil {
+				return err
+			}
+			if asJSON {
+				return encodeJSON(report)
+			}
+			fmt.Printf("Golden:       %s (%d clones, %d runs sampled)\n", report.Golden, report.Clones, report.Samples)
+			printResourceUsage("Per instance:", report.PerInstance)
+			printResourceUsage("Available:", report.Available)
+			printCapacityLimits(report.Limits)
+			fmt.Printf("Additional:   %d (bottleneck: %s)\n", report.Additional, report.Bottleneck)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&golden, "golden", "", "golden path, name in AVDCTL_GOLDEN_DIR or @channel")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "output JSON")
+	return cmd
+}
+
+func printResourceUsage(label string, u core.ResourceUsage) {
+	fmt.Printf("%-13s %.1f MiB memory, %.2f cores, %.1f MiB disk\n", label, float64(u.MemoryBytes)/(1<<20), u.CPUCores, float64(u.DiskBytes)/(1<<20))
+}
+
+func printCapacityLimits(limits map[string]int) {
+	resources := make([]string, 0, len(limits))
+	for resource := range limits {
+		resources = append(resources, resource)
+	}
+	sort.Strings(resources)
+	for _, resource := range resources {
+		fmt.Printf("  %-10s  allows %d more\n", resource, limits[resource])
+	}
+}
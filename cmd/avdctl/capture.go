@@ -0,0 +1,43 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	core "github.com/forkbombeu/avdctl/internal/avd"
+	"github.com/spf13/cobra"
+)
+
+func newAndroidCaptureCommand(env core.Env) *cobra.Command {
+	var serial, dir string
+	var captureJSON bool
+	cmd := &cobra.Command{
+		Use:   "capture",
+		Short: "Save a screenshot, logcat and bugreport of a running emulator into a directory",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if serial == "" || dir == "" {
+				return errors.New("--serial and --dir are required")
+			}
+			capture, err := core.CaptureArtifacts(env, serial, dir)
+			if err != nil {
+				return err
+			}
+			if captureJSON {
+				return encodeJSON(capture)
+			}
+			for _, path := range []string{capture.Screenshot, capture.Logcat, capture.Bugreport} {
+				if path != "" {
+					fmt.Println(path)
+				}
+			}
+			for _, msg := range capture.Errors {
+				fmt.Printf("failed: %s\n", msg)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&serial, "serial", "", "emulator serial (e.g. emulator-5580)")
+	cmd.Flags().StringVar(&dir, "dir", "", "output directory")
+	cmd.Flags().BoolVar(&captureJSON, "json", false, "output JSON")
+	return cmd
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	core "github.com/forkbombeu/avdctl/internal/avd"
+	"github.com/spf13/cobra"
+)
+
+func newAndroidChannelCommand(env core.Env) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "channel",
+		Short: "Point golden channels (latest, staging, production) at goldens; use them as --golden @<channel>",
+	}
+	cmd.AddCommand(
+		newAndroidChannelListCommand(env),
+		newAndroidChannelPromoteCommand(env),
+		newAndroidChannelRollbackCommand(env),
+		newAndroidChannelCheckCommand(env),
+	)
+	return cmd
+}
+
+func newAndroidChannelListCommand(env core.Env) *cobra.Command {
+	var asJSON bool
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the golden channels of AVDCTL_GOLDEN_DIR",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			channels, err := core.ListChannels(env)
+			if err != nil {
+				return err
+			}
+			if asJSON {
+				return encodeJSON(channels)
+			}
+			names := make([]string, 0, len(channels))
+			for channel := range channels {
+				names = append(names, string(channel))
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				pointer := channels[core.Channel(name)]
+				fmt.Printf("%-12s %s (promoted %s, %d earlier)\n", name, pointer.Golden,
+					pointer.PromotedAt.Local().Format(time.RFC3339), len(pointer.History))
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&asJSON, "json", false, "print the channels as JSON")
+	return cmd
+}
+
+func newAndroidChannelPromoteCommand(env core.Env) *cobra.Command {
+	var golden, channel string
+	var asJSON bool
+	cmd := &cobra.Command{
+		Use:   "promote --golden DIR|@CHANNEL --channel NAME",
+		Short: "Point a channel at a golden, keeping the previous one for rollback",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if golden == "" || channel == "" {
+				return errors.New("--golden and --channel are required")
+			}
+			pointer, err := core.Promote(env, golden, core.Channel(channel))
+			if err != nil {
+				return err
+			}
+			if asJSON {
+				return encodeJSON(pointer)
+			}
+			fmt.Printf("%s -> %s\n", channel, pointer.Golden)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&golden, "golden", "", "golden directory, or @<channel> to promote what another channel points at")
+	cmd.Flags().StringVar(&channel, "channel", "", "channel to move (e.g. staging, production)")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "print the channel pointer as JSON")
+	return cmd
+}
+
+func newAndroidChannelRollbackCommand(env core.Env) *cobra.Command {
+	var channel string
+	var asJSON bool
+	cmd := &cobra.Command{
+		Use:   "rollback --channel NAME",
+		Short: "Point a channel back at the golden it pointed at before its last promotion",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if channel == "" {
+				return errors.New("--channel is required")
+			}
+			pointer, err := core.Rollback(env, core.Channel(channel))
+			if err != nil {
+				return err
+			}
+			if asJSON {
+				return encodeJSON(pointer)
+			}
+			fmt.Printf("%s -> %s\n", channel, pointer.Golden)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&channel, "channel", "", "channel to roll back")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "print the channel pointer as JSON")
+	return cmd
+}
+
+func newAndroidChannelCheckCommand(env core.Env) *cobra.Command {
+	var channel string
+	var opts core.ChannelHealthOptions
+	var asJSON bool
+	cmd := &cobra.Command{
+		Use:   "check --channel NAME [--rollback]",
+		Short: "Health-check the running clones of a channel's golden, optionally rolling the channel back",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if channel == "" {
+				return errors.New("--channel is required")
+			}
+			health, err := core.CheckChannelHealth(env, core.Channel(channel), opts)
+			if err != nil {
+				return err
+			}
+			if asJSON {
+				return encodeJSON(health)
+			}
+			fmt.Printf("%s (%s): %d healthy, %d unhealthy, %d not booted\n", channel, health.Golden,
+				len(health.Healthy), len(health.Unhealthy), len(health.NotBooted))
+			serials := make([]string, 0, len(health.Unhealthy))
+			for serial := range health.Unhealthy {
+				serials = append(serials, serial)
+			}
+			sort.Strings(serials)
+			for _, serial := range serials {
+				fmt.Printf("  %s: %s\n", serial, health.Unhealthy[serial])
+			}
+			if health.RolledBack != "" {
+				fmt.Printf("Rolled %s back to %s\n", channel, health.RolledBack)
+			} else if len(serials) > 0 {
+				return fmt.Errorf("%d unhealthy clone(s) of %s: %s", len(serials), channel, strings.Join(serials, ", "))
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&channel, "channel", "", "channel whose clones to check (e.g. production)")
+	cmd.Flags().IntVar(&opts.MaxFailures, "max-failures", 1, "unhealthy clones that trigger a rollback")
+	cmd.Flags().BoolVar(&opts.Rollback, "rollback", false, "roll the channel back when --max-failures is reached")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "print the health report as JSON (exits 0 even with unhealthy clones)")
+	return cmd
+}
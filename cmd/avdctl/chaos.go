@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	core "github.com/forkbombeu/avdctl/internal/avd"
+	"github.com/spf13/cobra"
+)
+
+func newAndroidChaosCommand(env core.Env) *cobra.Command {
+	var policy core.ChaosPolicy
+	var query queryFlags
+	var asJSON bool
+	cmd := &cobra.Command{
+		Use:   "chaos",
+		Short: "Randomly kill, pause or degrade the network of running instances (Ctrl-C reverts and stops)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			spec, err := query.spec()
+			if err != nil {
+				return err
+			}
+			policy.Filters = spec.Filters
+			// Reverts report from timer goroutines.
+			var mu sync.Mutex
+			enc := json.NewEncoder(os.Stdout)
+			policy.OnEvent = func(ev core.ChaosEvent) {
+				mu.Lock()
+				defer mu.Unlock()
+				if asJSON {
+					_ = enc.Encode(ev)
+					return
+				}
+				printChaosEvent(ev)
+			}
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			env.Context = ctx
+			controller, err := core.NewChaosController(env, policy)
+			if err != nil {
+				return err
+			}
+			return controller.Run()
+		},
+	}
+	cmd.Flags().StringSliceVar(&policy.Actions, "action", nil, "actions to pick from: kill, pause, degrade-network (default all)")
+	cmd.Flags().Float64Var(&policy.Fraction, "fraction", 0.1, "share of the selected instances hit per round")
+	cmd.Flags().StringArrayVar(&query.filters, "filter", nil, "only hit instances matching field=value (repeatable, as for ps)")
+	cmd.Flags().DurationVar(&policy.Interval, "interval", time.Minute, "time between rounds")
+	cmd.Flags().IntVar(&policy.Rounds, "rounds", 0, "stop after this many rounds (0: until interrupted)")
+	cmd.Flags().DurationVar(&policy.PauseFor, "pause-for", 30*time.Second, "how long a paused instance stays paused")
+	cmd.Flags().DurationVar(&policy.DegradeFor, "degrade-for", time.Minute, "how long a degraded network lasts")
+	cmd.Flags().StringVar(&policy.NetworkDelay, "network-delay", "umts", "console network delay while degraded")
+	cmd.Flags().StringVar(&policy.NetworkSpeed, "network-speed", "gsm", "console network speed while degraded")
+	cmd.Flags().Int64Var(&policy.Seed, "seed", 0, "random seed, for reproducible runs")
+	cmd.Flags().BoolVar(&policy.DryRun, "dry-run", false, "print the actions without applying them")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "print events as JSON lines")
+	return cmd
+}
+
+func printChaosEvent(ev core.ChaosEvent) {
+	what := ev.Action
+	if ev.Reverted {
+		what += " reverted"
+	}
+	if ev.DryRun {
+		what += " (dry run)"
+	}
+	line := fmt.Sprintf("%s %s %s %s", ev.Time.Local().Format("15:04:05"), ev.Serial, ev.Name, what)
+	if ev.Error != "" {
+		line += ": " + ev.Error
+	}
+	fmt.Println(line)
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	core "github.com/forkbombeu/avdctl/internal/avd"
+	"github.com/spf13/cobra"
+)
+
+func newAndroidConsoleCommand(env core.Env) *cobra.Command {
+	var name, serial string
+	cmd := &cobra.Command{
+		Use:   "console (--name NAME | --serial SERIAL) -- COMMAND...",
+		Short: "Send a command to the emulator console (e.g. sensor set acceleration 0:9.8:0)",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			resolved, err := resolveAndroidSerial(env, name, serial)
+			if err != nil {
+				return err
+			}
+			command := strings.Join(args, " ")
+			if strings.TrimSpace(command) == "" {
+				return errors.New("console command is empty")
+			}
+			out, err := core.Console(env, resolved, command)
+			if err != nil {
+				return err
+			}
+			if out != "" {
+				fmt.Println(out)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&name, "name", "", "running AVD name")
+	cmd.Flags().StringVar(&serial, "serial", "", "emulator serial (e.g. emulator-5580)")
+	return cmd
+}
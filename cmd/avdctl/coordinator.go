@@ -0,0 +1,322 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/forkbombeu/avdctl/pkg/avdmanager"
+	"github.com/spf13/cobra"
+)
+
+func newFleetCommand() *cobra.Command {
+	agentsFile := os.Getenv("AVDCTL_FLEET_AGENTS")
+	if agentsFile == "" {
+		agentsFile = "agents.yaml"
+	}
+	var fleetFile string
+	coordinator := func() (*avdmanager.Coordinator, error) {
+		agents, err := avdmanager.LoadAgents(agentsFile)
+		if err != nil {
+			return nil, err
+		}
+		var policy avdmanager.PlacementPolicy
+		if fleetFile != "" {
+			fleet, err := avdmanager.LoadFleet(fleetFile)
+			if err != nil {
+				return nil, err
+			}
+			policy = avdmanager.AffinityFromFleet(fleet, nil)
+		}
+		return avdmanager.NewCoordinator(agents, policy)
+	}
+	cmd := &cobra.Command{
+		Use:   "fleet",
+		Short: "Coordinate several avdctl hosts: place clones and runs on the least-loaded one, list them as one",
+		Long: `The agents file lists the avdctl hosts to coordinate, reached over SSH like --ssh:
+
+  agents:
+    - name: rack-1
+      ssh: ci@rack-1.internal
+      capacity: 8
+    - name: local
+
+Clones go to the least-loaded agent that has the base, runs to the least-loaded agent that
+has the AVD and does not run it yet. Agents at capacity or unreachable are skipped.
+
+With --file, the clone labels and placement rules of a fleet file apply first:
+
+  clones:
+    - name: w-acme-1
+      labels: {customer: acme}
+  placement:
+    affinity: [customer]       # clones of one customer share a host
+    anti_affinity: [isolate]   # clones with the same isolate value never do`,
+	}
+	cmd.PersistentFlags().StringVar(&agentsFile, "agents", agentsFile, "agents file ($AVDCTL_FLEET_AGENTS when set)")
+	cmd.PersistentFlags().StringVarP(&fleetFile, "file", "f", "", "fleet file whose clone labels and placement rules constrain placement")
+	cmd.AddCommand(newFleetHostsCommand(coordinator))
+	cmd.AddCommand(newFleetListCommand(coordinator))
+	cmd.AddCommand(newFleetPSCommand(coordinator))
+	cmd.AddCommand(newFleetCloneCommand(coordinator))
+	cmd.AddCommand(newFleetRunCommand(coordinator))
+	cmd.AddCommand(newFleetLogsCommand(coordinator))
+	cmd.AddCommand(newFleetStopCommand(coordinator))
+	cmd.AddCommand(newFleetCapacityCommand(coordinator))
+	cmd.AddCommand(newFleetStatsCommand(coordinator))
+	return cmd
+}
+
+type coordinatorFunc func() (*avdmanager.Coordinator, error)
+
+func newFleetHostsCommand(coordinator coordinatorFunc) *cobra.Command {
+	var asJSON bool
+	cmd := &cobra.Command{
+		Use:   "hosts",
+		Short: "Show the load of every agent",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := coordinator()
+			if err != nil {
+				return err
+			}
+			loads := c.Loads()
+			if asJSON {
+				return encodeJSON(loads)
+			}
+			fmt.Printf("%-16s %-9s %s\n", "AGENT", "RUNNING", "AVDS")
+			for _, load := range loads {
+				if load.Error != "" {
+					fmt.Printf("%-16s %-9s %s\n", load.Agent, "-", "unreachable: "+firstErrorLine(load.Error))
+					continue
+				}
+				running := fmt.Sprint(load.Running)
+				if load.Capacity > 0 {
+					running += fmt.Sprintf("/%d", load.Capacity)
+				}
+				fmt.Printf("%-16s %-9s %d\n", load.Agent, running, len(load.AVDs))
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&asJSON, "json", false, "output JSON")
+	return cmd
+}
+
+func newFleetListCommand(coordinator coordinatorFunc) *cobra.Command {
+	var asJSON bool
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the AVDs of every agent",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := coordinator()
+			if err != nil {
+				return err
+			}
+			avds, err := c.List()
+			if asJSON {
+				if jsonErr := encodeJSON(avds); jsonErr != nil {
+					return jsonErr
+				}
+				return err
+			}
+			for _, a := range avds {
+				fmt.Printf("%-16s %-24s %s\n", a.Agent, a.Name, a.Path)
+			}
+			return err
+		},
+	}
+	cmd.Flags().BoolVar(&asJSON, "json", false, "output JSON")
+	return cmd
+}
+
+func newFleetPSCommand(coordinator coordinatorFunc) *cobra.Command {
+	var asJSON bool
+	cmd := &cobra.Command{
+		Use:   "ps",
+		Short: "List the running instances of every agent",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := coordinator()
+			if err != nil {
+				return err
+			}
+			procs, err := c.ListRunning()
+			if asJSON {
+				if jsonErr := encodeJSON(procs); jsonErr != nil {
+					return jsonErr
+				}
+				return err
+			}
+			for _, p := range procs {
+				state := "booting"
+				if p.Booted {
+					state = "booted"
+				}
+				fmt.Printf("%-16s %-16s %-24s %s\n", p.Agent, p.Serial, p.Name, state)
+			}
+			return err
+		},
+	}
+	cmd.Flags().BoolVar(&asJSON, "json", false, "output JSON")
+	return cmd
+}
+
+func newFleetCloneCommand(coordinator coordinatorFunc) *cobra.Command {
+	var base, name, golden string
+	cmd := &cobra.Command{
+		Use:   "clone",
+		Short: "Clone a base on the least-loaded agent that has it",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if base == "" || name == "" || golden == "" {
+				return fmt.Errorf("--base, --name and --golden are required")
+			}
+			c, err := coordinator()
+			if err != nil {
+				return err
+			}
+			info, err := c.Clone(avdmanager.CloneOptions{BaseName: base, CloneName: name, GoldenPath: golden})
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Cloned %s on %s (%s)\n", info.Name, info.Agent, info.Path)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&base, "base", "", "base AVD name")
+	cmd.Flags().StringVar(&name, "name", "", "clone name")
+	cmd.Flags().StringVar(&golden, "golden", "", "golden path or @channel, resolved on the chosen agent")
+	return cmd
+}
+
+func newFleetRunCommand(coordinator coordinatorFunc) *cobra.Command {
+	var name string
+	cmd := &cobra.Command{
+		Use:   "run",
+		Short: "Start an AVD on the least-loaded agent that has it",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if name == "" {
+				return fmt.Errorf("--name is required")
+			}
+			c, err := coordinator()
+			if err != nil {
+				return err
+			}
+			res, err := c.Start(avdmanager.RunOptions{Name: name})
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Started %s on %s %s (log: %s) mode %s\n", res.Name, res.Agent, res.Serial, res.LogPath, res.Mode)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&name, "name", "", "AVD name")
+	return cmd
+}
+
+func newFleetLogsCommand(coordinator coordinatorFunc) *cobra.Command {
+	var agent, serial string
+	var lines int
+	cmd := &cobra.Command{
+		Use:   "logs",
+		Short: "Print the emulator log of an instance on an agent",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if agent == "" || serial == "" {
+				return fmt.Errorf("--agent and --serial are required")
+			}
+			c, err := coordinator()
+			if err != nil {
+				return err
+			}
+			out, err := c.EmulatorLog(agent, serial, lines)
+			if err != nil {
+				return err
+			}
+			fmt.Print(out)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&agent, "agent", "", "agent running the instance (see fleet ps)")
+	cmd.Flags().StringVar(&serial, "serial", "", "emulator serial")
+	cmd.Flags().IntVarP(&lines, "lines", "n", 100, "print only the last lines (0 prints the whole log)")
+	return cmd
+}
+
+func newFleetStopCommand(coordinator coordinatorFunc) *cobra.Command {
+	var agent, serial string
+	cmd := &cobra.Command{
+		Use:   "stop",
+		Short: "Stop an instance on an agent",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if agent == "" || serial == "" {
+				return fmt.Errorf("--agent and --serial are required")
+			}
+			c, err := coordinator()
+			if err != nil {
+				return err
+			}
+			if err := c.Stop(agent, serial); err != nil {
+				return err
+			}
+			fmt.Printf("Stopped %s on %s\n", serial, agent)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&agent, "agent", "", "agent running the instance (see fleet ps)")
+	cmd.Flags().StringVar(&serial, "serial", "", "emulator serial")
+	return cmd
+}
+
+func newFleetCapacityCommand(coordinator coordinatorFunc) *cobra.Command {
+	var golden string
+	var asJSON bool
+	cmd := &cobra.Command{
+		Use:   "capacity",
+		Short: "Estimate how many more clones of a golden every agent, and the fleet, can run",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if golden == "" {
+				return fmt.Errorf("--golden is required")
+			}
+			c, err := coordinator()
+			if err != nil {
+				return err
+			}
+			total, reports := c.Capacity(golden)
+			if asJSON {
+				return encodeJSON(map[string]any{"additional": total, "agents": reports})
+			}
+			for _, r := range reports {
+				if r.Error != "" {
+					fmt.Printf("%-16s -      %s\n", r.Agent, firstErrorLine(r.Error))
+					continue
+				}
+				fmt.Printf("%-16s %-6d bottleneck %s (%d runs sampled)\n", r.Agent, r.Additional, r.Bottleneck, r.Samples)
+			}
+			fmt.Printf("%-16s %d\n", "fleet", total)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&golden, "golden", "", "golden path, name or @channel, resolved on each agent")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "output JSON")
+	return cmd
+}
+
+func newFleetStatsCommand(coordinator coordinatorFunc) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "stats",
+		Short: "Summarize the run history of every agent",
+	}
+	cmd.AddCommand(newStatsFailuresCommand(func(since time.Time) (avdmanager.FailureReport, error) {
+		c, err := coordinator()
+		if err != nil {
+			return avdmanager.FailureReport{}, err
+		}
+		return c.FailureStats(since)
+	}))
+	return cmd
+}
+
+// firstErrorLine keeps the first line of a remote error, which is followed by its stderr.
+func firstErrorLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	core "github.com/forkbombeu/avdctl/internal/avd"
+	"github.com/spf13/cobra"
+)
+
+func newAndroidCustomizeCommand(env core.Env) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "customize",
+		Short: "Helpers for a customize-start session (status, push, type)",
+	}
+	cmd.AddCommand(newAndroidCustomizeStatusCommand(env), newAndroidCustomizePushCommand(env), newAndroidCustomizeTypeCommand(env))
+	return cmd
+}
+
+func newAndroidCustomizeStatusCommand(env core.Env) *cobra.Command {
+	var name string
+	var asJSON bool
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show whether the customize-start emulator is still up and for how long",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if name == "" {
+				return errors.New("--name is required")
+			}
+			session, err := core.CustomizeStatus(env, name)
+			if err != nil {
+				return err
+			}
+			if asJSON {
+				return encodeJSON(session)
+			}
+			if !session.Running {
+				fmt.Printf("Customize session for %s is not running (started %s); run customize-start to resume or customize-finish to export\n",
+					name, session.StartedAt.Local().Format(time.RFC3339))
+				return nil
+			}
+			state := "booting"
+			if session.Booted {
+				state = "booted"
+			}
+			serial := session.Serial
+			if serial == "" {
+				serial = "not in adb yet"
+			}
+			fmt.Printf("Customize session for %s: running, %s (pid %d, %s, up %s)\nLog: %s\n",
+				name, state, session.PID, serial, session.Uptime().Round(time.Second), session.LogPath)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&name, "name", "", "AVD name")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "print the session as JSON")
+	return cmd
+}
+
+func newAndroidCustomizePushCommand(env core.Env) *cobra.Command {
+	var name, serial, dest string
+	cmd := &cobra.Command{
+		Use:   "push FILE...",
+		Short: "Copy host files into the emulator (default: " + core.DefaultPushDir + ")",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			resolved, err := resolveAndroidSerial(env, name, serial)
+			if err != nil {
+				return err
+			}
+			pushed, err := core.PushFiles(env, resolved, args, dest)
+			for _, guestPath := range pushed {
+				fmt.Printf("Pushed %s\n", guestPath)
+			}
+			return err
+		},
+	}
+	cmd.Flags().StringVar(&name, "name", "", "running AVD name")
+	cmd.Flags().StringVar(&serial, "serial", "", "emulator serial (e.g. emulator-5580)")
+	cmd.Flags().StringVar(&dest, "dest", "", "guest directory (default: "+core.DefaultPushDir+")")
+	return cmd
+}
+
+func newAndroidCustomizeTypeCommand(env core.Env) *cobra.Command {
+	var name, serial string
+	cmd := &cobra.Command{
+		Use:   "type [TEXT]",
+		Short: "Type text into the focused field; without TEXT it is read from stdin (e.g. xclip -o | avdctl customize type)",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			resolved, err := resolveAndroidSerial(env, name, serial)
+			if err != nil {
+				return err
+			}
+			var text string
+			if len(args) == 1 {
+				text = args[0]
+			} else {
+				b, err := io.ReadAll(os.Stdin)
+				if err != nil {
+					return fmt.Errorf("read stdin: %w", err)
+				}
+				text = strings.TrimRight(string(b), "\r\n")
+			}
+			if text == "" {
+				return errors.New("no text to type")
+			}
+			return core.TypeText(env, resolved, text)
+		},
+	}
+	cmd.Flags().StringVar(&name, "name", "", "running AVD name")
+	cmd.Flags().StringVar(&serial, "serial", "", "emulator serial (e.g. emulator-5580)")
+	return cmd
+}
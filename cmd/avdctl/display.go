@@ -0,0 +1,44 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	core "github.com/forkbombeu/avdctl/internal/avd"
+	"github.com/spf13/cobra"
+)
+
+func newAndroidDisplayCommand(env core.Env) *cobra.Command {
+	var name, serial, size string
+	var density int
+	var reset bool
+	cmd := &cobra.Command{
+		Use:   "display",
+		Short: "Override the screen resolution/density of a running emulator (wm size/density)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			resolved, err := resolveAndroidSerial(env, name, serial)
+			if err != nil {
+				return err
+			}
+			if reset {
+				if size != "" || density != 0 {
+					return errors.New("--reset cannot be combined with --size or --density")
+				}
+				return core.ResetDisplaySize(env, resolved)
+			}
+			var width, height int
+			if size != "" {
+				if _, err := fmt.Sscanf(size, "%dx%d", &width, &height); err != nil {
+					return fmt.Errorf("invalid --size %q (want WIDTHxHEIGHT)", size)
+				}
+			}
+			return core.SetDisplaySize(env, resolved, width, height, density)
+		},
+	}
+	cmd.Flags().StringVar(&name, "name", "", "running AVD name")
+	cmd.Flags().StringVar(&serial, "serial", "", "emulator serial (e.g. emulator-5580)")
+	cmd.Flags().StringVar(&size, "size", "", "resolution in pixels, e.g. 1200x1920")
+	cmd.Flags().IntVar(&density, "density", 0, "density in dpi, e.g. 240")
+	cmd.Flags().BoolVar(&reset, "reset", false, "restore the resolution and density of the device profile")
+	return cmd
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"errors"
+
+	core "github.com/forkbombeu/avdctl/internal/avd"
+	"github.com/spf13/cobra"
+)
+
+func newAndroidDozeCommand(env core.Env) *cobra.Command {
+	var name, serial string
+	var disable, enable bool
+	cmd := &cobra.Command{
+		Use:   "doze (--disable | --enable)",
+		Short: "Turn doze off (or back on) on a running emulator (dumpsys deviceidle)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			resolved, err := resolveAndroidSerial(env, name, serial)
+			if err != nil {
+				return err
+			}
+			if disable == enable {
+				return errors.New("use one of --disable or --enable")
+			}
+			if disable {
+				return core.DisableDoze(env, resolved)
+			}
+			return core.EnableDoze(env, resolved)
+		},
+	}
+	cmd.Flags().StringVar(&name, "name", "", "running AVD name")
+	cmd.Flags().StringVar(&serial, "serial", "", "emulator serial (e.g. emulator-5580)")
+	cmd.Flags().BoolVar(&disable, "disable", false, "disable light and deep doze until the next reboot")
+	cmd.Flags().BoolVar(&enable, "enable", false, "re-enable doze")
+	return cmd
+}
+
+func newAndroidStandbyBucketCommand(env core.Env) *cobra.Command {
+	var name, serial string
+	cmd := &cobra.Command{
+		Use:   "standby-bucket PACKAGE BUCKET",
+		Short: "Set the app standby bucket of a package (active, working_set, frequent, rare, restricted)",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			resolved, err := resolveAndroidSerial(env, name, serial)
+			if err != nil {
+				return err
+			}
+			return core.SetAppStandbyBucket(env, resolved, args[0], core.StandbyBucket(args[1]))
+		},
+	}
+	cmd.Flags().StringVar(&name, "name", "", "running AVD name")
+	cmd.Flags().StringVar(&serial, "serial", "", "emulator serial (e.g. emulator-5580)")
+	return cmd
+}
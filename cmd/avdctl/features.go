@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	core "github.com/forkbombeu/avdctl/internal/avd"
+	"github.com/spf13/cobra"
+)
+
+func newAndroidFeaturesCommand() *cobra.Command {
+	var asJSON bool
+	cmd := &cobra.Command{
+		Use:   "features",
+		Short: "Show the host-wide emulator features of advancedFeatures.ini",
+		Long: `advancedFeatures.ini turns emulator features such as Vulkan, VirtioWifi or
+BluetoothEmulation on or off for every AVD on the host. avdctl does not edit it; override a
+feature for one instance instead, on the command line of that run:
+
+  avdctl run --name w-acme --feature Vulkan=on --feature VirtioWifi=off`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			features, err := core.ReadAdvancedFeatures()
+			if err != nil {
+				return err
+			}
+			if asJSON {
+				return encodeJSON(features)
+			}
+			fmt.Printf("# %s\n", core.AdvancedFeaturesPath())
+			names := make([]string, 0, len(features))
+			for name := range features {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				state := "off"
+				if features[name] {
+					state = "on"
+				}
+				fmt.Printf("%s = %s\n", name, state)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&asJSON, "json", false, "print the features as a JSON object")
+	return cmd
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	core "github.com/forkbombeu/avdctl/internal/avd"
+	"github.com/spf13/cobra"
+)
+
+func newAndroidUpCommand(env core.Env) *cobra.Command {
+	var file string
+	var upJSON bool
+	cmd := &cobra.Command{
+		Use:   "up",
+		Short: "Converge Android bases, goldens and clones to a fleet file (create, clone, start)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fleet, err := core.LoadFleet(file)
+			if err != nil {
+				return err
+			}
+			report, err := core.FleetUp(env, fleet)
+			if err != nil {
+				return err
+			}
+			return printFleetReport(report, upJSON)
+		},
+	}
+	cmd.Flags().StringVarP(&file, "file", "f", "fleet.yaml", "fleet file")
+	cmd.Flags().BoolVar(&upJSON, "json", false, "output JSON")
+	return cmd
+}
+
+func newAndroidDownCommand(env core.Env) *cobra.Command {
+	var file string
+	var remove, downJSON bool
+	cmd := &cobra.Command{
+		Use:   "down",
+		Short: "Stop clones declared in a fleet file; --remove also deletes them",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fleet, err := core.LoadFleet(file)
+			if err != nil {
+				return err
+			}
+			report, err := core.FleetDown(env, fleet, remove)
+			if err != nil {
+				return err
+			}
+			return printFleetReport(report, downJSON)
+		},
+	}
+	cmd.Flags().StringVarP(&file, "file", "f", "fleet.yaml", "fleet file")
+	cmd.Flags().BoolVar(&remove, "remove", false, "delete clones after stopping them (bases and goldens are kept)")
+	cmd.Flags().BoolVar(&downJSON, "json", false, "output JSON")
+	return cmd
+}
+
+func newAndroidDiffCommand(env core.Env) *cobra.Command {
+	var file string
+	var diffJSON bool
+	cmd := &cobra.Command{
+		Use:   "diff",
+		Short: "Report drift between a fleet file and the host (AVDs, running instances, config values)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fleet, err := core.LoadFleet(file)
+			if err != nil {
+				return err
+			}
+			report, err := core.FleetDiff(env, fleet)
+			if err != nil {
+				return err
+			}
+			if diffJSON {
+				if err := encodeJSON(report); err != nil {
+					return err
+				}
+			} else {
+				if !report.Drifted() {
+					fmt.Println("(no drift)")
+				}
+				for _, d := range report.Drift {
+					line := fmt.Sprintf("%-9s %-18s %-22s want=%q got=%q", d.Kind, d.Name, d.Field, d.Want, d.Got)
+					if d.Detail != "" {
+						line += "  " + d.Detail
+					}
+					fmt.Println(line)
+				}
+			}
+			if report.Drifted() {
+				return fmt.Errorf("fleet drift detected: %d difference(s)", len(report.Drift))
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&file, "file", "f", "fleet.yaml", "fleet file")
+	cmd.Flags().BoolVar(&diffJSON, "json", false, "output JSON")
+	return cmd
+}
+
+func printFleetReport(report core.FleetReport, asJSON bool) error {
+	if asJSON {
+		if err := encodeJSON(report); err != nil {
+			return err
+		}
+	} else {
+		printFleetActions(report.Actions)
+	}
+	if report.Failed() {
+		return errors.New("fleet converge finished with failures")
+	}
+	return nil
+}
+
+func printFleetActions(actions []core.FleetAction) {
+	if len(actions) == 0 {
+		fmt.Println("(nothing to do)")
+	}
+	for _, action := range actions {
+		line := fmt.Sprintf("%-13s %-18s %s", action.Kind, action.Name, action.Status)
+		if action.Detail != "" {
+			line += "  " + action.Detail
+		}
+		fmt.Println(line)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	core "github.com/forkbombeu/avdctl/internal/avd"
+	"github.com/spf13/cobra"
+)
+
+func newAndroidForwardCommand(env core.Env) *cobra.Command {
+	return newAndroidTunnelCommand(env, "forward",
+		"Forward a host TCP port to an emulator port (adb forward; removed on stop)")
+}
+
+func newAndroidReverseCommand(env core.Env) *cobra.Command {
+	return newAndroidTunnelCommand(env, "reverse",
+		"Let an emulator reach a host TCP port (adb reverse; removed on stop)")
+}
+
+func newAndroidTunnelCommand(env core.Env, direction, short string) *cobra.Command {
+	var serial string
+	var hostPort, devicePort int
+	var remove, tunnelJSON bool
+	cmd := &cobra.Command{
+		Use:   direction,
+		Short: short,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if serial == "" {
+				return errors.New("--serial is required")
+			}
+			if devicePort <= 0 || hostPort < 0 || (hostPort == 0 && direction == "reverse") {
+				return errors.New("--device and --host ports are required")
+			}
+			fwd := core.PortForward{Direction: direction, HostPort: hostPort, DevicePort: devicePort}
+			if remove {
+				return core.RemoveForward(env, serial, fwd)
+			}
+			var err error
+			if direction == "reverse" {
+				fwd, err = core.Reverse(env, serial, devicePort, hostPort)
+			} else {
+				fwd, err = core.Forward(env, serial, hostPort, devicePort)
+			}
+			if err != nil {
+				return err
+			}
+			if tunnelJSON {
+				return encodeJSON(fwd)
+			}
+			if direction == "reverse" {
+				fmt.Printf("%s: device tcp:%d -> host tcp:%d\n", serial, fwd.DevicePort, fwd.HostPort)
+			} else {
+				fmt.Printf("%s: host tcp:%d -> device tcp:%d\n", serial, fwd.HostPort, fwd.DevicePort)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&serial, "serial", "", "emulator serial (e.g. emulator-5580)")
+	cmd.Flags().IntVar(&hostPort, "host", 0, "host TCP port (forward: 0 lets adb pick one)")
+	cmd.Flags().IntVar(&devicePort, "device", 0, "emulator TCP port")
+	cmd.Flags().BoolVar(&remove, "remove", false, "remove the tunnel instead of adding it")
+	cmd.Flags().BoolVar(&tunnelJSON, "json", false, "output JSON")
+	return cmd
+}
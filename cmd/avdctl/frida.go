@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+
+	core "github.com/forkbombeu/avdctl/internal/avd"
+	"github.com/spf13/cobra"
+)
+
+func newAndroidFridaCommand(env core.Env) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "frida",
+		Short: "Run frida-server on a running emulator for dynamic analysis (start, stop)",
+	}
+	cmd.AddCommand(newAndroidFridaStartCommand(env), newAndroidFridaStopCommand(env))
+	return cmd
+}
+
+func newAndroidFridaStartCommand(env core.Env) *cobra.Command {
+	var name, serial, server string
+	var port int
+	var jsonOut bool
+	cmd := &cobra.Command{
+		Use:   "start",
+		Short: "Start frida-server as root and forward a host port to it",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			resolved, err := resolveAndroidSerial(env, name, serial)
+			if err != nil {
+				return err
+			}
+			if server != "" {
+				env.FridaServer = server
+			}
+			session, err := core.StartFrida(env, resolved, port)
+			if err != nil {
+				return err
+			}
+			if jsonOut {
+				return encodeJSON(session)
+			}
+			fmt.Printf("frida-server running on %s (pid %d)\n", session.Serial, session.PID)
+			fmt.Printf("Attach with: frida -H 127.0.0.1:%d -f <package>  (or objection -N -h 127.0.0.1 -p %d)\n", session.HostPort, session.HostPort)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&name, "name", "", "running AVD name")
+	cmd.Flags().StringVar(&serial, "serial", "", "emulator serial (e.g. emulator-5580)")
+	cmd.Flags().IntVar(&port, "port", core.DefaultFridaPort, "host port forwarded to frida-server")
+	cmd.Flags().StringVar(&server, "server", "", "frida-server binary to install if the guest has none (default $AVDCTL_FRIDA_SERVER)")
+	cmd.Flags().BoolVar(&jsonOut, "json", false, "print the session as JSON")
+	return cmd
+}
+
+func newAndroidFridaStopCommand(env core.Env) *cobra.Command {
+	var name, serial string
+	cmd := &cobra.Command{
+		Use:   "stop",
+		Short: "Stop frida-server and remove its port forward",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			resolved, err := resolveAndroidSerial(env, name, serial)
+			if err != nil {
+				return err
+			}
+			if err := core.StopFrida(env, resolved); err != nil {
+				return err
+			}
+			fmt.Printf("frida-server stopped on %s\n", resolved)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&name, "name", "", "running AVD name")
+	cmd.Flags().StringVar(&serial, "serial", "", "emulator serial (e.g. emulator-5580)")
+	return cmd
+}
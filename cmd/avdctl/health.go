@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	core "github.com/forkbombeu/avdctl/internal/avd"
+	"github.com/spf13/cobra"
+)
+
+func newAndroidHealthCommand(env core.Env) *cobra.Command {
+	var name, serial string
+	var asJSON bool
+	cmd := &cobra.Command{
+		Use:   "health",
+		Short: "Run the post-boot health checks (package manager, userdata, home activity) on a booted instance",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			resolved, err := resolveAndroidSerial(env, name, serial)
+			if err != nil {
+				return err
+			}
+			checks := core.HealthChecks(env, resolved)
+			if asJSON {
+				return encodeJSON(checks)
+			}
+			var failed []string
+			for _, check := range checks {
+				status := "ok"
+				if !check.OK {
+					status = "FAIL " + check.Detail
+					failed = append(failed, check.Name)
+				}
+				fmt.Printf("%-18s %s\n", check.Name, status)
+			}
+			if len(failed) > 0 {
+				return fmt.Errorf("%s failed health checks: %s", resolved, strings.Join(failed, ", "))
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&name, "name", "", "running AVD name")
+	cmd.Flags().StringVar(&serial, "serial", "", "emulator serial (e.g. emulator-5580)")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "print the checks as JSON (exits 0 even with failed checks)")
+	return cmd
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	core "github.com/forkbombeu/avdctl/internal/avd"
+	"github.com/spf13/cobra"
+)
+
+func newAndroidHibernateCommand(env core.Env) *cobra.Command {
+	var asJSON, list bool
+	cmd := &cobra.Command{
+		Use:   "hibernate NAME",
+		Short: "Snapshot a running instance (RAM+disk) and stop it; wake brings it back in seconds",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if list {
+				out, err := core.ListHibernated(env)
+				if err != nil {
+					return err
+				}
+				if asJSON {
+					return encodeJSON(out)
+				}
+				for _, h := range out {
+					fmt.Printf("%s\tsince %s\t%.1f MiB\n", h.Name, h.Since.Local().Format(time.DateTime), float64(h.SizeBytes)/(1<<20))
+				}
+				return nil
+			}
+			if len(args) != 1 {
+				return errors.New("hibernate needs an AVD name (or --list)")
+			}
+			out, err := core.Hibernate(env, args[0])
+			if err != nil {
+				return err
+			}
+			if asJSON {
+				return encodeJSON(out)
+			}
+			fmt.Printf("Hibernated %s (snapshot %s, %.1f MiB)\n", out.Name, out.Snapshot, float64(out.SizeBytes)/(1<<20))
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&list, "list", false, "list hibernated instances")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "output JSON")
+	return cmd
+}
+
+func newAndroidWakeCommand(env core.Env) *cobra.Command {
+	var wait time.Duration
+	cmd := &cobra.Command{
+		Use:   "wake NAME",
+		Short: "Start a hibernated instance from its snapshot",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			res, err := core.Wake(env, args[0])
+			if err != nil {
+				return err
+			}
+			if wait > 0 {
+				if err := core.WaitForBoot(env, res.Serial, wait); err != nil {
+					return fmt.Errorf("%w\nemulator log: %s", err, res.LogPath)
+				}
+			}
+			printStarted(res)
+			return nil
+		},
+	}
+	cmd.Flags().DurationVar(&wait, "wait", 0, "wait up to this long for the guest to be ready (0: do not wait)")
+	return cmd
+}
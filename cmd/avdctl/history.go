@@ -0,0 +1,140 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	core "github.com/forkbombeu/avdctl/internal/avd"
+	"github.com/spf13/cobra"
+)
+
+func newAndroidHistoryCommand(env core.Env) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "Query, export or prune the run history of AVDs (runs, logs, screenshots, bugreports)",
+		Long: `Every start by avdctl is recorded with its emulator log, and every capture with its files.
+AVDCTL_RETENTION caps what each AVD keeps, e.g.
+
+  AVDCTL_RETENTION=runs.age=30d,runs.count=50,logs.size=500M,screenshots.count=20,bugreports.age=14d
+
+It is applied at every start and capture, by cleanup --force and by history prune. Without a
+runs count the last 20 runs are kept.`,
+	}
+
+	var listName string
+	var listJSON bool
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List the recorded runs of an AVD and their files, oldest first",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if listName == "" {
+				return errors.New("--name is required")
+			}
+			runs, err := core.RunHistory(env, listName)
+			if err != nil {
+				return err
+			}
+			if listJSON {
+				return encodeJSON(runs)
+			}
+			printRunHistory(runs)
+			return nil
+		},
+	}
+	list.Flags().StringVar(&listName, "name", "", "AVD name")
+	list.Flags().BoolVar(&listJSON, "json", false, "output JSON")
+
+	var exportName, output string
+	export := &cobra.Command{
+		Use:   "export",
+		Short: "Write the run history of an AVD and its files as a .tar.gz",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if exportName == "" {
+				return errors.New("--name is required")
+			}
+			var w io.Writer = os.Stdout
+			if output != "-" {
+				f, err := os.Create(output)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				w = f
+			}
+			if err := core.ExportHistory(env, exportName, w); err != nil {
+				return err
+			}
+			if output != "-" {
+				fmt.Fprintf(os.Stderr, "Exported history of %s to %s\n", exportName, output)
+			}
+			return nil
+		},
+	}
+	export.Flags().StringVar(&exportName, "name", "", "AVD name")
+	export.Flags().StringVarP(&output, "output", "o", "-", "archive path (- for stdout)")
+
+	var pruneName string
+	var dryRun, pruneJSON bool
+	prune := &cobra.Command{
+		Use:   "prune",
+		Short: "Apply AVDCTL_RETENTION to the run history of an AVD, or of every AVD",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			pruned, err := core.PruneHistory(env, pruneName, dryRun)
+			if err != nil {
+				return err
+			}
+			if pruneJSON {
+				return encodeJSON(pruned)
+			}
+			printPruned(pruned, dryRun)
+			return nil
+		},
+	}
+	prune.Flags().StringVar(&pruneName, "name", "", "AVD name (default: every AVD)")
+	prune.Flags().BoolVar(&dryRun, "dry-run", false, "show what would be pruned")
+	prune.Flags().BoolVar(&pruneJSON, "json", false, "output JSON")
+
+	cmd.AddCommand(list, export, prune)
+	return cmd
+}
+
+func printRunHistory(runs []core.InstanceRun) {
+	if len(runs) == 0 {
+		fmt.Println("(no runs recorded)")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STARTED\tSERIAL\tPID\tOUTCOME\tKIND\tFILE")
+	for _, run := range runs {
+		started := run.StartedAt.Local().Format("2006-01-02 15:04:05")
+		outcome := run.Outcome
+		if outcome == "" {
+			outcome = "running"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%s\n", started, run.Serial, run.PID, outcome, core.ArtifactLog, orDash(run.LogPath))
+		for _, a := range run.Artifacts {
+			fmt.Fprintf(w, "\t\t\t\t%s\t%s\n", a.Kind, a.Path)
+		}
+	}
+	_ = w.Flush()
+}
+
+func printPruned(pruned []core.PrunedItem, dryRun bool) {
+	var freed int64
+	for _, item := range pruned {
+		freed += item.SizeBytes
+		what := item.Path
+		if item.Kind == "run" {
+			what = "run started " + item.StartedAt.Local().Format("2006-01-02 15:04:05")
+		}
+		fmt.Printf("%s: %s %s (%s)\n", item.AVD, item.Kind, what, item.Reason)
+	}
+	verb := "Pruned"
+	if dryRun {
+		verb = "Would prune"
+	}
+	fmt.Printf("%s %d item(s), %.1f MiB\n", verb, len(pruned), float64(freed)/(1<<20))
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	core "github.com/forkbombeu/avdctl/internal/avd"
+	"github.com/spf13/cobra"
+)
+
+func newAndroidHostResumeCommand(env core.Env) *cobra.Command {
+	var asJSON, watch bool
+	var interval time.Duration
+	cmd := &cobra.Command{
+		Use:   "host-resume",
+		Short: "Re-validate running emulators after a host suspend (--watch: detect suspends and check after each)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if watch {
+				return core.WatchHostResume(env, interval, func(checks []core.ResumeCheck) {
+					fmt.Printf("host resumed at %s\n", time.Now().Format(time.RFC3339))
+					printResumeChecks(checks)
+				})
+			}
+			checks, err := core.CheckAfterResume(env)
+			if err != nil {
+				return err
+			}
+			if asJSON {
+				return encodeJSON(checks)
+			}
+			if lost := printResumeChecks(checks); len(lost) > 0 {
+				return fmt.Errorf("instances lost after host resume: %s", strings.Join(lost, ", "))
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&asJSON, "json", false, "print the checks as JSON (exits 0 even with lost instances)")
+	cmd.Flags().BoolVar(&watch, "watch", false, "keep polling the clocks and check the instances after every suspend")
+	cmd.Flags().DurationVar(&interval, "interval", core.DefaultResumePollInterval, "time between clock polls with --watch")
+	return cmd
+}
+
+// printResumeChecks prints one line per instance and returns the serials of the lost ones.
+func printResumeChecks(checks []core.ResumeCheck) []string {
+	var lost []string
+	for _, check := range checks {
+		status := "ok"
+		if check.ClockSynced {
+			status += fmt.Sprintf(" (clock resynced, was %s off)", check.ClockSkew)
+		}
+		if !check.Survived {
+			status = "LOST " + strings.Join(check.Problems, "; ")
+			lost = append(lost, check.Serial)
+		}
+		fmt.Printf("%-16s %-24s %s\n", check.Serial, check.Name, status)
+	}
+	return lost
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+
+	core "github.com/forkbombeu/avdctl/internal/avd"
+	"github.com/spf13/cobra"
+)
+
+func newHWConfigCommand(env core.Env) *cobra.Command {
+	var asJSON bool
+	cmd := &cobra.Command{
+		Use:   "hwconfig NAME",
+		Short: "Show an AVD's hardware-qemu.ini and the paths pointing into other AVDs",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out, err := core.InspectHardwareConfig(env, args[0])
+			if err != nil {
+				return err
+			}
+			if asJSON {
+				return encodeJSON(out)
+			}
+			for _, e := range out.Entries {
+				fmt.Printf("%s = %s\n", e.Key, e.Value)
+			}
+			for _, e := range out.OtherAVDPaths {
+				fmt.Printf("warning: %s points into another AVD: %s\n", e.Key, e.Value)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&asJSON, "json", false, "output JSON")
+	return cmd
+}
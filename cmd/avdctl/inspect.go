@@ -0,0 +1,130 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	core "github.com/forkbombeu/avdctl/internal/avd"
+	"github.com/spf13/cobra"
+)
+
+func newAndroidInspectCommand(env core.Env) *cobra.Command {
+	var name string
+	var asJSON bool
+	cmd := &cobra.Command{
+		Use:   "inspect",
+		Short: "Report everything known about one AVD (config, images, golden, runs, process, log, health)",
+		Long: `inspect gathers an AVD's config.ini, images and symlinks, the golden and customizations it
+was made with, its recent starts with the exact emulator and qemu command lines, the running
+process with its health checks, and the end of its emulator log. Paste the report, or the
+--json form, into support tickets.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if name == "" {
+				return errors.New("--name is required")
+			}
+			ins, err := core.Inspect(env, name)
+			if err != nil {
+				return err
+			}
+			if asJSON {
+				return encodeJSON(ins)
+			}
+			printInspection(ins)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&name, "name", "", "AVD name")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "print the report as JSON")
+	return cmd
+}
+
+func printInspection(ins core.Inspection) {
+	fmt.Printf("name:     %s (%s)\n", ins.Name, ins.Kind)
+	fmt.Printf("path:     %s\n", ins.Path)
+	if ins.Golden != "" {
+		fmt.Printf("golden:   %s\n", ins.Golden)
+	}
+	if m := ins.GoldenManifest; m != nil {
+		fmt.Printf("          from %s, created %s", m.Source, m.CreatedAt.Local().Format(time.RFC3339))
+		if m.Validation != nil {
+			fmt.Printf(", validated %s", m.Validation.ValidatedAt.Local().Format(time.RFC3339))
+		}
+		fmt.Println()
+	}
+	for _, apk := range ins.Customizations.APKs {
+		fmt.Printf("apk:      %s\n", apk)
+	}
+	for _, setting := range ins.Customizations.Settings {
+		fmt.Printf("setting:  %s\n", setting)
+	}
+
+	fmt.Println("\nconfig.ini:")
+	keys := make([]string, 0, len(ins.Config))
+	for k := range ins.Config {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Printf("  %s=%s\n", k, ins.Config[k])
+	}
+
+	fmt.Println("\nimages:")
+	for _, img := range ins.Images {
+		overlay := ""
+		if img.Overlay {
+			overlay = " (qcow2 overlay)"
+		}
+		fmt.Printf("  %-15s %9.1f MiB  %s%s\n", img.Role, float64(img.SizeBytes)/(1<<20), img.Path, overlay)
+	}
+	if len(ins.Links) > 0 {
+		fmt.Println("\nsymlinks:")
+		for _, l := range ins.Links {
+			status := "ok"
+			if !l.OK {
+				status = "BROKEN"
+			}
+			fmt.Printf("  %-6s %s -> %s\n", status, l.Path, l.Target)
+		}
+	}
+
+	if len(ins.History) > 0 {
+		fmt.Println("\nrecent starts:")
+		for _, run := range ins.History {
+			fmt.Printf("  %s  %-14s pid %-7d %s\n", run.StartedAt.Local().Format(time.RFC3339), run.Serial, run.PID, run.LogPath)
+		}
+	}
+	if len(ins.Command) > 0 {
+		state := "exited"
+		if ins.Running {
+			state = "running"
+		}
+		fmt.Printf("\nlast start: %s pid %d (%s)\n", ins.Serial, ins.PID, state)
+		fmt.Printf("  command: %s\n", strings.Join(ins.Command, " "))
+		if ins.QemuPID != 0 {
+			fmt.Printf("  qemu pid %d: %s\n", ins.QemuPID, strings.Join(ins.QemuCommand, " "))
+		}
+	}
+
+	fmt.Println()
+	if p := ins.Process; p != nil {
+		fmt.Printf("process:  %s pid %d, adb %s, booted %t\n", p.Serial, p.PID, p.ADBState, p.Booted)
+	} else {
+		fmt.Println("process:  not running")
+	}
+	for _, check := range ins.Checks {
+		status := "ok"
+		if !check.OK {
+			status = "FAIL " + check.Detail
+		}
+		fmt.Printf("  %-18s %s\n", check.Name, status)
+	}
+	if len(ins.LogTail) > 0 {
+		fmt.Printf("\nlog tail (%s):\n", ins.LogPath)
+		for _, line := range ins.LogTail {
+			fmt.Printf("  %s\n", line)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	core "github.com/forkbombeu/avdctl/internal/avd"
+	"github.com/spf13/cobra"
+)
+
+func newAndroidInstallCommand(env core.Env) *cobra.Command {
+	var name, serial string
+	cmd := &cobra.Command{
+		Use:   "install PATH",
+		Short: "Install an APK, split APK directory, .apks or .aab on a running emulator",
+		Long: `Install an app on a running emulator, replacing an installed version. PATH is an APK,
+a directory of split APKs (installed with adb install-multiple), or an .apks/.aab bundle whose
+splits are picked with bundletool for the AVD's ABI, density and API level ($AVDCTL_BUNDLETOOL,
+default bundletool; a .jar is run with java). Bundles are signed with ~/.android/debug.keystore.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			resolved, err := resolveAndroidSerial(env, name, serial)
+			if err != nil {
+				return err
+			}
+			if err := core.InstallAPK(env, resolved, args[0]); err != nil {
+				return err
+			}
+			fmt.Printf("Installed %s on %s\n", args[0], resolved)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&name, "name", "", "running AVD name")
+	cmd.Flags().StringVar(&serial, "serial", "", "emulator serial (e.g. emulator-5580)")
+	return cmd
+}
+
+func newAndroidDeviceSpecCommand(env core.Env) *cobra.Command {
+	var name string
+	cmd := &cobra.Command{
+		Use:   "device-spec",
+		Short: "Print the bundletool device spec of an AVD (for bundletool --device-spec)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if name == "" {
+				return errors.New("--name is required")
+			}
+			spec, err := core.DeviceSpecForAVD(env, name)
+			if err != nil {
+				return err
+			}
+			return encodeJSON(spec)
+		},
+	}
+	cmd.Flags().StringVar(&name, "name", "", "AVD name")
+	return cmd
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+
+	core "github.com/forkbombeu/avdctl/internal/avd"
+	"github.com/spf13/cobra"
+)
+
+func newAndroidLogsCommand(env core.Env) *cobra.Command {
+	var name, serial string
+	var lines int
+	cmd := &cobra.Command{
+		Use:   "logs",
+		Short: "Print the emulator log of a running instance avdctl started",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			resolved, err := resolveAndroidSerial(env, name, serial)
+			if err != nil {
+				return err
+			}
+			out, err := core.EmulatorLog(env, resolved, lines)
+			if err != nil {
+				return err
+			}
+			fmt.Print(out)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&name, "name", "", "AVD name of the running instance")
+	cmd.Flags().StringVar(&serial, "serial", "", "emulator serial (e.g. emulator-5580)")
+	cmd.Flags().IntVarP(&lines, "lines", "n", 100, "print only the last lines (0 prints the whole log)")
+	return cmd
+}
@@ -245,6 +245,31 @@ func TestPlatformListWithoutPlatformCombinesBothJSON(t *testing.T) {
 	}
 }
 
+func TestAndroidListFilterAndSort(t *testing.T) {
+	restore := restorePlatformHelperStubs()
+	t.Cleanup(restore)
+
+	androidListWideFn = func(core.Env) ([]core.Info, error) {
+		return []core.Info{
+			{Name: "base-a35", Kind: core.KindBase, SizeBytes: 900},
+			{Name: "w-big", Kind: core.KindClone, SizeBytes: 500},
+			{Name: "w-small", Kind: core.KindClone, SizeBytes: 100},
+		}, nil
+	}
+
+	root := newRootCommand("dev")
+	root.SetArgs([]string{"list", "android", "--json", "--filter", "name=prefix:w-", "--sort", "size"})
+	stdout := captureStdout(t, func() {
+		if err := root.Execute(); err != nil {
+			t.Fatalf("list execution failed: %v", err)
+		}
+	})
+	small, big := strings.Index(stdout, `"w-small"`), strings.Index(stdout, `"w-big"`)
+	if strings.Contains(stdout, "base-a35") || small < 0 || big < small {
+		t.Fatalf("expected w-small then w-big only:\n%s", stdout)
+	}
+}
+
 func TestPlatformRunWithoutPlatformPrefersAndroidOnNameCollision(t *testing.T) {
 	restore := restorePlatformHelperStubs()
 	t.Cleanup(restore)
@@ -252,8 +277,8 @@ func TestPlatformRunWithoutPlatformPrefersAndroidOnNameCollision(t *testing.T) {
 	androidListFn = func(core.Env) ([]core.Info, error) {
 		return []core.Info{{Name: "shared"}}, nil
 	}
-	androidRunAVDFn = func(core.Env, string) (string, error) {
-		return "emulator-5580", nil
+	androidRunAVDFn = func(_ core.Env, name string, _ ...string) (core.StartResult, error) {
+		return core.StartResult{Name: name, Serial: "emulator-5580", Port: 5580}, nil
 	}
 	iosEnsureSupportedFn = func() error { return nil }
 	iosListFn = func(ioscore.Env) ([]ioscore.Info, error) {
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	core "github.com/forkbombeu/avdctl/internal/avd"
+	"github.com/spf13/cobra"
+)
+
+func newAndroidMigrateCommand(env core.Env) *cobra.Command {
+	var opts core.MigrateOptions
+	var home core.HomeMigrationOptions
+	var migrateJSON bool
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Create a base on a new system image, re-applying the config, APKs and settings recorded for an old base/golden",
+		Long: `Create a base on a new system image, re-applying the config, APKs and settings recorded
+for an old base/golden (--from BASE --name NEW --image PKG).
+
+With --to, move a whole AVD home instead: every AVD of the directory --from goes to --to, with
+.ini paths, symlinks, hardware-qemu.ini paths and qcow2 backing files rewritten and verified.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if home.To != "" {
+				home.From = opts.From
+				return migrateHomeWithOutput(env, home, migrateJSON)
+			}
+			if opts.From == "" || opts.Name == "" || opts.SystemImage == "" {
+				return errors.New("--from, --name and --image are required")
+			}
+			report, err := core.MigrateBase(env, opts)
+			if err != nil {
+				return err
+			}
+			if migrateJSON {
+				if err := encodeJSON(report); err != nil {
+					return err
+				}
+			} else {
+				fmt.Printf("Migrated %s -> %s\n", report.From, report.Name)
+				if report.Golden != "" {
+					fmt.Printf("Golden saved: %s\n", report.Golden)
+				}
+				for _, step := range report.Steps {
+					line := fmt.Sprintf("%-8s %-8s %s", step.Status, step.Kind, step.Item)
+					if step.Detail != "" {
+						line += "  (" + step.Detail + ")"
+					}
+					fmt.Println(line)
+				}
+			}
+			if report.Failed() {
+				return fmt.Errorf("migration finished with failures: %d step(s) not carried over", len(report.NotCarried()))
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&opts.From, "from", "", "existing base AVD (e.g. base-a34), or with --to the AVD home to move")
+	cmd.Flags().StringVar(&opts.Golden, "golden", "", "golden of the old base whose recorded APKs and settings are re-applied")
+	cmd.Flags().StringVar(&opts.Name, "name", "", "new base AVD (e.g. base-a35)")
+	cmd.Flags().StringVar(&opts.SystemImage, "image", "", "system image package of the new base")
+	cmd.Flags().StringVar(&opts.Device, "device", "", "hardware profile (default: hw.device.name of --from)")
+	cmd.Flags().StringVar(&opts.Dest, "dest", "", "new golden directory; required to re-apply APKs and settings")
+	cmd.Flags().DurationVar(&opts.BootTimeout, "timeout", 3*time.Minute, "boot timeout while re-applying APKs and settings")
+	cmd.Flags().StringVar(&home.To, "to", "", "move the AVD home --from (a directory) to this directory")
+	cmd.Flags().BoolVar(&home.DryRun, "dry-run", false, "with --to, only show what would move")
+	cmd.Flags().BoolVar(&migrateJSON, "json", false, "output JSON")
+	return cmd
+}
+
+func migrateHomeWithOutput(env core.Env, opts core.HomeMigrationOptions, asJSON bool) error {
+	if opts.From == "" {
+		return errors.New("--from is required with --to")
+	}
+	report, err := core.MigrateHome(env, opts)
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		if err := encodeJSON(report); err != nil {
+			return err
+		}
+	} else {
+		for _, item := range report.AVDs {
+			line := fmt.Sprintf("%-8s %s", item.Status, item.Name)
+			if item.Error != "" {
+				line += "  (" + item.Error + ")"
+			} else if len(item.Rewritten) > 0 {
+				line += fmt.Sprintf("  (%d reference(s) rewritten)", len(item.Rewritten))
+			}
+			fmt.Println(line)
+			for _, problem := range item.Problems {
+				fmt.Printf("         problem: %s\n", problem)
+			}
+		}
+		if !opts.DryRun && len(report.AVDs) > 0 {
+			fmt.Printf("Set ANDROID_AVD_HOME=%s to use the new home\n", report.To)
+		}
+	}
+	if report.Failed() {
+		return errors.New("home migration finished with failures")
+	}
+	return nil
+}
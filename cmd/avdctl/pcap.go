@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+
+	core "github.com/forkbombeu/avdctl/internal/avd"
+	"github.com/spf13/cobra"
+)
+
+func newAndroidPcapCommand(env core.Env) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "pcap",
+		Short: "Capture the network traffic of a running emulator to a pcap file (start, stop)",
+	}
+	cmd.AddCommand(newAndroidPcapStartCommand(env), newAndroidPcapStopCommand(env))
+	return cmd
+}
+
+func newAndroidPcapStartCommand(env core.Env) *cobra.Command {
+	var name, serial, out string
+	cmd := &cobra.Command{
+		Use:   "start",
+		Short: "Start writing the guest's network traffic to a pcap file",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			resolved, err := resolveAndroidSerial(env, name, serial)
+			if err != nil {
+				return err
+			}
+			path, err := core.StartNetworkCapture(env, resolved, out)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Capturing to: %s\n", path)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&name, "name", "", "running AVD name")
+	cmd.Flags().StringVar(&serial, "serial", "", "emulator serial (e.g. emulator-5580)")
+	cmd.Flags().StringVar(&out, "out", ".", "pcap file, or directory for <serial>-<timestamp>.pcap")
+	return cmd
+}
+
+func newAndroidPcapStopCommand(env core.Env) *cobra.Command {
+	var name, serial string
+	cmd := &cobra.Command{
+		Use:   "stop",
+		Short: "Stop the network capture and print the pcap path",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			resolved, err := resolveAndroidSerial(env, name, serial)
+			if err != nil {
+				return err
+			}
+			path, err := core.StopNetworkCapture(env, resolved)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Capture saved: %s\n", path)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&name, "name", "", "running AVD name")
+	cmd.Flags().StringVar(&serial, "serial", "", "emulator serial (e.g. emulator-5580)")
+	return cmd
+}
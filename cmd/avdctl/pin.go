@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+
+	core "github.com/forkbombeu/avdctl/internal/avd"
+	"github.com/spf13/cobra"
+)
+
+func newPinCommand(env core.Env) *cobra.Command {
+	var reason string
+	cmd := &cobra.Command{
+		Use:   "pin NAME",
+		Short: "Protect an AVD from delete (until unpin or delete --force)",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := core.Pin(env, args[0], reason); err != nil {
+				return err
+			}
+			fmt.Printf("Pinned %s\n", args[0])
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&reason, "reason", "", "Why the AVD is kept, shown when a delete is refused")
+	return cmd
+}
+
+func newUnpinCommand(env core.Env) *cobra.Command {
+	return &cobra.Command{
+		Use:   "unpin NAME",
+		Short: "Remove the pin set by pin",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := core.Unpin(env, args[0]); err != nil {
+				return err
+			}
+			fmt.Printf("Unpinned %s\n", args[0])
+			return nil
+		},
+	}
+}
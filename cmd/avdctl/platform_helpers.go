@@ -2,24 +2,33 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
-	"os/exec"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"text/tabwriter"
+	"time"
 
 	core "github.com/forkbombeu/avdctl/internal/avd"
 	ioscore "github.com/forkbombeu/avdctl/internal/ios"
+	"github.com/spf13/cobra"
 )
 
 var (
-	androidDeleteFn      = core.Delete
+	androidDeleteFn      = core.DeleteWithOptions
+	androidDeleteTreeFn  = core.DeleteTree
 	androidListFn        = core.List
+	androidListWideFn    = core.ListWide
 	androidListRunningFn = core.ListRunning
-	androidRunAVDFn      = func(env core.Env, name string) (string, error) { return core.RunAVD(env, name) }
-	androidStartOnPortFn = func(env core.Env, name string, port int) (*exec.Cmd, string, string, error) {
-		return core.StartEmulatorOnPort(env, name, port)
+	androidRunAVDFn = func(env core.Env, name string, extra ...string) (core.StartResult, error) {
+		return core.RunAVD(env, name, extra...)
 	}
-	androidStopBySerialFn = core.StopBySerial
+	androidStartOnPortFn = func(env core.Env, name string, port int, extra ...string) (core.StartResult, error) {
+		return core.StartEmulatorOnPort(env, name, port, extra...)
+	}
+	androidStopBySerialFn = core.StopBySerialWithOptions
 
 	iosDeleteFn          = ioscore.Delete
 	iosEnsureSupportedFn = ioscore.EnsureSupported
@@ -56,6 +65,103 @@ func printAndroidList(infos []core.Info) {
 	}
 }
 
+// androidListFor returns the Android list function for `list` or `list --wide`, applying the
+// --filter/--sort query when one is given (queries need the wide fields).
+func androidListFor(wide bool, query queryFlags) func(core.Env) ([]core.Info, error) {
+	spec, err := query.spec()
+	if err != nil {
+		return func(core.Env) ([]core.Info, error) { return nil, err }
+	}
+	if len(spec.Filters) > 0 || spec.Sort != "" {
+		return func(env core.Env) ([]core.Info, error) {
+			infos, err := androidListWideFn(env)
+			if err != nil {
+				return nil, err
+			}
+			return core.QueryInfos(infos, spec)
+		}
+	}
+	if wide {
+		return androidListWideFn
+	}
+	return androidListFn
+}
+
+// androidPSFor returns the Android ps function, applying the --filter/--sort query.
+func androidPSFor(query queryFlags) func(core.Env) ([]core.ProcInfo, error) {
+	return func(env core.Env) ([]core.ProcInfo, error) {
+		spec, err := query.spec()
+		if err != nil {
+			return nil, err
+		}
+		procs, err := androidListRunningFn(env)
+		if err != nil || len(spec.Filters) == 0 && spec.Sort == "" {
+			return procs, err
+		}
+		return core.QueryProcs(env, procs, spec)
+	}
+}
+
+// queryFlags are the --filter and --sort flags of list and ps.
+type queryFlags struct {
+	filters []string
+	sort    string
+}
+
+func (q *queryFlags) register(cmd *cobra.Command, fields []string) {
+	cmd.Flags().StringArrayVar(&q.filters, "filter", nil, "keep entries matching field=value (repeatable; value may be prefix:X, suffix:X, contains:X, a glob or >N/<N); fields: "+strings.Join(fields, ", "))
+	cmd.Flags().StringVar(&q.sort, "sort", "", "sort by a field (e.g. name, size, uptime; prefix - to reverse)")
+}
+
+func (q queryFlags) spec() (core.QuerySpec, error) {
+	spec := core.QuerySpec{Sort: q.sort}
+	for _, f := range q.filters {
+		filter, err := core.ParseFilter(f)
+		if err != nil {
+			return core.QuerySpec{}, err
+		}
+		spec.Filters = append(spec.Filters, filter)
+	}
+	return spec, nil
+}
+
+func printAndroidListWide(infos []core.Info) {
+	if len(infos) == 0 {
+		fmt.Println("(no emulators)")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tKIND\tAPI\tABI\tDEVICE\tGOLDEN\tLAST BOOT\tSTATE")
+	for _, info := range infos {
+		api, golden, boot, state := "-", "-", "never", "stopped"
+		if info.APILevel > 0 {
+			api = strconv.Itoa(info.APILevel)
+		}
+		if info.Golden != "" {
+			golden = filepath.Base(info.Golden)
+		} else if info.Kind == core.KindClone {
+			golden = "(unknown)"
+		}
+		if info.LastBoot != nil {
+			boot = info.LastBoot.Local().Format("2006-01-02 15:04")
+		}
+		if info.Running {
+			state = "running " + info.Serial
+		} else if info.Quarantined {
+			state = "quarantined"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", info.Name, info.Kind, api, orDash(info.ABI), orDash(info.Device), golden, boot, state)
+	}
+	_ = w.Flush()
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
 func printIOSList(infos []ioscore.Info) {
 	if len(infos) == 0 {
 		fmt.Println("(no simulators)")
@@ -73,10 +179,26 @@ func printAndroidPS(procs []core.ProcInfo) {
 	}
 	for _, proc := range procs {
 		state := "booting"
-		if proc.Booted {
+		switch {
+		case proc.Paused:
+			state = "paused"
+		case proc.ADBState == core.ADBStateOffline || proc.ADBState == core.ADBStateUnauthorized:
+			state = "adb-" + proc.ADBState
+		case proc.Booted:
 			state = "ready"
 		}
-		fmt.Printf("%-18s %-14s port=%-5d pid=%-7d %s\n", proc.Name, proc.Serial, proc.Port, proc.PID, state)
+		line := fmt.Sprintf("%-18s %-14s port=%-5d pid=%-7d %s", proc.Name, proc.Serial, proc.Port, proc.PID, state)
+		if proc.Adopted {
+			line += " adopted"
+		}
+		for _, fwd := range proc.Forwards {
+			if fwd.Direction == "reverse" {
+				line += fmt.Sprintf(" reverse=%d->host:%d", fwd.DevicePort, fwd.HostPort)
+			} else {
+				line += fmt.Sprintf(" forward=host:%d->%d", fwd.HostPort, fwd.DevicePort)
+			}
+		}
+		fmt.Println(line)
 	}
 }
 
@@ -173,23 +295,102 @@ func printIOSStatus(env ioscore.Env, ref string) error {
 	return nil
 }
 
-func runAndroidWithOutput(env core.Env, name string, port int) error {
+func runAndroidWithOutput(env core.Env, name string, port int, sdk string, ephemeral bool, features []string, stall stallFlags) error {
 	if strings.TrimSpace(name) == "" {
 		return fmt.Errorf("--name is required")
 	}
-	if port > 0 {
-		if port%2 != 0 {
-			return fmt.Errorf("--port must be even")
-		}
-		_, _, logPath, err := androidStartOnPortFn(env, name, port)
-		if err != nil {
-			return err
+	env, err := env.WithSDK(sdk)
+	if err != nil {
+		return err
+	}
+	overrides, err := core.ParseFeatureOverrides(features)
+	if err != nil {
+		return err
+	}
+	extra := core.FeatureArgs(overrides)
+	if port > 0 && port%2 != 0 {
+		return fmt.Errorf("--port must be even")
+	}
+	start := func() (core.StartResult, error) {
+		switch {
+		case port > 0 && ephemeral:
+			return core.StartEphemeralOnPort(env, name, port, extra...)
+		case port > 0:
+			return androidStartOnPortFn(env, name, port, extra...)
+		case ephemeral:
+			return core.RunAVDEphemeral(env, name, extra...)
+		default:
+			return androidRunAVDFn(env, name, extra...)
 		}
-		fmt.Printf("Started %s on emulator-%d (log: %s)\n", name, port, logPath)
-		return nil
 	}
-	_, err := androidRunAVDFn(env, name)
-	return err
+	var res core.StartResult
+	if stall.retries > 0 {
+		res, err = core.StartWithStallRetry(env, stall.retries, stall.timeout, start)
+	} else {
+		res, err = start()
+	}
+	if err != nil {
+		return err
+	}
+	printStarted(res)
+	return nil
+}
+
+// isolationFlags are the --sandbox and --netns flags of the run commands; they override
+// Env.Sandbox and Env.CloneNetns.
+type isolationFlags struct {
+	tool       string
+	isolateNet bool
+	writable   []string
+	netns      bool
+	allow      []string
+	deny       []string
+}
+
+func (f *isolationFlags) register(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&f.tool, "sandbox", "", "confine the emulator with bwrap or nsjail (default $AVDCTL_SANDBOX)")
+	cmd.Flags().BoolVar(&f.isolateNet, "sandbox-isolate-net", false, "with --sandbox: own network namespace, only the console and adb ports forwarded (needs pasta)")
+	cmd.Flags().StringArrayVar(&f.writable, "sandbox-writable", nil, "with --sandbox: another host path the emulator may write (repeatable)")
+	cmd.Flags().BoolVar(&f.netns, "netns", false, "run in a per-clone network namespace with NATed egress (default $AVDCTL_NETNS, needs root)")
+	cmd.Flags().StringArrayVar(&f.allow, "netns-allow", nil, "only let the clone reach this address, CIDR or domain (repeatable, implies --netns)")
+	cmd.Flags().StringArrayVar(&f.deny, "netns-deny", nil, "keep the clone from reaching this address, CIDR or domain (repeatable, implies --netns)")
+}
+
+// stallFlags are the startup stall flags of the run commands (see core.StartWithStallRetry).
+type stallFlags struct {
+	retries int
+	timeout time.Duration
+}
+
+func (f *stallFlags) register(cmd *cobra.Command) {
+	cmd.Flags().IntVar(&f.retries, "startup-retries", 0, "kill and relaunch an emulator that stalls before adb sees it, up to this many times")
+	cmd.Flags().DurationVar(&f.timeout, "stall-timeout", core.DefaultStallTimeout, "with --startup-retries: how long the emulator may log nothing before it counts as stalled")
+}
+
+func (f *isolationFlags) set() bool {
+	return f.tool != "" || f.isolateNet || len(f.writable) > 0 || f.netns || len(f.allow) > 0 || len(f.deny) > 0
+}
+
+func (f *isolationFlags) apply(env core.Env) core.Env {
+	if f.tool != "" {
+		env.Sandbox = core.Sandbox{Tool: f.tool}
+	}
+	if f.isolateNet {
+		env.Sandbox.IsolateNetwork = true
+	}
+	env.Sandbox.Writable = append(env.Sandbox.Writable, f.writable...)
+	if f.netns || len(f.allow) > 0 || len(f.deny) > 0 {
+		env.CloneNetns = true
+	}
+	if len(f.allow) > 0 || len(f.deny) > 0 {
+		env.NetworkPolicy = core.NetworkPolicy{Allow: f.allow, Deny: f.deny}
+	}
+	return env
+}
+
+// printStarted prints the line the library's remote mode parses the start result from.
+func printStarted(res core.StartResult) {
+	fmt.Printf("Started %s on %s (log: %s) pid %d mode %s\n", res.Name, res.Serial, res.LogPath, res.PID, res.Mode)
 }
 
 func runIOSWithOutput(env ioscore.Env, ref string) error {
@@ -204,33 +405,38 @@ func runIOSWithOutput(env ioscore.Env, ref string) error {
 	return nil
 }
 
-func stopAndroidWithOutput(env core.Env, name, serial string) error {
-	if serial == "" && name == "" {
-		return fmt.Errorf("use --name or --serial")
-	}
-	resolvedSerial := serial
-	if resolvedSerial == "" {
-		procs, err := androidListRunningFn(env)
-		if err != nil {
-			return err
-		}
-		for _, proc := range procs {
-			if proc.Name == name {
-				resolvedSerial = proc.Serial
-				break
-			}
-		}
-		if resolvedSerial == "" {
-			return fmt.Errorf("no running emulator named %s", name)
-		}
+func stopAndroidWithOutput(env core.Env, name, serial string, opts core.StopOptions) error {
+	resolvedSerial, err := resolveAndroidSerial(env, name, serial)
+	if err != nil {
+		return err
 	}
-	if err := androidStopBySerialFn(env, resolvedSerial); err != nil {
+	if err := androidStopBySerialFn(env, resolvedSerial, opts); err != nil {
 		return err
 	}
 	fmt.Printf("Stopped %s\n", resolvedSerial)
 	return nil
 }
 
+// resolveAndroidSerial returns serial, or the serial of the running emulator named name.
+func resolveAndroidSerial(env core.Env, name, serial string) (string, error) {
+	if serial == "" && name == "" {
+		return "", fmt.Errorf("use --name or --serial")
+	}
+	if serial != "" {
+		return serial, nil
+	}
+	procs, err := androidListRunningFn(env)
+	if err != nil {
+		return "", err
+	}
+	for _, proc := range procs {
+		if proc.Name == name {
+			return proc.Serial, nil
+		}
+	}
+	return "", fmt.Errorf("no running emulator named %s", name)
+}
+
 func stopIOSWithOutput(env ioscore.Env, ref string) error {
 	if strings.TrimSpace(ref) == "" {
 		return fmt.Errorf("use --name or --udid")
@@ -242,8 +448,47 @@ func stopIOSWithOutput(env ioscore.Env, ref string) error {
 	return nil
 }
 
-func deleteAndroidWithOutput(env core.Env, name string) error {
-	return androidDeleteFn(env, name)
+// deleteFlags are the Android flags of delete.
+type deleteFlags struct {
+	force, noTrash, tree, dryRun, json bool
+}
+
+func (d *deleteFlags) register(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&d.force, "force", false, "Android: delete even if running (stops it), linked to by clones, or pinned")
+	cmd.Flags().BoolVar(&d.noTrash, "no-trash", false, "Android: remove at once instead of moving to the trash (no restore)")
+	cmd.Flags().BoolVar(&d.tree, "tree", false, "Android: delete a base with its clones and the registry goldens exported from it")
+	cmd.Flags().BoolVar(&d.dryRun, "dry-run", false, "with --tree: only print what would be deleted")
+	cmd.Flags().BoolVar(&d.json, "json", false, "with --tree: output JSON")
+}
+
+func deleteAndroidWithOutput(env core.Env, name string, flags deleteFlags) error {
+	if !flags.tree {
+		if flags.dryRun {
+			return errors.New("--dry-run needs --tree")
+		}
+		return androidDeleteFn(env, name, core.DeleteOptions{Force: flags.force, SkipTrash: flags.noTrash})
+	}
+	report, err := androidDeleteTreeFn(env, name, core.DeleteTreeOptions{DryRun: flags.dryRun, Force: flags.force, SkipTrash: flags.noTrash})
+	if err != nil {
+		return err
+	}
+	if flags.json {
+		if err := encodeJSON(report); err != nil {
+			return err
+		}
+	} else {
+		for _, item := range report.Items {
+			line := fmt.Sprintf("%-7s %-24s %s", item.Kind, item.Name, item.Status)
+			if item.Detail != "" {
+				line += "  " + item.Detail
+			}
+			fmt.Println(line)
+		}
+	}
+	if report.Failed() {
+		return fmt.Errorf("delete of %s tree finished with failures", name)
+	}
+	return nil
 }
 
 func deleteIOSWithOutput(env ioscore.Env, ref string) error {
@@ -254,6 +499,21 @@ func deleteIOSWithOutput(env ioscore.Env, ref string) error {
 	return nil
 }
 
+func parseKeyValueFlags(pairs []string) (map[string]string, error) {
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+	out := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("invalid %q: expected KEY=VALUE", pair)
+		}
+		out[strings.TrimSpace(key)] = value
+	}
+	return out, nil
+}
+
 func iosListIfSupported(env ioscore.Env) ([]ioscore.Info, error) {
 	if err := iosEnsureSupportedFn(); err != nil {
 		return nil, nil
@@ -315,9 +575,30 @@ func resolveTargetPlatform(androidFound bool, iosFound bool, ref string, android
 	return "", fmt.Errorf("device %q not found on android or ios", ref)
 }
 
+// addStopModeFlags registers --mode/--timeout/--outcome and returns a func that resolves them to StopOptions.
+func addStopModeFlags(cmd *cobra.Command) func() (core.StopOptions, error) {
+	var mode, outcome string
+	var timeout time.Duration
+	cmd.Flags().StringVar(&mode, "mode", string(core.StopConsoleKill), "shutdown mode: console-kill, guest-shutdown (adb shell reboot -p), or force")
+	cmd.Flags().DurationVar(&timeout, "timeout", 60*time.Second, "guest-shutdown wait before falling back to console-kill")
+	cmd.Flags().StringVar(&outcome, "outcome", core.RunStopped, "why the run ends, recorded in its history (stopped, lease-expired, ...)")
+	return func() (core.StopOptions, error) {
+		parsed, err := core.ParseStopMode(mode)
+		if err != nil {
+			return core.StopOptions{}, err
+		}
+		if _, err := core.ParseRunOutcome(outcome); err != nil {
+			return core.StopOptions{}, err
+		}
+		return core.StopOptions{Mode: parsed, Timeout: timeout, Outcome: outcome}, nil
+	}
+}
+
 func restorePlatformHelperStubs() func() {
 	prevAndroidDelete := androidDeleteFn
+	prevAndroidDeleteTree := androidDeleteTreeFn
 	prevAndroidList := androidListFn
+	prevAndroidListWide := androidListWideFn
 	prevAndroidListRunning := androidListRunningFn
 	prevAndroidRunAVD := androidRunAVDFn
 	prevAndroidStartOnPort := androidStartOnPortFn
@@ -332,7 +613,9 @@ func restorePlatformHelperStubs() func() {
 
 	return func() {
 		androidDeleteFn = prevAndroidDelete
+		androidDeleteTreeFn = prevAndroidDeleteTree
 		androidListFn = prevAndroidList
+		androidListWideFn = prevAndroidListWide
 		androidListRunningFn = prevAndroidListRunning
 		androidRunAVDFn = prevAndroidRunAVD
 		androidStartOnPortFn = prevAndroidStartOnPort
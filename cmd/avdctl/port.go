@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	core "github.com/forkbombeu/avdctl/internal/avd"
+	"github.com/spf13/cobra"
+)
+
+func newAndroidPortCommand(env core.Env) *cobra.Command {
+	var name string
+	var port int
+	var unset, asJSON bool
+	cmd := &cobra.Command{
+		Use:   "port",
+		Short: "Show or set the sticky console ports that keep each AVD on the same serial",
+		Long: `Every AVD started without --port gets the console port recorded for it, so w-acme
+is emulator-5580 on every start. The first start records a free port; a busy recorded
+port is replaced by a new one. --set assigns a port, --clear drops the assignment.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if port != 0 || unset {
+				if name == "" {
+					return errors.New("--name is required with --set or --clear")
+				}
+				if err := core.SetStickyPort(env, name, port); err != nil {
+					return err
+				}
+				if unset {
+					fmt.Printf("Cleared port of %s\n", name)
+				} else {
+					fmt.Printf("%s uses emulator-%d\n", name, port)
+				}
+				return nil
+			}
+			ports := core.StickyPorts(env)
+			if name != "" {
+				ports = map[string]int{name: ports[name]}
+			}
+			if asJSON {
+				return encodeJSON(ports)
+			}
+			names := make([]string, 0, len(ports))
+			for n := range ports {
+				names = append(names, n)
+			}
+			sort.Strings(names)
+			for _, n := range names {
+				if ports[n] == 0 {
+					fmt.Printf("%-24s -\n", n)
+					continue
+				}
+				fmt.Printf("%-24s emulator-%d\n", n, ports[n])
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&name, "name", "", "AVD name")
+	cmd.Flags().IntVar(&port, "set", 0, "assign this even console port")
+	cmd.Flags().BoolVar(&unset, "clear", false, "drop the port assignment")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "output JSON")
+	cmd.MarkFlagsMutuallyExclusive("set", "clear")
+	return cmd
+}
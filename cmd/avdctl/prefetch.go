@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	core "github.com/forkbombeu/avdctl/internal/avd"
+	"github.com/spf13/cobra"
+)
+
+func newAndroidPrefetchCommand(env core.Env) *cobra.Command {
+	var file string
+	var images, goldens []string
+	var check, prefetchJSON bool
+	cmd := &cobra.Command{
+		Use:   "prefetch",
+		Short: "Install system images and copy goldens locally ahead of offline (AVDCTL_OFFLINE) runs",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			req := core.ArtifactRequirements{SystemImages: images, Goldens: goldens}
+			if file != "" {
+				fleet, err := core.LoadFleet(file)
+				if err != nil {
+					return err
+				}
+				fromFleet := core.FleetRequirements(fleet)
+				req.SystemImages = append(req.SystemImages, fromFleet.SystemImages...)
+				req.Goldens = append(req.Goldens, fromFleet.Goldens...)
+			}
+			if len(req.SystemImages) == 0 && len(req.Goldens) == 0 {
+				return errors.New("nothing to prefetch: use -f, --image or --golden")
+			}
+
+			if check {
+				missing := core.CheckArtifacts(env, req)
+				if prefetchJSON {
+					if err := encodeJSON(&core.MissingArtifactsError{Missing: missing}); err != nil {
+						return err
+					}
+				}
+				if len(missing) > 0 {
+					return &core.MissingArtifactsError{Missing: missing}
+				}
+				if !prefetchJSON {
+					fmt.Println("All artifacts available locally")
+				}
+				return nil
+			}
+
+			report, err := core.Prefetch(env, req)
+			if err != nil {
+				return err
+			}
+			if prefetchJSON {
+				if err := encodeJSON(report); err != nil {
+					return err
+				}
+			} else {
+				for _, a := range report.Artifacts {
+					line := fmt.Sprintf("%-12s %-10s %s", a.Kind, a.Status, a.Ref)
+					if a.Path != "" && a.Path != a.Ref {
+						line += " -> " + a.Path
+					}
+					if a.Reason != "" {
+						line += "  " + a.Reason
+					}
+					fmt.Println(line)
+				}
+			}
+			if report.Failed() {
+				return errors.New("prefetch finished with failures")
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&file, "file", "f", "", "fleet file whose base images and goldens to prefetch")
+	cmd.Flags().StringArrayVar(&images, "image", nil, "system image package to install (repeatable)")
+	cmd.Flags().StringArrayVar(&goldens, "golden", nil, "golden directory to copy into AVDCTL_GOLDEN_DIR (repeatable)")
+	cmd.Flags().BoolVar(&check, "check", false, "only report missing artifacts (no network); exits non-zero if any")
+	cmd.Flags().BoolVar(&prefetchJSON, "json", false, "output JSON")
+	return cmd
+}
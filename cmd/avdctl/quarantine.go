@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	core "github.com/forkbombeu/avdctl/internal/avd"
+	"github.com/spf13/cobra"
+)
+
+func newAndroidQuarantineCommand(env core.Env) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "quarantine",
+		Short: "List, quarantine or release clones that starts refuse",
+		Long: `A quarantined clone is not started, by run or by a fleet coordinator, and its run history
+and files are kept whatever AVDCTL_RETENTION says.
+
+With AVDCTL_QUARANTINE_AFTER=N a clone is quarantined on its own when N starts in a row time
+out booting while a sibling clone of the same golden boots: its overlay, not the golden, is
+at fault. With AVDCTL_QUARANTINE_REMATERIALIZE=1 the next start of a quarantined clone resets
+it from its golden and releases it instead of failing.`,
+	}
+
+	var listJSON bool
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List the quarantined AVDs",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			recs, err := core.ListQuarantined(env)
+			if err != nil {
+				return err
+			}
+			if listJSON {
+				return encodeJSON(recs)
+			}
+			printQuarantined(recs)
+			return nil
+		},
+	}
+	list.Flags().BoolVar(&listJSON, "json", false, "output JSON")
+
+	var reason string
+	add := &cobra.Command{
+		Use:   "add NAME",
+		Short: "Quarantine an AVD until release",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := core.Quarantine(env, args[0], reason); err != nil {
+				return err
+			}
+			fmt.Printf("Quarantined %s\n", args[0])
+			return nil
+		},
+	}
+	add.Flags().StringVar(&reason, "reason", "", "why the AVD is quarantined")
+
+	var rematerialize bool
+	release := &cobra.Command{
+		Use:   "release NAME",
+		Short: "Let a quarantined AVD be started again",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := core.ReleaseQuarantine(env, args[0], rematerialize); err != nil {
+				return err
+			}
+			if rematerialize {
+				fmt.Printf("Reset %s from its golden and released it\n", args[0])
+			} else {
+				fmt.Printf("Released %s\n", args[0])
+			}
+			return nil
+		},
+	}
+	release.Flags().BoolVar(&rematerialize, "rematerialize", false, "reset the clone from its golden first (it must be stopped)")
+
+	cmd.AddCommand(list, add, release)
+	return cmd
+}
+
+func printQuarantined(recs []core.QuarantineRecord) {
+	if len(recs) == 0 {
+		fmt.Println("(no quarantined AVDs)")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSINCE\tREASON")
+	for _, rec := range recs {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", rec.Name, rec.Since.Local().Format("2006-01-02 15:04"), orDash(rec.Reason))
+	}
+	_ = w.Flush()
+}
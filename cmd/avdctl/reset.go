@@ -0,0 +1,41 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	core "github.com/forkbombeu/avdctl/internal/avd"
+	"github.com/spf13/cobra"
+)
+
+func newAndroidResetCommand(env core.Env) *cobra.Command {
+	var name, golden string
+	var asJSON bool
+	cmd := &cobra.Command{
+		Use:   "reset --name NAME --golden DIR|NAME|@CHANNEL",
+		Short: "Put a stopped clone back to a golden, skipping images that already match it",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if name == "" || golden == "" {
+				return errors.New("--name and --golden are required")
+			}
+			result, err := core.ResetClone(env, name, golden)
+			if err != nil {
+				return err
+			}
+			if asJSON {
+				return encodeJSON(result)
+			}
+			skipped := "none"
+			if len(result.Skipped) > 0 {
+				skipped = strings.Join(result.Skipped, ", ")
+			}
+			fmt.Printf("Clone reset: %s (copied %d image(s), unchanged: %s)\n", result.Name, len(result.Copied), skipped)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&name, "name", "", "clone to reset")
+	cmd.Flags().StringVar(&golden, "golden", "", "golden directory, name in AVDCTL_GOLDEN_DIR, or @<channel>")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "output JSON")
+	return cmd
+}
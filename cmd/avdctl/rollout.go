@@ -0,0 +1,51 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	core "github.com/forkbombeu/avdctl/internal/avd"
+	"github.com/spf13/cobra"
+)
+
+func newAndroidRolloutCommand(env core.Env) *cobra.Command {
+	var opts core.RolloutOptions
+	var asJSON bool
+	cmd := &cobra.Command{
+		Use:   "rollout --golden DIR|NAME|@CHANNEL [--from GOLDEN | --clone NAME...]",
+		Short: "Replace clones with clones of a new golden in health-checked batches, restoring a failed batch",
+		Example: `  avdctl rollout --golden v5 --batch 5
+  avdctl rollout --golden v5 --from v4 --batch 5
+  avdctl rollout --golden @production --clone w-acme --clone w-globex`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if opts.Golden == "" {
+				return errors.New("--golden is required")
+			}
+			report, err := core.Rollout(env, opts)
+			if err != nil {
+				return err
+			}
+			if asJSON {
+				if err := encodeJSON(report); err != nil {
+					return err
+				}
+			} else {
+				printFleetActions(report.Actions)
+				fmt.Printf("%d clone(s) now on %s\n", len(report.Updated), report.Golden)
+			}
+			if report.Aborted {
+				return errors.New("rollout aborted: a batch failed and was restored; later batches were not started")
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&opts.Golden, "golden", "", "new golden: directory, name in AVDCTL_GOLDEN_DIR, or @<channel>")
+	cmd.Flags().StringVar(&opts.Base, "base", "", "base AVD of the clones (default: the golden's source AVD)")
+	cmd.Flags().StringVar(&opts.From, "from", "", "replace every clone made from this golden (default: every clone of --base)")
+	cmd.Flags().StringArrayVar(&opts.Clones, "clone", nil, "clone to replace (repeatable)")
+	cmd.Flags().IntVar(&opts.BatchSize, "batch", core.DefaultRolloutBatch, "clones replaced and health-checked together")
+	cmd.Flags().DurationVar(&opts.BootTimeout, "boot-timeout", 3*time.Minute, "boot timeout of each new clone")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "output JSON")
+	return cmd
+}
@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	core "github.com/forkbombeu/avdctl/internal/avd"
@@ -44,8 +45,17 @@ Shared platform-aware commands:
   list, init-base, run, clone, delete, ps, status, stop
 
 Android-only commands:
-	save-golden, prewarm, customize-start, customize-finish, bake-apk,
-  stop-bluetooth, cleanup
+	save-golden, prewarm, customize-start, customize-finish, customize, bake-apk,
+  stop-bluetooth, cleanup, storage, emulator-version, up, down, diff, prefetch,
+  identity, integrity, forward, reverse, migrate, capture, trace, console,
+  sensor, rotate, posture, display, accessibility, doze, standby-bucket, channel,
+  rollout, reset, slug, pin, unpin, restore, trash, hwconfig, guest-storage,
+  resize-userdata, chaos, pause, resume, hibernate, wake, wait, adopt, health, port,
+  inspect, features, pcap, frida, install, device-spec, wait-app, host-resume,
+  logs, capacity, usage, history, stats, quarantine
+
+Multi-host commands:
+  fleet
 `,
 		Example: `  avdctl list
   avdctl run --name base-a35
@@ -82,9 +92,66 @@ Android-only commands:
 	root.AddCommand(newAndroidPrewarmCommand(androidEnv))
 	root.AddCommand(newAndroidCustomizeStartCommand(androidEnv))
 	root.AddCommand(newAndroidCustomizeFinishCommand(androidEnv))
+	root.AddCommand(newAndroidCustomizeCommand(androidEnv))
 	root.AddCommand(newAndroidBakeCommand(androidEnv))
 	root.AddCommand(newAndroidStopBluetoothCommand(androidEnv))
 	root.AddCommand(newAndroidCleanupCommand(androidEnv))
+	root.AddCommand(newAndroidStorageCommand(androidEnv))
+	root.AddCommand(newAndroidEmulatorVersionCommand(androidEnv))
+	root.AddCommand(newAndroidUpCommand(androidEnv))
+	root.AddCommand(newAndroidDownCommand(androidEnv))
+	root.AddCommand(newAndroidDiffCommand(androidEnv))
+	root.AddCommand(newAndroidPrefetchCommand(androidEnv))
+	root.AddCommand(newAndroidIdentityCommand(androidEnv))
+	root.AddCommand(newAndroidIntegrityCommand(androidEnv))
+	root.AddCommand(newAndroidForwardCommand(androidEnv))
+	root.AddCommand(newAndroidReverseCommand(androidEnv))
+	root.AddCommand(newAndroidMigrateCommand(androidEnv))
+	root.AddCommand(newAndroidCaptureCommand(androidEnv))
+	root.AddCommand(newAndroidTraceCommand(androidEnv))
+	root.AddCommand(newAndroidConsoleCommand(androidEnv))
+	root.AddCommand(newAndroidSensorCommand(androidEnv))
+	root.AddCommand(newAndroidRotateCommand(androidEnv))
+	root.AddCommand(newAndroidPostureCommand(androidEnv))
+	root.AddCommand(newAndroidDisplayCommand(androidEnv))
+	root.AddCommand(newAndroidAccessibilityCommand(androidEnv))
+	root.AddCommand(newAndroidDozeCommand(androidEnv))
+	root.AddCommand(newAndroidStandbyBucketCommand(androidEnv))
+	root.AddCommand(newAndroidChannelCommand(androidEnv))
+	root.AddCommand(newAndroidRolloutCommand(androidEnv))
+	root.AddCommand(newAndroidResetCommand(androidEnv))
+	root.AddCommand(newSlugCommand(androidEnv))
+	root.AddCommand(newPinCommand(androidEnv))
+	root.AddCommand(newUnpinCommand(androidEnv))
+	root.AddCommand(newRestoreCommand(androidEnv))
+	root.AddCommand(newTrashCommand(androidEnv))
+	root.AddCommand(newHWConfigCommand(androidEnv))
+	root.AddCommand(newAndroidGuestStorageCommand(androidEnv))
+	root.AddCommand(newAndroidResizeUserdataCommand(androidEnv))
+	root.AddCommand(newAndroidChaosCommand(androidEnv))
+	root.AddCommand(newAndroidPauseCommand(androidEnv))
+	root.AddCommand(newAndroidResumeCommand(androidEnv))
+	root.AddCommand(newAndroidHibernateCommand(androidEnv))
+	root.AddCommand(newAndroidWakeCommand(androidEnv))
+	root.AddCommand(newAndroidWaitCommand(androidEnv))
+	root.AddCommand(newAndroidAdoptCommand(androidEnv))
+	root.AddCommand(newAndroidHealthCommand(androidEnv))
+	root.AddCommand(newAndroidPortCommand(androidEnv))
+	root.AddCommand(newAndroidInspectCommand(androidEnv))
+	root.AddCommand(newAndroidFeaturesCommand())
+	root.AddCommand(newAndroidPcapCommand(androidEnv))
+	root.AddCommand(newAndroidFridaCommand(androidEnv))
+	root.AddCommand(newAndroidInstallCommand(androidEnv))
+	root.AddCommand(newAndroidDeviceSpecCommand(androidEnv))
+	root.AddCommand(newAndroidWaitAppCommand(androidEnv))
+	root.AddCommand(newAndroidHostResumeCommand(androidEnv))
+	root.AddCommand(newAndroidLogsCommand(androidEnv))
+	root.AddCommand(newAndroidCapacityCommand(androidEnv))
+	root.AddCommand(newAndroidUsageCommand(androidEnv))
+	root.AddCommand(newAndroidHistoryCommand(androidEnv))
+	root.AddCommand(newAndroidStatsCommand(androidEnv))
+	root.AddCommand(newAndroidQuarantineCommand(androidEnv))
+	root.AddCommand(newFleetCommand())
 	return root
 }
 
@@ -105,12 +172,13 @@ func newVersionCommand(root *cobra.Command, version string) *cobra.Command {
 }
 
 func newPlatformListCommand(androidEnv core.Env, iosEnv ioscore.Env) *cobra.Command {
-	var listJSON bool
+	var listJSON, wide bool
+	var query queryFlags
 	cmd := &cobra.Command{
 		Use:   "list",
 		Short: "List Android and iOS devices, or use `list android|ios`",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			androidInfos, err := androidListFn(androidEnv)
+			androidInfos, err := androidListFor(wide, query)(androidEnv)
 			if err != nil {
 				return err
 			}
@@ -125,7 +193,11 @@ func newPlatformListCommand(androidEnv core.Env, iosEnv ioscore.Env) *cobra.Comm
 				})
 			}
 			fmt.Println("Android")
-			printAndroidList(androidInfos)
+			if wide {
+				printAndroidListWide(androidInfos)
+			} else {
+				printAndroidList(androidInfos)
+			}
 			if iosInfos != nil {
 				fmt.Println()
 				fmt.Println("iOS")
@@ -135,6 +207,8 @@ func newPlatformListCommand(androidEnv core.Env, iosEnv ioscore.Env) *cobra.Comm
 		},
 	}
 	cmd.Flags().BoolVar(&listJSON, "json", false, "output JSON")
+	cmd.Flags().BoolVar(&wide, "wide", false, "add API level, ABI, device, kind, golden, last boot and state of Android AVDs")
+	query.register(cmd, core.InfoQueryFields)
 	cmd.AddCommand(newAndroidListCommand("android", androidEnv))
 	cmd.AddCommand(newIOSListCommand("ios", iosEnv))
 	return cmd
@@ -149,8 +223,12 @@ func newPlatformInitBaseCommand(androidEnv core.Env, iosEnv ioscore.Env) *cobra.
 }
 
 func newPlatformRunCommand(androidEnv core.Env, iosEnv ioscore.Env, redroidEnv redroidcore.Env) *cobra.Command {
-	var name string
+	var name, sdk string
 	var port int
+	var ephemeral, noBluetooth, shared bool
+	var features []string
+	var sandbox isolationFlags
+	var stall stallFlags
 	cmd := &cobra.Command{
 		Use:   "run",
 		Short: "Start a device; auto-detect android/ios by name, or use `run android|ios|redroid`",
@@ -165,16 +243,28 @@ func newPlatformRunCommand(androidEnv core.Env, iosEnv ioscore.Env, redroidEnv r
 				return err
 			}
 			if platform == "ios" {
-				if port != 0 {
-					return errors.New("--port is only supported for Android emulators")
+				if port != 0 || ephemeral || shared || len(features) > 0 || noBluetooth || sandbox.set() || stall.retries > 0 {
+					return errors.New("--port, --ephemeral, --shared, --feature, --no-bluetooth-emulation, --startup-retries, --sandbox and --netns are only supported for Android emulators")
 				}
 				return runIOSWithOutput(iosEnv, name)
 			}
-			return runAndroidWithOutput(androidEnv, name, port)
+			if noBluetooth {
+				features = append(features, core.FeatureBluetoothEmulation+"=off")
+			}
+			runEnv := sandbox.apply(androidEnv)
+			runEnv.SharedAVD = shared
+			return runAndroidWithOutput(runEnv, name, port, sdk, ephemeral, features, stall)
 		},
 	}
 	cmd.Flags().StringVar(&name, "name", "", "Device name or iOS simulator UDID")
 	cmd.Flags().IntVar(&port, "port", 0, "even TCP port to bind Android emulator (auto if omitted)")
+	cmd.Flags().StringVar(&sdk, "sdk", "", "named Android SDK from AVDCTL_SDKS to run the emulator from")
+	cmd.Flags().BoolVar(&ephemeral, "ephemeral", false, "run Android clones from tmpfs copies of their images, discarded at stop")
+	cmd.Flags().StringArrayVar(&features, "feature", nil, "override an emulator feature for this run: NAME=on|off (repeatable, see `avdctl features`)")
+	cmd.Flags().BoolVar(&noBluetooth, "no-bluetooth-emulation", false, "never start the emulated Bluetooth stack (turns the BluetoothEmulation feature off)")
+	cmd.Flags().BoolVar(&shared, "shared", false, "start an Android AVD even if another instance of it is running, read-only")
+	stall.register(cmd)
+	sandbox.register(cmd)
 	cmd.AddCommand(newAndroidRunCommand("android", androidEnv))
 	cmd.AddCommand(newIOSRunCommand("ios", iosEnv))
 	cmd.AddCommand(newRedroidRunCommand("redroid", redroidEnv))
@@ -182,6 +272,7 @@ func newPlatformRunCommand(androidEnv core.Env, iosEnv ioscore.Env, redroidEnv r
 }
 
 func newPlatformDeleteCommand(androidEnv core.Env, iosEnv ioscore.Env, redroidEnv redroidcore.Env) *cobra.Command {
+	var flags deleteFlags
 	cmd := &cobra.Command{
 		Use:   "delete NAME_OR_UDID",
 		Short: "Delete a device; auto-detect android/ios by ref, or use `delete android|ios|redroid`",
@@ -197,9 +288,10 @@ func newPlatformDeleteCommand(androidEnv core.Env, iosEnv ioscore.Env, redroidEn
 			if platform == "ios" {
 				return deleteIOSWithOutput(iosEnv, ref)
 			}
-			return deleteAndroidWithOutput(androidEnv, ref)
+			return deleteAndroidWithOutput(androidEnv, ref, flags)
 		},
 	}
+	flags.register(cmd)
 	cmd.AddCommand(newAndroidDeleteCommand("android", androidEnv))
 	cmd.AddCommand(newIOSDeleteCommand("ios", iosEnv))
 	cmd.AddCommand(newRedroidDeleteCommand("redroid", redroidEnv))
@@ -216,11 +308,12 @@ func newPlatformCloneCommand(androidEnv core.Env, iosEnv ioscore.Env) *cobra.Com
 
 func newPlatformPSCommand(androidEnv core.Env, iosEnv ioscore.Env) *cobra.Command {
 	var psJSON bool
+	var query queryFlags
 	cmd := &cobra.Command{
 		Use:   "ps",
 		Short: "List running Android and iOS devices, or use `ps android|ios`",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			androidProcs, err := androidListRunningFn(androidEnv)
+			androidProcs, err := androidPSFor(query)(androidEnv)
 			if err != nil {
 				return err
 			}
@@ -245,6 +338,7 @@ func newPlatformPSCommand(androidEnv core.Env, iosEnv ioscore.Env) *cobra.Comman
 		},
 	}
 	cmd.Flags().BoolVar(&psJSON, "json", false, "output JSON")
+	query.register(cmd, core.ProcQueryFields)
 	cmd.AddCommand(newAndroidPSCommand("android", androidEnv))
 	cmd.AddCommand(newIOSPSCommand("ios", iosEnv))
 	return cmd
@@ -305,12 +399,17 @@ func newPlatformStatusCommand(androidEnv core.Env, iosEnv ioscore.Env) *cobra.Co
 
 func newPlatformStopCommand(androidEnv core.Env, iosEnv ioscore.Env, redroidEnv redroidcore.Env) *cobra.Command {
 	var name, serial, udid string
+	var stopOptions func() (core.StopOptions, error)
 	cmd := &cobra.Command{
 		Use:   "stop",
 		Short: "Stop a device; auto-detect android/ios by ref, or use `stop android|ios|redroid`",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			stopOpts, err := stopOptions()
+			if err != nil {
+				return err
+			}
 			if strings.TrimSpace(serial) != "" {
-				return stopAndroidWithOutput(androidEnv, "", serial)
+				return stopAndroidWithOutput(androidEnv, "", serial, stopOpts)
 			}
 			if strings.TrimSpace(udid) != "" {
 				return stopIOSWithOutput(iosEnv, udid)
@@ -327,9 +426,10 @@ func newPlatformStopCommand(androidEnv core.Env, iosEnv ioscore.Env, redroidEnv
 			if platform == "ios" {
 				return stopIOSWithOutput(iosEnv, name)
 			}
-			return stopAndroidWithOutput(androidEnv, name, "")
+			return stopAndroidWithOutput(androidEnv, name, "", stopOpts)
 		},
 	}
+	stopOptions = addStopModeFlags(cmd)
 	cmd.Flags().StringVar(&name, "name", "", "Device name")
 	cmd.Flags().StringVar(&serial, "serial", "", "Android emulator serial (e.g., emulator-5582)")
 	cmd.Flags().StringVar(&udid, "udid", "", "iOS simulator UDID")
@@ -340,23 +440,30 @@ func newPlatformStopCommand(androidEnv core.Env, iosEnv ioscore.Env, redroidEnv
 }
 
 func newAndroidListCommand(use string, env core.Env) *cobra.Command {
-	var listJSON bool
+	var listJSON, wide bool
+	var query queryFlags
 	cmd := &cobra.Command{
 		Use:   use,
 		Short: "List Android AVDs under ANDROID_AVD_HOME",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			ls, err := androidListFn(env)
+			ls, err := androidListFor(wide, query)(env)
 			if err != nil {
 				return err
 			}
 			if listJSON {
 				return encodeJSON(ls)
 			}
-			printAndroidList(ls)
+			if wide {
+				printAndroidListWide(ls)
+			} else {
+				printAndroidList(ls)
+			}
 			return nil
 		},
 	}
 	cmd.Flags().BoolVar(&listJSON, "json", false, "output JSON")
+	cmd.Flags().BoolVar(&wide, "wide", false, "add API level, ABI, device, kind, golden, last boot and state")
+	query.register(cmd, core.InfoQueryFields)
 	return cmd
 }
 
@@ -382,7 +489,7 @@ func newIOSListCommand(use string, env ioscore.Env) *cobra.Command {
 }
 
 func newAndroidInitBaseCommand(use string, env core.Env) *cobra.Command {
-	var baseName, sysImg, device string
+	var baseName, sysImg, device, proxy string
 	cmd := &cobra.Command{
 		Use:   use,
 		Short: "Create a base Android AVD (auto-installs system image if missing)",
@@ -390,7 +497,17 @@ func newAndroidInitBaseCommand(use string, env core.Env) *cobra.Command {
 			if baseName == "" {
 				return errors.New("--name is required")
 			}
-			inf, err := core.InitBase(env, baseName, sysImg, device)
+			env, err := env.WithProxy(proxy)
+			if err != nil {
+				return err
+			}
+			lastStep := -1
+			inf, err := core.InitBaseWithProgress(env, baseName, sysImg, device, func(pkg string, percent int) {
+				if step := percent / 10; step > lastStep {
+					lastStep = step
+					fmt.Fprintf(os.Stderr, "Installing %s: %d%%\n", pkg, percent)
+				}
+			})
 			if err != nil {
 				return err
 			}
@@ -401,6 +518,7 @@ func newAndroidInitBaseCommand(use string, env core.Env) *cobra.Command {
 	cmd.Flags().StringVar(&baseName, "name", "base-a35", "AVD name (include API, e.g. base-a35)")
 	cmd.Flags().StringVar(&sysImg, "image", "system-images;android-35;google_apis_playstore;x86_64", "System image ID")
 	cmd.Flags().StringVar(&device, "device", "pixel_6", "Device profile")
+	cmd.Flags().StringVar(&proxy, "proxy", "", "download proxy for sdkmanager (overrides AVDCTL_PROXY/HTTPS_PROXY; \"none\" disables)")
 	return cmd
 }
 
@@ -428,17 +546,33 @@ func newIOSInitBaseCommand(use string, env ioscore.Env) *cobra.Command {
 }
 
 func newAndroidRunCommand(use string, env core.Env) *cobra.Command {
-	var runName string
+	var runName, runSDK string
 	var runPort int
+	var runEphemeral, runNoBluetooth, runShared bool
+	var runFeatures []string
+	var runSandbox isolationFlags
+	var runStall stallFlags
 	cmd := &cobra.Command{
 		Use:   use,
 		Short: "Run an Android AVD headless (no snapshots); supports parallel instances",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runAndroidWithOutput(env, runName, runPort)
+			if runNoBluetooth {
+				runFeatures = append(runFeatures, core.FeatureBluetoothEmulation+"=off")
+			}
+			runEnv := runSandbox.apply(env)
+			runEnv.SharedAVD = runShared
+			return runAndroidWithOutput(runEnv, runName, runPort, runSDK, runEphemeral, runFeatures, runStall)
 		},
 	}
 	cmd.Flags().StringVar(&runName, "name", "", "AVD name to run")
 	cmd.Flags().IntVar(&runPort, "port", 0, "even TCP port to bind emulator (auto if omitted)")
+	cmd.Flags().StringVar(&runSDK, "sdk", "", "named Android SDK from AVDCTL_SDKS to run the emulator from")
+	cmd.Flags().BoolVar(&runEphemeral, "ephemeral", false, "run from tmpfs copies of the writable images, discarded at stop")
+	cmd.Flags().StringArrayVar(&runFeatures, "feature", nil, "override an emulator feature for this run: NAME=on|off (repeatable)")
+	cmd.Flags().BoolVar(&runNoBluetooth, "no-bluetooth-emulation", false, "never start the emulated Bluetooth stack")
+	cmd.Flags().BoolVar(&runShared, "shared", false, "start even if another instance of the AVD is running, read-only (changes of one instance can corrupt the other)")
+	runStall.register(cmd)
+	runSandbox.register(cmd)
 	return cmd
 }
 
@@ -478,14 +612,17 @@ func newIOSCloneCommand(use string, env ioscore.Env) *cobra.Command {
 }
 
 func newAndroidDeleteCommand(use string, env core.Env) *cobra.Command {
-	return &cobra.Command{
+	var flags deleteFlags
+	cmd := &cobra.Command{
 		Use:   use + " NAME",
-		Short: "Delete an Android AVD (+ .ini)",
+		Short: "Delete an Android AVD (+ .ini); refuses running, linked-to or pinned AVDs without --force",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return core.Delete(env, args[0])
+			return deleteAndroidWithOutput(env, args[0], flags)
 		},
 	}
+	flags.register(cmd)
+	return cmd
 }
 
 func newIOSDeleteCommand(use string, env ioscore.Env) *cobra.Command {
@@ -501,11 +638,12 @@ func newIOSDeleteCommand(use string, env ioscore.Env) *cobra.Command {
 
 func newAndroidPSCommand(use string, env core.Env) *cobra.Command {
 	var psJSON bool
+	var query queryFlags
 	cmd := &cobra.Command{
 		Use:   use,
 		Short: "List running Android emulators with AVD name, serial, port, PID",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			procs, err := androidListRunningFn(env)
+			procs, err := androidPSFor(query)(env)
 			if err != nil {
 				return err
 			}
@@ -517,6 +655,7 @@ func newAndroidPSCommand(use string, env core.Env) *cobra.Command {
 		},
 	}
 	cmd.Flags().BoolVar(&psJSON, "json", false, "output JSON")
+	query.register(cmd, core.ProcQueryFields)
 	return cmd
 }
 
@@ -585,13 +724,19 @@ func newIOSStatusCommand(use string, env ioscore.Env) *cobra.Command {
 
 func newAndroidStopCommand(use string, env core.Env) *cobra.Command {
 	var stopName, stopSerial string
+	var stopOptions func() (core.StopOptions, error)
 	cmd := &cobra.Command{
 		Use:   use,
 		Short: "Stop a running Android emulator by --name or --serial",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return stopAndroidWithOutput(env, stopName, stopSerial)
+			opts, err := stopOptions()
+			if err != nil {
+				return err
+			}
+			return stopAndroidWithOutput(env, stopName, stopSerial, opts)
 		},
 	}
+	stopOptions = addStopModeFlags(cmd)
 	cmd.Flags().StringVar(&stopName, "name", "", "AVD name")
 	cmd.Flags().StringVar(&stopSerial, "serial", "", "emulator serial (e.g., emulator-5582)")
 	return cmd
@@ -616,7 +761,9 @@ func newIOSStopCommand(use string, env ioscore.Env) *cobra.Command {
 }
 
 func newAndroidSaveGoldenCommand(env core.Env) *cobra.Command {
-	var sgName, sgDest string
+	var sgName, sgDest, sgFsck, sgLayout string
+	var sgSelfContained, sgValidate bool
+	var sgValidateTimeout time.Duration
 	cmd := &cobra.Command{
 		Use:   "save-golden",
 		Short: "Export Android AVD userdata to compressed QCOW2 golden",
@@ -629,22 +776,46 @@ func newAndroidSaveGoldenCommand(env core.Env) *cobra.Command {
 				_ = os.MkdirAll(dir, 0o755)
 				sgDest = filepath.Join(dir, fmt.Sprintf("%s-userdata.qcow2", sgName))
 			}
-			dst, sz, err := core.SaveGolden(env, sgName, sgDest)
+			fsck, err := core.ParseFsckMode(sgFsck)
+			if err != nil {
+				return err
+			}
+			opts := core.SaveGoldenOptions{
+				Fsck: fsck, SelfContained: sgSelfContained, Validate: sgValidate, ValidateTimeout: sgValidateTimeout,
+			}
+			if sgLayout != "" {
+				layout, err := core.LoadGoldenLayout(sgLayout)
+				if err != nil {
+					return err
+				}
+				opts.Layout = &layout
+			}
+			dst, sz, err := core.SaveGoldenWithOptions(env, sgName, sgDest, opts)
 			if err != nil {
 				return err
 			}
 			fmt.Printf("Golden saved: %s (%d bytes)\n", dst, sz)
+			if sgValidate {
+				fmt.Println("Golden validated: a headless clone booted and passed the health checks")
+			}
 			return nil
 		},
 	}
 	cmd.Flags().StringVar(&sgName, "name", "", "AVD name")
 	cmd.Flags().StringVar(&sgDest, "dest", "", "Destination qcow2 (default: $AVDCTL_GOLDEN_DIR/<name>-userdata.qcow2)")
+	cmd.Flags().StringVar(&sgFsck, "fsck", "off", "check userdata with e2fsck before export: off, check (refuse on errors), repair")
+	cmd.Flags().BoolVar(&sgSelfContained, "self-contained", false, "also store base files and system image so clones don't need the base AVD")
+	cmd.Flags().BoolVar(&sgValidate, "validate", false, "boot a temporary headless clone, run health checks, and mark the golden validated")
+	cmd.Flags().DurationVar(&sgValidateTimeout, "validate-timeout", 3*time.Minute, "boot timeout for --validate")
+	cmd.Flags().StringVar(&sgLayout, "layout", "", "JSON golden layout (file or inline) listing the images to export (default: $AVDCTL_GOLDEN_LAYOUT or userdata, encryptionkey, cache, sdcard)")
 	return cmd
 }
 
 func newAndroidPrewarmCommand(env core.Env) *cobra.Command {
-	var pwName, pwDest string
+	var pwName, pwDest, pwLockOrientation, pwCompile string
 	var pwExtra, pwTimeout time.Duration
+	var pwADBKeys, pwSettings, pwAccessibility, pwWarmApps []string
+	var pwSelfContained, pwJSON, pwFreezeUpdates bool
 	cmd := &cobra.Command{
 		Use:   "prewarm",
 		Short: "Boot once (no snapshots), wait for boot, settle caches, then save golden QCOW2",
@@ -657,11 +828,24 @@ func newAndroidPrewarmCommand(env core.Env) *cobra.Command {
 				_ = os.MkdirAll(dir, 0o755)
 				pwDest = filepath.Join(dir, fmt.Sprintf("%s-prewarmed.qcow2", pwName))
 			}
-			dst, sz, err := core.PrewarmGolden(env, pwName, pwDest, pwExtra, pwTimeout)
+			if pwLockOrientation != "" {
+				lock, err := core.OrientationLockSettings(core.Orientation(pwLockOrientation))
+				if err != nil {
+					return err
+				}
+				pwSettings = append(pwSettings, lock...)
+			}
+			report, err := core.PrewarmGoldenWithReport(env, pwName, pwDest, pwExtra, pwTimeout,
+				core.PrewarmOptions{ADBKeys: pwADBKeys, SelfContained: pwSelfContained, Settings: pwSettings, AccessibilityServices: pwAccessibility,
+					WarmApps: pwWarmApps, CompileFilter: pwCompile, FreezeUpdates: pwFreezeUpdates})
 			if err != nil {
 				return err
 			}
-			fmt.Printf("Prewarmed golden saved: %s (%d bytes)\n", dst, sz)
+			if pwJSON {
+				return encodeJSON(report)
+			}
+			fmt.Printf("Prewarmed golden saved: %s (%d bytes)\n", report.Path, report.SizeBytes)
+			printBuildReport(report)
 			return nil
 		},
 	}
@@ -669,6 +853,15 @@ func newAndroidPrewarmCommand(env core.Env) *cobra.Command {
 	cmd.Flags().StringVar(&pwDest, "dest", "", "Destination qcow2 (default: $AVDCTL_GOLDEN_DIR/<name>-prewarmed.qcow2)")
 	cmd.Flags().DurationVar(&pwExtra, "extra", 30*time.Second, "extra settle time after boot")
 	cmd.Flags().DurationVar(&pwTimeout, "timeout", 3*time.Minute, "boot timeout")
+	cmd.Flags().StringArrayVar(&pwADBKeys, "adb-key", nil, "adbkey.pub to trust in the golden without authorization prompts (repeatable; default $AVDCTL_ADB_KEYS)")
+	cmd.Flags().BoolVar(&pwSelfContained, "self-contained", false, "also store base files and system image so clones don't need the base AVD")
+	cmd.Flags().StringArrayVar(&pwSettings, "setting", nil, "adb shell command run after boot and recorded for migrations, e.g. 'settings put global window_animation_scale 0' (repeatable)")
+	cmd.Flags().StringArrayVar(&pwAccessibility, "accessibility-service", nil, "accessibility service (package/.Class) to enable and verify before export; the app must be installed (repeatable)")
+	cmd.Flags().StringArrayVar(&pwWarmApps, "warm-app", nil, "package launched once until its first frame before export, recording its ART profile (repeatable)")
+	cmd.Flags().StringVar(&pwCompile, "compile", "", "ART compiler filter applied to all packages before export, e.g. speed-profile ("+strings.Join(core.CompilerFilters, ", ")+")")
+	cmd.Flags().BoolVar(&pwFreezeUpdates, "freeze-updates", false, "turn off automatic system updates and disable the Play Store (app and Play system updates) before export")
+	cmd.Flags().BoolVar(&pwJSON, "json", false, "print the build report (boot and settle time, peak RSS, image sizes, packages) as JSON")
+	cmd.Flags().StringVar(&pwLockOrientation, "lock-orientation", "", "disable auto-rotation so clones boot in this orientation (portrait, landscape, reverse-portrait, reverse-landscape)")
 	return cmd
 }
 
@@ -681,6 +874,11 @@ func newAndroidCustomizeStartCommand(env core.Env) *cobra.Command {
 			if csName == "" {
 				return errors.New("--name is required")
 			}
+			if session, err := core.CustomizeStatus(env, csName); err == nil && session.Running {
+				fmt.Printf("Customize session already running (pid %d, up %s); reconnected (log: %s)\n",
+					session.PID, session.Uptime().Round(time.Second), session.LogPath)
+				return nil
+			}
 			logPath, err := core.CustomizeStart(env, csName)
 			if err != nil {
 				return err
@@ -697,7 +895,7 @@ func newAndroidCustomizeFinishCommand(env core.Env) *cobra.Command {
 	var cfName, cfDest string
 	cmd := &cobra.Command{
 		Use:   "customize-finish",
-		Short: "Stop emulator (if running) and export userdata to golden directory (raw IMG format)",
+		Short: "Stop emulator (if running), wait for it to exit, and export userdata to golden directory (raw IMG format)",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if cfName == "" {
 				return errors.New("--name is required")
@@ -716,18 +914,45 @@ func newAndroidCustomizeFinishCommand(env core.Env) *cobra.Command {
 }
 
 func newAndroidCloneCommand(use string, env core.Env) *cobra.Command {
-	var clBase, clName, clGolden string
+	var clBase, clGolden, clValues, clLink string
+	var clNames, clVars, clLinkRules []string
+	var clProgress bool
 	cmd := &cobra.Command{
 		Use:   use,
 		Short: "Create clone by copying raw IMG files from golden directory (preserves all customizations)",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if clBase == "" || clName == "" {
+			if clBase == "" || len(clNames) == 0 {
 				return errors.New("--base and --name are required")
 			}
 			if clGolden == "" {
 				return errors.New("--golden is required")
 			}
-			inf, err := core.CloneFromGolden(env, clBase, clName, clGolden)
+			vars, err := parseKeyValueFlags(clVars)
+			if err != nil {
+				return err
+			}
+			opts := core.CloneOptions{ConfigVars: vars, ConfigValuesFile: clValues}
+			if clProgress {
+				opts.Progress = cloneProgressPrinter()
+			}
+			if opts.Links.Default, err = core.ParseLinkMode(clLink); err != nil {
+				return err
+			}
+			for _, raw := range clLinkRules {
+				rule, err := core.ParseLinkRule(raw)
+				if err != nil {
+					return err
+				}
+				opts.Links.Rules = append(opts.Links.Rules, rule)
+			}
+			if len(clNames) > 1 {
+				infos, err := core.CloneMany(env, clBase, clGolden, clNames, opts)
+				for _, inf := range infos {
+					fmt.Printf("Clone ready: %s at %s\n", inf.Name, inf.Path)
+				}
+				return err
+			}
+			inf, err := core.CloneFromGoldenWithOptions(env, clBase, clNames[0], clGolden, opts)
 			if err != nil {
 				return err
 			}
@@ -736,14 +961,39 @@ func newAndroidCloneCommand(use string, env core.Env) *cobra.Command {
 		},
 	}
 	cmd.Flags().StringVar(&clBase, "base", "", "Base AVD name (e.g., base-a35)")
-	cmd.Flags().StringVar(&clName, "name", "", "New clone name (e.g., w-<slug>)")
-	cmd.Flags().StringVar(&clGolden, "golden", "", "Path to golden directory")
+	cmd.Flags().StringArrayVar(&clNames, "name", nil, "New clone name (e.g., w-<slug>); repeat to clone several, $AVDCTL_IO_PARALLELISM at a time")
+	cmd.Flags().StringVar(&clGolden, "golden", "", "Path to golden directory, or @<channel> (e.g. @production)")
+	cmd.Flags().StringVar(&clValues, "values", "", "KEY=VALUE file with config template variables for this clone")
+	cmd.Flags().StringArrayVar(&clVars, "var", nil, "config template variable KEY=VALUE (repeatable, overrides --values)")
+	cmd.Flags().StringVar(&clLink, "link", "", "how to take base artifacts: symlink, hardlink or copy (default $AVDCTL_CLONE_LINK or symlink)")
+	cmd.Flags().StringArrayVar(&clLinkRules, "link-rule", nil, "per-artifact override PATTERN=MODE, e.g. kernel-ranchu=copy (repeatable)")
+	cmd.Flags().BoolVar(&clProgress, "progress", false, "print golden image copy progress to stderr")
 	return cmd
 }
 
+// cloneProgressPrinter prints image copy progress to stderr in 10% steps.
+func cloneProgressPrinter() core.CopyProgressFunc {
+	var mu sync.Mutex
+	lastStep := map[string]int64{}
+	return func(image string, copied, total int64) {
+		if total <= 0 {
+			return
+		}
+		step := copied * 10 / total
+		mu.Lock()
+		defer mu.Unlock()
+		if last, ok := lastStep[image]; ok && step <= last {
+			return
+		}
+		lastStep[image] = step
+		fmt.Fprintf(os.Stderr, "Copying %s: %d%% of %d MiB\n", image, step*10, total>>20)
+	}
+}
+
 func newAndroidBakeCommand(env core.Env) *cobra.Command {
-	var bkBase, bkName, bkGolden, bkOut string
-	var apks []string
+	var bkBase, bkName, bkGolden, bkOut, bkFrida string
+	var apks, obbs, appData []string
+	var bkJSON bool
 	cmd := &cobra.Command{
 		Use:   "bake-apk",
 		Short: "Clone -> boot -> install APK(s) -> shutdown -> export new golden",
@@ -751,15 +1001,23 @@ func newAndroidBakeCommand(env core.Env) *cobra.Command {
 			if bkBase == "" || bkName == "" || bkGolden == "" {
 				return errors.New("--base, --name, --golden are required")
 			}
-			if len(apks) == 0 {
-				return errors.New("--apk must be provided at least once")
+			if len(apks) == 0 && bkFrida == "" && len(obbs) == 0 && len(appData) == 0 {
+				return errors.New("--apk must be provided at least once (or --obb, --app-data, --frida-server)")
+			}
+			opts := core.BakeOptions{FridaServer: bkFrida, OBBs: obbs}
+			for _, spec := range appData {
+				data, err := core.ParseAppData(spec)
+				if err != nil {
+					return err
+				}
+				opts.AppData = append(opts.AppData, data)
 			}
 			if bkOut == "" {
 				dir := core.DefaultGoldenDir()
 				_ = os.MkdirAll(dir, 0o755)
 				bkOut = filepath.Join(dir, fmt.Sprintf("%s-baked.qcow2", bkName))
 			}
-			dst, sz, err := core.BakeAPK(env, bkBase, bkName, bkGolden, apks, 3*time.Minute)
+			report, err := core.BakeAPKWithReport(env, bkBase, bkName, bkGolden, apks, 3*time.Minute, opts)
 			if err != nil {
 				return err
 			}
@@ -767,19 +1025,42 @@ func newAndroidBakeCommand(env core.Env) *cobra.Command {
 			if err != nil {
 				return err
 			}
-			fmt.Printf("Baked clone at %s (%d bytes)\n", dst, sz)
+			if bkJSON {
+				return encodeJSON(struct {
+					core.BuildReport
+					Golden          string `json:"golden"`
+					GoldenSizeBytes int64  `json:"golden_size_bytes"`
+				}{report, dst2, sz2})
+			}
+			fmt.Printf("Baked clone at %s (%d bytes)\n", report.Path, report.SizeBytes)
 			fmt.Printf("Exported baked golden: %s (%d bytes)\n", dst2, sz2)
+			printBuildReport(report)
 			return nil
 		},
 	}
 	cmd.Flags().StringVar(&bkBase, "base", "", "Base AVD name")
 	cmd.Flags().StringVar(&bkName, "name", "", "New baked clone name (e.g., w-<slug>)")
 	cmd.Flags().StringVar(&bkGolden, "golden", "", "Path to base golden qcow2")
-	cmd.Flags().StringSliceVar(&apks, "apk", nil, "APK file(s) to install (repeatable)")
+	cmd.Flags().StringSliceVar(&apks, "apk", nil, "APK, split APK directory, .apks or .aab to install (repeatable)")
 	cmd.Flags().StringVar(&bkOut, "dest", "", "Destination golden qcow2 for baked image")
+	cmd.Flags().StringVar(&bkFrida, "frida-server", "", "frida-server binary for the guest ABI to install (needs a root-capable image)")
+	cmd.Flags().StringSliceVar(&obbs, "obb", nil, "OBB expansion file, main|patch.<versionCode>.<package>.obb (repeatable)")
+	cmd.Flags().StringArrayVar(&appData, "app-data", nil, "<package>=<dir> copied into the app's data directory (repeatable; root image or debuggable app)")
+	cmd.Flags().BoolVar(&bkJSON, "json", false, "print the build report of the clone and the exported golden as JSON")
 	return cmd
 }
 
+// printBuildReport prints the metrics of a prewarm or bake after its result line.
+func printBuildReport(r core.BuildReport) {
+	rss := "n/a"
+	if r.PeakRSSBytes > 0 {
+		rss = fmt.Sprintf("%d MiB", r.PeakRSSBytes>>20)
+	}
+	fmt.Printf("  boot %s, settle %s, peak RSS %s, images %d -> %d bytes, %d packages\n",
+		r.BootDuration.Round(time.Second), r.SettleDuration.Round(time.Second), rss,
+		r.ImageBytesBefore, r.ImageBytesAfter, r.Packages)
+}
+
 func newAndroidStopBluetoothCommand(env core.Env) *cobra.Command {
 	var stopBtName, stopBtSerial string
 	cmd := &cobra.Command{
@@ -822,7 +1103,7 @@ func newAndroidCleanupCommand(env core.Env) *cobra.Command {
 	var cleanupDryRun bool
 	cmd := &cobra.Command{
 		Use:   "cleanup",
-		Short: "Detect and optionally clean orphaned Android emulators and clones",
+		Short: "Detect and optionally clean orphaned Android emulators and clones, and prune run history",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			dryRun := !cleanupForce
 			if cleanupDryRun {
@@ -832,6 +1113,9 @@ func newAndroidCleanupCommand(env core.Env) *cobra.Command {
 			if err != nil {
 				return err
 			}
+			if len(report.PrunedHistory) > 0 {
+				printPruned(report.PrunedHistory, dryRun)
+			}
 			if len(report.OrphanedProcesses) == 0 && len(report.OrphanedAVDs) == 0 {
 				fmt.Println("No orphans found.")
 				return nil
@@ -857,6 +1141,155 @@ func newAndroidCleanupCommand(env core.Env) *cobra.Command {
 	return cmd
 }
 
+func newAndroidStorageCommand(env core.Env) *cobra.Command {
+	var storageJSON bool
+	cmd := &cobra.Command{
+		Use:   "storage",
+		Short: "Show filesystem type and capabilities of ANDROID_AVD_HOME and AVDCTL_GOLDEN_DIR",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			infos, err := core.StorageReport(env)
+			if err != nil {
+				return err
+			}
+			if storageJSON {
+				return encodeJSON(infos)
+			}
+			for _, info := range infos {
+				fmt.Printf("%-40s fs=%-8s shared=%-5v symlinks=%-5v locking=%v\n",
+					info.Path, info.FSType, info.Shared, info.Symlinks, info.Locking)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&storageJSON, "json", false, "output JSON")
+	return cmd
+}
+
+func newAndroidEmulatorVersionCommand(env core.Env) *cobra.Command {
+	var versionJSON bool
+	var versionSDK string
+	cmd := &cobra.Command{
+		Use:   "emulator-version",
+		Short: "Show the installed emulator version and gated features; checks AVDCTL_EMULATOR_VERSION",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			env, err := env.WithSDK(versionSDK)
+			if err != nil {
+				return err
+			}
+			version, err := core.DetectEmulatorVersion(env)
+			if err != nil {
+				return err
+			}
+			_, checkErr := core.CheckEmulatorVersion(env)
+			if versionJSON {
+				out := struct {
+					Version  string                `json:"version"`
+					Build    string                `json:"build,omitempty"`
+					Required string                `json:"required,omitempty"`
+					Features core.EmulatorFeatures `json:"features"`
+				}{version.String(), version.Build, env.RequiredEmulatorVersion, version.Features()}
+				if err := encodeJSON(out); err != nil {
+					return err
+				}
+				return checkErr
+			}
+			features := version.Features()
+			fmt.Printf("emulator %s", version)
+			if version.Build != "" {
+				fmt.Printf(" (build %s)", version.Build)
+			}
+			fmt.Println()
+			fmt.Printf("snapshot-flags=%v qt-hide-window=%v grpc=%v wifi-mac=%v\n",
+				features.SnapshotFlags, features.QtHideWindow, features.GRPC, features.WifiMAC)
+			if env.RequiredEmulatorVersion != "" && checkErr == nil {
+				fmt.Printf("matches required %s\n", env.RequiredEmulatorVersion)
+			}
+			return checkErr
+		},
+	}
+	cmd.Flags().BoolVar(&versionJSON, "json", false, "output JSON")
+	cmd.Flags().StringVar(&versionSDK, "sdk", "", "named Android SDK from AVDCTL_SDKS to inspect")
+	return cmd
+}
+
+func newAndroidIdentityCommand(env core.Env) *cobra.Command {
+	var idName, idApply string
+	var idJSON bool
+	cmd := &cobra.Command{
+		Use:   "identity",
+		Short: "Show a clone's Wi-Fi MAC and Android ID, or re-apply them to a running emulator",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if idName == "" {
+				return errors.New("--name is required")
+			}
+			if idApply != "" {
+				if err := core.ApplyCloneIdentity(env, idApply, idName); err != nil {
+					return err
+				}
+			}
+			identity, err := core.ReadCloneIdentity(env, idName)
+			if err != nil {
+				return err
+			}
+			if idJSON {
+				return encodeJSON(identity)
+			}
+			fmt.Printf("wifi_mac=%s android_id=%s applied=%v\n", identity.WifiMAC, identity.AndroidID, identity.AndroidIDApplied)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&idName, "name", "", "clone name")
+	cmd.Flags().StringVar(&idApply, "apply", "", "serial of the running clone to write the Android ID to")
+	cmd.Flags().BoolVar(&idJSON, "json", false, "output JSON")
+	return cmd
+}
+
+func newAndroidIntegrityCommand(env core.Env) *cobra.Command {
+	var inSerial, inGolden string
+	var inJSON bool
+	cmd := &cobra.Command{
+		Use:   "integrity",
+		Short: "Estimate a running emulator's Play Integrity verdict; with --golden, fail if it degraded",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if inSerial == "" {
+				return errors.New("--serial is required")
+			}
+			report, err := core.CheckIntegrity(env, inSerial)
+			if err != nil {
+				return err
+			}
+			var degraded error
+			if inGolden != "" {
+				manifest, err := core.ReadGoldenManifest(inGolden)
+				if err != nil {
+					return err
+				}
+				if manifest.Integrity == nil {
+					return fmt.Errorf("golden %s has no recorded integrity report (prewarm records one)", inGolden)
+				}
+				if report.DegradedFrom(*manifest.Integrity) {
+					degraded = fmt.Errorf("integrity degraded: golden %s, %s %s", manifest.Integrity.Verdict, inSerial, report.Verdict)
+				}
+			}
+			if inJSON {
+				if err := encodeJSON(report); err != nil {
+					return err
+				}
+				return degraded
+			}
+			fmt.Printf("%s: %s\n", inSerial, report.Verdict)
+			for _, reason := range report.Reasons {
+				fmt.Printf("  - %s\n", reason)
+			}
+			return degraded
+		},
+	}
+	cmd.Flags().StringVar(&inSerial, "serial", "", "emulator serial (e.g. emulator-5580)")
+	cmd.Flags().StringVar(&inGolden, "golden", "", "golden directory whose recorded verdict the device must still meet")
+	cmd.Flags().BoolVar(&inJSON, "json", false, "output JSON")
+	return cmd
+}
+
 func newRedroidRunCommand(use string, env redroidcore.Env) *cobra.Command {
 	defaultDataDir := redroidcore.DefaultDataDir()
 	defaultDataTar := redroidcore.DefaultDataTar()
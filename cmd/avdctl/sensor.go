@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	core "github.com/forkbombeu/avdctl/internal/avd"
+	"github.com/spf13/cobra"
+)
+
+func newAndroidSensorCommand(env core.Env) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sensor",
+		Short: "Simulate emulator sensors (set, play)",
+	}
+	cmd.AddCommand(newAndroidSensorSetCommand(env), newAndroidSensorPlayCommand(env))
+	return cmd
+}
+
+func newAndroidSensorSetCommand(env core.Env) *cobra.Command {
+	var name, serial string
+	cmd := &cobra.Command{
+		Use:   "set SENSOR VALUE[:VALUE...]",
+		Short: "Set a sensor (acceleration, gyroscope, magnetic-field, orientation, pressure, temperature, humidity, light, proximity)",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			resolved, err := resolveAndroidSerial(env, name, serial)
+			if err != nil {
+				return err
+			}
+			var values []float64
+			for _, field := range strings.Split(args[1], ":") {
+				v, err := strconv.ParseFloat(field, 64)
+				if err != nil {
+					return fmt.Errorf("invalid sensor value %q", field)
+				}
+				values = append(values, v)
+			}
+			return core.SetSensor(env, resolved, core.Sensor(args[0]), values)
+		},
+	}
+	cmd.Flags().StringVar(&name, "name", "", "running AVD name")
+	cmd.Flags().StringVar(&serial, "serial", "", "emulator serial (e.g. emulator-5580)")
+	return cmd
+}
+
+func newAndroidSensorPlayCommand(env core.Env) *cobra.Command {
+	var name, serial, traceFile, traceText string
+	cmd := &cobra.Command{
+		Use:   "play",
+		Short: "Feed a recorded sensor trace (lines of offset_ms,sensor,values...) over time",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			resolved, err := resolveAndroidSerial(env, name, serial)
+			if err != nil {
+				return err
+			}
+			if (traceFile == "") == (traceText == "") {
+				return errors.New("use one of --trace or --trace-text")
+			}
+			if traceFile != "" {
+				b, err := os.ReadFile(traceFile)
+				if err != nil {
+					return fmt.Errorf("read sensor trace: %w", err)
+				}
+				traceText = string(b)
+			}
+			samples, err := core.ParseSensorTrace(strings.NewReader(traceText))
+			if err != nil {
+				return err
+			}
+			if err := core.PlaySensorTrace(env, resolved, samples); err != nil {
+				return err
+			}
+			fmt.Printf("Played %d sensor samples on %s\n", len(samples), resolved)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&name, "name", "", "running AVD name")
+	cmd.Flags().StringVar(&serial, "serial", "", "emulator serial (e.g. emulator-5580)")
+	cmd.Flags().StringVar(&traceFile, "trace", "", "sensor trace file")
+	cmd.Flags().StringVar(&traceText, "trace-text", "", "sensor trace contents")
+	return cmd
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	core "github.com/forkbombeu/avdctl/internal/avd"
+	"github.com/spf13/cobra"
+)
+
+func newSlugCommand(env core.Env) *cobra.Command {
+	return &cobra.Command{
+		Use:   "slug TEXT...",
+		Short: "Print an AVD name built from arbitrary text that satisfies the naming policy",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name, err := core.SlugName(env, strings.Join(args, " "))
+			if err != nil {
+				return err
+			}
+			fmt.Println(name)
+			return nil
+		},
+	}
+}
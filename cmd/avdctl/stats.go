@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	core "github.com/forkbombeu/avdctl/internal/avd"
+	"github.com/spf13/cobra"
+)
+
+func newAndroidStatsCommand(env core.Env) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "stats",
+		Short: "Summarize the run history of the AVDs on this host",
+	}
+	cmd.AddCommand(newStatsFailuresCommand(func(since time.Time) (core.FailureReport, error) {
+		return core.FailureStats(env, since)
+	}))
+	return cmd
+}
+
+// newStatsFailuresCommand is "stats failures" on one host (stats) or on every agent (fleet stats).
+func newStatsFailuresCommand(failureStats func(since time.Time) (core.FailureReport, error)) *cobra.Command {
+	var sinceFlag string
+	var asJSON bool
+	cmd := &cobra.Command{
+		Use:   "failures",
+		Short: "Count the failed runs by class: boot-timeout, crash, killed-by-gc, lease-expired, host-shutdown",
+		Example: `  avdctl stats failures --since 7d
+  avdctl fleet stats failures --since 7d --json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			since, err := parseUsageTime(sinceFlag, time.Now())
+			if err != nil {
+				return err
+			}
+			report, err := failureStats(since)
+			if asJSON {
+				if jsonErr := encodeJSON(report); jsonErr != nil {
+					return jsonErr
+				}
+				return err
+			}
+			printFailureReport(report)
+			return err
+		},
+	}
+	cmd.Flags().StringVar(&sinceFlag, "since", "7d", "count runs started since: a duration back from now (7d, 12h) or a date (2006-01-02, RFC 3339)")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "output JSON")
+	return cmd
+}
+
+func printFailureReport(report core.FailureReport) {
+	share := func(n int) float64 {
+		if report.Runs == 0 {
+			return 0
+		}
+		return 100 * float64(n) / float64(report.Runs)
+	}
+	fmt.Printf("Runs since %s: %d, failed %d (%.1f%%)\n", report.Since.Local().Format("2006-01-02 15:04"), report.Runs, report.Failed, share(report.Failed))
+	if len(report.Classes) == 0 {
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CLASS\tRUNS\tOF RUNS\tLAST\tAVDS")
+	for _, c := range report.Classes {
+		fmt.Fprintf(w, "%s\t%d\t%.1f%%\t%s\t%s\n", c.Class, c.Runs, share(c.Runs), c.Last.Local().Format("2006-01-02 15:04"), strings.Join(c.AVDs, ", "))
+	}
+	_ = w.Flush()
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	core "github.com/forkbombeu/avdctl/internal/avd"
+	"github.com/spf13/cobra"
+)
+
+func newAndroidTraceCommand(env core.Env) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "trace",
+		Short: "Record a perfetto trace on a running emulator (start, stop)",
+	}
+	cmd.AddCommand(newAndroidTraceStartCommand(env), newAndroidTraceStopCommand(env))
+	return cmd
+}
+
+func newAndroidTraceStartCommand(env core.Env) *cobra.Command {
+	var serial, configFile, configText string
+	var duration time.Duration
+	cmd := &cobra.Command{
+		Use:   "start",
+		Short: "Start a background perfetto session (waits for the emulator, so it can run right after start)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if serial == "" {
+				return errors.New("--serial is required")
+			}
+			if configFile != "" && configText != "" {
+				return errors.New("use --config or --config-text, not both")
+			}
+			cfg := core.TraceConfig{Config: configText, Duration: duration}
+			if configFile != "" {
+				b, err := os.ReadFile(configFile)
+				if err != nil {
+					return fmt.Errorf("read trace config: %w", err)
+				}
+				cfg.Config = string(b)
+			}
+			if err := core.StartTrace(env, serial, cfg); err != nil {
+				return err
+			}
+			fmt.Printf("Trace started on %s\n", serial)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&serial, "serial", "", "emulator serial (e.g. emulator-5580)")
+	cmd.Flags().StringVar(&configFile, "config", "", "perfetto text-format config file (default: scheduling, CPU and framework atrace)")
+	cmd.Flags().StringVar(&configText, "config-text", "", "perfetto text-format config")
+	cmd.Flags().DurationVar(&duration, "duration", core.DefaultTraceDuration, "stop the trace on its own after this long")
+	return cmd
+}
+
+func newAndroidTraceStopCommand(env core.Env) *cobra.Command {
+	var serial, out string
+	cmd := &cobra.Command{
+		Use:   "stop",
+		Short: "Stop the perfetto session and pull the trace",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if serial == "" {
+				return errors.New("--serial is required")
+			}
+			path, err := core.StopTrace(env, serial, out)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Trace saved: %s\n", path)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&serial, "serial", "", "emulator serial (e.g. emulator-5580)")
+	cmd.Flags().StringVar(&out, "out", ".", "output file, or directory for <serial>-<timestamp>.perfetto-trace")
+	return cmd
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	core "github.com/forkbombeu/avdctl/internal/avd"
+	"github.com/spf13/cobra"
+)
+
+func newRestoreCommand(env core.Env) *cobra.Command {
+	var asJSON bool
+	cmd := &cobra.Command{
+		Use:   "restore NAME|ID",
+		Short: "Bring a deleted AVD or golden back from the trash",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			entry, err := core.Restore(env, args[0])
+			if err != nil {
+				return err
+			}
+			if asJSON {
+				return encodeJSON(entry)
+			}
+			fmt.Printf("Restored %s %s to %s\n", entry.Kind, entry.Name, entry.Path)
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&asJSON, "json", false, "output JSON")
+	return cmd
+}
+
+func newTrashCommand(env core.Env) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "trash",
+		Short: "List or purge deleted AVDs and goldens kept for restore",
+	}
+
+	var listJSON bool
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List the trash, most recently deleted first",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			entries, err := core.ListTrash(env)
+			if err != nil {
+				return err
+			}
+			if listJSON {
+				return encodeJSON(entries)
+			}
+			printTrash(entries)
+			return nil
+		},
+	}
+	list.Flags().BoolVar(&listJSON, "json", false, "output JSON")
+
+	var all, purgeJSON bool
+	purge := &cobra.Command{
+		Use:   "purge",
+		Short: "Permanently remove expired trash entries (--all: every entry)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			purged, err := core.PurgeTrash(env, all)
+			if err != nil {
+				return err
+			}
+			if purgeJSON {
+				return encodeJSON(purged)
+			}
+			var freed int64
+			for _, entry := range purged {
+				freed += entry.SizeBytes
+			}
+			fmt.Printf("Purged %d entr(ies), %.1f MiB\n", len(purged), float64(freed)/(1<<20))
+			return nil
+		},
+	}
+	purge.Flags().BoolVar(&all, "all", false, "purge entries that have not expired too")
+	purge.Flags().BoolVar(&purgeJSON, "json", false, "output JSON")
+
+	cmd.AddCommand(list, purge)
+	return cmd
+}
+
+func printTrash(entries []core.TrashEntry) {
+	if len(entries) == 0 {
+		fmt.Println("(trash is empty)")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tKIND\tDELETED\tEXPIRES\tSIZE\tID")
+	for _, entry := range entries {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%.1f MiB\t%s\n", entry.Name, entry.Kind,
+			entry.DeletedAt.Local().Format("2006-01-02 15:04"), entry.ExpiresAt.Local().Format("2006-01-02 15:04"),
+			float64(entry.SizeBytes)/(1<<20), entry.ID)
+	}
+	_ = w.Flush()
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	core "github.com/forkbombeu/avdctl/internal/avd"
+	"github.com/spf13/cobra"
+)
+
+func newAndroidUsageCommand(env core.Env) *cobra.Command {
+	var sinceFlag, untilFlag, groupBy, file, format string
+	cmd := &cobra.Command{
+		Use:   "usage",
+		Short: "Account emulator-hours, disk GB-days and boots per AVD or per fleet file label",
+		Long: `Sums, over a window, what each AVD used according to the ledger avdctl appends to on every
+run, stop and delete (ANDROID_AVD_HOME/.avdctl/usage.jsonl). --group-by sums AVDs by the value
+of a clone label from the fleet file, e.g. customer for chargeback.`,
+		Example: `  avdctl usage --since 30d
+  avdctl usage --group-by customer --since 30d -f fleet.yaml --format csv > usage.csv`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			now := time.Now().UTC()
+			since, err := parseUsageTime(sinceFlag, now)
+			if err != nil {
+				return err
+			}
+			until := now
+			if untilFlag != "" {
+				if until, err = parseUsageTime(untilFlag, now); err != nil {
+					return err
+				}
+			}
+			usage, err := core.Usage(env, since, until)
+			if err != nil {
+				return err
+			}
+			var groups []core.UsageGroup
+			if groupBy != "" {
+				fleet, err := core.LoadFleet(file)
+				if err != nil {
+					return err
+				}
+				groups = core.GroupUsage(usage, fleet.Labels(), groupBy)
+			} else {
+				for _, u := range usage {
+					groups = append(groups, core.UsageGroup{Group: u.AVD, EmulatorHours: u.EmulatorHours, DiskGBDays: u.DiskGBDays, Boots: u.Boots, AVDs: []string{u.AVD}})
+				}
+			}
+			return printUsage(format, groupBy, groups, usage)
+		},
+	}
+	cmd.Flags().StringVar(&sinceFlag, "since", "30d", "window start: a duration back from now (30d, 12h) or a date (2006-01-02, RFC 3339)")
+	cmd.Flags().StringVar(&untilFlag, "until", "", "window end, like --since (default now)")
+	cmd.Flags().StringVar(&groupBy, "group-by", "", "clone label to group by (default: one row per AVD)")
+	cmd.Flags().StringVarP(&file, "file", "f", "fleet.yaml", "fleet file with the clone labels, for --group-by")
+	cmd.Flags().StringVar(&format, "format", "table", "table, csv or json")
+	return cmd
+}
+
+// parseUsageTime parses "30d", a Go duration (both back from now), a date or an RFC 3339 time.
+func parseUsageTime(s string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	if d, err := time.ParseDuration(s); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q (want e.g. 30d, 12h, 2006-01-02 or RFC 3339)", s)
+}
+
+func printUsage(format, groupBy string, groups []core.UsageGroup, usage []core.AVDUsage) error {
+	header := groupBy
+	if header == "" {
+		header = "avd"
+	}
+	switch format {
+	case "json":
+		if groupBy == "" {
+			return encodeJSON(usage)
+		}
+		return encodeJSON(groups)
+	case "csv":
+		w := csv.NewWriter(os.Stdout)
+		_ = w.Write([]string{header, "emulator_hours", "disk_gb_days", "boots", "avds"})
+		for _, g := range groups {
+			_ = w.Write([]string{g.Group, strconv.FormatFloat(g.EmulatorHours, 'f', 2, 64), strconv.FormatFloat(g.DiskGBDays, 'f', 2, 64), strconv.Itoa(g.Boots), strings.Join(g.AVDs, " ")})
+		}
+		w.Flush()
+		return w.Error()
+	case "table", "":
+		fmt.Printf("%-24s %14s %12s %6s\n", strings.ToUpper(header), "EMULATOR-HOURS", "DISK-GB-DAYS", "BOOTS")
+		for _, g := range groups {
+			name := g.Group
+			if name == "" {
+				name = "(unlabeled)"
+			}
+			fmt.Printf("%-24s %14.2f %12.2f %6d\n", name, g.EmulatorHours, g.DiskGBDays, g.Boots)
+		}
+		return nil
+	}
+	return fmt.Errorf("invalid --format %q (want table, csv or json)", format)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	core "github.com/forkbombeu/avdctl/internal/avd"
+	"github.com/spf13/cobra"
+)
+
+func newAndroidGuestStorageCommand(env core.Env) *cobra.Command {
+	var name, serial string
+	var asJSON, watch bool
+	var threshold float64
+	var interval time.Duration
+	cmd := &cobra.Command{
+		Use:   "guest-storage",
+		Short: "Show free space of /data on a running emulator (--watch: alert when nearly full)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			resolved, err := resolveAndroidSerial(env, name, serial)
+			if err != nil {
+				return err
+			}
+			if watch {
+				return core.WatchGuestStorage(env, resolved, core.StorageWatchOptions{
+					Threshold: threshold,
+					Interval:  interval,
+					OnAlert: func(s core.GuestStorage) {
+						fmt.Printf("%s: /data %.0f%% used, %.1f MiB free\n", s.Serial, 100*s.UsedFraction(), float64(s.AvailBytes)/(1<<20))
+					},
+				})
+			}
+			storage, err := core.GuestDataStorage(env, resolved)
+			if err != nil {
+				return err
+			}
+			if asJSON {
+				return encodeJSON(storage)
+			}
+			fmt.Printf("%s: /data %.1f MiB of %.1f MiB used (%.0f%%), %.1f MiB free\n", storage.Serial,
+				float64(storage.UsedBytes)/(1<<20), float64(storage.SizeBytes)/(1<<20),
+				100*storage.UsedFraction(), float64(storage.AvailBytes)/(1<<20))
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&name, "name", "", "running AVD name")
+	cmd.Flags().StringVar(&serial, "serial", "", "emulator serial (e.g. emulator-5580)")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "output JSON")
+	cmd.Flags().BoolVar(&watch, "watch", false, "keep checking and print an alert when usage reaches --threshold")
+	cmd.Flags().Float64Var(&threshold, "threshold", core.DefaultStorageAlertThreshold, "used fraction of /data that raises an alert")
+	cmd.Flags().DurationVar(&interval, "interval", time.Minute, "time between checks with --watch")
+	return cmd
+}
+
+func newAndroidResizeUserdataCommand(env core.Env) *cobra.Command {
+	var name, size string
+	var asJSON bool
+	cmd := &cobra.Command{
+		Use:   "resize-userdata --name NAME --size SIZE",
+		Short: "Grow the userdata image and filesystem of a stopped AVD (e.g. --size 8G)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if name == "" || size == "" {
+				return errors.New("--name and --size are required")
+			}
+			bytes, err := core.ParseSize(size)
+			if err != nil {
+				return err
+			}
+			result, err := core.ResizeUserdata(env, name, bytes)
+			if err != nil {
+				return err
+			}
+			if asJSON {
+				return encodeJSON(result)
+			}
+			fmt.Printf("Resized %s: %.1f MiB -> %.1f MiB\n", result.Image,
+				float64(result.OldSizeBytes)/(1<<20), float64(result.NewSizeBytes)/(1<<20))
+			if !result.FilesystemResized {
+				fmt.Println("The emulator grows the filesystem on the next boot.")
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&name, "name", "", "AVD name")
+	cmd.Flags().StringVar(&size, "size", "", "new userdata size (e.g. 8G)")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "output JSON")
+	return cmd
+}
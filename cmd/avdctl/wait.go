@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	core "github.com/forkbombeu/avdctl/internal/avd"
+	"github.com/spf13/cobra"
+)
+
+func newAndroidWaitCommand(env core.Env) *cobra.Command {
+	var name, serial string
+	var timeout time.Duration
+	var probe core.ReadyBroadcast
+	var phaseTimeouts []string
+	cmd := &cobra.Command{
+		Use:   "wait",
+		Short: "Wait for a running instance to boot and, with --ready-action, for an app to report ready",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			resolved, err := resolveAndroidSerial(env, name, serial)
+			if err != nil {
+				return err
+			}
+			phases, err := core.ParsePhaseTimeouts(phaseTimeouts)
+			if err != nil {
+				return err
+			}
+			opts := core.WaitOptions{
+				Timeout:       timeout,
+				PhaseTimeouts: phases,
+				Progress: func(status string, elapsed time.Duration) {
+					fmt.Fprintf(os.Stderr, "%s: %s (%s)\n", resolved, status, elapsed.Round(time.Second))
+				},
+			}
+			if probe.Action != "" {
+				opts.ReadyBroadcast = &probe
+			}
+			if err := core.WaitForBootWithOptions(env, resolved, opts); err != nil {
+				return err
+			}
+			fmt.Printf("%s ready\n", resolved)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&name, "name", "", "running AVD name")
+	cmd.Flags().StringVar(&serial, "serial", "", "emulator serial (e.g. emulator-5580)")
+	cmd.Flags().DurationVar(&timeout, "timeout", 3*time.Minute, "overall wait timeout")
+	cmd.Flags().StringVar(&probe.Action, "ready-action", "", "intent action an app answers once it is up (am broadcast probe)")
+	cmd.Flags().StringVar(&probe.Component, "ready-component", "", "explicit receiver for the probe, e.g. com.example/.ReadyReceiver")
+	cmd.Flags().StringVar(&probe.Package, "ready-package", "", "deliver the probe only to this package")
+	cmd.Flags().IntVar(&probe.ResultCode, "ready-code", 0, "result code meaning ready (default RESULT_OK, -1)")
+	cmd.Flags().StringVar(&probe.Data, "ready-data", "", "result data that must match too")
+	cmd.Flags().DurationVar(&probe.Interval, "ready-interval", time.Second, "delay between probes")
+	cmd.Flags().StringArrayVar(&phaseTimeouts, "phase-timeout", nil, "limit one boot phase, e.g. waiting_adb=30s (waiting_adb, checking_bootanim, post_boot, waiting_ready)")
+	return cmd
+}
+
+func newAndroidWaitAppCommand(env core.Env) *cobra.Command {
+	var name, serial string
+	var timeout time.Duration
+	var asJSON bool
+	cmd := &cobra.Command{
+		Use:   "wait-app PACKAGE",
+		Short: "Launch an app on a booted instance and wait until its first window is drawn",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			resolved, err := resolveAndroidSerial(env, name, serial)
+			if err != nil {
+				return err
+			}
+			ready, err := core.WaitForApp(env, resolved, args[0], timeout)
+			if err != nil {
+				return err
+			}
+			if asJSON {
+				return encodeJSON(ready)
+			}
+			fmt.Printf("%s ready on %s (pid %d, %s)\n", ready.Activity, resolved, ready.PID, ready.Duration.Round(time.Millisecond))
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&name, "name", "", "running AVD name")
+	cmd.Flags().StringVar(&serial, "serial", "", "emulator serial (e.g. emulator-5580)")
+	cmd.Flags().DurationVar(&timeout, "timeout", time.Minute, "how long to wait for the first frame")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "print the result as JSON")
+	return cmd
+}
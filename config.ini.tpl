@@ -5,6 +5,8 @@
 # ------------------------------------------------------------------
 # This template is used when AVDCTL_CONFIG_TEMPLATE is set.
 # Otherwise, base AVD's config.ini is used and sanitized.
+# It is rendered as a Go template (see internal/avd/template.go for
+# the variables); comments here are copied into every clone as is.
 # ------------------------------------------------------------------
 
 PlayStore.enabled=no
@@ -44,7 +46,7 @@ hw.battery=yes
 hw.camera.back=emulated
 hw.camera.front=none
 hw.cpu.arch=x86_64
-hw.cpu.ncore=4
+hw.cpu.ncore={{default "4" .Cores}}
 hw.dPad=no
 hw.device.hash2=MD5:2016577e1656e8e7c2adb0fac972beea
 hw.device.manufacturer=Google
@@ -55,7 +57,7 @@ hw.device.name=pixel_6
 # ------------------------------------------------------------------
 hw.lcd.backlight=yes
 hw.lcd.circular=false
-hw.lcd.density=420
+hw.lcd.density={{default "420" .Density}}
 hw.lcd.depth=16
 hw.lcd.height=2400
 hw.lcd.vsync=60
@@ -166,7 +168,7 @@ sdcard.size=512 MB
 # ------------------------------------------------------------------
 # MEMORY
 # ------------------------------------------------------------------
-hw.ramSize=1536M
+hw.ramSize={{default "1536M" .RAM}}
 vm.heapSize=228M
 
 # ------------------------------------------------------------------
@@ -55,11 +55,12 @@ func main() {
 			cloneName := "w-" + cust
 
 			// Start emulator in background
-			serial, err := avd.RunAVD(env, cloneName)
+			res, err := avd.RunAVD(env, cloneName)
 			if err != nil {
 				results <- fmt.Sprintf("✗ %s: failed to start (%v)", cust, err)
 				return
 			}
+			serial := res.Serial
 
 			results <- fmt.Sprintf("→ %s: starting emulator on %s...", cust, serial)
 
@@ -14,6 +14,7 @@ require (
 	go.opentelemetry.io/otel/sdk v1.38.0
 	go.opentelemetry.io/otel/trace v1.42.0
 	golang.org/x/crypto v0.41.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -0,0 +1,72 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// accessibilityActiveTimeout bounds the wait for the accessibility manager to bind a service.
+const accessibilityActiveTimeout = 15 * time.Second
+
+// EnableAccessibilityService enables an accessibility service on serial, keeping the ones
+// already enabled, and waits until the accessibility manager reports it active. component is
+// "package/class" or "package/.Class"; the app must be installed.
+func EnableAccessibilityService(env Env, serial, component string) error {
+	_, span := startSpan(env, "avd.EnableAccessibilityService", attribute.String("serial", serial), attribute.String("component", component))
+	defer span.End()
+	_, err := enableAccessibilityService(env, serial, component)
+	recordSpanError(span, err)
+	return err
+}
+
+// enableAccessibilityService returns the settings it applied, for recording as customizations.
+func enableAccessibilityService(env Env, serial, component string) ([]string, error) {
+	full, err := expandComponent(component)
+	if err != nil {
+		return nil, err
+	}
+	out, errOut, err := runCommandOutputWithEnv(env.Context, nil, nil, env.ADB,
+		"-s", serial, "shell", "settings", "get", "secure", "enabled_accessibility_services")
+	if err != nil {
+		return nil, fmt.Errorf("read enabled accessibility services on %s: %w: %s", serial, err, strings.TrimSpace(errOut))
+	}
+	var enabled []string
+	if current := strings.TrimSpace(out); current != "" && current != "null" {
+		enabled = strings.Split(current, ":")
+	}
+	settings := []string{
+		"settings put secure enabled_accessibility_services " + strings.Join(appendMissing(enabled, []string{full}), ":"),
+		"settings put secure accessibility_enabled 1",
+	}
+	if err := applySettings(env, serial, settings); err != nil {
+		return nil, err
+	}
+	for deadline := time.Now().Add(accessibilityActiveTimeout); ; time.Sleep(500 * time.Millisecond) {
+		dump, _, err := runCommandOutputWithEnv(env.Context, nil, nil, env.ADB, "-s", serial, "shell", "dumpsys", "accessibility")
+		if err == nil && strings.Contains(dump, full) {
+			logEvent(env, "accessibility service enabled", "serial", serial, "component", full)
+			return settings, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("accessibility service %s is not active on %s (is the app installed?)", full, serial)
+		}
+	}
+}
+
+// expandComponent turns "package/.Class" into "package/package.Class".
+func expandComponent(component string) (string, error) {
+	pkg, class, ok := strings.Cut(strings.TrimSpace(component), "/")
+	if !ok || pkg == "" || class == "" {
+		return "", fmt.Errorf("invalid accessibility service %q (want package/class)", component)
+	}
+	if strings.HasPrefix(class, ".") {
+		class = pkg + class
+	}
+	return pkg + "/" + class, nil
+}
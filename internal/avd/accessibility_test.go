@@ -0,0 +1,42 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEnableAccessibilityServiceKeepsEnabledServices(t *testing.T) {
+	env := newTestEnv(t)
+	logPath := filepath.Join(t.TempDir(), "adb.log")
+	// adb -s SERIAL shell <cmd>: applySetting passes the whole command as one argument.
+	script := `#!/bin/sh
+case "$4" in
+  "settings put"*) echo "$4" >> ` + logPath + ` ;;
+  settings) echo "com.other/com.other.Reader" ;;
+  dumpsys) echo "Enabled services:{{com.other/com.other.Reader}, {com.example.driver/com.example.driver.A11yService}}" ;;
+esac
+`
+	if err := os.WriteFile(env.ADB, []byte(script), 0o755); err != nil {
+		t.Fatalf("write adb stub: %v", err)
+	}
+	if err := EnableAccessibilityService(env, "emulator-5580", "com.example.driver/.A11yService"); err != nil {
+		t.Fatalf("EnableAccessibilityService: %v", err)
+	}
+	log, _ := os.ReadFile(logPath)
+	want := "settings put secure enabled_accessibility_services com.other/com.other.Reader:com.example.driver/com.example.driver.A11yService\n" +
+		"settings put secure accessibility_enabled 1\n"
+	if string(log) != want {
+		t.Fatalf("settings = %q, want %q", log, want)
+	}
+}
+
+func TestEnableAccessibilityServiceRejectsInvalidComponent(t *testing.T) {
+	if err := EnableAccessibilityService(newTestEnv(t), "emulator-5580", "com.example.driver"); err == nil || !strings.Contains(err.Error(), "package/class") {
+		t.Fatalf("expected invalid component error, got %v", err)
+	}
+}
@@ -0,0 +1,182 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// guestADBKeysPath is where adbd reads the public keys it trusts without prompting.
+const guestADBKeysPath = "/data/misc/adb/adb_keys"
+
+// adbPublicKeySize is the size of the RSA public key blob adb writes to adbkey.pub
+// (a 2048-bit mincrypt RSAPublicKey), before base64 encoding.
+const adbPublicKeySize = 524
+
+// ADBPublicKey is one entry of an adb_keys file.
+type ADBPublicKey struct {
+	Key     string `json:"-"` // base64 key blob
+	Comment string `json:"comment,omitempty"`
+	// Fingerprint is SHA256:<base64> of the decoded key blob.
+	Fingerprint string `json:"fingerprint"`
+}
+
+// Line returns the key in adbkey.pub format.
+func (k ADBPublicKey) Line() string {
+	if k.Comment == "" {
+		return k.Key
+	}
+	return k.Key + " " + k.Comment
+}
+
+// detectADBKeyFiles reads AVDCTL_ADB_KEYS, a list of adbkey.pub paths separated by the OS list separator.
+func detectADBKeyFiles() []string {
+	return filepath.SplitList(os.Getenv("AVDCTL_ADB_KEYS"))
+}
+
+// ReadADBPublicKeys reads adbkey.pub files (one or more keys per file) and returns the unique keys
+// in order. Invalid lines are rejected so a typo never produces a golden that still prompts.
+func ReadADBPublicKeys(paths []string) ([]ADBPublicKey, error) {
+	var keys []ADBPublicKey
+	seen := map[string]bool{}
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		f, err := os.Open(expandHome(path))
+		if err != nil {
+			return nil, fmt.Errorf("read adb public key: %w", err)
+		}
+		scanner := bufio.NewScanner(f)
+		lineNo := 0
+		for scanner.Scan() {
+			lineNo++
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			key, err := parseADBPublicKey(line)
+			if err != nil {
+				_ = f.Close()
+				return nil, fmt.Errorf("%s:%d: %w", path, lineNo, err)
+			}
+			if !seen[key.Key] {
+				seen[key.Key] = true
+				keys = append(keys, key)
+			}
+		}
+		err = scanner.Err()
+		_ = f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("read adb public key: %w", err)
+		}
+	}
+	return keys, nil
+}
+
+func parseADBPublicKey(line string) (ADBPublicKey, error) {
+	blob, comment, _ := strings.Cut(line, " ")
+	raw, err := base64.StdEncoding.DecodeString(blob)
+	if err != nil {
+		return ADBPublicKey{}, fmt.Errorf("not an adb public key: %w", err)
+	}
+	if len(raw) != adbPublicKeySize {
+		return ADBPublicKey{}, fmt.Errorf("not an adb public key: %d-byte key blob, want %d (use adbkey.pub, not adbkey)",
+			len(raw), adbPublicKeySize)
+	}
+	sum := sha256.Sum256(raw)
+	return ADBPublicKey{
+		Key:         blob,
+		Comment:     strings.TrimSpace(comment),
+		Fingerprint: "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:]),
+	}, nil
+}
+
+// PreseedADBKeys writes keys to /data/misc/adb/adb_keys on a booted emulator, replacing the file, so
+// hosts holding the matching private keys connect without the RSA authorization dialog. It needs
+// adbd to run as root, which google_apis images allow and Play Store images do not.
+func PreseedADBKeys(env Env, serial string, keys []ADBPublicKey) error {
+	_, span := startSpan(env, "avd.PreseedADBKeys",
+		attribute.String("serial", serial),
+		attribute.Int("keys", len(keys)),
+	)
+	defer span.End()
+	if len(keys) == 0 {
+		err := errors.New("no adb public keys to preseed")
+		recordSpanError(span, err)
+		return err
+	}
+	if err := adbRoot(env, serial); err != nil {
+		err = fmt.Errorf("adb_keys cannot be preseeded: %w", err)
+		recordSpanError(span, err)
+		return err
+	}
+
+	lines := make([]string, 0, len(keys))
+	for _, key := range keys {
+		lines = append(lines, key.Line())
+	}
+	tmp, err := os.CreateTemp("", "avdctl-adb-keys-*")
+	if err != nil {
+		recordSpanError(span, err)
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.WriteString(strings.Join(lines, "\n") + "\n")
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		recordSpanError(span, err)
+		return err
+	}
+
+	if _, errOut, err := runCommandOutputWithEnv(env.Context, nil, nil, env.ADB, "-s", serial, "push", tmp.Name(), guestADBKeysPath); err != nil {
+		err = fmt.Errorf("push adb_keys to %s: %w: %s", serial, err, strings.TrimSpace(errOut))
+		recordSpanError(span, err)
+		return err
+	}
+	// sync so the file reaches userdata before the emulator is killed and the image exported.
+	script := fmt.Sprintf("chown system:shell %[1]s && chmod 0640 %[1]s && (restorecon %[1]s || true) && sync", guestADBKeysPath)
+	if out, err := runCommandCombinedOutputWithEnv(env.Context, nil, nil, env.ADB, "-s", serial, "shell", script); err != nil {
+		err = fmt.Errorf("set adb_keys permissions on %s: %w: %s", serial, err, strings.TrimSpace(string(out)))
+		recordSpanError(span, err)
+		return err
+	}
+	logEvent(env, "adb keys preseeded", "serial", serial, "keys", len(keys))
+	return nil
+}
+
+// adbRoot restarts adbd as root and confirms it; "adb root" exits 0 even when refused.
+func adbRoot(env Env, serial string) error {
+	out, _ := runCommandCombinedOutputWithEnv(env.Context, nil, nil, env.ADB, "-s", serial, "root")
+	if strings.Contains(string(out), "cannot run as root") {
+		return fmt.Errorf("adbd on %s cannot run as root (Play Store images)", serial)
+	}
+	ctx := env.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	if err := runWithContext(ctx, env, env.ADB, "-s", serial, "wait-for-device"); err != nil {
+		return fmt.Errorf("wait for adbd restart on %s: %w", serial, err)
+	}
+	uid, _, err := runCommandOutputWithEnv(env.Context, nil, nil, env.ADB, "-s", serial, "shell", "id", "-u")
+	if err != nil || strings.TrimSpace(uid) != "0" {
+		return fmt.Errorf("adbd on %s is not running as root (id -u: %q)", serial, strings.TrimSpace(uid))
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ADB states of a running emulator, as reported in ProcInfo.ADBState.
+const (
+	ADBStateOnline       = "online"       // adb "device": shell and install work
+	ADBStateOffline      = "offline"      // the transport is up but the device does not answer
+	ADBStateUnauthorized = "unauthorized" // the guest has not accepted the host's adb key
+)
+
+// adbRecoverySettle is how long ListRunning waits for reconnected devices to come back online.
+var adbRecoverySettle = 3 * time.Second
+
+// adbState maps the state column of `adb devices` to an ADBState* value. States adb reports
+// while a device is coming up (authorizing, connecting) count as offline.
+func adbState(raw string) string {
+	switch raw {
+	case "device":
+		return ADBStateOnline
+	case "unauthorized":
+		return ADBStateUnauthorized
+	default:
+		return ADBStateOffline
+	}
+}
+
+// offlineEmulators returns the emulator serials that `adb devices` output lists as offline,
+// except those of emulators stopped by Pause, which cannot answer until resumed.
+func offlineEmulators(out string) []string {
+	var serials []string
+	for _, line := range strings.Split(out, "\n") {
+		f := parseADBDeviceLine(line)
+		if len(f) < 2 || !strings.HasPrefix(f[0], "emulator-") || adbState(f[1]) != ADBStateOffline {
+			continue
+		}
+		if port, err := strconv.Atoi(strings.TrimPrefix(f[0], "emulator-")); err == nil {
+			if pid := findEmulatorPID(port); pid > 0 && isStoppedProcess(pid) {
+				continue
+			}
+		}
+		serials = append(serials, f[0])
+	}
+	return serials
+}
+
+// recoverOfflineDevices is the recovery pass of ListRunning for emulators left offline, e.g.
+// after the host slept. It asks adb to reconnect all offline devices, then re-handshakes each
+// one still offline, and returns the fresh `adb devices` output (out itself when nothing was
+// offline).
+func recoverOfflineDevices(env Env, out string) string {
+	offline := offlineEmulators(out)
+	if len(offline) == 0 {
+		return out
+	}
+	logEvent(env, "adb offline devices, reconnecting", "serials", strings.Join(offline, ","))
+	_, _, _ = runCommandOutputWithEnv(env.Context, nil, nil, env.ADB, "reconnect", "offline")
+	deadline := time.Now().Add(adbRecoverySettle)
+	handshake := true
+	for {
+		fresh, _, err := runCommandOutputWithEnv(env.Context, nil, nil, env.ADB, "devices")
+		if err != nil {
+			return out
+		}
+		out = fresh
+		offline = offlineEmulators(out)
+		if len(offline) == 0 || !time.Now().Before(deadline) {
+			break
+		}
+		if handshake {
+			// Restart the transport of the devices the global reconnect did not bring back.
+			for _, serial := range offline {
+				_, _, _ = runCommandOutputWithEnv(env.Context, nil, nil, env.ADB, "-s", serial, "reconnect")
+			}
+			handshake = false
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+	if len(offline) > 0 {
+		logEvent(env, "adb devices still offline", "serials", strings.Join(offline, ","))
+	} else {
+		logEvent(env, "adb offline devices recovered")
+	}
+	return out
+}
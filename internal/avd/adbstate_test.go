@@ -0,0 +1,88 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newOfflineADBEnv stubs adb with emulator-5790 offline until any reconnect, and
+// emulator-5792 unauthorized; calls are logged to the returned file.
+func newOfflineADBEnv(t *testing.T, recovers bool) (Env, string) {
+	t.Helper()
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "calls.log")
+	recovered := filepath.Join(dir, "recovered")
+	mark := ":"
+	if recovers {
+		mark = "touch " + recovered
+	}
+	script := "#!/bin/sh\n" +
+		"echo \"$@\" >> " + logPath + "\n" +
+		"case \"$*\" in\n" +
+		"devices) state=offline; [ -f " + recovered + " ] && state=device\n" +
+		"  printf 'List of devices attached\\nemulator-5790\\t%s\\nemulator-5792\\tunauthorized\\n' $state ;;\n" +
+		"*reconnect*) " + mark + " ;;\n" +
+		"*sys.boot_completed*) echo 1 ;;\n" +
+		"esac\n"
+	adb := filepath.Join(dir, "adb")
+	if err := os.WriteFile(adb, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return Env{AVDHome: dir, ADB: adb, Emulator: filepath.Join(dir, "missing")}, logPath
+}
+
+func TestListRunningRecoversOfflineDevices(t *testing.T) {
+	defer func(d time.Duration) { adbRecoverySettle = d }(adbRecoverySettle)
+	adbRecoverySettle = time.Second
+	env, logPath := newOfflineADBEnv(t, true)
+	procs, err := ListRunning(env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	states := map[string]ProcInfo{}
+	for _, p := range procs {
+		states[p.Serial] = p
+	}
+	if p := states["emulator-5790"]; p.ADBState != ADBStateOnline || !p.Booted {
+		t.Fatalf("emulator-5790 not recovered: %+v", p)
+	}
+	if p := states["emulator-5792"]; p.ADBState != ADBStateUnauthorized || p.Booted {
+		t.Fatalf("emulator-5792 = %+v", p)
+	}
+	b, _ := os.ReadFile(logPath)
+	if !strings.Contains(string(b), "reconnect offline\n") {
+		t.Fatalf("no reconnect issued:\n%s", b)
+	}
+	if strings.Contains(string(b), "-s emulator-5792 shell") {
+		t.Fatalf("shell command sent to an unauthorized device:\n%s", b)
+	}
+}
+
+func TestListRunningReportsDevicesThatStayOffline(t *testing.T) {
+	defer func(d time.Duration) { adbRecoverySettle = d }(adbRecoverySettle)
+	adbRecoverySettle = 300 * time.Millisecond
+	env, logPath := newOfflineADBEnv(t, false)
+	procs, err := ListRunning(env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var offline *ProcInfo
+	for i := range procs {
+		if procs[i].Serial == "emulator-5790" {
+			offline = &procs[i]
+		}
+	}
+	if offline == nil || offline.ADBState != ADBStateOffline || offline.Booted {
+		t.Fatalf("emulator-5790 = %+v", offline)
+	}
+	b, _ := os.ReadFile(logPath)
+	if !strings.Contains(string(b), "-s emulator-5790 reconnect\n") {
+		t.Fatalf("no per-device reconnect:\n%s", b)
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// adoptedFilename, in Env.AVDHome, lists the emulators started outside avdctl that Adopt
+// registered. It lives there rather than in an AVD directory because an adopted AVD (e.g. one
+// of Android Studio) need not be under AVDHome.
+const adoptedFilename = "avdctl-adopted.json"
+
+// AdoptedInstance is an emulator started outside avdctl and registered with Adopt.
+type AdoptedInstance struct {
+	Serial    string    `json:"serial"`
+	Name      string    `json:"name"`
+	AVDPath   string    `json:"avd_path,omitempty"`
+	PID       int       `json:"pid"`
+	LogPath   string    `json:"log_path,omitempty"` // the file the emulator's stdout goes to, if any
+	AdoptedAt time.Time `json:"adopted_at"`
+}
+
+// Adopt registers the emulator on serial, started outside avdctl (e.g. by Android Studio), so
+// ps names it and reports its log, and CleanupOrphans no longer treats it as an orphan even when
+// its AVD is not in Env.AVDHome. The registration lasts as long as that emulator process.
+func Adopt(env Env, serial string) (AdoptedInstance, error) {
+	_, span := startSpan(env, "avd.Adopt", attribute.String("serial", serial))
+	defer span.End()
+	fail := func(err error) (AdoptedInstance, error) {
+		recordSpanError(span, err)
+		return AdoptedInstance{}, err
+	}
+	port, err := consolePort(serial)
+	if err != nil {
+		return fail(err)
+	}
+	pid := findEmulatorPID(port)
+	if pid == 0 {
+		return fail(fmt.Errorf("no emulator process found for %s", serial))
+	}
+	inst := AdoptedInstance{Serial: serial, PID: pid, AdoptedAt: time.Now().UTC(), LogPath: stdoutFile(pid)}
+	if inst.Name = findEmulatorNameFromPID(pid); inst.Name == "" {
+		inst.Name, _ = GetAVDNameFromSerial(env, serial)
+	}
+	if out, err := Console(env, serial, "avd path"); err == nil && strings.TrimSpace(out) != "" {
+		inst.AVDPath = strings.TrimSpace(out)
+	} else if inst.Name != "" {
+		if dir := filepath.Join(env.AVDHome, inst.Name+".avd"); isDir(dir) {
+			inst.AVDPath = dir
+		}
+	}
+	err = updateAdopted(env, func(adopted map[string]AdoptedInstance) { adopted[serial] = inst })
+	if err != nil {
+		return fail(fmt.Errorf("register %s: %w", serial, err))
+	}
+	span.SetAttributes(attribute.String("name", inst.Name), attribute.Int("pid", pid))
+	logEvent(env, "emulator adopted", "serial", serial, "name", inst.Name, "pid", pid, "avd_path", inst.AVDPath)
+	return inst, nil
+}
+
+// ListAdopted returns the adopted emulators that are still running, sorted by serial.
+func ListAdopted(env Env) []AdoptedInstance {
+	adopted := liveAdopted(env)
+	out := make([]AdoptedInstance, 0, len(adopted))
+	for _, inst := range adopted {
+		out = append(out, inst)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Serial < out[j].Serial })
+	return out
+}
+
+// liveAdopted reads the adopted emulators, keeping those whose process still runs on the
+// adopted port; a serial reused by another process is not adopted.
+func liveAdopted(env Env) map[string]AdoptedInstance {
+	adopted := readAdopted(env)
+	for serial, inst := range adopted {
+		port, err := consolePort(serial)
+		if err != nil || findEmulatorPID(port) != inst.PID {
+			delete(adopted, serial)
+		}
+	}
+	return adopted
+}
+
+func readAdopted(env Env) map[string]AdoptedInstance {
+	adopted := map[string]AdoptedInstance{}
+	b, err := os.ReadFile(filepath.Join(env.AVDHome, adoptedFilename))
+	if err == nil {
+		_ = json.Unmarshal(b, &adopted)
+	}
+	return adopted
+}
+
+// updateAdopted applies change to the registry under its lock, dropping entries whose process
+// has exited.
+func updateAdopted(env Env, change func(map[string]AdoptedInstance)) error {
+	path := filepath.Join(env.AVDHome, adoptedFilename)
+	unlock, err := acquireFileLock(env.Context, path+".lock", nil)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	adopted := liveAdopted(env)
+	change(adopted)
+	b, err := json.MarshalIndent(adopted, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(b, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// stdoutFile returns the regular file the standard output of pid is redirected to, if any.
+func stdoutFile(pid int) string {
+	target, err := os.Readlink(filepath.Join("/proc", strconv.Itoa(pid), "fd", "1"))
+	if err != nil || !filepath.IsAbs(target) {
+		return ""
+	}
+	if st, err := os.Stat(target); err != nil || !st.Mode().IsRegular() {
+		return ""
+	}
+	return target
+}
+
+func isDir(path string) bool {
+	st, err := os.Stat(path)
+	return err == nil && st.IsDir()
+}
@@ -0,0 +1,74 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestAdoptKeepsExternalInstanceOutOfOrphans(t *testing.T) {
+	if _, err := os.Stat("/proc/self/stat"); err != nil {
+		t.Skip("needs /proc")
+	}
+	env := newTestEnv(t)
+	logPath := filepath.Join(t.TempDir(), "studio.log")
+	logFile, err := os.Create(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer logFile.Close()
+	// A stand-in for an instance Android Studio started on port 5794, logging to a file.
+	cmd := exec.Command("sh", "-c", "sleep 30; :", "qemu-system-test", "-port", "5794")
+	cmd.Stdout = logFile
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = cmd.Process.Kill(); _ = cmd.Wait() })
+	serial := "emulator-5794"
+
+	report, err := CleanupOrphans(env, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hasOrphan(report, serial) {
+		t.Fatalf("external instance not an orphan before Adopt: %+v", report)
+	}
+	inst, err := Adopt(env, serial)
+	if err != nil {
+		t.Fatalf("Adopt: %v", err)
+	}
+	if inst.PID != cmd.Process.Pid || inst.LogPath != logPath {
+		t.Fatalf("adopted = %+v", inst)
+	}
+	procs, err := ListRunning(env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range procs {
+		if p.Serial == serial && (!p.Adopted || p.LogPath != logPath) {
+			t.Fatalf("ListRunning entry = %+v", p)
+		}
+	}
+	if report, _ := CleanupOrphans(env, false); hasOrphan(report, serial) {
+		t.Fatal("adopted instance reported as an orphan")
+	}
+
+	_ = cmd.Process.Kill()
+	_ = cmd.Wait()
+	if got := ListAdopted(env); len(got) != 0 {
+		t.Fatalf("exited instance still adopted: %+v", got)
+	}
+}
+
+func hasOrphan(report CleanupReport, serial string) bool {
+	for _, p := range report.OrphanedProcesses {
+		if p.Serial == serial {
+			return true
+		}
+	}
+	return false
+}
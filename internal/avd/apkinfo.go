@@ -0,0 +1,283 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"archive/zip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"unicode/utf16"
+)
+
+// APKMetadata is what an APK's AndroidManifest.xml declares about its package. BakeAPK
+// records it in the AVD's Customizations, and so in the manifests of goldens exported from it.
+type APKMetadata struct {
+	Path        string   `json:"path"`
+	Package     string   `json:"package"`
+	Split       string   `json:"split,omitempty"` // name of a configuration or feature split; empty for the base APK
+	VersionCode int64    `json:"version_code,omitempty"`
+	VersionName string   `json:"version_name,omitempty"`
+	MinSDK      int      `json:"min_sdk,omitempty"`
+	TargetSDK   int      `json:"target_sdk,omitempty"`
+	MaxSDK      int      `json:"max_sdk,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
+}
+
+// CompatibleWith returns an error when the APK cannot be installed on API level api: below
+// its minSdkVersion or above its maxSdkVersion. An unknown level (0) is not checked.
+func (m APKMetadata) CompatibleWith(api int) error {
+	if api == 0 {
+		return nil
+	}
+	if m.MinSDK > api {
+		return fmt.Errorf("%s (%s) needs API level %d or newer, the image is API %d", m.Package, m.Path, m.MinSDK, api)
+	}
+	if m.MaxSDK != 0 && m.MaxSDK < api {
+		return fmt.Errorf("%s (%s) supports up to API level %d, the image is API %d", m.Package, m.Path, m.MaxSDK, api)
+	}
+	return nil
+}
+
+// Resource IDs of the android: manifest attributes read by ReadAPKMetadata. Shrunk APKs may
+// strip the attribute names from the string pool, leaving only these.
+const (
+	attrName             = 0x01010003
+	attrMinSdkVersion    = 0x0101020c
+	attrVersionCode      = 0x0101021b
+	attrVersionName      = 0x0101021c
+	attrTargetSdkVersion = 0x01010270
+	attrMaxSdkVersion    = 0x01010271
+)
+
+// Chunk types of Android binary XML (frameworks/base/libs/androidfw/ResourceTypes.h).
+const (
+	axmlStringPool   = 0x0001
+	axmlFile         = 0x0003
+	axmlStartElement = 0x0102
+	axmlResourceMap  = 0x0180
+
+	axmlUTF8Flag      = 1 << 8
+	axmlTypeReference = 0x01
+	axmlTypeString    = 0x03
+	axmlNoEntry       = 0xffffffff
+)
+
+// ReadAPKMetadata parses the binary AndroidManifest.xml of the APK at path.
+func ReadAPKMetadata(path string) (APKMetadata, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return APKMetadata{}, fmt.Errorf("open apk %s: %w", path, err)
+	}
+	defer zr.Close()
+	for _, f := range zr.File {
+		if f.Name != "AndroidManifest.xml" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return APKMetadata{}, fmt.Errorf("open manifest of %s: %w", path, err)
+		}
+		data, err := io.ReadAll(io.LimitReader(rc, 16<<20))
+		rc.Close()
+		if err != nil {
+			return APKMetadata{}, fmt.Errorf("read manifest of %s: %w", path, err)
+		}
+		meta, err := parseBinaryManifest(data)
+		if err != nil {
+			return APKMetadata{}, fmt.Errorf("manifest of %s: %w", path, err)
+		}
+		meta.Path = path
+		return meta, nil
+	}
+	return APKMetadata{}, fmt.Errorf("%s has no AndroidManifest.xml (not an APK?)", path)
+}
+
+// axmlAttr is one attribute of a start element, with its value as a string.
+type axmlAttr struct {
+	name  string
+	resID uint32
+	value string
+}
+
+func parseBinaryManifest(data []byte) (APKMetadata, error) {
+	le := binary.LittleEndian
+	if len(data) < 8 || le.Uint16(data) != axmlFile {
+		return APKMetadata{}, errors.New("not an Android binary XML file")
+	}
+	var (
+		meta   APKMetadata
+		strs   []string
+		resIDs []uint32
+		str    = func(i uint32) string {
+			if int(i) < len(strs) {
+				return strs[i]
+			}
+			return ""
+		}
+	)
+	for off := int(le.Uint16(data[2:])); off+8 <= len(data); {
+		typ, headerSize, size := le.Uint16(data[off:]), int(le.Uint16(data[off+2:])), int(le.Uint32(data[off+4:]))
+		if size < 8 || off+size > len(data) || headerSize > size {
+			return APKMetadata{}, fmt.Errorf("truncated chunk at offset %d", off)
+		}
+		chunk := data[off : off+size]
+		switch typ {
+		case axmlStringPool:
+			pool, err := parseStringPool(chunk)
+			if err != nil {
+				return APKMetadata{}, err
+			}
+			strs = pool
+		case axmlResourceMap:
+			for i := headerSize; i+4 <= size; i += 4 {
+				resIDs = append(resIDs, le.Uint32(chunk[i:]))
+			}
+		case axmlStartElement:
+			if headerSize+20 > size {
+				return APKMetadata{}, fmt.Errorf("truncated element at offset %d", off)
+			}
+			ext := chunk[headerSize:]
+			element := str(le.Uint32(ext[4:]))
+			attrStart, attrSize, attrCount := int(le.Uint16(ext[8:])), int(le.Uint16(ext[10:])), int(le.Uint16(ext[12:]))
+			var attrs []axmlAttr
+			for i := 0; i < attrCount; i++ {
+				a := headerSize + attrStart + i*attrSize
+				if a+20 > size {
+					return APKMetadata{}, fmt.Errorf("truncated attribute in <%s>", element)
+				}
+				nameIdx, raw := le.Uint32(chunk[a+4:]), le.Uint32(chunk[a+8:])
+				dataType, value := chunk[a+15], le.Uint32(chunk[a+16:])
+				attr := axmlAttr{name: str(nameIdx)}
+				if int(nameIdx) < len(resIDs) {
+					attr.resID = resIDs[nameIdx]
+				}
+				switch {
+				case dataType == axmlTypeString:
+					attr.value = str(value)
+				case raw != axmlNoEntry:
+					attr.value = str(raw)
+				case dataType == axmlTypeReference:
+					// @string/... values need resources.arsc; leave them unknown.
+				default:
+					attr.value = strconv.FormatInt(int64(int32(value)), 10)
+				}
+				attrs = append(attrs, attr)
+			}
+			applyManifestElement(&meta, element, attrs)
+		}
+		off += size
+	}
+	if meta.Package == "" {
+		return APKMetadata{}, errors.New("no package name in <manifest>")
+	}
+	return meta, nil
+}
+
+// applyManifestElement copies the attributes of the elements avdctl records into meta.
+func applyManifestElement(meta *APKMetadata, element string, attrs []axmlAttr) {
+	get := func(resID uint32, name string) string {
+		for _, a := range attrs {
+			if (resID != 0 && a.resID == resID) || a.name == name {
+				return a.value
+			}
+		}
+		return ""
+	}
+	switch element {
+	case "manifest":
+		meta.Package = get(0, "package")
+		meta.Split = get(0, "split")
+		meta.VersionCode, _ = strconv.ParseInt(get(attrVersionCode, "versionCode"), 10, 64)
+		meta.VersionName = get(attrVersionName, "versionName")
+	case "uses-sdk":
+		// Preview codenames ("UpsideDownCake") leave the level unknown.
+		meta.MinSDK, _ = strconv.Atoi(get(attrMinSdkVersion, "minSdkVersion"))
+		meta.TargetSDK, _ = strconv.Atoi(get(attrTargetSdkVersion, "targetSdkVersion"))
+		meta.MaxSDK, _ = strconv.Atoi(get(attrMaxSdkVersion, "maxSdkVersion"))
+	case "uses-permission", "uses-permission-sdk-23":
+		if name := get(attrName, "name"); name != "" {
+			meta.Permissions = append(meta.Permissions, name)
+		}
+	}
+}
+
+// parseStringPool decodes a ResStringPool chunk, UTF-8 or UTF-16.
+func parseStringPool(chunk []byte) ([]string, error) {
+	le := binary.LittleEndian
+	if len(chunk) < 28 {
+		return nil, errors.New("truncated string pool")
+	}
+	headerSize := int(le.Uint16(chunk[2:]))
+	count, flags, start := int(le.Uint32(chunk[8:])), le.Uint32(chunk[16:]), int(le.Uint32(chunk[20:]))
+	if headerSize+count*4 > len(chunk) || start > len(chunk) {
+		return nil, errors.New("truncated string pool")
+	}
+	strs := make([]string, count)
+	for i := range strs {
+		p := start + int(le.Uint32(chunk[headerSize+i*4:]))
+		if p >= len(chunk) {
+			return nil, fmt.Errorf("string %d out of range", i)
+		}
+		var ok bool
+		if flags&axmlUTF8Flag != 0 {
+			strs[i], ok = poolUTF8(chunk[p:])
+		} else {
+			strs[i], ok = poolUTF16(chunk[p:])
+		}
+		if !ok {
+			return nil, fmt.Errorf("string %d out of range", i)
+		}
+	}
+	return strs, nil
+}
+
+func poolUTF8(b []byte) (string, bool) {
+	// The UTF-16 length comes first, then the byte length; each is 1 or 2 bytes.
+	skip := func(b []byte) (int, int, bool) {
+		if len(b) < 1 {
+			return 0, 0, false
+		}
+		if b[0]&0x80 == 0 {
+			return int(b[0]), 1, true
+		}
+		if len(b) < 2 {
+			return 0, 0, false
+		}
+		return int(b[0]&0x7f)<<8 | int(b[1]), 2, true
+	}
+	_, n1, ok := skip(b)
+	if !ok {
+		return "", false
+	}
+	length, n2, ok := skip(b[n1:])
+	if !ok || n1+n2+length > len(b) {
+		return "", false
+	}
+	return string(b[n1+n2 : n1+n2+length]), true
+}
+
+func poolUTF16(b []byte) (string, bool) {
+	le := binary.LittleEndian
+	if len(b) < 2 {
+		return "", false
+	}
+	length, p := int(le.Uint16(b)), 2
+	if length&0x8000 != 0 {
+		if len(b) < 4 {
+			return "", false
+		}
+		length, p = (length&0x7fff)<<16|int(le.Uint16(b[2:])), 4
+	}
+	if p+length*2 > len(b) {
+		return "", false
+	}
+	units := make([]uint16, length)
+	for i := range units {
+		units[i] = le.Uint16(b[p+i*2:])
+	}
+	return string(utf16.Decode(units)), true
+}
@@ -0,0 +1,215 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"archive/zip"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf16"
+)
+
+// testElement is a start element of a binary manifest; attribute values that parse as
+// integers are encoded as TYPE_INT_DEC, the others as strings.
+type testElement struct {
+	name  string
+	attrs [][2]string
+}
+
+// buildBinaryManifest encodes elements as Android binary XML the way aapt2 does: android:
+// attribute names first in the string pool, matched by the resource map.
+func buildBinaryManifest(t *testing.T, utf8Pool bool, elements []testElement) []byte {
+	t.Helper()
+	resIDs := map[string]uint32{
+		"name": attrName, "minSdkVersion": attrMinSdkVersion, "versionCode": attrVersionCode,
+		"versionName": attrVersionName, "targetSdkVersion": attrTargetSdkVersion, "maxSdkVersion": attrMaxSdkVersion,
+	}
+	var strs []string
+	var resMap []uint32
+	index := func(s string) uint32 {
+		if i := slices.Index(strs, s); i >= 0 {
+			return uint32(i)
+		}
+		strs = append(strs, s)
+		return uint32(len(strs) - 1)
+	}
+	for _, name := range []string{"name", "minSdkVersion", "versionCode", "versionName", "targetSdkVersion", "maxSdkVersion"} {
+		index(name)
+		resMap = append(resMap, resIDs[name])
+	}
+	le := binary.LittleEndian
+	var body []byte
+	for _, e := range elements {
+		attrs := make([]byte, 0, 20*len(e.attrs))
+		for _, a := range e.attrs {
+			attr := make([]byte, 20)
+			le.PutUint32(attr[0:], axmlNoEntry)
+			le.PutUint32(attr[4:], index(a[0]))
+			le.PutUint16(attr[12:], 8)
+			if n, err := strconv.ParseUint(a[1], 10, 32); err == nil {
+				le.PutUint32(attr[8:], axmlNoEntry)
+				attr[15] = 0x10
+				le.PutUint32(attr[16:], uint32(n))
+			} else {
+				v := index(a[1])
+				le.PutUint32(attr[8:], v)
+				attr[15] = axmlTypeString
+				le.PutUint32(attr[16:], v)
+			}
+			attrs = append(attrs, attr...)
+		}
+		chunk := make([]byte, 36, 36+len(attrs))
+		le.PutUint16(chunk[0:], axmlStartElement)
+		le.PutUint16(chunk[2:], 16)
+		le.PutUint32(chunk[4:], uint32(36+len(attrs)))
+		le.PutUint32(chunk[16:], axmlNoEntry)
+		le.PutUint32(chunk[20:], index(e.name))
+		le.PutUint16(chunk[24:], 20)
+		le.PutUint16(chunk[26:], 20)
+		le.PutUint16(chunk[28:], uint16(len(e.attrs)))
+		body = append(body, append(chunk, attrs...)...)
+	}
+
+	var data []byte
+	offsets := make([]byte, 4*len(strs))
+	for i, s := range strs {
+		le.PutUint32(offsets[4*i:], uint32(len(data)))
+		if utf8Pool {
+			data = append(data, byte(len(utf16.Encode([]rune(s)))), byte(len(s)))
+			data = append(data, s...)
+			data = append(data, 0)
+			continue
+		}
+		units := utf16.Encode([]rune(s))
+		data = le.AppendUint16(data, uint16(len(units)))
+		for _, u := range units {
+			data = le.AppendUint16(data, u)
+		}
+		data = le.AppendUint16(data, 0)
+	}
+	for len(data)%4 != 0 {
+		data = append(data, 0)
+	}
+	pool := make([]byte, 28)
+	le.PutUint16(pool[0:], axmlStringPool)
+	le.PutUint16(pool[2:], 28)
+	le.PutUint32(pool[4:], uint32(28+len(offsets)+len(data)))
+	le.PutUint32(pool[8:], uint32(len(strs)))
+	if utf8Pool {
+		le.PutUint32(pool[16:], axmlUTF8Flag)
+	}
+	le.PutUint32(pool[20:], uint32(28+len(offsets)))
+	pool = append(append(pool, offsets...), data...)
+
+	resChunk := make([]byte, 8, 8+4*len(resMap))
+	le.PutUint16(resChunk[0:], axmlResourceMap)
+	le.PutUint16(resChunk[2:], 8)
+	le.PutUint32(resChunk[4:], uint32(8+4*len(resMap)))
+	for _, id := range resMap {
+		resChunk = le.AppendUint32(resChunk, id)
+	}
+
+	out := make([]byte, 8)
+	le.PutUint16(out[0:], axmlFile)
+	le.PutUint16(out[2:], 8)
+	out = append(append(append(out, pool...), resChunk...), body...)
+	le.PutUint32(out[4:], uint32(len(out)))
+	return out
+}
+
+func writeTestAPK(t *testing.T, path string, manifest []byte) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("create apk: %v", err)
+	}
+	zw := zip.NewWriter(f)
+	w, err := zw.Create("AndroidManifest.xml")
+	if err == nil {
+		_, err = w.Write(manifest)
+	}
+	if err == nil {
+		err = zw.Close()
+	}
+	if err != nil {
+		t.Fatalf("write apk: %v", err)
+	}
+	_ = f.Close()
+}
+
+func acmeManifest(t *testing.T, utf8Pool bool, minSDK string) []byte {
+	return buildBinaryManifest(t, utf8Pool, []testElement{
+		{"manifest", [][2]string{{"versionCode", "4210"}, {"versionName", "4.2.1"}, {"package", "com.acme.wallet"}}},
+		{"uses-sdk", [][2]string{{"minSdkVersion", minSDK}, {"targetSdkVersion", "34"}}},
+		{"uses-permission", [][2]string{{"name", "android.permission.INTERNET"}}},
+		{"uses-permission", [][2]string{{"name", "android.permission.CAMERA"}}},
+		{"application", [][2]string{{"name", "com.acme.wallet.App"}}},
+	})
+}
+
+func TestReadAPKMetadata(t *testing.T) {
+	for _, utf8Pool := range []bool{false, true} {
+		path := filepath.Join(t.TempDir(), "wallet.apk")
+		writeTestAPK(t, path, acmeManifest(t, utf8Pool, "26"))
+		meta, err := ReadAPKMetadata(path)
+		if err != nil {
+			t.Fatalf("ReadAPKMetadata(utf8=%v): %v", utf8Pool, err)
+		}
+		want := APKMetadata{
+			Path: path, Package: "com.acme.wallet", VersionCode: 4210, VersionName: "4.2.1", MinSDK: 26, TargetSDK: 34,
+			Permissions: []string{"android.permission.INTERNET", "android.permission.CAMERA"},
+		}
+		if meta.Package != want.Package || meta.VersionCode != want.VersionCode || meta.VersionName != want.VersionName ||
+			meta.MinSDK != want.MinSDK || meta.TargetSDK != want.TargetSDK || !slices.Equal(meta.Permissions, want.Permissions) {
+			t.Fatalf("metadata(utf8=%v) = %+v, want %+v", utf8Pool, meta, want)
+		}
+		if err := meta.CompatibleWith(25); err == nil || !strings.Contains(err.Error(), "API level 26") {
+			t.Fatalf("expected an API level error, got %v", err)
+		}
+		if err := meta.CompatibleWith(34); err != nil {
+			t.Fatalf("CompatibleWith(34): %v", err)
+		}
+	}
+
+	notAPK := filepath.Join(t.TempDir(), "notes.apk")
+	if err := os.WriteFile(notAPK, []byte("not a zip"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadAPKMetadata(notAPK); err == nil {
+		t.Fatal("expected an error for a file that is not an APK")
+	}
+}
+
+func TestBakeAPKRefusesIncompatibleAPK(t *testing.T) {
+	env := newTestEnv(t)
+	makeBaseAVD(t, env, "base-a30")
+	cfg := filepath.Join(env.AVDHome, "base-a30.avd", "config.ini")
+	if err := os.WriteFile(cfg, []byte("image.sysdir.1=system-images/android-30/google_apis/x86_64/\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	apk := filepath.Join(t.TempDir(), "wallet.apk")
+	writeTestAPK(t, apk, acmeManifest(t, false, "33"))
+
+	_, _, err := BakeAPK(env, "base-a30", "w-baked", makeGoldenDir(t), []string{apk}, time.Minute)
+	if err == nil || !strings.Contains(err.Error(), "needs API level 33 or newer, the image is API 30") {
+		t.Fatalf("expected an API level refusal, got %v", err)
+	}
+	if _, statErr := os.Stat(filepath.Join(env.AVDHome, "w-baked.avd")); !os.IsNotExist(statErr) {
+		t.Fatalf("the clone was created before the APK check: %v", statErr)
+	}
+}
+
+func TestCustomizationsMergePackages(t *testing.T) {
+	old := Customizations{Packages: []APKMetadata{{Package: "com.acme.wallet", VersionCode: 1}, {Package: "com.acme.maps"}}}
+	merged := old.merge(Customizations{Packages: []APKMetadata{{Package: "com.acme.wallet", VersionCode: 2}}})
+	if len(merged.Packages) != 2 || merged.Packages[0].VersionCode != 2 || old.Packages[0].VersionCode != 1 {
+		t.Fatalf("merged packages = %+v (old %+v)", merged.Packages, old.Packages)
+	}
+}
@@ -0,0 +1,281 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// APKFile is what an APKValidator looks at: one APK, one split of a split set, or an .aab.
+type APKFile struct {
+	Path     string
+	SHA256   string      // hex digest of the file
+	Metadata APKMetadata // zero for .aab bundles
+}
+
+// APKValidator vets customer-supplied apps before they are installed on any AVD or baked into
+// a golden (Env.APKValidators). A non-nil error refuses the install; every verdict is logged
+// and written to the audit log.
+type APKValidator interface {
+	Name() string
+	Validate(ctx context.Context, apk APKFile) error
+}
+
+// SignatureValidator accepts APKs whose signature verifies with apksigner and whose signer
+// certificate is one of Certs. An .aab carries no APK signature, and its splits are signed by
+// bundletool at install; deliver an .apks built with the customer key to pin its certificate.
+type SignatureValidator struct {
+	Certs     []string // SHA-256 digests of the accepted signing certificates (hex, colons allowed)
+	APKSigner string   // apksigner from build-tools (default "apksigner")
+}
+
+func (v SignatureValidator) Name() string { return "signature" }
+
+func (v SignatureValidator) Validate(ctx context.Context, apk APKFile) error {
+	bin := v.APKSigner
+	if bin == "" {
+		bin = "apksigner"
+	}
+	out, err := runCommandCombinedOutputWithEnv(ctx, nil, nil, bin, "verify", "--print-certs", apk.Path)
+	if err != nil {
+		return fmt.Errorf("signature does not verify: %w: %s", err, firstLine(strings.TrimSpace(string(out))))
+	}
+	var signers []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if _, digest, ok := strings.Cut(line, "certificate SHA-256 digest: "); ok {
+			signers = append(signers, normalizeDigest(digest))
+		}
+	}
+	if len(signers) == 0 {
+		return errors.New("apksigner reported no signer certificate")
+	}
+	for _, cert := range v.Certs {
+		if slices.Contains(signers, normalizeDigest(cert)) {
+			return nil
+		}
+	}
+	return fmt.Errorf("signed by %s, not by an expected certificate", strings.Join(signers, ", "))
+}
+
+// HashListValidator checks the SHA-256 of each file against a list, one hex digest per line
+// (text after it and # comments are ignored). The file is re-read on every check, so it can
+// be updated while avdctl runs. With Allow, only listed files pass; otherwise listed files
+// are refused.
+type HashListValidator struct {
+	Path  string
+	Allow bool
+}
+
+func (v HashListValidator) Name() string {
+	if v.Allow {
+		return "hash-allowlist"
+	}
+	return "hash-denylist"
+}
+
+func (v HashListValidator) Validate(_ context.Context, apk APKFile) error {
+	f, err := os.Open(v.Path)
+	if err != nil {
+		return fmt.Errorf("hash list: %w", err)
+	}
+	defer f.Close()
+	listed := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(strings.SplitN(scanner.Text(), "#", 2)[0])
+		if len(fields) > 0 && normalizeDigest(fields[0]) == apk.SHA256 {
+			listed = true
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("hash list: %w", err)
+	}
+	switch {
+	case v.Allow && !listed:
+		return fmt.Errorf("sha256 %s is not in the allowlist %s", apk.SHA256, v.Path)
+	case !v.Allow && listed:
+		return fmt.Errorf("sha256 %s is in the denylist %s", apk.SHA256, v.Path)
+	}
+	return nil
+}
+
+// DefaultVirusTotalURL is the VirusTotal v3 API.
+const DefaultVirusTotalURL = "https://www.virustotal.com/api/v3"
+
+// VirusTotalValidator looks the SHA-256 of each file up on VirusTotal. Files are never
+// uploaded: customer binaries stay private, so a file VirusTotal has not seen passes unless
+// RequireKnown is set. Lookup failures refuse the install.
+type VirusTotalValidator struct {
+	APIKey       string
+	MaxMalicious int  // engines allowed to flag the file as malicious (default 0)
+	RequireKnown bool // refuse files unknown to VirusTotal
+	BaseURL      string
+	Client       *http.Client
+}
+
+func (v VirusTotalValidator) Name() string { return "virustotal" }
+
+func (v VirusTotalValidator) Validate(ctx context.Context, apk APKFile) error {
+	base := v.BaseURL
+	if base == "" {
+		base = DefaultVirusTotalURL
+	}
+	client := v.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(base, "/")+"/files/"+apk.SHA256, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("x-apikey", v.APIKey)
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("virustotal lookup: %w", err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		if v.RequireKnown {
+			return fmt.Errorf("sha256 %s is unknown to VirusTotal", apk.SHA256)
+		}
+		return nil
+	default:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("virustotal lookup: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	var report struct {
+		Data struct {
+			Attributes struct {
+				Stats struct {
+					Malicious  int `json:"malicious"`
+					Suspicious int `json:"suspicious"`
+				} `json:"last_analysis_stats"`
+			} `json:"attributes"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		return fmt.Errorf("virustotal report: %w", err)
+	}
+	if stats := report.Data.Attributes.Stats; stats.Malicious > v.MaxMalicious {
+		return fmt.Errorf("flagged malicious by %d VirusTotal engines (%d suspicious)", stats.Malicious, stats.Suspicious)
+	}
+	return nil
+}
+
+// vetAPKSet runs Env.APKValidators on the files of set: the APKs, or the bundle itself for an
+// .aab, whose splits are only generated here.
+func vetAPKSet(env Env, set apkSet) error {
+	if len(env.APKValidators) == 0 {
+		return nil
+	}
+	files := set.APKs
+	if strings.EqualFold(filepath.Ext(set.Source), ".aab") {
+		files = []string{set.Source}
+	}
+	for _, path := range files {
+		apk := APKFile{Path: path}
+		sum, err := fileSHA256(path)
+		if err != nil {
+			return err
+		}
+		apk.SHA256 = sum
+		if !strings.EqualFold(filepath.Ext(path), ".aab") {
+			apk.Metadata, _ = ReadAPKMetadata(path)
+		}
+		for _, v := range env.APKValidators {
+			verr := v.Validate(spanContext(env), apk)
+			ev := AuditEvent{
+				Action:  "apk-validation",
+				Subject: set.Source,
+				Outcome: "allowed",
+				Detail: map[string]string{
+					"validator": v.Name(), "file": filepath.Base(path), "sha256": apk.SHA256,
+					"package": apk.Metadata.Package, "version": apk.Metadata.VersionName,
+				},
+			}
+			if verr != nil {
+				ev.Outcome = "denied"
+				ev.Detail["reason"] = verr.Error()
+			}
+			logEvent(env, "apk validated", "apk", path, "validator", v.Name(), "outcome", ev.Outcome, "sha256", apk.SHA256)
+			if err := recordAudit(env, ev); err != nil {
+				return err
+			}
+			if verr != nil {
+				return fmt.Errorf("%s refused by the %s validator: %w", path, v.Name(), verr)
+			}
+		}
+	}
+	return nil
+}
+
+// detectAPKValidators builds the validators configured by environment variables.
+func detectAPKValidators(sdkRoot string) []APKValidator {
+	var validators []APKValidator
+	if path := os.Getenv("AVDCTL_APK_ALLOWLIST"); path != "" {
+		validators = append(validators, HashListValidator{Path: path, Allow: true})
+	}
+	if path := os.Getenv("AVDCTL_APK_DENYLIST"); path != "" {
+		validators = append(validators, HashListValidator{Path: path})
+	}
+	if certs := envList("AVDCTL_APK_CERTS"); len(certs) > 0 {
+		validators = append(validators, SignatureValidator{Certs: certs, APKSigner: findAPKSigner(sdkRoot)})
+	}
+	if key := os.Getenv("AVDCTL_VIRUSTOTAL_API_KEY"); key != "" {
+		validators = append(validators, VirusTotalValidator{APIKey: key, RequireKnown: envBool("AVDCTL_VIRUSTOTAL_REQUIRE_KNOWN")})
+	}
+	return validators
+}
+
+// findAPKSigner returns apksigner from the newest build-tools of sdkRoot, or "apksigner".
+func findAPKSigner(sdkRoot string) string {
+	matches, _ := filepath.Glob(filepath.Join(sdkRoot, "build-tools", "*", "apksigner"))
+	if sdkRoot == "" || len(matches) == 0 {
+		return "apksigner"
+	}
+	slices.SortFunc(matches, func(a, b string) int {
+		return compareBuildTools(filepath.Base(filepath.Dir(a)), filepath.Base(filepath.Dir(b)))
+	})
+	return matches[len(matches)-1]
+}
+
+// compareBuildTools orders build-tools revisions such as "34.0.0" and "35.0.0-rc1" numerically.
+func compareBuildTools(a, b string) int {
+	as, bs := strings.FieldsFunc(a, isRevisionSep), strings.FieldsFunc(b, isRevisionSep)
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aerr := strconv.Atoi(as[i])
+		bn, berr := strconv.Atoi(bs[i])
+		if aerr != nil || berr != nil {
+			if c := strings.Compare(as[i], bs[i]); c != 0 {
+				return c
+			}
+			continue
+		}
+		if an != bn {
+			return an - bn
+		}
+	}
+	return len(as) - len(bs)
+}
+
+func isRevisionSep(r rune) bool { return r == '.' || r == '-' }
+
+func normalizeDigest(s string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(s), ":", ""))
+}
@@ -0,0 +1,145 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSignatureValidator(t *testing.T) {
+	signer := filepath.Join(t.TempDir(), "apksigner")
+	script := "#!/bin/sh\necho 'Signer #1 certificate DN: CN=Acme'\necho 'Signer #1 certificate SHA-256 digest: 0a1b2c3d'\n"
+	if err := os.WriteFile(signer, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	apk := APKFile{Path: "/srv/app.apk"}
+	if err := (SignatureValidator{Certs: []string{"0A:1B:2C:3D"}, APKSigner: signer}).Validate(context.Background(), apk); err != nil {
+		t.Fatalf("expected signer to be accepted: %v", err)
+	}
+	err := SignatureValidator{Certs: []string{"ffff"}, APKSigner: signer}.Validate(context.Background(), apk)
+	if err == nil || !strings.Contains(err.Error(), "0a1b2c3d") {
+		t.Fatalf("expected an unexpected-signer error, got %v", err)
+	}
+	if err := os.WriteFile(signer, []byte("#!/bin/sh\necho 'DOES NOT VERIFY'\nexit 1\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	err = SignatureValidator{Certs: []string{"0a1b2c3d"}, APKSigner: signer}.Validate(context.Background(), apk)
+	if err == nil || !strings.Contains(err.Error(), "DOES NOT VERIFY") {
+		t.Fatalf("expected a verification error, got %v", err)
+	}
+}
+
+func TestHashListValidator(t *testing.T) {
+	list := filepath.Join(t.TempDir(), "hashes.txt")
+	if err := os.WriteFile(list, []byte("# known bad\nAABB  dropper.apk\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	listed, other := APKFile{SHA256: "aabb"}, APKFile{SHA256: "ccdd"}
+	deny, allow := HashListValidator{Path: list}, HashListValidator{Path: list, Allow: true}
+	if deny.Validate(context.Background(), listed) == nil || deny.Validate(context.Background(), other) != nil {
+		t.Fatal("denylist should refuse only the listed hash")
+	}
+	if allow.Validate(context.Background(), listed) != nil || allow.Validate(context.Background(), other) == nil {
+		t.Fatal("allowlist should accept only the listed hash")
+	}
+}
+
+func TestVirusTotalValidator(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-apikey") != "k" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/files/bad":
+			_, _ = w.Write([]byte(`{"data":{"attributes":{"last_analysis_stats":{"malicious":3,"suspicious":1}}}}`))
+		case "/files/clean":
+			_, _ = w.Write([]byte(`{"data":{"attributes":{"last_analysis_stats":{"malicious":0}}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	v := VirusTotalValidator{APIKey: "k", BaseURL: srv.URL}
+	ctx := context.Background()
+	if err := v.Validate(ctx, APKFile{SHA256: "bad"}); err == nil || !strings.Contains(err.Error(), "3 VirusTotal engines") {
+		t.Fatalf("expected a malicious verdict, got %v", err)
+	}
+	if err := v.Validate(ctx, APKFile{SHA256: "clean"}); err != nil {
+		t.Fatalf("clean file refused: %v", err)
+	}
+	if err := v.Validate(ctx, APKFile{SHA256: "new"}); err != nil {
+		t.Fatalf("unknown file refused without RequireKnown: %v", err)
+	}
+	v.RequireKnown = true
+	if err := v.Validate(ctx, APKFile{SHA256: "new"}); err == nil {
+		t.Fatal("expected unknown file to be refused with RequireKnown")
+	}
+	v.MaxMalicious, v.APIKey = 5, "wrong"
+	if err := v.Validate(ctx, APKFile{SHA256: "clean"}); err == nil {
+		t.Fatal("expected a failed lookup to refuse the file")
+	}
+}
+
+func TestPrepareAPKSetRecordsValidationInAuditLog(t *testing.T) {
+	env := newTestEnv(t)
+	dir := t.TempDir()
+	apk := filepath.Join(dir, "acme.apk")
+	writeTestAPK(t, apk, acmeManifest(t, true, "24"))
+	sum, err := fileSHA256(apk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list := filepath.Join(dir, "deny.txt")
+	if err := os.WriteFile(list, []byte("\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	env.APKValidators = []APKValidator{HashListValidator{Path: list}}
+	env.AuditLog = filepath.Join(dir, "audit", "avdctl.jsonl")
+	env.CorrelationID = "wf-7"
+
+	set, err := prepareAPKSet(env, apk, nil)
+	if err != nil {
+		t.Fatalf("prepareAPKSet(allowed): %v", err)
+	}
+	set.cleanup()
+	if err := os.WriteFile(list, []byte(sum+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := prepareAPKSet(env, apk, nil); err == nil || !strings.Contains(err.Error(), "hash-denylist") {
+		t.Fatalf("expected the denylist to refuse %s, got %v", apk, err)
+	}
+
+	data, err := os.ReadFile(env.AuditLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("audit log has %d lines, want 2:\n%s", len(lines), data)
+	}
+	var events []AuditEvent
+	for _, line := range lines {
+		var ev AuditEvent
+		if err := json.Unmarshal([]byte(line), &ev); err != nil {
+			t.Fatalf("audit line %q: %v", line, err)
+		}
+		events = append(events, ev)
+	}
+	if events[0].Outcome != "allowed" || events[1].Outcome != "denied" {
+		t.Fatalf("outcomes = %q, %q", events[0].Outcome, events[1].Outcome)
+	}
+	ev := events[1]
+	if ev.Action != "apk-validation" || ev.Subject != apk || ev.CorrelationID != "wf-7" ||
+		ev.Detail["sha256"] != sum || ev.Detail["package"] != "com.acme.wallet" || ev.Detail["reason"] == "" {
+		t.Fatalf("unexpected audit event %+v", ev)
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// AppReady is what WaitForApp saw once the app was up.
+type AppReady struct {
+	Package  string        `json:"package"`
+	Activity string        `json:"activity"` // launched component, e.g. com.acme.wallet/.MainActivity
+	PID      int           `json:"pid"`
+	Duration time.Duration `json:"duration"` // from the launch to the first drawn window
+}
+
+// appPollInterval is how often WaitForApp looks at the process and the window.
+const appPollInterval = 500 * time.Millisecond
+
+// WaitForApp launches the launcher activity of pkg on a booted instance and waits until its
+// process runs and one of its windows has been drawn (dumpsys window), without any
+// instrumentation in the app. A crash or ANR dialog for pkg ends the wait early.
+func WaitForApp(env Env, serial, pkg string, timeout time.Duration) (AppReady, error) {
+	_, span := startSpan(env, "avd.WaitForApp", attribute.String("serial", serial), attribute.String("package", pkg))
+	defer span.End()
+	fail := func(err error) (AppReady, error) {
+		recordSpanError(span, err)
+		return AppReady{}, err
+	}
+	if !packageRe.MatchString(pkg) {
+		return fail(fmt.Errorf("invalid package %q", pkg))
+	}
+	if timeout <= 0 {
+		timeout = time.Minute
+	}
+	ctx := env.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	adb := func(args ...string) (string, error) {
+		out, errOut, err := runCommandOutputWithEnv(ctx, nil, nil, env.ADB, append([]string{"-s", serial, "shell"}, args...)...)
+		if err != nil {
+			return out, fmt.Errorf("%w: %s", err, strings.TrimSpace(out+errOut))
+		}
+		return out, nil
+	}
+
+	out, err := adb("cmd", "package", "resolve-activity", "--brief", "-c", "android.intent.category.LAUNCHER", "-a", "android.intent.action.MAIN", pkg)
+	if err != nil {
+		return fail(fmt.Errorf("resolve launcher activity of %s on %s: %w", pkg, serial, err))
+	}
+	component := lastLine(out)
+	if !strings.HasPrefix(component, pkg+"/") {
+		return fail(fmt.Errorf("%s has no launcher activity on %s (is it installed?)", pkg, serial))
+	}
+	start := time.Now()
+	if out, err := adb("am", "start", "-n", component); err != nil || strings.Contains(out, "Error:") {
+		if err == nil {
+			err = fmt.Errorf("%s", strings.TrimSpace(out))
+		}
+		return fail(fmt.Errorf("launch %s on %s: %w", component, serial, err))
+	}
+
+	ready := AppReady{Package: pkg, Activity: component}
+	deadline := start.Add(timeout)
+	last := "process not started"
+	for {
+		// pidof exits 1 while the process is not up.
+		out, _, _ := runCommandOutputWithEnv(ctx, nil, nil, env.ADB, "-s", serial, "shell", "pidof", pkg)
+		ready.PID = 0
+		if fields := strings.Fields(out); len(fields) > 0 {
+			ready.PID, _ = strconv.Atoi(fields[0])
+		}
+		if ready.PID > 0 {
+			windows, err := adb("dumpsys", "window", "windows")
+			if err != nil {
+				last = err.Error()
+			} else if title := appErrorWindow(windows, pkg); title != "" {
+				return fail(fmt.Errorf("%s on %s: %s", pkg, serial, title))
+			} else if appWindowDrawn(windows, pkg) {
+				ready.Duration = time.Since(start)
+				span.SetAttributes(attribute.String("ready_after", ready.Duration.String()), attribute.Int("pid", ready.PID))
+				logEvent(env, "app ready", "serial", serial, "package", pkg, "activity", component, "pid", ready.PID, "duration", ready.Duration.String())
+				return ready, nil
+			} else {
+				last = "no window drawn yet"
+			}
+		}
+		if !time.Now().Add(appPollInterval).Before(deadline) {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return fail(ctx.Err())
+		case <-time.After(appPollInterval):
+		}
+	}
+	return fail(fmt.Errorf("%s on %s not ready within %s (%s)", pkg, serial, timeout.Round(time.Second), last))
+}
+
+// appWindows splits `dumpsys window windows` into the blocks of each window, keyed by their
+// header line, e.g. "Window #4 Window{9c1f u0 com.acme.wallet/com.acme.wallet.MainActivity}:".
+func appWindows(dump string) map[string]string {
+	windows := map[string]string{}
+	header := ""
+	var block strings.Builder
+	flush := func() {
+		if header != "" {
+			windows[header] = block.String()
+		}
+		block.Reset()
+	}
+	for _, line := range strings.Split(dump, "\n") {
+		if trimmed := strings.TrimSpace(line); strings.HasPrefix(trimmed, "Window #") {
+			flush()
+			header = trimmed
+			continue
+		}
+		block.WriteString(line)
+		block.WriteByte('\n')
+	}
+	flush()
+	return windows
+}
+
+// appWindowDrawn reports whether a window of pkg has finished its first draw.
+func appWindowDrawn(dump, pkg string) bool {
+	for header, block := range appWindows(dump) {
+		if strings.Contains(header, " "+pkg+"/") && strings.Contains(block, "mDrawState=HAS_DRAWN") {
+			return true
+		}
+	}
+	return false
+}
+
+// appErrorWindow returns the title of a crash or ANR dialog shown for pkg, if any.
+func appErrorWindow(dump, pkg string) string {
+	for header := range appWindows(dump) {
+		for _, title := range []string{"Application Error: " + pkg, "Application Not Responding: " + pkg} {
+			if strings.Contains(header, title+"}") {
+				return title
+			}
+		}
+	}
+	return ""
+}
+
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
@@ -0,0 +1,80 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const appWindowsDump = `WINDOW MANAGER WINDOWS (dumpsys window windows)
+  Window #1 Window{4e2d u0 com.android.systemui/StatusBar}:
+    mDrawState=HAS_DRAWN
+  Window #2 Window{9c1f u0 com.acme.wallet/com.acme.wallet.MainActivity}:
+    mHasSurface=true
+    mDrawState=%s
+`
+
+// appADB installs an adb stub where the app process shows up on the second pidof and its
+// window reports drawState.
+func appADB(t *testing.T, env Env, drawState string) string {
+	t.Helper()
+	dir := t.TempDir()
+	logPath, polls := filepath.Join(dir, "adb.log"), filepath.Join(dir, "polls")
+	dump := filepath.Join(dir, "windows.txt")
+	if err := os.WriteFile(dump, []byte(strings.Replace(appWindowsDump, "%s", drawState, 1)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	script := `#!/bin/sh
+echo "$@" >> ` + logPath + `
+case "$4" in
+  cmd) echo "priority=0 preferredOrder=0 match=0x108000 specificIndex=-1 isDefault=true"; echo "com.acme.wallet/.MainActivity" ;;
+  am) echo "Starting: Intent { cmp=com.acme.wallet/.MainActivity }" ;;
+  pidof) echo x >> ` + polls + `; [ "$(wc -l < ` + polls + `)" -ge 2 ] && echo 4242 || exit 1 ;;
+  dumpsys) cat ` + dump + ` ;;
+esac
+`
+	if err := os.WriteFile(env.ADB, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return logPath
+}
+
+func TestWaitForApp(t *testing.T) {
+	env := newTestEnv(t)
+	logPath := appADB(t, env, "HAS_DRAWN")
+	ready, err := WaitForApp(env, "emulator-5596", "com.acme.wallet", 10*time.Second)
+	if err != nil {
+		t.Fatalf("WaitForApp: %v", err)
+	}
+	if ready.PID != 4242 || ready.Activity != "com.acme.wallet/.MainActivity" || ready.Duration <= 0 {
+		t.Fatalf("ready = %+v", ready)
+	}
+	calls, _ := os.ReadFile(logPath)
+	if !strings.Contains(string(calls), "shell am start -n com.acme.wallet/.MainActivity") {
+		t.Fatalf("app not launched:\n%s", calls)
+	}
+}
+
+func TestWaitForAppTimesOutBeforeFirstFrame(t *testing.T) {
+	env := newTestEnv(t)
+	appADB(t, env, "DRAW_PENDING")
+	_, err := WaitForApp(env, "emulator-5596", "com.acme.wallet", 1500*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "no window drawn yet") {
+		t.Fatalf("expected a timeout waiting for the first frame, got %v", err)
+	}
+}
+
+func TestAppErrorWindow(t *testing.T) {
+	dump := "  Window #3 Window{77aa u0 Application Error: com.acme.wallet}:\n    mDrawState=HAS_DRAWN\n"
+	if got := appErrorWindow(dump, "com.acme.wallet"); got != "Application Error: com.acme.wallet" {
+		t.Fatalf("appErrorWindow = %q", got)
+	}
+	if appErrorWindow(dump, "com.acme") != "" || appWindowDrawn(dump, "com.acme.wallet") {
+		t.Fatal("crash dialog mistaken for another package or for the app window")
+	}
+}
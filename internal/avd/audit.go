@@ -0,0 +1,58 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// AuditEvent is one line of the audit log (Env.AuditLog): a decision avdctl took about
+// something supplied from outside, such as an APK about to be installed.
+type AuditEvent struct {
+	Time          time.Time         `json:"time"`
+	Action        string            `json:"action"`  // e.g. "apk-validation"
+	Subject       string            `json:"subject"` // e.g. the APK path
+	Outcome       string            `json:"outcome"` // e.g. "allowed", "denied"
+	Detail        map[string]string `json:"detail,omitempty"`
+	CorrelationID string            `json:"correlation_id,omitempty"`
+}
+
+var auditMu sync.Mutex
+
+// recordAudit appends ev to Env.AuditLog as a JSON line, and does nothing without one. The
+// log is opened in append mode, so several avdctl processes can share it.
+func recordAudit(env Env, ev AuditEvent) error {
+	if env.AuditLog == "" {
+		return nil
+	}
+	if ev.Time.IsZero() {
+		ev.Time = time.Now().UTC()
+	}
+	if ev.CorrelationID == "" {
+		ev.CorrelationID = env.CorrelationID
+	}
+	b, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	auditMu.Lock()
+	defer auditMu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(env.AuditLog), 0o755); err != nil {
+		return fmt.Errorf("audit log: %w", err)
+	}
+	f, err := os.OpenFile(env.AuditLog, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("audit log: %w", err)
+	}
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("audit log: %w", err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,76 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// I/O scheduling classes of ioprio_set(2).
+const (
+	ioClassBestEffort = 2
+	ioClassIdle       = 3
+)
+
+// parseIOPriority parses Env.IOPriority: "idle", or "best-effort" with an optional level
+// ("best-effort:7", 0 = highest, 7 = lowest; default 7).
+func parseIOPriority(s string) (class, level int, err error) {
+	name, levelStr, hasLevel := strings.Cut(strings.ToLower(strings.TrimSpace(s)), ":")
+	switch name {
+	case "idle":
+		if hasLevel {
+			return 0, 0, fmt.Errorf("invalid I/O priority %q: idle takes no level", s)
+		}
+		return ioClassIdle, 0, nil
+	case "best-effort", "be":
+		level = 7
+		if hasLevel {
+			level, err = strconv.Atoi(levelStr)
+			if err != nil || level < 0 || level > 7 {
+				return 0, 0, fmt.Errorf("invalid I/O priority %q: level must be 0-7", s)
+			}
+		}
+		return ioClassBestEffort, level, nil
+	}
+	return 0, 0, fmt.Errorf("invalid I/O priority %q (want idle or best-effort[:0-7])", s)
+}
+
+// enterBackgroundIO gives the calling goroutine Env.IOPriority for the rest of its life: it is
+// locked to its OS thread, whose I/O priority is lowered, and the thread exits with the
+// goroutine. Processes it starts inherit the priority. Only call it from goroutines that end
+// with their task (see runIOPool).
+func enterBackgroundIO(env Env) error {
+	if env.IOPriority == "" {
+		return nil
+	}
+	class, level, err := parseIOPriority(env.IOPriority)
+	if err != nil {
+		return err
+	}
+	runtime.LockOSThread()
+	if err := setThreadIOPriority(class, level); err != nil {
+		logEvent(env, "background I/O priority not applied", "priority", env.IOPriority, "error", err)
+	}
+	return nil
+}
+
+// runBackgroundCommand runs a heavy helper (qemu-img, e2fsck) in Env.IOCgroup, if set, so the
+// io.max limits of that cgroup apply to it.
+func runBackgroundCommand(env Env, stdout, stderr io.Writer, bin string, args ...string) error {
+	cmd := commandContextWithEnv(spanContext(env), nil, bin, args...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if env.IOCgroup != "" {
+		release, err := useIOCgroup(cmd, env.IOCgroup)
+		if err != nil {
+			return fmt.Errorf("I/O cgroup %s: %w", env.IOCgroup, err)
+		}
+		defer release()
+	}
+	return cmd.Run()
+}
@@ -0,0 +1,41 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+//go:build linux
+
+package avd
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+const (
+	ioprioWhoProcess = 1
+	ioprioClassShift = 13
+)
+
+// setThreadIOPriority sets the I/O priority of the calling OS thread.
+func setThreadIOPriority(class, level int) error {
+	prio := uintptr(class<<ioprioClassShift | level)
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOPRIO_SET, ioprioWhoProcess, uintptr(syscall.Gettid()), prio); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// useIOCgroup makes cmd start inside the cgroup v2 directory dir. The returned func closes the
+// directory once cmd has started.
+func useIOCgroup(cmd *exec.Cmd, dir string) (func(), error) {
+	f, err := os.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.UseCgroupFD = true
+	cmd.SysProcAttr.CgroupFD = int(f.Fd())
+	return func() { _ = f.Close() }, nil
+}
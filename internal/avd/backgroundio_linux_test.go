@@ -0,0 +1,32 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+//go:build linux
+
+package avd
+
+import (
+	"syscall"
+	"testing"
+)
+
+func TestRunIOPoolAppliesIOPriority(t *testing.T) {
+	env := Env{IOParallelism: 2, IOPriority: "idle"}
+	err := runIOPool(env, 3, func(i int) error {
+		prio, _, errno := syscall.Syscall(syscall.SYS_IOPRIO_GET, ioprioWhoProcess, uintptr(syscall.Gettid()), 0)
+		if errno != 0 {
+			return errno
+		}
+		if class := int(prio) >> ioprioClassShift; class != ioClassIdle {
+			t.Errorf("task %d runs in I/O class %d, want idle", i, class)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("runIOPool: %v", err)
+	}
+
+	if err := runIOPool(Env{IOPriority: "realtime"}, 1, func(int) error { return nil }); err == nil {
+		t.Fatal("runIOPool accepted an invalid I/O priority")
+	}
+}
@@ -0,0 +1,19 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+//go:build !linux
+
+package avd
+
+import (
+	"errors"
+	"os/exec"
+)
+
+func setThreadIOPriority(class, level int) error {
+	return errors.ErrUnsupported
+}
+
+func useIOCgroup(cmd *exec.Cmd, dir string) (func(), error) {
+	return nil, errors.New("cgroups are only available on Linux")
+}
@@ -0,0 +1,146 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// BootPhase is a stage of a boot wait, the status a BootProgressFunc receives.
+type BootPhase string
+
+const (
+	BootPhaseWaitingADB       BootPhase = "waiting_adb"       // until adb sees the instance
+	BootPhaseCheckingBootanim BootPhase = "checking_bootanim" // until sys.boot_completed=1 (package scanning happens here)
+	BootPhaseBootComplete     BootPhase = "boot_complete"     // reported once the OS has booted
+	BootPhasePostBoot         BootPhase = "post_boot"         // until the package manager answers; only with a timeout
+	BootPhaseWaitingReady     BootPhase = "waiting_ready"     // until the app answers the ReadyBroadcast
+	BootPhaseAppReady         BootPhase = "app_ready"         // reported once the app answered
+)
+
+// BootPhases lists the phases in the order a wait goes through them.
+var BootPhases = []BootPhase{
+	BootPhaseWaitingADB, BootPhaseCheckingBootanim, BootPhaseBootComplete,
+	BootPhasePostBoot, BootPhaseWaitingReady, BootPhaseAppReady,
+}
+
+// PhaseTimeouts bounds single phases of a boot wait within its overall timeout, e.g. a short
+// BootPhaseWaitingADB to fail fast on an instance adb never sees while leaving the rest of the
+// budget to package scanning. Phases without an entry are only bounded by the overall timeout.
+// boot_complete and app_ready are instants and take no timeout.
+type PhaseTimeouts map[BootPhase]time.Duration
+
+// Validate checks that only waiting phases have a positive timeout.
+func (p PhaseTimeouts) Validate() error {
+	for phase, timeout := range p {
+		switch phase {
+		case BootPhaseWaitingADB, BootPhaseCheckingBootanim, BootPhasePostBoot, BootPhaseWaitingReady:
+		default:
+			return fmt.Errorf("boot phase %q takes no timeout (use one of waiting_adb, checking_bootanim, post_boot, waiting_ready)", phase)
+		}
+		if timeout <= 0 {
+			return fmt.Errorf("boot phase %s needs a positive timeout, got %s", phase, timeout)
+		}
+	}
+	return nil
+}
+
+// Args returns the timeouts in the "phase=duration" form of ParsePhaseTimeouts, in phase order.
+func (p PhaseTimeouts) Args() []string {
+	var args []string
+	for _, phase := range BootPhases {
+		if timeout, ok := p[phase]; ok {
+			args = append(args, string(phase)+"="+timeout.String())
+		}
+	}
+	return args
+}
+
+// ParsePhaseTimeouts parses "phase=duration" entries such as "waiting_adb=30s".
+func ParsePhaseTimeouts(specs []string) (PhaseTimeouts, error) {
+	if len(specs) == 0 {
+		return nil, nil
+	}
+	timeouts := PhaseTimeouts{}
+	for _, spec := range specs {
+		phase, value, ok := strings.Cut(spec, "=")
+		if !ok {
+			return nil, fmt.Errorf("phase timeout %q is not phase=duration", spec)
+		}
+		timeout, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("phase timeout %q: %w", spec, err)
+		}
+		timeouts[BootPhase(strings.TrimSpace(phase))] = timeout
+	}
+	return timeouts, timeouts.Validate()
+}
+
+// budget returns when phase has to end, starting now: at its own timeout if that comes before
+// deadline (limited), else at deadline.
+func (p PhaseTimeouts) budget(phase BootPhase, deadline time.Time) (end time.Time, limited bool) {
+	if timeout, ok := p[phase]; ok {
+		if end := time.Now().Add(timeout); end.Before(deadline) {
+			return end, true
+		}
+	}
+	return deadline, false
+}
+
+// BootPhaseTimeoutError reports a phase that ran out of its PhaseTimeouts entry.
+type BootPhaseTimeoutError struct {
+	Serial  string
+	Phase   BootPhase
+	Timeout time.Duration
+	Detail  string // what the phase last saw
+}
+
+func (e *BootPhaseTimeoutError) Error() string {
+	msg := fmt.Sprintf("%s: boot phase %s did not finish within %s", e.Serial, e.Phase, e.Timeout)
+	if e.Detail != "" {
+		msg += " (" + e.Detail + ")"
+	}
+	return msg
+}
+
+// waitPostBoot runs the post_boot phase: once the OS has booted, the package manager may still
+// be busy, so it polls `pm path android` until it answers. Without a post_boot timeout it does
+// nothing.
+func waitPostBoot(ctx context.Context, env Env, serial string, phases PhaseTimeouts, deadline time.Time, report func(string)) error {
+	if _, ok := phases[BootPhasePostBoot]; !ok {
+		return nil
+	}
+	report(string(BootPhasePostBoot))
+	end, limited := phases.budget(BootPhasePostBoot, deadline)
+	last := ""
+	for {
+		out, errOut, err := runCommandOutputWithEnv(ctx, nil, nil, env.ADB, "-s", serial, "shell", "pm", "path", "android")
+		if err == nil && strings.HasPrefix(strings.TrimSpace(out), "package:") {
+			return nil
+		}
+		last = strings.TrimSpace(out + errOut)
+		if last == "" && err != nil {
+			last = err.Error()
+		}
+		if !time.Now().Add(500 * time.Millisecond).Before(end) {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+	detail := "package manager not answering"
+	if last != "" {
+		detail += ": " + last
+	}
+	if limited {
+		return &BootPhaseTimeoutError{Serial: serial, Phase: BootPhasePostBoot, Timeout: phases[BootPhasePostBoot], Detail: detail}
+	}
+	return fmt.Errorf("boot timeout on %s (%s)", serial, detail)
+}
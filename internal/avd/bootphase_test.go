@@ -0,0 +1,68 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParsePhaseTimeouts(t *testing.T) {
+	got, err := ParsePhaseTimeouts([]string{"waiting_adb=30s", "checking_bootanim = 4m"})
+	if err != nil {
+		t.Fatalf("ParsePhaseTimeouts: %v", err)
+	}
+	if got[BootPhaseWaitingADB] != 30*time.Second || got[BootPhaseCheckingBootanim] != 4*time.Minute {
+		t.Fatalf("timeouts = %v", got)
+	}
+	if args := got.Args(); !slices.Equal(args, []string{"waiting_adb=30s", "checking_bootanim=4m0s"}) {
+		t.Fatalf("Args = %v", args)
+	}
+	for _, bad := range []string{"app_ready=5s", "waiting_adb=-1s", "waiting_adb", "post_boot=soon"} {
+		if _, err := ParsePhaseTimeouts([]string{bad}); err == nil {
+			t.Fatalf("expected an error for %q", bad)
+		}
+	}
+}
+
+func TestWaitForBootPhaseTimeouts(t *testing.T) {
+	cases := []struct {
+		phase BootPhase
+		adb   string // body of the adb stub
+	}{
+		{BootPhaseWaitingADB, `[ "$1" = wait-for-device ] && exec sleep 30`},
+		{BootPhaseCheckingBootanim, `[ "$4" = getprop ] && echo 0`},
+		{BootPhasePostBoot, `[ "$4" = getprop ] && echo 1; [ "$4" = pm ] && echo "Error: Could not access the Package Manager"`},
+	}
+	for _, tc := range cases {
+		t.Run(string(tc.phase), func(t *testing.T) {
+			env := newTestEnv(t)
+			env.ADB = filepath.Join(t.TempDir(), "adb")
+			if err := os.WriteFile(env.ADB, []byte("#!/bin/sh\n"+tc.adb+"\nexit 0\n"), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			var statuses []string
+			start := time.Now()
+			err := WaitForBootWithOptions(env, "emulator-5554", WaitOptions{
+				Timeout:       time.Minute,
+				PhaseTimeouts: PhaseTimeouts{tc.phase: time.Second},
+				Progress:      func(status string, _ time.Duration) { statuses = append(statuses, status) },
+			})
+			var phaseErr *BootPhaseTimeoutError
+			if !errors.As(err, &phaseErr) || phaseErr.Phase != tc.phase {
+				t.Fatalf("WaitForBootWithOptions = %v, want a %s phase timeout", err, tc.phase)
+			}
+			if elapsed := time.Since(start); elapsed > 15*time.Second {
+				t.Fatalf("phase timeout took %s", elapsed)
+			}
+			if statuses[len(statuses)-1] != string(tc.phase) {
+				t.Fatalf("last status = %s, want %s (%v)", statuses[len(statuses)-1], tc.phase, statuses)
+			}
+		})
+	}
+}
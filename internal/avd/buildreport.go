@@ -0,0 +1,112 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BuildReport describes a PrewarmGoldenWithReport or BakeAPKWithReport run, so pipelines can
+// track the health of their golden builds over time.
+type BuildReport struct {
+	Path             string        `json:"path"`               // exported golden, or the data image of a baked clone
+	SizeBytes        int64         `json:"size_bytes"`         // size of Path
+	BootDuration     time.Duration `json:"boot_duration"`      // from emulator start to sys.boot_completed
+	SettleDuration   time.Duration `json:"settle_duration"`    // from boot to shutdown: setup, installs and settle time
+	PeakRSSBytes     int64         `json:"peak_rss_bytes"`     // peak resident memory of the emulator processes; 0 where unknown
+	ImageBytesBefore int64         `json:"image_bytes_before"` // writable images of the AVD before boot
+	ImageBytesAfter  int64         `json:"image_bytes_after"`  // writable images of the AVD after shutdown
+	Packages         int           `json:"packages"`           // third-party packages installed at shutdown
+}
+
+// imageBytes sums the writable images of avdDir.
+func imageBytes(avdDir string) int64 {
+	var total int64
+	for _, img := range writableImages(avdDir) {
+		total += img.SizeBytes
+	}
+	return total
+}
+
+// installedPackages counts the third-party packages on serial, or returns 0 when pm fails.
+func installedPackages(env Env, serial string) int {
+	out, _, err := runCommandOutputWithEnv(spanContext(env), nil, nil, env.ADB, "-s", serial, "shell", "pm", "list", "packages", "-3")
+	if err != nil {
+		return 0
+	}
+	n := 0
+	for _, line := range strings.Split(out, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "package:") {
+			n++
+		}
+	}
+	return n
+}
+
+// peakRSS returns the resident memory high-water mark (VmHWM) of pid and its descendants,
+// which include qemu when pid is the emulator launcher or a sandbox. It is sampled before the
+// emulator is killed and is 0 without /proc.
+func peakRSS(pid int) int64 {
+	if pid <= 0 {
+		return 0
+	}
+	children := map[int][]int{}
+	entries, _ := filepath.Glob("/proc/[0-9]*/stat")
+	for _, entry := range entries {
+		child, err := strconv.Atoi(filepath.Base(filepath.Dir(entry)))
+		if err != nil {
+			continue
+		}
+		if _, ppid, err := readProcessState(child); err == nil {
+			children[ppid] = append(children[ppid], child)
+		}
+	}
+	var total int64
+	seen := map[int]bool{}
+	queue := []int{pid}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+		total += processHWM(p)
+		queue = append(queue, children[p]...)
+	}
+	return total
+}
+
+// processHWM reads VmHWM of pid in bytes.
+func processHWM(pid int) int64 {
+	data, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "status"))
+	if err != nil {
+		return 0
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if value, ok := strings.CutPrefix(line, "VmHWM:"); ok {
+			fields := strings.Fields(value) // "123456 kB"
+			if len(fields) == 0 {
+				return 0
+			}
+			kb, err := strconv.ParseInt(fields[0], 10, 64)
+			if err != nil {
+				return 0
+			}
+			return kb * 1024
+		}
+	}
+	return 0
+}
+
+func logBuildReport(env Env, name string, r BuildReport) {
+	logEvent(env, "golden build report", "name", name, "path", r.Path,
+		"boot", r.BootDuration.Round(time.Millisecond).String(), "settle", r.SettleDuration.Round(time.Millisecond).String(),
+		"peak_rss_bytes", r.PeakRSSBytes, "image_bytes_before", r.ImageBytesBefore, "image_bytes_after", r.ImageBytesAfter,
+		"packages", r.Packages)
+}
@@ -0,0 +1,70 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestInstalledPackages(t *testing.T) {
+	env := newTestEnv(t)
+	stub := "#!/bin/sh\nprintf 'package:com.acme.wallet\\npackage:com.acme.helper\\n\\n'\n"
+	if err := os.WriteFile(env.ADB, []byte(stub), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if n := installedPackages(env, "emulator-5580"); n != 2 {
+		t.Fatalf("installedPackages = %d, want 2", n)
+	}
+	if err := os.WriteFile(env.ADB, []byte("#!/bin/sh\nexit 1\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if n := installedPackages(env, "emulator-5580"); n != 0 {
+		t.Fatalf("installedPackages with a failing pm = %d, want 0", n)
+	}
+}
+
+func TestPeakRSSIncludesChildren(t *testing.T) {
+	if _, err := os.Stat("/proc/self/status"); err != nil {
+		t.Skip("no /proc")
+	}
+	// The shell stands in for the emulator launcher and sleep for qemu.
+	cmd := exec.Command("sh", "-c", "sleep 30 & wait")
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = cmd.Process.Kill(); _ = cmd.Wait() }()
+	own := processHWM(cmd.Process.Pid)
+	if own <= 0 {
+		t.Fatalf("processHWM(%d) = %d", cmd.Process.Pid, own)
+	}
+	var total int64
+	for i := 0; i < 50; i++ {
+		if total = peakRSS(cmd.Process.Pid); total > own {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if total <= own {
+		t.Fatalf("peakRSS = %d, want more than the launcher alone (%d)", total, own)
+	}
+	if peakRSS(0) != 0 {
+		t.Fatal("peakRSS(0) should be 0")
+	}
+}
+
+func TestImageBytes(t *testing.T) {
+	dir := t.TempDir()
+	for name, size := range map[string]int{"userdata-qemu.img": 3000, "cache.img": 200, "config.ini": 50} {
+		if err := os.WriteFile(filepath.Join(dir, name), make([]byte, size), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := imageBytes(dir); got != 3200 {
+		t.Fatalf("imageBytes = %d, want 3200", got)
+	}
+}
@@ -0,0 +1,230 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// DefaultLCDDensity is the screen density assumed for AVDs whose config.ini has no
+// hw.lcd.density.
+const DefaultLCDDensity = 420
+
+// DeviceSpec is the bundletool device specification of an AVD: what picks the ABI, density,
+// language and SDK splits of an app bundle for it.
+type DeviceSpec struct {
+	SupportedABIs    []string `json:"supportedAbis"`
+	SupportedLocales []string `json:"supportedLocales"`
+	ScreenDensity    int      `json:"screenDensity"`
+	SDKVersion       int      `json:"sdkVersion"`
+}
+
+// DeviceSpecForAVD derives the device spec of AVD name from its config.ini, without booting
+// it. Clones share the spec of their base.
+func DeviceSpecForAVD(env Env, name string) (DeviceSpec, error) {
+	info := Info{Name: name, Path: filepath.Join(env.AVDHome, name+".avd")}
+	cfg, err := os.ReadFile(filepath.Join(info.Path, "config.ini"))
+	if err != nil {
+		return DeviceSpec{}, fmt.Errorf("device spec of %s: %w", name, err)
+	}
+	describeAVD(&info, nil)
+	if info.ABI == "" || info.APILevel == 0 {
+		return DeviceSpec{}, fmt.Errorf("device spec of %s: config.ini has no abi.type or system image API level", name)
+	}
+	spec := DeviceSpec{SupportedABIs: []string{info.ABI}, SupportedLocales: []string{"en-US"}, ScreenDensity: DefaultLCDDensity, SDKVersion: info.APILevel}
+	// x86_64 system images run 32-bit x86 code too.
+	if info.ABI == "x86_64" {
+		spec.SupportedABIs = append(spec.SupportedABIs, "x86")
+	}
+	if density, err := strconv.Atoi(configValue(cfg, "hw.lcd.density")); err == nil && density > 0 {
+		spec.ScreenDensity = density
+	}
+	return spec, nil
+}
+
+// apkSet is what one install delivers: a single APK, or the splits of a split APK set or app
+// bundle with the base APK first.
+type apkSet struct {
+	Source string   // path as given: .apk, directory of splits, .apks or .aab
+	APKs   []string // files passed to adb install / install-multiple
+	tmp    string   // extracted splits, removed by cleanup
+}
+
+// prepareAPKSet resolves path into the APKs to install. A directory is a split APK set; .apks
+// archives (bundletool build-apks output) and .aab bundles are turned into the splits matching
+// spec with bundletool. spec is only called for those. The result has passed
+// Env.APKValidators.
+func prepareAPKSet(env Env, path string, spec func() (DeviceSpec, error)) (apkSet, error) {
+	set := apkSet{Source: path}
+	st, err := os.Stat(path)
+	if err != nil {
+		return set, err
+	}
+	switch ext := strings.ToLower(filepath.Ext(path)); {
+	case st.IsDir():
+		matches, _ := filepath.Glob(filepath.Join(path, "*.apk"))
+		if len(matches) == 0 {
+			return set, fmt.Errorf("split APK directory %s has no .apk files", path)
+		}
+		set.APKs = matches
+	case ext == ".apks" || ext == ".aab":
+		if err := set.extract(env, spec); err != nil {
+			set.cleanup()
+			return apkSet{Source: path}, err
+		}
+	default:
+		set.APKs = []string{path}
+		return set, vetAPKSet(env, set)
+	}
+	if err := set.sortBaseFirst(); err != nil {
+		set.cleanup()
+		return apkSet{Source: path}, err
+	}
+	if err := vetAPKSet(env, set); err != nil {
+		set.cleanup()
+		return apkSet{Source: path}, err
+	}
+	return set, nil
+}
+
+// extract runs bundletool to get the splits of an .apks or .aab source for spec.
+func (s *apkSet) extract(env Env, spec func() (DeviceSpec, error)) error {
+	ds, err := spec()
+	if err != nil {
+		return err
+	}
+	tmp, err := os.MkdirTemp("", "avdctl-apkset-")
+	if err != nil {
+		return err
+	}
+	s.tmp = tmp
+	specPath := filepath.Join(tmp, "device-spec.json")
+	b, _ := json.Marshal(ds)
+	if err := os.WriteFile(specPath, b, 0o644); err != nil {
+		return err
+	}
+	apks := s.Source
+	if strings.EqualFold(filepath.Ext(s.Source), ".aab") {
+		// Signed with ~/.android/debug.keystore by bundletool's default.
+		apks = filepath.Join(tmp, "bundle.apks")
+		if err := runBundletool(env, "build-apks", "--bundle", s.Source, "--output", apks, "--device-spec", specPath); err != nil {
+			return err
+		}
+	}
+	out := filepath.Join(tmp, "splits")
+	if err := runBundletool(env, "extract-apks", "--apks", apks, "--output-dir", out, "--device-spec", specPath); err != nil {
+		return err
+	}
+	s.APKs, _ = filepath.Glob(filepath.Join(out, "*.apk"))
+	if len(s.APKs) == 0 {
+		return fmt.Errorf("bundletool extracted no APKs from %s for %v", s.Source, ds.SupportedABIs)
+	}
+	return nil
+}
+
+// sortBaseFirst orders the splits so the one without a split name, the base, comes first.
+func (s *apkSet) sortBaseFirst() error {
+	sort.Strings(s.APKs)
+	base := -1
+	for i, apk := range s.APKs {
+		meta, err := ReadAPKMetadata(apk)
+		if err != nil {
+			return err
+		}
+		if meta.Split == "" {
+			if base >= 0 {
+				return fmt.Errorf("%s holds more than one base APK (%s, %s)", s.Source, filepath.Base(s.APKs[base]), filepath.Base(apk))
+			}
+			base = i
+		}
+	}
+	if base < 0 {
+		return fmt.Errorf("%s has no base APK, only splits", s.Source)
+	}
+	s.APKs[0], s.APKs[base] = s.APKs[base], s.APKs[0]
+	return nil
+}
+
+// metadata reads the manifest of the base APK, recorded under the source path.
+func (s apkSet) metadata() (APKMetadata, error) {
+	meta, err := ReadAPKMetadata(s.APKs[0])
+	meta.Path = s.Source
+	return meta, err
+}
+
+// install installs the set on serial, replacing an installed version.
+func (s apkSet) install(env Env, serial string) error {
+	args := []string{"-s", serial, "install", "-r", s.APKs[0]}
+	if len(s.APKs) > 1 {
+		args = append([]string{"-s", serial, "install-multiple", "-r"}, s.APKs...)
+	}
+	if out, err := runCommandCombinedOutputWithEnv(env.Context, nil, nil, env.ADB, args...); err != nil {
+		return fmt.Errorf("install %s: %w: %s", s.Source, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func (s apkSet) cleanup() {
+	if s.tmp != "" {
+		_ = os.RemoveAll(s.tmp)
+	}
+}
+
+// runBundletool runs Env.Bundletool, through java -jar when it is a jar.
+func runBundletool(env Env, args ...string) error {
+	bin := env.Bundletool
+	if bin == "" {
+		bin = "bundletool"
+	}
+	subcommand := args[0]
+	if strings.HasSuffix(bin, ".jar") {
+		args = append([]string{"-jar", bin}, args...)
+		bin = "java"
+	}
+	if out, err := runCommandCombinedOutputWithEnv(env.Context, nil, nil, bin, args...); err != nil {
+		return fmt.Errorf("bundletool %s: %w: %s", subcommand, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// InstallAPK installs path on the running emulator serial: an APK, a directory of split APKs,
+// or an .apks/.aab bundle whose splits are picked for the AVD running on serial.
+func InstallAPK(env Env, serial, path string) error {
+	_, span := startSpan(env, "avd.InstallAPK", attribute.String("serial", serial), attribute.String("path", path))
+	defer span.End()
+	fail := func(err error) error {
+		recordSpanError(span, err)
+		return err
+	}
+	set, err := prepareAPKSet(env, path, func() (DeviceSpec, error) {
+		procs, err := ListRunning(env)
+		if err != nil {
+			return DeviceSpec{}, err
+		}
+		for _, p := range procs {
+			if p.Serial == serial && p.Name != "" {
+				return DeviceSpecForAVD(env, p.Name)
+			}
+		}
+		return DeviceSpec{}, errors.New("no running AVD on " + serial + " to derive the device spec from")
+	})
+	if err != nil {
+		return fail(err)
+	}
+	defer set.cleanup()
+	if err := set.install(env, serial); err != nil {
+		return fail(err)
+	}
+	logEvent(env, "apk installed", "serial", serial, "path", path, "apks", len(set.APKs))
+	return nil
+}
@@ -0,0 +1,138 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func splitManifest(t *testing.T, split string) []byte {
+	attrs := [][2]string{{"versionCode", "7"}, {"package", "com.acme.wallet"}}
+	if split != "" {
+		attrs = append(attrs, [2]string{"split", split})
+	}
+	return buildBinaryManifest(t, false, []testElement{{"manifest", attrs}, {"uses-sdk", [][2]string{{"minSdkVersion", "24"}}}})
+}
+
+func TestDeviceSpecForAVD(t *testing.T) {
+	env := newTestEnv(t)
+	makeBaseAVD(t, env, "base-a34")
+	cfg := "image.sysdir.1=system-images/android-34/google_apis/x86_64/\nabi.type=x86_64\nhw.lcd.density=440\n"
+	if err := os.WriteFile(filepath.Join(env.AVDHome, "base-a34.avd", "config.ini"), []byte(cfg), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	spec, err := DeviceSpecForAVD(env, "base-a34")
+	if err != nil {
+		t.Fatalf("DeviceSpecForAVD: %v", err)
+	}
+	if !slices.Equal(spec.SupportedABIs, []string{"x86_64", "x86"}) || spec.ScreenDensity != 440 || spec.SDKVersion != 34 {
+		t.Fatalf("spec = %+v", spec)
+	}
+	makeBaseAVD(t, env, "bare")
+	if _, err := DeviceSpecForAVD(env, "bare"); err == nil {
+		t.Fatal("expected an error for a config without ABI and API level")
+	}
+}
+
+func TestSplitAPKDirectoryInstallsBaseFirst(t *testing.T) {
+	env := newTestEnv(t)
+	dir := t.TempDir()
+	splits := filepath.Join(dir, "wallet")
+	if err := os.MkdirAll(splits, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeTestAPK(t, filepath.Join(splits, "a-config.x86_64.apk"), splitManifest(t, "config.x86_64"))
+	writeTestAPK(t, filepath.Join(splits, "base.apk"), splitManifest(t, ""))
+	logPath := filepath.Join(dir, "adb.log")
+	if err := os.WriteFile(env.ADB, []byte("#!/bin/sh\necho \"$@\" >> "+logPath+"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := InstallAPK(env, "emulator-5592", splits); err != nil {
+		t.Fatalf("InstallAPK: %v", err)
+	}
+	calls, _ := os.ReadFile(logPath)
+	want := "-s emulator-5592 install-multiple -r " + filepath.Join(splits, "base.apk") + " " + filepath.Join(splits, "a-config.x86_64.apk")
+	if strings.TrimSpace(string(calls)) != want {
+		t.Fatalf("adb calls = %q, want %q", calls, want)
+	}
+
+	writeTestAPK(t, filepath.Join(splits, "other.apk"), splitManifest(t, ""))
+	if err := InstallAPK(env, "emulator-5592", splits); err == nil || !strings.Contains(err.Error(), "more than one base APK") {
+		t.Fatalf("expected a two-base error, got %v", err)
+	}
+}
+
+func TestPrepareAPKSetSplitsBundleWithBundletool(t *testing.T) {
+	env := newTestEnv(t)
+	dir := t.TempDir()
+	base := filepath.Join(dir, "base-master.apk")
+	writeTestAPK(t, base, splitManifest(t, ""))
+	abi := filepath.Join(dir, "base-x86_64.apk")
+	writeTestAPK(t, abi, splitManifest(t, "config.x86_64"))
+	logPath := filepath.Join(dir, "bundletool.log")
+	env.Bundletool = filepath.Join(dir, "bundletool")
+	script := `#!/bin/sh
+echo "$@" >> ` + logPath + `
+cmd=$1; shift
+while [ $# -gt 0 ]; do
+  case "$1" in
+    --output) touch "$2" ;;
+    --output-dir) mkdir -p "$2" && cp ` + abi + ` ` + base + ` "$2" ;;
+    --device-spec) cat "$2" >> ` + logPath + ` ;;
+  esac
+  shift
+done
+`
+	if err := os.WriteFile(env.Bundletool, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	bundle := filepath.Join(dir, "wallet.aab")
+	if err := os.WriteFile(bundle, []byte("bundle"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	spec := func() (DeviceSpec, error) {
+		return DeviceSpec{SupportedABIs: []string{"x86_64"}, SupportedLocales: []string{"en-US"}, ScreenDensity: 420, SDKVersion: 34}, nil
+	}
+
+	set, err := prepareAPKSet(env, bundle, spec)
+	if err != nil {
+		t.Fatalf("prepareAPKSet: %v", err)
+	}
+	if len(set.APKs) != 2 || filepath.Base(set.APKs[0]) != "base-master.apk" || !strings.HasPrefix(set.APKs[0], set.tmp) {
+		t.Fatalf("apks = %v", set.APKs)
+	}
+	meta, err := set.metadata()
+	if err != nil || meta.Package != "com.acme.wallet" || meta.Path != bundle {
+		t.Fatalf("metadata = %+v, %v", meta, err)
+	}
+	set.cleanup()
+	if _, err := os.Stat(set.tmp); !os.IsNotExist(err) {
+		t.Fatalf("splits not cleaned up: %v", err)
+	}
+	calls, _ := os.ReadFile(logPath)
+	for _, needle := range []string{"build-apks --bundle " + bundle, "extract-apks --apks ", `"supportedAbis":["x86_64"]`, `"sdkVersion":34`} {
+		if !strings.Contains(string(calls), needle) {
+			t.Fatalf("bundletool calls missing %q:\n%s", needle, calls)
+		}
+	}
+}
+
+func TestRunBundletoolJarErrorNamesSubcommand(t *testing.T) {
+	env := newTestEnv(t)
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "java"), []byte("#!/bin/sh\necho 'invalid bundle' >&2\nexit 1\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin)
+	env.Bundletool = filepath.Join(t.TempDir(), "bundletool-all.jar")
+	err := runBundletool(env, "build-apks", "--bundle", "wallet.aab")
+	if err == nil || !strings.HasPrefix(err.Error(), "bundletool build-apks:") || !strings.Contains(err.Error(), "invalid bundle") {
+		t.Fatalf("err = %v, want it to name build-apks", err)
+	}
+}
@@ -0,0 +1,275 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ResourceUsage is what an emulator instance used, or what the host has left (see Capacity).
+type ResourceUsage struct {
+	MemoryBytes int64   `json:"memory_bytes"` // peak resident memory of qemu
+	CPUCores    float64 `json:"cpu_cores"`    // average cores busy over the run
+	DiskBytes   int64   `json:"disk_bytes"`   // space allocated to the AVD directory
+}
+
+// Resources Capacity can report as the bottleneck.
+const (
+	ResourceMemory = "memory"
+	ResourceCPU    = "cpu"
+	ResourceDisk   = "disk"
+)
+
+// CapacityReport estimates how many more instances of clones of one golden the host can run.
+type CapacityReport struct {
+	Golden    string `json:"golden"`
+	Clones    int    `json:"clones"`    // clones of the golden on the host
+	Samples   int    `json:"samples"`   // runs of those clones with recorded or live usage
+	Instances int    `json:"instances"` // emulators running on the host, of any AVD
+	// PerInstance is the estimated need of one more instance: the highest peak memory and
+	// clone disk usage seen, and the mean CPU.
+	PerInstance ResourceUsage `json:"per_instance"`
+	Available   ResourceUsage `json:"available"` // MemAvailable, idle cores, free space in AVDHome
+	// Limits is how many instances each resource allows; resources an instance does not
+	// measurably use are left out.
+	Limits     map[string]int `json:"limits"`
+	Additional int            `json:"additional"`
+	Bottleneck string         `json:"bottleneck"`
+}
+
+// Capacity estimates how many more clones of golden (a path, a name in Env.GoldenDir or
+// "@channel") the host can run at once, and which resource runs out first. It is based on the
+// usage Stop records in the run history of each clone and on the clones running now.
+func Capacity(env Env, golden string) (CapacityReport, error) {
+	_, span := startSpan(env, "avd.Capacity", attribute.String("golden", golden))
+	defer span.End()
+	report, err := capacity(env, golden)
+	if err != nil {
+		recordSpanError(span, err)
+		return report, err
+	}
+	span.SetAttributes(attribute.Int("additional", report.Additional), attribute.String("bottleneck", report.Bottleneck))
+	logEvent(env, "capacity estimated", "golden", report.Golden, "samples", report.Samples, "additional", report.Additional, "bottleneck", report.Bottleneck)
+	return report, nil
+}
+
+func capacity(env Env, golden string) (CapacityReport, error) {
+	dir, err := ResolveGolden(env, golden)
+	if err != nil {
+		return CapacityReport{}, err
+	}
+	fingerprint, err := goldenFingerprint(dir)
+	if err != nil {
+		return CapacityReport{}, fmt.Errorf("golden %s: %w", golden, err)
+	}
+	infos, err := List(env)
+	if err != nil {
+		return CapacityReport{}, err
+	}
+	report := CapacityReport{Golden: dir, Limits: map[string]int{}}
+	var cpu float64
+	for _, info := range infos {
+		b, err := os.ReadFile(filepath.Join(info.Path, cloneFingerprintFilename))
+		if err != nil || strings.TrimSpace(string(b)) != fingerprint {
+			continue
+		}
+		report.Clones++
+		report.PerInstance.DiskBytes = max(report.PerInstance.DiskBytes, diskUsage(info.Path))
+		var samples []ResourceUsage
+		for _, run := range readRunHistory(info.Path) {
+			if run.Usage != nil {
+				samples = append(samples, *run.Usage)
+			}
+		}
+		if rec, ok := readInstance(info.Path); ok && rec.PID > 0 && syscall.Kill(rec.PID, 0) == nil && !isZombieProcess(rec.PID) {
+			if usage, ok := sampleUsage(rec.PID, info.Path); ok {
+				samples = append(samples, usage)
+			}
+		}
+		for _, s := range samples {
+			report.Samples++
+			report.PerInstance.MemoryBytes = max(report.PerInstance.MemoryBytes, s.MemoryBytes)
+			report.PerInstance.DiskBytes = max(report.PerInstance.DiskBytes, s.DiskBytes)
+			cpu += s.CPUCores
+		}
+	}
+	if report.Clones == 0 {
+		return report, fmt.Errorf("no clones of golden %s on this host", dir)
+	}
+	if report.Samples == 0 {
+		return report, fmt.Errorf("no usage recorded for clones of golden %s yet: run one and stop it with avdctl, or keep one running", dir)
+	}
+	report.PerInstance.CPUCores = cpu / float64(report.Samples)
+	if procs, err := ListRunning(env); err == nil {
+		report.Instances = len(procs)
+	}
+	report.Available, err = hostAvailable(env)
+	if err != nil {
+		return report, err
+	}
+	limit := func(resource string, available, need float64) {
+		if need > 0 {
+			report.Limits[resource] = int(math.Max(0, math.Floor(available/need)))
+		}
+	}
+	limit(ResourceMemory, float64(report.Available.MemoryBytes), float64(report.PerInstance.MemoryBytes))
+	limit(ResourceCPU, report.Available.CPUCores, report.PerInstance.CPUCores)
+	limit(ResourceDisk, float64(report.Available.DiskBytes), float64(report.PerInstance.DiskBytes))
+	resources := make([]string, 0, len(report.Limits))
+	for resource := range report.Limits {
+		resources = append(resources, resource)
+	}
+	sort.Slice(resources, func(i, j int) bool {
+		if li, lj := report.Limits[resources[i]], report.Limits[resources[j]]; li != lj {
+			return li < lj
+		}
+		return resources[i] < resources[j]
+	})
+	if len(resources) > 0 {
+		report.Bottleneck = resources[0]
+		report.Additional = report.Limits[resources[0]]
+	}
+	return report, nil
+}
+
+// hostAvailable returns the memory, idle cores (cores minus the 1-minute load average) and
+// AVDHome space the host has left.
+func hostAvailable(env Env) (ResourceUsage, error) {
+	var avail ResourceUsage
+	meminfo, err := os.ReadFile("/proc/meminfo")
+	if err != nil {
+		return avail, errors.New("capacity needs /proc/meminfo (Linux hosts only)")
+	}
+	avail.MemoryBytes = meminfoBytes(string(meminfo), "MemAvailable")
+	avail.CPUCores = float64(runtime.NumCPU())
+	if b, err := os.ReadFile("/proc/loadavg"); err == nil {
+		if fields := strings.Fields(string(b)); len(fields) > 0 {
+			if load, err := strconv.ParseFloat(fields[0], 64); err == nil {
+				avail.CPUCores = math.Max(0, avail.CPUCores-load)
+			}
+		}
+	}
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(env.AVDHome, &st); err != nil {
+		return avail, fmt.Errorf("statfs %s: %w", env.AVDHome, err)
+	}
+	avail.DiskBytes = int64(uint64(st.Bavail) * uint64(st.Bsize))
+	return avail, nil
+}
+
+// meminfoBytes returns the value of key in /proc/meminfo (or a /proc/<pid>/status), in bytes.
+func meminfoBytes(data, key string) int64 {
+	for _, line := range strings.Split(data, "\n") {
+		if v, ok := strings.CutPrefix(line, key+":"); ok {
+			kb, _ := strconv.ParseInt(strings.TrimSuffix(strings.TrimSpace(v), " kB"), 10, 64)
+			return kb << 10
+		}
+	}
+	return 0
+}
+
+// sampleUsage measures the emulator launched as pid, on the AVD in avdDir: the peak memory and
+// average CPU of its qemu process, and the disk used by the AVD.
+func sampleUsage(pid int, avdDir string) (ResourceUsage, bool) {
+	if qemu, _, ok := qemuChild(pid); ok {
+		pid = qemu
+	}
+	status, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "status"))
+	if err != nil {
+		return ResourceUsage{}, false
+	}
+	usage := ResourceUsage{MemoryBytes: meminfoBytes(string(status), "VmHWM"), DiskBytes: diskUsage(avdDir)}
+	if ticks, ok := processCPUTicks(pid); ok {
+		if started, ok := processStartTime(pid); ok {
+			if elapsed := time.Since(started).Seconds(); elapsed > 0 {
+				usage.CPUCores = float64(ticks) / clockTicks / elapsed
+			}
+		}
+	}
+	return usage, true
+}
+
+// processCPUTicks returns the user and system time of pid, in clock ticks.
+func processCPUTicks(pid int) (int64, bool) {
+	stat, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return 0, false
+	}
+	data := string(stat)
+	rparen := strings.LastIndex(data, ")")
+	if rparen == -1 || rparen+2 >= len(data) {
+		return 0, false
+	}
+	// utime and stime are fields 14 and 15; fields after the command name start at field 3.
+	fields := strings.Fields(data[rparen+2:])
+	if len(fields) < 13 {
+		return 0, false
+	}
+	utime, err1 := strconv.ParseInt(fields[11], 10, 64)
+	stime, err2 := strconv.ParseInt(fields[12], 10, 64)
+	if err1 != nil || err2 != nil {
+		return 0, false
+	}
+	return utime + stime, true
+}
+
+// diskUsage is the space allocated to the regular files under path. Unlike dirSize it counts
+// sparse images by their allocated blocks and skips symlinked base artifacts.
+func diskUsage(path string) int64 {
+	var size int64
+	_ = filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err != nil || !info.Mode().IsRegular() {
+			return nil
+		}
+		if st, ok := info.Sys().(*syscall.Stat_t); ok {
+			size += int64(st.Blocks) * 512
+		} else {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
+
+// recordRunUsage samples the emulator serial before Stop takes it down and adds the usage to
+// the run history of its AVD, for Capacity. It returns the AVD directory and PID of the run,
+// for endRun once the stop succeeded.
+func recordRunUsage(env Env, serial string) (string, int, bool) {
+	avdDir, rec, ok := runningAVDDir(env, serial)
+	if !ok {
+		return "", 0, false
+	}
+	usage, ok := sampleUsage(rec.PID, avdDir)
+	if !ok {
+		return avdDir, rec.PID, true
+	}
+	runs := readRunHistory(avdDir)
+	for i := len(runs) - 1; i >= 0; i-- {
+		if runs[i].PID == rec.PID {
+			runs[i].Usage = &usage
+			writeRunHistory(avdDir, runs)
+			break
+		}
+	}
+	return avdDir, rec.PID, true
+}
+
+// endRun records, once Stop took serial down, the end and outcome of its run pid in the run
+// history of avdDir and the stop in the usage ledger.
+func endRun(env Env, avdDir string, pid int, serial, outcome string) {
+	markRun(avdDir, pid, outcome, "", true)
+	recordUsage(env, usageStop, strings.TrimSuffix(filepath.Base(avdDir), ".avd"), serial)
+}
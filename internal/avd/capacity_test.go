@@ -0,0 +1,81 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCapacity(t *testing.T) {
+	if _, err := os.Stat("/proc/meminfo"); err != nil {
+		t.Skip("needs /proc")
+	}
+	env := newTestEnv(t)
+	golden := makeGoldenDir(t)
+	fingerprint, err := goldenFingerprint(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	usages := map[string]ResourceUsage{
+		"w-1":   {MemoryBytes: 1 << 30, CPUCores: 0.5},
+		"w-2":   {MemoryBytes: 2 << 30, CPUCores: 1.5, DiskBytes: 4 << 20},
+		"other": {MemoryBytes: 64 << 30, CPUCores: 8},
+	}
+	for name, usage := range usages {
+		makeBaseAVD(t, env, name)
+		avdDir := filepath.Join(env.AVDHome, name+".avd")
+		if name != "other" {
+			if err := os.WriteFile(filepath.Join(avdDir, cloneFingerprintFilename), []byte(fingerprint+"\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		writeRunHistory(avdDir, []InstanceRun{{StartedAt: time.Now(), PID: 1}, {StartedAt: time.Now(), PID: 2, Usage: &usage}})
+	}
+
+	report, err := Capacity(env, golden)
+	if err != nil {
+		t.Fatalf("Capacity: %v", err)
+	}
+	if report.Clones != 2 || report.Samples != 2 || report.PerInstance.MemoryBytes != 2<<30 || report.PerInstance.CPUCores != 1 || report.PerInstance.DiskBytes != 4<<20 {
+		t.Fatalf("report = %+v", report)
+	}
+	if want := int(report.Available.MemoryBytes / (2 << 30)); report.Limits[ResourceMemory] != want {
+		t.Fatalf("memory limit = %d, want %d", report.Limits[ResourceMemory], want)
+	}
+	if report.Additional != report.Limits[report.Bottleneck] {
+		t.Fatalf("additional %d does not match the %s limit %v", report.Additional, report.Bottleneck, report.Limits)
+	}
+	for resource, n := range report.Limits {
+		if n < report.Additional {
+			t.Fatalf("%s allows %d, fewer than the %s bottleneck", resource, n, report.Bottleneck)
+		}
+	}
+
+	if _, err := Capacity(env, makeGoldenDir(t)); err == nil {
+		t.Fatal("expected an error for a golden without clones")
+	}
+}
+
+func TestRecordRunUsage(t *testing.T) {
+	if _, err := os.Stat("/proc/self/status"); err != nil {
+		t.Skip("needs /proc")
+	}
+	env := newTestEnv(t)
+	makeBaseAVD(t, env, "w-1")
+	avdDir := filepath.Join(env.AVDHome, "w-1.avd")
+	rec := instanceRecord{Serial: "emulator-5590", PID: os.Getpid(), StartedAt: time.Now()}
+	recordInstance(avdDir, rec)
+	recordRun(env, avdDir, rec)
+
+	if _, pid, ok := recordRunUsage(env, "emulator-5590"); !ok || pid != rec.PID {
+		t.Fatalf("recordRunUsage = %d, %v, want the run of pid %d", pid, ok, rec.PID)
+	}
+	runs := readRunHistory(avdDir)
+	if len(runs) != 1 || runs[0].Usage == nil || runs[0].Usage.MemoryBytes == 0 || runs[0].Usage.DiskBytes == 0 {
+		t.Fatalf("runs = %+v, want the run with its usage", runs)
+	}
+}
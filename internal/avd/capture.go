@@ -0,0 +1,118 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Capture lists the evidence files collected from an emulator by CaptureArtifacts.
+// Artifacts that could not be collected are left empty and explained in Errors.
+type Capture struct {
+	Dir        string   `json:"dir"`
+	Screenshot string   `json:"screenshot,omitempty"` // PNG from screencap
+	Logcat     string   `json:"logcat,omitempty"`     // logcat -d dump of all buffers
+	Bugreport  string   `json:"bugreport,omitempty"`  // bugreport zip
+	Errors     []string `json:"errors,omitempty"`
+}
+
+// CaptureArtifacts saves a screenshot, the logcat buffers and a bugreport of serial into dir.
+// It keeps going when one artifact fails; the error is for a dir that cannot be created.
+func CaptureArtifacts(env Env, serial, dir string) (Capture, error) {
+	_, span := startSpan(env, "avd.CaptureArtifacts", attribute.String("serial", serial), attribute.String("dir", dir))
+	defer span.End()
+	capture := Capture{Dir: dir}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		err = fmt.Errorf("create capture dir: %w", err)
+		recordSpanError(span, err)
+		return capture, err
+	}
+	fail := func(what string, err error, errOut string) {
+		msg := fmt.Sprintf("%s: %v", what, err)
+		if errOut = strings.TrimSpace(errOut); errOut != "" {
+			msg += ": " + errOut
+		}
+		capture.Errors = append(capture.Errors, msg)
+	}
+
+	save := func(what, name, data string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			fail(what, err, "")
+			return ""
+		}
+		return path
+	}
+
+	// exec-out keeps the PNG binary-safe (shell would translate line endings on old images).
+	out, errOut, err := runCommandOutputWithEnv(env.Context, nil, nil, env.ADB, "-s", serial, "exec-out", "screencap", "-p")
+	switch {
+	case err != nil:
+		fail("screenshot", err, errOut)
+	case out == "":
+		fail("screenshot", fmt.Errorf("empty screencap output"), errOut)
+	default:
+		capture.Screenshot = save("screenshot", "screenshot.png", out)
+	}
+
+	out, errOut, err = runCommandOutputWithEnv(env.Context, nil, nil, env.ADB, "-s", serial, "logcat", "-d", "-b", "all", "-v", "threadtime")
+	if err != nil {
+		fail("logcat", err, errOut)
+	} else {
+		capture.Logcat = save("logcat", "logcat.txt", out)
+	}
+
+	path := filepath.Join(dir, "bugreport.zip")
+	combined, err := runCommandCombinedOutputWithEnv(env.Context, nil, nil, env.ADB, "-s", serial, "bugreport", path)
+	switch {
+	case err != nil:
+		fail("bugreport", err, string(combined))
+	case !fileExists(path):
+		fail("bugreport", fmt.Errorf("adb did not write %s", path), string(combined))
+	default:
+		capture.Bugreport = path
+	}
+
+	recordCapture(env, serial, capture)
+	logEvent(env, "artifacts captured", "serial", serial, "dir", dir, "errors", len(capture.Errors))
+	return capture, nil
+}
+
+// recordCapture adds the files of capture to the current run of the instance serial, when
+// avdctl started it, and applies Env.Retention to that AVD.
+func recordCapture(env Env, serial string, capture Capture) {
+	avdDir, rec, ok := runningAVDDir(env, serial)
+	if !ok {
+		return
+	}
+	runs := readRunHistory(avdDir)
+	for i := len(runs) - 1; i >= 0; i-- {
+		if runs[i].PID != rec.PID {
+			continue
+		}
+		now := time.Now().UTC()
+		for _, a := range []RunArtifact{
+			{Kind: ArtifactScreenshot, Path: capture.Screenshot},
+			{Kind: ArtifactLogcat, Path: capture.Logcat},
+			{Kind: ArtifactBugreport, Path: capture.Bugreport},
+		} {
+			if a.Path != "" {
+				if abs, err := filepath.Abs(a.Path); err == nil {
+					a.Path = abs
+				}
+				a.CapturedAt = now
+				runs[i].Artifacts = append(runs[i].Artifacts, a)
+			}
+		}
+		writeRunHistory(avdDir, runs)
+		applyRetention(env, avdDir, false)
+		return
+	}
+}
@@ -0,0 +1,42 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCaptureArtifactsKeepsGoingOnFailure(t *testing.T) {
+	env := newTestEnv(t)
+	// adb -s SERIAL <cmd> ...; bugreport fails, as it does on images without dumpstate.
+	script := `#!/bin/sh
+case "$3" in
+  exec-out) printf '\211PNG' ;;
+  logcat) echo "I ActivityManager: Start proc" ;;
+  bugreport) echo "dumpstate not available" >&2; exit 1 ;;
+esac
+`
+	if err := os.WriteFile(env.ADB, []byte(script), 0o755); err != nil {
+		t.Fatalf("write adb stub: %v", err)
+	}
+	dir := filepath.Join(t.TempDir(), "session")
+	capture, err := CaptureArtifacts(env, "emulator-5580", dir)
+	if err != nil {
+		t.Fatalf("CaptureArtifacts: %v", err)
+	}
+	png, err := os.ReadFile(capture.Screenshot)
+	if err != nil || string(png) != "\x89PNG" {
+		t.Fatalf("screenshot = %q, %v", png, err)
+	}
+	logcat, err := os.ReadFile(capture.Logcat)
+	if err != nil || !strings.Contains(string(logcat), "ActivityManager") {
+		t.Fatalf("logcat = %q, %v", logcat, err)
+	}
+	if capture.Bugreport != "" || len(capture.Errors) != 1 || !strings.Contains(capture.Errors[0], "dumpstate not available") {
+		t.Fatalf("unexpected bugreport result: %#v", capture)
+	}
+}
@@ -0,0 +1,294 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// channelsFilename holds the channel pointers of the golden registry (Env.GoldenDir).
+const channelsFilename = "avdctl-channels.json"
+
+// channelHistoryLimit is how many earlier goldens a channel remembers for Rollback.
+const channelHistoryLimit = 10
+
+// Channel names a pointer to a golden. Besides the well-known ones any name without "/" works.
+type Channel string
+
+const (
+	ChannelLatest     Channel = "latest" // moved by every SaveGolden into Env.GoldenDir
+	ChannelStaging    Channel = "staging"
+	ChannelProduction Channel = "production"
+)
+
+// ChannelPointer is the golden a channel points at, and the ones it pointed at before.
+type ChannelPointer struct {
+	Golden     string         `json:"golden"`
+	PromotedAt time.Time      `json:"promoted_at"`
+	History    []ChannelEntry `json:"history,omitempty"` // most recent first
+}
+
+// ChannelEntry is an earlier target of a channel.
+type ChannelEntry struct {
+	Golden     string    `json:"golden"`
+	PromotedAt time.Time `json:"promoted_at"`
+}
+
+// goldenRefPrefix marks a channel reference where a golden path is expected, e.g. "@production".
+const goldenRefPrefix = "@"
+
+// ResolveGolden returns the golden directory for ref: "@<channel>" is looked up in the
+// registry, a bare name that is not a path here (e.g. "v5") is taken from Env.GoldenDir, and
+// anything else is returned unchanged.
+func ResolveGolden(env Env, ref string) (string, error) {
+	channel, ok := strings.CutPrefix(ref, goldenRefPrefix)
+	if !ok {
+		if env.GoldenDir != "" && ref != "" && !strings.ContainsRune(ref, filepath.Separator) && !fileExists(ref) {
+			if candidate := filepath.Join(env.GoldenDir, ref); fileExists(candidate) {
+				return candidate, nil
+			}
+		}
+		return ref, nil
+	}
+	channels, err := readChannels(env)
+	if err != nil {
+		return "", err
+	}
+	pointer, ok := channels[Channel(channel)]
+	if !ok {
+		return "", fmt.Errorf("channel %s does not point at a golden; promote one first", channel)
+	}
+	return pointer.Golden, nil
+}
+
+// ListChannels returns the channel pointers of the registry.
+func ListChannels(env Env) (map[Channel]ChannelPointer, error) {
+	return readChannels(env)
+}
+
+// Promote points channel at golden, remembering its previous golden for Rollback.
+func Promote(env Env, golden string, channel Channel) (ChannelPointer, error) {
+	_, span := startSpan(env, "avd.Promote", attribute.String("golden", golden), attribute.String("channel", string(channel)))
+	defer span.End()
+	pointer, err := updateChannels(env, func(channels map[Channel]ChannelPointer) (ChannelPointer, error) {
+		if err := validChannel(channel); err != nil {
+			return ChannelPointer{}, err
+		}
+		resolved, err := ResolveGolden(env, golden)
+		if err != nil {
+			return ChannelPointer{}, err
+		}
+		abs, err := filepath.Abs(resolved)
+		if err != nil {
+			return ChannelPointer{}, err
+		}
+		if _, err := ReadGoldenManifest(abs); err != nil {
+			return ChannelPointer{}, fmt.Errorf("%s is not a golden: %w", abs, err)
+		}
+		current := channels[channel]
+		if current.Golden == abs {
+			return current, nil
+		}
+		next := ChannelPointer{Golden: abs, PromotedAt: time.Now().UTC(), History: current.History}
+		if current.Golden != "" {
+			next.History = append([]ChannelEntry{{Golden: current.Golden, PromotedAt: current.PromotedAt}}, current.History...)
+		}
+		if len(next.History) > channelHistoryLimit {
+			next.History = next.History[:channelHistoryLimit]
+		}
+		channels[channel] = next
+		return next, nil
+	})
+	recordSpanError(span, err)
+	if err == nil {
+		logEvent(env, "golden promoted", "channel", channel, "golden", pointer.Golden)
+	}
+	return pointer, err
+}
+
+// Rollback points channel back at the golden it pointed at before the last promotion. The
+// rolled-back golden is dropped from the history.
+func Rollback(env Env, channel Channel) (ChannelPointer, error) {
+	_, span := startSpan(env, "avd.Rollback", attribute.String("channel", string(channel)))
+	defer span.End()
+	var from string
+	pointer, err := updateChannels(env, func(channels map[Channel]ChannelPointer) (ChannelPointer, error) {
+		current, ok := channels[channel]
+		if !ok {
+			return ChannelPointer{}, fmt.Errorf("channel %s does not point at a golden", channel)
+		}
+		if len(current.History) == 0 {
+			return ChannelPointer{}, fmt.Errorf("channel %s has no earlier golden to roll back to", channel)
+		}
+		from = current.Golden
+		previous := current.History[0]
+		next := ChannelPointer{Golden: previous.Golden, PromotedAt: previous.PromotedAt, History: current.History[1:]}
+		channels[channel] = next
+		return next, nil
+	})
+	recordSpanError(span, err)
+	if err == nil {
+		logEvent(env, "golden rolled back", "channel", channel, "from", from, "to", pointer.Golden)
+	}
+	return pointer, err
+}
+
+// ChannelHealthOptions configures CheckChannelHealth.
+type ChannelHealthOptions struct {
+	// MaxFailures is how many unhealthy clones trigger a rollback (default: 1).
+	MaxFailures int
+	// Rollback rolls the channel back when MaxFailures is reached.
+	Rollback bool
+}
+
+// ChannelHealth reports the health of the running clones of a channel's golden.
+type ChannelHealth struct {
+	Channel    Channel           `json:"channel"`
+	Golden     string            `json:"golden"`
+	Healthy    []string          `json:"healthy"`                  // serials that passed every check
+	Unhealthy  map[string]string `json:"unhealthy"`                // serial -> failed checks
+	NotBooted  []string          `json:"not_booted"`               // running clones not yet booted (not checked)
+	RolledBack string            `json:"rolled_back_to,omitempty"` // golden the channel now points at, after a rollback
+}
+
+// CheckChannelHealth runs the golden health checks on every booted, running clone of the
+// golden channel points at, and rolls the channel back if too many fail and opts.Rollback is
+// set. Run it periodically (e.g. from a timer) after promoting to production.
+func CheckChannelHealth(env Env, channel Channel, opts ChannelHealthOptions) (ChannelHealth, error) {
+	_, span := startSpan(env, "avd.CheckChannelHealth", attribute.String("channel", string(channel)))
+	defer span.End()
+	fail := func(err error) (ChannelHealth, error) {
+		recordSpanError(span, err)
+		return ChannelHealth{}, err
+	}
+	if opts.MaxFailures <= 0 {
+		opts.MaxFailures = 1
+	}
+	golden, err := ResolveGolden(env, goldenRefPrefix+string(channel))
+	if err != nil {
+		return fail(err)
+	}
+	fingerprint, err := goldenFingerprint(golden)
+	if err != nil {
+		return fail(fmt.Errorf("fingerprint golden: %w", err))
+	}
+	procs, err := ListRunning(env)
+	if err != nil {
+		return fail(err)
+	}
+	health := ChannelHealth{Channel: channel, Golden: golden, Unhealthy: map[string]string{}}
+	for _, p := range procs {
+		data, err := os.ReadFile(filepath.Join(env.AVDHome, p.Name+".avd", cloneFingerprintFilename))
+		if err != nil || strings.TrimSpace(string(data)) != fingerprint {
+			continue
+		}
+		if !p.Booted {
+			health.NotBooted = append(health.NotBooted, p.Serial)
+			continue
+		}
+		var failed []string
+		for _, check := range runHealthChecks(env, p.Serial) {
+			if !check.OK {
+				failed = append(failed, check.Name+": "+check.Detail)
+			}
+		}
+		if len(failed) > 0 {
+			health.Unhealthy[p.Serial] = strings.Join(failed, "; ")
+		} else {
+			health.Healthy = append(health.Healthy, p.Serial)
+		}
+	}
+	sort.Strings(health.Healthy)
+	sort.Strings(health.NotBooted)
+	if len(health.Unhealthy) >= opts.MaxFailures && opts.Rollback {
+		pointer, err := Rollback(env, channel)
+		if err != nil {
+			return fail(fmt.Errorf("%d unhealthy clones of %s, rollback failed: %w", len(health.Unhealthy), channel, err))
+		}
+		health.RolledBack = pointer.Golden
+	}
+	return health, nil
+}
+
+// inGoldenRegistry reports whether goldenDir lives directly under Env.GoldenDir.
+func inGoldenRegistry(env Env, goldenDir string) bool {
+	if env.GoldenDir == "" {
+		return false
+	}
+	registry, err1 := filepath.Abs(env.GoldenDir)
+	dir, err2 := filepath.Abs(goldenDir)
+	return err1 == nil && err2 == nil && filepath.Dir(dir) == registry
+}
+
+func validChannel(channel Channel) error {
+	if channel == "" || strings.ContainsAny(string(channel), "/@") {
+		return fmt.Errorf("invalid channel name %q", channel)
+	}
+	return nil
+}
+
+func channelsPath(env Env) (string, error) {
+	if env.GoldenDir == "" {
+		return "", errors.New("golden registry needs AVDCTL_GOLDEN_DIR")
+	}
+	return filepath.Join(env.GoldenDir, channelsFilename), nil
+}
+
+func readChannels(env Env) (map[Channel]ChannelPointer, error) {
+	path, err := channelsPath(env)
+	if err != nil {
+		return nil, err
+	}
+	channels := map[Channel]ChannelPointer{}
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return channels, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read golden channels: %w", err)
+	}
+	if err := json.Unmarshal(b, &channels); err != nil {
+		return nil, fmt.Errorf("parse golden channels: %w", err)
+	}
+	return channels, nil
+}
+
+// updateChannels applies fn to the registry under its lock and writes the result.
+func updateChannels(env Env, fn func(map[Channel]ChannelPointer) (ChannelPointer, error)) (ChannelPointer, error) {
+	path, err := channelsPath(env)
+	if err != nil {
+		return ChannelPointer{}, err
+	}
+	unlock, err := acquireFileLock(env.Context, path+".lock", nil)
+	if err != nil {
+		return ChannelPointer{}, err
+	}
+	defer unlock()
+	channels, err := readChannels(env)
+	if err != nil {
+		return ChannelPointer{}, err
+	}
+	pointer, err := fn(channels)
+	if err != nil {
+		return ChannelPointer{}, err
+	}
+	b, err := json.MarshalIndent(channels, "", "  ")
+	if err != nil {
+		return ChannelPointer{}, err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(b, '\n'), 0o644); err != nil {
+		return ChannelPointer{}, fmt.Errorf("write golden channels: %w", err)
+	}
+	return pointer, os.Rename(tmp, path)
+}
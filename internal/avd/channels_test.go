@@ -0,0 +1,121 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newRegistryGolden(t *testing.T, env Env, name string) string {
+	t.Helper()
+	dir := filepath.Join(env.GoldenDir, name)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, img := range []string{"userdata-qemu.img", "encryptionkey.img", "cache.img", "sdcard.img"} {
+		if err := os.WriteFile(filepath.Join(dir, img), []byte(name+"-"+img), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writeGoldenManifest(dir, GoldenManifest{Source: "base-a35", CreatedAt: time.Now().UTC()}); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestPromoteAndRollback(t *testing.T) {
+	env := newTestEnv(t)
+	env.GoldenDir = t.TempDir()
+	v4 := newRegistryGolden(t, env, "v4")
+	v5 := newRegistryGolden(t, env, "v5")
+
+	if _, err := Promote(env, v4, ChannelProduction); err != nil {
+		t.Fatalf("Promote v4: %v", err)
+	}
+	if _, err := Promote(env, v5, ChannelStaging); err != nil {
+		t.Fatalf("Promote v5 to staging: %v", err)
+	}
+	pointer, err := Promote(env, "@staging", ChannelProduction)
+	if err != nil || pointer.Golden != v5 || len(pointer.History) != 1 || pointer.History[0].Golden != v4 {
+		t.Fatalf("Promote @staging = %#v, %v", pointer, err)
+	}
+	if golden, err := ResolveGolden(env, "@production"); err != nil || golden != v5 {
+		t.Fatalf("ResolveGolden = %q, %v", golden, err)
+	}
+
+	pointer, err = Rollback(env, ChannelProduction)
+	if err != nil || pointer.Golden != v4 || len(pointer.History) != 0 {
+		t.Fatalf("Rollback = %#v, %v", pointer, err)
+	}
+	if _, err := Rollback(env, ChannelProduction); err == nil || !strings.Contains(err.Error(), "no earlier golden") {
+		t.Fatalf("expected empty history error, got %v", err)
+	}
+	if _, err := Promote(env, t.TempDir(), ChannelStaging); err == nil || !strings.Contains(err.Error(), "not a golden") {
+		t.Fatalf("expected not a golden error, got %v", err)
+	}
+	if _, err := ResolveGolden(env, "@canary"); err == nil {
+		t.Fatal("expected unknown channel error")
+	}
+}
+
+func TestCheckChannelHealthRollsBackOnFailures(t *testing.T) {
+	env := newTestEnv(t)
+	env.GoldenDir = t.TempDir()
+	v4 := newRegistryGolden(t, env, "v4")
+	v5 := newRegistryGolden(t, env, "v5")
+	for _, golden := range []string{v4, v5} {
+		if _, err := Promote(env, golden, ChannelProduction); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fingerprint, err := goldenFingerprint(v5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(env.AVDHome, "w-1.avd"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeCloneFingerprint(filepath.Join(env.AVDHome, "w-1.avd"), fingerprint); err != nil {
+		t.Fatal(err)
+	}
+	// One booted clone of v5 whose home activity no longer resolves.
+	script := `#!/bin/sh
+case "$1" in
+  devices) printf 'List of devices attached\nemulator-5580\tdevice\n' ;;
+  -s)
+    case "$3" in
+      emu) printf 'w-1\nOK\n' ;;
+      shell)
+        case "$4" in
+          getprop) echo 1 ;;
+          pm) echo "package:/system/framework/framework-res.apk" ;;
+          touch*) echo ok ;;
+          cmd) echo "No activity found" ;;
+        esac ;;
+    esac ;;
+esac
+`
+	if err := os.WriteFile(env.ADB, []byte(script), 0o755); err != nil {
+		t.Fatalf("write adb stub: %v", err)
+	}
+
+	health, err := CheckChannelHealth(env, ChannelProduction, ChannelHealthOptions{})
+	if err != nil {
+		t.Fatalf("CheckChannelHealth: %v", err)
+	}
+	if len(health.Unhealthy) != 1 || !strings.Contains(health.Unhealthy["emulator-5580"], "home-activity") || health.RolledBack != "" {
+		t.Fatalf("unexpected health %#v", health)
+	}
+	health, err = CheckChannelHealth(env, ChannelProduction, ChannelHealthOptions{Rollback: true})
+	if err != nil || health.RolledBack != v4 {
+		t.Fatalf("CheckChannelHealth with rollback = %#v, %v", health, err)
+	}
+	if golden, _ := ResolveGolden(env, "@production"); golden != v4 {
+		t.Fatalf("production = %q after rollback, want %q", golden, v4)
+	}
+}
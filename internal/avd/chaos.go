@@ -0,0 +1,270 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+	"syscall"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Chaos actions.
+const (
+	ChaosKill           = "kill"            // SIGKILL the emulator, no clean shutdown
+	ChaosPause          = "pause"           // Pause for PauseFor, then Resume
+	ChaosDegradeNetwork = "degrade-network" // console network delay/speed for DegradeFor
+)
+
+// ChaosPolicy selects which running instances a ChaosController disrupts, and how.
+type ChaosPolicy struct {
+	// Actions are picked uniformly for each hit instance (default: all three).
+	Actions []string `json:"actions,omitempty"`
+	// Fraction is the share of the selected instances hit per round, rounded up (default 0.1).
+	Fraction float64 `json:"fraction,omitempty"`
+	// Filters select the instances that may be hit, as for QueryRunning.
+	Filters []Filter `json:"filters,omitempty"`
+	// Interval is the time between rounds of Run (default 1m).
+	Interval time.Duration `json:"interval,omitempty"`
+	// Rounds stops Run after that many rounds (0: until env.Context is done).
+	Rounds int `json:"rounds,omitempty"`
+	// PauseFor and DegradeFor are how long a pause or a degraded network lasts (default 30s, 1m).
+	PauseFor   time.Duration `json:"pause_for,omitempty"`
+	DegradeFor time.Duration `json:"degrade_for,omitempty"`
+	// NetworkDelay and NetworkSpeed are console "network delay"/"network speed" values used by
+	// ChaosDegradeNetwork (default "umts" and "gsm").
+	NetworkDelay string `json:"network_delay,omitempty"`
+	NetworkSpeed string `json:"network_speed,omitempty"`
+	// Seed makes the choices reproducible; 0 seeds from the clock.
+	Seed int64 `json:"seed,omitempty"`
+	// DryRun reports the actions as events without applying them.
+	DryRun bool `json:"dry_run,omitempty"`
+	// OnEvent is called for every action, revert and failure.
+	OnEvent func(ChaosEvent) `json:"-"`
+}
+
+// ChaosEvent is one action of a ChaosController. Reverted events report the end of a pause or
+// of a degraded network.
+type ChaosEvent struct {
+	Time     time.Time `json:"time"`
+	Serial   string    `json:"serial"`
+	Name     string    `json:"name,omitempty"`
+	Action   string    `json:"action"`
+	Reverted bool      `json:"reverted,omitempty"`
+	DryRun   bool      `json:"dry_run,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// ChaosController disrupts a fraction of the running instances per round, to check that the
+// orchestration above avdctl copes with emulators that die, hang or lose their network.
+type ChaosController struct {
+	env    Env
+	policy ChaosPolicy
+
+	mu      sync.Mutex
+	rng     *rand.Rand
+	pending map[string]chaosRevert // serial -> its pause or degraded network
+
+	list func() ([]ProcInfo, error)
+	do   func(action string, p ProcInfo, revert bool) error
+}
+
+type chaosRevert struct {
+	action string
+	proc   ProcInfo
+	timer  *time.Timer
+}
+
+// NewChaosController checks policy and returns a controller for it.
+func NewChaosController(env Env, policy ChaosPolicy) (*ChaosController, error) {
+	if len(policy.Actions) == 0 {
+		policy.Actions = []string{ChaosKill, ChaosPause, ChaosDegradeNetwork}
+	}
+	for _, a := range policy.Actions {
+		switch a {
+		case ChaosKill, ChaosPause, ChaosDegradeNetwork:
+		default:
+			return nil, fmt.Errorf("unknown chaos action %q (want kill, pause or degrade-network)", a)
+		}
+	}
+	if policy.Fraction < 0 || policy.Fraction > 1 {
+		return nil, fmt.Errorf("chaos fraction %v must be between 0 and 1", policy.Fraction)
+	}
+	if policy.Fraction == 0 {
+		policy.Fraction = 0.1
+	}
+	if policy.Interval <= 0 {
+		policy.Interval = time.Minute
+	}
+	if policy.PauseFor <= 0 {
+		policy.PauseFor = 30 * time.Second
+	}
+	if policy.DegradeFor <= 0 {
+		policy.DegradeFor = time.Minute
+	}
+	if policy.NetworkDelay == "" {
+		policy.NetworkDelay = "umts"
+	}
+	if policy.NetworkSpeed == "" {
+		policy.NetworkSpeed = "gsm"
+	}
+	seed := policy.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	c := &ChaosController{
+		env:     env,
+		policy:  policy,
+		rng:     rand.New(rand.NewSource(seed)),
+		pending: map[string]chaosRevert{},
+	}
+	c.list = func() ([]ProcInfo, error) { return QueryRunning(env, QuerySpec{Filters: policy.Filters}) }
+	c.do = c.apply
+	return c, nil
+}
+
+// Round hits Fraction of the selected instances once and returns the actions taken. Pauses and
+// network degradations are reverted in the background after PauseFor and DegradeFor.
+func (c *ChaosController) Round() ([]ChaosEvent, error) {
+	_, span := startSpan(c.env, "avd.ChaosRound", attribute.Float64("fraction", c.policy.Fraction))
+	defer span.End()
+	procs, err := c.list()
+	if err != nil {
+		recordSpanError(span, err)
+		return nil, err
+	}
+	c.mu.Lock()
+	var candidates []ProcInfo
+	for _, p := range procs {
+		if _, busy := c.pending[p.Serial]; !busy {
+			candidates = append(candidates, p)
+		}
+	}
+	c.rng.Shuffle(len(candidates), func(i, j int) { candidates[i], candidates[j] = candidates[j], candidates[i] })
+	hit := candidates[:min(len(candidates), int(math.Ceil(c.policy.Fraction*float64(len(candidates)))))]
+	actions := make([]string, len(hit))
+	for i := range hit {
+		actions[i] = c.policy.Actions[c.rng.Intn(len(c.policy.Actions))]
+	}
+	c.mu.Unlock()
+
+	var events []ChaosEvent
+	for i, p := range hit {
+		events = append(events, c.hit(actions[i], p))
+	}
+	span.SetAttributes(attribute.Int("candidates", len(candidates)), attribute.Int("hit", len(hit)))
+	return events, nil
+}
+
+func (c *ChaosController) hit(action string, p ProcInfo) ChaosEvent {
+	var err error
+	if !c.policy.DryRun {
+		err = c.do(action, p, false)
+	}
+	ev := c.emit(action, p, false, err)
+	if err != nil || c.policy.DryRun || action == ChaosKill {
+		return ev
+	}
+	after := c.policy.PauseFor
+	if action == ChaosDegradeNetwork {
+		after = c.policy.DegradeFor
+	}
+	c.mu.Lock()
+	c.pending[p.Serial] = chaosRevert{action: action, proc: p, timer: time.AfterFunc(after, func() { c.revert(action, p) })}
+	c.mu.Unlock()
+	return ev
+}
+
+// revert ends the pause or degraded network of p, once.
+func (c *ChaosController) revert(action string, p ProcInfo) {
+	c.mu.Lock()
+	_, pending := c.pending[p.Serial]
+	delete(c.pending, p.Serial)
+	c.mu.Unlock()
+	if pending {
+		c.emit(action, p, true, c.do(action, p, true))
+	}
+}
+
+func (c *ChaosController) emit(action string, p ProcInfo, reverted bool, err error) ChaosEvent {
+	ev := ChaosEvent{Time: time.Now().UTC(), Serial: p.Serial, Name: p.Name, Action: action, Reverted: reverted, DryRun: c.policy.DryRun}
+	if err != nil {
+		ev.Error = err.Error()
+	}
+	logEvent(c.env, "chaos action", "action", action, "serial", p.Serial, "name", p.Name,
+		"reverted", reverted, "dry_run", c.policy.DryRun, "error", ev.Error)
+	if c.policy.OnEvent != nil {
+		c.policy.OnEvent(ev)
+	}
+	return ev
+}
+
+// Run plays a round every Interval until env.Context is done or Rounds rounds have had their
+// Interval, then reverts what is still paused or degraded.
+func (c *ChaosController) Run() error {
+	ctx := c.env.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	defer c.Restore()
+	for round := 1; ; round++ {
+		if _, err := c.Round(); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(c.policy.Interval):
+		}
+		if c.policy.Rounds > 0 && round >= c.policy.Rounds {
+			return nil
+		}
+	}
+}
+
+// Restore immediately resumes paused instances and restores degraded networks.
+func (c *ChaosController) Restore() {
+	c.mu.Lock()
+	var due []chaosRevert
+	for _, r := range c.pending {
+		if r.timer.Stop() {
+			due = append(due, r)
+		}
+	}
+	c.mu.Unlock()
+	for _, r := range due {
+		c.revert(r.action, r.proc)
+	}
+}
+
+func (c *ChaosController) apply(action string, p ProcInfo, revert bool) error {
+	switch action {
+	case ChaosKill:
+		if p.PID > 0 {
+			return syscall.Kill(p.PID, syscall.SIGKILL)
+		}
+		return run(c.env, c.env.ADB, "-s", p.Serial, "emu", "kill")
+	case ChaosPause:
+		if revert {
+			return Resume(c.env, p.Serial)
+		}
+		return Pause(c.env, p.Serial)
+	case ChaosDegradeNetwork:
+		delay, speed := c.policy.NetworkDelay, c.policy.NetworkSpeed
+		if revert {
+			delay, speed = "none", "full"
+		}
+		if _, err := Console(c.env, p.Serial, "network delay "+delay); err != nil {
+			return err
+		}
+		_, err := Console(c.env, p.Serial, "network speed "+speed)
+		return err
+	}
+	return fmt.Errorf("unknown chaos action %q", action)
+}
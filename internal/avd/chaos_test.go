@@ -0,0 +1,94 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newStubChaos(t *testing.T, policy ChaosPolicy, instances int) (*ChaosController, func() []string) {
+	t.Helper()
+	c, err := NewChaosController(Env{}, policy)
+	if err != nil {
+		t.Fatalf("NewChaosController: %v", err)
+	}
+	var procs []ProcInfo
+	for i := 0; i < instances; i++ {
+		procs = append(procs, ProcInfo{Serial: fmt.Sprintf("emulator-%d", 5554+2*i), Name: fmt.Sprintf("w-%d", i)})
+	}
+	var mu sync.Mutex
+	var calls []string
+	c.list = func() ([]ProcInfo, error) { return procs, nil }
+	c.do = func(action string, p ProcInfo, revert bool) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if revert {
+			action = "un" + action
+		}
+		calls = append(calls, action+" "+p.Name)
+		return nil
+	}
+	return c, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), calls...)
+	}
+}
+
+func TestChaosRoundHitsFractionAndReverts(t *testing.T) {
+	c, calls := newStubChaos(t, ChaosPolicy{Actions: []string{ChaosPause}, Fraction: 0.25, PauseFor: time.Hour, Seed: 1}, 10)
+	events, err := c.Round()
+	if err != nil {
+		t.Fatalf("Round: %v", err)
+	}
+	if len(events) != 3 { // ceil(0.25 * 10)
+		t.Fatalf("events = %+v", events)
+	}
+	// Paused instances are not hit again until they are resumed.
+	if events, _ := c.Round(); len(events) != 2 { // ceil(0.25 * 7)
+		t.Fatalf("second round events = %+v", events)
+	}
+	c.Restore()
+	got := calls()
+	if len(got) != 10 {
+		t.Fatalf("calls = %v", got)
+	}
+	for _, call := range got[5:] {
+		if call[:7] != "unpause" {
+			t.Fatalf("calls = %v, want 5 pauses then 5 resumes", got)
+		}
+	}
+}
+
+func TestChaosKillIsNotRevertedAndDryRunDoesNothing(t *testing.T) {
+	c, calls := newStubChaos(t, ChaosPolicy{Actions: []string{ChaosKill}, Fraction: 1}, 2)
+	if _, err := c.Round(); err != nil {
+		t.Fatalf("Round: %v", err)
+	}
+	c.Restore()
+	if got := calls(); len(got) != 2 || got[0][:4] != "kill" {
+		t.Fatalf("calls = %v", got)
+	}
+
+	var seen []ChaosEvent
+	c, calls = newStubChaos(t, ChaosPolicy{Fraction: 1, DryRun: true, OnEvent: func(ev ChaosEvent) { seen = append(seen, ev) }}, 3)
+	if _, err := c.Round(); err != nil {
+		t.Fatalf("Round: %v", err)
+	}
+	if len(calls()) != 0 || len(seen) != 3 || !seen[0].DryRun {
+		t.Fatalf("dry run: calls = %v, events = %+v", calls(), seen)
+	}
+}
+
+func TestChaosPolicyValidation(t *testing.T) {
+	if _, err := NewChaosController(Env{}, ChaosPolicy{Actions: []string{"reboot"}}); err == nil {
+		t.Fatal("expected unknown action error")
+	}
+	if _, err := NewChaosController(Env{}, ChaosPolicy{Fraction: 2}); err == nil {
+		t.Fatal("expected fraction error")
+	}
+}
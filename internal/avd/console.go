@@ -0,0 +1,152 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// consoleTimeout bounds each read from the emulator console.
+const consoleTimeout = 10 * time.Second
+
+// consoleTokenPathRe extracts the token file the console names in its auth banner.
+var consoleTokenPathRe = regexp.MustCompile(`'([^']*emulator_console_auth_token)'`)
+
+// ConsoleError is a KO reply from the emulator console.
+type ConsoleError struct {
+	Command string
+	Message string
+}
+
+func (e *ConsoleError) Error() string {
+	return fmt.Sprintf("console %q: KO: %s", e.Command, e.Message)
+}
+
+// Console sends one command to the emulator console of serial (telnet on its console port,
+// authenticated with ~/.emulator_console_auth_token) and returns the reply without the
+// trailing OK. A KO reply is returned as *ConsoleError.
+func Console(env Env, serial, command string) (string, error) {
+	_, span := startSpan(env, "avd.Console", attribute.String("serial", serial), attribute.String("command", command))
+	defer span.End()
+	session, err := openConsole(env, serial)
+	if err != nil {
+		recordSpanError(span, err)
+		return "", err
+	}
+	defer session.Close()
+	out, err := session.send(command)
+	recordSpanError(span, err)
+	return out, err
+}
+
+// consoleSession is an authenticated connection to an emulator console.
+type consoleSession struct {
+	serial string
+	conn   net.Conn
+	r      *bufio.Reader
+}
+
+// openConsole connects to the console of serial and authenticates if it asks for a token.
+func openConsole(env Env, serial string) (*consoleSession, error) {
+	port, err := consolePort(serial)
+	if err != nil {
+		return nil, err
+	}
+	dialer := net.Dialer{Timeout: 5 * time.Second}
+	conn, err := dialer.DialContext(spanContext(env), "tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	if err != nil {
+		return nil, fmt.Errorf("connect to console of %s: %w", serial, err)
+	}
+	s := &consoleSession{serial: serial, conn: conn, r: bufio.NewReader(conn)}
+	banner, err := s.readReply("")
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if strings.Contains(banner, "Authentication required") {
+		token, err := consoleAuthToken(banner)
+		if err == nil {
+			_, err = s.send("auth " + token)
+		}
+		if err != nil {
+			conn.Close()
+			var koErr *ConsoleError
+			if errors.As(err, &koErr) {
+				return nil, fmt.Errorf("console authentication on %s failed: %s", serial, koErr.Message)
+			}
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// send runs one command and returns its reply without the trailing OK.
+func (s *consoleSession) send(command string) (string, error) {
+	if _, err := fmt.Fprintf(s.conn, "%s\n", command); err != nil {
+		return "", fmt.Errorf("write console command to %s: %w", s.serial, err)
+	}
+	return s.readReply(command)
+}
+
+func (s *consoleSession) readReply(command string) (string, error) {
+	var lines []string
+	for {
+		_ = s.conn.SetReadDeadline(time.Now().Add(consoleTimeout))
+		line, err := s.r.ReadString('\n')
+		if err != nil {
+			return "", fmt.Errorf("read console reply of %s: %w", s.serial, err)
+		}
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case line == "OK":
+			return strings.Join(lines, "\n"), nil
+		case strings.HasPrefix(line, "KO"):
+			return "", &ConsoleError{Command: command, Message: strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(line, "KO"), ":"))}
+		}
+		lines = append(lines, line)
+	}
+}
+
+func (s *consoleSession) Close() error {
+	_, _ = fmt.Fprint(s.conn, "quit\n")
+	return s.conn.Close()
+}
+
+// consoleAuthToken reads the token file named in the banner, defaulting to the one in $HOME.
+func consoleAuthToken(banner string) (string, error) {
+	path := ""
+	if m := consoleTokenPathRe.FindStringSubmatch(banner); m != nil {
+		path = m[1]
+	} else {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("locate console auth token: %w", err)
+		}
+		path = filepath.Join(home, ".emulator_console_auth_token")
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("read console auth token: %w", err)
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+func consolePort(serial string) (int, error) {
+	port, err := strconv.Atoi(strings.TrimPrefix(serial, "emulator-"))
+	if err != nil || !strings.HasPrefix(serial, "emulator-") {
+		return 0, fmt.Errorf("%s is not an emulator serial (want emulator-<port>)", serial)
+	}
+	return port, nil
+}
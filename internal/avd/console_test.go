@@ -0,0 +1,102 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// startFakeConsole serves the emulator console protocol: an auth banner naming tokenPath,
+// then one reply per command from replies (unknown commands get KO).
+func startFakeConsole(t *testing.T, tokenPath string, replies map[string]string) (string, <-chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	received := make(chan string, 16)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		fmt.Fprintf(conn, "Android Console: Authentication required\r\nAndroid Console: type 'auth <auth_token>' to authenticate\r\n"+
+			"Android Console: you can find your <auth_token> in \r\n'%s'\r\nOK\r\n", tokenPath)
+		r := bufio.NewReader(conn)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimSpace(line)
+			received <- line
+			switch {
+			case line == "quit":
+				return
+			case line == "auth s3cret":
+				fmt.Fprint(conn, "Android Console: type 'help' for a list of commands\r\nOK\r\n")
+			case strings.HasPrefix(line, "auth "):
+				fmt.Fprint(conn, "KO: authentication token does not match ~/.emulator_console_auth_token\r\n")
+			default:
+				if reply, ok := replies[line]; ok {
+					fmt.Fprint(conn, reply+"OK\r\n")
+				} else {
+					fmt.Fprint(conn, "KO: unknown command, try 'help'\r\n")
+				}
+			}
+		}
+	}()
+	return "emulator-" + strconv.Itoa(ln.Addr().(*net.TCPAddr).Port), received
+}
+
+func writeConsoleToken(t *testing.T, token string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), ".emulator_console_auth_token")
+	if err := os.WriteFile(path, []byte(token+"\n"), 0o600); err != nil {
+		t.Fatalf("write token: %v", err)
+	}
+	return path
+}
+
+func TestConsoleAuthenticatesAndReturnsReply(t *testing.T) {
+	serial, received := startFakeConsole(t, writeConsoleToken(t, "s3cret"), map[string]string{
+		"sensor get acceleration": "acceleration = 0:9.81:0\r\n",
+	})
+	out, err := Console(newTestEnv(t), serial, "sensor get acceleration")
+	if err != nil {
+		t.Fatalf("Console: %v", err)
+	}
+	if out != "acceleration = 0:9.81:0" {
+		t.Fatalf("reply = %q", out)
+	}
+	if first := <-received; first != "auth s3cret" {
+		t.Fatalf("expected auth first, got %q", first)
+	}
+}
+
+func TestConsoleReportsKO(t *testing.T) {
+	serial, _ := startFakeConsole(t, writeConsoleToken(t, "s3cret"), nil)
+	_, err := Console(newTestEnv(t), serial, "rotat")
+	var koErr *ConsoleError
+	if !errors.As(err, &koErr) || koErr.Message != "unknown command, try 'help'" {
+		t.Fatalf("expected console KO, got %v", err)
+	}
+}
+
+func TestConsoleRejectsWrongToken(t *testing.T) {
+	serial, _ := startFakeConsole(t, writeConsoleToken(t, "stale"), nil)
+	if _, err := Console(newTestEnv(t), serial, "power status"); err == nil || !strings.Contains(err.Error(), "authentication") {
+		t.Fatalf("expected authentication error, got %v", err)
+	}
+}
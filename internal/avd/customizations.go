@@ -0,0 +1,122 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// customizationsFilename records, next to an AVD's config.ini, what was applied to its userdata.
+const customizationsFilename = "avdctl-customizations.json"
+
+// Customizations are the changes made to an AVD's userdata by avdctl: APKs, expansion files
+// and app data installed by BakeAPK and shell commands run by PrewarmGoldenWithOptions. They are carried into golden
+// manifests and inherited by clones, so MigrateBase can re-apply them on a new API level.
+type Customizations struct {
+	APKs     []string      `json:"apks,omitempty"`     // absolute paths of installed APKs
+	Packages []APKMetadata `json:"packages,omitempty"` // manifest metadata of those APKs
+	OBBs     []string      `json:"obbs,omitempty"`     // absolute paths of pushed expansion files
+	AppData  []AppData     `json:"app_data,omitempty"` // directories copied into app data directories
+	Settings []string      `json:"settings,omitempty"` // adb shell commands, e.g. "settings put global window_animation_scale 0"
+}
+
+// Empty reports whether nothing was recorded.
+func (c Customizations) Empty() bool {
+	return len(c.APKs) == 0 && len(c.Packages) == 0 && len(c.OBBs) == 0 && len(c.AppData) == 0 && len(c.Settings) == 0
+}
+
+// merge appends the entries of other that c does not have yet. A package of other replaces
+// the entry of c with the same name, as reinstalling it does.
+func (c Customizations) merge(other Customizations) Customizations {
+	packages := append([]APKMetadata{}, c.Packages...)
+	for _, pkg := range other.Packages {
+		if i := slices.IndexFunc(packages, func(p APKMetadata) bool { return p.Package == pkg.Package }); i >= 0 {
+			packages[i] = pkg
+		} else {
+			packages = append(packages, pkg)
+		}
+	}
+	appData := append([]AppData{}, c.AppData...)
+	for _, data := range other.AppData {
+		if !slices.Contains(appData, data) {
+			appData = append(appData, data)
+		}
+	}
+	return Customizations{
+		APKs:     appendMissing(c.APKs, other.APKs),
+		Packages: packages,
+		OBBs:     appendMissing(c.OBBs, other.OBBs),
+		AppData:  appData,
+		Settings: appendMissing(c.Settings, other.Settings),
+	}
+}
+
+// packageOf returns the recorded metadata of the APK at path.
+func (c Customizations) packageOf(path string) (APKMetadata, bool) {
+	for _, pkg := range c.Packages {
+		if pkg.Path == path {
+			return pkg, true
+		}
+	}
+	return APKMetadata{}, false
+}
+
+// ReadCustomizations returns the customizations recorded for AVD name (empty if none).
+func ReadCustomizations(env Env, name string) (Customizations, error) {
+	return readCustomizations(filepath.Join(env.AVDHome, name+".avd"))
+}
+
+func readCustomizations(avdDir string) (Customizations, error) {
+	b, err := os.ReadFile(filepath.Join(avdDir, customizationsFilename))
+	if os.IsNotExist(err) {
+		return Customizations{}, nil
+	}
+	if err != nil {
+		return Customizations{}, fmt.Errorf("read customizations: %w", err)
+	}
+	var c Customizations
+	if err := json.Unmarshal(b, &c); err != nil {
+		return Customizations{}, fmt.Errorf("parse customizations: %w", err)
+	}
+	return c, nil
+}
+
+// recordCustomizations merges c into the record of avdDir.
+func recordCustomizations(avdDir string, c Customizations) error {
+	if c.Empty() {
+		return nil
+	}
+	current, err := readCustomizations(avdDir)
+	if err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(current.merge(c), "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(avdDir, customizationsFilename)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(b, '\n'), 0o644); err != nil {
+		return fmt.Errorf("write customizations: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+func appendMissing(dst, src []string) []string {
+	seen := make(map[string]bool, len(dst))
+	for _, s := range dst {
+		seen[s] = true
+	}
+	for _, s := range src {
+		if !seen[s] {
+			dst = append(dst, s)
+			seen[s] = true
+		}
+	}
+	return dst
+}
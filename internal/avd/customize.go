@@ -0,0 +1,137 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// customizeSessionFilename tracks the GUI emulator started by CustomizeStart.
+const customizeSessionFilename = "avdctl-customize.json"
+
+// customizeStopTimeout bounds the wait for the customize emulator to exit before export.
+const customizeStopTimeout = 60 * time.Second
+
+// CustomizeSession describes the GUI emulator started by CustomizeStart for an AVD.
+type CustomizeSession struct {
+	Name      string    `json:"name"`
+	PID       int       `json:"pid"`
+	StartedAt time.Time `json:"started_at"`
+	LogPath   string    `json:"log_path"`
+	Running   bool      `json:"running"`
+	Serial    string    `json:"serial,omitempty"` // once adb sees the emulator
+	Booted    bool      `json:"booted"`
+}
+
+// Uptime is how long the session has been running (zero if it is not).
+func (s CustomizeSession) Uptime() time.Duration {
+	if !s.Running || s.StartedAt.IsZero() {
+		return 0
+	}
+	return time.Since(s.StartedAt)
+}
+
+// CustomizeStatus reports the customize session of AVD name. It returns an error if
+// CustomizeStart was never run (or the session was finished).
+func CustomizeStatus(env Env, name string) (CustomizeSession, error) {
+	session, ok, err := readCustomizeSession(filepath.Join(env.AVDHome, name+".avd"))
+	if err != nil {
+		return session, err
+	}
+	if !ok {
+		return session, fmt.Errorf("no customize session for %s; start one with customize-start", name)
+	}
+	session.Running = customizeProcessAlive(session.PID, name)
+	if session.Running {
+		if procs, err := ListRunning(env); err == nil {
+			for _, p := range procs {
+				if p.Name == name {
+					session.Serial, session.Booted = p.Serial, p.Booted
+					break
+				}
+			}
+		}
+	}
+	return session, nil
+}
+
+func readCustomizeSession(avdDir string) (CustomizeSession, bool, error) {
+	b, err := os.ReadFile(filepath.Join(avdDir, customizeSessionFilename))
+	if os.IsNotExist(err) {
+		return CustomizeSession{}, false, nil
+	}
+	if err != nil {
+		return CustomizeSession{}, false, fmt.Errorf("read customize session: %w", err)
+	}
+	var session CustomizeSession
+	if err := json.Unmarshal(b, &session); err != nil {
+		return CustomizeSession{}, false, fmt.Errorf("parse customize session: %w", err)
+	}
+	return session, true, nil
+}
+
+func writeCustomizeSession(avdDir string, session CustomizeSession) error {
+	session.Running, session.Serial, session.Booted = false, "", false
+	b, err := json.MarshalIndent(session, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(avdDir, customizeSessionFilename), append(b, '\n'), 0o644); err != nil {
+		return fmt.Errorf("write customize session: %w", err)
+	}
+	return nil
+}
+
+// customizeProcessAlive reports whether pid is still the emulator of AVD name, so a recycled
+// pid is not mistaken for the session.
+func customizeProcessAlive(pid int, name string) bool {
+	if pid <= 0 || syscall.Kill(pid, 0) != nil || isZombieProcess(pid) {
+		return false
+	}
+	cmdline, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "cmdline"))
+	if err != nil {
+		return true // no /proc: trust the signal check
+	}
+	return bytes.Contains(cmdline, []byte("-avd\x00"+name+"\x00"))
+}
+
+// finishCustomizeSession flushes and stops the customize emulator of name and waits until it
+// has exited, so the export does not race qemu's last writes to userdata.
+func finishCustomizeSession(env Env, name string) error {
+	avdDir := filepath.Join(env.AVDHome, name+".avd")
+	session, tracked, err := readCustomizeSession(avdDir)
+	if err != nil {
+		return err
+	}
+	if procs, err := ListRunning(env); err == nil {
+		for _, p := range procs {
+			if p.Name == name {
+				_ = run(env, env.ADB, "-s", p.Serial, "shell", "sync")
+				KillEmulator(env, p.Serial)
+				break
+			}
+		}
+	}
+	if tracked {
+		for deadline := time.Now().Add(customizeStopTimeout); customizeProcessAlive(session.PID, name); time.Sleep(500 * time.Millisecond) {
+			if time.Now().After(deadline) {
+				return fmt.Errorf("customize emulator of %s (pid %d) is still running after %s; refusing to export while disk writes may be pending",
+					name, session.PID, customizeStopTimeout)
+			}
+		}
+		if err := os.Remove(filepath.Join(avdDir, customizeSessionFilename)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	syscall.Sync()
+	return nil
+}
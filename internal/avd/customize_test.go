@@ -0,0 +1,61 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestCustomizeStartTracksAndResumesSession(t *testing.T) {
+	env := newTestEnv(t)
+	makeBaseAVD(t, env, "demo")
+	env.Emulator = filepath.Join(t.TempDir(), "emulator")
+	script := "#!/bin/sh\ntrap 'exit 0' INT TERM\nwhile true; do sleep 1; done\n"
+	if err := os.WriteFile(env.Emulator, []byte(script), 0o755); err != nil {
+		t.Fatalf("write emulator stub: %v", err)
+	}
+	if _, err := CustomizeStatus(env, "demo"); err == nil || !strings.Contains(err.Error(), "no customize session") {
+		t.Fatalf("expected no session, got %v", err)
+	}
+
+	logPath, err := CustomizeStart(env, "demo")
+	if err != nil {
+		t.Fatalf("CustomizeStart: %v", err)
+	}
+	session, err := CustomizeStatus(env, "demo")
+	if err != nil {
+		t.Fatalf("CustomizeStatus: %v", err)
+	}
+	t.Cleanup(func() { _ = syscall.Kill(-session.PID, syscall.SIGKILL) })
+	if !session.Running || session.PID == 0 || session.LogPath != logPath || session.Uptime() <= 0 {
+		t.Fatalf("unexpected session %#v", session)
+	}
+
+	resumed, err := CustomizeStart(env, "demo")
+	if err != nil || resumed != logPath {
+		t.Fatalf("resume = %q, %v", resumed, err)
+	}
+	if again, _ := CustomizeStatus(env, "demo"); again.PID != session.PID {
+		t.Fatalf("resume started a new emulator: pid %d, want %d", again.PID, session.PID)
+	}
+
+	_ = syscall.Kill(-session.PID, syscall.SIGTERM)
+	for deadline := time.Now().Add(5 * time.Second); customizeProcessAlive(session.PID, "demo") && time.Now().Before(deadline); {
+		time.Sleep(50 * time.Millisecond)
+	}
+	if stopped, _ := CustomizeStatus(env, "demo"); stopped.Running {
+		t.Fatal("session still reported running after the emulator exited")
+	}
+	if err := finishCustomizeSession(env, "demo"); err != nil {
+		t.Fatalf("finishCustomizeSession: %v", err)
+	}
+	if fileExists(filepath.Join(env.AVDHome, "demo.avd", customizeSessionFilename)) {
+		t.Fatal("session file not removed after finish")
+	}
+}
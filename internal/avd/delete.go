@@ -0,0 +1,225 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// pinFilename marks an AVD that Delete must keep; it holds the reason given to Pin.
+const pinFilename = "avdctl-pin"
+
+// DeleteOptions controls DeleteWithOptions.
+type DeleteOptions struct {
+	// Force deletes the AVD even when DeleteBlockedError would be returned: a running
+	// emulator is stopped first, and clones still linking into a base are left dangling.
+	Force bool
+	// SkipTrash removes the AVD at once instead of moving it to the trash (see Restore).
+	SkipTrash bool
+}
+
+// DeleteBlockedError is returned by Delete when the AVD is running, is a base whose files
+// clones still link to, or is pinned.
+type DeleteBlockedError struct {
+	Name      string
+	Serial    string   // emulator running the AVD, if any
+	Clones    []string // clones with symlinks into the AVD
+	Pinned    bool
+	PinReason string // reason given to Pin, may be empty
+}
+
+func (e *DeleteBlockedError) Error() string {
+	var reasons []string
+	if e.Serial != "" {
+		reasons = append(reasons, fmt.Sprintf("it is running as %s", e.Serial))
+	}
+	if len(e.Clones) > 0 {
+		reasons = append(reasons, fmt.Sprintf("clones link to it: %s", strings.Join(e.Clones, ", ")))
+	}
+	if e.Pinned {
+		pin := "it is pinned"
+		if e.PinReason != "" {
+			pin += " (" + e.PinReason + ")"
+		}
+		reasons = append(reasons, pin)
+	}
+	return fmt.Sprintf("cannot delete AVD %s: %s; force the delete to override", e.Name, strings.Join(reasons, "; "))
+}
+
+// Delete moves an AVD (.avd directory and .ini) to the trash, from which Restore brings it back
+// until Env.TrashRetention has passed. It refuses with *DeleteBlockedError when the AVD is
+// running, referenced by clones or pinned; see DeleteWithOptions.
+func Delete(env Env, name string) error {
+	return DeleteWithOptions(env, name, DeleteOptions{})
+}
+
+// DeleteWithOptions is Delete with opts.Force to override the safety checks.
+func DeleteWithOptions(env Env, name string, opts DeleteOptions) error {
+	_, span := startSpan(env, "avd.Delete", attribute.String("name", name), attribute.Bool("force", opts.Force))
+	defer span.End()
+	fail := func(err error) error {
+		recordSpanError(span, err)
+		return err
+	}
+	if name == "" {
+		return fail(errors.New("empty name"))
+	}
+	avdDir := filepath.Join(env.AVDHome, name+".avd")
+	ini := filepath.Join(env.AVDHome, name+".ini")
+
+	if _, err := os.Stat(avdDir); err != nil {
+		if os.IsNotExist(err) {
+			if _, iniErr := os.Stat(ini); os.IsNotExist(iniErr) {
+				return nil
+			}
+		} else {
+			return fail(err)
+		}
+	}
+
+	blocked, err := deleteBlockers(env, name)
+	if err != nil {
+		return fail(err)
+	}
+	if blocked != nil {
+		if !opts.Force {
+			return fail(blocked)
+		}
+		logEvent(env, "forced delete", "name", name, "blocked_by", blocked.Error())
+		if blocked.Serial != "" {
+			if err := StopBySerial(env, blocked.Serial); err != nil {
+				return fail(fmt.Errorf("stop %s before delete: %w", blocked.Serial, err))
+			}
+		}
+	}
+
+	trashed := !opts.SkipTrash && env.trashEnabled()
+	if trashed {
+		if _, err := moveToTrash(env, TrashAVD, name, avdDir, ini); err != nil {
+			return fail(err)
+		}
+	} else {
+		_ = os.RemoveAll(avdDir)
+		_ = os.Remove(ini)
+	}
+	recordUsage(env, usageDelete, name, "")
+	releaseStickyPort(env, name)
+	logEvent(env, "avd deleted", "name", name, "trashed", trashed)
+	return nil
+}
+
+// deleteBlockers returns what keeps name from being deleted, or nil.
+func deleteBlockers(env Env, name string) (*DeleteBlockedError, error) {
+	blocked := &DeleteBlockedError{Name: name}
+	procs, err := ListRunning(env)
+	if err != nil {
+		return nil, err
+	}
+	for _, proc := range procs {
+		if proc.Name == name {
+			blocked.Serial = proc.Serial
+			break
+		}
+	}
+	avdDir := filepath.Join(env.AVDHome, name+".avd")
+	if blocked.Clones, err = linkingAVDs(env, avdDir); err != nil {
+		return nil, err
+	}
+	blocked.PinReason, blocked.Pinned = pinOf(avdDir)
+	if blocked.Serial == "" && len(blocked.Clones) == 0 && !blocked.Pinned {
+		return nil, nil
+	}
+	return blocked, nil
+}
+
+// linkingAVDs returns the other AVDs in env.AVDHome holding a symlink into avdDir.
+func linkingAVDs(env Env, avdDir string) ([]string, error) {
+	target, err := filepath.Abs(avdDir)
+	if err != nil {
+		return nil, err
+	}
+	if resolved, err := filepath.EvalSymlinks(target); err == nil {
+		target = resolved
+	}
+	dirs, err := filepath.Glob(filepath.Join(env.AVDHome, "*.avd"))
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, dir := range dirs {
+		if filepath.Clean(dir) == filepath.Clean(avdDir) {
+			continue
+		}
+		if linksInto(dir, target, avdDir) {
+			names = append(names, strings.TrimSuffix(filepath.Base(dir), ".avd"))
+		}
+	}
+	return names, nil
+}
+
+func linksInto(dir string, targets ...string) bool {
+	found := false
+	_ = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.Type()&fs.ModeSymlink == 0 {
+			return nil
+		}
+		link, err := os.Readlink(path)
+		if err != nil {
+			return nil
+		}
+		if !filepath.IsAbs(link) {
+			link = filepath.Join(filepath.Dir(path), link)
+		}
+		for _, target := range targets {
+			if rel, err := filepath.Rel(target, link); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				found = true
+				return filepath.SkipAll
+			}
+		}
+		return nil
+	})
+	return found
+}
+
+// Pin protects name from Delete (without DeleteOptions.Force). reason is shown when a delete
+// is refused, e.g. "hand-configured base for API 34".
+func Pin(env Env, name, reason string) error {
+	avdDir := filepath.Join(env.AVDHome, name+".avd")
+	if !fileExists(avdDir) {
+		return fmt.Errorf("AVD %s not found", name)
+	}
+	if err := os.WriteFile(filepath.Join(avdDir, pinFilename), []byte(strings.TrimSpace(reason)+"\n"), 0o644); err != nil {
+		return fmt.Errorf("pin %s: %w", name, err)
+	}
+	logEvent(env, "avd pinned", "name", name, "reason", reason)
+	return nil
+}
+
+// Unpin removes the pin set by Pin. Unpinning an AVD that is not pinned is a no-op.
+func Unpin(env Env, name string) error {
+	avdDir := filepath.Join(env.AVDHome, name+".avd")
+	if !fileExists(avdDir) {
+		return fmt.Errorf("AVD %s not found", name)
+	}
+	if err := os.Remove(filepath.Join(avdDir, pinFilename)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("unpin %s: %w", name, err)
+	}
+	logEvent(env, "avd unpinned", "name", name)
+	return nil
+}
+
+func pinOf(avdDir string) (string, bool) {
+	b, err := os.ReadFile(filepath.Join(avdDir, pinFilename))
+	if err != nil {
+		return "", false
+	}
+	return strings.TrimSpace(string(b)), true
+}
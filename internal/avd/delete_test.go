@@ -0,0 +1,78 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDeleteRefusesLinkedBase(t *testing.T) {
+	env := newTestEnv(t)
+	makeBaseAVD(t, env, "base")
+	if err := os.WriteFile(filepath.Join(env.AVDHome, "base.avd", "advancedFeatures.ini"), []byte("hw.cpu.ncore=2\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CloneFromGolden(env, "base", "w-1", makeGoldenDir(t)); err != nil {
+		t.Fatalf("CloneFromGolden: %v", err)
+	}
+
+	err := Delete(env, "base")
+	var blocked *DeleteBlockedError
+	if !errors.As(err, &blocked) {
+		t.Fatalf("Delete(base) = %v, want *DeleteBlockedError", err)
+	}
+	if !reflect.DeepEqual(blocked.Clones, []string{"w-1"}) || blocked.Pinned || blocked.Serial != "" {
+		t.Fatalf("blocked = %#v", blocked)
+	}
+	if !fileExists(filepath.Join(env.AVDHome, "base.avd")) {
+		t.Fatal("refused delete removed the base")
+	}
+
+	if err := Delete(env, "w-1"); err != nil {
+		t.Fatalf("Delete(clone): %v", err)
+	}
+	if err := Delete(env, "base"); err != nil {
+		t.Fatalf("Delete(base) without clones: %v", err)
+	}
+}
+
+func TestDeletePinned(t *testing.T) {
+	env := newTestEnv(t)
+	makeBaseAVD(t, env, "base")
+	if err := Pin(env, "base", "hand-configured"); err != nil {
+		t.Fatalf("Pin: %v", err)
+	}
+
+	var blocked *DeleteBlockedError
+	if err := Delete(env, "base"); !errors.As(err, &blocked) || !blocked.Pinned || blocked.PinReason != "hand-configured" {
+		t.Fatalf("Delete(pinned) = %v", err)
+	}
+	if err := DeleteWithOptions(env, "base", DeleteOptions{Force: true}); err != nil {
+		t.Fatalf("forced Delete: %v", err)
+	}
+	if fileExists(filepath.Join(env.AVDHome, "base.avd")) {
+		t.Fatal("forced delete kept the AVD")
+	}
+}
+
+func TestUnpin(t *testing.T) {
+	env := newTestEnv(t)
+	makeBaseAVD(t, env, "base")
+	if err := Pin(env, "base", ""); err != nil {
+		t.Fatalf("Pin: %v", err)
+	}
+	if err := Unpin(env, "base"); err != nil {
+		t.Fatalf("Unpin: %v", err)
+	}
+	if err := Unpin(env, "base"); err != nil {
+		t.Fatalf("Unpin again: %v", err)
+	}
+	if err := Delete(env, "base"); err != nil {
+		t.Fatalf("Delete(unpinned): %v", err)
+	}
+}
@@ -0,0 +1,210 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// DeleteTreeOptions controls DeleteTree.
+type DeleteTreeOptions struct {
+	// DryRun only reports the plan; every item is "planned".
+	DryRun bool
+	// Force deletes running and pinned items (see DeleteOptions.Force) and goldens that a
+	// channel still points at; such channels must be promoted again before use.
+	Force bool
+	// SkipTrash removes the items at once instead of moving them to the trash.
+	SkipTrash bool
+}
+
+// DeleteTreeItem is one AVD or golden of a DeleteTree plan.
+type DeleteTreeItem struct {
+	Kind   string `json:"kind"` // clone, golden, base
+	Name   string `json:"name"`
+	Path   string `json:"path"`
+	Status string `json:"status"` // planned, done, failed, skipped
+	Detail string `json:"detail,omitempty"`
+}
+
+// DeleteTreeReport lists the items of a DeleteTree run, in deletion order.
+type DeleteTreeReport struct {
+	Base  string           `json:"base"`
+	Items []DeleteTreeItem `json:"items"`
+}
+
+// Failed reports whether any item failed or was skipped.
+func (r DeleteTreeReport) Failed() bool {
+	for _, item := range r.Items {
+		if item.Status == "failed" || item.Status == "skipped" {
+			return true
+		}
+	}
+	return false
+}
+
+// DeleteTree decommissions base with everything derived from it: the goldens of Env.GoldenDir
+// exported from it, then the clones linking to base or cloned from one of those goldens. Clones
+// are deleted first, then goldens, then base; when an item fails, the items it depends on are
+// skipped, so a failure never leaves a clone without its base.
+func DeleteTree(env Env, base string, opts DeleteTreeOptions) (DeleteTreeReport, error) {
+	_, span := startSpan(env, "avd.DeleteTree", attribute.String("base", base), attribute.Bool("dry_run", opts.DryRun))
+	defer span.End()
+	fail := func(err error) (DeleteTreeReport, error) {
+		recordSpanError(span, err)
+		return DeleteTreeReport{}, err
+	}
+	baseDir := filepath.Join(env.AVDHome, base+".avd")
+	if base == "" || !fileExists(baseDir) {
+		return fail(fmt.Errorf("AVD %s not found", base))
+	}
+	if isCloneDir(baseDir) {
+		return fail(fmt.Errorf("%s is a clone, not a base; use delete", base))
+	}
+	plan, err := deleteTreePlan(env, base)
+	if err != nil {
+		return fail(err)
+	}
+	report := DeleteTreeReport{Base: base, Items: plan}
+	if opts.DryRun {
+		return report, nil
+	}
+
+	channels := map[Channel]ChannelPointer{}
+	if env.GoldenDir != "" {
+		if channels, err = readChannels(env); err != nil {
+			return fail(err)
+		}
+	}
+	var failedClone, failedAny string
+	for i := range report.Items {
+		item := &report.Items[i]
+		blocker := ""
+		switch item.Kind {
+		case "golden":
+			blocker = failedClone
+		case "base":
+			blocker = failedAny
+		}
+		if blocker != "" {
+			item.Status, item.Detail = "skipped", blocker+" was not deleted"
+			continue
+		}
+		var err error
+		if item.Kind == "golden" {
+			err = deleteGolden(env, item.Path, channels, opts)
+		} else {
+			err = DeleteWithOptions(env, item.Name, DeleteOptions{Force: opts.Force, SkipTrash: opts.SkipTrash})
+		}
+		if err != nil {
+			item.Status, item.Detail = "failed", err.Error()
+			if failedAny == "" {
+				failedAny = item.Kind + " " + item.Name
+			}
+			if item.Kind == "clone" && failedClone == "" {
+				failedClone = failedAny
+			}
+			continue
+		}
+		item.Status = "done"
+	}
+	logEvent(env, "delete tree completed", "base", base, "items", len(report.Items), "failed", report.Failed())
+	return report, nil
+}
+
+// deleteTreePlan returns the clones, goldens and base to delete for base, in that order.
+func deleteTreePlan(env Env, base string) ([]DeleteTreeItem, error) {
+	baseDir := filepath.Join(env.AVDHome, base+".avd")
+	var goldens []DeleteTreeItem
+	for _, dir := range goldensOfBase(env, base) {
+		goldens = append(goldens, DeleteTreeItem{Kind: "golden", Name: filepath.Base(dir), Path: dir, Status: "planned"})
+	}
+	sort.Slice(goldens, func(i, j int) bool { return goldens[i].Name < goldens[j].Name })
+
+	clones, err := clonesOfBase(env, base)
+	if err != nil {
+		return nil, err
+	}
+	var items []DeleteTreeItem
+	for _, info := range clones {
+		items = append(items, DeleteTreeItem{Kind: "clone", Name: info.Name, Path: info.Path, Status: "planned"})
+	}
+	items = append(items, goldens...)
+	return append(items, DeleteTreeItem{Kind: "base", Name: base, Path: baseDir, Status: "planned"}), nil
+}
+
+// goldensOfBase returns the goldens of Env.GoldenDir exported from base, by fingerprint.
+func goldensOfBase(env Env, base string) map[string]string {
+	goldens := map[string]string{}
+	for fingerprint, dir := range registryFingerprints(env) {
+		if manifest, err := ReadGoldenManifest(dir); err == nil && manifest.Source == base {
+			goldens[fingerprint] = dir
+		}
+	}
+	return goldens
+}
+
+// clonesOfBase returns the clones linking to base or cloned from one of its goldens in
+// Env.GoldenDir, in List order.
+func clonesOfBase(env Env, base string) ([]Info, error) {
+	goldens := goldensOfBase(env, base)
+	linked, err := linkingAVDs(env, filepath.Join(env.AVDHome, base+".avd"))
+	if err != nil {
+		return nil, err
+	}
+	isLinked := map[string]bool{}
+	for _, name := range linked {
+		isLinked[name] = true
+	}
+	infos, err := List(env)
+	if err != nil {
+		return nil, err
+	}
+	var clones []Info
+	for _, info := range infos {
+		if info.Name == base || !isCloneDir(info.Path) {
+			continue
+		}
+		fingerprint, _ := os.ReadFile(filepath.Join(info.Path, cloneFingerprintFilename))
+		if _, ok := goldens[strings.TrimSpace(string(fingerprint))]; isLinked[info.Name] || ok {
+			clones = append(clones, info)
+		}
+	}
+	return clones, nil
+}
+
+// deleteGolden trashes or removes the registry golden goldenDir, refusing one a channel points
+// at unless opts.Force.
+func deleteGolden(env Env, goldenDir string, channels map[Channel]ChannelPointer, opts DeleteTreeOptions) error {
+	var pointing []string
+	for channel, pointer := range channels {
+		if sameDir(pointer.Golden, goldenDir) {
+			pointing = append(pointing, string(channel))
+		}
+	}
+	if len(pointing) > 0 && !opts.Force {
+		sort.Strings(pointing)
+		return fmt.Errorf("channel %s points at it; promote another golden first", strings.Join(pointing, ", "))
+	}
+	if !opts.SkipTrash && env.trashEnabled() {
+		_, err := moveToTrash(env, TrashGolden, filepath.Base(goldenDir), goldenDir)
+		return err
+	}
+	if err := os.RemoveAll(goldenDir); err != nil {
+		return err
+	}
+	logEvent(env, "golden deleted", "path", goldenDir, "channels", strings.Join(pointing, ","))
+	return nil
+}
+
+func sameDir(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && filepath.Clean(absA) == filepath.Clean(absB)
+}
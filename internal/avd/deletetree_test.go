@@ -0,0 +1,69 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func treeItems(report DeleteTreeReport) []string {
+	var items []string
+	for _, item := range report.Items {
+		items = append(items, item.Kind+":"+item.Name+":"+item.Status)
+	}
+	return items
+}
+
+func TestDeleteTreePlansAndDeletesInOrder(t *testing.T) {
+	env := newTestEnv(t)
+	env.GoldenDir = t.TempDir()
+	makeBaseAVD(t, env, "base-a35")
+	makeBaseAVD(t, env, "base-a34")
+	v5 := newRegistryGolden(t, env, "v5")
+	if _, err := CloneFromGolden(env, "base-a35", "w-1", v5); err != nil {
+		t.Fatalf("CloneFromGolden: %v", err)
+	}
+	if _, err := CloneFromGolden(env, "base-a34", "other", makeGoldenDir(t)); err != nil {
+		t.Fatalf("CloneFromGolden: %v", err)
+	}
+	if _, err := Promote(env, v5, ChannelProduction); err != nil {
+		t.Fatalf("Promote: %v", err)
+	}
+
+	plan, err := DeleteTree(env, "base-a35", DeleteTreeOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("DeleteTree(dry run): %v", err)
+	}
+	want := []string{"clone:w-1:planned", "golden:v5:planned", "base:base-a35:planned"}
+	if got := treeItems(plan); len(got) != len(want) || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+		t.Fatalf("plan = %v, want %v", got, want)
+	}
+	if !fileExists(filepath.Join(env.AVDHome, "w-1.avd")) {
+		t.Fatal("dry run deleted the clone")
+	}
+
+	// production still points at v5, so the golden and then the base are kept.
+	report, err := DeleteTree(env, "base-a35", DeleteTreeOptions{})
+	if err != nil {
+		t.Fatalf("DeleteTree: %v", err)
+	}
+	got := treeItems(report)
+	if got[0] != "clone:w-1:done" || got[1] != "golden:v5:failed" || got[2] != "base:base-a35:skipped" || !report.Failed() {
+		t.Fatalf("report = %v", got)
+	}
+
+	report, err = DeleteTree(env, "base-a35", DeleteTreeOptions{Force: true})
+	if err != nil || report.Failed() {
+		t.Fatalf("DeleteTree(force) = %v, %v", treeItems(report), err)
+	}
+	for _, gone := range []string{filepath.Join(env.AVDHome, "base-a35.avd"), v5} {
+		if fileExists(gone) {
+			t.Fatalf("%s not deleted", gone)
+		}
+	}
+	if !fileExists(filepath.Join(env.AVDHome, "other.avd")) || !fileExists(filepath.Join(env.AVDHome, "base-a34.avd")) {
+		t.Fatal("unrelated AVDs deleted")
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// SetDisplaySize overrides the resolution (width x height pixels) and density (dpi) of the
+// emulator behind serial with wm size/density. A zero width and height, or a zero density,
+// leaves that setting alone. The override lasts until ResetDisplaySize or a cold boot.
+func SetDisplaySize(env Env, serial string, width, height, density int) error {
+	_, span := startSpan(env, "avd.SetDisplaySize",
+		attribute.String("serial", serial),
+		attribute.Int("width", width),
+		attribute.Int("height", height),
+		attribute.Int("density", density),
+	)
+	defer span.End()
+	fail := func(err error) error {
+		recordSpanError(span, err)
+		return err
+	}
+	var settings []string
+	switch {
+	case width < 0 || height < 0 || density < 0:
+		return fail(errors.New("display size and density must not be negative"))
+	case (width == 0) != (height == 0):
+		return fail(errors.New("set both width and height"))
+	case width > 0:
+		settings = append(settings, fmt.Sprintf("wm size %dx%d", width, height))
+	}
+	if density > 0 {
+		settings = append(settings, "wm density "+strconv.Itoa(density))
+	}
+	if len(settings) == 0 {
+		return fail(errors.New("nothing to set: give a size, a density or both"))
+	}
+	return fail(applySettings(env, serial, settings))
+}
+
+// ResetDisplaySize restores the resolution and density of the device profile.
+func ResetDisplaySize(env Env, serial string) error {
+	_, span := startSpan(env, "avd.ResetDisplaySize", attribute.String("serial", serial))
+	defer span.End()
+	err := applySettings(env, serial, []string{"wm size reset", "wm density reset"})
+	recordSpanError(span, err)
+	return err
+}
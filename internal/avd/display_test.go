@@ -0,0 +1,41 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSetAndResetDisplaySize(t *testing.T) {
+	env := newTestEnv(t)
+	logPath := filepath.Join(t.TempDir(), "adb.log")
+	if err := os.WriteFile(env.ADB, []byte("#!/bin/sh\necho \"$@\" >> "+logPath+"\n"), 0o755); err != nil {
+		t.Fatalf("write adb stub: %v", err)
+	}
+	if err := SetDisplaySize(env, "emulator-5580", 1200, 1920, 240); err != nil {
+		t.Fatalf("SetDisplaySize: %v", err)
+	}
+	if err := SetDisplaySize(env, "emulator-5580", 0, 0, 320); err != nil {
+		t.Fatalf("SetDisplaySize density only: %v", err)
+	}
+	if err := ResetDisplaySize(env, "emulator-5580"); err != nil {
+		t.Fatalf("ResetDisplaySize: %v", err)
+	}
+	log, _ := os.ReadFile(logPath)
+	want := "-s emulator-5580 shell wm size 1200x1920\n" +
+		"-s emulator-5580 shell wm density 240\n" +
+		"-s emulator-5580 shell wm density 320\n" +
+		"-s emulator-5580 shell wm size reset\n" +
+		"-s emulator-5580 shell wm density reset\n"
+	if string(log) != want {
+		t.Fatalf("adb calls = %q, want %q", log, want)
+	}
+	for _, bad := range [][3]int{{1200, 0, 0}, {0, 0, 0}, {-1, 10, 0}} {
+		if err := SetDisplaySize(env, "emulator-5580", bad[0], bad[1], bad[2]); err == nil {
+			t.Fatalf("SetDisplaySize(%v) should fail", bad)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// StandbyBucket is an app standby bucket, as accepted by am set-standby-bucket.
+type StandbyBucket string
+
+const (
+	StandbyActive     StandbyBucket = "active"
+	StandbyWorkingSet StandbyBucket = "working_set"
+	StandbyFrequent   StandbyBucket = "frequent"
+	StandbyRare       StandbyBucket = "rare"
+	StandbyRestricted StandbyBucket = "restricted" // Android 12+
+)
+
+// DisableDoze turns off light and deep doze on serial (dumpsys deviceidle disable), so a
+// long-lived clone does not defer jobs, alarms and network access of the app under test.
+// Like the real setting, it does not survive a reboot.
+func DisableDoze(env Env, serial string) error {
+	_, span := startSpan(env, "avd.DisableDoze", attribute.String("serial", serial))
+	defer span.End()
+	err := applySetting(env, serial, "dumpsys deviceidle disable")
+	recordSpanError(span, err)
+	return err
+}
+
+// EnableDoze restores doze after DisableDoze.
+func EnableDoze(env Env, serial string) error {
+	_, span := startSpan(env, "avd.EnableDoze", attribute.String("serial", serial))
+	defer span.End()
+	err := applySetting(env, serial, "dumpsys deviceidle enable")
+	recordSpanError(span, err)
+	return err
+}
+
+// SetAppStandbyBucket moves pkg into bucket on serial; StandbyActive keeps its background
+// work from being throttled.
+func SetAppStandbyBucket(env Env, serial, pkg string, bucket StandbyBucket) error {
+	_, span := startSpan(env, "avd.SetAppStandbyBucket",
+		attribute.String("serial", serial),
+		attribute.String("package", pkg),
+		attribute.String("bucket", string(bucket)),
+	)
+	defer span.End()
+	var err error
+	switch bucket {
+	case StandbyActive, StandbyWorkingSet, StandbyFrequent, StandbyRare, StandbyRestricted:
+		if pkg == "" {
+			err = fmt.Errorf("package name is required")
+		} else {
+			err = applySetting(env, serial, fmt.Sprintf("am set-standby-bucket %s %s", pkg, bucket))
+		}
+	default:
+		err = fmt.Errorf("unknown standby bucket %q (want active, working_set, frequent, rare or restricted)", bucket)
+	}
+	recordSpanError(span, err)
+	return err
+}
@@ -0,0 +1,38 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDozeAndStandbyBucketCommands(t *testing.T) {
+	env := newTestEnv(t)
+	logPath := filepath.Join(t.TempDir(), "adb.log")
+	if err := os.WriteFile(env.ADB, []byte("#!/bin/sh\necho \"$@\" >> "+logPath+"\n"), 0o755); err != nil {
+		t.Fatalf("write adb stub: %v", err)
+	}
+	if err := DisableDoze(env, "emulator-5580"); err != nil {
+		t.Fatalf("DisableDoze: %v", err)
+	}
+	if err := SetAppStandbyBucket(env, "emulator-5580", "com.example.app", StandbyActive); err != nil {
+		t.Fatalf("SetAppStandbyBucket: %v", err)
+	}
+	if err := EnableDoze(env, "emulator-5580"); err != nil {
+		t.Fatalf("EnableDoze: %v", err)
+	}
+	log, _ := os.ReadFile(logPath)
+	want := "-s emulator-5580 shell dumpsys deviceidle disable\n" +
+		"-s emulator-5580 shell am set-standby-bucket com.example.app active\n" +
+		"-s emulator-5580 shell dumpsys deviceidle enable\n"
+	if string(log) != want {
+		t.Fatalf("adb calls = %q, want %q", log, want)
+	}
+	if err := SetAppStandbyBucket(env, "emulator-5580", "com.example.app", StandbyBucket("never")); err == nil || !strings.Contains(err.Error(), "unknown standby bucket") {
+		t.Fatalf("expected unknown bucket error, got %v", err)
+	}
+}
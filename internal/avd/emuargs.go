@@ -0,0 +1,372 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// EmulatorArgs describes the flags of an emulator launch. Every start path builds one (see
+// HeadlessArgs) so the flags stay the same between Run, Prewarm, Bake and Customize; Args
+// validates the combination and String prints the effective command line. Launches add the
+// flags of Env.DefaultEmulatorArgs the caller does not set.
+type EmulatorArgs struct {
+	Name    string
+	Port    int // console port, even; adb uses Port+1 unless ADBPort is set
+	ADBPort int // with Port, passed as -ports
+
+	NoWindow     bool
+	NoBootAnim   bool
+	NoAudio      bool
+	NoMetrics    bool
+	NoLocationUI bool
+	SkipADBAuth  bool
+	ReadOnly     bool   // allow other instances of the same AVD
+	GPU          string // -gpu mode, e.g. "swiftshader_indirect"
+	Logcat       string // -logcat filter, e.g. "*:S"
+
+	Snapshot       string // snapshot to boot from; conflicts with NoSnapshot and NoSnapshotLoad
+	NoSnapshot     bool
+	NoSnapshotLoad bool
+	NoSnapshotSave bool
+
+	WifiMAC     string
+	DNS         []string          // -dns-server addresses
+	Props       map[string]string // -prop key=value, emitted sorted by key
+	CameraBack  string            // "emulated", "virtualscene", "webcamN" or "none"
+	CameraFront string            // "emulated", "webcamN" or "none"
+	// Features override the host's advancedFeatures.ini for this launch (true = on), passed
+	// as -feature (see ParseFeatureOverrides).
+	Features map[string]bool
+
+	// Extra flags are appended verbatim, after the typed ones.
+	Extra []string
+}
+
+// HeadlessArgs returns the flags avdctl starts name with on port: no window, audio or boot
+// animation, a cold boot that saves no snapshot, software GPU and a silent logcat.
+func HeadlessArgs(name string, port int) EmulatorArgs {
+	return EmulatorArgs{
+		Name:           name,
+		Port:           port,
+		NoWindow:       true,
+		NoBootAnim:     true,
+		NoSnapshot:     true,
+		NoSnapshotLoad: true,
+		NoSnapshotSave: true,
+		SkipADBAuth:    true,
+		NoMetrics:      true,
+		NoLocationUI:   true,
+		NoAudio:        true,
+		ReadOnly:       true,
+		GPU:            "swiftshader_indirect",
+		Logcat:         "*:S",
+	}
+}
+
+var (
+	emulatorGPUModes = []string{"auto", "auto-no-window", "host", "swiftshader_indirect", "angle_indirect", "guest", "off"}
+	webcamPattern    = regexp.MustCompile(`^webcam[0-9]+$`)
+)
+
+// Console ports the emulator accepts for -port.
+const (
+	minConsolePort = 5554
+	maxConsolePort = 5800
+)
+
+// checkConsolePort reports why the emulator would reject port as its console port, if it would.
+func checkConsolePort(port int) error {
+	var errs []error
+	// emulator uses a pair: <port> and <port+1>; must be even
+	if port%2 != 0 {
+		errs = append(errs, fmt.Errorf("port %d is odd; emulator requires even port numbers (uses port and port+1)", port))
+	}
+	if port < minConsolePort || port > maxConsolePort {
+		errs = append(errs, fmt.Errorf("port %d out of valid range (%d-%d)", port, minConsolePort, maxConsolePort))
+	}
+	return errors.Join(errs...)
+}
+
+// Validate reports flag combinations the emulator would reject or silently ignore.
+func (a EmulatorArgs) Validate() error {
+	var errs []error
+	if a.Name == "" {
+		errs = append(errs, errors.New("empty AVD name"))
+	}
+	if a.Port != 0 {
+		if err := checkConsolePort(a.Port); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if a.ADBPort != 0 && (a.Port == 0 || a.ADBPort == a.Port) {
+		errs = append(errs, fmt.Errorf("adb port %d needs a different console port", a.ADBPort))
+	}
+	if a.GPU != "" && !slices.Contains(emulatorGPUModes, a.GPU) {
+		errs = append(errs, fmt.Errorf("unknown gpu mode %q (want one of %s)", a.GPU, strings.Join(emulatorGPUModes, ", ")))
+	}
+	if a.Snapshot != "" && (a.NoSnapshot || a.NoSnapshotLoad) {
+		errs = append(errs, fmt.Errorf("snapshot %s cannot be loaded with -no-snapshot or -no-snapshot-load", a.Snapshot))
+	}
+	for _, dns := range a.DNS {
+		if net.ParseIP(dns) == nil {
+			errs = append(errs, fmt.Errorf("dns server %q is not an IP address", dns))
+		}
+	}
+	for key := range a.Props {
+		if key == "" || strings.ContainsAny(key, "= \t") {
+			errs = append(errs, fmt.Errorf("invalid property name %q", key))
+		}
+	}
+	for name := range a.Features {
+		if !featureNameRe.MatchString(name) {
+			errs = append(errs, fmt.Errorf("invalid feature name %q", name))
+		}
+	}
+	if err := checkCamera("back", a.CameraBack, "virtualscene"); err != nil {
+		errs = append(errs, err)
+	}
+	if err := checkCamera("front", a.CameraFront); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+func checkCamera(side, mode string, extra ...string) error {
+	if mode == "" || mode == "emulated" || mode == "none" || webcamPattern.MatchString(mode) || slices.Contains(extra, mode) {
+		return nil
+	}
+	return fmt.Errorf("unknown %s camera %q", side, mode)
+}
+
+// Args validates a and returns its flags.
+func (a EmulatorArgs) Args() ([]string, error) {
+	if err := a.Validate(); err != nil {
+		return nil, err
+	}
+	return a.flags(), nil
+}
+
+// String returns the flags as a shell-quoted command line, without validating them.
+func (a EmulatorArgs) String() string {
+	return shellJoin(a.flags())
+}
+
+func (a EmulatorArgs) flags() []string {
+	args := []string{"-avd", a.Name}
+	switch {
+	case a.Port != 0 && a.ADBPort != 0:
+		args = append(args, "-ports", fmt.Sprintf("%d,%d", a.Port, a.ADBPort))
+	case a.Port != 0:
+		args = append(args, "-port", strconv.Itoa(a.Port))
+	}
+	for _, f := range []struct {
+		on   bool
+		flag string
+	}{
+		{a.NoWindow, "-no-window"},
+		{a.NoBootAnim, "-no-boot-anim"},
+		{a.NoSnapshot, "-no-snapshot"},
+		{a.NoSnapshotLoad, "-no-snapshot-load"},
+		{a.NoSnapshotSave, "-no-snapshot-save"},
+		{a.SkipADBAuth, "-skip-adb-auth"},
+		{a.NoMetrics, "-no-metrics"},
+		{a.NoLocationUI, "-no-location-ui"},
+		{a.NoAudio, "-no-audio"},
+		{a.ReadOnly, "-read-only"},
+	} {
+		if f.on {
+			args = append(args, f.flag)
+		}
+	}
+	for _, f := range [][2]string{
+		{"-gpu", a.GPU},
+		{"-logcat", a.Logcat},
+		{"-snapshot", a.Snapshot},
+		{"-wifi-mac-address", a.WifiMAC},
+		{"-dns-server", strings.Join(a.DNS, ",")},
+		{"-camera-back", a.CameraBack},
+		{"-camera-front", a.CameraFront},
+	} {
+		if f[1] != "" {
+			args = append(args, f[0], f[1])
+		}
+	}
+	if len(a.Features) > 0 {
+		args = append(args, "-feature", featureList(a.Features))
+	}
+	keys := make([]string, 0, len(a.Props))
+	for key := range a.Props {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		args = append(args, "-prop", key+"="+a.Props[key])
+	}
+	return append(args, a.Extra...)
+}
+
+// withExtra folds the flags of an extraArgs list into the typed fields, so "-gpu host" replaces
+// the default mode instead of repeating the flag, and "-snapshot NAME" turns the cold-boot
+// flags off. Flags it does not know go to Extra in order.
+func (a EmulatorArgs) withExtra(extra ...string) EmulatorArgs {
+	a.Props, a.Features = maps.Clone(a.Props), maps.Clone(a.Features)
+	a.DNS, a.Extra = slices.Clone(a.DNS), slices.Clone(a.Extra)
+	bools := map[string]*bool{
+		"-no-window":        &a.NoWindow,
+		"-no-boot-anim":     &a.NoBootAnim,
+		"-no-audio":         &a.NoAudio,
+		"-no-metrics":       &a.NoMetrics,
+		"-no-location-ui":   &a.NoLocationUI,
+		"-skip-adb-auth":    &a.SkipADBAuth,
+		"-read-only":        &a.ReadOnly,
+		"-no-snapshot":      &a.NoSnapshot,
+		"-no-snapshot-load": &a.NoSnapshotLoad,
+		"-no-snapshot-save": &a.NoSnapshotSave,
+	}
+	values := map[string]*string{
+		"-gpu":              &a.GPU,
+		"-logcat":           &a.Logcat,
+		"-wifi-mac-address": &a.WifiMAC,
+		"-camera-back":      &a.CameraBack,
+		"-camera-front":     &a.CameraFront,
+	}
+	snapshot := false
+	for i := 0; i < len(extra); i++ {
+		flag := extra[i]
+		if p, ok := bools[flag]; ok {
+			*p = true
+			continue
+		}
+		if i+1 >= len(extra) {
+			a.Extra = append(a.Extra, flag)
+			continue
+		}
+		value := extra[i+1]
+		switch p, ok := values[flag]; {
+		case ok:
+			*p = value
+		case flag == "-snapshot":
+			a.Snapshot, snapshot = value, true
+		case flag == "-dns-server":
+			a.DNS = append(a.DNS, strings.Split(value, ",")...)
+		case flag == "-feature":
+			a.Features = parseFeatureList(a.Features, value)
+		case flag == "-prop" && strings.Contains(value, "="):
+			key, val, _ := strings.Cut(value, "=")
+			if a.Props == nil {
+				a.Props = map[string]string{}
+			}
+			a.Props[key] = val
+		default:
+			a.Extra = append(a.Extra, flag)
+			continue
+		}
+		i++
+	}
+	if snapshot {
+		// Loading a snapshot; -no-snapshot-save stays, so exiting saves nothing.
+		a.NoSnapshot, a.NoSnapshotLoad = false, false
+	}
+	return a
+}
+
+// withDefaults adds the flags of defaults (see Env.DefaultEmulatorArgs) that a does not decide
+// itself: switches are turned on, valued flags only fill empty fields and unknown flags go before
+// a's own Extra.
+func (a EmulatorArgs) withDefaults(defaults []string) EmulatorArgs {
+	if len(defaults) == 0 {
+		return a
+	}
+	d := EmulatorArgs{}.withExtra(defaults...)
+	a.NoWindow = a.NoWindow || d.NoWindow
+	a.NoBootAnim = a.NoBootAnim || d.NoBootAnim
+	a.NoAudio = a.NoAudio || d.NoAudio
+	a.NoMetrics = a.NoMetrics || d.NoMetrics
+	a.NoLocationUI = a.NoLocationUI || d.NoLocationUI
+	a.SkipADBAuth = a.SkipADBAuth || d.SkipADBAuth
+	a.ReadOnly = a.ReadOnly || d.ReadOnly
+	a.NoSnapshotSave = a.NoSnapshotSave || d.NoSnapshotSave
+	if a.Snapshot == "" {
+		// A snapshot load of the caller wins over a default cold boot.
+		a.NoSnapshot = a.NoSnapshot || d.NoSnapshot
+		a.NoSnapshotLoad = a.NoSnapshotLoad || d.NoSnapshotLoad
+	}
+	for _, f := range []struct{ dst, src *string }{
+		{&a.GPU, &d.GPU},
+		{&a.Logcat, &d.Logcat},
+		{&a.Snapshot, &d.Snapshot},
+		{&a.WifiMAC, &d.WifiMAC},
+		{&a.CameraBack, &d.CameraBack},
+		{&a.CameraFront, &d.CameraFront},
+	} {
+		if *f.dst == "" {
+			*f.dst = *f.src
+		}
+	}
+	if len(a.DNS) == 0 {
+		a.DNS = d.DNS
+	}
+	a.Features = maps.Clone(a.Features)
+	for name, on := range d.Features {
+		if _, ok := a.Features[name]; !ok {
+			if a.Features == nil {
+				a.Features = map[string]bool{}
+			}
+			a.Features[name] = on
+		}
+	}
+	a.Props = maps.Clone(a.Props)
+	for key, value := range d.Props {
+		if _, ok := a.Props[key]; !ok {
+			if a.Props == nil {
+				a.Props = map[string]string{}
+			}
+			a.Props[key] = value
+		}
+	}
+	a.Extra = append(d.Extra, a.Extra...)
+	return a
+}
+
+// defaultEmulatorArgs returns the flags of Env.EmulatorArgsFile followed by
+// Env.DefaultEmulatorArgs.
+func defaultEmulatorArgs(env Env) ([]string, error) {
+	var args []string
+	if env.EmulatorArgsFile != "" {
+		b, err := os.ReadFile(env.EmulatorArgsFile)
+		if err != nil {
+			return nil, fmt.Errorf("read emulator args file: %w", err)
+		}
+		for _, line := range strings.Split(string(b), "\n") {
+			line, _, _ = strings.Cut(line, "#")
+			args = append(args, strings.Fields(line)...)
+		}
+	}
+	return append(args, env.DefaultEmulatorArgs...), nil
+}
+
+func shellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = shellQuote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+func shellQuote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\n'\"\\$`*?[]{}()<>|&;#~!") {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
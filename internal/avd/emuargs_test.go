@@ -0,0 +1,108 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestHeadlessArgsFlags(t *testing.T) {
+	args, err := HeadlessArgs("w-1", 5580).Args()
+	if err != nil {
+		t.Fatalf("Args: %v", err)
+	}
+	want := []string{
+		"-avd", "w-1", "-port", "5580",
+		"-no-window", "-no-boot-anim", "-no-snapshot", "-no-snapshot-load", "-no-snapshot-save",
+		"-skip-adb-auth", "-no-metrics", "-no-location-ui", "-no-audio", "-read-only",
+		"-gpu", "swiftshader_indirect", "-logcat", "*:S",
+	}
+	if !slices.Equal(args, want) {
+		t.Fatalf("HeadlessArgs = %v\nwant %v", args, want)
+	}
+	if got := HeadlessArgs("w-1", 5580).String(); !strings.Contains(got, "-logcat '*:S'") {
+		t.Fatalf("String did not quote the logcat filter: %s", got)
+	}
+}
+
+func TestEmulatorArgsWithExtraFoldsKnownFlags(t *testing.T) {
+	a := HeadlessArgs("w-1", 5580).withExtra(
+		"-snapshot", hibernateSnapshot, "-gpu", "host", "-prop", "persist.sys.locale=it-IT",
+		"-dns-server", "1.1.1.1,8.8.8.8", "-data", "/tmp/run/userdata-qemu.img", "-verbose",
+	)
+	if a.NoSnapshot || a.NoSnapshotLoad || !a.NoSnapshotSave || a.Snapshot != hibernateSnapshot {
+		t.Fatalf("snapshot flags = %+v", a)
+	}
+	args, err := a.Args()
+	if err != nil {
+		t.Fatalf("Args: %v", err)
+	}
+	if n := strings.Count(strings.Join(args, " "), "-gpu"); n != 1 || !slices.Contains(args, "host") {
+		t.Fatalf("expected -gpu host once: %v", args)
+	}
+	tail := []string{"-prop", "persist.sys.locale=it-IT", "-data", "/tmp/run/userdata-qemu.img", "-verbose"}
+	if !slices.Equal(args[len(args)-len(tail):], tail) || !slices.Contains(args, "1.1.1.1,8.8.8.8") {
+		t.Fatalf("args = %v", args)
+	}
+}
+
+func TestEmulatorArgsValidate(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		edit func(*EmulatorArgs)
+		want string
+	}{
+		{"odd port", func(a *EmulatorArgs) { a.Port = 5581 }, "is odd"},
+		{"port range", func(a *EmulatorArgs) { a.Port = 6000 }, "out of valid range"},
+		{"gpu", func(a *EmulatorArgs) { a.GPU = "vulkan" }, "unknown gpu mode"},
+		{"snapshot", func(a *EmulatorArgs) { a.Snapshot = "s" }, "cannot be loaded"},
+		{"dns", func(a *EmulatorArgs) { a.DNS = []string{"dns.example"} }, "not an IP address"},
+		{"prop", func(a *EmulatorArgs) { a.Props = map[string]string{"a=b": "c"} }, "invalid property"},
+		{"camera", func(a *EmulatorArgs) { a.CameraFront = "virtualscene" }, "front camera"},
+	} {
+		a := HeadlessArgs("w-1", 5580)
+		tc.edit(&a)
+		if err := a.Validate(); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("%s: Validate() = %v, want %q", tc.name, err, tc.want)
+		}
+	}
+	a := HeadlessArgs("w-1", 5580)
+	a.CameraBack, a.CameraFront = "virtualscene", "webcam0"
+	if err := a.Validate(); err != nil {
+		t.Fatalf("valid cameras rejected: %v", err)
+	}
+}
+
+func TestEmulatorArgsWithDefaults(t *testing.T) {
+	env := newTestEnv(t)
+	env.EmulatorArgsFile = filepath.Join(t.TempDir(), "farm.args")
+	if err := os.WriteFile(env.EmulatorArgsFile, []byte("# farm-wide\n-gpu host -prop a=file\n-restart-when-stalled\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	env.DefaultEmulatorArgs = []string{"-prop", "b=env", "-no-metrics"}
+	defaults, err := defaultEmulatorArgs(env)
+	if err != nil {
+		t.Fatalf("defaultEmulatorArgs: %v", err)
+	}
+
+	a := EmulatorArgs{Name: "w-1", Props: map[string]string{"a": "caller"}}.withDefaults(defaults)
+	if a.GPU != "host" || !a.NoMetrics || a.Props["a"] != "caller" || a.Props["b"] != "env" {
+		t.Fatalf("withDefaults = %+v", a)
+	}
+	if want := []string{"-restart-when-stalled"}; !slices.Equal(a.Extra, want) {
+		t.Fatalf("Extra = %v, want %v", a.Extra, want)
+	}
+	if h := HeadlessArgs("w-1", 5580).withDefaults(defaults); h.GPU != "swiftshader_indirect" {
+		t.Fatalf("default overrode the caller's gpu: %s", h.GPU)
+	}
+
+	env.EmulatorArgsFile = filepath.Join(t.TempDir(), "missing.args")
+	if _, err := defaultEmulatorArgs(env); err == nil {
+		t.Fatal("expected an error for a missing args file")
+	}
+}
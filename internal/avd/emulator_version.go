@@ -0,0 +1,173 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// EmulatorVersion is the parsed output of `emulator -version`.
+type EmulatorVersion struct {
+	Major int    `json:"major"`
+	Minor int    `json:"minor"`
+	Patch int    `json:"patch"`
+	Build string `json:"build,omitempty"` // build_id, when reported
+}
+
+func (v EmulatorVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// AtLeast reports whether v >= major.minor.patch.
+func (v EmulatorVersion) AtLeast(major, minor, patch int) bool {
+	if v.Major != major {
+		return v.Major > major
+	}
+	if v.Minor != minor {
+		return v.Minor > minor
+	}
+	return v.Patch >= patch
+}
+
+// EmulatorFeatures lists version-dependent emulator capabilities avdctl relies on.
+type EmulatorFeatures struct {
+	SnapshotFlags bool `json:"snapshot_flags"` // -no-snapshot-load / -no-snapshot-save
+	QtHideWindow  bool `json:"qt_hide_window"` // -qt-hide-window
+	GRPC          bool `json:"grpc"`           // -grpc console endpoint
+	WifiMAC       bool `json:"wifi_mac"`       // -wifi-mac-address
+}
+
+// Features returns the capabilities available in v.
+func (v EmulatorVersion) Features() EmulatorFeatures {
+	return EmulatorFeatures{
+		SnapshotFlags: v.AtLeast(27, 0, 0),
+		QtHideWindow:  v.AtLeast(29, 0, 0),
+		GRPC:          v.AtLeast(30, 0, 0),
+		WifiMAC:       v.AtLeast(31, 0, 0),
+	}
+}
+
+var emulatorVersionRE = regexp.MustCompile(`version (\d+)\.(\d+)\.(\d+)(?:\.\d+)?(?:.*build_id (\w+))?`)
+
+func parseEmulatorVersion(out string) (EmulatorVersion, error) {
+	m := emulatorVersionRE.FindStringSubmatch(out)
+	if m == nil {
+		return EmulatorVersion{}, fmt.Errorf("unrecognized emulator version output: %q", strings.TrimSpace(firstLine(out)))
+	}
+	major, _ := strconv.Atoi(m[1])
+	minor, _ := strconv.Atoi(m[2])
+	patch, _ := strconv.Atoi(m[3])
+	return EmulatorVersion{Major: major, Minor: minor, Patch: patch, Build: m[4]}, nil
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
+
+type emulatorVersionResult struct {
+	version EmulatorVersion
+	err     error
+}
+
+// emulatorVersions caches detection per emulator binary for the lifetime of the process.
+var emulatorVersions sync.Map
+
+// DetectEmulatorVersion runs `emulator -version` once per binary and caches the result.
+func DetectEmulatorVersion(env Env) (EmulatorVersion, error) {
+	if env.Emulator == "" {
+		return EmulatorVersion{}, errors.New("emulator binary not configured")
+	}
+	if cached, ok := emulatorVersions.Load(env.Emulator); ok {
+		res := cached.(emulatorVersionResult)
+		return res.version, res.err
+	}
+	_, span := startSpan(env, "avd.DetectEmulatorVersion", attribute.String("emulator", env.Emulator))
+	defer span.End()
+
+	ctx := env.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+	out, errOut, err := runCommandOutputWithEnv(ctx, nil, nil, env.Emulator, "-version")
+	var version EmulatorVersion
+	if err != nil {
+		err = fmt.Errorf("emulator -version: %w", err)
+	} else {
+		version, err = parseEmulatorVersion(out + errOut)
+	}
+	recordSpanError(span, err)
+	if err == nil {
+		span.SetAttributes(attribute.String("version", version.String()))
+	}
+	emulatorVersions.Store(env.Emulator, emulatorVersionResult{version: version, err: err})
+	return version, err
+}
+
+// CheckEmulatorVersion fails if Env.RequiredEmulatorVersion is set and the installed emulator
+// does not match it. The requirement is a version prefix: "34.1" accepts any 34.1.x.
+func CheckEmulatorVersion(env Env) (EmulatorVersion, error) {
+	required := strings.TrimSpace(env.RequiredEmulatorVersion)
+	version, err := DetectEmulatorVersion(env)
+	if required == "" {
+		return version, nil // detection is best-effort without a pin
+	}
+	if err != nil {
+		return version, fmt.Errorf("required emulator %s: %w", required, err)
+	}
+	have := strings.Split(version.String(), ".")
+	for i, part := range strings.Split(required, ".") {
+		if i >= len(have) || have[i] != part {
+			return version, fmt.Errorf("emulator version %s does not match required %s (%s)",
+				version, required, env.Emulator)
+		}
+	}
+	return version, nil
+}
+
+// emulatorStartArgs returns the default emulator flags, dropping the ones the installed version
+// does not understand, and rejects extra flags that need a newer emulator.
+func emulatorStartArgs(env Env, base []string, extraArgs []string) ([]string, error) {
+	version, err := CheckEmulatorVersion(env)
+	if err != nil {
+		return nil, err
+	}
+	known := version != (EmulatorVersion{})
+	features := version.Features()
+	args := make([]string, 0, len(base)+len(extraArgs))
+	for i := 0; i < len(base); i++ {
+		arg := base[i]
+		switch {
+		case known && !features.SnapshotFlags && (arg == "-no-snapshot-load" || arg == "-no-snapshot-save"):
+			continue
+		case arg == "-wifi-mac-address" && !features.WifiMAC:
+			i++ // also drop the address; an unknown version may predate the flag
+			continue
+		}
+		args = append(args, arg)
+	}
+	if !known {
+		return append(args, extraArgs...), nil // unknown version: keep historical flags
+	}
+	for _, arg := range extraArgs {
+		switch {
+		case arg == "-qt-hide-window" && !features.QtHideWindow:
+			return nil, fmt.Errorf("%s requires emulator >= 29.0.0 (installed %s)", arg, version)
+		case strings.HasPrefix(arg, "-grpc") && !features.GRPC:
+			return nil, fmt.Errorf("%s requires emulator >= 30.0.0 (installed %s)", arg, version)
+		}
+	}
+	return append(args, extraArgs...), nil
+}
@@ -0,0 +1,98 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func writeEmulatorVersionStub(t *testing.T, env *Env, version string) {
+	t.Helper()
+	env.Emulator = filepath.Join(t.TempDir(), "emulator")
+	script := "#!/bin/sh\necho 'INFO    | Android emulator version " + version + " (build_id 11525734) (CL:N/A)'\n"
+	if err := os.WriteFile(env.Emulator, []byte(script), 0o755); err != nil {
+		t.Fatalf("write emulator stub: %v", err)
+	}
+}
+
+func TestParseEmulatorVersion(t *testing.T) {
+	v, err := parseEmulatorVersion("Android emulator version 34.1.19.0 (build_id 11525734) (CL:N/A)\n")
+	if err != nil {
+		t.Fatalf("parseEmulatorVersion: %v", err)
+	}
+	if v.String() != "34.1.19" || v.Build != "11525734" {
+		t.Fatalf("unexpected version %#v", v)
+	}
+	if !v.AtLeast(34, 1, 19) || v.AtLeast(34, 2, 0) || !v.AtLeast(30, 9, 9) {
+		t.Fatalf("AtLeast comparisons wrong for %s", v)
+	}
+	if _, err := parseEmulatorVersion("emulator: command not found"); err == nil {
+		t.Fatal("expected parse error")
+	}
+}
+
+func TestCheckEmulatorVersionPin(t *testing.T) {
+	env := newTestEnv(t)
+	writeEmulatorVersionStub(t, &env, "34.1.19.0")
+
+	env.RequiredEmulatorVersion = "34.1"
+	if _, err := CheckEmulatorVersion(env); err != nil {
+		t.Fatalf("prefix pin should match: %v", err)
+	}
+	env.RequiredEmulatorVersion = "35.2.10"
+	if _, err := CheckEmulatorVersion(env); err == nil || !strings.Contains(err.Error(), "does not match required 35.2.10") {
+		t.Fatalf("expected version mismatch, got %v", err)
+	}
+	env.RequiredEmulatorVersion = "34.1.1"
+	if _, err := CheckEmulatorVersion(env); err == nil {
+		t.Fatal("34.1.1 must not match 34.1.19")
+	}
+}
+
+func TestEmulatorStartArgsGatesFeatures(t *testing.T) {
+	env := newTestEnv(t)
+	writeEmulatorVersionStub(t, &env, "26.1.4")
+	base := []string{"-avd", "demo", "-no-snapshot", "-no-snapshot-load", "-no-snapshot-save"}
+
+	args, err := emulatorStartArgs(env, base, nil)
+	if err != nil {
+		t.Fatalf("emulatorStartArgs: %v", err)
+	}
+	if slices.Contains(args, "-no-snapshot-load") || !slices.Contains(args, "-no-snapshot") {
+		t.Fatalf("snapshot flags not gated: %v", args)
+	}
+	if _, err := emulatorStartArgs(env, base, []string{"-grpc", "8554"}); err == nil {
+		t.Fatal("expected -grpc to be rejected on emulator 26")
+	}
+}
+
+func TestInstanceEmulatorFromStartRecord(t *testing.T) {
+	env := newTestEnv(t)
+	writeEmulatorVersionStub(t, &env, "35.2.10")
+	other := env
+	writeEmulatorVersionStub(t, &other, "34.1.19.0")
+	makeBaseAVD(t, env, "w-other")
+	makeBaseAVD(t, env, "w-default")
+	recordInstance(filepath.Join(env.AVDHome, "w-other.avd"), instanceRecord{Serial: "emulator-5580", PID: os.Getpid(), Emulator: other.Emulator})
+
+	p := ProcInfo{Name: "w-other", Serial: "emulator-5580", PID: os.Getpid()}
+	if got := instanceEmulator(env, p); got != other.Emulator {
+		t.Fatalf("instanceEmulator = %q, want the recorded %q", got, other.Emulator)
+	}
+	instEnv := env
+	instEnv.Emulator = instanceEmulator(env, p)
+	if v, err := DetectEmulatorVersion(instEnv); err != nil || v.String() != "34.1.19" {
+		t.Fatalf("version of the recorded emulator = %v, %v", v, err)
+	}
+	if v, err := DetectEmulatorVersion(env); err != nil || v.String() != "35.2.10" {
+		t.Fatalf("version of Env.Emulator = %v, %v", v, err)
+	}
+	if got := instanceEmulator(env, ProcInfo{Name: "w-default", Serial: "emulator-5582", PID: os.Getpid()}); got != env.Emulator {
+		t.Fatalf("instanceEmulator without a record = %q, want Env.Emulator", got)
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// EmulatorLog returns the last lines of the emulator log of the running instance serial (all of
+// it when lines <= 0). Only instances avdctl started have a log.
+func EmulatorLog(env Env, serial string, lines int) (string, error) {
+	procs, err := ListRunning(env)
+	if err != nil {
+		return "", err
+	}
+	for _, proc := range procs {
+		if proc.Serial != serial {
+			continue
+		}
+		if proc.LogPath == "" {
+			return "", fmt.Errorf("%s was not started by avdctl and has no emulator log", serial)
+		}
+		data, err := os.ReadFile(proc.LogPath)
+		if err != nil {
+			return "", fmt.Errorf("read emulator log of %s: %w", serial, err)
+		}
+		return lastLines(string(data), lines), nil
+	}
+	return "", fmt.Errorf("no running emulator %s", serial)
+}
+
+// lastLines returns the last n lines of s, or all of s when n <= 0.
+func lastLines(s string, n int) string {
+	if n <= 0 {
+		return s
+	}
+	trimmed := strings.TrimSuffix(s, "\n")
+	for i, idx := 0, len(trimmed); i < n; i++ {
+		if idx = strings.LastIndexByte(trimmed[:idx], '\n'); idx < 0 {
+			return s
+		}
+		if i == n-1 {
+			return s[idx+1:]
+		}
+	}
+	return s
+}
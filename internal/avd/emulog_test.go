@@ -0,0 +1,18 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import "testing"
+
+func TestLastLines(t *testing.T) {
+	log := "one\ntwo\nthree\n"
+	for n, want := range map[int]string{0: log, 1: "three\n", 2: "two\nthree\n", 3: log, 10: log} {
+		if got := lastLines(log, n); got != want {
+			t.Fatalf("lastLines(%d) = %q, want %q", n, got, want)
+		}
+	}
+	if got := lastLines("a\nb", 1); got != "b" {
+		t.Fatalf("lastLines without a trailing newline = %q", got)
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// DefaultEphemeralDir is the tmpfs ephemeral runs copy clone images into when
+// Env.EphemeralDir is not set.
+const DefaultEphemeralDir = "/dev/shm"
+
+// ephemeralRunDir holds the images of the ephemeral run on port.
+func ephemeralRunDir(env Env, port int) string {
+	root := env.EphemeralDir
+	if root == "" {
+		root = DefaultEphemeralDir
+	}
+	return filepath.Join(root, "avdctl-ephemeral", strconv.Itoa(port))
+}
+
+// StartEphemeralOnPort starts clone name on port like StartEmulatorOnPort, but from copies of
+// its writable images in Env.EphemeralDir (a tmpfs). The clone's own images are never written;
+// the copies are discarded when the emulator is stopped with StopBySerial.
+func StartEphemeralOnPort(env Env, name string, port int, extraArgs ...string) (StartResult, error) {
+	args, err := prepareEphemeral(env, name, port)
+	if err != nil {
+		return StartResult{}, err
+	}
+	res, err := StartEmulatorOnPort(env, name, port, append(args, extraArgs...)...)
+	if err != nil {
+		discardEphemeral(env, port)
+	}
+	return res, err
+}
+
+// RunAVDEphemeral is RunAVD for StartEphemeralOnPort.
+func RunAVDEphemeral(env Env, name string, extraArgs ...string) (StartResult, error) {
+	return runAVD(env, name, true, extraArgs...)
+}
+
+// prepareEphemeral copies the writable images of name, and their qcow2 overlays, into the run
+// directory of port and returns the emulator flags that use them. It fails when the tmpfs lacks
+// room for the full size of the images, since a guest filling its sparse userdata must not hit
+// ENOSPC mid-test.
+func prepareEphemeral(env Env, name string, port int) ([]string, error) {
+	_, span := startSpan(env, "avd.prepareEphemeral", attribute.String("name", name), attribute.Int("port", port))
+	defer span.End()
+	fail := func(err error) ([]string, error) {
+		recordSpanError(span, err)
+		return nil, err
+	}
+	cloneDir := filepath.Join(env.AVDHome, name+".avd")
+	if !fileExists(cloneDir) {
+		return fail(fmt.Errorf("AVD %s not found", name))
+	}
+	var files []string
+	var need int64
+	flags := map[string]string{}
+	for _, li := range cloneLayout(cloneDir).Images {
+		img := li.Name
+		if li.EmulatorFlag == "" {
+			// Without a flag the emulator would write the clone's own copy.
+			logEvent(env, "ephemeral run keeps image in the clone", "name", name, "image", img)
+			continue
+		}
+		flags[img] = li.EmulatorFlag
+		for _, file := range []string{img, img + ".qcow2"} {
+			st, err := os.Stat(filepath.Join(cloneDir, file))
+			if err != nil {
+				continue
+			}
+			files = append(files, file)
+			need += st.Size()
+		}
+	}
+	runDir := ephemeralRunDir(env, port)
+	_ = os.RemoveAll(runDir) // left over from an emulator that exited on its own
+	if err := os.MkdirAll(runDir, 0o700); err != nil {
+		return fail(fmt.Errorf("ephemeral run directory: %w", err))
+	}
+	if storage, err := DetectStorage(runDir); err == nil && storage.FSType != "tmpfs" {
+		logEvent(env, "ephemeral run directory is not a tmpfs", "path", runDir, "fs_type", storage.FSType)
+	}
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(runDir, &st); err != nil {
+		discardEphemeral(env, port)
+		return fail(fmt.Errorf("statfs %s: %w", runDir, err))
+	}
+	if free := int64(uint64(st.Bavail) * uint64(st.Bsize)); need > free {
+		discardEphemeral(env, port)
+		return fail(fmt.Errorf("ephemeral run of %s needs %d MiB in %s, only %d MiB free", name, need>>20, filepath.Dir(runDir), free>>20))
+	}
+
+	// The launch waits for these copies, so Env.IOBandwidth (meant for background work) is not applied.
+	copyEnv := env
+	copyEnv.IOBandwidth = 0
+	var args []string
+	for _, file := range files {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(runDir, file)), 0o700); err != nil {
+			discardEphemeral(env, port)
+			return fail(fmt.Errorf("ephemeral run directory: %w", err))
+		}
+		if err := copyImage(copyEnv, filepath.Join(cloneDir, file), filepath.Join(runDir, file), nil); err != nil {
+			discardEphemeral(env, port)
+			return fail(fmt.Errorf("copy %s to tmpfs: %w", file, err))
+		}
+		if flag, ok := flags[file]; ok {
+			args = append(args, flag, filepath.Join(runDir, file))
+		}
+	}
+	logEvent(env, "ephemeral images ready", "name", name, "port", port, "path", runDir, "bytes", need)
+	return args, nil
+}
+
+// discardEphemeral removes the images of the ephemeral run on port, if any.
+func discardEphemeral(env Env, port int) {
+	runDir := ephemeralRunDir(env, port)
+	if !fileExists(runDir) {
+		return
+	}
+	if err := os.RemoveAll(runDir); err != nil {
+		logEvent(env, "ephemeral images not removed", "path", runDir, "error", err)
+		return
+	}
+	logEvent(env, "ephemeral images discarded", "port", port, "path", runDir)
+}
@@ -0,0 +1,65 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPrepareEphemeralCopiesWritableImages(t *testing.T) {
+	env := newTestEnv(t)
+	env.EphemeralDir = t.TempDir()
+	makeBaseAVD(t, env, "w-1")
+	cloneDir := filepath.Join(env.AVDHome, "w-1.avd")
+	for _, img := range []string{"userdata-qemu.img", "userdata-qemu.img.qcow2", "cache.img"} {
+		if err := os.WriteFile(filepath.Join(cloneDir, img), []byte(img), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	args, err := prepareEphemeral(env, "w-1", 5580)
+	if err != nil {
+		t.Fatalf("prepareEphemeral: %v", err)
+	}
+	runDir := ephemeralRunDir(env, 5580)
+	want := "-data " + filepath.Join(runDir, "userdata-qemu.img") + " -cache " + filepath.Join(runDir, "cache.img")
+	if got := strings.Join(args, " "); got != want {
+		t.Fatalf("args = %q, want %q", got, want)
+	}
+	if b, err := os.ReadFile(filepath.Join(runDir, "userdata-qemu.img.qcow2")); err != nil || string(b) != "userdata-qemu.img.qcow2" {
+		t.Fatalf("overlay copy = %q, %v", b, err)
+	}
+
+	discardEphemeral(env, 5580)
+	if fileExists(runDir) {
+		t.Fatal("run directory left behind")
+	}
+	if !fileExists(filepath.Join(cloneDir, "userdata-qemu.img")) {
+		t.Fatal("clone image removed")
+	}
+}
+
+func TestPrepareEphemeralChecksFreeSpace(t *testing.T) {
+	env := newTestEnv(t)
+	env.EphemeralDir = t.TempDir()
+	makeBaseAVD(t, env, "w-1")
+	f, err := os.Create(filepath.Join(env.AVDHome, "w-1.avd", "userdata-qemu.img"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Truncate(1 << 50); err != nil { // sparse: far larger than any test filesystem
+		t.Skipf("sparse file: %v", err)
+	}
+	f.Close()
+
+	if _, err := prepareEphemeral(env, "w-1", 5580); err == nil || !strings.Contains(err.Error(), "MiB free") {
+		t.Fatalf("prepareEphemeral error = %v, want a free space error", err)
+	}
+	if fileExists(ephemeralRunDir(env, 5580)) {
+		t.Fatal("run directory left behind")
+	}
+}
@@ -0,0 +1,196 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Outcomes of a run (InstanceRun.Outcome). RunStopped is a run stopped as asked; the others
+// classify failed runs.
+const (
+	RunStopped          = "stopped"
+	FailureBootTimeout  = "boot-timeout"  // did not boot in time, or stalled during startup
+	FailureCrash        = "crash"         // exited without Stop while the host stayed up
+	FailureKilledByGC   = "killed-by-gc"  // stopped by cleanup as an orphan
+	FailureLeaseExpired = "lease-expired" // stopped because the time granted to it ran out
+	FailureHostShutdown = "host-shutdown" // the host shut down or rebooted under it
+)
+
+// ParseRunOutcome checks an outcome given to Stop; empty means RunStopped.
+func ParseRunOutcome(s string) (string, error) {
+	switch s {
+	case "":
+		return RunStopped, nil
+	case RunStopped, FailureBootTimeout, FailureCrash, FailureKilledByGC, FailureLeaseExpired, FailureHostShutdown:
+		return s, nil
+	}
+	return "", fmt.Errorf("invalid run outcome %q (want %s, %s, %s, %s, %s or %s)", s,
+		RunStopped, FailureBootTimeout, FailureCrash, FailureKilledByGC, FailureLeaseExpired, FailureHostShutdown)
+}
+
+// FailureCount is how many runs failed one way (see FailureStats).
+type FailureCount struct {
+	Class string    `json:"class"`
+	Runs  int       `json:"runs"`
+	AVDs  []string  `json:"avds"`
+	Last  time.Time `json:"last"` // start of the latest run that failed so
+}
+
+// FailureReport sums the outcomes of the runs started since Since.
+type FailureReport struct {
+	Since   time.Time      `json:"since"`
+	Runs    int            `json:"runs"`
+	Failed  int            `json:"failed"`
+	Classes []FailureCount `json:"classes"` // most frequent first
+}
+
+// markRun sets the outcome of the run of avdDir launched as pid, unless it has one already, so
+// a boot timeout is not overwritten by the Stop that follows it. ended also records the end.
+func markRun(avdDir string, pid int, outcome, detail string, ended bool) {
+	runs := readRunHistory(avdDir)
+	for i := len(runs) - 1; i >= 0; i-- {
+		if runs[i].PID != pid {
+			continue
+		}
+		if runs[i].Outcome == "" {
+			runs[i].Outcome, runs[i].FailureDetail = outcome, detail
+		}
+		if ended && runs[i].EndedAt == nil {
+			now := time.Now().UTC()
+			runs[i].EndedAt = &now
+		}
+		writeRunHistory(avdDir, runs)
+		return
+	}
+}
+
+// recordRunFailure classifies the current run of the running instance serial. A boot timeout
+// may quarantine the clone (see checkFlakyStart).
+func recordRunFailure(env Env, serial, outcome, detail string) {
+	if avdDir, rec, ok := runningAVDDir(env, serial); ok {
+		markRun(avdDir, rec.PID, outcome, detail, false)
+		logEvent(env, "run failed", "serial", serial, "outcome", outcome, "detail", detail)
+		if outcome == FailureBootTimeout {
+			checkFlakyStart(env, avdDir)
+		}
+	}
+}
+
+// bootTimedOut reports whether err is a boot wait running out of time.
+func bootTimedOut(err error) bool {
+	var phaseErr *BootPhaseTimeoutError
+	return errors.As(err, &phaseErr) || (err != nil && strings.HasPrefix(err.Error(), "boot timeout"))
+}
+
+// classifyRuns gives an outcome to the runs of avdDir that ended without one: they exited
+// without Stop, because the host went down when it booted after they started, else because the
+// emulator crashed. Those runs also get an end: the host boot, else the last write to their
+// emulator log, else now. The run of a live instance is left alone.
+func classifyRuns(avdDir string, runs []InstanceRun) ([]InstanceRun, bool) {
+	livePID := 0
+	if rec, ok := readInstance(avdDir); ok && rec.PID > 0 && syscall.Kill(rec.PID, 0) == nil && !isZombieProcess(rec.PID) {
+		livePID = rec.PID
+	}
+	boot, haveBoot := bootTime()
+	changed := false
+	for i := range runs {
+		run := &runs[i]
+		hostDown := haveBoot && boot.After(run.StartedAt)
+		if !hostDown && i == len(runs)-1 && livePID > 0 && run.PID == livePID {
+			continue
+		}
+		if run.Outcome == "" {
+			switch {
+			case hostDown:
+				run.Outcome = FailureHostShutdown
+			case run.Usage != nil:
+				// Stopped before outcomes were recorded: Stop sampled it.
+				run.Outcome = RunStopped
+			default:
+				run.Outcome = FailureCrash
+				if data, err := os.ReadFile(run.LogPath); run.LogPath != "" && err == nil {
+					run.FailureDetail = strings.TrimSpace(lastLines(string(data), 1))
+				}
+			}
+			changed = true
+		}
+		if run.EndedAt == nil {
+			end := time.Now().UTC()
+			if hostDown {
+				end = boot.UTC()
+			} else if st, err := os.Stat(run.LogPath); run.LogPath != "" && err == nil && st.ModTime().After(run.StartedAt) {
+				end = st.ModTime().UTC()
+			}
+			run.EndedAt = &end
+			changed = true
+		}
+	}
+	return runs, changed
+}
+
+// FailureStats classifies the runs of every AVD started since since, from their run history,
+// and counts each failure class. The history keeps the runs Env.Retention allows.
+func FailureStats(env Env, since time.Time) (FailureReport, error) {
+	_, span := startSpan(env, "avd.FailureStats", attribute.String("since", since.Format(time.RFC3339)))
+	defer span.End()
+	report := FailureReport{Since: since}
+	dirs, err := filepath.Glob(filepath.Join(env.AVDHome, "*.avd"))
+	if err != nil {
+		recordSpanError(span, err)
+		return report, err
+	}
+	classes := map[string]*FailureCount{}
+	for _, avdDir := range dirs {
+		name := strings.TrimSuffix(filepath.Base(avdDir), ".avd")
+		runs, _ := classifyRuns(avdDir, readRunHistory(avdDir))
+		for _, run := range runs {
+			if run.StartedAt.Before(since) {
+				continue
+			}
+			report.Runs++
+			if run.Outcome == "" || run.Outcome == RunStopped {
+				continue
+			}
+			report.Failed++
+			c, ok := classes[run.Outcome]
+			if !ok {
+				c = &FailureCount{Class: run.Outcome}
+				classes[run.Outcome] = c
+			}
+			c.Runs++
+			if len(c.AVDs) == 0 || c.AVDs[len(c.AVDs)-1] != name {
+				c.AVDs = append(c.AVDs, name)
+			}
+			if run.StartedAt.After(c.Last) {
+				c.Last = run.StartedAt
+			}
+		}
+	}
+	for _, c := range classes {
+		report.Classes = append(report.Classes, *c)
+	}
+	sortFailureCounts(report.Classes)
+	span.SetAttributes(attribute.Int("runs", report.Runs), attribute.Int("failed", report.Failed))
+	return report, nil
+}
+
+// sortFailureCounts orders classes most frequent first, then by name.
+func sortFailureCounts(classes []FailureCount) {
+	sort.Slice(classes, func(i, j int) bool {
+		if classes[i].Runs != classes[j].Runs {
+			return classes[i].Runs > classes[j].Runs
+		}
+		return classes[i].Class < classes[j].Class
+	})
+}
@@ -0,0 +1,96 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestClassifyRuns(t *testing.T) {
+	boot, ok := bootTime()
+	if !ok {
+		t.Skip("no /proc/stat boot time")
+	}
+	env := newTestEnv(t)
+	makeBaseAVD(t, env, "w")
+	avdDir := filepath.Join(env.AVDHome, "w.avd")
+	logPath := filepath.Join(t.TempDir(), "w.log")
+	if err := os.WriteFile(logPath, []byte("boot\nFATAL: qemu exited with signal 11\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	recordInstance(avdDir, instanceRecord{PID: os.Getpid(), StartedAt: time.Now()})
+	runs := []InstanceRun{
+		{StartedAt: boot.Add(-time.Hour), PID: 11},
+		{StartedAt: boot.Add(time.Second), PID: 12, LogPath: logPath},
+		{StartedAt: boot.Add(2 * time.Second), PID: 13, Usage: &ResourceUsage{MemoryBytes: 1}},
+		{StartedAt: boot.Add(3 * time.Second), PID: 14, Outcome: FailureLeaseExpired},
+		{StartedAt: time.Now(), PID: os.Getpid()},
+	}
+	got, changed := classifyRuns(avdDir, runs)
+	var outcomes []string
+	for _, run := range got {
+		outcomes = append(outcomes, run.Outcome)
+	}
+	want := []string{FailureHostShutdown, FailureCrash, RunStopped, FailureLeaseExpired, ""}
+	if !changed || !slices.Equal(outcomes, want) {
+		t.Fatalf("outcomes = %q, want %q", outcomes, want)
+	}
+	if got[1].FailureDetail != "FATAL: qemu exited with signal 11" {
+		t.Fatalf("crash detail = %q", got[1].FailureDetail)
+	}
+}
+
+func TestMarkRunKeepsFirstOutcome(t *testing.T) {
+	env := newTestEnv(t)
+	makeBaseAVD(t, env, "w")
+	avdDir := filepath.Join(env.AVDHome, "w.avd")
+	writeRunHistory(avdDir, []InstanceRun{{StartedAt: time.Now(), PID: 42}})
+	markRun(avdDir, 42, FailureBootTimeout, "boot timeout after 3m0s", false)
+	markRun(avdDir, 42, RunStopped, "", true)
+	runs := readRunHistory(avdDir)
+	if runs[0].Outcome != FailureBootTimeout || runs[0].FailureDetail != "boot timeout after 3m0s" || runs[0].EndedAt == nil {
+		t.Fatalf("run = %+v", runs[0])
+	}
+	if !bootTimedOut(&BootPhaseTimeoutError{Phase: BootPhaseWaitingADB}) || bootTimedOut(errors.New("adb: device offline")) || bootTimedOut(nil) {
+		t.Fatal("bootTimedOut misclassifies errors")
+	}
+	if _, err := ParseRunOutcome("exploded"); err == nil {
+		t.Fatal("ParseRunOutcome accepted an unknown outcome")
+	}
+}
+
+func TestFailureStats(t *testing.T) {
+	env := newTestEnv(t)
+	now := time.Now().UTC()
+	for name, outcomes := range map[string][]string{
+		"w-1": {RunStopped, FailureBootTimeout, FailureCrash},
+		"w-2": {FailureBootTimeout, FailureBootTimeout, RunStopped},
+	} {
+		makeBaseAVD(t, env, name)
+		runs := []InstanceRun{{StartedAt: now.AddDate(0, 0, -30), PID: 1, Outcome: FailureCrash}}
+		for i, outcome := range outcomes {
+			runs = append(runs, InstanceRun{StartedAt: now.Add(time.Duration(i-3) * time.Hour), PID: 10 + i, Outcome: outcome})
+		}
+		writeRunHistory(filepath.Join(env.AVDHome, name+".avd"), runs)
+	}
+	report, err := FailureStats(env, now.AddDate(0, 0, -7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Runs != 6 || report.Failed != 4 || len(report.Classes) != 2 {
+		t.Fatalf("report = %+v", report)
+	}
+	top := report.Classes[0]
+	if top.Class != FailureBootTimeout || top.Runs != 3 || !slices.Equal(top.AVDs, []string{"w-1", "w-2"}) {
+		t.Fatalf("top class = %+v", top)
+	}
+	if report.Classes[1].Class != FailureCrash || report.Classes[1].Runs != 1 {
+		t.Fatalf("second class = %+v", report.Classes[1])
+	}
+}
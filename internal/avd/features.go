@@ -0,0 +1,120 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// advancedFeaturesFilename is the host-wide emulator feature file. avdctl never writes it:
+// per-run overrides go on the command line with -feature, so an experiment on one instance
+// does not change every AVD on the host.
+const advancedFeaturesFilename = "advancedFeatures.ini"
+
+// FeatureBluetoothEmulation is the emulator feature of the emulated Bluetooth stack; turning it
+// off keeps the stack, and its "Bluetooth keeps stopping" dialogs, from starting at all.
+const FeatureBluetoothEmulation = "BluetoothEmulation"
+
+var featureNameRe = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// AdvancedFeaturesPath returns the host feature file the emulator reads:
+// $ANDROID_EMULATOR_HOME/advancedFeatures.ini, else $ANDROID_USER_HOME's, else ~/.android's.
+func AdvancedFeaturesPath() string {
+	for _, k := range []string{"ANDROID_EMULATOR_HOME", "ANDROID_USER_HOME"} {
+		if dir := os.Getenv(k); dir != "" {
+			return filepath.Join(dir, advancedFeaturesFilename)
+		}
+	}
+	home := os.Getenv("HOME")
+	if usr, err := user.Current(); err == nil && usr.HomeDir != "" {
+		home = usr.HomeDir
+	}
+	return filepath.Join(home, ".android", advancedFeaturesFilename)
+}
+
+// ReadAdvancedFeatures returns the features set in the host feature file (true = on). A
+// missing file yields an empty map.
+func ReadAdvancedFeatures() (map[string]bool, error) {
+	b, err := os.ReadFile(AdvancedFeaturesPath())
+	if os.IsNotExist(err) {
+		return map[string]bool{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", advancedFeaturesFilename, err)
+	}
+	features := map[string]bool{}
+	for k, v := range parseConfigINI(b) {
+		features[k] = strings.EqualFold(v, "on")
+	}
+	return features, nil
+}
+
+// ParseFeatureOverrides parses per-run feature overrides: "Vulkan" or "Vulkan=on" turns a
+// feature on, "-Vulkan" or "Vulkan=off" turns it off.
+func ParseFeatureOverrides(specs []string) (map[string]bool, error) {
+	features := map[string]bool{}
+	for _, spec := range specs {
+		name, value, hasValue := strings.Cut(strings.TrimSpace(spec), "=")
+		on := true
+		switch {
+		case hasValue && strings.EqualFold(value, "off"):
+			on = false
+		case hasValue && !strings.EqualFold(value, "on"):
+			return nil, fmt.Errorf("feature %q: value must be on or off", spec)
+		case !hasValue && strings.HasPrefix(name, "-"):
+			name, on = name[1:], false
+		}
+		if !featureNameRe.MatchString(name) {
+			return nil, fmt.Errorf("invalid feature name %q", name)
+		}
+		features[name] = on
+	}
+	return features, nil
+}
+
+// FeatureArgs returns the -feature flag for features, for the extraArgs of the start
+// functions; nil when there are none.
+func FeatureArgs(features map[string]bool) []string {
+	if len(features) == 0 {
+		return nil
+	}
+	return []string{"-feature", featureList(features)}
+}
+
+// featureList renders features as the emulator's comma list, "-" marking disabled ones.
+func featureList(features map[string]bool) string {
+	names := make([]string, 0, len(features))
+	for name := range features {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		if !features[name] {
+			names[i] = "-" + name
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// parseFeatureList is the inverse of featureList; it merges into features.
+func parseFeatureList(features map[string]bool, list string) map[string]bool {
+	if features == nil {
+		features = map[string]bool{}
+	}
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if disabled, ok := strings.CutPrefix(name, "-"); ok {
+			features[disabled] = false
+		} else if name != "" {
+			features[name] = true
+		}
+	}
+	return features
+}
@@ -0,0 +1,67 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestParseFeatureOverrides(t *testing.T) {
+	got, err := ParseFeatureOverrides([]string{"Vulkan", "VirtioWifi=off", "-BluetoothEmulation", "GLDirectMem=ON"})
+	if err != nil {
+		t.Fatalf("ParseFeatureOverrides: %v", err)
+	}
+	want := map[string]bool{"Vulkan": true, "VirtioWifi": false, "BluetoothEmulation": false, "GLDirectMem": true}
+	if !maps.Equal(got, want) {
+		t.Fatalf("overrides = %v, want %v", got, want)
+	}
+	if args := FeatureArgs(got); !slices.Equal(args, []string{"-feature", "-BluetoothEmulation,GLDirectMem,-VirtioWifi,Vulkan"}) {
+		t.Fatalf("FeatureArgs = %v", args)
+	}
+	for _, bad := range []string{"Vulkan=maybe", "bad name", "=on"} {
+		if _, err := ParseFeatureOverrides([]string{bad}); err == nil {
+			t.Fatalf("expected an error for %q", bad)
+		}
+	}
+}
+
+func TestFeatureOverridesFoldIntoArgs(t *testing.T) {
+	a := HeadlessArgs("w-1", 5580).withExtra("-feature", "Vulkan,-VirtioWifi", "-feature", "VirtioWifi")
+	if want := map[string]bool{"Vulkan": true, "VirtioWifi": true}; !maps.Equal(a.Features, want) {
+		t.Fatalf("Features = %v, want %v", a.Features, want)
+	}
+	a = a.withDefaults([]string{"-feature", "-Vulkan,BluetoothEmulation"})
+	if !a.Features["Vulkan"] || !a.Features["BluetoothEmulation"] {
+		t.Fatalf("defaults should only add features the run does not set: %v", a.Features)
+	}
+	args, err := a.Args()
+	if err != nil {
+		t.Fatalf("Args: %v", err)
+	}
+	if i := slices.Index(args, "-feature"); i < 0 || args[i+1] != "BluetoothEmulation,VirtioWifi,Vulkan" {
+		t.Fatalf("args = %v", args)
+	}
+}
+
+func TestReadAdvancedFeatures(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("ANDROID_EMULATOR_HOME", home)
+	if got, err := ReadAdvancedFeatures(); err != nil || len(got) != 0 {
+		t.Fatalf("missing file = %v, %v", got, err)
+	}
+	if err := os.WriteFile(filepath.Join(home, "advancedFeatures.ini"), []byte("# host\nVulkan = on\nVirtioWifi = off\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadAdvancedFeatures()
+	if err != nil {
+		t.Fatalf("ReadAdvancedFeatures: %v", err)
+	}
+	if want := map[string]bool{"Vulkan": true, "VirtioWifi": false}; !maps.Equal(got, want) {
+		t.Fatalf("features = %v, want %v", got, want)
+	}
+}
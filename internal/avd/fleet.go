@@ -0,0 +1,413 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"gopkg.in/yaml.v3"
+)
+
+// Fleet is the declarative description of bases, goldens and clones read from a fleet file.
+type Fleet struct {
+	Bases   []FleetBase   `yaml:"bases" json:"bases"`
+	Goldens []FleetGolden `yaml:"goldens" json:"goldens"`
+	Clones  []FleetClone  `yaml:"clones" json:"clones"`
+	// Placement holds the rules a multi-host coordinator applies to the clones' labels.
+	Placement FleetPlacement `yaml:"placement" json:"placement,omitempty"`
+}
+
+// FleetPlacement lists label keys that constrain where a coordinator places clones across
+// hosts. Clones with the same value of an Affinity key are kept on one host; clones with the
+// same value of an AntiAffinity key never share a host.
+type FleetPlacement struct {
+	Affinity     []string `yaml:"affinity" json:"affinity,omitempty"`
+	AntiAffinity []string `yaml:"anti_affinity" json:"anti_affinity,omitempty"`
+}
+
+// Labels returns the labels of every clone, by clone name.
+func (f Fleet) Labels() map[string]map[string]string {
+	labels := map[string]map[string]string{}
+	for _, c := range f.Clones {
+		if len(c.Labels) > 0 {
+			labels[c.Name] = c.Labels
+		}
+	}
+	return labels
+}
+
+// FleetBase describes a base AVD created with InitBase when missing.
+type FleetBase struct {
+	Name   string `yaml:"name" json:"name"`
+	Image  string `yaml:"image" json:"image"`
+	Device string `yaml:"device" json:"device"`
+}
+
+// FleetGolden describes a golden directory. If it is missing and Prewarm is set,
+// it is produced from Base with PrewarmGolden; otherwise up fails.
+type FleetGolden struct {
+	Name        string        `yaml:"name" json:"name"`
+	Base        string        `yaml:"base" json:"base"`
+	Path        string        `yaml:"path" json:"path"`
+	Prewarm     bool          `yaml:"prewarm" json:"prewarm"`
+	Settle      time.Duration `yaml:"settle" json:"settle"`
+	BootTimeout time.Duration `yaml:"boot_timeout" json:"boot_timeout"`
+	ADBKeys     []string      `yaml:"adb_keys" json:"adb_keys,omitempty"` // adbkey.pub files preseeded on prewarm
+	// SelfContained embeds the base and system image so clones don't need the base AVD.
+	SelfContained bool `yaml:"self_contained" json:"self_contained,omitempty"`
+}
+
+// FleetClone describes a clone and how it should run.
+type FleetClone struct {
+	Name   string            `yaml:"name" json:"name"`
+	Base   string            `yaml:"base" json:"base"`
+	Golden string            `yaml:"golden" json:"golden"` // golden name from the file, or a path
+	Vars   map[string]string `yaml:"vars" json:"vars,omitempty"`
+	Values string            `yaml:"values" json:"values,omitempty"`
+	Run    bool              `yaml:"run" json:"run"`
+	Port   int               `yaml:"port" json:"port,omitempty"`
+	Args   []string          `yaml:"args" json:"args,omitempty"`
+	Labels map[string]string `yaml:"labels" json:"labels,omitempty"` // matched by the placement rules
+	// Link and LinkRules select how base artifacts are materialized (see LinkPolicy),
+	// e.g. link: copy, or link_rules: ["kernel-ranchu=copy"].
+	Link      string   `yaml:"link" json:"link,omitempty"`
+	LinkRules []string `yaml:"link_rules" json:"link_rules,omitempty"`
+}
+
+func (c FleetClone) linkPolicy() (LinkPolicy, error) {
+	mode, err := ParseLinkMode(c.Link)
+	if err != nil {
+		return LinkPolicy{}, err
+	}
+	policy := LinkPolicy{Default: mode}
+	for _, raw := range c.LinkRules {
+		rule, err := ParseLinkRule(raw)
+		if err != nil {
+			return LinkPolicy{}, err
+		}
+		policy.Rules = append(policy.Rules, rule)
+	}
+	return policy, nil
+}
+
+// FleetAction records one step taken (or skipped) while converging a fleet.
+type FleetAction struct {
+	Kind   string `json:"kind"` // create-base, create-golden, clone, start, stop, delete
+	Name   string `json:"name"`
+	Status string `json:"status"` // done, unchanged, failed
+	Detail string `json:"detail,omitempty"`
+}
+
+// FleetReport lists the actions of an up or down run.
+type FleetReport struct {
+	Actions []FleetAction `json:"actions"`
+}
+
+// Failed reports whether any action failed.
+func (r FleetReport) Failed() bool {
+	for _, a := range r.Actions {
+		if a.Status == "failed" {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *FleetReport) add(kind, name, status, detail string) {
+	r.Actions = append(r.Actions, FleetAction{Kind: kind, Name: name, Status: status, Detail: detail})
+}
+
+// LoadFleet reads and validates a fleet file. Relative paths resolve against the file's directory.
+func LoadFleet(path string) (Fleet, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return Fleet{}, fmt.Errorf("read fleet file: %w", err)
+	}
+	var fleet Fleet
+	if err := yaml.Unmarshal(b, &fleet); err != nil {
+		return Fleet{}, fmt.Errorf("parse fleet file %s: %w", path, err)
+	}
+	dir := filepath.Dir(path)
+	goldenNames := map[string]bool{}
+	for i := range fleet.Goldens {
+		fleet.Goldens[i].Path = resolveFleetPath(dir, fleet.Goldens[i].Path)
+		for j, key := range fleet.Goldens[i].ADBKeys {
+			fleet.Goldens[i].ADBKeys[j] = resolveFleetPath(dir, key)
+		}
+		goldenNames[fleet.Goldens[i].Name] = true
+	}
+	for i := range fleet.Clones {
+		if ref := fleet.Clones[i].Golden; ref != "" && !goldenNames[ref] {
+			fleet.Clones[i].Golden = resolveFleetPath(dir, ref)
+		}
+		if fleet.Clones[i].Values != "" {
+			fleet.Clones[i].Values = resolveFleetPath(dir, fleet.Clones[i].Values)
+		}
+	}
+	if err := fleet.Validate(); err != nil {
+		return Fleet{}, fmt.Errorf("invalid fleet file %s: %w", path, err)
+	}
+	return fleet, nil
+}
+
+// Validate checks that required fields are set, AVD names are unique and ports are even.
+func (f Fleet) Validate() error {
+	var issues []string
+	seen := map[string]bool{}
+	for _, b := range f.Bases {
+		if strings.TrimSpace(b.Name) == "" {
+			issues = append(issues, "base with empty name")
+			continue
+		}
+		if b.Image == "" {
+			issues = append(issues, fmt.Sprintf("base %s: image is required", b.Name))
+		}
+		if seen[b.Name] {
+			issues = append(issues, fmt.Sprintf("duplicate AVD name %s", b.Name))
+		}
+		seen[b.Name] = true
+	}
+	goldens := map[string]bool{}
+	for _, g := range f.Goldens {
+		if strings.TrimSpace(g.Name) == "" || g.Path == "" {
+			issues = append(issues, fmt.Sprintf("golden %q: name and path are required", g.Name))
+			continue
+		}
+		if goldens[g.Name] {
+			issues = append(issues, fmt.Sprintf("duplicate golden %s", g.Name))
+		}
+		goldens[g.Name] = true
+		if g.Prewarm && g.Base == "" {
+			issues = append(issues, fmt.Sprintf("golden %s: prewarm requires base", g.Name))
+		}
+	}
+	for _, c := range f.Clones {
+		if strings.TrimSpace(c.Name) == "" {
+			issues = append(issues, "clone with empty name")
+			continue
+		}
+		if seen[c.Name] {
+			issues = append(issues, fmt.Sprintf("duplicate AVD name %s", c.Name))
+		}
+		seen[c.Name] = true
+		if c.Base == "" || c.Golden == "" {
+			issues = append(issues, fmt.Sprintf("clone %s: base and golden are required", c.Name))
+		}
+		if c.Port != 0 && c.Port%2 != 0 {
+			issues = append(issues, fmt.Sprintf("clone %s: port %d must be even", c.Name, c.Port))
+		}
+		if _, err := c.linkPolicy(); err != nil {
+			issues = append(issues, fmt.Sprintf("clone %s: %v", c.Name, err))
+		}
+	}
+	for _, key := range f.Placement.AntiAffinity {
+		if strings.TrimSpace(key) == "" {
+			issues = append(issues, "placement: empty anti_affinity label")
+		}
+		for _, other := range f.Placement.Affinity {
+			if key == other {
+				issues = append(issues, fmt.Sprintf("placement: label %s is both affinity and anti_affinity", key))
+			}
+		}
+	}
+	for _, key := range f.Placement.Affinity {
+		if strings.TrimSpace(key) == "" {
+			issues = append(issues, "placement: empty affinity label")
+		}
+	}
+	if len(issues) > 0 {
+		return errors.New(strings.Join(issues, "; "))
+	}
+	return nil
+}
+
+func (f Fleet) goldenPath(ref string) string {
+	for _, g := range f.Goldens {
+		if g.Name == ref {
+			return g.Path
+		}
+	}
+	return ref
+}
+
+// FleetUp converges the host to the fleet: missing bases are created, missing prewarm goldens
+// are produced, clones are materialized (idempotently) and clones with run=true are started.
+func FleetUp(env Env, fleet Fleet) (FleetReport, error) {
+	_, span := startSpan(env, "avd.FleetUp",
+		attribute.Int("bases", len(fleet.Bases)),
+		attribute.Int("clones", len(fleet.Clones)),
+	)
+	defer span.End()
+	report := FleetReport{}
+
+	existing, err := List(env)
+	if err != nil && !os.IsNotExist(err) {
+		recordSpanError(span, err)
+		return report, err
+	}
+	have := map[string]bool{}
+	for _, info := range existing {
+		have[info.Name] = true
+	}
+
+	for _, b := range fleet.Bases {
+		if have[b.Name] {
+			report.add("create-base", b.Name, "unchanged", "")
+			continue
+		}
+		device := b.Device
+		if device == "" {
+			device = "pixel_6"
+		}
+		if _, err := InitBase(env, b.Name, b.Image, device); err != nil {
+			report.add("create-base", b.Name, "failed", err.Error())
+			continue
+		}
+		have[b.Name] = true
+		report.add("create-base", b.Name, "done", "")
+	}
+
+	for _, g := range fleet.Goldens {
+		if _, err := os.Stat(g.Path); err == nil {
+			report.add("create-golden", g.Name, "unchanged", g.Path)
+			continue
+		}
+		if !g.Prewarm {
+			report.add("create-golden", g.Name, "failed", fmt.Sprintf("golden %s not found and prewarm is disabled", g.Path))
+			continue
+		}
+		settle, timeout := g.Settle, g.BootTimeout
+		if settle == 0 {
+			settle = 30 * time.Second
+		}
+		if timeout == 0 {
+			timeout = 3 * time.Minute
+		}
+		dst, _, err := PrewarmGoldenWithOptions(env, g.Base, g.Path, settle, timeout, PrewarmOptions{ADBKeys: g.ADBKeys, SelfContained: g.SelfContained})
+		if err != nil {
+			report.add("create-golden", g.Name, "failed", err.Error())
+			continue
+		}
+		report.add("create-golden", g.Name, "done", dst)
+	}
+
+	running, err := ListRunning(env)
+	if err != nil {
+		recordSpanError(span, err)
+		return report, err
+	}
+	runningByName := map[string]ProcInfo{}
+	for _, proc := range running {
+		if proc.Name != "" {
+			runningByName[proc.Name] = proc
+		}
+	}
+
+	for _, c := range fleet.Clones {
+		golden := fleet.goldenPath(c.Golden)
+		status := "done"
+		if have[c.Name] {
+			status = "unchanged"
+		}
+		links, _ := c.linkPolicy() // checked by Validate
+		opts := CloneOptions{ConfigVars: c.Vars, ConfigValuesFile: c.Values, Links: links}
+		if _, err := CloneFromGoldenWithOptions(env, c.Base, c.Name, golden, opts); err != nil {
+			report.add("clone", c.Name, "failed", err.Error())
+			continue
+		}
+		report.add("clone", c.Name, status, golden)
+
+		if !c.Run {
+			continue
+		}
+		if proc, ok := runningByName[c.Name]; ok {
+			report.add("start", c.Name, "unchanged", proc.Serial)
+			continue
+		}
+		serial, err := startFleetClone(env, c)
+		if err != nil {
+			report.add("start", c.Name, "failed", err.Error())
+			continue
+		}
+		report.add("start", c.Name, "done", serial)
+	}
+
+	logEvent(env, "fleet up completed", "actions", len(report.Actions), "failed", report.Failed())
+	return report, nil
+}
+
+func startFleetClone(env Env, c FleetClone) (string, error) {
+	start := RunAVD
+	if c.Port != 0 {
+		start = func(env Env, name string, args ...string) (StartResult, error) {
+			return StartEmulatorOnPort(env, name, c.Port, args...)
+		}
+	}
+	res, err := start(env, c.Name, c.Args...)
+	return res.Serial, err
+}
+
+// FleetDown stops the fleet's running clones and, with remove, deletes them.
+// Bases and goldens are never removed.
+func FleetDown(env Env, fleet Fleet, remove bool) (FleetReport, error) {
+	_, span := startSpan(env, "avd.FleetDown", attribute.Bool("remove", remove))
+	defer span.End()
+	report := FleetReport{}
+
+	running, err := ListRunning(env)
+	if err != nil {
+		recordSpanError(span, err)
+		return report, err
+	}
+	for _, c := range fleet.Clones {
+		for _, proc := range running {
+			if proc.Name != c.Name {
+				continue
+			}
+			if err := StopBySerial(env, proc.Serial); err != nil {
+				report.add("stop", c.Name, "failed", err.Error())
+			} else {
+				report.add("stop", c.Name, "done", proc.Serial)
+			}
+		}
+		if !remove {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(env.AVDHome, c.Name+".avd")); os.IsNotExist(err) {
+			report.add("delete", c.Name, "unchanged", "")
+			continue
+		}
+		if err := Delete(env, c.Name); err != nil {
+			report.add("delete", c.Name, "failed", err.Error())
+			continue
+		}
+		report.add("delete", c.Name, "done", "")
+	}
+
+	logEvent(env, "fleet down completed", "actions", len(report.Actions), "failed", report.Failed())
+	return report, nil
+}
+
+func resolveFleetPath(dir, path string) string {
+	path = expandHome(path)
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, strings.TrimPrefix(path, "~"))
+		}
+	}
+	return path
+}
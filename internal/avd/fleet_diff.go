@@ -0,0 +1,167 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// FleetDrift is one difference between a fleet file and the host.
+type FleetDrift struct {
+	Kind   string `json:"kind"` // base, golden, clone, instance, config
+	Name   string `json:"name"`
+	Field  string `json:"field"` // presence, golden, running, port, or a config.ini key
+	Want   string `json:"want"`
+	Got    string `json:"got"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// FleetDiffReport lists the drift found by FleetDiff; an empty list means the host matches.
+type FleetDiffReport struct {
+	Drift []FleetDrift `json:"drift"`
+}
+
+// Drifted reports whether any difference was found.
+func (r FleetDiffReport) Drifted() bool { return len(r.Drift) > 0 }
+
+func (r *FleetDiffReport) add(kind, name, field, want, got, detail string) {
+	r.Drift = append(r.Drift, FleetDrift{Kind: kind, Name: name, Field: field, Want: want, Got: got, Detail: detail})
+}
+
+// FleetDiff compares the fleet with the AVDs, goldens, running instances and clone config.ini
+// values on the host without changing anything. Clones on the host that the fleet does not declare
+// are reported too.
+func FleetDiff(env Env, fleet Fleet) (FleetDiffReport, error) {
+	_, span := startSpan(env, "avd.FleetDiff", attribute.Int("clones", len(fleet.Clones)))
+	defer span.End()
+	report := FleetDiffReport{Drift: []FleetDrift{}}
+
+	existing, err := List(env)
+	if err != nil && !os.IsNotExist(err) {
+		recordSpanError(span, err)
+		return report, err
+	}
+	have := map[string]bool{}
+	for _, info := range existing {
+		have[info.Name] = true
+	}
+	running, err := ListRunning(env)
+	if err != nil {
+		recordSpanError(span, err)
+		return report, err
+	}
+	runningByName := map[string]ProcInfo{}
+	for _, proc := range running {
+		if proc.Name != "" {
+			runningByName[proc.Name] = proc
+		}
+	}
+
+	declared := map[string]bool{}
+	for _, b := range fleet.Bases {
+		declared[b.Name] = true
+		if !have[b.Name] {
+			report.add("base", b.Name, "presence", "present", "missing", "")
+		}
+	}
+	for _, g := range fleet.Goldens {
+		if _, err := os.Stat(g.Path); err != nil {
+			report.add("golden", g.Name, "presence", "present", "missing", g.Path)
+		}
+	}
+	for _, c := range fleet.Clones {
+		declared[c.Name] = true
+		diffFleetClone(env, fleet, c, have[c.Name], &report)
+
+		proc, isRunning := runningByName[c.Name]
+		if c.Run != isRunning {
+			report.add("instance", c.Name, "running", strconv.FormatBool(c.Run), strconv.FormatBool(isRunning), proc.Serial)
+		}
+		if isRunning && c.Port != 0 && proc.Port != c.Port {
+			report.add("instance", c.Name, "port", strconv.Itoa(c.Port), strconv.Itoa(proc.Port), proc.Serial)
+		}
+	}
+
+	for _, info := range existing {
+		if declared[info.Name] || !isCloneDir(info.Path) {
+			continue
+		}
+		report.add("clone", info.Name, "presence", "absent", "present", "clone not declared in fleet file")
+	}
+
+	span.SetAttributes(attribute.Int("drift", len(report.Drift)))
+	logEvent(env, "fleet diff completed", "drift", len(report.Drift))
+	return report, nil
+}
+
+func diffFleetClone(env Env, fleet Fleet, c FleetClone, exists bool, report *FleetDiffReport) {
+	if !exists {
+		report.add("clone", c.Name, "presence", "present", "missing", "")
+		return
+	}
+	golden := fleet.goldenPath(c.Golden)
+	cloneDir := filepath.Join(env.AVDHome, c.Name+".avd")
+	if fingerprint, err := goldenFingerprint(golden); err == nil {
+		if _, err := cloneMatchesFingerprint(cloneDir, fingerprint); err != nil {
+			report.add("clone", c.Name, "golden", golden, "different", err.Error())
+		}
+	}
+
+	baseDir := filepath.Join(env.AVDHome, c.Base+".avd")
+	embedded, selfContained := "", false
+	if _, err := os.Stat(baseDir); err != nil {
+		if abs, err := filepath.Abs(golden); err == nil {
+			if embedded, selfContained = embeddedBase(abs); selfContained {
+				baseDir = embedded
+			}
+		}
+	}
+	want, err := cloneConfig(baseDir, c.Base, c.Name, golden, CloneOptions{ConfigVars: c.Vars, ConfigValuesFile: c.Values})
+	if err != nil {
+		report.add("config", c.Name, "config.ini", "", "", fmt.Sprintf("render desired config: %v", err))
+		return
+	}
+	if selfContained {
+		want = pointSysdirAtGolden(want, filepath.Dir(embedded))
+	}
+	got, err := os.ReadFile(filepath.Join(cloneDir, "config.ini"))
+	if err != nil {
+		report.add("config", c.Name, "config.ini", "present", "missing", err.Error())
+		return
+	}
+	wantValues, gotValues := parseConfigINI(want), parseConfigINI(got)
+	keys := make([]string, 0, len(wantValues))
+	for k := range wantValues {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if g, ok := gotValues[k]; !ok || g != wantValues[k] {
+			report.add("config", c.Name, k, wantValues[k], g, "")
+		}
+	}
+}
+
+// parseConfigINI reads key=value lines from an AVD config.ini; later duplicates win,
+// as they do for the emulator.
+func parseConfigINI(b []byte) map[string]string {
+	values := map[string]string{}
+	for _, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if k, v, ok := strings.Cut(line, "="); ok {
+			values[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	return values
+}
@@ -0,0 +1,67 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFleetDiffReportsDrift(t *testing.T) {
+	env := newTestEnv(t)
+	makeBaseAVD(t, env, "base-a35")
+	golden := makeGoldenDir(t)
+	fleet := Fleet{
+		Bases:   []FleetBase{{Name: "base-a35", Image: "system-images;android-35;google_apis;x86_64"}},
+		Goldens: []FleetGolden{{Name: "a35", Base: "base-a35", Path: golden}},
+		Clones:  []FleetClone{{Name: "w-acme", Base: "base-a35", Golden: "a35"}},
+	}
+	if _, err := FleetUp(env, fleet); err != nil {
+		t.Fatalf("FleetUp: %v", err)
+	}
+
+	report, err := FleetDiff(env, fleet)
+	if err != nil {
+		t.Fatalf("FleetDiff: %v", err)
+	}
+	if report.Drifted() {
+		t.Fatalf("expected no drift after up, got %#v", report.Drift)
+	}
+
+	cfgPath := filepath.Join(env.AVDHome, "w-acme.avd", "config.ini")
+	cfg, err := os.ReadFile(cfgPath)
+	if err != nil {
+		t.Fatalf("read clone config: %v", err)
+	}
+	cfg = []byte(strings.Replace(string(cfg), "hw.device.name=pixel_6", "hw.device.name=pixel_7", 1))
+	if err := os.WriteFile(cfgPath, cfg, 0o644); err != nil {
+		t.Fatalf("write clone config: %v", err)
+	}
+	if _, err := CloneFromGolden(env, "base-a35", "w-stray", golden); err != nil {
+		t.Fatalf("CloneFromGolden: %v", err)
+	}
+	fleet.Clones = append(fleet.Clones, FleetClone{Name: "w-gino", Base: "base-a35", Golden: "a35", Run: true})
+
+	report, err = FleetDiff(env, fleet)
+	if err != nil {
+		t.Fatalf("FleetDiff: %v", err)
+	}
+	want := map[string]FleetDrift{
+		"config/w-acme/hw.device.name": {Want: "pixel_6", Got: "pixel_7"},
+		"clone/w-gino/presence":        {Want: "present", Got: "missing"},
+		"instance/w-gino/running":      {Want: "true", Got: "false"},
+		"clone/w-stray/presence":       {Want: "absent", Got: "present"},
+	}
+	if len(report.Drift) != len(want) {
+		t.Fatalf("drift = %#v", report.Drift)
+	}
+	for _, d := range report.Drift {
+		w, ok := want[d.Kind+"/"+d.Name+"/"+d.Field]
+		if !ok || w.Want != d.Want || w.Got != d.Got {
+			t.Fatalf("unexpected drift %#v", d)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFleetFile(t *testing.T, dir, body string) string {
+	t.Helper()
+	path := filepath.Join(dir, "fleet.yaml")
+	if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+		t.Fatalf("write fleet file: %v", err)
+	}
+	return path
+}
+
+func TestLoadFleetResolvesGoldenReferences(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFleetFile(t, dir, `
+bases:
+  - name: base-a35
+    image: system-images;android-35;google_apis_playstore;x86_64
+goldens:
+  - name: a35
+    base: base-a35
+    path: goldens/base-a35
+    prewarm: true
+    settle: 10s
+clones:
+  - name: w-acme
+    base: base-a35
+    golden: a35
+    run: true
+    port: 5580
+    vars:
+      RAM: 4096M
+    labels:
+      customer: acme
+  - name: w-gino
+    base: base-a35
+    golden: other/golden
+placement:
+  affinity: [customer]
+`)
+	fleet, err := LoadFleet(path)
+	if err != nil {
+		t.Fatalf("LoadFleet: %v", err)
+	}
+	if got := fleet.goldenPath("a35"); got != filepath.Join(dir, "goldens", "base-a35") {
+		t.Fatalf("golden path = %q", got)
+	}
+	if got := fleet.Clones[1].Golden; got != filepath.Join(dir, "other", "golden") {
+		t.Fatalf("clone golden path = %q", got)
+	}
+	if fleet.Goldens[0].Settle != 10*time.Second {
+		t.Fatalf("settle = %s", fleet.Goldens[0].Settle)
+	}
+	if fleet.Clones[0].Vars["RAM"] != "4096M" || !fleet.Clones[0].Run {
+		t.Fatalf("unexpected clone spec: %#v", fleet.Clones[0])
+	}
+	if labels := fleet.Labels(); len(labels) != 1 || labels["w-acme"]["customer"] != "acme" || fleet.Placement.Affinity[0] != "customer" {
+		t.Fatalf("labels = %v, placement = %+v", labels, fleet.Placement)
+	}
+}
+
+func TestLoadFleetValidation(t *testing.T) {
+	path := writeFleetFile(t, t.TempDir(), `
+clones:
+  - name: w-acme
+    base: base-a35
+    golden: g
+    port: 5581
+  - name: w-acme
+    base: base-a35
+placement:
+  affinity: [customer]
+  anti_affinity: [customer]
+`)
+	_, err := LoadFleet(path)
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+	for _, needle := range []string{"must be even", "duplicate AVD name w-acme", "base and golden are required", "customer is both affinity and anti_affinity"} {
+		if !strings.Contains(err.Error(), needle) {
+			t.Fatalf("error missing %q: %v", needle, err)
+		}
+	}
+}
+
+func TestFleetUpAndDownConvergeClones(t *testing.T) {
+	env := newTestEnv(t)
+	makeBaseAVD(t, env, "base-a35")
+	golden := makeGoldenDir(t)
+	fleet := Fleet{
+		Bases:   []FleetBase{{Name: "base-a35", Image: "system-images;android-35;google_apis;x86_64"}},
+		Goldens: []FleetGolden{{Name: "a35", Base: "base-a35", Path: golden}},
+		Clones:  []FleetClone{{Name: "w-acme", Base: "base-a35", Golden: "a35"}},
+	}
+
+	report, err := FleetUp(env, fleet)
+	if err != nil {
+		t.Fatalf("FleetUp: %v", err)
+	}
+	if report.Failed() {
+		t.Fatalf("unexpected failure: %#v", report)
+	}
+	if got := fleetActionStatus(report, "clone", "w-acme"); got != "done" {
+		t.Fatalf("first clone status = %q", got)
+	}
+
+	report, err = FleetUp(env, fleet)
+	if err != nil {
+		t.Fatalf("FleetUp again: %v", err)
+	}
+	if got := fleetActionStatus(report, "clone", "w-acme"); got != "unchanged" {
+		t.Fatalf("second clone status = %q", got)
+	}
+
+	report, err = FleetDown(env, fleet, true)
+	if err != nil {
+		t.Fatalf("FleetDown: %v", err)
+	}
+	if got := fleetActionStatus(report, "delete", "w-acme"); got != "done" {
+		t.Fatalf("delete status = %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(env.AVDHome, "w-acme.avd")); !os.IsNotExist(err) {
+		t.Fatal("expected clone removed by down --remove")
+	}
+	if _, err := os.Stat(filepath.Join(env.AVDHome, "base-a35.avd")); err != nil {
+		t.Fatal("down must keep bases")
+	}
+}
+
+func TestFleetUpReportsMissingGolden(t *testing.T) {
+	env := newTestEnv(t)
+	fleet := Fleet{Goldens: []FleetGolden{{Name: "gone", Path: filepath.Join(t.TempDir(), "missing")}}}
+	report, err := FleetUp(env, fleet)
+	if err != nil {
+		t.Fatalf("FleetUp: %v", err)
+	}
+	if !report.Failed() || fleetActionStatus(report, "create-golden", "gone") != "failed" {
+		t.Fatalf("expected failed golden action: %#v", report)
+	}
+}
+
+func fleetActionStatus(report FleetReport, kind, name string) string {
+	for _, action := range report.Actions {
+		if action.Kind == kind && action.Name == name {
+			return action.Status
+		}
+	}
+	return ""
+}
@@ -0,0 +1,147 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// PortForward is a TCP tunnel managed by adb for one emulator.
+// Direction "forward" carries host connections to the device; "reverse" carries device
+// connections to the host (e.g. a clone reaching a mock server running on the CI host).
+type PortForward struct {
+	Direction  string `json:"direction"` // forward, reverse
+	HostPort   int    `json:"host_port"`
+	DevicePort int    `json:"device_port"`
+}
+
+// Forward makes hostPort on the host connect to devicePort on the emulator. A hostPort of 0
+// lets adb pick a free port, which is returned.
+func Forward(env Env, serial string, hostPort, devicePort int) (PortForward, error) {
+	_, span := startSpan(env, "avd.Forward",
+		attribute.String("serial", serial),
+		attribute.Int("host_port", hostPort),
+		attribute.Int("device_port", devicePort),
+	)
+	defer span.End()
+	out, errOut, err := runCommandOutputWithEnv(env.Context, nil, nil, env.ADB,
+		"-s", serial, "forward", tcpSpec(hostPort), tcpSpec(devicePort))
+	if err != nil {
+		err = fmt.Errorf("adb forward on %s: %w: %s", serial, err, strings.TrimSpace(errOut))
+		recordSpanError(span, err)
+		return PortForward{}, err
+	}
+	if hostPort == 0 {
+		// adb prints the allocated port when asked for tcp:0.
+		if n, convErr := strconv.Atoi(strings.TrimSpace(out)); convErr == nil {
+			hostPort = n
+		}
+	}
+	logEvent(env, "port forward added", "serial", serial, "host_port", hostPort, "device_port", devicePort)
+	return PortForward{Direction: "forward", HostPort: hostPort, DevicePort: devicePort}, nil
+}
+
+// Reverse makes devicePort on the emulator connect to hostPort on the host.
+func Reverse(env Env, serial string, devicePort, hostPort int) (PortForward, error) {
+	_, span := startSpan(env, "avd.Reverse",
+		attribute.String("serial", serial),
+		attribute.Int("device_port", devicePort),
+		attribute.Int("host_port", hostPort),
+	)
+	defer span.End()
+	if _, errOut, err := runCommandOutputWithEnv(env.Context, nil, nil, env.ADB,
+		"-s", serial, "reverse", tcpSpec(devicePort), tcpSpec(hostPort)); err != nil {
+		err = fmt.Errorf("adb reverse on %s: %w: %s", serial, err, strings.TrimSpace(errOut))
+		recordSpanError(span, err)
+		return PortForward{}, err
+	}
+	logEvent(env, "reverse forward added", "serial", serial, "device_port", devicePort, "host_port", hostPort)
+	return PortForward{Direction: "reverse", HostPort: hostPort, DevicePort: devicePort}, nil
+}
+
+// RemoveForward removes one forward or reverse tunnel.
+func RemoveForward(env Env, serial string, fwd PortForward) error {
+	args := []string{"-s", serial, "forward", "--remove", tcpSpec(fwd.HostPort)}
+	if fwd.Direction == "reverse" {
+		args = []string{"-s", serial, "reverse", "--remove", tcpSpec(fwd.DevicePort)}
+	}
+	if _, errOut, err := runCommandOutputWithEnv(env.Context, nil, nil, env.ADB, args...); err != nil {
+		return fmt.Errorf("remove %s tunnel on %s: %w: %s", fwd.Direction, serial, err, strings.TrimSpace(errOut))
+	}
+	logEvent(env, "port forward removed", "serial", serial, "direction", fwd.Direction,
+		"host_port", fwd.HostPort, "device_port", fwd.DevicePort)
+	return nil
+}
+
+// ListForwards returns the TCP tunnels adb holds for serial. Non-TCP tunnels (localabstract,
+// jdwp, ...) are not managed by avdctl and are skipped.
+func ListForwards(env Env, serial string) ([]PortForward, error) {
+	var forwards []PortForward
+	out, _, err := runCommandOutputWithEnv(env.Context, nil, nil, env.ADB, "forward", "--list")
+	if err != nil {
+		return nil, fmt.Errorf("adb forward --list: %w", err)
+	}
+	for _, line := range strings.Split(out, "\n") {
+		// <serial> <host spec> <device spec>
+		f := strings.Fields(line)
+		if len(f) != 3 || f[0] != serial {
+			continue
+		}
+		host, okHost := parseTCPSpec(f[1])
+		device, okDevice := parseTCPSpec(f[2])
+		if okHost && okDevice {
+			forwards = append(forwards, PortForward{Direction: "forward", HostPort: host, DevicePort: device})
+		}
+	}
+	out, _, err = runCommandOutputWithEnv(env.Context, nil, nil, env.ADB, "-s", serial, "reverse", "--list")
+	if err != nil {
+		return nil, fmt.Errorf("adb reverse --list: %w", err)
+	}
+	for _, line := range strings.Split(out, "\n") {
+		// <transport> <device spec> <host spec>
+		f := strings.Fields(line)
+		if len(f) != 3 {
+			continue
+		}
+		device, okDevice := parseTCPSpec(f[1])
+		host, okHost := parseTCPSpec(f[2])
+		if okHost && okDevice {
+			forwards = append(forwards, PortForward{Direction: "reverse", HostPort: host, DevicePort: device})
+		}
+	}
+	return forwards, nil
+}
+
+// clearPortForwards drops every tunnel of serial, so a stopped emulator leaves no host
+// listeners behind for the next instance that reuses its ports.
+func clearPortForwards(env Env, serial string) {
+	_, _, _ = runCommandOutputWithEnv(env.Context, nil, nil, env.ADB, "-s", serial, "reverse", "--remove-all")
+	// "adb forward --remove-all" drops the forwards of every device, so remove them one by one.
+	forwards, err := ListForwards(env, serial)
+	if err != nil {
+		return
+	}
+	for _, fwd := range forwards {
+		if fwd.Direction == "forward" {
+			_ = RemoveForward(env, serial, fwd)
+		}
+	}
+}
+
+func tcpSpec(port int) string {
+	return "tcp:" + strconv.Itoa(port)
+}
+
+func parseTCPSpec(spec string) (int, bool) {
+	port, ok := strings.CutPrefix(spec, "tcp:")
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.Atoi(port)
+	return n, err == nil
+}
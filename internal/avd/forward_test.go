@@ -0,0 +1,76 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeForwardADBStub(t *testing.T, env Env) string {
+	t.Helper()
+	logPath := filepath.Join(t.TempDir(), "adb.log")
+	script := `#!/bin/sh
+echo "$@" >> ` + logPath + `
+case "$*" in
+  "forward --list")
+    printf 'emulator-5598 tcp:8080 tcp:8080\nemulator-5600 tcp:9000 tcp:9000\nemulator-5598 tcp:7000 localabstract:chrome\n' ;;
+  "-s emulator-5598 reverse --list")
+    printf 'host-19 tcp:3000 tcp:4000\n' ;;
+  "-s emulator-5598 forward tcp:0 tcp:80")
+    echo 41234 ;;
+esac
+exit 0
+`
+	if err := os.WriteFile(env.ADB, []byte(script), 0o755); err != nil {
+		t.Fatalf("write adb stub: %v", err)
+	}
+	return logPath
+}
+
+func TestListForwardsFiltersBySerial(t *testing.T) {
+	env := newTestEnv(t)
+	writeForwardADBStub(t, env)
+	got, err := ListForwards(env, "emulator-5598")
+	if err != nil {
+		t.Fatalf("ListForwards: %v", err)
+	}
+	want := []PortForward{
+		{Direction: "forward", HostPort: 8080, DevicePort: 8080},
+		{Direction: "reverse", HostPort: 4000, DevicePort: 3000},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ListForwards = %#v, want %#v", got, want)
+	}
+}
+
+func TestForwardAllocatesHostPort(t *testing.T) {
+	env := newTestEnv(t)
+	writeForwardADBStub(t, env)
+	fwd, err := Forward(env, "emulator-5598", 0, 80)
+	if err != nil {
+		t.Fatalf("Forward: %v", err)
+	}
+	if fwd.HostPort != 41234 || fwd.DevicePort != 80 {
+		t.Fatalf("unexpected forward: %#v", fwd)
+	}
+}
+
+func TestClearPortForwardsOnlyTouchesSerial(t *testing.T) {
+	env := newTestEnv(t)
+	logPath := writeForwardADBStub(t, env)
+	clearPortForwards(env, "emulator-5598")
+	calls, _ := os.ReadFile(logPath)
+	for _, needle := range []string{"-s emulator-5598 reverse --remove-all", "-s emulator-5598 forward --remove tcp:8080"} {
+		if !strings.Contains(string(calls), needle) {
+			t.Fatalf("adb calls missing %q:\n%s", needle, calls)
+		}
+	}
+	if strings.Contains(string(calls), "tcp:9000") || strings.Contains(string(calls), "forward --remove-all") {
+		t.Fatalf("cleared forwards of another instance:\n%s", calls)
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// guestFridaServerPath is where avdctl installs frida-server in the guest. It lives in
+// userdata, so a golden baked with it gives every clone the server.
+const guestFridaServerPath = "/data/local/tmp/frida-server"
+
+// DefaultFridaPort is the port frida-server listens on in the guest, and the host port
+// StartFrida forwards to it unless told otherwise (frida and objection connect there by default).
+const DefaultFridaPort = 27042
+
+// FridaSession is a frida-server running in a guest, reachable on the host through an adb
+// forward (frida -H 127.0.0.1:HostPort, or frida -U through adb itself).
+type FridaSession struct {
+	Serial     string `json:"serial"`
+	PID        int    `json:"pid"`
+	HostPort   int    `json:"host_port"`
+	DevicePort int    `json:"device_port"`
+}
+
+// InstallFridaServer pushes the frida-server binary for the guest ABI (e.g.
+// frida-server-16.5.9-android-x86_64) to serial. adbd is restarted as root, which frida-server
+// needs to instrument other apps.
+func InstallFridaServer(env Env, serial, server string) error {
+	_, span := startSpan(env, "avd.InstallFridaServer", attribute.String("serial", serial))
+	defer span.End()
+	fail := func(err error) error {
+		recordSpanError(span, err)
+		return err
+	}
+	if server == "" {
+		return fail(errors.New("no frida-server binary given (download frida-server-<version>-android-<abi> from the Frida releases)"))
+	}
+	if _, err := os.Stat(server); err != nil {
+		return fail(fmt.Errorf("frida-server: %w", err))
+	}
+	if err := adbRoot(env, serial); err != nil {
+		return fail(fmt.Errorf("frida-server needs root: %w", err))
+	}
+	if out, err := runCommandCombinedOutputWithEnv(env.Context, nil, nil, env.ADB, "-s", serial, "push", server, guestFridaServerPath); err != nil {
+		return fail(fmt.Errorf("push frida-server to %s: %w: %s", serial, err, strings.TrimSpace(string(out))))
+	}
+	// sync so a baked server reaches userdata before the instance is killed for export.
+	if out, err := runCommandCombinedOutputWithEnv(env.Context, nil, nil, env.ADB, "-s", serial, "shell", "chmod 0755 "+guestFridaServerPath+" && sync"); err != nil {
+		return fail(fmt.Errorf("chmod frida-server on %s: %w: %s", serial, err, strings.TrimSpace(string(out))))
+	}
+	logEvent(env, "frida-server installed", "serial", serial, "source", server)
+	return nil
+}
+
+// StartFrida starts frida-server as root on serial and forwards hostPort (0 = DefaultFridaPort)
+// to it. A guest without the server gets Env.FridaServer installed first. A server that is
+// already running is reused.
+func StartFrida(env Env, serial string, hostPort int) (FridaSession, error) {
+	_, span := startSpan(env, "avd.StartFrida", attribute.String("serial", serial), attribute.Int("host_port", hostPort))
+	defer span.End()
+	fail := func(err error) (FridaSession, error) {
+		recordSpanError(span, err)
+		return FridaSession{}, err
+	}
+	if hostPort == 0 {
+		hostPort = DefaultFridaPort
+	}
+	if err := adbRoot(env, serial); err != nil {
+		return fail(fmt.Errorf("frida-server needs root: %w", err))
+	}
+	if _, _, err := runCommandOutputWithEnv(env.Context, nil, nil, env.ADB, "-s", serial, "shell", "test", "-x", guestFridaServerPath); err != nil {
+		if env.FridaServer == "" {
+			return fail(fmt.Errorf("frida-server is not installed on %s; bake it into the golden or set AVDCTL_FRIDA_SERVER", serial))
+		}
+		if err := InstallFridaServer(env, serial, env.FridaServer); err != nil {
+			return fail(err)
+		}
+	}
+	pid := fridaPID(env, serial)
+	if pid == 0 {
+		listen := "0.0.0.0:" + strconv.Itoa(DefaultFridaPort)
+		script := fmt.Sprintf("nohup %s -D -l %s >/dev/null 2>&1 &", guestFridaServerPath, listen)
+		if out, err := runCommandCombinedOutputWithEnv(env.Context, nil, nil, env.ADB, "-s", serial, "shell", script); err != nil {
+			return fail(fmt.Errorf("start frida-server on %s: %w: %s", serial, err, strings.TrimSpace(string(out))))
+		}
+		for deadline := time.Now().Add(10 * time.Second); pid == 0 && time.Now().Before(deadline); {
+			time.Sleep(500 * time.Millisecond)
+			pid = fridaPID(env, serial)
+		}
+		if pid == 0 {
+			return fail(fmt.Errorf("frida-server did not stay up on %s (wrong ABI, or SELinux denial: see adb logcat)", serial))
+		}
+	}
+	fwd, err := Forward(env, serial, hostPort, DefaultFridaPort)
+	if err != nil {
+		return fail(err)
+	}
+	session := FridaSession{Serial: serial, PID: pid, HostPort: fwd.HostPort, DevicePort: DefaultFridaPort}
+	logEvent(env, "frida-server started", "serial", serial, "pid", pid, "host_port", fwd.HostPort)
+	return session, nil
+}
+
+// StopFrida kills frida-server on serial and removes the forwards to its port.
+func StopFrida(env Env, serial string) error {
+	_, span := startSpan(env, "avd.StopFrida", attribute.String("serial", serial))
+	defer span.End()
+	if pid := fridaPID(env, serial); pid != 0 {
+		if out, err := runCommandCombinedOutputWithEnv(env.Context, nil, nil, env.ADB, "-s", serial, "shell", "kill", strconv.Itoa(pid)); err != nil {
+			err = fmt.Errorf("stop frida-server on %s: %w: %s", serial, err, strings.TrimSpace(string(out)))
+			recordSpanError(span, err)
+			return err
+		}
+	}
+	if forwards, err := ListForwards(env, serial); err == nil {
+		for _, fwd := range forwards {
+			if fwd.Direction == "forward" && fwd.DevicePort == DefaultFridaPort {
+				_ = RemoveForward(env, serial, fwd)
+			}
+		}
+	}
+	logEvent(env, "frida-server stopped", "serial", serial)
+	return nil
+}
+
+// fridaPID returns the pid of the frida-server avdctl installed on serial, or 0.
+func fridaPID(env Env, serial string) int {
+	out, _, err := runCommandOutputWithEnv(env.Context, nil, nil, env.ADB, "-s", serial, "shell", "pidof", guestFridaServerPath)
+	if err != nil {
+		return 0 // pidof exits 1 when nothing matches
+	}
+	for _, field := range strings.Fields(out) {
+		if pid, err := strconv.Atoi(field); err == nil {
+			return pid
+		}
+	}
+	return 0
+}
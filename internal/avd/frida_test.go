@@ -0,0 +1,71 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStartFridaInstallsAndForwards(t *testing.T) {
+	env := newTestEnv(t)
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "adb.log")
+	installed := filepath.Join(dir, "installed")
+	running := filepath.Join(dir, "running")
+	script := `#!/bin/sh
+echo "$@" >> ` + logPath + `
+[ "$1 $2" = "forward --list" ] && [ -f ` + running + `.fwd ] && echo "$SERIAL tcp:27099 tcp:27042"
+case "$3" in
+  push) touch ` + installed + ` ;;
+  forward) [ "$4" = --remove ] && rm -f ` + running + `.fwd || touch ` + running + `.fwd ;;
+  shell)
+    case "$4" in
+      id) echo 0 ;;
+      test) [ -f ` + installed + ` ] || exit 1 ;;
+      pidof) [ -f ` + running + ` ] && echo 4242 || exit 1 ;;
+      nohup*) touch ` + running + ` ;;
+    esac ;;
+esac
+exit 0
+`
+	script = strings.ReplaceAll(script, "$SERIAL", "emulator-5592")
+	if err := os.WriteFile(env.ADB, []byte(script), 0o755); err != nil {
+		t.Fatalf("write adb stub: %v", err)
+	}
+
+	if _, err := StartFrida(env, "emulator-5592", 0); err == nil || !strings.Contains(err.Error(), "AVDCTL_FRIDA_SERVER") {
+		t.Fatalf("expected a missing server error, got %v", err)
+	}
+	env.FridaServer = filepath.Join(dir, "frida-server-16.5.9-android-x86_64")
+	if err := os.WriteFile(env.FridaServer, []byte("ELF"), 0o644); err != nil {
+		t.Fatalf("write server: %v", err)
+	}
+	session, err := StartFrida(env, "emulator-5592", 27099)
+	if err != nil {
+		t.Fatalf("StartFrida: %v", err)
+	}
+	if session.PID != 4242 || session.HostPort != 27099 || session.DevicePort != DefaultFridaPort {
+		t.Fatalf("session = %+v", session)
+	}
+	calls, _ := os.ReadFile(logPath)
+	for _, needle := range []string{"-s emulator-5592 root", "push " + env.FridaServer + " " + guestFridaServerPath,
+		"-l 0.0.0.0:27042", "forward tcp:27099 tcp:27042"} {
+		if !strings.Contains(string(calls), needle) {
+			t.Fatalf("adb calls missing %q:\n%s", needle, calls)
+		}
+	}
+
+	if err := StopFrida(env, "emulator-5592"); err != nil {
+		t.Fatalf("StopFrida: %v", err)
+	}
+	calls, _ = os.ReadFile(logPath)
+	for _, needle := range []string{"shell kill 4242", "forward --remove tcp:27099"} {
+		if !strings.Contains(string(calls), needle) {
+			t.Fatalf("adb calls missing %q:\n%s", needle, calls)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// FsckMode selects whether SaveGoldenWithOptions checks the exported userdata filesystem.
+type FsckMode string
+
+const (
+	FsckOff    FsckMode = ""       // no check
+	FsckCheck  FsckMode = "check"  // e2fsck -n; refuse the export on errors
+	FsckRepair FsckMode = "repair" // e2fsck -y; refuse only if errors remain
+)
+
+// ParseFsckMode parses a --fsck value ("", "off", "check" or "repair").
+func ParseFsckMode(s string) (FsckMode, error) {
+	switch mode := FsckMode(strings.TrimSpace(s)); mode {
+	case FsckOff, "off":
+		return FsckOff, nil
+	case FsckCheck, FsckRepair:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid fsck mode %q (expected off, check or repair)", s)
+	}
+}
+
+// FsckResult is the outcome of an e2fsck run, recorded in the golden manifest.
+type FsckResult struct {
+	Image    string   `json:"image"`
+	Mode     FsckMode `json:"mode"`
+	ExitCode int      `json:"exit_code"`
+	Clean    bool     `json:"clean"`    // no errors found
+	Repaired bool     `json:"repaired"` // errors found and corrected
+	Output   string   `json:"output,omitempty"`
+}
+
+// OK reports whether the filesystem is usable (clean, or repaired).
+func (r FsckResult) OK() bool { return r.Clean || r.Repaired }
+
+// checkFilesystem runs e2fsck on a raw ext4 image. Exported goldens are always raw
+// (SaveGolden converts qcow2 overlays first), so no qemu-nbd attachment is needed.
+func checkFilesystem(env Env, image string, mode FsckMode) (FsckResult, error) {
+	_, span := startSpan(env, "avd.checkFilesystem",
+		attribute.String("image", image),
+		attribute.String("mode", string(mode)),
+	)
+	defer span.End()
+
+	bin := env.E2fsck
+	if bin == "" {
+		bin = "e2fsck"
+	}
+	args := []string{"-f", "-n", image}
+	if mode == FsckRepair {
+		args = []string{"-f", "-y", image}
+	}
+	var stdout, stderr bytes.Buffer
+	err := runBackgroundCommand(env, &stdout, &stderr, bin, args...)
+	result := FsckResult{Image: image, Mode: mode, Output: strings.TrimSpace(stdout.String() + stderr.String())}
+
+	// e2fsck exit status is a bit mask: 1 = errors corrected, 2 = corrected (reboot),
+	// 4 = errors left uncorrected, 8+ = operational error.
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitCode()
+	default:
+		recordSpanError(span, err)
+		return result, fmt.Errorf("run e2fsck: %w", err)
+	}
+	if result.ExitCode >= 8 {
+		err := fmt.Errorf("e2fsck failed on %s (exit %d): %s", image, result.ExitCode, result.Output)
+		recordSpanError(span, err)
+		return result, err
+	}
+	result.Clean = result.ExitCode == 0
+	result.Repaired = result.ExitCode&4 == 0 && result.ExitCode&3 != 0
+	span.SetAttributes(attribute.Int("exit_code", result.ExitCode), attribute.Bool("clean", result.Clean))
+	logEvent(env, "filesystem check finished", "image", image, "mode", mode, "exit_code", result.ExitCode)
+	return result, nil
+}
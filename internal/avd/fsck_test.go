@@ -0,0 +1,104 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func newFsckTestEnv(t *testing.T, fsckExit int) Env {
+	t.Helper()
+	env := newTestEnv(t)
+	tools := t.TempDir()
+	env.QemuImg = filepath.Join(tools, "qemu-img")
+	env.E2fsck = filepath.Join(tools, "e2fsck")
+	qemu := "#!/bin/sh\ncp \"$4\" \"$5\"\n"
+	fsck := "#!/bin/sh\necho \"e2fsck $@\"\nexit " + strconv.Itoa(fsckExit) + "\n"
+	if err := os.WriteFile(env.QemuImg, []byte(qemu), 0o755); err != nil {
+		t.Fatalf("write qemu-img stub: %v", err)
+	}
+	if err := os.WriteFile(env.E2fsck, []byte(fsck), 0o755); err != nil {
+		t.Fatalf("write e2fsck stub: %v", err)
+	}
+	makeBaseAVD(t, env, "demo")
+	if err := os.WriteFile(filepath.Join(env.AVDHome, "demo.avd", "userdata-qemu.img"), []byte("userdata"), 0o644); err != nil {
+		t.Fatalf("write userdata: %v", err)
+	}
+	return env
+}
+
+func TestSaveGoldenRefusesCorruptUserdata(t *testing.T) {
+	env := newFsckTestEnv(t, 4)
+	dest := filepath.Join(t.TempDir(), "golden")
+	_, _, err := SaveGoldenWithOptions(env, "demo", dest, SaveGoldenOptions{Fsck: FsckCheck})
+	if err == nil || !strings.Contains(err.Error(), "refusing to export") {
+		t.Fatalf("expected refused export, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "userdata-qemu.img")); !os.IsNotExist(err) {
+		t.Fatal("corrupt userdata must not be published")
+	}
+	// Nothing of the refused export is left: no golden without a manifest, no partial export.
+	if entries, _ := os.ReadDir(filepath.Dir(dest)); len(entries) != 0 {
+		t.Fatalf("refused export left %v behind", entries)
+	}
+}
+
+func TestSaveGoldenReplacesExistingGolden(t *testing.T) {
+	env := newFsckTestEnv(t, 0)
+	dest := filepath.Join(t.TempDir(), "golden")
+	for i := 0; i < 2; i++ {
+		if _, _, err := SaveGoldenWithOptions(env, "demo", dest, SaveGoldenOptions{Fsck: FsckCheck}); err != nil {
+			t.Fatalf("SaveGoldenWithOptions #%d: %v", i+1, err)
+		}
+	}
+	if _, err := ReadGoldenManifest(dest); err != nil {
+		t.Fatalf("ReadGoldenManifest: %v", err)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(dest)); len(entries) != 1 || entries[0].Name() != "golden" {
+		t.Fatalf("expected only the golden, got %v", entries)
+	}
+}
+
+func TestSaveGoldenRecordsRepairInManifest(t *testing.T) {
+	env := newFsckTestEnv(t, 1)
+	dest := filepath.Join(t.TempDir(), "golden")
+	if _, _, err := SaveGoldenWithOptions(env, "demo", dest, SaveGoldenOptions{Fsck: FsckRepair}); err != nil {
+		t.Fatalf("SaveGoldenWithOptions: %v", err)
+	}
+	manifest, err := ReadGoldenManifest(dest)
+	if err != nil {
+		t.Fatalf("ReadGoldenManifest: %v", err)
+	}
+	if manifest.Source != "demo" || len(manifest.Images) != 1 {
+		t.Fatalf("unexpected manifest: %#v", manifest)
+	}
+	if manifest.Fsck == nil || !manifest.Fsck.Repaired || manifest.Fsck.Clean || manifest.Fsck.Mode != FsckRepair {
+		t.Fatalf("unexpected fsck result: %#v", manifest.Fsck)
+	}
+	if !strings.Contains(manifest.Fsck.Output, "-f -y") {
+		t.Fatalf("expected repair flags in output: %q", manifest.Fsck.Output)
+	}
+}
+
+func TestGoldenFingerprintIgnoresManifest(t *testing.T) {
+	golden := makeGoldenDir(t)
+	before, err := goldenFingerprint(golden)
+	if err != nil {
+		t.Fatalf("goldenFingerprint: %v", err)
+	}
+	if err := writeGoldenManifest(golden, GoldenManifest{Source: "demo"}); err != nil {
+		t.Fatalf("writeGoldenManifest: %v", err)
+	}
+	after, err := goldenFingerprint(golden)
+	if err != nil {
+		t.Fatalf("goldenFingerprint: %v", err)
+	}
+	if before != after {
+		t.Fatal("manifest must not change the golden fingerprint")
+	}
+}
@@ -0,0 +1,277 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// DefaultStorageAlertThreshold is the used fraction of /data at which WatchGuestStorage alerts.
+const DefaultStorageAlertThreshold = 0.9
+
+// GuestStorage is the usage of /data in a running guest.
+type GuestStorage struct {
+	Serial     string `json:"serial"`
+	SizeBytes  int64  `json:"size_bytes"`
+	UsedBytes  int64  `json:"used_bytes"`
+	AvailBytes int64  `json:"avail_bytes"`
+}
+
+// UsedFraction is the used share of /data, between 0 and 1.
+func (s GuestStorage) UsedFraction() float64 {
+	if s.SizeBytes <= 0 {
+		return 0
+	}
+	return float64(s.UsedBytes) / float64(s.SizeBytes)
+}
+
+// GuestDataStorage reports the free space of /data on serial (df inside the guest).
+func GuestDataStorage(env Env, serial string) (GuestStorage, error) {
+	_, span := startSpan(env, "avd.GuestDataStorage", attribute.String("serial", serial))
+	defer span.End()
+	out, err := runCommandCombinedOutputWithEnv(env.Context, nil, nil, env.ADB, "-s", serial, "shell", "df", "-k", "/data")
+	if err != nil {
+		err = fmt.Errorf("df /data on %s: %w: %s", serial, err, bytes.TrimSpace(out))
+		recordSpanError(span, err)
+		return GuestStorage{}, err
+	}
+	storage, err := parseDataDF(string(out))
+	if err != nil {
+		err = fmt.Errorf("df /data on %s: %w", serial, err)
+		recordSpanError(span, err)
+		return GuestStorage{}, err
+	}
+	storage.Serial = serial
+	return storage, nil
+}
+
+// parseDataDF parses "df -k /data" output. Long device names may wrap the row, so the numbers
+// are taken from the end of everything after the header.
+func parseDataDF(out string) (GuestStorage, error) {
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) < 2 {
+		return GuestStorage{}, fmt.Errorf("unexpected output %q", strings.TrimSpace(out))
+	}
+	fields := strings.Fields(strings.Join(lines[1:], " "))
+	if len(fields) < 6 {
+		return GuestStorage{}, fmt.Errorf("unexpected output %q", strings.TrimSpace(out))
+	}
+	var kb [3]int64
+	for i, f := range fields[len(fields)-5 : len(fields)-2] {
+		n, err := strconv.ParseInt(f, 10, 64)
+		if err != nil {
+			return GuestStorage{}, fmt.Errorf("unexpected output %q", strings.TrimSpace(out))
+		}
+		kb[i] = n * 1024
+	}
+	return GuestStorage{SizeBytes: kb[0], UsedBytes: kb[1], AvailBytes: kb[2]}, nil
+}
+
+// StorageWatchOptions controls WatchGuestStorage.
+type StorageWatchOptions struct {
+	// Threshold is the used fraction of /data that raises an alert (default 0.9).
+	Threshold float64
+	// Interval is the time between checks (default 1m).
+	Interval time.Duration
+	// OnAlert is called when usage reaches Threshold, and again only after it dropped below.
+	OnAlert func(GuestStorage)
+}
+
+func (o StorageWatchOptions) withDefaults() StorageWatchOptions {
+	if o.Threshold <= 0 {
+		o.Threshold = DefaultStorageAlertThreshold
+	}
+	if o.Interval <= 0 {
+		o.Interval = time.Minute
+	}
+	return o
+}
+
+// WatchGuestStorage checks /data on serial every Interval until env.Context is done or a check
+// fails (e.g. the emulator exited), logging a "guest storage nearly full" event and calling
+// OnAlert when usage reaches Threshold. A full /data makes installs and tests fail in ways that
+// look unrelated; grow it with ResizeUserdata.
+func WatchGuestStorage(env Env, serial string, opts StorageWatchOptions) error {
+	return watchGuestStorage(env.Context, env, opts, func() (GuestStorage, error) {
+		return GuestDataStorage(env, serial)
+	})
+}
+
+func watchGuestStorage(ctx context.Context, env Env, opts StorageWatchOptions, check func() (GuestStorage, error)) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	opts = opts.withDefaults()
+	alerted := false
+	for {
+		storage, err := check()
+		if err != nil {
+			return err
+		}
+		full := storage.UsedFraction() >= opts.Threshold
+		if full && !alerted {
+			logEvent(env, "guest storage nearly full", "serial", storage.Serial,
+				"used_bytes", storage.UsedBytes, "size_bytes", storage.SizeBytes, "threshold", opts.Threshold)
+			if opts.OnAlert != nil {
+				opts.OnAlert(storage)
+			}
+		}
+		alerted = full
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(opts.Interval):
+		}
+	}
+}
+
+// ResizeResult is the outcome of ResizeUserdata.
+type ResizeResult struct {
+	Name         string `json:"name"`
+	Image        string `json:"image"`
+	OldSizeBytes int64  `json:"old_size_bytes"`
+	NewSizeBytes int64  `json:"new_size_bytes"`
+	// FilesystemResized is false when the ext4 filesystem is left for the emulator to grow on the
+	// next boot: for qcow2 overlays, or without resize2fs on the host.
+	FilesystemResized bool `json:"filesystem_resized"`
+}
+
+// ResizeUserdata grows the data image of AVD name to newSize bytes with qemu-img resize, grows
+// its ext4 filesystem with resize2fs (raw images) and records the size as
+// disk.dataPartition.size in config.ini. The emulator holds the image open, so the AVD must be
+// stopped; images are never shrunk.
+func ResizeUserdata(env Env, name string, newSize int64) (ResizeResult, error) {
+	_, span := startSpan(env, "avd.ResizeUserdata", attribute.String("avd.name", name), attribute.Int64("size_bytes", newSize))
+	defer span.End()
+	fail := func(err error) (ResizeResult, error) {
+		recordSpanError(span, err)
+		return ResizeResult{}, err
+	}
+	avdDir := filepath.Join(env.AVDHome, name+".avd")
+	if !fileExists(avdDir) {
+		return fail(fmt.Errorf("AVD %s not found", name))
+	}
+	procs, err := ListRunning(env)
+	if err != nil {
+		return fail(err)
+	}
+	for _, p := range procs {
+		if p.Name == name {
+			return fail(fmt.Errorf("%s is running as %s; stop it before resizing userdata", name, p.Serial))
+		}
+	}
+	var data *WritableImage
+	for _, img := range writableImages(avdDir) {
+		if img.Role == RoleData {
+			data = &img
+			break
+		}
+	}
+	if data == nil {
+		return fail(fmt.Errorf("no userdata image in %s (looked for %s)", avdDir, dataImageVariants()))
+	}
+	format := "raw"
+	if data.Overlay {
+		format = "qcow2"
+	}
+	oldSize, err := imageVirtualSize(env, data.Path, format)
+	if err != nil {
+		return fail(err)
+	}
+	if newSize <= oldSize {
+		return fail(fmt.Errorf("userdata of %s is already %d bytes; ResizeUserdata only grows it", name, oldSize))
+	}
+	if err := run(env, env.QemuImg, "resize", "-f", format, data.Path, strconv.FormatInt(newSize, 10)); err != nil {
+		return fail(err)
+	}
+	result := ResizeResult{Name: name, Image: data.Path, OldSizeBytes: oldSize, NewSizeBytes: newSize}
+	if !data.Overlay {
+		if result.FilesystemResized, err = resizeFilesystem(env, data.Path); err != nil {
+			return fail(err)
+		}
+	}
+	cfgPath := filepath.Join(avdDir, "config.ini")
+	if cfg, err := os.ReadFile(cfgPath); err == nil {
+		cfg = setConfigValue(cfg, "disk.dataPartition.size", strconv.FormatInt(newSize, 10))
+		if err := os.WriteFile(cfgPath, cfg, 0o644); err != nil {
+			return fail(fmt.Errorf("update config.ini: %w", err))
+		}
+	}
+	logEvent(env, "userdata resized", "name", name, "image", data.Path,
+		"old_size_bytes", oldSize, "new_size_bytes", newSize, "filesystem_resized", result.FilesystemResized)
+	return result, nil
+}
+
+// imageVirtualSize returns the size the guest sees for a disk image.
+func imageVirtualSize(env Env, path, format string) (int64, error) {
+	if format == "raw" {
+		st, err := os.Stat(path)
+		if err != nil {
+			return 0, err
+		}
+		return st.Size(), nil
+	}
+	out, _, err := runCommandOutputWithEnv(env.Context, nil, nil, env.QemuImg, "info", "--output=json", "-f", format, path)
+	if err != nil {
+		return 0, fmt.Errorf("qemu-img info %s: %w", path, err)
+	}
+	var info struct {
+		VirtualSize int64 `json:"virtual-size"`
+	}
+	if err := json.Unmarshal([]byte(out), &info); err != nil {
+		return 0, fmt.Errorf("parse qemu-img info %s: %w", path, err)
+	}
+	return info.VirtualSize, nil
+}
+
+// resizeFilesystem grows the ext4 filesystem of a raw image to the image size. resize2fs
+// wants a freshly checked filesystem, so e2fsck -f -y runs first. It reports false when
+// resize2fs is not installed.
+func resizeFilesystem(env Env, image string) (bool, error) {
+	bin := env.Resize2fs
+	if bin == "" {
+		bin = "resize2fs"
+	}
+	if _, err := exec.LookPath(bin); err != nil {
+		logEvent(env, "resize2fs not found; the emulator grows the filesystem on next boot", "image", image)
+		return false, nil
+	}
+	result, err := checkFilesystem(env, image, FsckRepair)
+	if err != nil {
+		return false, err
+	}
+	if !result.OK() {
+		return false, fmt.Errorf("userdata filesystem has errors (e2fsck exit %d); not resizing it:\n%s", result.ExitCode, result.Output)
+	}
+	var out bytes.Buffer
+	if err := runBackgroundCommand(env, &out, &out, bin, image); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return false, fmt.Errorf("resize2fs %s failed (exit %d):\n%s", image, exitErr.ExitCode(), strings.TrimSpace(out.String()))
+		}
+		return false, fmt.Errorf("run resize2fs: %w", err)
+	}
+	return true, nil
+}
+
+// ParseSize parses an image size such as "8G", "512M" or "8589934592" (powers of 1024).
+func ParseSize(s string) (int64, error) {
+	n, err := ParseByteRate(s)
+	if err != nil || n <= 0 || strings.Contains(s, "/") {
+		return 0, fmt.Errorf("invalid size %q (want e.g. 8G)", s)
+	}
+	return n, nil
+}
@@ -0,0 +1,101 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDataDF(t *testing.T) {
+	out := "Filesystem     1K-blocks    Used Available Use% Mounted on\n" +
+		"/dev/block/vdc    6102624 5492361    610263  90% /data\n"
+	s, err := parseDataDF(out)
+	if err != nil {
+		t.Fatalf("parseDataDF: %v", err)
+	}
+	if s.SizeBytes != 6102624*1024 || s.AvailBytes != 610263*1024 || s.UsedFraction() < 0.89 {
+		t.Fatalf("storage = %+v", s)
+	}
+	wrapped := "Filesystem 1K-blocks Used Available Use% Mounted on\n/dev/block/by-name/very-long-userdata-name\n 100 50 50 50% /data\n"
+	if s, err := parseDataDF(wrapped); err != nil || s.UsedBytes != 50*1024 {
+		t.Fatalf("parseDataDF(wrapped) = %+v, %v", s, err)
+	}
+	if _, err := parseDataDF("df: /data: Permission denied"); err == nil {
+		t.Fatal("expected error for unparsable output")
+	}
+}
+
+func TestWatchGuestStorageAlertsOncePerCrossing(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	used := []int64{50, 95, 96, 10, 99}
+	var alerts []int64
+	check := func() (GuestStorage, error) {
+		if len(used) == 0 {
+			return GuestStorage{}, errors.New("emulator gone")
+		}
+		s := GuestStorage{Serial: "emulator-5580", SizeBytes: 100, UsedBytes: used[0]}
+		used = used[1:]
+		return s, nil
+	}
+	opts := StorageWatchOptions{Interval: time.Millisecond, OnAlert: func(s GuestStorage) { alerts = append(alerts, s.UsedBytes) }}
+	err := watchGuestStorage(ctx, Env{}, opts, check)
+	if err == nil || err.Error() != "emulator gone" {
+		t.Fatalf("watch returned %v", err)
+	}
+	if len(alerts) != 2 || alerts[0] != 95 || alerts[1] != 99 {
+		t.Fatalf("alerts = %v", alerts)
+	}
+}
+
+func TestResizeUserdataGrowsRawImage(t *testing.T) {
+	env := newTestEnv(t)
+	tools := t.TempDir()
+	env.QemuImg = filepath.Join(tools, "qemu-img")
+	env.Resize2fs = filepath.Join(tools, "missing-resize2fs")
+	// qemu-img resize -f raw IMAGE SIZE
+	if err := os.WriteFile(env.QemuImg, []byte("#!/bin/sh\ntruncate -s \"$5\" \"$4\"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	makeBaseAVD(t, env, "demo")
+	dir := filepath.Join(env.AVDHome, "demo.avd")
+	if err := os.WriteFile(filepath.Join(dir, "userdata.img"), make([]byte, 4096), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := ResizeUserdata(env, "demo", 8192)
+	if err != nil {
+		t.Fatalf("ResizeUserdata: %v", err)
+	}
+	if result.OldSizeBytes != 4096 || result.NewSizeBytes != 8192 || result.FilesystemResized {
+		t.Fatalf("result = %+v", result)
+	}
+	if st, err := os.Stat(filepath.Join(dir, "userdata.img")); err != nil || st.Size() != 8192 {
+		t.Fatalf("image not grown: %v", err)
+	}
+	cfg, _ := os.ReadFile(filepath.Join(dir, "config.ini"))
+	if configValue(cfg, "disk.dataPartition.size") != "8192" {
+		t.Fatalf("config.ini not updated:\n%s", cfg)
+	}
+	if _, err := ResizeUserdata(env, "demo", 4096); err == nil || !strings.Contains(err.Error(), "only grows") {
+		t.Fatalf("expected shrink refusal, got %v", err)
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	if n, err := ParseSize("8G"); err != nil || n != 8<<30 {
+		t.Fatalf("ParseSize(8G) = %d, %v", n, err)
+	}
+	for _, bad := range []string{"", "0", "200M/s", "big"} {
+		if _, err := ParseSize(bad); err == nil {
+			t.Fatalf("ParseSize(%q) succeeded", bad)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// hibernatedFilename records, in the AVD directory, the snapshot a hibernated instance is
+// woken from.
+const hibernatedFilename = "avdctl-hibernated.json"
+
+// hibernateSnapshot is the emulator snapshot Hibernate saves; each Hibernate overwrites it.
+const hibernateSnapshot = "avdctl-hibernate"
+
+// Hibernation is a stopped instance whose RAM and disk state were saved by Hibernate.
+type Hibernation struct {
+	Name     string    `json:"name"`
+	Snapshot string    `json:"snapshot"`
+	Port     int       `json:"port"` // console port it ran on; Wake reuses it when free
+	Since    time.Time `json:"since"`
+	// SizeBytes is the size of the snapshot under the AVD's snapshots directory.
+	SizeBytes int64 `json:"size_bytes"`
+}
+
+// Hibernate saves a snapshot of the running instance name through the console, then stops it
+// so it holds no CPU, memory or ports. Wake boots it from the snapshot in seconds. A paused
+// instance is resumed first, since a stopped process cannot answer the console.
+func Hibernate(env Env, name string) (Hibernation, error) {
+	_, span := startSpan(env, "avd.Hibernate", attribute.String("name", name))
+	defer span.End()
+	fail := func(err error) (Hibernation, error) {
+		recordSpanError(span, err)
+		return Hibernation{}, err
+	}
+	procs, err := ListRunning(env)
+	if err != nil {
+		return fail(err)
+	}
+	var proc ProcInfo
+	for _, p := range procs {
+		if p.Name == name {
+			proc = p
+			break
+		}
+	}
+	if proc.Serial == "" {
+		return fail(fmt.Errorf("%s is not running", name))
+	}
+	if proc.Paused {
+		if err := Resume(env, proc.Serial); err != nil {
+			return fail(err)
+		}
+	}
+	if _, err := Console(env, proc.Serial, "avd snapshot save "+hibernateSnapshot); err != nil {
+		return fail(fmt.Errorf("hibernate %s: save snapshot: %w", name, err))
+	}
+	if err := StopBySerialWithOptions(env, proc.Serial, StopOptions{Mode: StopConsoleKill}); err != nil {
+		return fail(fmt.Errorf("hibernate %s: %w", name, err))
+	}
+	avdDir := filepath.Join(env.AVDHome, name+".avd")
+	h := Hibernation{
+		Name:      name,
+		Snapshot:  hibernateSnapshot,
+		Port:      proc.Port,
+		Since:     time.Now().UTC(),
+		SizeBytes: dirSize(filepath.Join(avdDir, "snapshots", hibernateSnapshot)),
+	}
+	if err := writeHibernation(avdDir, h); err != nil {
+		return fail(fmt.Errorf("record hibernation of %s: %w", name, err))
+	}
+	span.SetAttributes(attribute.Int64("snapshot_bytes", h.SizeBytes))
+	logEvent(env, "emulator hibernated", "name", name, "serial", proc.Serial, "snapshot_bytes", h.SizeBytes)
+	return h, nil
+}
+
+// Wake starts a hibernated instance from its snapshot, on its previous port when that is free.
+// Like StartEmulatorOnPort it returns once the emulator is launched; wait for boot separately.
+func Wake(env Env, name string) (StartResult, error) {
+	_, span := startSpan(env, "avd.Wake", attribute.String("name", name))
+	defer span.End()
+	fail := func(err error) (StartResult, error) {
+		recordSpanError(span, err)
+		return StartResult{}, err
+	}
+	avdDir := filepath.Join(env.AVDHome, name+".avd")
+	h, ok := readHibernation(avdDir)
+	if !ok {
+		return fail(fmt.Errorf("%s is not hibernated", name))
+	}
+	if _, err := os.Stat(filepath.Join(avdDir, "snapshots", h.Snapshot)); err != nil {
+		clearHibernation(avdDir)
+		return fail(fmt.Errorf("snapshot %s of %s is gone: %w", h.Snapshot, name, err))
+	}
+	port := h.Port
+	if port == 0 || !isPortPairFree(env, port) {
+		var err error
+		if port, err = stickyPort(env, name); err != nil {
+			return fail(err)
+		}
+	}
+	// StartEmulatorWithArgs clears the hibernation record once the emulator is launched.
+	args := HeadlessArgs(name, port)
+	args.Snapshot, args.NoSnapshot, args.NoSnapshotLoad = h.Snapshot, false, false
+	res, err := StartEmulatorWithArgs(env, args)
+	if err != nil {
+		return fail(fmt.Errorf("wake %s: %w", name, err))
+	}
+	span.SetAttributes(attribute.String("serial", res.Serial))
+	logEvent(env, "emulator woken", "name", name, "serial", res.Serial, "hibernated_for", time.Since(h.Since).Round(time.Second))
+	return res, nil
+}
+
+// ListHibernated returns the hibernated instances under Env.AVDHome, sorted by name.
+func ListHibernated(env Env) ([]Hibernation, error) {
+	dirs, err := filepath.Glob(filepath.Join(env.AVDHome, "*.avd"))
+	if err != nil {
+		return nil, err
+	}
+	var out []Hibernation
+	for _, dir := range dirs {
+		if h, ok := readHibernation(dir); ok {
+			out = append(out, h)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+func writeHibernation(avdDir string, h Hibernation) error {
+	b, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(avdDir, hibernatedFilename), append(b, '\n'), 0o644)
+}
+
+func readHibernation(avdDir string) (Hibernation, bool) {
+	b, err := os.ReadFile(filepath.Join(avdDir, hibernatedFilename))
+	if err != nil {
+		return Hibernation{}, false
+	}
+	var h Hibernation
+	if err := json.Unmarshal(b, &h); err != nil || h.Snapshot == "" {
+		return Hibernation{}, false
+	}
+	return h, true
+}
+
+// clearHibernation forgets a hibernation on every start: once the instance ran again, its disk
+// no longer matches the snapshot.
+func clearHibernation(avdDir string) {
+	_ = os.Remove(filepath.Join(avdDir, hibernatedFilename))
+}
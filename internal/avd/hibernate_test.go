@@ -0,0 +1,43 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestListHibernatedAndWakeChecks(t *testing.T) {
+	env := newTestEnv(t)
+	makeBaseAVD(t, env, "w-2")
+	makeBaseAVD(t, env, "w-1")
+	makeBaseAVD(t, env, "idle")
+	for _, name := range []string{"w-2", "w-1"} {
+		h := Hibernation{Name: name, Snapshot: hibernateSnapshot, Port: 5580, Since: time.Now().UTC()}
+		if err := writeHibernation(filepath.Join(env.AVDHome, name+".avd"), h); err != nil {
+			t.Fatal(err)
+		}
+	}
+	list, err := ListHibernated(env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0].Name != "w-1" || list[1].Name != "w-2" {
+		t.Fatalf("ListHibernated = %+v", list)
+	}
+
+	if _, err := Wake(env, "idle"); err == nil || !strings.Contains(err.Error(), "not hibernated") {
+		t.Fatalf("Wake of a non-hibernated AVD: %v", err)
+	}
+	// w-1 has no snapshot on disk: Wake fails and forgets the stale record.
+	if _, err := Wake(env, "w-1"); err == nil || !strings.Contains(err.Error(), "is gone") {
+		t.Fatalf("Wake without a snapshot: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(env.AVDHome, "w-1.avd", hibernatedFilename)); !os.IsNotExist(err) {
+		t.Fatalf("stale hibernation record kept: %v", err)
+	}
+}
@@ -0,0 +1,345 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// HomeMigrationOptions selects the AVD home to move and where to.
+type HomeMigrationOptions struct {
+	From   string // current AVD home, e.g. ~/.android/avd
+	To     string // new AVD home, e.g. /data/avd; created if missing
+	DryRun bool   // only report what would move
+}
+
+// HomeMigrationItem is one AVD moved by MigrateHome.
+type HomeMigrationItem struct {
+	Name   string `json:"name"`
+	Status string `json:"status"` // moved, planned, failed
+	// Rewritten lists what was pointed at the new home: the .ini path, symlinks,
+	// hardware-qemu.ini keys and qcow2 backing files.
+	Rewritten []string `json:"rewritten,omitempty"`
+	// Problems are the references that do not resolve after the move.
+	Problems []string `json:"problems,omitempty"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// HomeMigrationReport lists the AVDs handled by MigrateHome.
+type HomeMigrationReport struct {
+	From string              `json:"from"`
+	To   string              `json:"to"`
+	AVDs []HomeMigrationItem `json:"avds"`
+}
+
+// Failed reports whether an AVD could not be moved or does not resolve after the move.
+func (r HomeMigrationReport) Failed() bool {
+	for _, item := range r.AVDs {
+		if item.Status == "failed" || len(item.Problems) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// MigrateHome moves every AVD of opts.From (its .avd directory and .ini) to opts.To, for hosts
+// whose home partition is too small for a fleet. Moves within a filesystem are renames; across
+// filesystems the files are copied (sparse, symlinks kept) and then removed. Once every AVD has
+// moved, the references into the old home are rewritten: the .ini path, absolute symlinks (a
+// clone's links to its base), hardware-qemu.ini paths and qcow2 backing files. Each AVD is then
+// verified and the references that still do not resolve are reported. Nothing moves while an
+// emulator of opts.From is running. Point ANDROID_AVD_HOME at opts.To afterwards.
+func MigrateHome(env Env, opts HomeMigrationOptions) (HomeMigrationReport, error) {
+	_, span := startSpan(env, "avd.MigrateHome", attribute.String("from", opts.From), attribute.String("to", opts.To))
+	defer span.End()
+	fail := func(err error) (HomeMigrationReport, error) {
+		recordSpanError(span, err)
+		return HomeMigrationReport{}, err
+	}
+	from, err := filepath.Abs(opts.From)
+	if err != nil {
+		return fail(err)
+	}
+	to, err := filepath.Abs(opts.To)
+	if err != nil {
+		return fail(err)
+	}
+	if from == to {
+		return fail(errors.New("--from and --to are the same directory"))
+	}
+	if _, inside := pathInside(from, to); inside {
+		return fail(fmt.Errorf("%s is inside %s", to, from))
+	}
+	if _, inside := pathInside(to, from); inside {
+		return fail(fmt.Errorf("%s is inside %s", from, to))
+	}
+	fromEnv := env
+	fromEnv.AVDHome = from
+	avds, err := List(fromEnv)
+	if err != nil {
+		return fail(err)
+	}
+	procs, err := ListRunning(fromEnv)
+	if err != nil {
+		return fail(err)
+	}
+	inHome := map[string]bool{}
+	for _, info := range avds {
+		inHome[info.Name] = true
+	}
+	var running []string
+	for _, p := range procs {
+		if inHome[p.Name] {
+			running = append(running, p.Name)
+		}
+	}
+	if len(running) > 0 {
+		return fail(fmt.Errorf("stop the running AVDs first: %s", strings.Join(running, ", ")))
+	}
+
+	report := HomeMigrationReport{From: from, To: to, AVDs: []HomeMigrationItem{}}
+	if !opts.DryRun {
+		if err := os.MkdirAll(to, 0o755); err != nil {
+			return fail(fmt.Errorf("create %s: %w", to, err))
+		}
+	}
+	var moved []int
+	for _, info := range avds {
+		item := HomeMigrationItem{Name: info.Name, Status: "planned"}
+		dstDir := filepath.Join(to, info.Name+".avd")
+		switch {
+		case fileExists(dstDir) || fileExists(filepath.Join(to, info.Name+".ini")):
+			item.Status, item.Error = "failed", "already exists in "+to
+		case !opts.DryRun:
+			if err := moveAVD(from, to, info.Name); err != nil {
+				item.Status, item.Error = "failed", err.Error()
+			} else {
+				item.Status = "moved"
+				moved = append(moved, len(report.AVDs))
+			}
+		}
+		report.AVDs = append(report.AVDs, item)
+	}
+	// References are rewritten once all AVDs have moved: a clone may link into a base that
+	// comes later in the listing.
+	for _, i := range moved {
+		item := &report.AVDs[i]
+		item.Rewritten = rewriteHomeReferences(env, from, to, item.Name)
+		item.Problems = verifyHomeReferences(env, from, to, item.Name)
+	}
+	span.SetAttributes(attribute.Int("moved", len(moved)))
+	logEvent(env, "avd home migrated", "from", from, "to", to, "moved", len(moved), "dry_run", opts.DryRun, "failed", report.Failed())
+	return report, nil
+}
+
+// moveAVD moves the .avd directory and then the .ini of name from one home to the other.
+func moveAVD(from, to, name string) error {
+	if err := movePath(filepath.Join(from, name+".avd"), filepath.Join(to, name+".avd")); err != nil {
+		return err
+	}
+	ini := filepath.Join(from, name+".ini")
+	if !fileExists(ini) {
+		return nil
+	}
+	return movePath(ini, filepath.Join(to, name+".ini"))
+}
+
+// movePath renames src to dst, falling back to copy-and-remove across filesystems.
+func movePath(src, dst string) error {
+	err := os.Rename(src, dst)
+	if !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+	if err := copyTree(src, dst); err != nil {
+		_ = os.RemoveAll(dst)
+		return fmt.Errorf("copy %s: %w", src, err)
+	}
+	return os.RemoveAll(src)
+}
+
+// copyTree copies src to dst: directories, regular files (sparse, with their mode and
+// modification time, which clone fingerprints depend on) and symlinks as links.
+func copyTree(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(src, path)
+		target := filepath.Join(dst, rel)
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case entry.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		case entry.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case entry.Type().IsRegular():
+			if err := copyFile(path, target, info.Mode().Perm()); err != nil {
+				return err
+			}
+			return os.Chtimes(target, info.ModTime(), info.ModTime())
+		}
+		return nil
+	})
+}
+
+// rewriteHomeReferences points the references of the moved AVD name from the old home at the
+// new one. Failures are left to verifyHomeReferences to report.
+func rewriteHomeReferences(env Env, from, to, name string) []string {
+	var rewritten []string
+	avdDir := filepath.Join(to, name+".avd")
+	iniPath := filepath.Join(to, name+".ini")
+	if b, err := os.ReadFile(iniPath); err == nil {
+		// path.rel is relative to the Android user home, which the new home need not be under.
+		out := []string{}
+		for _, line := range strings.Split(strings.TrimRight(string(b), "\n"), "\n") {
+			switch {
+			case strings.HasPrefix(line, "path="):
+				line = "path=" + avdDir
+			case strings.HasPrefix(line, "path.rel="):
+				continue
+			}
+			out = append(out, line)
+		}
+		if os.WriteFile(iniPath, []byte(strings.Join(out, "\n")+"\n"), 0o644) == nil {
+			rewritten = append(rewritten, name+".ini path")
+		}
+	}
+	_ = filepath.WalkDir(avdDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.Type()&fs.ModeSymlink == 0 {
+			return nil
+		}
+		link, err := os.Readlink(path)
+		if err != nil {
+			return nil
+		}
+		if rel, ok := pathInside(from, link); ok {
+			if os.Remove(path) == nil && os.Symlink(filepath.Join(to, rel), path) == nil {
+				relPath, _ := filepath.Rel(avdDir, path)
+				rewritten = append(rewritten, "symlink "+relPath)
+			}
+		}
+		return nil
+	})
+	if cfg, err := ReadHardwareConfig(avdDir); err == nil {
+		if keys := cfg.RewritePaths(from, to); len(keys) > 0 &&
+			os.WriteFile(filepath.Join(avdDir, hardwareConfigFilename), cfg.Bytes(), 0o644) == nil {
+			for _, key := range keys {
+				rewritten = append(rewritten, hardwareConfigFilename+" "+key)
+			}
+		}
+	}
+	for _, img := range qcow2Images(avdDir) {
+		backing, format := qcow2Backing(env, img)
+		rel, ok := pathInside(from, backing)
+		if !ok {
+			continue
+		}
+		args := []string{"rebase", "-u", "-f", "qcow2", "-b", filepath.Join(to, rel)}
+		if format != "" {
+			args = append(args, "-F", format)
+		}
+		if run(env, env.QemuImg, append(args, img)...) == nil {
+			rewritten = append(rewritten, "backing file of "+filepath.Base(img))
+		}
+	}
+	return rewritten
+}
+
+// verifyHomeReferences reports the references of the moved AVD name that do not resolve, or
+// still point into the old home.
+func verifyHomeReferences(env Env, from, to, name string) []string {
+	var problems []string
+	avdDir := filepath.Join(to, name+".avd")
+	check := func(what, target string) {
+		if _, inside := pathInside(from, target); inside {
+			problems = append(problems, fmt.Sprintf("%s still points into %s: %s", what, from, target))
+		} else if _, err := os.Stat(target); err != nil {
+			problems = append(problems, fmt.Sprintf("%s does not resolve: %s", what, target))
+		}
+	}
+	_ = filepath.WalkDir(avdDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.Type()&fs.ModeSymlink == 0 {
+			return nil
+		}
+		link, err := os.Readlink(path)
+		if err != nil {
+			return nil
+		}
+		if !filepath.IsAbs(link) {
+			link = filepath.Join(filepath.Dir(path), link)
+		}
+		relPath, _ := filepath.Rel(avdDir, path)
+		check("symlink "+relPath, link)
+		return nil
+	})
+	if cfg, err := ReadHardwareConfig(avdDir); err == nil {
+		for _, e := range cfg.Entries() {
+			if _, inside := pathInside(from, e.Value); inside {
+				check(hardwareConfigFilename+" "+e.Key, e.Value)
+			}
+		}
+	}
+	for _, img := range qcow2Images(avdDir) {
+		if backing, _ := qcow2Backing(env, img); backing != "" {
+			check("backing file of "+filepath.Base(img), backing)
+		}
+	}
+	return problems
+}
+
+// qcow2Images returns the regular .qcow2 files directly in avdDir.
+func qcow2Images(avdDir string) []string {
+	matches, _ := filepath.Glob(filepath.Join(avdDir, "*.qcow2"))
+	var out []string
+	for _, m := range matches {
+		if st, err := os.Lstat(m); err == nil && st.Mode().IsRegular() {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// qcow2Backing returns the absolute backing file of a qcow2 image and its format, as reported
+// by qemu-img info; both are empty without a backing file or without qemu-img.
+func qcow2Backing(env Env, img string) (string, string) {
+	if env.QemuImg == "" {
+		return "", ""
+	}
+	out, _, err := runCommandOutputWithEnv(env.Context, nil, nil, env.QemuImg, "info", "--output=json", "-f", "qcow2", img)
+	if err != nil {
+		return "", ""
+	}
+	var info struct {
+		Backing       string `json:"backing-filename"`
+		FullBacking   string `json:"full-backing-filename"`
+		BackingFormat string `json:"backing-filename-format"`
+	}
+	if json.Unmarshal([]byte(out), &info) != nil {
+		return "", ""
+	}
+	backing := info.FullBacking
+	if backing == "" {
+		backing = info.Backing
+	}
+	if backing != "" && !filepath.IsAbs(backing) {
+		backing = filepath.Join(filepath.Dir(img), backing)
+	}
+	return backing, info.BackingFormat
+}
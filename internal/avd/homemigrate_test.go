@@ -0,0 +1,120 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newHomeMigrationEnv creates base "demo" and clone "w-1" in a home under a temp dir. The
+// clone links demo's system image, names it in hardware-qemu.ini and has a qcow2 overlay
+// backed by demo's userdata; the qemu-img stub reports and rebases that backing file.
+func newHomeMigrationEnv(t *testing.T) (Env, string, string) {
+	t.Helper()
+	env := newTestEnv(t)
+	root := t.TempDir()
+	from, to := filepath.Join(root, "home", "avd"), filepath.Join(root, "data", "avd")
+	base, clone := filepath.Join(from, "demo.avd"), filepath.Join(from, "w-1.avd")
+	for _, dir := range []string{base, clone} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := map[string]string{
+		filepath.Join(base, "config.ini"):               "hw.device.name=pixel_6\n",
+		filepath.Join(base, "system.img"):               "system",
+		filepath.Join(base, "userdata.img"):             "data",
+		filepath.Join(from, "demo.ini"):                 "avd.ini.encoding=UTF-8\npath=" + base + "\npath.rel=avd/demo.avd\n",
+		filepath.Join(clone, "config.ini"):              "hw.device.name=pixel_6\n",
+		filepath.Join(clone, "userdata-qemu.img.qcow2"): "overlay",
+		filepath.Join(clone, hardwareConfigFilename):    "disk.systemPartition.initPath = " + filepath.Join(base, "system.img") + "\nhw.ramSize = 2048\n",
+		filepath.Join(from, "w-1.ini"):                  "avd.ini.encoding=UTF-8\npath=" + clone + "\npath.rel=avd/w-1.avd\n",
+		filepath.Join(root, "backing"):                  filepath.Join(base, "userdata.img"),
+	}
+	for path, body := range files {
+		if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(filepath.Join(base, "system.img"), filepath.Join(clone, "system.img")); err != nil {
+		t.Fatal(err)
+	}
+	state := filepath.Join(root, "backing")
+	stub := "#!/bin/sh\n" +
+		"case \"$1\" in\n" +
+		"info) printf '{\"backing-filename\": \"%s\", \"backing-filename-format\": \"raw\"}' \"$(cat " + state + ")\" ;;\n" +
+		"rebase) printf '%s' \"$6\" > " + state + " ;;\n" +
+		"esac\n"
+	env.QemuImg = filepath.Join(root, "qemu-img")
+	if err := os.WriteFile(env.QemuImg, []byte(stub), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return env, from, to
+}
+
+func TestMigrateHomeRewritesReferences(t *testing.T) {
+	env, from, to := newHomeMigrationEnv(t)
+	report, err := MigrateHome(env, HomeMigrationOptions{From: from, To: to})
+	if err != nil {
+		t.Fatalf("MigrateHome: %v", err)
+	}
+	if report.Failed() || len(report.AVDs) != 2 {
+		t.Fatalf("report = %+v", report)
+	}
+	if fileExists(filepath.Join(from, "w-1.avd")) || fileExists(filepath.Join(from, "demo.ini")) {
+		t.Fatal("old home still holds AVDs")
+	}
+	clone := filepath.Join(to, "w-1.avd")
+	if link, _ := os.Readlink(filepath.Join(clone, "system.img")); link != filepath.Join(to, "demo.avd", "system.img") {
+		t.Fatalf("symlink not retargeted: %s", link)
+	}
+	ini, _ := os.ReadFile(filepath.Join(to, "w-1.ini"))
+	if !strings.Contains(string(ini), "path="+clone+"\n") || strings.Contains(string(ini), "path.rel") {
+		t.Fatalf("ini not rewritten:\n%s", ini)
+	}
+	hw, _ := os.ReadFile(filepath.Join(clone, hardwareConfigFilename))
+	if !strings.Contains(string(hw), filepath.Join(to, "demo.avd", "system.img")) {
+		t.Fatalf("hardware-qemu.ini not rewritten:\n%s", hw)
+	}
+	var item HomeMigrationItem
+	for _, it := range report.AVDs {
+		if it.Name == "w-1" {
+			item = it
+		}
+	}
+	if !strings.Contains(strings.Join(item.Rewritten, ","), "backing file of userdata-qemu.img.qcow2") {
+		t.Fatalf("overlay not rebased: %+v", item)
+	}
+}
+
+func TestMigrateHomeReportsDanglingReferences(t *testing.T) {
+	env, from, to := newHomeMigrationEnv(t)
+	if err := os.Remove(filepath.Join(from, "demo.avd", "system.img")); err != nil {
+		t.Fatal(err)
+	}
+	report, err := MigrateHome(env, HomeMigrationOptions{From: from, To: to})
+	if err != nil {
+		t.Fatalf("MigrateHome: %v", err)
+	}
+	if !report.Failed() {
+		t.Fatalf("dangling symlink not reported: %+v", report)
+	}
+}
+
+func TestMigrateHomeDryRunAndChecks(t *testing.T) {
+	env, from, to := newHomeMigrationEnv(t)
+	report, err := MigrateHome(env, HomeMigrationOptions{From: from, To: to, DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.AVDs) != 2 || report.AVDs[0].Status != "planned" || fileExists(to) {
+		t.Fatalf("dry run moved something: %+v", report)
+	}
+	if _, err := MigrateHome(env, HomeMigrationOptions{From: from, To: filepath.Join(from, "sub")}); err == nil {
+		t.Fatal("expected an error for a destination inside the source")
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ResumeCheck is the state of a running instance re-validated after the host resumed from
+// suspend (see CheckAfterResume).
+type ResumeCheck struct {
+	Serial      string   `json:"serial"`
+	Name        string   `json:"name"`
+	Survived    bool     `json:"survived"`
+	ClockSkew   string   `json:"clock_skew,omitempty"` // guest clock behind (or ahead) of the host, when checked
+	ClockSynced bool     `json:"clock_synced"`         // the guest clock was set to the host's
+	Problems    []string `json:"problems,omitempty"`
+}
+
+// DefaultResumePollInterval is how often WatchHostResume compares the clocks.
+const DefaultResumePollInterval = 10 * time.Second
+
+// resumeSlack is how far the wall clock may run ahead of the monotonic clock between two polls
+// (NTP steps, a loaded host) before it counts as a suspend.
+const resumeSlack = 5 * time.Second
+
+// guestClockTolerance is the guest clock skew left alone after a resume.
+const guestClockTolerance = 2 * time.Second
+
+// hostSlept returns how long the host was suspended between two polls, given the wall clock and
+// monotonic time elapsed: the monotonic clock stops during suspend, the wall clock does not.
+func hostSlept(wall, mono time.Duration) time.Duration {
+	if slept := wall - mono; slept > resumeSlack {
+		return slept
+	}
+	return 0
+}
+
+// WatchHostResume compares the wall and monotonic clocks every interval (default
+// DefaultResumePollInterval) until env.Context is done. When the host was suspended, it runs
+// CheckAfterResume and passes the checks to onResume, if set.
+func WatchHostResume(env Env, interval time.Duration, onResume func([]ResumeCheck)) error {
+	ctx := env.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if interval <= 0 {
+		interval = DefaultResumePollInterval
+	}
+	prev := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+		now := time.Now()
+		slept := hostSlept(now.Round(0).Sub(prev.Round(0)), now.Sub(prev))
+		prev = now
+		if slept == 0 {
+			continue
+		}
+		logEvent(env, "host resumed", "suspended_for", slept.Round(time.Second))
+		checks, err := CheckAfterResume(env)
+		if err != nil {
+			return err
+		}
+		if onResume != nil {
+			onResume(checks)
+		}
+	}
+}
+
+// CheckAfterResume re-validates the running instances after a host suspend: ListRunning
+// reconnects adb transports that went offline, the console and adb ports must accept
+// connections again and guest clocks that fell behind are set to the host's. Instances that did
+// not survive are logged as "instance lost after host resume"; stop and restart them.
+func CheckAfterResume(env Env) ([]ResumeCheck, error) {
+	_, span := startSpan(env, "avd.CheckAfterResume")
+	defer span.End()
+	procs, err := ListRunning(env)
+	if err != nil {
+		recordSpanError(span, err)
+		return nil, err
+	}
+	checks := make([]ResumeCheck, 0, len(procs))
+	lost := 0
+	for _, proc := range procs {
+		check := checkResumed(env, proc)
+		if check.Survived {
+			logEvent(env, "instance survived host resume", "serial", check.Serial, "name", check.Name, "clock_synced", check.ClockSynced)
+		} else {
+			lost++
+			logEvent(env, "instance lost after host resume", "serial", check.Serial, "name", check.Name, "problems", strings.Join(check.Problems, "; "))
+		}
+		checks = append(checks, check)
+	}
+	span.SetAttributes(attribute.Int("instances", len(checks)), attribute.Int("lost", lost))
+	return checks, nil
+}
+
+func checkResumed(env Env, proc ProcInfo) ResumeCheck {
+	check := ResumeCheck{Serial: proc.Serial, Name: proc.Name}
+	if proc.Paused {
+		// Paused instances are stopped on purpose; Resume brings them back.
+		check.Survived = true
+		return check
+	}
+	if proc.PID > 0 && (syscall.Kill(proc.PID, 0) != nil || isZombieProcess(proc.PID)) {
+		check.Problems = append(check.Problems, fmt.Sprintf("emulator process %d is gone", proc.PID))
+	}
+	if proc.Port > 0 {
+		for _, port := range []int{proc.Port, proc.Port + 1} {
+			conn, err := net.DialTimeout("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)), 2*time.Second)
+			if err != nil {
+				check.Problems = append(check.Problems, fmt.Sprintf("port %d not accepting connections", port))
+				continue
+			}
+			_ = conn.Close()
+		}
+	}
+	switch proc.ADBState {
+	case ADBStateOnline:
+		if err := syncGuestClock(env, &check); err != nil {
+			logEvent(env, "guest clock not synced after host resume", "serial", proc.Serial, "error", err)
+		}
+	case "":
+		check.Problems = append(check.Problems, "not listed by adb")
+	default:
+		check.Problems = append(check.Problems, "adb "+proc.ADBState)
+	}
+	check.Survived = len(check.Problems) == 0
+	return check
+}
+
+// syncGuestClock sets the guest clock of check.Serial to the host's when it is off by more than
+// guestClockTolerance, as after a host suspend the guest did not notice.
+func syncGuestClock(env Env, check *ResumeCheck) error {
+	out, _, err := runCommandOutputWithEnv(env.Context, nil, nil, env.ADB, "-s", check.Serial, "shell", "date", "+%s")
+	if err != nil {
+		return fmt.Errorf("read guest clock: %w", err)
+	}
+	guest, err := strconv.ParseInt(strings.TrimSpace(out), 10, 64)
+	if err != nil {
+		return fmt.Errorf("read guest clock: unexpected output %q", strings.TrimSpace(out))
+	}
+	now := time.Now().UTC()
+	skew := now.Sub(time.Unix(guest, 0)).Truncate(time.Second)
+	check.ClockSkew = skew.String()
+	if skew.Abs() <= guestClockTolerance {
+		return nil
+	}
+	// toybox date sets MMDDhhmm[[CC]YY][.ss]; only root may set the clock.
+	set := now.Format("010215042006.05")
+	if out, err := runCommandCombinedOutputWithEnv(env.Context, nil, nil, env.ADB, "-s", check.Serial, "shell", "su", "0", "date", "-u", set); err != nil {
+		return fmt.Errorf("set guest clock: %w: %s", err, firstLine(strings.TrimSpace(string(out))))
+	}
+	check.ClockSynced = true
+	return nil
+}
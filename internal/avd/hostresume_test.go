@@ -0,0 +1,72 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHostSlept(t *testing.T) {
+	if slept := hostSlept(10*time.Second, 10*time.Second); slept != 0 {
+		t.Fatalf("awake host reported asleep for %s", slept)
+	}
+	if slept := hostSlept(12*time.Second, 10*time.Second); slept != 0 {
+		t.Fatalf("clock step reported as a %s suspend", slept)
+	}
+	if slept := hostSlept(time.Hour, 10*time.Second); slept != time.Hour-10*time.Second {
+		t.Fatalf("hostSlept = %s", slept)
+	}
+}
+
+// listenPortPair listens on an even console port and the adb port after it.
+func listenPortPair(t *testing.T) int {
+	t.Helper()
+	for port := 5700; port < 5800; port += 2 {
+		console, err := net.Listen("tcp", "127.0.0.1:"+strconv.Itoa(port))
+		if err != nil {
+			continue
+		}
+		adb, err := net.Listen("tcp", "127.0.0.1:"+strconv.Itoa(port+1))
+		if err != nil {
+			console.Close()
+			continue
+		}
+		t.Cleanup(func() { console.Close(); adb.Close() })
+		return port
+	}
+	t.Skip("no free port pair")
+	return 0
+}
+
+func TestCheckResumed(t *testing.T) {
+	env := newTestEnv(t)
+	logPath := filepath.Join(t.TempDir(), "adb.log")
+	// A guest clock an hour behind, as after a suspend.
+	script := "#!/bin/sh\necho \"$@\" >> " + logPath + "\ncase \"$*\" in\n  *\"date +%s\"*) echo " +
+		strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10) + " ;;\nesac\n"
+	if err := os.WriteFile(env.ADB, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	port := listenPortPair(t)
+	serial := "emulator-" + strconv.Itoa(port)
+	check := checkResumed(env, ProcInfo{Serial: serial, Name: "w-1", Port: port, PID: os.Getpid(), ADBState: ADBStateOnline})
+	if !check.Survived || !check.ClockSynced {
+		t.Fatalf("check = %+v, want a survivor with a synced clock", check)
+	}
+	calls, _ := os.ReadFile(logPath)
+	if !strings.Contains(string(calls), "-s "+serial+" shell su 0 date -u ") {
+		t.Fatalf("guest clock not set:\n%s", calls)
+	}
+
+	lost := checkResumed(env, ProcInfo{Serial: "emulator-5798", Name: "w-2", Port: 5798, ADBState: ADBStateOffline})
+	if lost.Survived || len(lost.Problems) != 3 {
+		t.Fatalf("check = %+v, want two closed ports and an offline adb", lost)
+	}
+}
@@ -0,0 +1,196 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// hardwareConfigFilename is the resolved hardware configuration the emulator writes into the
+// AVD directory on every boot, with absolute paths to the images it uses.
+const hardwareConfigFilename = "hardware-qemu.ini"
+
+// HardwareConfig is a parsed hardware-qemu.ini. Lines that are not "key = value" (comments,
+// blanks) are kept, so Bytes reproduces the file with only the changed values rewritten.
+type HardwareConfig struct {
+	lines []hwLine
+}
+
+type hwLine struct {
+	raw        string
+	key, value string
+	entry      bool
+}
+
+// HardwareEntry is one key of a hardware-qemu.ini.
+type HardwareEntry struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// ParseHardwareConfig parses the contents of a hardware-qemu.ini.
+func ParseHardwareConfig(b []byte) HardwareConfig {
+	var cfg HardwareConfig
+	text := strings.TrimSuffix(strings.ReplaceAll(string(b), "\r\n", "\n"), "\n")
+	if text == "" {
+		return cfg
+	}
+	for _, raw := range strings.Split(text, "\n") {
+		line := hwLine{raw: raw}
+		trimmed := strings.TrimSpace(raw)
+		if k, v, ok := strings.Cut(raw, "="); ok && !strings.HasPrefix(trimmed, "#") && !strings.HasPrefix(trimmed, ";") {
+			line.key, line.value, line.entry = strings.TrimSpace(k), strings.TrimSpace(v), true
+		}
+		cfg.lines = append(cfg.lines, line)
+	}
+	return cfg
+}
+
+// ReadHardwareConfig reads the hardware-qemu.ini of avdDir.
+func ReadHardwareConfig(avdDir string) (HardwareConfig, error) {
+	b, err := os.ReadFile(filepath.Join(avdDir, hardwareConfigFilename))
+	if err != nil {
+		return HardwareConfig{}, err
+	}
+	return ParseHardwareConfig(b), nil
+}
+
+// Get returns the value of key.
+func (c HardwareConfig) Get(key string) (string, bool) {
+	for _, l := range c.lines {
+		if l.entry && l.key == key {
+			return l.value, true
+		}
+	}
+	return "", false
+}
+
+// Set sets key to value, appending the key when it is not present.
+func (c *HardwareConfig) Set(key, value string) {
+	for i, l := range c.lines {
+		if l.entry && l.key == key {
+			c.lines[i] = hwLine{raw: key + " = " + value, key: key, value: value, entry: true}
+			return
+		}
+	}
+	c.lines = append(c.lines, hwLine{raw: key + " = " + value, key: key, value: value, entry: true})
+}
+
+// Entries returns the keys of c in file order.
+func (c HardwareConfig) Entries() []HardwareEntry {
+	var entries []HardwareEntry
+	for _, l := range c.lines {
+		if l.entry {
+			entries = append(entries, HardwareEntry{Key: l.key, Value: l.value})
+		}
+	}
+	return entries
+}
+
+// Bytes renders c in hardware-qemu.ini format.
+func (c HardwareConfig) Bytes() []byte {
+	var b strings.Builder
+	for _, l := range c.lines {
+		b.WriteString(l.raw)
+		b.WriteByte('\n')
+	}
+	return []byte(b.String())
+}
+
+// RewritePaths points every value that is fromDir or a path inside it at the same path inside
+// toDir, and returns the keys it changed.
+func (c *HardwareConfig) RewritePaths(fromDir, toDir string) []string {
+	fromDir, toDir = filepath.Clean(fromDir), filepath.Clean(toDir)
+	var changed []string
+	for _, e := range c.Entries() {
+		rel, ok := pathInside(fromDir, e.Value)
+		if !ok {
+			continue
+		}
+		c.Set(e.Key, filepath.Join(toDir, rel))
+		changed = append(changed, e.Key)
+	}
+	return changed
+}
+
+// pathInside returns path relative to dir when path is an absolute path at or below dir.
+func pathInside(dir, path string) (string, bool) {
+	if !filepath.IsAbs(path) {
+		return "", false
+	}
+	rel, err := filepath.Rel(dir, filepath.Clean(path))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return rel, true
+}
+
+// HardwareInspection is the hardware-qemu.ini of an AVD.
+type HardwareInspection struct {
+	Name    string          `json:"name"`
+	Path    string          `json:"path"`
+	Entries []HardwareEntry `json:"entries"`
+	// OtherAVDPaths are the entries pointing into another AVD's directory, e.g. a clone
+	// still using its base's images.
+	OtherAVDPaths []HardwareEntry `json:"other_avd_paths,omitempty"`
+}
+
+// InspectHardwareConfig parses the hardware-qemu.ini of AVD name and reports the paths that
+// point into other AVDs. The emulator only writes the file once the AVD has booted.
+func InspectHardwareConfig(env Env, name string) (HardwareInspection, error) {
+	_, span := startSpan(env, "avd.InspectHardwareConfig", attribute.String("avd.name", name))
+	defer span.End()
+	fail := func(err error) (HardwareInspection, error) {
+		recordSpanError(span, err)
+		return HardwareInspection{}, err
+	}
+	avdDir := filepath.Join(env.AVDHome, name+".avd")
+	if !fileExists(avdDir) {
+		return fail(fmt.Errorf("AVD %s not found", name))
+	}
+	cfg, err := ReadHardwareConfig(avdDir)
+	if os.IsNotExist(err) {
+		return fail(fmt.Errorf("%s has no %s yet (boot it once)", name, hardwareConfigFilename))
+	}
+	if err != nil {
+		return fail(fmt.Errorf("read %s: %w", hardwareConfigFilename, err))
+	}
+	out := HardwareInspection{Name: name, Path: filepath.Join(avdDir, hardwareConfigFilename), Entries: cfg.Entries()}
+	home := filepath.Clean(env.AVDHome)
+	for _, e := range out.Entries {
+		rel, ok := pathInside(home, e.Value)
+		if !ok || rel == "." {
+			continue
+		}
+		if top := strings.Split(rel, string(filepath.Separator))[0]; top != name+".avd" && strings.HasSuffix(top, ".avd") {
+			out.OtherAVDPaths = append(out.OtherAVDPaths, e)
+		}
+	}
+	return out, nil
+}
+
+// writeCloneHardwareConfig writes the base's hardware-qemu.ini into cloneDir as a regular
+// file with the paths into the base pointed at the clone. A linked copy would keep the clone
+// booting from (and the emulator rewriting) the base's files.
+func writeCloneHardwareConfig(env Env, baseDir, cloneDir string) error {
+	cfg, err := ReadHardwareConfig(baseDir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read base %s: %w", hardwareConfigFilename, err)
+	}
+	if changed := cfg.RewritePaths(baseDir, cloneDir); len(changed) > 0 {
+		logEvent(env, "clone hardware config rewritten", "clone", cloneDir, "keys", strings.Join(changed, ","))
+	}
+	if err := os.WriteFile(filepath.Join(cloneDir, hardwareConfigFilename), cfg.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("write %s: %w", hardwareConfigFilename, err)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHardwareConfigRewritePaths(t *testing.T) {
+	in := "# generated\nhw.ramSize = 2048\ndisk.dataPartition.path = /avd/base.avd/userdata-qemu.img\n" +
+		"disk.systemPartition.initPath = /sdk/system-images/android-35/system.img\nkernel.path=/avd/base.avd2/kernel\n"
+	cfg := ParseHardwareConfig([]byte(in))
+	if v, ok := cfg.Get("hw.ramSize"); !ok || v != "2048" {
+		t.Fatalf("Get(hw.ramSize) = %q, %v", v, ok)
+	}
+	changed := cfg.RewritePaths("/avd/base.avd", "/avd/w-1.avd")
+	if len(changed) != 1 || changed[0] != "disk.dataPartition.path" {
+		t.Fatalf("changed = %v", changed)
+	}
+	want := "# generated\nhw.ramSize = 2048\ndisk.dataPartition.path = /avd/w-1.avd/userdata-qemu.img\n" +
+		"disk.systemPartition.initPath = /sdk/system-images/android-35/system.img\nkernel.path=/avd/base.avd2/kernel\n"
+	if got := string(cfg.Bytes()); got != want {
+		t.Fatalf("Bytes() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestCloneRewritesHardwareConfig(t *testing.T) {
+	env := newTestEnv(t)
+	makeBaseAVD(t, env, "base-a35")
+	baseDir := filepath.Join(env.AVDHome, "base-a35.avd")
+	hw := "disk.dataPartition.path = " + filepath.Join(baseDir, "userdata-qemu.img") + "\n"
+	if err := os.WriteFile(filepath.Join(baseDir, hardwareConfigFilename), []byte(hw), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CloneFromGolden(env, "base-a35", "w-1", makeGoldenDir(t)); err != nil {
+		t.Fatalf("CloneFromGolden: %v", err)
+	}
+	path := filepath.Join(env.AVDHome, "w-1.avd", hardwareConfigFilename)
+	if st, err := os.Lstat(path); err != nil || !st.Mode().IsRegular() {
+		t.Fatalf("clone %s must be a regular file: %v", hardwareConfigFilename, err)
+	}
+	inspection, err := InspectHardwareConfig(env, "w-1")
+	if err != nil {
+		t.Fatalf("InspectHardwareConfig: %v", err)
+	}
+	if len(inspection.OtherAVDPaths) != 0 {
+		t.Fatalf("clone still points into the base: %v", inspection.OtherAVDPaths)
+	}
+	if v := inspection.Entries[0].Value; v != filepath.Join(env.AVDHome, "w-1.avd", "userdata-qemu.img") {
+		t.Fatalf("data partition = %s", v)
+	}
+
+	// A linked copy from before the rewrite is reported.
+	if err := os.WriteFile(path, []byte(hw), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if inspection, err = InspectHardwareConfig(env, "w-1"); err != nil || len(inspection.OtherAVDPaths) != 1 {
+		t.Fatalf("InspectHardwareConfig = %+v, %v", inspection, err)
+	}
+}
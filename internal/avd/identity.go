@@ -0,0 +1,137 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// cloneIdentityFilename stores a clone's network identity next to its config.ini.
+const cloneIdentityFilename = "avdctl-identity.json"
+
+// CloneIdentity is the per-clone identity assigned at creation, so clones of one golden do not
+// present the same Wi-Fi MAC and Android ID to backends.
+type CloneIdentity struct {
+	WifiMAC   string `json:"wifi_mac"`   // passed to the emulator as -wifi-mac-address
+	AndroidID string `json:"android_id"` // written to settings secure android_id after first boot
+	// AndroidIDApplied is set once the Android ID has been written to the guest.
+	AndroidIDApplied bool `json:"android_id_applied"`
+}
+
+// ReadCloneIdentity returns the identity of clone name.
+func ReadCloneIdentity(env Env, name string) (CloneIdentity, error) {
+	b, err := os.ReadFile(filepath.Join(env.AVDHome, name+".avd", cloneIdentityFilename))
+	if err != nil {
+		return CloneIdentity{}, fmt.Errorf("read clone identity: %w", err)
+	}
+	var identity CloneIdentity
+	if err := json.Unmarshal(b, &identity); err != nil {
+		return CloneIdentity{}, fmt.Errorf("parse clone identity: %w", err)
+	}
+	return identity, nil
+}
+
+func writeCloneIdentity(cloneDir string, identity CloneIdentity) error {
+	b, err := json.MarshalIndent(identity, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(cloneDir, cloneIdentityFilename)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(b, '\n'), 0o644); err != nil {
+		return fmt.Errorf("write clone identity: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// newCloneIdentity generates a random identity whose MAC is not used by another clone in AVDHome.
+func newCloneIdentity(env Env) (CloneIdentity, error) {
+	used := map[string]bool{}
+	if paths, err := filepath.Glob(filepath.Join(env.AVDHome, "*.avd", cloneIdentityFilename)); err == nil {
+		for _, path := range paths {
+			name := strings.TrimSuffix(filepath.Base(filepath.Dir(path)), ".avd")
+			if identity, err := ReadCloneIdentity(env, name); err == nil {
+				used[identity.WifiMAC] = true
+			}
+		}
+	}
+	for {
+		mac, err := randomMAC()
+		if err != nil {
+			return CloneIdentity{}, err
+		}
+		if used[mac] {
+			continue
+		}
+		id := make([]byte, 8)
+		if _, err := rand.Read(id); err != nil {
+			return CloneIdentity{}, err
+		}
+		return CloneIdentity{WifiMAC: mac, AndroidID: hex.EncodeToString(id)}, nil
+	}
+}
+
+// randomMAC returns a random locally administered unicast MAC address.
+func randomMAC() (string, error) {
+	mac := make(net.HardwareAddr, 6)
+	if _, err := rand.Read(mac); err != nil {
+		return "", err
+	}
+	mac[0] = mac[0]&^0x01 | 0x02
+	return mac.String(), nil
+}
+
+// ApplyCloneIdentity writes the Android ID of clone name to the booted emulator at serial.
+// Apps targeting Android 8+ read a per-app SSAID instead, which the guest derives on first use.
+func ApplyCloneIdentity(env Env, serial, name string) error {
+	_, span := startSpan(env, "avd.ApplyCloneIdentity",
+		attribute.String("serial", serial),
+		attribute.String("name", name),
+	)
+	defer span.End()
+	identity, err := ReadCloneIdentity(env, name)
+	if err != nil {
+		recordSpanError(span, err)
+		return err
+	}
+	out, err := runCommandCombinedOutputWithEnv(env.Context, nil, nil, env.ADB,
+		"-s", serial, "shell", "settings", "put", "secure", "android_id", identity.AndroidID)
+	if err != nil {
+		err = fmt.Errorf("set android_id on %s: %w: %s", serial, err, strings.TrimSpace(string(out)))
+		recordSpanError(span, err)
+		return err
+	}
+	identity.AndroidIDApplied = true
+	if err := writeCloneIdentity(filepath.Join(env.AVDHome, name+".avd"), identity); err != nil {
+		recordSpanError(span, err)
+		return err
+	}
+	logEvent(env, "clone identity applied", "serial", serial, "name", name)
+	return nil
+}
+
+// applyCloneIdentityOnBoot applies a clone's identity the first time it finishes booting.
+// AVDs without an identity (bases, goldens) are left alone; failures are only logged.
+func applyCloneIdentityOnBoot(env Env, serial string) {
+	name, _ := GetAVDNameFromSerial(env, serial)
+	if name == "" {
+		return
+	}
+	identity, err := ReadCloneIdentity(env, name)
+	if err != nil || identity.AndroidIDApplied {
+		return
+	}
+	if err := ApplyCloneIdentity(env, serial, name); err != nil {
+		logEvent(env, "clone identity apply failed", "serial", serial, "name", name, "error", err)
+	}
+}
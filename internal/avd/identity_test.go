@@ -0,0 +1,98 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCloneFromGoldenAssignsDistinctIdentities(t *testing.T) {
+	env := newTestEnv(t)
+	makeBaseAVD(t, env, "base-a35")
+	golden := makeGoldenDir(t)
+	for _, name := range []string{"w-one", "w-two"} {
+		if _, err := CloneFromGolden(env, "base-a35", name, golden); err != nil {
+			t.Fatalf("CloneFromGolden %s: %v", name, err)
+		}
+	}
+	one, err := ReadCloneIdentity(env, "w-one")
+	if err != nil {
+		t.Fatalf("ReadCloneIdentity: %v", err)
+	}
+	two, err := ReadCloneIdentity(env, "w-two")
+	if err != nil {
+		t.Fatalf("ReadCloneIdentity: %v", err)
+	}
+	if one.WifiMAC == two.WifiMAC || one.AndroidID == two.AndroidID {
+		t.Fatalf("clones share an identity: %#v %#v", one, two)
+	}
+	mac, err := net.ParseMAC(one.WifiMAC)
+	if err != nil || mac[0]&0x01 != 0 || mac[0]&0x02 == 0 {
+		t.Fatalf("expected locally administered unicast MAC, got %q", one.WifiMAC)
+	}
+	if len(one.AndroidID) != 16 {
+		t.Fatalf("android id = %q", one.AndroidID)
+	}
+
+	if _, err := CloneFromGolden(env, "base-a35", "w-one", golden); err != nil {
+		t.Fatalf("re-clone: %v", err)
+	}
+	again, _ := ReadCloneIdentity(env, "w-one")
+	if again != one {
+		t.Fatalf("idempotent clone changed identity: %#v -> %#v", one, again)
+	}
+}
+
+func TestEmulatorStartArgsGatesWifiMAC(t *testing.T) {
+	env := newTestEnv(t)
+	base := []string{"-avd", "demo", "-wifi-mac-address", "02:00:00:00:00:01", "-no-snapshot"}
+
+	writeEmulatorVersionStub(t, &env, "30.9.5")
+	args, err := emulatorStartArgs(env, base, nil)
+	if err != nil {
+		t.Fatalf("emulatorStartArgs: %v", err)
+	}
+	if slices.Contains(args, "-wifi-mac-address") || slices.Contains(args, "02:00:00:00:00:01") || !slices.Contains(args, "-no-snapshot") {
+		t.Fatalf("expected MAC flag dropped on emulator 30: %v", args)
+	}
+
+	writeEmulatorVersionStub(t, &env, "34.2.13")
+	args, err = emulatorStartArgs(env, base, nil)
+	if err != nil {
+		t.Fatalf("emulatorStartArgs: %v", err)
+	}
+	if !slices.Contains(args, "02:00:00:00:00:01") {
+		t.Fatalf("expected MAC flag kept on emulator 34: %v", args)
+	}
+}
+
+func TestApplyCloneIdentityOnBootRunsOnce(t *testing.T) {
+	env := newTestEnv(t)
+	makeBaseAVD(t, env, "base-a35")
+	if _, err := CloneFromGolden(env, "base-a35", "w-id", makeGoldenDir(t)); err != nil {
+		t.Fatalf("CloneFromGolden: %v", err)
+	}
+	logPath := filepath.Join(t.TempDir(), "adb.log")
+	script := "#!/bin/sh\necho \"$@\" >> " + logPath + "\n[ \"$3\" = emu ] && printf 'w-id\\nOK\\n'\nexit 0\n"
+	if err := os.WriteFile(env.ADB, []byte(script), 0o755); err != nil {
+		t.Fatalf("write adb stub: %v", err)
+	}
+
+	applyCloneIdentityOnBoot(env, "emulator-5594")
+	applyCloneIdentityOnBoot(env, "emulator-5594")
+
+	identity, _ := ReadCloneIdentity(env, "w-id")
+	if !identity.AndroidIDApplied {
+		t.Fatal("expected identity marked applied")
+	}
+	calls, _ := os.ReadFile(logPath)
+	if n := strings.Count(string(calls), "settings put secure android_id "+identity.AndroidID); n != 1 {
+		t.Fatalf("expected one android_id write, got %d:\n%s", n, calls)
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"context"
+	"io"
+	"os"
+	"time"
+)
+
+// CopyProgressFunc reports the progress of an image copy: copied of total bytes. Clones copy
+// several images at once, so it may be called concurrently for different images.
+type CopyProgressFunc func(image string, copied, total int64)
+
+// copyChunk is how much is copied between progress reports; throttled copies use
+// throttledCopyChunk so the rate stays smooth.
+const (
+	copyChunk          = 64 << 20
+	throttledCopyChunk = 4 << 20
+)
+
+// imageCopy configures copyFileContents.
+type imageCopy struct {
+	ctx      context.Context
+	rate     int64 // bytes per second; 0 = unlimited
+	progress func(copied, total int64)
+}
+
+// copyImage copies the golden image src to dst with env.IOBandwidth as limit (see
+// copyFileContents).
+func copyImage(env Env, src, dst string, progress func(copied, total int64)) error {
+	return CopyMaterializer{Bandwidth: env.IOBandwidth}.Materialize(spanContext(env), CloneImage{Source: src, Dest: dst, Progress: progress})
+}
+
+// copyFileContents copies in to the empty file out without reading it into memory. Unthrottled
+// copies first try a reflink (FICLONE: no data copied on btrfs, XFS and other CoW filesystems).
+// Otherwise only the data segments of in are copied (holes stay holes, so sparse images stay
+// sparse) with copy_file_range where the kernel supports it.
+func copyFileContents(out, in *os.File, opts imageCopy) error {
+	st, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	size := st.Size()
+	report := func(copied int64) {
+		if opts.progress != nil {
+			opts.progress(copied, size)
+		}
+	}
+	if opts.rate <= 0 && reflinkFile(out, in) == nil {
+		report(size)
+		return nil
+	}
+	chunk := int64(copyChunk)
+	if opts.rate > 0 {
+		chunk = throttledCopyChunk
+	}
+	segments, err := dataSegments(in, size)
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	var copied int64
+	for _, seg := range segments {
+		if _, err := in.Seek(seg[0], io.SeekStart); err != nil {
+			return err
+		}
+		if _, err := out.Seek(seg[0], io.SeekStart); err != nil {
+			return err
+		}
+		for off := seg[0]; off < seg[1]; {
+			n, err := io.CopyN(out, in, min(chunk, seg[1]-off))
+			off += n
+			copied += n
+			if err != nil {
+				return err
+			}
+			report(copied)
+			if opts.rate > 0 {
+				due := start.Add(time.Duration(float64(copied) / float64(opts.rate) * float64(time.Second)))
+				if wait := time.Until(due); wait > 0 && opts.ctx != nil {
+					if err := sleepContext(opts.ctx, wait); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+	// Trailing holes are not copied; give out its full size.
+	if err := out.Truncate(size); err != nil {
+		return err
+	}
+	if copied < size {
+		report(size)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+//go:build linux
+
+package avd
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+const (
+	ficlone  = 0x40049409 // _IOW(0x94, 9, int)
+	seekData = 3
+	seekHole = 4
+)
+
+// reflinkFile makes dst share src's blocks (FICLONE). It fails on filesystems without reflinks.
+func reflinkFile(dst, src *os.File) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, dst.Fd(), ficlone, src.Fd()); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// dataSegments returns the [start, end) ranges of f that hold data, found with
+// SEEK_DATA/SEEK_HOLE. Filesystems without hole reporting yield the whole file.
+func dataSegments(f *os.File, size int64) ([][2]int64, error) {
+	var segments [][2]int64
+	for off := int64(0); off < size; {
+		data, err := f.Seek(off, seekData)
+		if errors.Is(err, syscall.ENXIO) {
+			break // only a hole is left
+		}
+		if err != nil {
+			return [][2]int64{{0, size}}, nil
+		}
+		hole, err := f.Seek(data, seekHole)
+		if err != nil {
+			return [][2]int64{{0, size}}, nil
+		}
+		segments = append(segments, [2]int64{data, min(hole, size)})
+		off = hole
+	}
+	return segments, nil
+}
@@ -0,0 +1,20 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+//go:build !linux
+
+package avd
+
+import (
+	"errors"
+	"os"
+)
+
+func reflinkFile(dst, src *os.File) error {
+	return errors.ErrUnsupported
+}
+
+// dataSegments returns the whole file: holes are only detected on Linux.
+func dataSegments(f *os.File, size int64) ([][2]int64, error) {
+	return [][2]int64{{0, size}}, nil
+}
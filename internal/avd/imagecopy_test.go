@@ -0,0 +1,75 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// writeSparseImage creates a 64 MiB file holding only "data" at 32 MiB.
+func writeSparseImage(t *testing.T, path string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := f.Truncate(64 << 20); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteAt([]byte("data"), 32<<20); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCopyImagePreservesHoles(t *testing.T) {
+	dir := t.TempDir()
+	src, dst := filepath.Join(dir, "userdata-qemu.img"), filepath.Join(dir, "copy.img")
+	writeSparseImage(t, src)
+	var last, total int64
+	if err := copyImage(Env{}, src, dst, func(copied, size int64) { last, total = copied, size }); err != nil {
+		t.Fatalf("copyImage: %v", err)
+	}
+	want, _ := os.ReadFile(src)
+	got, err := os.ReadFile(dst)
+	if err != nil || !bytes.Equal(got, want) {
+		t.Fatalf("copy differs from source (%v)", err)
+	}
+	if last != 64<<20 || total != 64<<20 {
+		t.Fatalf("final progress %d/%d, want %d", last, total, 64<<20)
+	}
+	if runtime.GOOS == "linux" {
+		st, err := os.Stat(dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if allocated := st.Sys().(*syscall.Stat_t).Blocks * 512; allocated > 8<<20 {
+			t.Fatalf("copy allocates %d bytes; holes were filled", allocated)
+		}
+	}
+}
+
+func TestCopyImageThrottled(t *testing.T) {
+	dir := t.TempDir()
+	src, dst := filepath.Join(dir, "cache.img"), filepath.Join(dir, "copy.img")
+	if err := os.WriteFile(src, bytes.Repeat([]byte("x"), 8<<20), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if err := copyImage(Env{IOBandwidth: 32 << 20}, src, dst, nil); err != nil {
+		t.Fatalf("copyImage: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Fatalf("8M at 32M/s took %s, want about 250ms", elapsed)
+	}
+	if st, err := os.Stat(dst); err != nil || st.Size() != 8<<20 {
+		t.Fatalf("copy size = %v, %v", st, err)
+	}
+}
@@ -0,0 +1,247 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// imageHashesFilename records, per image of a clone, the golden content it was copied from and
+// the size and mtime it had right after the copy. An image still at that size and mtime holds
+// that content, so ResetClone can skip it when the golden image has the same hash.
+const imageHashesFilename = "avdctl-image-hashes.json"
+
+// imageHash is the cache entry of one clone image.
+type imageHash struct {
+	SHA256  string `json:"sha256"`
+	Size    int64  `json:"size"`
+	ModTime int64  `json:"mtime_ns"`
+}
+
+// ResetResult reports which images ResetClone copied and which already matched the golden.
+type ResetResult struct {
+	Info
+	Copied  []string `json:"copied"`
+	Skipped []string `json:"skipped"`
+}
+
+// ResetClone puts a stopped clone back to the state of golden (a directory, registry name or
+// "@<channel>"): its images are re-copied from the golden and snapshots are dropped, keeping the
+// clone's config and identity. An image still untouched since it was copied from a golden image
+// with the same content hash is not copied again, so resetting an unused clone is near instant.
+func ResetClone(env Env, name, golden string) (ResetResult, error) {
+	_, span := startSpan(env, "avd.ResetClone", attribute.String("clone", name))
+	defer span.End()
+	fail := func(err error) (ResetResult, error) {
+		recordSpanError(span, err)
+		return ResetResult{}, err
+	}
+	golden, err := ResolveGolden(env, golden)
+	if err != nil {
+		return fail(err)
+	}
+	goldenDir, err := filepath.Abs(golden)
+	if err != nil {
+		return fail(err)
+	}
+	cloneDir := filepath.Join(env.AVDHome, name+".avd")
+	if !isCloneDir(cloneDir) {
+		return fail(fmt.Errorf("%s is not a clone", name))
+	}
+	procs, err := ListRunning(env)
+	if err != nil {
+		return fail(err)
+	}
+	for _, p := range procs {
+		if p.Name == name {
+			return fail(fmt.Errorf("cannot reset running AVD %s; stop it first", name))
+		}
+	}
+	fingerprint, err := goldenFingerprint(goldenDir)
+	if err != nil {
+		return fail(fmt.Errorf("fingerprint golden: %w", err))
+	}
+	goldenHashes, err := goldenImageHashes(goldenDir)
+	if err != nil {
+		return fail(err)
+	}
+	cache := readImageHashes(cloneDir)
+	layout := goldenLayout(goldenDir)
+	images := layout.Names()
+
+	result := ResetResult{}
+	copied := make([]bool, len(images))
+	err = runIOPool(env, len(images), func(i int) error {
+		img := images[i]
+		sum, ok := goldenHashes[img]
+		if !ok {
+			return nil // not in the golden; the clone keeps its own (e.g. a generated sdcard)
+		}
+		dst := filepath.Join(cloneDir, img)
+		if cache[img].SHA256 == sum && imageUnchanged(dst, cache[img]) {
+			return nil
+		}
+		if err := materializeImage(env, goldenDir, cloneDir, img, nil); err != nil {
+			return fmt.Errorf("copy %s: %w", img, err)
+		}
+		copied[i] = true
+		return nil
+	})
+	if err != nil {
+		return fail(err)
+	}
+	for i, img := range images {
+		if _, ok := goldenHashes[img]; !ok {
+			continue
+		}
+		if copied[i] {
+			result.Copied = append(result.Copied, img)
+		} else {
+			result.Skipped = append(result.Skipped, img)
+		}
+	}
+	_ = os.RemoveAll(filepath.Join(cloneDir, "snapshots"))
+	qcow2Files, _ := filepath.Glob(filepath.Join(cloneDir, "*.qcow2"))
+	for _, f := range qcow2Files {
+		_ = os.Remove(f)
+	}
+	if err := recordImageHashes(cloneDir, goldenHashes); err != nil {
+		return fail(err)
+	}
+	if err := writeCloneLayout(cloneDir, layout); err != nil {
+		return fail(err)
+	}
+	if err := writeCloneFingerprint(cloneDir, fingerprint); err != nil {
+		return fail(err)
+	}
+	if result.Info, err = infoOf(env, name); err != nil {
+		return fail(err)
+	}
+	recordUsage(env, usageDisk, name, "")
+	logEvent(env, "clone reset", "clone", name, "golden", goldenDir, "copied", len(result.Copied), "skipped", len(result.Skipped))
+	return result, nil
+}
+
+// goldenImageHashes returns the SHA-256 of each image of goldenDir. Hashes are kept in the
+// manifest (see GoldenImage.SHA256); missing or stale ones are computed and stored back when
+// the golden directory is writable.
+func goldenImageHashes(goldenDir string) (map[string]string, error) {
+	manifest, manifestErr := ReadGoldenManifest(goldenDir)
+	known := map[string]GoldenImage{}
+	for _, img := range manifest.Images {
+		known[img.Name] = img
+	}
+	hashes := map[string]string{}
+	updated := false
+	names := goldenLayout(goldenDir).Names()
+	for _, img := range names {
+		st, err := os.Stat(filepath.Join(goldenDir, img))
+		if err != nil {
+			continue
+		}
+		if entry, ok := known[img]; ok && entry.SHA256 != "" && goldenImageUnchanged(st, entry) {
+			hashes[img] = entry.SHA256
+			continue
+		}
+		sum, err := fileSHA256(filepath.Join(goldenDir, img))
+		if err != nil {
+			return nil, fmt.Errorf("hash golden %s: %w", img, err)
+		}
+		hashes[img] = sum
+		known[img] = GoldenImage{Name: img, SizeBytes: st.Size(), ModTime: st.ModTime().UnixNano(), SHA256: sum}
+		updated = true
+	}
+	if updated && manifestErr == nil {
+		images := make([]GoldenImage, 0, len(known))
+		for _, img := range names {
+			if entry, ok := known[img]; ok {
+				images = append(images, entry)
+			}
+		}
+		manifest.Images = images
+		_ = writeGoldenManifest(goldenDir, manifest) // best effort: the golden may be read-only
+	}
+	return hashes, nil
+}
+
+// manifestImageHashes returns the hashes recorded in goldenDir's manifest, without computing any.
+func manifestImageHashes(goldenDir string) map[string]string {
+	hashes := map[string]string{}
+	manifest, err := ReadGoldenManifest(goldenDir)
+	if err != nil {
+		return hashes
+	}
+	for _, img := range manifest.Images {
+		if img.SHA256 == "" {
+			continue
+		}
+		if st, err := os.Stat(filepath.Join(goldenDir, img.Name)); err == nil && goldenImageUnchanged(st, img) {
+			hashes[img.Name] = img.SHA256
+		}
+	}
+	return hashes
+}
+
+// goldenImageUnchanged reports whether the golden image st still has the size and mtime its
+// manifest entry was hashed at.
+func goldenImageUnchanged(st os.FileInfo, entry GoldenImage) bool {
+	return st.Size() == entry.SizeBytes && st.ModTime().UnixNano() == entry.ModTime
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func imageUnchanged(path string, entry imageHash) bool {
+	st, err := os.Stat(path)
+	return err == nil && st.Size() == entry.Size && st.ModTime().UnixNano() == entry.ModTime
+}
+
+func readImageHashes(cloneDir string) map[string]imageHash {
+	cache := map[string]imageHash{}
+	b, err := os.ReadFile(filepath.Join(cloneDir, imageHashesFilename))
+	if err == nil {
+		_ = json.Unmarshal(b, &cache) // a broken cache only costs copies
+	}
+	return cache
+}
+
+// recordImageHashes stores, for each image of cloneDir copied from a golden image with a known
+// hash, that hash with the image's current size and mtime. Other entries are dropped.
+func recordImageHashes(cloneDir string, hashes map[string]string) error {
+	cache := map[string]imageHash{}
+	for img, sum := range hashes {
+		st, err := os.Stat(filepath.Join(cloneDir, img))
+		if err != nil {
+			continue
+		}
+		cache[img] = imageHash{SHA256: sum, Size: st.Size(), ModTime: st.ModTime().UnixNano()}
+	}
+	b, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(cloneDir, imageHashesFilename)
+	if err := os.WriteFile(path+".tmp", append(b, '\n'), 0o644); err != nil {
+		return fmt.Errorf("write image hashes: %w", err)
+	}
+	return os.Rename(path+".tmp", path)
+}
@@ -0,0 +1,95 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResetCloneSkipsUnchangedImages(t *testing.T) {
+	env := newTestEnv(t)
+	env.GoldenDir = t.TempDir()
+	makeBaseAVD(t, env, "base-a35")
+	v4 := newRegistryGolden(t, env, "v4")
+	if _, err := CloneFromGolden(env, "base-a35", "w-1", v4); err != nil {
+		t.Fatalf("clone: %v", err)
+	}
+
+	// The first reset hashes v4 (its manifest has no hashes yet) and copies everything.
+	result, err := ResetClone(env, "w-1", v4)
+	if err != nil || len(result.Copied) != 4 {
+		t.Fatalf("first ResetClone = %#v, %v", result, err)
+	}
+	if hashes := manifestImageHashes(v4); len(hashes) != 4 {
+		t.Fatalf("golden hashes not kept in the manifest: %v", hashes)
+	}
+	result, err = ResetClone(env, "w-1", v4)
+	if err != nil || len(result.Copied) != 0 || len(result.Skipped) != 4 {
+		t.Fatalf("ResetClone of an unused clone = %#v, %v", result, err)
+	}
+
+	userdata := filepath.Join(env.AVDHome, "w-1.avd", "userdata-qemu.img")
+	time.Sleep(10 * time.Millisecond)
+	if err := os.WriteFile(userdata, []byte("used"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	result, err = ResetClone(env, "w-1", v4)
+	if err != nil || strings.Join(result.Copied, ",") != "userdata-qemu.img" {
+		t.Fatalf("ResetClone after use = %#v, %v", result, err)
+	}
+	if b, _ := os.ReadFile(userdata); string(b) != "v4-userdata-qemu.img" {
+		t.Fatalf("userdata not restored: %q", b)
+	}
+
+	// A golden that differs only in userdata shares the other images.
+	v5 := newRegistryGolden(t, env, "v5")
+	for _, img := range []string{"encryptionkey.img", "cache.img", "sdcard.img"} {
+		if err := os.WriteFile(filepath.Join(v5, img), []byte("v4-"+img), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	result, err = ResetClone(env, "w-1", "v5")
+	if err != nil || strings.Join(result.Copied, ",") != "userdata-qemu.img" || len(result.Skipped) != 3 {
+		t.Fatalf("ResetClone to v5 = %#v, %v", result, err)
+	}
+	fingerprint, _ := goldenFingerprint(v5)
+	if ok, err := cloneMatchesFingerprint(filepath.Join(env.AVDHome, "w-1.avd"), fingerprint); !ok {
+		t.Fatalf("clone fingerprint not moved to v5: %v", err)
+	}
+}
+
+func TestResetCloneRehashesGoldenRewrittenInPlace(t *testing.T) {
+	env := newTestEnv(t)
+	env.GoldenDir = t.TempDir()
+	makeBaseAVD(t, env, "base-a35")
+	v4 := newRegistryGolden(t, env, "v4")
+	if _, err := CloneFromGolden(env, "base-a35", "w-1", v4); err != nil {
+		t.Fatalf("clone: %v", err)
+	}
+	if _, err := ResetClone(env, "w-1", v4); err != nil {
+		t.Fatalf("first ResetClone: %v", err)
+	}
+
+	// Same size, new content: only the mtime tells the manifest hash is stale.
+	cache := filepath.Join(v4, "cache.img")
+	old, err := os.ReadFile(cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if err := os.WriteFile(cache, []byte(strings.ToUpper(string(old))), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	result, err := ResetClone(env, "w-1", v4)
+	if err != nil || strings.Join(result.Copied, ",") != "cache.img" {
+		t.Fatalf("ResetClone after an in-place rewrite = %#v, %v", result, err)
+	}
+	if b, _ := os.ReadFile(filepath.Join(env.AVDHome, "w-1.avd", "cache.img")); string(b) != strings.ToUpper(string(old)) {
+		t.Fatalf("cache.img not re-copied: %q", b)
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Roles of the writable images of an AVD.
+const (
+	RoleData          = "data"
+	RoleEncryptionKey = "encryption-key"
+	RoleCache         = "cache"
+	RoleSDCard        = "sdcard"
+)
+
+// WritableImage is a writable image found in an AVD directory.
+type WritableImage struct {
+	Role string `json:"role"`
+	// Name is the file name the image has in goldens and clones, e.g. "userdata-qemu.img"
+	// for a data partition the emulator named userdata.img.
+	Name string `json:"name"`
+	// Path holds the current content: the qcow2 overlay when there is one, else the raw image.
+	Path      string `json:"path"`
+	Overlay   bool   `json:"overlay,omitempty"`
+	SizeBytes int64  `json:"size_bytes"`
+}
+
+// imageRoles lists, per role, the golden name, the hardware-qemu.ini key the emulator records
+// the image under, and the file names emulator releases use for it, most current first.
+var imageRoles = []struct {
+	role, name, hwKey string
+	variants          []string
+}{
+	{RoleData, "userdata-qemu.img", "disk.dataPartition.path", []string{"userdata-qemu.img", "userdata.img"}},
+	{RoleEncryptionKey, "encryptionkey.img", "disk.encryptionKeyPartition.path", []string{"encryptionkey.img"}},
+	{RoleCache, "cache.img", "disk.cachePartition.path", []string{"cache.img", "cache-qemu.img"}},
+	{RoleSDCard, "sdcard.img", "hw.sdCard.path", []string{"sdcard.img"}},
+}
+
+// WritableImages returns the writable images of AVD name as they exist on disk. The paths the
+// emulator recorded in hardware-qemu.ini win over the file names known for each role.
+func WritableImages(env Env, name string) ([]WritableImage, error) {
+	avdDir := filepath.Join(env.AVDHome, name+".avd")
+	if !fileExists(avdDir) {
+		return nil, fmt.Errorf("AVD %s not found", name)
+	}
+	return writableImages(avdDir), nil
+}
+
+func writableImages(avdDir string) []WritableImage {
+	hw, _ := os.ReadFile(filepath.Join(avdDir, hardwareConfigFilename))
+	var images []WritableImage
+	for _, r := range imageRoles {
+		var candidates []string
+		if p := configValue(hw, r.hwKey); p != "" {
+			if !filepath.IsAbs(p) {
+				p = filepath.Join(avdDir, p)
+			}
+			// Paths outside the AVD (a base's image in a clone) are not this AVD's images.
+			if filepath.Dir(p) == filepath.Clean(avdDir) {
+				candidates = append(candidates, filepath.Base(p))
+			}
+		}
+		candidates = append(candidates, r.variants...)
+		for _, file := range candidates {
+			if img, ok := statWritableImage(avdDir, file); ok {
+				img.Role, img.Name = r.role, r.name
+				images = append(images, img)
+				break
+			}
+		}
+	}
+	return images
+}
+
+func statWritableImage(avdDir, file string) (WritableImage, bool) {
+	for _, overlay := range []bool{true, false} {
+		path := filepath.Join(avdDir, file)
+		if overlay {
+			path += ".qcow2"
+		}
+		if st, err := os.Stat(path); err == nil && st.Mode().IsRegular() {
+			return WritableImage{Path: path, Overlay: overlay, SizeBytes: st.Size()}, true
+		}
+	}
+	return WritableImage{}, false
+}
+
+// writableImageRole returns the role of the image goldens call name, or "" for images of a
+// custom layout that are not writable partitions.
+func writableImageRole(name string) string {
+	for _, r := range imageRoles {
+		if r.name == name {
+			return r.role
+		}
+	}
+	return ""
+}
+
+// userdataImage returns the data image of avdDir (overlay first) and its size, or the path it
+// would have and 0 when there is none yet.
+func userdataImage(avdDir string) (string, int64) {
+	for _, img := range writableImages(avdDir) {
+		if img.Role == RoleData {
+			return img.Path, img.SizeBytes
+		}
+	}
+	return filepath.Join(avdDir, "userdata-qemu.img"), 0
+}
+
+// dataImageVariants lists the data image names for errors.
+func dataImageVariants() string {
+	return strings.Join(imageRoles[0].variants, ", ")
+}
@@ -0,0 +1,78 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWritableImagesFollowsHardwareConfig(t *testing.T) {
+	env := newTestEnv(t)
+	makeBaseAVD(t, env, "demo")
+	dir := filepath.Join(env.AVDHome, "demo.avd")
+	hw := "disk.dataPartition.path = " + filepath.Join(dir, "userdata.img") + "\n" +
+		"disk.cachePartition.path = /elsewhere/base.avd/cache.img\n"
+	for file, content := range map[string]string{
+		"hardware-qemu.ini":  hw,
+		"userdata.img":       "raw",
+		"userdata.img.qcow2": "overlay",
+		"cache.img":          "cache",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	images, err := WritableImages(env, "demo")
+	if err != nil {
+		t.Fatalf("WritableImages: %v", err)
+	}
+	if len(images) != 2 {
+		t.Fatalf("images = %+v", images)
+	}
+	data, cache := images[0], images[1]
+	if data.Role != RoleData || data.Name != "userdata-qemu.img" || !data.Overlay || filepath.Base(data.Path) != "userdata.img.qcow2" {
+		t.Fatalf("data image = %+v", data)
+	}
+	// The cache path points outside the AVD, so the AVD's own cache.img is used.
+	if cache.Role != RoleCache || cache.Path != filepath.Join(dir, "cache.img") {
+		t.Fatalf("cache image = %+v", cache)
+	}
+	if info, _ := infoOf(env, "demo"); info.Userdata != data.Path {
+		t.Fatalf("info userdata = %s, want %s", info.Userdata, data.Path)
+	}
+}
+
+func TestSaveGoldenExportsUserdataVariant(t *testing.T) {
+	env := newFsckTestEnv(t, 0)
+	dir := filepath.Join(env.AVDHome, "demo.avd")
+	if err := os.Rename(filepath.Join(dir, "userdata-qemu.img"), filepath.Join(dir, "userdata.img")); err != nil {
+		t.Fatal(err)
+	}
+	dest := filepath.Join(t.TempDir(), "golden")
+	if _, _, err := SaveGoldenWithOptions(env, "demo", dest, SaveGoldenOptions{}); err != nil {
+		t.Fatalf("SaveGolden: %v", err)
+	}
+	b, err := os.ReadFile(filepath.Join(dest, "userdata-qemu.img"))
+	if err != nil || string(b) != "userdata" {
+		t.Fatalf("exported userdata = %q, %v", b, err)
+	}
+}
+
+func TestSaveGoldenRefusesMissingUserdata(t *testing.T) {
+	env := newFsckTestEnv(t, 0)
+	if err := os.Remove(filepath.Join(env.AVDHome, "demo.avd", "userdata-qemu.img")); err != nil {
+		t.Fatal(err)
+	}
+	dest := filepath.Join(t.TempDir(), "golden")
+	_, _, err := SaveGoldenWithOptions(env, "demo", dest, SaveGoldenOptions{})
+	if err == nil || !strings.Contains(err.Error(), "no userdata image") {
+		t.Fatalf("expected missing userdata error, got %v", err)
+	}
+	if fileExists(filepath.Join(dest, goldenManifestFilename)) {
+		t.Fatal("golden without userdata must not be published")
+	}
+}
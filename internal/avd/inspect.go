@@ -0,0 +1,198 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// inspectLogLines is how many lines of the emulator log Inspect includes.
+const inspectLogLines = 40
+
+// Inspection is everything avdctl knows about one AVD, for debugging and support tickets (see
+// Inspect). The Serial to QemuCommand fields describe its last start by avdctl.
+type Inspection struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	Kind string `json:"kind"` // KindBase or KindClone
+
+	Serial    string     `json:"serial,omitempty"`
+	Running   bool       `json:"running"`
+	PID       int        `json:"pid,omitempty"`
+	StartedAt *time.Time `json:"started_at,omitempty"`
+	LogPath   string     `json:"log_path,omitempty"`
+	// Command is the emulator program and its flags, as avdctl ran them.
+	Command []string `json:"command,omitempty"`
+	// QemuPID and QemuCommand describe the qemu-system process behind the emulator; they are
+	// empty until Inspect has seen the instance running.
+	QemuPID     int      `json:"qemu_pid,omitempty"`
+	QemuCommand []string `json:"qemu_command,omitempty"`
+
+	// Config is the config.ini the emulator reads.
+	Config map[string]string `json:"config"`
+	Images []WritableImage   `json:"images"`
+	// Links are the symlinks in the AVD directory, e.g. a clone's links into its base.
+	Links []LinkHealth `json:"links,omitempty"`
+	// Golden is the golden a clone was made from, when it is in Env.GoldenDir.
+	Golden         string          `json:"golden,omitempty"`
+	GoldenManifest *GoldenManifest `json:"golden_manifest,omitempty"`
+	Customizations Customizations  `json:"customizations"`
+	// History lists the last starts by avdctl, oldest first.
+	History []InstanceRun `json:"history,omitempty"`
+	// Process is the running instance, if any; Checks are its health checks once booted.
+	Process *ProcInfo     `json:"process,omitempty"`
+	Checks  []HealthCheck `json:"checks,omitempty"`
+	// LogTail is the end of the emulator log of the running instance, else of the last start.
+	LogTail []string `json:"log_tail,omitempty"`
+}
+
+// LinkHealth is a symlink in an AVD directory and whether its target exists.
+type LinkHealth struct {
+	Path   string `json:"path"` // relative to the AVD directory
+	Target string `json:"target"`
+	OK     bool   `json:"ok"`
+}
+
+// Inspect gathers what is known about AVD name: its config, images and symlinks, the golden
+// and customizations it was made with, its run history, the running process with its health
+// checks, and the end of its log. While the emulator runs, its qemu child is resolved from
+// /proc and added to the start record, so the qemu command line stays available after it exits.
+func Inspect(env Env, name string) (Inspection, error) {
+	_, span := startSpan(env, "avd.Inspect", attribute.String("name", name))
+	defer span.End()
+	avdDir := filepath.Join(env.AVDHome, name+".avd")
+	if !fileExists(avdDir) {
+		err := fmt.Errorf("AVD %s not found", name)
+		recordSpanError(span, err)
+		return Inspection{}, err
+	}
+	info := Info{Name: name, Path: avdDir}
+	describeAVD(&info, registryFingerprints(env))
+	ins := Inspection{Name: name, Path: avdDir, Kind: info.Kind, Golden: info.Golden, Images: writableImages(avdDir)}
+	if ins.Images == nil {
+		ins.Images = []WritableImage{}
+	}
+	cfg, _ := os.ReadFile(filepath.Join(avdDir, "config.ini"))
+	ins.Config = parseConfigINI(cfg)
+	ins.Links = avdLinks(avdDir)
+	if ins.Golden != "" {
+		if manifest, err := ReadGoldenManifest(ins.Golden); err == nil {
+			ins.GoldenManifest = &manifest
+		}
+	}
+	if c, err := readCustomizations(avdDir); err == nil {
+		ins.Customizations = c
+	}
+	ins.History = readRunHistory(avdDir)
+
+	if rec, ok := readInstance(avdDir); ok {
+		ins.Running = rec.PID > 0 && syscall.Kill(rec.PID, 0) == nil && !isZombieProcess(rec.PID)
+		if ins.Running && rec.QemuPID == 0 {
+			if pid, argv, ok := qemuChild(rec.PID); ok {
+				rec.QemuPID, rec.QemuArgs = pid, argv
+				recordInstance(avdDir, rec)
+			}
+		}
+		ins.Serial, ins.PID, ins.LogPath = rec.Serial, rec.PID, rec.LogPath
+		if !rec.StartedAt.IsZero() {
+			started := rec.StartedAt
+			ins.StartedAt = &started
+		}
+		ins.Command = rec.Args
+		if rec.Program != "" {
+			ins.Command = append([]string{rec.Program}, rec.Args...)
+		}
+		ins.QemuPID, ins.QemuCommand = rec.QemuPID, rec.QemuArgs
+	}
+
+	if procs, err := ListRunning(env); err == nil {
+		for _, p := range procs {
+			if p.Name == name {
+				ins.Process = &p
+				break
+			}
+		}
+	}
+	logPath := ins.LogPath
+	if ins.Process != nil {
+		if ins.Process.LogPath != "" {
+			logPath = ins.Process.LogPath
+		}
+		if ins.Process.Booted {
+			ins.Checks = runHealthChecks(env, ins.Process.Serial)
+		}
+	}
+	if logPath != "" {
+		ins.LogTail = tailLines(logPath, inspectLogLines)
+	}
+	span.SetAttributes(attribute.Bool("running", ins.Process != nil), attribute.Int("qemu_pid", ins.QemuPID))
+	return ins, nil
+}
+
+// avdLinks returns the symlinks under avdDir with whether each resolves.
+func avdLinks(avdDir string) []LinkHealth {
+	var links []LinkHealth
+	_ = filepath.WalkDir(avdDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.Type()&fs.ModeSymlink == 0 {
+			return nil
+		}
+		target, err := os.Readlink(path)
+		if err != nil {
+			return nil
+		}
+		rel, _ := filepath.Rel(avdDir, path)
+		links = append(links, LinkHealth{Path: rel, Target: target, OK: fileExists(path)})
+		return nil
+	})
+	return links
+}
+
+// tailLines returns the last n lines of the file at path.
+func tailLines(path string, n int) []string {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+		if len(lines) > n {
+			lines = lines[1:]
+		}
+	}
+	return lines
+}
+
+// qemuChild returns the qemu process of the emulator launcher pid and its full command line:
+// pid itself when the launcher exec'd qemu, else its qemu-system child.
+func qemuChild(pid int) (int, []string, bool) {
+	if argv := processCmdline(pid); len(argv) > 0 && strings.Contains(filepath.Base(argv[0]), "qemu-system") {
+		return pid, argv, true
+	}
+	procs, err := listQemuProcesses()
+	if err != nil {
+		return 0, nil, false
+	}
+	for _, p := range procs {
+		if p.ParentPID != pid || !strings.Contains(p.Cmdline, "qemu-system") {
+			continue
+		}
+		if argv := processCmdline(p.PID); len(argv) > 0 {
+			return p.PID, argv, true
+		}
+	}
+	return 0, nil, false
+}
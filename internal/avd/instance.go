@@ -0,0 +1,236 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// instanceFilename records, in the AVD directory, how avdctl last started its emulator, so ps
+// can report the log path of an instance started by another avdctl process.
+const instanceFilename = "avdctl-instance.json"
+
+// runHistoryFilename keeps, in the AVD directory, the starts by avdctl that Env.Retention
+// keeps: without a runs count, the last maxRunHistory.
+const (
+	runHistoryFilename = "avdctl-runs.json"
+	maxRunHistory      = 20
+)
+
+// clockTicks is USER_HZ, the unit of the start time in /proc/<pid>/stat; it is 100 on every
+// Linux architecture the emulator runs on.
+const clockTicks = 100
+
+type instanceRecord struct {
+	Serial    string    `json:"serial,omitempty"`
+	PID       int       `json:"pid"`
+	LogPath   string    `json:"log_path,omitempty"`
+	StartedAt time.Time `json:"started_at"`
+	Program   string    `json:"program,omitempty"`
+	Args      []string  `json:"args"`
+	// Emulator is the Env.Emulator the instance was started with (Program may be a sandbox).
+	Emulator string `json:"emulator,omitempty"`
+	// QemuPID and QemuArgs (the full command line) are filled in by Inspect.
+	QemuPID  int      `json:"qemu_pid,omitempty"`
+	QemuArgs []string `json:"qemu_args,omitempty"`
+}
+
+func recordInstance(avdDir string, rec instanceRecord) {
+	b, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return
+	}
+	_ = os.WriteFile(filepath.Join(avdDir, instanceFilename), append(b, '\n'), 0o644)
+}
+
+// InstanceRun is one start of an AVD by avdctl (see Inspection.History).
+type InstanceRun struct {
+	StartedAt time.Time  `json:"started_at"`
+	Serial    string     `json:"serial,omitempty"`
+	PID       int        `json:"pid"`
+	LogPath   string     `json:"log_path,omitempty"`
+	BootedAt  *time.Time `json:"booted_at,omitempty"` // set when a boot wait succeeds
+	EndedAt   *time.Time `json:"ended_at,omitempty"`  // set by Stop, or by classifyRuns
+	// Outcome is RunStopped or a failure class (FailureBootTimeout, ...); empty while the run
+	// lasts. Runs that exited without Stop are classified at the next start.
+	Outcome       string `json:"outcome,omitempty"`
+	FailureDetail string `json:"failure_detail,omitempty"`
+	// Usage is sampled by Stop; runs that ended otherwise have none.
+	Usage *ResourceUsage `json:"usage,omitempty"`
+	// Artifacts are the files CaptureArtifacts collected during the run.
+	Artifacts []RunArtifact `json:"artifacts,omitempty"`
+}
+
+// recordRun appends the start in rec to the run history of avdDir, then applies Env.Retention.
+func recordRun(env Env, avdDir string, rec instanceRecord) {
+	runs, _ := classifyRuns(avdDir, readRunHistory(avdDir))
+	runs = append(runs, InstanceRun{StartedAt: rec.StartedAt, Serial: rec.Serial, PID: rec.PID, LogPath: rec.LogPath})
+	writeRunHistory(avdDir, runs)
+	applyRetention(env, avdDir, false)
+}
+
+// runningAVDDir finds the AVD directory whose instance record names serial, while that
+// instance runs.
+func runningAVDDir(env Env, serial string) (string, instanceRecord, bool) {
+	dirs, _ := filepath.Glob(filepath.Join(env.AVDHome, "*.avd"))
+	for _, avdDir := range dirs {
+		rec, ok := readInstance(avdDir)
+		if ok && rec.Serial == serial && rec.PID > 0 && syscall.Kill(rec.PID, 0) == nil {
+			return avdDir, rec, true
+		}
+	}
+	return "", instanceRecord{}, false
+}
+
+// writeRunHistory replaces the run history of avdDir through a rename, so it never writes
+// through a link into another AVD's history.
+func writeRunHistory(avdDir string, runs []InstanceRun) {
+	b, err := json.MarshalIndent(runs, "", "  ")
+	if err != nil {
+		return
+	}
+	path := filepath.Join(avdDir, runHistoryFilename)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(b, '\n'), 0o644); err != nil {
+		return
+	}
+	_ = os.Rename(tmp, path)
+}
+
+func readRunHistory(avdDir string) []InstanceRun {
+	b, err := os.ReadFile(filepath.Join(avdDir, runHistoryFilename))
+	if err != nil {
+		return nil
+	}
+	var runs []InstanceRun
+	_ = json.Unmarshal(b, &runs)
+	return runs
+}
+
+func readInstance(avdDir string) (instanceRecord, bool) {
+	b, err := os.ReadFile(filepath.Join(avdDir, instanceFilename))
+	if err != nil {
+		return instanceRecord{}, false
+	}
+	var rec instanceRecord
+	if err := json.Unmarshal(b, &rec); err != nil {
+		return instanceRecord{}, false
+	}
+	return rec, true
+}
+
+// describeProcess fills the LogPath, StartedAt, GPU and Args of p. Args and StartedAt come from
+// /proc when the pid is known; the start record avdctl wrote for that pid (or, without a pid,
+// that serial) supplies the log path and stands in for /proc elsewhere.
+func describeProcess(env Env, p *ProcInfo) {
+	if p.PID > 0 {
+		p.Args = processArgs(p.PID)
+		if started, ok := processStartTime(p.PID); ok {
+			p.StartedAt = &started
+		}
+	}
+	if p.Name != "" {
+		rec, ok := readInstance(filepath.Join(env.AVDHome, p.Name+".avd"))
+		if ok && ((p.PID > 0 && rec.PID == p.PID) || (p.PID == 0 && rec.Serial == p.Serial)) {
+			p.LogPath = rec.LogPath
+			if p.Args == nil {
+				p.Args = rec.Args
+			}
+			if p.StartedAt == nil && !rec.StartedAt.IsZero() {
+				started := rec.StartedAt
+				p.StartedAt = &started
+			}
+		}
+	}
+	p.GPU = argValue(p.Args, "-gpu")
+}
+
+// instanceEmulator returns the emulator binary the instance p was started with, from its start
+// record, else Env.Emulator.
+func instanceEmulator(env Env, p ProcInfo) string {
+	if p.Name != "" {
+		rec, ok := readInstance(filepath.Join(env.AVDHome, p.Name+".avd"))
+		if ok && rec.Emulator != "" && ((p.PID > 0 && rec.PID == p.PID) || (p.PID == 0 && rec.Serial == p.Serial)) {
+			return rec.Emulator
+		}
+	}
+	return env.Emulator
+}
+
+// processArgs returns the command-line arguments of pid, without the program name.
+func processArgs(pid int) []string {
+	if argv := processCmdline(pid); len(argv) > 0 {
+		return argv[1:]
+	}
+	return nil
+}
+
+// processCmdline returns the command line of pid, program name included.
+func processCmdline(pid int) []string {
+	b, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "cmdline"))
+	if err != nil || len(b) == 0 {
+		return nil
+	}
+	return strings.Split(string(bytes.TrimRight(b, "\x00")), "\x00")
+}
+
+// processStartTime returns when pid started, from its start time in /proc/<pid>/stat (clock
+// ticks since boot) and the boot time in /proc/stat.
+func processStartTime(pid int) (time.Time, bool) {
+	stat, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return time.Time{}, false
+	}
+	data := string(stat)
+	rparen := strings.LastIndex(data, ")")
+	if rparen == -1 || rparen+2 >= len(data) {
+		return time.Time{}, false
+	}
+	// Fields after the command name start at field 3 (state); starttime is field 22.
+	fields := strings.Fields(data[rparen+2:])
+	if len(fields) < 20 {
+		return time.Time{}, false
+	}
+	ticks, err := strconv.ParseInt(fields[19], 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	boot, ok := bootTime()
+	if !ok {
+		return time.Time{}, false
+	}
+	return boot.Add(time.Duration(ticks) * time.Second / clockTicks).UTC(), true
+}
+
+func bootTime() (time.Time, bool) {
+	b, err := os.ReadFile("/proc/stat")
+	if err != nil {
+		return time.Time{}, false
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		if v, ok := strings.CutPrefix(line, "btime "); ok {
+			if secs, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64); err == nil {
+				return time.Unix(secs, 0), true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// argValue returns the value following flag in args, if any.
+func argValue(args []string, flag string) string {
+	for i, arg := range args {
+		if arg == flag && i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,145 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestDescribeProcessFromProcAndStartRecord(t *testing.T) {
+	if _, err := os.Stat("/proc/self/stat"); err != nil {
+		t.Skip("needs /proc")
+	}
+	cmd := exec.Command("sh", "-c", "sleep 30; :", "qemu-system-test", "-port", "5796", "-gpu", "host")
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = cmd.Process.Kill(); _ = cmd.Wait() })
+	env := newTestEnv(t)
+	makeBaseAVD(t, env, "demo")
+	avdDir := filepath.Join(env.AVDHome, "demo.avd")
+	recordInstance(avdDir, instanceRecord{Serial: "emulator-5796", PID: cmd.Process.Pid, LogPath: "/tmp/demo.log", StartedAt: time.Now().UTC()})
+
+	p := ProcInfo{Serial: "emulator-5796", Name: "demo", Port: 5796, PID: cmd.Process.Pid}
+	describeProcess(env, &p)
+	if p.LogPath != "/tmp/demo.log" || p.GPU != "host" {
+		t.Fatalf("log/gpu = %q, %q", p.LogPath, p.GPU)
+	}
+	if want := []string{"-c", "sleep 30; :", "qemu-system-test", "-port", "5796", "-gpu", "host"}; !slices.Equal(p.Args, want) {
+		t.Fatalf("Args = %q", p.Args)
+	}
+	if p.StartedAt == nil || time.Since(*p.StartedAt) > time.Minute || time.Since(*p.StartedAt) < -2*time.Second {
+		t.Fatalf("StartedAt = %v", p.StartedAt)
+	}
+
+	// A record for another pid (the AVD was restarted by someone else) is ignored.
+	other := ProcInfo{Serial: "emulator-5796", Name: "demo", Port: 5796, PID: cmd.Process.Pid + 100000}
+	describeProcess(env, &other)
+	if other.LogPath != "" {
+		t.Fatalf("stale start record used: %+v", other)
+	}
+}
+
+func TestInspectReportsStartAndQemuChild(t *testing.T) {
+	if _, err := os.Stat("/proc/self/stat"); err != nil {
+		t.Skip("needs /proc")
+	}
+	sleep, err := exec.LookPath("sleep")
+	if err != nil {
+		t.Skip("needs sleep")
+	}
+	qemu := filepath.Join(t.TempDir(), "qemu-system-test")
+	if err := os.Symlink(sleep, qemu); err != nil {
+		t.Fatal(err)
+	}
+	launcher := exec.Command("sh", "-c", qemu+" 30 & wait")
+	if err := launcher.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = launcher.Process.Kill(); _ = launcher.Wait() })
+	env := newTestEnv(t)
+	makeBaseAVD(t, env, "demo")
+	avdDir := filepath.Join(env.AVDHome, "demo.avd")
+	logPath := filepath.Join(t.TempDir(), "demo.log")
+	if err := os.WriteFile(logPath, []byte("boot\nready\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("/nonexistent/system.img", filepath.Join(avdDir, "system.img")); err != nil {
+		t.Fatal(err)
+	}
+	rec := instanceRecord{Serial: "emulator-5796", PID: launcher.Process.Pid, LogPath: logPath, Program: "/sdk/launcher", Args: []string{"-avd", "demo"}, StartedAt: time.Now().UTC()}
+	recordInstance(avdDir, rec)
+	recordRun(env, avdDir, rec)
+
+	var got Inspection
+	deadline := time.Now().Add(5 * time.Second)
+	for got.QemuPID == 0 && time.Now().Before(deadline) {
+		if got, err = Inspect(env, "demo"); err != nil {
+			t.Fatalf("Inspect: %v", err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if got.QemuPID != 0 {
+		t.Cleanup(func() { _ = syscall.Kill(got.QemuPID, syscall.SIGKILL) })
+	}
+	if !got.Running || got.QemuPID == 0 || !slices.Equal(got.QemuCommand, []string{qemu, "30"}) {
+		t.Fatalf("Inspect = %+v", got)
+	}
+	if want := []string{"/sdk/launcher", "-avd", "demo"}; !slices.Equal(got.Command, want) {
+		t.Fatalf("Command = %q, want %q", got.Command, want)
+	}
+
+	if len(got.History) != 1 || got.History[0].PID != rec.PID || !slices.Equal(got.LogTail, []string{"boot", "ready"}) {
+		t.Fatalf("history/log tail = %+v, %q", got.History, got.LogTail)
+	}
+	if len(got.Links) != 1 || got.Links[0].Path != "system.img" || got.Links[0].OK {
+		t.Fatalf("Links = %+v, want the broken system.img link", got.Links)
+	}
+	if got.Kind != KindBase || got.Config == nil {
+		t.Fatalf("kind/config = %q, %v", got.Kind, got.Config)
+	}
+
+	// The qemu command line stays in the record after the instance exits.
+	_ = launcher.Process.Kill()
+	_ = launcher.Wait()
+	after, err := Inspect(env, "demo")
+	if err != nil || after.Running || after.QemuPID != got.QemuPID {
+		t.Fatalf("Inspect after exit = %+v, %v", after, err)
+	}
+	if _, err := Inspect(env, "missing"); err == nil {
+		t.Fatal("expected an error without a start record")
+	}
+}
+
+func TestCloneKeepsOwnRunHistory(t *testing.T) {
+	env := newTestEnv(t)
+	makeBaseAVD(t, env, "base")
+	baseDir := filepath.Join(env.AVDHome, "base.avd")
+	writeRunHistory(baseDir, []InstanceRun{{StartedAt: time.Now().Add(-time.Hour), PID: 7, Outcome: RunStopped}})
+	if _, err := CloneFromGolden(env, "base", "w-1", makeGoldenDir(t)); err != nil {
+		t.Fatalf("CloneFromGolden: %v", err)
+	}
+	cloneDir := filepath.Join(env.AVDHome, "w-1.avd")
+	if _, err := os.Lstat(filepath.Join(cloneDir, runHistoryFilename)); !os.IsNotExist(err) {
+		t.Fatalf("clone has the base's run history: %v", err)
+	}
+
+	// A link left by an older clone is replaced, not written through.
+	if err := os.Symlink(filepath.Join(baseDir, runHistoryFilename), filepath.Join(cloneDir, runHistoryFilename)); err != nil {
+		t.Fatal(err)
+	}
+	writeRunHistory(cloneDir, []InstanceRun{{StartedAt: time.Now(), PID: 8}})
+	if base := readRunHistory(baseDir); len(base) != 1 || base[0].PID != 7 {
+		t.Fatalf("base history = %+v", base)
+	}
+	if clone := readRunHistory(cloneDir); len(clone) != 1 || clone[0].PID != 8 {
+		t.Fatalf("clone history = %+v", clone)
+	}
+}
@@ -0,0 +1,182 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Integrity verdict classes, in increasing order, as named by the Play Integrity API.
+const (
+	IntegrityUnevaluated = "UNEVALUATED" // no Google Play services: the API cannot run at all
+	IntegrityNone        = "NO_INTEGRITY"
+	IntegrityBasic       = "MEETS_BASIC_INTEGRITY"
+	IntegrityDevice      = "MEETS_DEVICE_INTEGRITY"
+	IntegrityStrong      = "MEETS_STRONG_INTEGRITY"
+)
+
+var integrityRank = map[string]int{
+	IntegrityUnevaluated: 0,
+	IntegrityNone:        1,
+	IntegrityBasic:       2,
+	IntegrityDevice:      3,
+	IntegrityStrong:      4,
+}
+
+// IntegritySignals are the device properties a verdict is estimated from.
+type IntegritySignals struct {
+	Emulator          bool   `json:"emulator"`
+	PlayServices      string `json:"play_services,omitempty"` // versionName of com.google.android.gms
+	PlayStore         bool   `json:"play_store"`
+	BuildType         string `json:"build_type"` // user, userdebug, eng
+	BuildTags         string `json:"build_tags"` // release-keys, test-keys, dev-keys
+	Debuggable        bool   `json:"debuggable"`
+	VerifiedBootState string `json:"verified_boot_state,omitempty"` // green, yellow, orange
+	BootloaderLocked  bool   `json:"bootloader_locked"`
+	SuBinary          bool   `json:"su_binary"`
+}
+
+// IntegrityReport is the estimated Play Integrity posture of a running device. The verdict is
+// derived locally from the signals Google's checks are known to use; it does not call Google.
+type IntegrityReport struct {
+	Serial    string           `json:"serial,omitempty"`
+	CheckedAt time.Time        `json:"checked_at"`
+	Verdict   string           `json:"verdict"`
+	Signals   IntegritySignals `json:"signals"`
+	// Reasons explain why the next verdict class is not met.
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+// DegradedFrom reports whether r has a lower verdict class than before (e.g. a clone's report
+// compared with the one recorded in its golden's manifest).
+func (r IntegrityReport) DegradedFrom(before IntegrityReport) bool {
+	return integrityRank[r.Verdict] < integrityRank[before.Verdict]
+}
+
+// CheckIntegrity probes a booted device over adb and estimates its integrity verdict.
+func CheckIntegrity(env Env, serial string) (IntegrityReport, error) {
+	_, span := startSpan(env, "avd.CheckIntegrity", attribute.String("serial", serial))
+	defer span.End()
+
+	props, _, err := runCommandOutputWithEnv(env.Context, nil, nil, env.ADB, "-s", serial, "shell", "getprop")
+	if err != nil {
+		err = fmt.Errorf("read properties of %s: %w", serial, err)
+		recordSpanError(span, err)
+		return IntegrityReport{}, err
+	}
+	p := parseGetprop(props)
+	if len(p) == 0 {
+		err := errors.New("no properties returned by " + serial + "; is it booted?")
+		recordSpanError(span, err)
+		return IntegrityReport{}, err
+	}
+	signals := IntegritySignals{
+		Emulator:          p["ro.kernel.qemu"] == "1" || p["ro.boot.qemu"] == "1" || strings.HasPrefix(p["ro.hardware"], "ranchu"),
+		BuildType:         p["ro.build.type"],
+		BuildTags:         p["ro.build.tags"],
+		Debuggable:        p["ro.debuggable"] == "1",
+		VerifiedBootState: p["ro.boot.verifiedbootstate"],
+		BootloaderLocked:  p["ro.boot.flash.locked"] == "1",
+	}
+	signals.PlayServices = packageVersion(env, serial, "com.google.android.gms")
+	signals.PlayStore = packageInstalled(env, serial, "com.android.vending")
+	if out, _, _ := runCommandOutputWithEnv(env.Context, nil, nil, env.ADB, "-s", serial, "shell",
+		"command -v su || ls /system/xbin/su /system/bin/su 2>/dev/null"); strings.TrimSpace(out) != "" {
+		signals.SuBinary = true
+	}
+
+	report := classifyIntegrity(signals)
+	report.Serial = serial
+	report.CheckedAt = time.Now().UTC()
+	span.SetAttributes(attribute.String("verdict", report.Verdict))
+	logEvent(env, "integrity checked", "serial", serial, "verdict", report.Verdict)
+	return report, nil
+}
+
+// classifyIntegrity maps signals to the highest verdict class they can plausibly reach.
+func classifyIntegrity(s IntegritySignals) IntegrityReport {
+	report := IntegrityReport{Signals: s}
+	if s.PlayServices == "" {
+		report.Verdict = IntegrityUnevaluated
+		report.Reasons = []string{"Google Play services not installed"}
+		return report
+	}
+
+	var basic []string
+	if s.BuildTags != "release-keys" {
+		basic = append(basic, fmt.Sprintf("build signed with %s", s.BuildTags))
+	}
+	if s.Debuggable || s.BuildType != "user" {
+		basic = append(basic, fmt.Sprintf("debuggable %s build", s.BuildType))
+	}
+	if s.SuBinary {
+		basic = append(basic, "su binary present")
+	}
+	if !s.PlayStore {
+		basic = append(basic, "Play Store not installed")
+	}
+	if len(basic) > 0 {
+		report.Verdict = IntegrityNone
+		report.Reasons = basic
+		return report
+	}
+
+	var device []string
+	if s.Emulator {
+		device = append(device, "emulator hardware")
+	}
+	if s.VerifiedBootState != "green" {
+		device = append(device, fmt.Sprintf("verified boot state %q", s.VerifiedBootState))
+	}
+	if !s.BootloaderLocked {
+		device = append(device, "bootloader unlocked")
+	}
+	if len(device) > 0 {
+		report.Verdict = IntegrityBasic
+		report.Reasons = device
+		return report
+	}
+	// Strong integrity needs hardware-backed key attestation, which cannot be probed over adb.
+	report.Verdict = IntegrityDevice
+	report.Reasons = []string{"hardware key attestation not checked"}
+	return report
+}
+
+// parseGetprop parses "[key]: [value]" lines printed by getprop.
+func parseGetprop(out string) map[string]string {
+	props := map[string]string{}
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "]: [")
+		if !ok || !strings.HasPrefix(key, "[") || !strings.HasSuffix(value, "]") {
+			continue
+		}
+		props[strings.TrimPrefix(key, "[")] = strings.TrimSuffix(value, "]")
+	}
+	return props
+}
+
+func packageInstalled(env Env, serial, pkg string) bool {
+	out, _, err := runCommandOutputWithEnv(env.Context, nil, nil, env.ADB, "-s", serial, "shell", "pm", "path", pkg)
+	return err == nil && strings.Contains(out, "package:")
+}
+
+func packageVersion(env Env, serial, pkg string) string {
+	if !packageInstalled(env, serial, pkg) {
+		return ""
+	}
+	out, _, _ := runCommandOutputWithEnv(env.Context, nil, nil, env.ADB, "-s", serial, "shell", "dumpsys", "package", pkg)
+	for _, line := range strings.Split(out, "\n") {
+		if v, ok := strings.CutPrefix(strings.TrimSpace(line), "versionName="); ok {
+			return v
+		}
+	}
+	return "unknown"
+}
@@ -0,0 +1,80 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestClassifyIntegrity(t *testing.T) {
+	playstore := IntegritySignals{
+		Emulator:     true,
+		PlayServices: "24.40.33",
+		PlayStore:    true,
+		BuildType:    "user",
+		BuildTags:    "release-keys",
+	}
+	googleAPIs := playstore
+	googleAPIs.PlayStore = false
+	googleAPIs.BuildType, googleAPIs.BuildTags, googleAPIs.Debuggable = "userdebug", "dev-keys", true
+	aosp := IntegritySignals{Emulator: true, BuildType: "userdebug"}
+
+	cases := []struct {
+		signals IntegritySignals
+		want    string
+	}{
+		{playstore, IntegrityBasic},
+		{googleAPIs, IntegrityNone},
+		{aosp, IntegrityUnevaluated},
+	}
+	for _, tc := range cases {
+		if got := classifyIntegrity(tc.signals); got.Verdict != tc.want || len(got.Reasons) == 0 {
+			t.Fatalf("classifyIntegrity(%+v) = %s %v, want %s", tc.signals, got.Verdict, got.Reasons, tc.want)
+		}
+	}
+
+	rooted := playstore
+	rooted.SuBinary = true
+	after, before := classifyIntegrity(rooted), classifyIntegrity(playstore)
+	if !after.DegradedFrom(before) || before.DegradedFrom(after) {
+		t.Fatalf("expected %s to be degraded from %s", after.Verdict, before.Verdict)
+	}
+}
+
+func TestCheckIntegrityProbesDevice(t *testing.T) {
+	env := newTestEnv(t)
+	script := `#!/bin/sh
+case "$4" in
+  getprop) printf '[ro.kernel.qemu]: [1]\n[ro.build.type]: [user]\n[ro.build.tags]: [release-keys]\n[ro.debuggable]: [0]\n' ;;
+  pm) echo "package:/data/app/$6/base.apk" ;;
+  dumpsys) echo "    versionName=24.40.33 (190400-123)" ;;
+esac
+exit 0
+`
+	if err := os.WriteFile(env.ADB, []byte(script), 0o755); err != nil {
+		t.Fatalf("write adb stub: %v", err)
+	}
+	report, err := CheckIntegrity(env, "emulator-5596")
+	if err != nil {
+		t.Fatalf("CheckIntegrity: %v", err)
+	}
+	if report.Verdict != IntegrityBasic || !report.Signals.Emulator || !report.Signals.PlayStore {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if report.Signals.PlayServices != "24.40.33 (190400-123)" {
+		t.Fatalf("play services version = %q", report.Signals.PlayServices)
+	}
+	if !strings.Contains(strings.Join(report.Reasons, ","), "emulator hardware") {
+		t.Fatalf("expected emulator reason, got %v", report.Reasons)
+	}
+}
+
+func TestParseGetprop(t *testing.T) {
+	props := parseGetprop("[ro.build.tags]: [release-keys]\n[empty]: []\nnoise\n")
+	if props["ro.build.tags"] != "release-keys" || props["empty"] != "" || len(props) != 2 {
+		t.Fatalf("unexpected props: %#v", props)
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// AVDInUseError is returned when an AVD is started while another emulator uses its writable
+// images: two instances writing the same userdata corrupt it. Set Env.SharedAVD to start a
+// read-only instance anyway.
+type AVDInUseError struct {
+	Name   string
+	Serial string // instance adb sees, when known
+	PID    int    // emulator process, when known
+	Lock   string // live emulator lock file the instance was found by
+}
+
+func (e *AVDInUseError) Error() string {
+	msg := "AVD " + e.Name + " already running"
+	switch {
+	case e.Serial != "":
+		msg += " on " + e.Serial
+	case e.Lock != "":
+		msg += fmt.Sprintf(" (pid %d holds %s)", e.PID, filepath.Base(e.Lock))
+	case e.PID > 0:
+		msg += fmt.Sprintf(" (emulator pid %d)", e.PID)
+	}
+	return msg + "; stop it first or start a shared read-only instance"
+}
+
+// avdInUse looks for another emulator using the writable images of name: a process started
+// with -avd name whose data is not elsewhere (ephemeral runs keep theirs on a tmpfs), or a
+// lock file the emulator keeps next to the images, held by a live process. avdctl launches
+// pass -read-only and create no locks, so the locks come from launches outside avdctl.
+func avdInUse(env Env, name string) *AVDInUseError {
+	avdDir := filepath.Join(env.AVDHome, name+".avd")
+	entries, _ := filepath.Glob("/proc/[0-9]*")
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(filepath.Base(entry))
+		if err != nil || pid == os.Getpid() {
+			continue
+		}
+		args := processArgs(pid)
+		if argValue(args, "-avd") != name || isZombieProcess(pid) {
+			continue
+		}
+		if data := argValue(args, "-data"); data != "" && !strings.HasPrefix(filepath.Clean(data), avdDir+string(filepath.Separator)) {
+			continue
+		}
+		return &AVDInUseError{Name: name, PID: pid}
+	}
+	locks, _ := filepath.Glob(filepath.Join(avdDir, "*.lock"))
+	for _, lock := range locks {
+		if pid := lockOwner(lock); pid > 0 && pid != os.Getpid() && syscall.Kill(pid, 0) == nil && !isZombieProcess(pid) {
+			return &AVDInUseError{Name: name, PID: pid, Lock: lock}
+		}
+	}
+	return nil
+}
+
+// lockOwner returns the pid recorded in an emulator lock: a directory holding a "pid" file,
+// or a file holding the pid. Locks without one (such as avdctl's own flock files) return 0.
+func lockOwner(lock string) int {
+	path := lock
+	if st, err := os.Stat(lock); err == nil && st.IsDir() {
+		path = filepath.Join(lock, "pid")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0
+	}
+	return pid
+}
+
+// avdInUseGrace is how long a start waits for an emulator of the same AVD that is shutting
+// down, e.g. right after KillEmulator, before it reports the AVD in use.
+const avdInUseGrace = 5 * time.Second
+
+// waitAVDFree returns nil once no other emulator uses name, or its AVDInUseError after
+// avdInUseGrace.
+func waitAVDFree(env Env, name string) *AVDInUseError {
+	deadline := time.Now().Add(avdInUseGrace)
+	for {
+		inUse := avdInUse(env, name)
+		if inUse == nil || !time.Now().Before(deadline) {
+			return inUse
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// startFakeEmulator runs a process whose command line carries args, as an emulator's would.
+func startFakeEmulator(t *testing.T, args ...string) *exec.Cmd {
+	t.Helper()
+	cmd := exec.Command("sh", append([]string{"-c", "sleep 30", "sh"}, args...)...)
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = cmd.Process.Kill(); _ = cmd.Wait() })
+	return cmd
+}
+
+func TestAVDInUseByProcess(t *testing.T) {
+	if _, err := os.Stat("/proc/self/cmdline"); err != nil {
+		t.Skip("no /proc")
+	}
+	env := newTestEnv(t)
+	name := "w-inuse-" + strconv.Itoa(os.Getpid())
+	makeBaseAVD(t, env, name)
+	if inUse := avdInUse(env, name); inUse != nil {
+		t.Fatalf("unexpected %v", inUse)
+	}
+	// An ephemeral run keeps its data on the tmpfs and does not hold the clone.
+	startFakeEmulator(t, "-avd", name, "-data", filepath.Join(t.TempDir(), "userdata-qemu.img"))
+	if inUse := avdInUse(env, name); inUse != nil {
+		t.Fatalf("ephemeral run reported as %v", inUse)
+	}
+	cmd := startFakeEmulator(t, "-avd", name, "-read-only")
+	inUse := avdInUse(env, name)
+	if inUse == nil || inUse.PID != cmd.Process.Pid {
+		t.Fatalf("avdInUse = %v, want pid %d", inUse, cmd.Process.Pid)
+	}
+	if !strings.Contains(inUse.Error(), "already running") {
+		t.Fatalf("unexpected message %q", inUse.Error())
+	}
+}
+
+func TestAVDInUseByLock(t *testing.T) {
+	env := newTestEnv(t)
+	makeBaseAVD(t, env, "w-locked")
+	lock := filepath.Join(env.AVDHome, "w-locked.avd", "hardware-qemu.ini.lock")
+	if err := os.MkdirAll(lock, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	cmd := startFakeEmulator(t)
+	if err := os.WriteFile(filepath.Join(lock, "pid"), []byte(strconv.Itoa(cmd.Process.Pid)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	inUse := avdInUse(env, "w-locked")
+	if inUse == nil || inUse.Lock != lock || inUse.PID != cmd.Process.Pid {
+		t.Fatalf("avdInUse = %+v, want the lock held by %d", inUse, cmd.Process.Pid)
+	}
+
+	// A lock left behind by a crashed emulator is stale.
+	_ = cmd.Process.Kill()
+	_ = cmd.Wait()
+	if inUse := avdInUse(env, "w-locked"); inUse != nil {
+		t.Fatalf("stale lock reported as %v", inUse)
+	}
+	// avdctl's own flock files hold no pid.
+	if err := os.WriteFile(filepath.Join(env.AVDHome, "w-locked.avd", "avdctl-port.lock"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if inUse := avdInUse(env, "w-locked"); inUse != nil {
+		t.Fatalf("empty lock reported as %v", inUse)
+	}
+}
+
+func TestStartRefusesAVDInUse(t *testing.T) {
+	env := newTestEnv(t)
+	makeBaseAVD(t, env, "w-locked")
+	lock := filepath.Join(env.AVDHome, "w-locked.avd", "userdata-qemu.img.lock")
+	if err := os.WriteFile(lock, []byte(strconv.Itoa(startFakeEmulator(t).Process.Pid)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := StartEmulatorOnPort(env, "w-locked", 5590)
+	var inUse *AVDInUseError
+	if !errors.As(err, &inUse) || inUse.Name != "w-locked" {
+		t.Fatalf("expected an AVDInUseError, got %v", err)
+	}
+}
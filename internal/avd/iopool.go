@@ -0,0 +1,93 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultIOParallelism is how many image conversions or copies run at once when
+// Env.IOParallelism is not set.
+const DefaultIOParallelism = 2
+
+func (env Env) ioParallelism() int {
+	if env.IOParallelism > 0 {
+		return env.IOParallelism
+	}
+	return DefaultIOParallelism
+}
+
+// runIOPool runs task(0..n-1) on at most env.ioParallelism() workers, each at Env.IOPriority,
+// and returns the errors of the failed tasks, in task order. Tasks not yet started when
+// env.Context is done fail with the context error.
+func runIOPool(env Env, n int, task func(i int) error) error {
+	ctx := spanContext(env)
+	errs := make([]error, n)
+	sem := make(chan struct{}, env.ioParallelism())
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			errs[i] = ctx.Err()
+			continue
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := enterBackgroundIO(env); err != nil {
+				errs[i] = err
+				return
+			}
+			errs[i] = task(i)
+		}(i)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// qemuImgConvert converts src to a raw image at dst, limited to env.IOBandwidth and run in
+// Env.IOCgroup.
+func qemuImgConvert(env Env, src, dst string) error {
+	args := []string{"convert", "-O", "raw"}
+	if env.IOBandwidth > 0 {
+		args = append(args, "-r", strconv.FormatInt(env.IOBandwidth, 10))
+	}
+	args = append(args, src, dst)
+	var buf bytes.Buffer
+	if err := runBackgroundCommand(env, &buf, commandStderrWriter(env, env.QemuImg, args, &buf), env.QemuImg, args...); err != nil {
+		return fmt.Errorf("%s %v failed: %v\n%s", env.QemuImg, args, err, buf.String())
+	}
+	return nil
+}
+
+// ParseByteRate parses a bandwidth such as "200M", "1.5G", "512K" or "1048576", optionally
+// followed by "B" and/or "/s". Suffixes are powers of 1024.
+func ParseByteRate(s string) (int64, error) {
+	v := strings.ToUpper(strings.TrimSpace(s))
+	v = strings.TrimSuffix(strings.TrimSuffix(v, "/S"), "B")
+	mult := int64(1)
+	switch {
+	case strings.HasSuffix(v, "K"):
+		mult = 1 << 10
+	case strings.HasSuffix(v, "M"):
+		mult = 1 << 20
+	case strings.HasSuffix(v, "G"):
+		mult = 1 << 30
+	}
+	if mult > 1 {
+		v = v[:len(v)-1]
+	}
+	f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+	if err != nil || f < 0 {
+		return 0, fmt.Errorf("invalid byte rate %q (want e.g. 200M)", s)
+	}
+	return int64(f * float64(mult)), nil
+}
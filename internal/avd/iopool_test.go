@@ -0,0 +1,83 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunIOPoolBoundsConcurrency(t *testing.T) {
+	env := Env{IOParallelism: 2}
+	var active, peak atomic.Int32
+	err := runIOPool(env, 6, func(i int) error {
+		n := active.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		active.Add(-1)
+		if i == 4 {
+			return errors.New("task 4 failed")
+		}
+		return nil
+	})
+	if err == nil || err.Error() != "task 4 failed" {
+		t.Fatalf("runIOPool error = %v", err)
+	}
+	if peak.Load() != 2 {
+		t.Fatalf("peak concurrency = %d, want 2", peak.Load())
+	}
+}
+
+func TestParseByteRate(t *testing.T) {
+	for in, want := range map[string]int64{"1048576": 1 << 20, "200M": 200 << 20, "1.5G": 3 << 29, "512kb/s": 512 << 10} {
+		if got, err := ParseByteRate(in); err != nil || got != want {
+			t.Errorf("ParseByteRate(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	if _, err := ParseByteRate("fast"); err == nil {
+		t.Error("expected error for invalid rate")
+	}
+}
+
+func TestCloneMany(t *testing.T) {
+	env := newTestEnv(t)
+	env.IOParallelism = 2
+	makeBaseAVD(t, env, "base-a35")
+	golden := makeGoldenDir(t)
+	names := []string{"w-1", "w-2", "w-3"}
+	infos, err := CloneMany(env, "base-a35", golden, names, CloneOptions{})
+	if err != nil || len(infos) != 3 {
+		t.Fatalf("CloneMany = %#v, %v", infos, err)
+	}
+	fingerprint, _ := goldenFingerprint(golden)
+	for i, info := range infos {
+		if info.Name != names[i] {
+			t.Fatalf("clone %d is %s, want %s", i, info.Name, names[i])
+		}
+		if ok, err := cloneMatchesFingerprint(info.Path, fingerprint); err != nil || !ok {
+			t.Fatalf("%s is not a clone of the golden (%v)", info.Name, err)
+		}
+	}
+}
+
+func TestParseIOPriority(t *testing.T) {
+	for in, want := range map[string][2]int{"idle": {ioClassIdle, 0}, "best-effort": {ioClassBestEffort, 7}, "BE:2": {ioClassBestEffort, 2}} {
+		class, level, err := parseIOPriority(in)
+		if err != nil || class != want[0] || level != want[1] {
+			t.Fatalf("parseIOPriority(%q) = %d, %d, %v; want %v", in, class, level, err, want)
+		}
+	}
+	for _, in := range []string{"realtime", "best-effort:8", "idle:1"} {
+		if _, _, err := parseIOPriority(in); err == nil {
+			t.Fatalf("parseIOPriority(%q) succeeded", in)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// layoutFilename records, in a clone, the layout of the golden it was cloned from.
+const layoutFilename = "avdctl-layout.json"
+
+// GoldenLayout is the set of writable images SaveGolden exports and clones get copies of.
+// It is stored in the golden manifest; goldens without one use DefaultGoldenLayout.
+type GoldenLayout struct {
+	Images []LayoutImage `json:"images"`
+}
+
+// LayoutImage is one file of a GoldenLayout, relative to the AVD directory.
+type LayoutImage struct {
+	Name string `json:"name"` // e.g. "userdata-qemu.img" or "vendor-qemu.img"
+	// CopyAsIs copies the file unchanged instead of converting it (or its qcow2 overlay) to a
+	// raw image with qemu-img; for files that are not disk images, e.g. snapshot data.
+	CopyAsIs bool `json:"copy_as_is,omitempty"`
+	// Fsck marks the ext4 image checked by SaveGoldenOptions.Fsck.
+	Fsck bool `json:"fsck,omitempty"`
+	// EmulatorFlag points the emulator at another copy of the image (ephemeral runs), e.g. "-data".
+	EmulatorFlag string `json:"emulator_flag,omitempty"`
+}
+
+// DefaultGoldenLayout is the image set of goldens written before layouts were recorded.
+func DefaultGoldenLayout() GoldenLayout {
+	return GoldenLayout{Images: []LayoutImage{
+		{Name: "userdata-qemu.img", Fsck: true, EmulatorFlag: "-data"},
+		{Name: "encryptionkey.img", EmulatorFlag: "-encryption-key"},
+		{Name: "cache.img", EmulatorFlag: "-cache"},
+		{Name: "sdcard.img", EmulatorFlag: "-sdcard"},
+	}}
+}
+
+// Names returns the image names of l, in order.
+func (l GoldenLayout) Names() []string {
+	names := make([]string, len(l.Images))
+	for i, img := range l.Images {
+		names[i] = img.Name
+	}
+	return names
+}
+
+// Validate checks that l names at least one image, each once, inside the AVD directory.
+func (l GoldenLayout) Validate() error {
+	if len(l.Images) == 0 {
+		return fmt.Errorf("golden layout has no images")
+	}
+	seen := map[string]bool{}
+	for _, img := range l.Images {
+		name := filepath.Clean(img.Name)
+		if img.Name == "" || filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("golden layout image %q must be a path inside the AVD directory", img.Name)
+		}
+		if name == "config.ini" || name == goldenManifestFilename {
+			return fmt.Errorf("golden layout image %q is reserved", img.Name)
+		}
+		if seen[name] {
+			return fmt.Errorf("golden layout lists %s twice", img.Name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+// LoadGoldenLayout reads a layout from a JSON file, or from ref itself when it is inline JSON
+// (starts with "{").
+func LoadGoldenLayout(ref string) (GoldenLayout, error) {
+	b := []byte(ref)
+	if !strings.HasPrefix(strings.TrimSpace(ref), "{") {
+		var err error
+		if b, err = os.ReadFile(ref); err != nil {
+			return GoldenLayout{}, fmt.Errorf("read golden layout: %w", err)
+		}
+	}
+	var layout GoldenLayout
+	if err := json.Unmarshal(b, &layout); err != nil {
+		return GoldenLayout{}, fmt.Errorf("parse golden layout: %w", err)
+	}
+	if err := layout.Validate(); err != nil {
+		return GoldenLayout{}, err
+	}
+	return layout, nil
+}
+
+// saveLayout returns the layout SaveGolden uses: override, else Env.GoldenLayoutFile, else
+// DefaultGoldenLayout.
+func saveLayout(env Env, override *GoldenLayout) (GoldenLayout, error) {
+	if override != nil {
+		return *override, override.Validate()
+	}
+	if env.GoldenLayoutFile != "" {
+		return LoadGoldenLayout(env.GoldenLayoutFile)
+	}
+	return DefaultGoldenLayout(), nil
+}
+
+// goldenLayout returns the layout recorded in goldenDir's manifest, or DefaultGoldenLayout.
+func goldenLayout(goldenDir string) GoldenLayout {
+	if manifest, err := ReadGoldenManifest(goldenDir); err == nil && manifest.Layout != nil {
+		return *manifest.Layout
+	}
+	return DefaultGoldenLayout()
+}
+
+// cloneLayout returns the layout a clone was created or last reset with, or DefaultGoldenLayout.
+func cloneLayout(cloneDir string) GoldenLayout {
+	b, err := os.ReadFile(filepath.Join(cloneDir, layoutFilename))
+	if err != nil {
+		return DefaultGoldenLayout()
+	}
+	var layout GoldenLayout
+	if err := json.Unmarshal(b, &layout); err != nil || len(layout.Images) == 0 {
+		return DefaultGoldenLayout()
+	}
+	return layout
+}
+
+func writeCloneLayout(cloneDir string, layout GoldenLayout) error {
+	b, err := json.MarshalIndent(layout, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(cloneDir, layoutFilename), append(b, '\n'), 0o644); err != nil {
+		return fmt.Errorf("write clone layout: %w", err)
+	}
+	return nil
+}
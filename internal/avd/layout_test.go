@@ -0,0 +1,78 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCustomLayoutSavedAndCloned(t *testing.T) {
+	env := newFsckTestEnv(t, 0)
+	baseDir := filepath.Join(env.AVDHome, "demo.avd")
+	if err := os.WriteFile(filepath.Join(baseDir, "vendor-qemu.img"), []byte("vendor"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(baseDir, "data"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(baseDir, "data", "state.bin"), []byte("state"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	layout := GoldenLayout{Images: []LayoutImage{
+		{Name: "userdata-qemu.img", Fsck: true, EmulatorFlag: "-data"},
+		{Name: "vendor-qemu.img"},
+		{Name: "data/state.bin", CopyAsIs: true},
+	}}
+	golden := filepath.Join(t.TempDir(), "golden")
+	if _, _, err := SaveGoldenWithOptions(env, "demo", golden, SaveGoldenOptions{Layout: &layout}); err != nil {
+		t.Fatalf("SaveGoldenWithOptions: %v", err)
+	}
+	manifest, err := ReadGoldenManifest(golden)
+	if err != nil || manifest.Layout == nil || len(manifest.Layout.Images) != 3 || len(manifest.Images) != 3 {
+		t.Fatalf("manifest = %#v, %v", manifest, err)
+	}
+
+	if _, err := CloneFromGolden(env, "demo", "w-1", golden); err != nil {
+		t.Fatalf("CloneFromGolden: %v", err)
+	}
+	cloneDir := filepath.Join(env.AVDHome, "w-1.avd")
+	for _, img := range []string{"vendor-qemu.img", "data/state.bin"} {
+		st, err := os.Lstat(filepath.Join(cloneDir, img))
+		if err != nil || st.Mode()&os.ModeSymlink != 0 {
+			t.Fatalf("clone %s = %v, %v; want a copy", img, st, err)
+		}
+	}
+	if got := cloneLayout(cloneDir); len(got.Images) != 3 || got.Images[1].Name != "vendor-qemu.img" {
+		t.Fatalf("clone layout = %#v", got)
+	}
+	if err := os.WriteFile(filepath.Join(cloneDir, "vendor-qemu.img"), []byte("changed"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(filepath.Join(baseDir, "vendor-qemu.img")); string(b) != "vendor" {
+		t.Fatalf("writing the clone changed the base: %q", b)
+	}
+}
+
+func TestGoldenLayoutValidateAndLoad(t *testing.T) {
+	for _, bad := range []GoldenLayout{
+		{},
+		{Images: []LayoutImage{{Name: "../escape.img"}}},
+		{Images: []LayoutImage{{Name: "/abs.img"}}},
+		{Images: []LayoutImage{{Name: "a.img"}, {Name: "a.img"}}},
+		{Images: []LayoutImage{{Name: "config.ini"}}},
+	} {
+		if err := bad.Validate(); err == nil {
+			t.Fatalf("Validate(%#v) accepted", bad)
+		}
+	}
+	layout, err := LoadGoldenLayout(`{"images":[{"name":"userdata-qemu.img","fsck":true}]}`)
+	if err != nil || len(layout.Images) != 1 || !layout.Images[0].Fsck {
+		t.Fatalf("LoadGoldenLayout(inline) = %#v, %v", layout, err)
+	}
+	if names := DefaultGoldenLayout().Names(); len(names) != 4 || names[0] != "userdata-qemu.img" {
+		t.Fatalf("default layout = %v", names)
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LinkMode selects how a clone gets a read-only artifact from its base AVD.
+type LinkMode string
+
+const (
+	// LinkSymlink points at the base file (the default). Copied instead on shared storage.
+	LinkSymlink LinkMode = "symlink"
+	// LinkHardlink shares the inode with the base, so the clone survives the base being
+	// deleted. Falls back to a copy when the base is on another filesystem.
+	LinkHardlink LinkMode = "hardlink"
+	// LinkCopy gives the clone its own copy, so it can be moved independently of the base.
+	LinkCopy LinkMode = "copy"
+)
+
+// ParseLinkMode parses a --link value; empty means no preference.
+func ParseLinkMode(s string) (LinkMode, error) {
+	switch mode := LinkMode(strings.TrimSpace(s)); mode {
+	case "", LinkSymlink, LinkHardlink, LinkCopy:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid link mode %q (want symlink, hardlink or copy)", s)
+	}
+}
+
+// LinkRule applies Mode to base artifacts whose path relative to the base directory, or whose
+// file name, matches Pattern (filepath.Match syntax).
+type LinkRule struct {
+	Pattern string   `json:"pattern"`
+	Mode    LinkMode `json:"mode"`
+}
+
+// ParseLinkRule parses PATTERN=MODE, e.g. "kernel-ranchu=copy".
+func ParseLinkRule(s string) (LinkRule, error) {
+	pattern, value, ok := strings.Cut(s, "=")
+	if !ok || strings.TrimSpace(pattern) == "" {
+		return LinkRule{}, fmt.Errorf("invalid link rule %q (want PATTERN=MODE)", s)
+	}
+	mode, err := ParseLinkMode(value)
+	if err != nil {
+		return LinkRule{}, err
+	}
+	if mode == "" {
+		return LinkRule{}, fmt.Errorf("invalid link rule %q: mode is required", s)
+	}
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return LinkRule{}, fmt.Errorf("invalid link rule %q: %w", s, err)
+	}
+	return LinkRule{Pattern: strings.TrimSpace(pattern), Mode: mode}, nil
+}
+
+// LinkPolicy decides how each base artifact is materialized in a clone. The first matching
+// rule wins; otherwise Default applies (falling back to Env.CloneLinkMode, then LinkSymlink).
+type LinkPolicy struct {
+	Default LinkMode   `json:"default,omitempty"`
+	Rules   []LinkRule `json:"rules,omitempty"`
+}
+
+func (p LinkPolicy) modeFor(env Env, rel string) LinkMode {
+	for _, rule := range p.Rules {
+		if ok, _ := filepath.Match(rule.Pattern, rel); ok {
+			return rule.Mode
+		}
+		if ok, _ := filepath.Match(rule.Pattern, filepath.Base(rel)); ok {
+			return rule.Mode
+		}
+	}
+	switch {
+	case p.Default != "":
+		return p.Default
+	case env.CloneLinkMode != "":
+		return env.CloneLinkMode
+	}
+	return LinkSymlink
+}
+
+// linkArtifact materializes src at dst with mode. Symlinks become copies when the storage
+// cannot hold them; hardlinks become copies across filesystems.
+func linkArtifact(src, dst string, mode LinkMode, storage StorageInfo) (LinkMode, error) {
+	if mode == LinkSymlink && !storage.Symlinks {
+		mode = LinkCopy
+	}
+	switch mode {
+	case LinkSymlink:
+		if err := os.Symlink(src, dst); err != nil && !os.IsExist(err) {
+			return mode, err
+		}
+		return mode, nil
+	case LinkHardlink:
+		if err := os.Link(src, dst); err == nil || os.IsExist(err) {
+			return mode, nil
+		}
+		// EXDEV (other filesystem), EPERM (filesystem without hardlinks): copy instead.
+		mode = LinkCopy
+	}
+	return mode, copyFile(src, dst, 0o644)
+}
@@ -0,0 +1,91 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseLinkRule(t *testing.T) {
+	rule, err := ParseLinkRule("*.img=copy")
+	if err != nil || rule.Pattern != "*.img" || rule.Mode != LinkCopy {
+		t.Fatalf("ParseLinkRule = %#v, %v", rule, err)
+	}
+	for _, bad := range []string{"kernel-ranchu", "=copy", "kernel-ranchu=move", "kernel-ranchu=", "[=copy"} {
+		if _, err := ParseLinkRule(bad); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestLinkPolicyModeFor(t *testing.T) {
+	env := Env{CloneLinkMode: LinkHardlink}
+	policy := LinkPolicy{Rules: []LinkRule{{Pattern: "kernel-*", Mode: LinkCopy}, {Pattern: "data/*", Mode: LinkSymlink}}}
+	cases := map[string]LinkMode{
+		"kernel-ranchu":        LinkCopy,
+		"data/misc":            LinkSymlink,
+		"advancedFeatures.ini": LinkHardlink,
+		"sub/kernel-ranchu2":   LinkCopy, // matched on the file name
+	}
+	for rel, want := range cases {
+		if got := policy.modeFor(env, rel); got != want {
+			t.Fatalf("modeFor(%q) = %s, want %s", rel, got, want)
+		}
+	}
+	if got := (LinkPolicy{}).modeFor(Env{}, "x"); got != LinkSymlink {
+		t.Fatalf("default mode = %s", got)
+	}
+}
+
+func TestCloneFromGoldenAppliesLinkPolicy(t *testing.T) {
+	env := newTestEnv(t)
+	makeBaseAVD(t, env, "base-a35")
+	baseDir := filepath.Join(env.AVDHome, "base-a35.avd")
+	for _, name := range []string{"kernel-ranchu", "advancedFeatures.ini", "emu-launch-params.txt"} {
+		if err := os.WriteFile(filepath.Join(baseDir, name), []byte(name), 0o644); err != nil {
+			t.Fatalf("write base artifact: %v", err)
+		}
+	}
+	opts := CloneOptions{Links: LinkPolicy{
+		Default: LinkHardlink,
+		Rules:   []LinkRule{{Pattern: "kernel-ranchu", Mode: LinkCopy}, {Pattern: "*.txt", Mode: LinkSymlink}},
+	}}
+	if _, err := CloneFromGoldenWithOptions(env, "base-a35", "w-links", makeGoldenDir(t), opts); err != nil {
+		t.Fatalf("CloneFromGoldenWithOptions: %v", err)
+	}
+	cloneDir := filepath.Join(env.AVDHome, "w-links.avd")
+
+	stat := func(name string) (os.FileInfo, os.FileInfo) {
+		t.Helper()
+		clone, err := os.Lstat(filepath.Join(cloneDir, name))
+		if err != nil {
+			t.Fatalf("lstat clone %s: %v", name, err)
+		}
+		base, err := os.Stat(filepath.Join(baseDir, name))
+		if err != nil {
+			t.Fatalf("stat base %s: %v", name, err)
+		}
+		return clone, base
+	}
+	if clone, base := stat("kernel-ranchu"); clone.Mode()&os.ModeSymlink != 0 || os.SameFile(clone, base) {
+		t.Fatal("kernel-ranchu should be an independent copy")
+	}
+	if clone, base := stat("advancedFeatures.ini"); clone.Mode()&os.ModeSymlink != 0 || !os.SameFile(clone, base) {
+		t.Fatal("advancedFeatures.ini should be hardlinked")
+	}
+	if clone, _ := stat("emu-launch-params.txt"); clone.Mode()&os.ModeSymlink == 0 {
+		t.Fatal("emu-launch-params.txt should be symlinked")
+	}
+}
+
+func TestCloneFromGoldenRejectsInvalidEnvLinkMode(t *testing.T) {
+	env := newTestEnv(t)
+	env.CloneLinkMode = "reflink"
+	makeBaseAVD(t, env, "base-a35")
+	if _, err := CloneFromGolden(env, "base-a35", "w-bad", makeGoldenDir(t)); err == nil {
+		t.Fatal("expected invalid AVDCTL_CLONE_LINK error")
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// lastBootFilename records when avdctl last started the emulator of an AVD.
+const lastBootFilename = "avdctl-last-boot"
+
+// Kinds of AVD reported by ListWide.
+const (
+	KindBase  = "base"
+	KindClone = "clone"
+)
+
+var apiLevelRe = regexp.MustCompile(`android-(\d+)`)
+
+// ListWide is List with each entry's config (API level, ABI, device profile), whether it is a
+// base or a clone and of which golden, its last boot and whether it is running.
+func ListWide(env Env) ([]Info, error) {
+	_, span := startSpan(env, "avd.ListWide")
+	defer span.End()
+	infos, err := List(env)
+	if err != nil {
+		recordSpanError(span, err)
+		return nil, err
+	}
+	procs, err := ListRunning(env)
+	if err != nil {
+		recordSpanError(span, err)
+		return nil, err
+	}
+	running := map[string]ProcInfo{}
+	for _, p := range procs {
+		if p.Name != "" {
+			running[p.Name] = p
+		}
+	}
+	goldens := registryFingerprints(env)
+	for i := range infos {
+		describeAVD(&infos[i], goldens)
+		if p, ok := running[infos[i].Name]; ok {
+			infos[i].Running = true
+			infos[i].Booted = p.Booted
+			infos[i].Serial = p.Serial
+		}
+	}
+	span.SetAttributes(attribute.Int("avds", len(infos)), attribute.Int("running", len(running)))
+	return infos, nil
+}
+
+// describeAVD fills the config, kind and last boot of info from its .avd directory.
+func describeAVD(info *Info, goldens map[string]string) {
+	cfg, _ := os.ReadFile(filepath.Join(info.Path, "config.ini"))
+	if m := apiLevelRe.FindStringSubmatch(configValue(cfg, "image.sysdir.1") + " " + configValue(cfg, "target")); m != nil {
+		info.APILevel, _ = strconv.Atoi(m[1])
+	}
+	info.ABI = configValue(cfg, "abi.type")
+	info.Device = configValue(cfg, "hw.device.name")
+	info.Kind = KindBase
+	if fingerprint, err := os.ReadFile(filepath.Join(info.Path, cloneFingerprintFilename)); err == nil {
+		info.Kind = KindClone
+		info.Golden = goldens[strings.TrimSpace(string(fingerprint))]
+	}
+	if boot, ok := lastBoot(info.Path); ok {
+		info.LastBoot = &boot
+	}
+}
+
+// registryFingerprints maps the fingerprint of each golden in Env.GoldenDir to its directory.
+func registryFingerprints(env Env) map[string]string {
+	goldens := map[string]string{}
+	if env.GoldenDir == "" {
+		return goldens
+	}
+	entries, err := os.ReadDir(env.GoldenDir)
+	if err != nil {
+		return goldens
+	}
+	for _, e := range entries {
+		if !e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		dir := filepath.Join(env.GoldenDir, e.Name())
+		if fingerprint, err := goldenFingerprint(dir); err == nil {
+			goldens[fingerprint] = dir
+		}
+	}
+	return goldens
+}
+
+// recordLastBoot notes that the emulator of the AVD in avdDir has just been started.
+func recordLastBoot(avdDir string) {
+	_ = os.WriteFile(filepath.Join(avdDir, lastBootFilename), []byte(time.Now().UTC().Format(time.RFC3339)+"\n"), 0o644)
+}
+
+// lastBoot returns the last start recorded by avdctl, else the time the emulator last wrote
+// hardware-qemu.ini (it does so on every boot).
+func lastBoot(avdDir string) (time.Time, bool) {
+	if b, err := os.ReadFile(filepath.Join(avdDir, lastBootFilename)); err == nil {
+		if t, err := time.Parse(time.RFC3339, strings.TrimSpace(string(b))); err == nil {
+			return t, true
+		}
+	}
+	if st, err := os.Stat(filepath.Join(avdDir, "hardware-qemu.ini")); err == nil {
+		return st.ModTime().UTC(), true
+	}
+	return time.Time{}, false
+}
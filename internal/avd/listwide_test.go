@@ -0,0 +1,44 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestListWideDescribesBasesAndClones(t *testing.T) {
+	env := newTestEnv(t)
+	env.GoldenDir = t.TempDir()
+	baseDir := filepath.Join(env.AVDHome, "base-a35.avd")
+	if err := os.MkdirAll(baseDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	cfg := "abi.type=x86_64\nhw.device.name=pixel_6\nimage.sysdir.1=system-images/android-35/google_apis/x86_64/\n"
+	if err := os.WriteFile(filepath.Join(baseDir, "config.ini"), []byte(cfg), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	golden := newRegistryGolden(t, env, "v5")
+	if _, err := CloneFromGolden(env, "base-a35", "w-1", golden); err != nil {
+		t.Fatalf("CloneFromGolden: %v", err)
+	}
+	recordLastBoot(filepath.Join(env.AVDHome, "w-1.avd"))
+
+	infos, err := ListWide(env)
+	if err != nil {
+		t.Fatalf("ListWide: %v", err)
+	}
+	byName := map[string]Info{}
+	for _, info := range infos {
+		byName[info.Name] = info
+	}
+	base, clone := byName["base-a35"], byName["w-1"]
+	if base.Kind != KindBase || base.APILevel != 35 || base.ABI != "x86_64" || base.Device != "pixel_6" || base.LastBoot != nil {
+		t.Fatalf("base = %#v", base)
+	}
+	if clone.Kind != KindClone || clone.Golden != golden || clone.LastBoot == nil || clone.Running {
+		t.Fatalf("clone = %#v", clone)
+	}
+}
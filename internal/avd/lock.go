@@ -0,0 +1,131 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// lockPollInterval is how often a waiting process retries a held lock.
+var lockPollInterval = 250 * time.Millisecond
+
+// acquireFileLock takes an exclusive advisory lock (flock) on path, creating it if needed,
+// and blocks until the lock is free or ctx is done. onWait is called once if the lock is held
+// by another process. The returned func releases the lock.
+//
+// Where advisory locks are not reliable (StorageInfo.Locking false, as on NFS), the lock is
+// instead the lockfile path+".pid", created with O_EXCL (see tryLockfile).
+func acquireFileLock(ctx context.Context, path string, onWait func()) (func(), error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("create lock dir: %w", err)
+	}
+	if info, err := detectStorageFn(filepath.Dir(path)); err == nil && !info.Locking {
+		return acquireLockfile(ctx, path+".pid", onWait)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open lock %s: %w", path, err)
+	}
+	err = pollLock(ctx, path, onWait, func() (bool, error) {
+		err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return false, nil
+		}
+		return err == nil, err
+	})
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return func() {
+		_ = syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
+
+// acquireLockfile is acquireFileLock with the lockfile path, for storage without reliable
+// advisory locks.
+func acquireLockfile(ctx context.Context, path string, onWait func()) (func(), error) {
+	host, _ := os.Hostname()
+	owner := fmt.Sprintf("%s %d\n", host, os.Getpid())
+	if err := pollLock(ctx, path, onWait, func() (bool, error) { return tryLockfile(path, owner) }); err != nil {
+		return nil, err
+	}
+	return func() { _ = os.Remove(path) }, nil
+}
+
+// pollLock calls try until it takes the lock, it fails, or ctx is done.
+func pollLock(ctx context.Context, path string, onWait func(), try func() (bool, error)) error {
+	waited := false
+	for {
+		ok, err := try()
+		if err != nil {
+			return fmt.Errorf("lock %s: %w", path, err)
+		}
+		if ok {
+			return nil
+		}
+		if !waited && onWait != nil {
+			onWait()
+		}
+		waited = true
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for lock %s: %w", path, ctx.Err())
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
+
+// tryLockfile creates the lockfile path holding owner ("<host> <pid>"). A lockfile left by a
+// process of this host that has exited is stale and taken over; one of another host is only
+// ever released by its owner, or by removing it by hand.
+func tryLockfile(path, owner string) (bool, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err == nil {
+		_, werr := f.WriteString(owner)
+		if err := errors.Join(werr, f.Close()); err != nil {
+			_ = os.Remove(path)
+			return false, err
+		}
+		return true, nil
+	}
+	if !errors.Is(err, fs.ErrExist) {
+		return false, err
+	}
+	if lockfileStale(path) && os.Remove(path) == nil {
+		return tryLockfile(path, owner)
+	}
+	return false, nil
+}
+
+// lockfileStale reports whether the lockfile path names a process of this host that is gone.
+// An unreadable or half-written lockfile is not stale: its owner may still be writing it.
+func lockfileStale(path string) bool {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	host, pidText, ok := strings.Cut(strings.TrimSpace(string(b)), " ")
+	pid, err := strconv.Atoi(pidText)
+	if !ok || err != nil || pid <= 0 {
+		return false
+	}
+	if me, _ := os.Hostname(); host != me {
+		return false
+	}
+	return errors.Is(syscall.Kill(pid, syscall.Signal(0)), syscall.ESRCH)
+}
@@ -0,0 +1,75 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// goldenManifestFilename is written next to the images of a golden directory.
+// It is excluded from the golden fingerprint so rewriting it never invalidates clones.
+const goldenManifestFilename = "manifest.json"
+
+// GoldenManifest records how a golden directory was produced.
+type GoldenManifest struct {
+	Source          string        `json:"source"` // AVD the golden was exported from
+	CreatedAt       time.Time     `json:"created_at"`
+	EmulatorVersion string        `json:"emulator_version,omitempty"` // emulator installed at export, if detected
+	Images          []GoldenImage `json:"images"`
+	Fsck            *FsckResult   `json:"fsck,omitempty"`
+	// ADBKeys are the host keys preseeded into /data/misc/adb/adb_keys.
+	ADBKeys []ADBPublicKey `json:"adb_keys,omitempty"`
+	// Integrity is the integrity posture estimated while prewarming, before export.
+	Integrity *IntegrityReport `json:"integrity,omitempty"`
+	// SelfContained goldens carry base/ and system/ so they clone without the base AVD;
+	// SystemImageDir is the image.sysdir.1 the source AVD booted.
+	SelfContained  bool   `json:"self_contained,omitempty"`
+	SystemImageDir string `json:"system_image_dir,omitempty"`
+	// Customizations are the APKs and settings applied to the source AVD's userdata.
+	Customizations *Customizations `json:"customizations,omitempty"`
+	// Validation is set once a clone of the golden booted and passed the health checks.
+	Validation *GoldenValidation `json:"validation,omitempty"`
+	// Layout is the image set of the golden; nil means DefaultGoldenLayout.
+	Layout *GoldenLayout `json:"layout,omitempty"`
+}
+
+// GoldenImage is one raw image stored in a golden directory.
+type GoldenImage struct {
+	Name      string `json:"name"`
+	SizeBytes int64  `json:"size_bytes"`
+	SHA256    string `json:"sha256,omitempty"` // content hash, lets ResetClone skip unchanged images
+	// ModTime is the image's mtime (ns) when SHA256 was computed; the hash is only trusted while
+	// size and mtime both match, as an image rewritten in place keeps its size.
+	ModTime int64 `json:"mtime_ns,omitempty"`
+}
+
+// ReadGoldenManifest reads manifest.json from a golden directory.
+func ReadGoldenManifest(goldenDir string) (GoldenManifest, error) {
+	b, err := os.ReadFile(filepath.Join(goldenDir, goldenManifestFilename))
+	if err != nil {
+		return GoldenManifest{}, fmt.Errorf("read golden manifest: %w", err)
+	}
+	var manifest GoldenManifest
+	if err := json.Unmarshal(b, &manifest); err != nil {
+		return GoldenManifest{}, fmt.Errorf("parse golden manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+func writeGoldenManifest(goldenDir string, manifest GoldenManifest) error {
+	b, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(goldenDir, goldenManifestFilename)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(b, '\n'), 0o644); err != nil {
+		return fmt.Errorf("write golden manifest: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,75 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+)
+
+// CloneImage is one writable image of a golden a CloneMaterializer puts into a clone.
+type CloneImage struct {
+	Name     string                    // path relative to the golden and the clone, e.g. "userdata-qemu.img"
+	Source   string                    // the image in the golden directory
+	Dest     string                    // where the clone's emulator opens it; its directory exists
+	Progress func(copied, total int64) // optional, for CloneOptions.Progress
+}
+
+// CloneMaterializer creates the writable images of clones from a golden (Env.CloneMaterializer),
+// for storage where a plain copy is the wrong tool: an RBD clone of a snapshot, an overlayfs
+// upper directory, a storage array's own clone call. CloneFromGolden calls Materialize once per
+// layout image present in the golden, at most Env.IOParallelism at a time, after the clone's
+// config and read-only artifacts are in place; ResetClone calls it again for the images that
+// changed, so Dest may exist and must then be replaced.
+//
+// Dest must end up as a raw image the emulator can open and write, either a file or a symlink to
+// one. After Materialize, avdctl treats it as any other file of the clone: it is hashed, moved
+// to the trash and removed along with the clone, so storage that needs its own cleanup should
+// track its clones by Dest.
+type CloneMaterializer interface {
+	Materialize(ctx context.Context, img CloneImage) error
+}
+
+// CopyMaterializer copies images, reflinked where the filesystem supports it and otherwise
+// streamed with holes preserved. It is what clones use without an Env.CloneMaterializer.
+type CopyMaterializer struct {
+	Bandwidth int64 // bytes per second per image; 0 = unlimited
+}
+
+func (c CopyMaterializer) Materialize(ctx context.Context, img CloneImage) error {
+	in, err := os.Open(img.Source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(img.Dest, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if err := copyFileContents(out, in, imageCopy{ctx: ctx, rate: c.Bandwidth, progress: img.Progress}); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// cloneMaterializer returns Env.CloneMaterializer, or a CopyMaterializer limited to
+// Env.IOBandwidth.
+func (env Env) cloneMaterializer() CloneMaterializer {
+	if env.CloneMaterializer != nil {
+		return env.CloneMaterializer
+	}
+	return CopyMaterializer{Bandwidth: env.IOBandwidth}
+}
+
+// materializeImage puts the golden image name of goldenDir into cloneDir.
+func materializeImage(env Env, goldenDir, cloneDir, name string, progress func(copied, total int64)) error {
+	return env.cloneMaterializer().Materialize(spanContext(env), CloneImage{
+		Name:     name,
+		Source:   filepath.Join(goldenDir, name),
+		Dest:     filepath.Join(cloneDir, name),
+		Progress: progress,
+	})
+}
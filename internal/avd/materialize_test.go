@@ -0,0 +1,84 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// linkMaterializer stands in for storage with its own cloning: it links each image to a
+// "volume" it tracks instead of copying it.
+type linkMaterializer struct {
+	mu      sync.Mutex
+	volumes string
+	images  []string
+	fail    string
+}
+
+func (l *linkMaterializer) Materialize(_ context.Context, img CloneImage) error {
+	if img.Name == l.fail {
+		return errors.New("volume quota exceeded")
+	}
+	data, err := os.ReadFile(img.Source)
+	if err != nil {
+		return err
+	}
+	volume := filepath.Join(l.volumes, strings.ReplaceAll(img.Dest, string(filepath.Separator), "_"))
+	if err := os.WriteFile(volume, data, 0o600); err != nil {
+		return err
+	}
+	_ = os.Remove(img.Dest)
+	l.mu.Lock()
+	l.images = append(l.images, img.Name)
+	l.mu.Unlock()
+	return os.Symlink(volume, img.Dest)
+}
+
+func TestCloneMaterializer(t *testing.T) {
+	env := newTestEnv(t)
+	makeBaseAVD(t, env, "base")
+	materializer := &linkMaterializer{volumes: t.TempDir()}
+	env.CloneMaterializer = materializer
+	if _, err := CloneFromGolden(env, "base", "w-1", makeGoldenDir(t)); err != nil {
+		t.Fatalf("CloneFromGolden: %v", err)
+	}
+	if !slices.Contains(materializer.images, "userdata-qemu.img") {
+		t.Fatalf("userdata not materialized, got %q", materializer.images)
+	}
+	dst := filepath.Join(env.AVDHome, "w-1.avd", "userdata-qemu.img")
+	if st, err := os.Lstat(dst); err != nil || st.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("userdata is not the materializer's link: %v", err)
+	}
+	if data, _ := os.ReadFile(dst); string(data) != "data-0" {
+		t.Fatalf("userdata = %q", data)
+	}
+
+	materializer.fail = "userdata-qemu.img"
+	if _, err := CloneFromGolden(env, "base", "w-2", makeGoldenDir(t)); err == nil || !strings.Contains(err.Error(), "volume quota exceeded") {
+		t.Fatalf("expected the materializer's error, got %v", err)
+	}
+}
+
+func TestCopyMaterializer(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "userdata-qemu.img")
+	if err := os.WriteFile(src, []byte("golden"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var reported int64
+	img := CloneImage{Name: "userdata-qemu.img", Source: src, Dest: filepath.Join(dir, "clone.img"), Progress: func(copied, _ int64) { reported = copied }}
+	if err := (CopyMaterializer{}).Materialize(context.Background(), img); err != nil {
+		t.Fatalf("Materialize: %v", err)
+	}
+	if data, _ := os.ReadFile(img.Dest); string(data) != "golden" || reported != 6 {
+		t.Fatalf("copied %q, progress %d", data, reported)
+	}
+}
@@ -0,0 +1,330 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// MigrateOptions selects the base to migrate and the system image of the new API level.
+type MigrateOptions struct {
+	From        string        // existing base AVD, e.g. base-a34
+	Golden      string        // golden of From whose recorded customizations are re-applied (optional)
+	Name        string        // new base AVD, e.g. base-a35
+	SystemImage string        // sdkmanager package of the new base
+	Device      string        // hardware profile (default: hw.device.name of From)
+	Dest        string        // new golden directory; APKs and settings are only re-applied when set
+	BootTimeout time.Duration // boot timeout while re-applying APKs and settings (default: 3m)
+}
+
+// MigrationStep is one customization carried over (or not) by MigrateBase.
+type MigrationStep struct {
+	Kind   string `json:"kind"` // config, apk, setting, adb-key, userdata
+	Item   string `json:"item"`
+	Status string `json:"status"` // applied, skipped, failed
+	Detail string `json:"detail,omitempty"`
+}
+
+// MigrationReport lists the steps of a MigrateBase run.
+type MigrationReport struct {
+	From   string          `json:"from"`
+	Name   string          `json:"name"`
+	Golden string          `json:"golden,omitempty"` // new golden directory, when Dest was set
+	Steps  []MigrationStep `json:"steps"`
+}
+
+// Failed reports whether any step failed.
+func (r MigrationReport) Failed() bool {
+	for _, s := range r.Steps {
+		if s.Status == "failed" {
+			return true
+		}
+	}
+	return false
+}
+
+// NotCarried returns the steps that were skipped or failed.
+func (r MigrationReport) NotCarried() []MigrationStep {
+	var steps []MigrationStep
+	for _, s := range r.Steps {
+		if s.Status != "applied" {
+			steps = append(steps, s)
+		}
+	}
+	return steps
+}
+
+func (r *MigrationReport) add(kind, item, status, detail string) {
+	r.Steps = append(r.Steps, MigrationStep{Kind: kind, Item: item, Status: status, Detail: detail})
+}
+
+// avdIdentityKeys name the AVD itself and are never carried over.
+var avdIdentityKeys = map[string]bool{
+	"AvdId": true, "avd.ini.displayname": true, "avd.ini.encoding": true, "avd.id": true, "avd.name": true,
+}
+
+// isImageBoundKey reports whether a config.ini key describes the system image, so its old
+// value cannot be applied to a base on another image.
+func isImageBoundKey(key string) bool {
+	switch key {
+	case "abi.type", "hw.cpu.arch", "target", "PlayStore.enabled":
+		return true
+	}
+	return strings.HasPrefix(key, "image.sysdir.") || strings.HasPrefix(key, "tag.")
+}
+
+// MigrateBase creates base opts.Name on opts.SystemImage and re-applies what was recorded for
+// opts.From: its config.ini values, and the APKs and settings recorded for it and its golden.
+// Config values that differ from the new base's defaults are treated as overrides. APKs and
+// settings need a boot, so they are only re-applied when opts.Dest is set; the new base is then
+// exported there as a golden. Steps that could not be carried over are reported, not returned
+// as errors; the returned error is for failures that leave no usable base.
+func MigrateBase(env Env, opts MigrateOptions) (MigrationReport, error) {
+	_, span := startSpan(env, "avd.MigrateBase",
+		attribute.String("from", opts.From),
+		attribute.String("name", opts.Name),
+		attribute.String("system_image", opts.SystemImage),
+	)
+	defer span.End()
+	report := MigrationReport{From: opts.From, Name: opts.Name}
+	fail := func(err error) (MigrationReport, error) {
+		recordSpanError(span, err)
+		return report, err
+	}
+	if opts.From == "" || opts.Name == "" || opts.SystemImage == "" {
+		return fail(errors.New("from, name and system image are required"))
+	}
+	if opts.From == opts.Name {
+		return fail(errors.New("the new base must have a different name"))
+	}
+	if opts.BootTimeout == 0 {
+		opts.BootTimeout = 3 * time.Minute
+	}
+	fromDir := filepath.Join(env.AVDHome, opts.From+".avd")
+	oldCfg, err := os.ReadFile(filepath.Join(fromDir, "config.ini"))
+	if err != nil {
+		return fail(fmt.Errorf("read config of %s: %w", opts.From, err))
+	}
+	if _, err := os.Stat(filepath.Join(env.AVDHome, opts.Name+".avd")); err == nil {
+		return fail(fmt.Errorf("AVD %s already exists", opts.Name))
+	}
+	custom, err := readCustomizations(fromDir)
+	if err != nil {
+		return fail(err)
+	}
+	var oldManifest GoldenManifest
+	if opts.Golden != "" {
+		if oldManifest, err = ReadGoldenManifest(opts.Golden); err != nil {
+			return fail(err)
+		}
+		if oldManifest.Customizations != nil {
+			custom = custom.merge(*oldManifest.Customizations)
+		}
+	}
+	device := opts.Device
+	if device == "" {
+		if device = configValue(oldCfg, "hw.device.name"); device == "" {
+			return fail(fmt.Errorf("config of %s has no hw.device.name; a device is required", opts.From))
+		}
+	}
+	logEvent(env, "avd migration start", "from", opts.From, "name", opts.Name, "system_image", opts.SystemImage)
+
+	if _, err := InitBase(env, opts.Name, opts.SystemImage, device); err != nil {
+		return fail(err)
+	}
+	newDir := filepath.Join(env.AVDHome, opts.Name+".avd")
+	if err := migrateConfig(&report, oldCfg, filepath.Join(newDir, "config.ini")); err != nil {
+		return fail(err)
+	}
+	if opts.Golden != "" {
+		report.add("userdata", opts.Golden, "skipped",
+			"app data, accounts and manual changes in the golden are not portable across API levels")
+	}
+
+	keys, _ := ReadADBPublicKeys(env.ADBKeyFiles)
+	available := map[string]bool{}
+	for _, key := range keys {
+		available[key.Fingerprint] = true
+	}
+	if opts.Dest == "" {
+		for _, apk := range custom.APKs {
+			report.add("apk", apk, "skipped", "no destination golden to install into")
+		}
+		for _, obb := range custom.OBBs {
+			report.add("obb", obb, "skipped", "no destination golden to push to")
+		}
+		for _, data := range custom.AppData {
+			report.add("app-data", data.Package+"="+data.Dir, "skipped", "no destination golden to seed")
+		}
+		for _, setting := range custom.Settings {
+			report.add("setting", setting, "skipped", "no destination golden to apply to")
+		}
+		for _, key := range oldManifest.ADBKeys {
+			report.add("adb-key", key.Fingerprint, "skipped", "no destination golden to preseed")
+		}
+		logEvent(env, "avd migration finished", "from", opts.From, "name", opts.Name, "steps", len(report.Steps))
+		return report, nil
+	}
+	for _, key := range oldManifest.ADBKeys {
+		if !available[key.Fingerprint] {
+			report.add("adb-key", key.Fingerprint, "skipped", "public key not available; list it in AVDCTL_ADB_KEYS")
+		}
+	}
+
+	goldenDir, err := reapplyCustomizations(env, &report, opts, custom, keys)
+	if err != nil {
+		return fail(err)
+	}
+	report.Golden = goldenDir
+	logEvent(env, "avd migration finished", "from", opts.From, "name", opts.Name, "golden", goldenDir, "steps", len(report.Steps))
+	return report, nil
+}
+
+// migrateConfig copies the values of oldCfg that the new config lacks or sets differently.
+func migrateConfig(report *MigrationReport, oldCfg []byte, cfgPath string) error {
+	newCfg, err := os.ReadFile(cfgPath)
+	if err != nil {
+		return fmt.Errorf("read new config: %w", err)
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(oldCfg))
+	for scanner.Scan() {
+		k, v, ok := strings.Cut(scanner.Text(), "=")
+		key, value := strings.TrimSpace(k), strings.TrimSpace(v)
+		if !ok || key == "" || strings.HasPrefix(key, "#") || avdIdentityKeys[key] {
+			continue
+		}
+		current := configValue(newCfg, key)
+		if current == value {
+			continue
+		}
+		if isImageBoundKey(key) {
+			report.add("config", key+"="+value, "skipped", fmt.Sprintf("tied to the system image (new base: %s)", current))
+			continue
+		}
+		newCfg = setConfigValue(newCfg, key, value)
+		detail := ""
+		if current != "" {
+			detail = "was " + current
+		}
+		report.add("config", key+"="+value, "applied", detail)
+	}
+	if err := os.WriteFile(cfgPath, newCfg, 0o644); err != nil {
+		return fmt.Errorf("write new config: %w", err)
+	}
+	return nil
+}
+
+// reapplyCustomizations boots the new base, installs the APKs, runs the settings, preseeds
+// the ADB keys and exports it to opts.Dest. A step that fails is reported and skipped.
+func reapplyCustomizations(env Env, report *MigrationReport, opts MigrateOptions, custom Customizations, keys []ADBPublicKey) (string, error) {
+	ensureADB(env)
+	port, err := FindFreeEvenPortWithEnv(env, 5580, 5800)
+	if err != nil {
+		return "", fmt.Errorf("no free port available for migration: %w", err)
+	}
+	res, err := StartEmulatorOnPort(env, opts.Name, port)
+	if err != nil {
+		return "", err
+	}
+	serial, logPath := res.Serial, res.LogPath
+	defer func() { _ = res.Cmd.Process.Kill() }()
+	if err := waitForEmulatorSerial(env, serial, 60*time.Second); err != nil {
+		return "", fmt.Errorf("ADB failed to detect emulator serial %s: %w\nEmulator log: %s", serial, err, logPath)
+	}
+	if err := WaitForBoot(env, serial, opts.BootTimeout); err != nil {
+		return "", fmt.Errorf("%w\nEmulator log: %s", err, logPath)
+	}
+	completeSetup(env, serial)
+
+	var applied Customizations
+	spec := func() (DeviceSpec, error) { return DeviceSpecForAVD(env, opts.Name) }
+	for _, apk := range custom.APKs {
+		if !fileExists(apk) {
+			report.add("apk", apk, "skipped", "APK file not found")
+			continue
+		}
+		// Bundles are split again for the new image.
+		set, err := prepareAPKSet(env, apk, spec)
+		if err == nil {
+			err = set.install(env, serial)
+			set.cleanup()
+		}
+		if err != nil {
+			// e.g. INSTALL_FAILED_OLDER_SDK / INSTALL_FAILED_NO_MATCHING_ABIS on the new image
+			report.add("apk", apk, "failed", err.Error())
+			continue
+		}
+		report.add("apk", apk, "applied", "")
+		applied.APKs = append(applied.APKs, apk)
+		if pkg, ok := custom.packageOf(apk); ok {
+			applied.Packages = append(applied.Packages, pkg)
+		}
+	}
+	for _, obb := range custom.OBBs {
+		if !fileExists(obb) {
+			report.add("obb", obb, "skipped", "OBB file not found")
+			continue
+		}
+		if err := PushOBB(env, serial, obb); err != nil {
+			report.add("obb", obb, "failed", err.Error())
+			continue
+		}
+		report.add("obb", obb, "applied", "")
+		applied.OBBs = append(applied.OBBs, obb)
+	}
+	for _, data := range custom.AppData {
+		if err := PushAppData(env, serial, data); err != nil {
+			report.add("app-data", data.Package+"="+data.Dir, "failed", err.Error())
+			continue
+		}
+		report.add("app-data", data.Package+"="+data.Dir, "applied", "")
+		applied.AppData = append(applied.AppData, data)
+	}
+	for _, setting := range custom.Settings {
+		if err := applySetting(env, serial, setting); err != nil {
+			report.add("setting", setting, "failed", err.Error())
+			continue
+		}
+		report.add("setting", setting, "applied", "")
+		applied.Settings = append(applied.Settings, setting)
+	}
+	if len(keys) > 0 {
+		if err := PreseedADBKeys(env, serial, keys); err != nil {
+			report.add("adb-key", strings.Join(env.ADBKeyFiles, ","), "failed", err.Error())
+			keys = nil
+		}
+		for _, key := range keys {
+			report.add("adb-key", key.Fingerprint, "applied", "")
+		}
+	}
+	KillEmulator(env, serial)
+
+	if err := recordCustomizations(filepath.Join(env.AVDHome, opts.Name+".avd"), applied); err != nil {
+		return "", err
+	}
+	goldenDir, _, err := SaveGolden(env, opts.Name, opts.Dest)
+	if err != nil {
+		return "", err
+	}
+	if len(keys) > 0 {
+		manifest, err := ReadGoldenManifest(goldenDir)
+		if err != nil {
+			return "", err
+		}
+		manifest.ADBKeys = keys
+		if err := writeGoldenManifest(goldenDir, manifest); err != nil {
+			return "", err
+		}
+	}
+	return goldenDir, nil
+}
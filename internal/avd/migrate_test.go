@@ -0,0 +1,140 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newMigrateTestEnv(t *testing.T) (Env, string) {
+	t.Helper()
+	env := newTestEnv(t)
+	env.SDKRoot = t.TempDir()
+	if err := os.MkdirAll(filepath.Join(env.SDKRoot, "system-images", "android-35", "google_apis", "x86_64"), 0o755); err != nil {
+		t.Fatalf("mkdir system image: %v", err)
+	}
+	logPath := filepath.Join(t.TempDir(), "avdmanager.log")
+	env.AvdMgr = filepath.Join(t.TempDir(), "avdmanager")
+	// avdmanager create avd -n NAME -k IMAGE -d DEVICE --force
+	script := `#!/bin/sh
+echo "$@" >> ` + logPath + `
+mkdir -p ` + env.AVDHome + `/$4.avd
+printf 'AvdId=%s\nhw.device.name=%s\nhw.ramSize=2048\nimage.sysdir.1=system-images/android-35/google_apis/x86_64/\n' "$4" "$8" > ` + env.AVDHome + `/$4.avd/config.ini
+`
+	if err := os.WriteFile(env.AvdMgr, []byte(script), 0o755); err != nil {
+		t.Fatalf("write avdmanager stub: %v", err)
+	}
+	makeBaseAVD(t, env, "base-a34")
+	cfg := "AvdId=base-a34\nhw.device.name=pixel_6\nhw.ramSize=4096\nhw.keyboard=yes\n" +
+		"image.sysdir.1=system-images/android-34/google_apis/x86_64/\n"
+	if err := os.WriteFile(filepath.Join(env.AVDHome, "base-a34.avd", "config.ini"), []byte(cfg), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	return env, logPath
+}
+
+func TestMigrateBaseCarriesConfigOverrides(t *testing.T) {
+	env, logPath := newMigrateTestEnv(t)
+	custom := Customizations{APKs: []string{"/apks/wallet.apk"}, Settings: []string{"settings put global window_animation_scale 0"}}
+	if err := recordCustomizations(filepath.Join(env.AVDHome, "base-a34.avd"), custom); err != nil {
+		t.Fatalf("recordCustomizations: %v", err)
+	}
+
+	report, err := MigrateBase(env, MigrateOptions{
+		From:        "base-a34",
+		Name:        "base-a35",
+		SystemImage: "system-images;android-35;google_apis;x86_64",
+	})
+	if err != nil {
+		t.Fatalf("MigrateBase: %v", err)
+	}
+	calls, _ := os.ReadFile(logPath)
+	if !strings.Contains(string(calls), "-n base-a35 -k system-images;android-35;google_apis;x86_64 -d pixel_6") {
+		t.Fatalf("unexpected avdmanager call: %s", calls)
+	}
+	cfg, err := os.ReadFile(filepath.Join(env.AVDHome, "base-a35.avd", "config.ini"))
+	if err != nil {
+		t.Fatalf("read new config: %v", err)
+	}
+	want := map[string]string{
+		"AvdId":          "base-a35",
+		"hw.ramSize":     "4096",
+		"hw.keyboard":    "yes",
+		"image.sysdir.1": "system-images/android-35/google_apis/x86_64/",
+	}
+	for key, value := range want {
+		if got := configValue(cfg, key); got != value {
+			t.Fatalf("%s = %q, want %q", key, got, value)
+		}
+	}
+
+	wantSteps := []MigrationStep{
+		{Kind: "config", Item: "hw.ramSize=4096", Status: "applied", Detail: "was 2048"},
+		{Kind: "config", Item: "hw.keyboard=yes", Status: "applied"},
+		{Kind: "config", Item: "image.sysdir.1=system-images/android-34/google_apis/x86_64/", Status: "skipped",
+			Detail: "tied to the system image (new base: system-images/android-35/google_apis/x86_64/)"},
+		{Kind: "apk", Item: "/apks/wallet.apk", Status: "skipped", Detail: "no destination golden to install into"},
+		{Kind: "setting", Item: "settings put global window_animation_scale 0", Status: "skipped", Detail: "no destination golden to apply to"},
+	}
+	if !reflect.DeepEqual(report.Steps, wantSteps) {
+		t.Fatalf("steps = %#v\nwant %#v", report.Steps, wantSteps)
+	}
+	if report.Failed() || len(report.NotCarried()) != 3 {
+		t.Fatalf("unexpected report status: %#v", report)
+	}
+}
+
+func TestMigrateBaseRefusesExistingTarget(t *testing.T) {
+	env, _ := newMigrateTestEnv(t)
+	makeBaseAVD(t, env, "base-a35")
+	_, err := MigrateBase(env, MigrateOptions{From: "base-a34", Name: "base-a35", SystemImage: "system-images;android-35;google_apis;x86_64"})
+	if err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("expected existing AVD error, got %v", err)
+	}
+}
+
+func TestCustomizationsFlowThroughGoldenToClone(t *testing.T) {
+	env := newFsckTestEnv(t, 0)
+	baseDir := filepath.Join(env.AVDHome, "demo.avd")
+	if err := recordCustomizations(baseDir, Customizations{APKs: []string{"/apks/a.apk"}}); err != nil {
+		t.Fatalf("recordCustomizations: %v", err)
+	}
+	if err := recordCustomizations(baseDir, Customizations{APKs: []string{"/apks/a.apk", "/apks/b.apk"}, Settings: []string{"true"}}); err != nil {
+		t.Fatalf("recordCustomizations: %v", err)
+	}
+	want := Customizations{APKs: []string{"/apks/a.apk", "/apks/b.apk"}, Settings: []string{"true"}}
+
+	golden := filepath.Join(t.TempDir(), "golden")
+	if _, _, err := SaveGolden(env, "demo", golden); err != nil {
+		t.Fatalf("SaveGolden: %v", err)
+	}
+	manifest, err := ReadGoldenManifest(golden)
+	if err != nil {
+		t.Fatalf("ReadGoldenManifest: %v", err)
+	}
+	if manifest.Customizations == nil || !reflect.DeepEqual(*manifest.Customizations, want) {
+		t.Fatalf("manifest customizations = %#v", manifest.Customizations)
+	}
+
+	if err := os.WriteFile(filepath.Join(golden, "sdcard.img"), []byte("sdcard"), 0o644); err != nil {
+		t.Fatalf("write sdcard: %v", err)
+	}
+	if _, err := CloneFromGolden(env, "demo", "w-custom", golden); err != nil {
+		t.Fatalf("CloneFromGolden: %v", err)
+	}
+	got, err := ReadCustomizations(env, "w-custom")
+	if err != nil {
+		t.Fatalf("ReadCustomizations: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("clone customizations = %#v, want %#v", got, want)
+	}
+	if st, err := os.Lstat(filepath.Join(env.AVDHome, "w-custom.avd", customizationsFilename)); err != nil || st.Mode()&os.ModeSymlink != 0 {
+		t.Fatal("clone must own its customizations record")
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DefaultNamePattern is the set of names avdmanager and the emulator accept.
+const DefaultNamePattern = `^[A-Za-z0-9][A-Za-z0-9._-]*$`
+
+// DefaultNameMaxLength keeps "<name>.avd", emulator log names and adb output readable.
+const DefaultNameMaxLength = 100
+
+// NamingPolicy constrains the names of new bases and clones.
+type NamingPolicy struct {
+	Pattern   string // regexp a name must match (AVDCTL_NAME_PATTERN, default DefaultNamePattern)
+	MaxLength int    // AVDCTL_NAME_MAX_LENGTH (default DefaultNameMaxLength)
+	Prefix    string // required prefix, e.g. "w-" (AVDCTL_NAME_PREFIX, optional)
+}
+
+// InvalidNameError is returned when a name breaks the naming policy.
+type InvalidNameError struct {
+	Name   string
+	Reason string
+}
+
+func (e *InvalidNameError) Error() string {
+	return fmt.Sprintf("invalid AVD name %q: %s", e.Name, e.Reason)
+}
+
+func (p NamingPolicy) pattern() (*regexp.Regexp, error) {
+	pattern := p.Pattern
+	if pattern == "" {
+		pattern = DefaultNamePattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid naming pattern %q: %w", pattern, err)
+	}
+	return re, nil
+}
+
+func (p NamingPolicy) maxLength() int {
+	if p.MaxLength > 0 {
+		return p.MaxLength
+	}
+	return DefaultNameMaxLength
+}
+
+// ValidateName checks name against env.Naming. Policy violations are *InvalidNameError.
+func ValidateName(env Env, name string) error {
+	policy := env.Naming
+	re, err := policy.pattern()
+	if err != nil {
+		return err
+	}
+	switch {
+	case name == "":
+		return &InvalidNameError{Name: name, Reason: "name is empty"}
+	case len(name) > policy.maxLength():
+		return &InvalidNameError{Name: name, Reason: fmt.Sprintf("longer than %d characters", policy.maxLength())}
+	case !strings.HasPrefix(name, policy.Prefix):
+		return &InvalidNameError{Name: name, Reason: fmt.Sprintf("must start with %q", policy.Prefix)}
+	case !re.MatchString(name):
+		return &InvalidNameError{Name: name, Reason: fmt.Sprintf("must match %s", re)}
+	}
+	return nil
+}
+
+// slugFold maps common accented letters to ASCII before slugging.
+var slugFold = strings.NewReplacer(
+	"à", "a", "á", "a", "â", "a", "ã", "a", "ä", "a", "å", "a", "æ", "ae",
+	"ç", "c", "è", "e", "é", "e", "ê", "e", "ë", "e",
+	"ì", "i", "í", "i", "î", "i", "ï", "i", "ñ", "n",
+	"ò", "o", "ó", "o", "ô", "o", "õ", "o", "ö", "o", "ø", "o", "œ", "oe",
+	"ù", "u", "ú", "u", "û", "u", "ü", "u", "ý", "y", "ÿ", "y", "ß", "ss",
+)
+
+// SlugName turns an arbitrary string (e.g. a customer name) into a name that satisfies
+// env.Naming: lower-cased, accents folded, other characters collapsed to "-", prefixed and cut
+// to the maximum length. It fails when no such name exists, e.g. for a custom pattern the slug
+// cannot match.
+func SlugName(env Env, s string) (string, error) {
+	policy := env.Naming
+	var b strings.Builder
+	dash := false
+	for _, r := range slugFold.Replace(strings.ToLower(s)) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	slug := strings.TrimSuffix(b.String(), "-")
+	if slug == "" {
+		return "", &InvalidNameError{Name: s, Reason: "no letters or digits to build a name from"}
+	}
+	if !strings.HasPrefix(slug, policy.Prefix) {
+		slug = policy.Prefix + slug
+	}
+	if len(slug) > policy.maxLength() {
+		slug = strings.TrimRight(slug[:policy.maxLength()], "-")
+	}
+	if err := ValidateName(env, slug); err != nil {
+		return "", err
+	}
+	return slug, nil
+}
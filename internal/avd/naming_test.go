@@ -0,0 +1,56 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateName(t *testing.T) {
+	env := Env{Naming: NamingPolicy{Prefix: "w-", MaxLength: 12}}
+	if err := ValidateName(env, "w-acme_1.2"); err != nil {
+		t.Fatalf("ValidateName(valid) = %v", err)
+	}
+	for _, name := range []string{"", "acme", "w-acme corp", "w-" + strings.Repeat("x", 12)} {
+		var nameErr *InvalidNameError
+		if err := ValidateName(env, name); !errors.As(err, &nameErr) || nameErr.Name != name {
+			t.Fatalf("ValidateName(%q) = %v, want *InvalidNameError", name, err)
+		}
+	}
+	if err := ValidateName(Env{Naming: NamingPolicy{Pattern: "("}}, "w-1"); err == nil || !strings.Contains(err.Error(), "naming pattern") {
+		t.Fatalf("ValidateName with broken pattern = %v", err)
+	}
+}
+
+func TestSlugName(t *testing.T) {
+	env := Env{Naming: NamingPolicy{Prefix: "w-", MaxLength: 20}}
+	for in, want := range map[string]string{
+		"Müller & Söhne GmbH":        "w-muller-sohne-gmbh",
+		"  ACME Corp. (EU)  ":        "w-acme-corp-eu",
+		"w-already":                  "w-already",
+		"Café de la Paix, Paris 9e!": "w-cafe-de-la-paix-pa",
+	} {
+		got, err := SlugName(env, in)
+		if err != nil || got != want {
+			t.Fatalf("SlugName(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := SlugName(env, "!!!"); err == nil {
+		t.Fatal("SlugName accepted text without letters or digits")
+	}
+}
+
+func TestCloneRejectsInvalidName(t *testing.T) {
+	env := newTestEnv(t)
+	makeBaseAVD(t, env, "base")
+	var nameErr *InvalidNameError
+	if _, err := CloneFromGolden(env, "base", "bad name", makeGoldenDir(t)); !errors.As(err, &nameErr) {
+		t.Fatalf("CloneFromGolden(bad name) = %v, want *InvalidNameError", err)
+	}
+	if _, err := InitBase(env, "bad/name", "system-images;android-35;google_apis;x86_64", "pixel_6"); !errors.As(err, &nameErr) {
+		t.Fatalf("InitBase(bad/name) = %v, want *InvalidNameError", err)
+	}
+}
@@ -0,0 +1,236 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// DefaultCloneNetnsDNS is the resolver of clone network namespaces when Env.CloneNetnsDNS is
+// unset. Host resolvers on loopback or private addresses are out of reach from a namespace.
+const DefaultCloneNetnsDNS = "1.1.1.1"
+
+// netnsEtcDir holds the per-namespace files `ip netns exec` bind-mounts over /etc.
+var netnsEtcDir = "/etc/netns"
+
+// netnsBlockedRanges are the destinations clone namespaces cannot reach: other clones, the
+// host's internal networks and link-local (cloud metadata) services.
+var netnsBlockedRanges = []string{"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "100.64.0.0/10", "169.254.0.0/16"}
+
+// cloneNetwork is the network namespace of the instance on one console port: a veth pair
+// whose host end NATs egress, with the console and adb ports mapped from host loopback to the
+// namespace's loopback.
+type cloneNetwork struct {
+	Port   int
+	NS     string // namespace name
+	HostIf string // host end of the veth pair
+	NSIf   string // namespace end
+	HostIP string
+	NSIP   string
+	DNS    string   // resolver of the namespace
+	Allow  []string // CIDRs of NetworkPolicy.Allow
+	Deny   []string // CIDRs of NetworkPolicy.Deny
+}
+
+// newCloneNetwork lays out the namespace of port: 10.213.N.0/30 with N the port's slot.
+func newCloneNetwork(port int) (cloneNetwork, error) {
+	slot := (port - 5554) / 2
+	if port%2 != 0 || slot < 0 || slot > 255 {
+		return cloneNetwork{}, fmt.Errorf("no network namespace slot for port %d", port)
+	}
+	p := strconv.Itoa(port)
+	subnet := "10.213." + strconv.Itoa(slot) + "."
+	return cloneNetwork{
+		Port:   port,
+		NS:     "avdctl-" + p,
+		HostIf: "avh" + p,
+		NSIf:   "avg" + p,
+		HostIP: subnet + "1",
+		NSIP:   subnet + "2",
+	}, nil
+}
+
+// execPrefix runs a command inside the namespace.
+func (n cloneNetwork) execPrefix() []string {
+	return []string{"ip", "netns", "exec", n.NS}
+}
+
+func (n cloneNetwork) ports() string {
+	return strconv.Itoa(n.Port) + "," + strconv.Itoa(n.Port+1)
+}
+
+// hostRules are the iptables rules of the host side, as [table, chain, match...]. They are
+// inserted in order, each at the head of its chain, so later rules take precedence.
+func (n cloneNetwork) hostRules() [][]string {
+	rules := [][]string{
+		{"nat", "POSTROUTING", "-s", n.NSIP + "/32", "!", "-o", n.HostIf, "-j", "MASQUERADE"},
+		{"nat", "OUTPUT", "-d", "127.0.0.1/32", "-p", "tcp", "-m", "multiport", "--dports", n.ports(), "-j", "DNAT", "--to-destination", n.NSIP},
+		{"nat", "POSTROUTING", "-s", "127.0.0.1/32", "-o", n.HostIf, "-j", "SNAT", "--to-source", n.HostIP},
+		{"filter", "INPUT", "-i", n.HostIf, "-j", "DROP"},
+		{"filter", "INPUT", "-i", n.HostIf, "-m", "conntrack", "--ctstate", "ESTABLISHED,RELATED", "-j", "ACCEPT"},
+		{"filter", "FORWARD", "-o", n.HostIf, "-m", "conntrack", "--ctstate", "ESTABLISHED,RELATED", "-j", "ACCEPT"},
+	}
+	egress := "ACCEPT"
+	if len(n.Allow) > 0 {
+		egress = "DROP"
+	}
+	rules = append(rules, []string{"filter", "FORWARD", "-i", n.HostIf, "-j", egress})
+	for _, cidr := range netnsBlockedRanges {
+		rules = append(rules, []string{"filter", "FORWARD", "-i", n.HostIf, "-d", cidr, "-j", "DROP"})
+	}
+	if len(n.Allow) > 0 && n.DNS != "" {
+		for _, proto := range []string{"udp", "tcp"} {
+			rules = append(rules, []string{"filter", "FORWARD", "-i", n.HostIf, "-d", n.DNS + "/32", "-p", proto, "--dport", "53", "-j", "ACCEPT"})
+		}
+	}
+	for _, cidr := range n.Allow {
+		rules = append(rules, []string{"filter", "FORWARD", "-i", n.HostIf, "-d", cidr, "-j", "ACCEPT"})
+	}
+	for _, cidr := range n.Deny {
+		rules = append(rules, []string{"filter", "FORWARD", "-i", n.HostIf, "-d", cidr, "-j", "DROP"})
+	}
+	return rules
+}
+
+// iptablesRule returns the iptables argv that applies op ("-I" or "-D") to rule.
+func (n cloneNetwork) iptablesRule(op string, rule []string) []string {
+	args := []string{"iptables", "-w", "-t", rule[0], op, rule[1]}
+	args = append(args, rule[2:]...)
+	return append(args, "-m", "comment", "--comment", n.NS)
+}
+
+// setupCommands creates the namespace, its veth pair and routing, then the NAT, port mapping
+// and isolation rules.
+func (n cloneNetwork) setupCommands() [][]string {
+	in := n.execPrefix()
+	cmds := [][]string{
+		{"ip", "netns", "add", n.NS},
+		{"ip", "link", "add", n.HostIf, "type", "veth", "peer", "name", n.NSIf},
+		{"ip", "link", "set", n.NSIf, "netns", n.NS},
+		{"ip", "addr", "add", n.HostIP + "/30", "dev", n.HostIf},
+		{"ip", "link", "set", n.HostIf, "up"},
+		append(in, "ip", "link", "set", "lo", "up"),
+		append(in, "ip", "addr", "add", n.NSIP+"/30", "dev", n.NSIf),
+		append(in, "ip", "link", "set", n.NSIf, "up"),
+		append(in, "ip", "route", "add", "default", "via", n.HostIP),
+		{"sysctl", "-qw", "net.ipv4.ip_forward=1"},
+		// Loopback-sourced and loopback-bound packets cross the veth for the port mapping.
+		{"sysctl", "-qw", "net.ipv4.conf." + n.HostIf + ".route_localnet=1"},
+		append(in, "sysctl", "-qw", "net.ipv4.conf."+n.NSIf+".route_localnet=1"),
+		append(in, "iptables", "-w", "-t", "nat", "-A", "PREROUTING", "-i", n.NSIf, "-d", n.NSIP+"/32",
+			"-p", "tcp", "-m", "multiport", "--dports", n.ports(), "-j", "DNAT", "--to-destination", "127.0.0.1"),
+	}
+	for _, rule := range n.hostRules() {
+		cmds = append(cmds, n.iptablesRule("-I", rule))
+	}
+	return cmds
+}
+
+// ruleDeletes returns the iptables argv that delete the rules of the namespace in the
+// `iptables -S` listing of table. Rules are found by their comment, so rules from a policy
+// resolved at start are removed without resolving it again.
+func (n cloneNetwork) ruleDeletes(table, listing string) [][]string {
+	var cmds [][]string
+	for _, line := range strings.Split(listing, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != "-A" {
+			continue
+		}
+		// Some iptables versions quote comments in listings; the delete takes the bare one.
+		i := slices.IndexFunc(fields, func(f string) bool { return strings.Trim(f, `"`) == n.NS })
+		if i < 0 {
+			continue
+		}
+		fields[i] = n.NS
+		fields[0] = "-D"
+		cmds = append(cmds, append([]string{"iptables", "-w", "-t", table}, fields...))
+	}
+	return cmds
+}
+
+func runNetnsCommands(env Env, cmds [][]string) error {
+	for _, c := range cmds {
+		if _, errOut, err := runCommandOutputWithEnv(env.Context, nil, nil, c[0], c[1:]...); err != nil {
+			return fmt.Errorf("%s: %w: %s", strings.Join(c, " "), err, strings.TrimSpace(errOut))
+		}
+	}
+	return nil
+}
+
+// setupCloneNetwork creates the network namespace of the instance on port, replacing one left
+// over by an emulator that exited on its own. Needs root.
+func setupCloneNetwork(env Env, port int) (cloneNetwork, error) {
+	_, span := startSpan(env, "avd.setupCloneNetwork", attribute.Int("port", port))
+	defer span.End()
+	fail := func(err error) (cloneNetwork, error) {
+		recordSpanError(span, err)
+		return cloneNetwork{}, err
+	}
+	if os.Geteuid() != 0 {
+		return fail(errors.New("per-clone network namespaces need avdctl to run as root"))
+	}
+	n, err := newCloneNetwork(port)
+	if err != nil {
+		return fail(err)
+	}
+	if err := env.NetworkPolicy.Validate(); err != nil {
+		return fail(err)
+	}
+	if n.Allow, n.Deny, err = env.NetworkPolicy.resolve(env.Context); err != nil {
+		return fail(err)
+	}
+	teardownCloneNetwork(env, port)
+	n.DNS = env.CloneNetnsDNS
+	if n.DNS == "" {
+		n.DNS = DefaultCloneNetnsDNS
+	}
+	etc := filepath.Join(netnsEtcDir, n.NS)
+	if err := os.MkdirAll(etc, 0o755); err != nil {
+		return fail(fmt.Errorf("network namespace resolver: %w", err))
+	}
+	if err := os.WriteFile(filepath.Join(etc, "resolv.conf"), []byte("nameserver "+n.DNS+"\n"), 0o644); err != nil {
+		return fail(fmt.Errorf("network namespace resolver: %w", err))
+	}
+	if err := runNetnsCommands(env, n.setupCommands()); err != nil {
+		teardownCloneNetwork(env, port)
+		return fail(fmt.Errorf("network namespace %s: %w", n.NS, err))
+	}
+	logEvent(env, "clone network namespace created", "port", port, "netns", n.NS, "address", n.NSIP,
+		"allow", strings.Join(n.Allow, ","), "deny", strings.Join(n.Deny, ","))
+	return n, nil
+}
+
+// teardownCloneNetwork removes the network namespace of the instance on port, if any.
+func teardownCloneNetwork(env Env, port int) {
+	n, err := newCloneNetwork(port)
+	if err != nil {
+		return
+	}
+	etc := filepath.Join(netnsEtcDir, n.NS)
+	if !fileExists(filepath.Join("/run/netns", n.NS)) && !fileExists(etc) {
+		return
+	}
+	// Deleting the namespace also removes the veth pair and the rules inside it.
+	cmds := [][]string{{"ip", "netns", "delete", n.NS}}
+	for _, table := range []string{"filter", "nat"} {
+		listing, _, err := runCommandOutputWithEnv(env.Context, nil, nil, "iptables", "-w", "-t", table, "-S")
+		if err == nil {
+			cmds = append(n.ruleDeletes(table, listing), cmds...)
+		}
+	}
+	// Rules that are already gone fail to delete; that is the state we want.
+	for _, c := range cmds {
+		_, _, _ = runCommandOutputWithEnv(env.Context, nil, nil, c[0], c[1:]...)
+	}
+	_ = os.RemoveAll(etc)
+	logEvent(env, "clone network namespace removed", "port", port, "netns", n.NS)
+}
@@ -0,0 +1,95 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCloneNetworkLayout(t *testing.T) {
+	n, err := newCloneNetwork(5580)
+	if err != nil {
+		t.Fatalf("newCloneNetwork: %v", err)
+	}
+	if n.NS != "avdctl-5580" || n.HostIf != "avh5580" || n.HostIP != "10.213.13.1" || n.NSIP != "10.213.13.2" {
+		t.Fatalf("layout = %+v", n)
+	}
+	for _, bad := range []int{5555, 5000, 6200} {
+		if _, err := newCloneNetwork(bad); err == nil {
+			t.Fatalf("expected no slot for port %d", bad)
+		}
+	}
+}
+
+func TestCloneNetworkRuleDeletes(t *testing.T) {
+	n, _ := newCloneNetwork(5554)
+	listing := "-P FORWARD ACCEPT\n" +
+		"-A FORWARD -i avh5554 -d 10.0.0.0/8 -m comment --comment avdctl-5554 -j DROP\n" +
+		"-A FORWARD -i avh5556 -j ACCEPT -m comment --comment avdctl-5556\n" +
+		"-A INPUT -i avh5554 -j DROP -m comment --comment \"avdctl-5554\"\n"
+	got := n.ruleDeletes("filter", listing)
+	want := [][]string{
+		strings.Fields("iptables -w -t filter -D FORWARD -i avh5554 -d 10.0.0.0/8 -m comment --comment avdctl-5554 -j DROP"),
+		strings.Fields("iptables -w -t filter -D INPUT -i avh5554 -j DROP -m comment --comment avdctl-5554"),
+	}
+	if !slices.EqualFunc(got, want, slices.Equal[[]string]) {
+		t.Fatalf("deletes = %v, want %v", got, want)
+	}
+	var mapped bool
+	for _, c := range n.setupCommands() {
+		if slices.Contains(c, "DNAT") && slices.Contains(c, "5554,5555") {
+			mapped = true
+		}
+	}
+	if !mapped {
+		t.Fatal("setup does not map the console and adb ports")
+	}
+}
+
+func TestCloneNetworkPolicyRules(t *testing.T) {
+	n, _ := newCloneNetwork(5554)
+	n.DNS = "1.1.1.1"
+	n.Allow = []string{"10.1.2.0/24"}
+	n.Deny = []string{"10.1.2.9/32"}
+	var forward []string
+	for _, rule := range n.hostRules() {
+		if rule[1] == "FORWARD" && slices.Contains(rule, "-i") {
+			forward = append(forward, strings.Join(rule[2:], " "))
+		}
+	}
+	// Rules are inserted at the head of the chain: the last one listed is matched first.
+	want := []string{
+		"-i avh5554 -j DROP",
+		"-i avh5554 -d 10.0.0.0/8 -j DROP",
+		"-i avh5554 -d 172.16.0.0/12 -j DROP",
+		"-i avh5554 -d 192.168.0.0/16 -j DROP",
+		"-i avh5554 -d 100.64.0.0/10 -j DROP",
+		"-i avh5554 -d 169.254.0.0/16 -j DROP",
+		"-i avh5554 -d 1.1.1.1/32 -p udp --dport 53 -j ACCEPT",
+		"-i avh5554 -d 1.1.1.1/32 -p tcp --dport 53 -j ACCEPT",
+		"-i avh5554 -d 10.1.2.0/24 -j ACCEPT",
+		"-i avh5554 -d 10.1.2.9/32 -j DROP",
+	}
+	if !slices.Equal(forward, want) {
+		t.Fatalf("FORWARD rules\n%s\nwant\n%s", strings.Join(forward, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestNetworkPolicyValidate(t *testing.T) {
+	ok := NetworkPolicy{Allow: []string{"api.staging.example.com", "10.1.0.0/16"}, Deny: []string{"203.0.113.7"}}
+	if err := ok.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	allow, deny, err := NetworkPolicy{Allow: []string{"10.1.0.0/16"}, Deny: []string{"203.0.113.7"}}.resolve(nil)
+	if err != nil || !slices.Equal(allow, []string{"10.1.0.0/16"}) || !slices.Equal(deny, []string{"203.0.113.7/32"}) {
+		t.Fatalf("resolve = %v, %v, %v", allow, deny, err)
+	}
+	for _, bad := range []string{"2001:db8::/32", "not a host", "*.example.com"} {
+		if err := (NetworkPolicy{Deny: []string{bad}}).Validate(); err == nil {
+			t.Fatalf("expected an error for %q", bad)
+		}
+	}
+}
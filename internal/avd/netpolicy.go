@@ -0,0 +1,99 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+)
+
+var domainRe = regexp.MustCompile(`^([A-Za-z0-9]([A-Za-z0-9-]*[A-Za-z0-9])?\.)+[A-Za-z]{2,}$`)
+
+// NetworkPolicy limits where a clone in its own network namespace (Env.CloneNetns) can connect.
+// Entries are IPv4 addresses, CIDRs or domain names; domains are resolved on the host when the
+// instance starts, so addresses a name gains later are not covered.
+type NetworkPolicy struct {
+	// Allow, when set, is the only egress the clone gets (its resolver is always allowed).
+	// Allowed destinations are reachable even inside the private ranges blocked by default.
+	Allow []string `json:"allow,omitempty"`
+	// Deny is blocked egress; it wins over Allow.
+	Deny []string `json:"deny,omitempty"`
+}
+
+// IsZero reports whether p leaves egress as the namespace defaults.
+func (p NetworkPolicy) IsZero() bool { return len(p.Allow) == 0 && len(p.Deny) == 0 }
+
+// Validate checks that every entry is an address, a CIDR or a domain name.
+func (p NetworkPolicy) Validate() error {
+	var errs []error
+	for _, entry := range append(append([]string{}, p.Allow...), p.Deny...) {
+		if _, err := policyCIDRs(context.Background(), entry, false); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// resolve returns the allowed and denied CIDRs of p.
+func (p NetworkPolicy) resolve(ctx context.Context) (allow, deny []string, err error) {
+	for _, entry := range p.Allow {
+		cidrs, err := policyCIDRs(ctx, entry, true)
+		if err != nil {
+			return nil, nil, err
+		}
+		allow = append(allow, cidrs...)
+	}
+	for _, entry := range p.Deny {
+		cidrs, err := policyCIDRs(ctx, entry, true)
+		if err != nil {
+			return nil, nil, err
+		}
+		deny = append(deny, cidrs...)
+	}
+	return allow, deny, nil
+}
+
+// policyCIDRs turns a policy entry into IPv4 CIDRs, looking domains up when lookup is set.
+func policyCIDRs(ctx context.Context, entry string, lookup bool) ([]string, error) {
+	entry = strings.TrimSpace(entry)
+	if _, ipnet, err := net.ParseCIDR(entry); err == nil {
+		if ipnet.IP.To4() == nil {
+			return nil, fmt.Errorf("network policy %q: only IPv4 is supported", entry)
+		}
+		return []string{ipnet.String()}, nil
+	}
+	if ip := net.ParseIP(entry); ip != nil {
+		if ip.To4() == nil {
+			return nil, fmt.Errorf("network policy %q: only IPv4 is supported", entry)
+		}
+		return []string{ip.String() + "/32"}, nil
+	}
+	if !domainRe.MatchString(entry) {
+		return nil, fmt.Errorf("network policy %q: not an address, CIDR or domain", entry)
+	}
+	if !lookup {
+		return nil, nil
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, entry)
+	if err != nil {
+		return nil, fmt.Errorf("network policy %q: %w", entry, err)
+	}
+	var cidrs []string
+	for _, addr := range addrs {
+		if ip4 := addr.IP.To4(); ip4 != nil {
+			cidrs = append(cidrs, ip4.String()+"/32")
+		}
+	}
+	if len(cidrs) == 0 {
+		return nil, fmt.Errorf("network policy %q: no IPv4 address", entry)
+	}
+	return cidrs, nil
+}
@@ -0,0 +1,249 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Artifact is a system image or golden directory an operation depends on.
+type Artifact struct {
+	Kind   string `json:"kind"` // system-image, golden
+	Ref    string `json:"ref"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// MissingArtifactsError is returned in offline mode when required artifacts are not available locally.
+type MissingArtifactsError struct {
+	Missing []Artifact `json:"missing"`
+}
+
+func (e *MissingArtifactsError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "offline mode: %d required artifact(s) missing (run `avdctl prefetch` while online):", len(e.Missing))
+	for _, a := range e.Missing {
+		fmt.Fprintf(&b, "\n  %-12s %s: %s", a.Kind, a.Ref, a.Reason)
+	}
+	return b.String()
+}
+
+// ArtifactRequirements lists the system images and goldens a workflow needs.
+type ArtifactRequirements struct {
+	SystemImages []string `json:"system_images"`
+	Goldens      []string `json:"goldens"`
+}
+
+// FleetRequirements collects the system images of the fleet's bases and all goldens it references.
+func FleetRequirements(fleet Fleet) ArtifactRequirements {
+	images := map[string]bool{}
+	goldens := map[string]bool{}
+	for _, b := range fleet.Bases {
+		images[b.Image] = true
+	}
+	for _, g := range fleet.Goldens {
+		goldens[g.Path] = true
+	}
+	for _, c := range fleet.Clones {
+		goldens[fleet.goldenPath(c.Golden)] = true
+	}
+	return ArtifactRequirements{SystemImages: sortedKeys(images), Goldens: sortedKeys(goldens)}
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		if k != "" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// CheckArtifacts reports which required artifacts are not available locally, without network
+// access: system images must be installed in SDKRoot, goldens must exist in GoldenDir (as
+// prefetched copies) or at a local path.
+func CheckArtifacts(env Env, req ArtifactRequirements) []Artifact {
+	var missing []Artifact
+	for _, pkg := range req.SystemImages {
+		switch {
+		case env.SDKRoot == "":
+			missing = append(missing, Artifact{Kind: "system-image", Ref: pkg, Reason: "ANDROID_SDK_ROOT not set"})
+		case !sysImgInstalled(env, pkg):
+			missing = append(missing, Artifact{Kind: "system-image", Ref: pkg, Reason: "not installed in " + env.SDKRoot})
+		}
+	}
+	for _, golden := range req.Goldens {
+		if _, err := localGolden(env, golden); err != nil {
+			missing = append(missing, Artifact{Kind: "golden", Ref: golden, Reason: err.Error()})
+		}
+	}
+	return missing
+}
+
+// localGolden resolves golden to a directory usable offline: a prefetched copy in GoldenDir
+// wins, otherwise the path itself if it exists on local (non-shared) storage.
+func localGolden(env Env, golden string) (string, error) {
+	dir := golden
+	if filepath.Ext(golden) == ".qcow2" {
+		dir = filepath.Dir(golden)
+	}
+	if env.GoldenDir != "" {
+		cached := filepath.Join(env.GoldenDir, filepath.Base(dir))
+		if st, err := os.Stat(cached); err == nil && st.IsDir() {
+			if cached == dir {
+				return golden, nil
+			}
+			return cached, nil
+		}
+	}
+	if _, err := os.Stat(dir); err != nil {
+		return "", errors.New("not found and not prefetched into " + env.GoldenDir)
+	}
+	storage, err := detectStorageFn(dir)
+	if err != nil {
+		return "", err
+	}
+	if storage.Shared {
+		return "", fmt.Errorf("on shared storage (%s) and not prefetched into %s", storage.FSType, env.GoldenDir)
+	}
+	return golden, nil
+}
+
+// PrefetchedArtifact records what Prefetch did for one artifact.
+type PrefetchedArtifact struct {
+	Artifact
+	Status string `json:"status"` // done, unchanged, failed
+	Path   string `json:"path,omitempty"`
+}
+
+// PrefetchReport lists the artifacts handled by Prefetch.
+type PrefetchReport struct {
+	Artifacts []PrefetchedArtifact `json:"artifacts"`
+}
+
+// Failed reports whether any artifact could not be prefetched.
+func (r PrefetchReport) Failed() bool {
+	for _, a := range r.Artifacts {
+		if a.Status == "failed" {
+			return true
+		}
+	}
+	return false
+}
+
+// Prefetch populates the local artifact cache ahead of offline runs: missing system images are
+// installed with sdkmanager and goldens not already local are copied into GoldenDir (keeping
+// file times, so clone fingerprints match the source).
+func Prefetch(env Env, req ArtifactRequirements) (PrefetchReport, error) {
+	_, span := startSpan(env, "avd.Prefetch",
+		attribute.Int("system_images", len(req.SystemImages)),
+		attribute.Int("goldens", len(req.Goldens)),
+	)
+	defer span.End()
+	report := PrefetchReport{Artifacts: []PrefetchedArtifact{}}
+	if env.Offline {
+		err := errors.New("prefetch needs network access; unset AVDCTL_OFFLINE")
+		recordSpanError(span, err)
+		return report, err
+	}
+
+	for _, pkg := range req.SystemImages {
+		entry := PrefetchedArtifact{Artifact: Artifact{Kind: "system-image", Ref: pkg}, Status: "unchanged"}
+		if !sysImgInstalled(env, pkg) {
+			entry.Status = "done"
+			if err := installSysImg(env, pkg, nil); err != nil {
+				entry.Status, entry.Reason = "failed", err.Error()
+			}
+		}
+		report.Artifacts = append(report.Artifacts, entry)
+	}
+
+	for _, golden := range req.Goldens {
+		entry := PrefetchedArtifact{Artifact: Artifact{Kind: "golden", Ref: golden}}
+		dir := golden
+		if filepath.Ext(golden) == ".qcow2" {
+			dir = filepath.Dir(golden)
+		}
+		dest := filepath.Join(env.GoldenDir, filepath.Base(dir))
+		copied, err := copyGoldenDir(dir, dest)
+		switch {
+		case err != nil:
+			entry.Status, entry.Reason = "failed", err.Error()
+		case copied:
+			entry.Status, entry.Path = "done", dest
+		default:
+			entry.Status, entry.Path = "unchanged", dest
+		}
+		report.Artifacts = append(report.Artifacts, entry)
+	}
+
+	logEvent(env, "prefetch completed", "artifacts", len(report.Artifacts), "failed", report.Failed())
+	return report, nil
+}
+
+// copyGoldenDir mirrors the regular files of src (recursively) into dst, skipping files whose
+// size and modification time already match. It reports whether anything was copied.
+func copyGoldenDir(src, dst string) (bool, error) {
+	srcAbs, err := filepath.Abs(src)
+	if err != nil {
+		return false, err
+	}
+	dstAbs, err := filepath.Abs(dst)
+	if err != nil {
+		return false, err
+	}
+	if srcAbs == dstAbs {
+		if _, err := os.Stat(srcAbs); err != nil {
+			return false, err
+		}
+		return false, nil
+	}
+	if _, err := os.Stat(srcAbs); err != nil {
+		return false, fmt.Errorf("read golden %s: %w", src, err)
+	}
+	copied := false
+	err = filepath.WalkDir(srcAbs, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(srcAbs, path)
+		target := filepath.Join(dstAbs, rel)
+		if entry.IsDir() {
+			return os.MkdirAll(target, 0o755)
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		st, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		if have, err := os.Stat(target); err == nil && have.Size() == st.Size() && have.ModTime().Equal(st.ModTime()) {
+			return nil
+		}
+		tmp := target + ".tmp"
+		if err := copyFile(path, tmp, st.Mode().Perm()); err != nil {
+			_ = os.Remove(tmp)
+			return fmt.Errorf("copy %s: %w", rel, err)
+		}
+		if err := os.Chtimes(tmp, st.ModTime(), st.ModTime()); err != nil {
+			return err
+		}
+		if err := os.Rename(tmp, target); err != nil {
+			return err
+		}
+		copied = true
+		return nil
+	})
+	return copied, err
+}
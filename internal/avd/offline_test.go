@@ -0,0 +1,90 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEnsureSysImgOfflineDoesNotInstall(t *testing.T) {
+	env := newTestEnv(t)
+	env.SDKRoot = t.TempDir()
+	env.Offline = true
+	marker := filepath.Join(t.TempDir(), "ran")
+	env.SdkManager = filepath.Join(t.TempDir(), "sdkmanager")
+	if err := os.WriteFile(env.SdkManager, []byte("#!/bin/sh\ntouch "+marker+"\n"), 0o755); err != nil {
+		t.Fatalf("write sdkmanager stub: %v", err)
+	}
+
+	err := ensureSysImg(env, "system-images;android-35;google_apis;x86_64", nil)
+	var missing *MissingArtifactsError
+	if !errors.As(err, &missing) || len(missing.Missing) != 1 || missing.Missing[0].Kind != "system-image" {
+		t.Fatalf("expected MissingArtifactsError, got %v", err)
+	}
+	if _, err := os.Stat(marker); !os.IsNotExist(err) {
+		t.Fatal("sdkmanager must not run in offline mode")
+	}
+}
+
+func TestPrefetchCopiesGoldenAndOfflineCloneUsesIt(t *testing.T) {
+	env := newTestEnv(t)
+	env.GoldenDir = filepath.Join(t.TempDir(), "cache")
+	makeBaseAVD(t, env, "base-a35")
+	golden := makeGoldenDir(t)
+	req := ArtifactRequirements{Goldens: []string{golden}}
+
+	report, err := Prefetch(env, req)
+	if err != nil || report.Failed() {
+		t.Fatalf("Prefetch: %v %#v", err, report)
+	}
+	if got := report.Artifacts[0].Status; got != "done" {
+		t.Fatalf("first prefetch status = %q", got)
+	}
+	cached := filepath.Join(env.GoldenDir, "golden")
+	want, _ := goldenFingerprint(golden)
+	if got, _ := goldenFingerprint(cached); got != want {
+		t.Fatalf("prefetched fingerprint %q, want %q", got, want)
+	}
+	report, err = Prefetch(env, req)
+	if err != nil || report.Artifacts[0].Status != "unchanged" {
+		t.Fatalf("second prefetch: %v %#v", err, report)
+	}
+
+	env.Offline = true
+	if missing := CheckArtifacts(env, req); len(missing) != 0 {
+		t.Fatalf("unexpected missing artifacts: %#v", missing)
+	}
+	if err := os.RemoveAll(golden); err != nil {
+		t.Fatalf("remove source golden: %v", err)
+	}
+	if _, err := CloneFromGolden(env, "base-a35", "w-offline", golden); err != nil {
+		t.Fatalf("offline clone: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(env.AVDHome, "w-offline.avd", "userdata-qemu.img")); err != nil {
+		t.Fatalf("expected userdata copied from prefetched golden: %v", err)
+	}
+	if _, err := Prefetch(env, req); err == nil {
+		t.Fatal("expected prefetch to refuse offline mode")
+	}
+}
+
+func TestOfflineCloneRejectsSharedGolden(t *testing.T) {
+	orig := detectStorageFn
+	detectStorageFn = func(path string) (StorageInfo, error) {
+		return StorageInfo{Path: path, FSType: "nfs", Shared: true}, nil
+	}
+	t.Cleanup(func() { detectStorageFn = orig })
+
+	env := newTestEnv(t)
+	env.Offline = true
+	makeBaseAVD(t, env, "base-a35")
+	_, err := CloneFromGolden(env, "base-a35", "w-shared", makeGoldenDir(t))
+	var missing *MissingArtifactsError
+	if !errors.As(err, &missing) || missing.Missing[0].Kind != "golden" {
+		t.Fatalf("expected missing golden error, got %v", err)
+	}
+}
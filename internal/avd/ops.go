@@ -23,6 +23,7 @@ import (
 	"time"
 
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Info struct {
@@ -30,11 +31,28 @@ type Info struct {
 	Path      string `json:"path"`
 	Userdata  string `json:"userdata"`
 	SizeBytes int64  `json:"size_bytes"`
+	// Quarantined is set while starts refuse the AVD (see Quarantine).
+	Quarantined bool `json:"quarantined,omitempty"`
+
+	// Filled by ListWide only.
+	APILevel int        `json:"api_level,omitempty"`
+	ABI      string     `json:"abi,omitempty"`
+	Device   string     `json:"device,omitempty"` // hardware profile (hw.device.name)
+	Kind     string     `json:"kind,omitempty"`   // KindBase or KindClone
+	Golden   string     `json:"golden,omitempty"` // golden a clone was made from, if it is in Env.GoldenDir
+	LastBoot *time.Time `json:"last_boot,omitempty"`
+	Running  bool       `json:"running,omitempty"`
+	Booted   bool       `json:"booted,omitempty"` // running and fully booted
+	Serial   string     `json:"serial,omitempty"`
 }
 
 const cloneFingerprintFilename = ".golden.fingerprint"
 
-// BootProgressFunc is called to report boot progress status.
+// sidecarPrefix starts the name of every file avdctl keeps in an AVD directory (avdctl-pin,
+// avdctl-runs.json, ...). They describe that AVD alone, so a clone never links to the base's.
+const sidecarPrefix = "avdctl-"
+
+// BootProgressFunc is called to report boot progress status, a BootPhase.
 type BootProgressFunc func(status string, elapsed time.Duration)
 
 func commandStderrWriter(env Env, bin string, args []string, buf *bytes.Buffer) io.Writer {
@@ -78,44 +96,39 @@ func List(env Env) ([]Info, error) {
 		}
 		name := strings.TrimSuffix(e.Name(), ".avd")
 		dir := filepath.Join(env.AVDHome, e.Name())
-		ud := filepath.Join(dir, "userdata-qemu.img.qcow2")
-		if _, err := os.Stat(ud); err != nil {
-			ud = filepath.Join(dir, "userdata.img")
-		}
-		var sz int64
-		if st, err := os.Stat(ud); err == nil {
-			sz = st.Size()
-		}
-		out = append(out, Info{Name: name, Path: dir, Userdata: ud, SizeBytes: sz})
+		ud, sz := userdataImage(dir)
+		_, quarantined := quarantineOf(dir)
+		out = append(out, Info{Name: name, Path: dir, Userdata: ud, SizeBytes: sz, Quarantined: quarantined})
 	}
 	return out, nil
 }
 
-func ensureSysImg(env Env, pkg string) error {
-	if env.SDKRoot != "" {
-		// quick existence probe
-		parts := strings.Split(pkg, ";")
-		if len(parts) >= 3 {
-			p := filepath.Join(env.SDKRoot, "system-images", parts[1], parts[2], "x86_64")
-			if _, err := os.Stat(p); err == nil {
-				return nil
-			}
-		}
+func ensureSysImg(env Env, pkg string, progress InstallProgressFunc) error {
+	// quick existence probe
+	if sysImgInstalled(env, pkg) {
+		return nil
+	}
+	if env.Offline {
+		return &MissingArtifactsError{Missing: CheckArtifacts(env, ArtifactRequirements{SystemImages: []string{pkg}})}
 	}
 	// install via sdkmanager
-	// accept licenses if needed
-	_ = run(env, env.SdkManager, "--licenses")
-	return run(env, env.SdkManager, pkg)
+	return installSysImg(env, pkg, progress)
 }
 
 func InitBase(env Env, name, sysImage, device string) (Info, error) {
-	if name == "" {
-		return Info{}, errors.New("empty AVD name")
+	return InitBaseWithProgress(env, name, sysImage, device, nil)
+}
+
+// InitBaseWithProgress is InitBase with system image install progress reporting.
+// Concurrent callers share one sdkmanager run (see installSysImg).
+func InitBaseWithProgress(env Env, name, sysImage, device string, progress InstallProgressFunc) (Info, error) {
+	if err := ValidateName(env, name); err != nil {
+		return Info{}, err
 	}
 	if err := os.MkdirAll(env.AVDHome, 0o755); err != nil {
 		return Info{}, err
 	}
-	if err := ensureSysImg(env, sysImage); err != nil {
+	if err := ensureSysImg(env, sysImage, progress); err != nil {
 		return Info{}, fmt.Errorf("failed to ensure system image: %w", err)
 	}
 	out, err := runCommandCombinedOutputWithEnv(env.Context, nil, strings.NewReader("no\n"), env.AvdMgr, "create", "avd",
@@ -126,58 +139,244 @@ func InitBase(env Env, name, sysImage, device string) (Info, error) {
 	return infoOf(env, name)
 }
 
-// SaveGolden exports an AVD's writable images (userdata, encryptionkey, cache) to a golden directory.
+// SaveGolden exports an AVD's writable images (userdata, encryptionkey, cache, sdcard by default;
+// see GoldenLayout) to a golden directory.
 // Converts qcow2 overlays to raw IMG format to prevent Android emulator from re-creating overlays on boot.
 // Returns the golden directory path and total size.
 func SaveGolden(env Env, name, dest string) (string, int64, error) {
+	return SaveGoldenWithOptions(env, name, dest, SaveGoldenOptions{})
+}
+
+// SaveGoldenOptions customizes SaveGoldenWithOptions.
+type SaveGoldenOptions struct {
+	// Fsck checks (or repairs) the exported userdata filesystem before it is published.
+	Fsck FsckMode
+	// SelfContained also stores the base AVD's read-only files and its system image in the
+	// golden, so it can be cloned on a host without the base AVD or the system image.
+	SelfContained bool
+	// Validate boot-tests a temporary clone of the golden after export (see ValidateGolden),
+	// with ValidateTimeout as boot timeout (default: 3m). A golden that fails is not saved.
+	Validate        bool
+	ValidateTimeout time.Duration
+	// Layout selects the images to export (default: Env.GoldenLayoutFile, else
+	// DefaultGoldenLayout). It is recorded in the manifest and followed by clones.
+	Layout *GoldenLayout
+}
+
+// SaveGoldenWithOptions is SaveGolden with an optional userdata filesystem check.
+// The export is refused if the check leaves errors; the result is recorded in manifest.json.
+// AVDs that received secrets at runtime are never exported (ErrSecretsProvisioned).
+//
+// The golden is exported into a hidden sibling directory and moved into place only once it is
+// complete (and validated, with opts.Validate), so a failed export leaves nothing behind.
+func SaveGoldenWithOptions(env Env, name, dest string, opts SaveGoldenOptions) (goldenDir string, totalSize int64, err error) {
 	avdPath := filepath.Join(env.AVDHome, name+".avd")
+	if SecretsProvisioned(env, name) {
+		return "", 0, fmt.Errorf("save golden from %s: %w", name, ErrSecretsProvisioned)
+	}
+	layout, err := saveLayout(env, opts.Layout)
+	if err != nil {
+		return "", 0, err
+	}
 
 	// Create golden directory
-	goldenDir := dest
+	goldenDir = dest
 	if filepath.Ext(dest) == ".qcow2" {
 		// Legacy single-file mode: create directory instead
 		goldenDir = strings.TrimSuffix(dest, ".qcow2")
 	}
-	if err := os.MkdirAll(goldenDir, 0o755); err != nil {
+	exportDir, err := newPartialGoldenDir(goldenDir)
+	if err != nil {
 		return "", 0, err
 	}
+	defer func() {
+		if err != nil {
+			_ = os.RemoveAll(exportDir)
+		}
+	}()
 
-	// List of writable images to save (base name)
-	images := []string{"userdata-qemu.img", "encryptionkey.img", "cache.img", "sdcard.img"}
-	var totalSize int64
-
-	for _, img := range images {
-		// Prefer qcow2 overlay (has customizations), fallback to raw
-		src := filepath.Join(avdPath, img+".qcow2")
-		if _, err := os.Stat(src); err != nil {
-			src = filepath.Join(avdPath, img)
-			if _, err2 := os.Stat(src); err2 != nil {
-				continue // Skip if not found
+	manifest := GoldenManifest{Source: name, CreatedAt: time.Now().UTC(), Images: []GoldenImage{}, Layout: &layout}
+	if version, err := DetectEmulatorVersion(env); err == nil {
+		manifest.EmulatorVersion = version.String()
+	}
+
+	type export struct {
+		LayoutImage
+		img, src string
+		size     int64
+		modTime  int64
+		sha256   string
+		fsck     *FsckResult
+	}
+	// Writable partitions are looked up where the emulator put them (hardware-qemu.ini, then
+	// the names emulator releases use) and exported under the layout's name.
+	detected := map[string]WritableImage{}
+	for _, wi := range writableImages(avdPath) {
+		detected[wi.Name] = wi
+	}
+	var exports []*export
+	for _, li := range layout.Images {
+		img := li.Name
+		var src string
+		if role := writableImageRole(img); role != "" && !li.CopyAsIs {
+			wi, ok := detected[img]
+			if !ok && role == RoleData {
+				return "", 0, fmt.Errorf("no userdata image in %s (looked for %s); refusing to export a golden without user data",
+					avdPath, dataImageVariants())
+			}
+			src = wi.Path
+		} else {
+			// Prefer qcow2 overlay (has customizations), fallback to raw
+			src = filepath.Join(avdPath, img+".qcow2")
+			if _, err := os.Stat(src); err != nil || li.CopyAsIs {
+				src = filepath.Join(avdPath, img)
+				if _, err2 := os.Stat(src); err2 != nil {
+					src = ""
+				}
 			}
 		}
-
-		// Convert to raw IMG (not qcow2) to prevent emulator from creating overlays
-		dstFile := filepath.Join(goldenDir, img)
+		if src == "" {
+			logEvent(env, "golden image not found, skipped", "avd", name, "image", img)
+			continue
+		}
+		exports = append(exports, &export{LayoutImage: li, img: img, src: src})
+	}
+	// Images are converted concurrently, at most Env.IOParallelism at a time.
+	err = runIOPool(env, len(exports), func(i int) error {
+		e := exports[i]
+		dstFile := filepath.Join(exportDir, e.img)
 		tmp := dstFile + ".tmp"
-		if err := run(env, env.QemuImg, "convert", "-O", "raw", src, tmp); err != nil {
-			return "", 0, fmt.Errorf("convert %s: %w", img, err)
+		if err := os.MkdirAll(filepath.Dir(dstFile), 0o755); err != nil {
+			return err
+		}
+		if e.CopyAsIs {
+			if err := copyImage(env, e.src, tmp, nil); err != nil {
+				return fmt.Errorf("copy %s: %w", e.img, err)
+			}
+		} else if err := qemuImgConvert(env, e.src, tmp); err != nil {
+			// Convert to raw IMG (not qcow2) to prevent emulator from creating overlays
+			return fmt.Errorf("convert %s: %w", e.img, err)
+		}
+		if e.Fsck && opts.Fsck != FsckOff {
+			result, err := checkFilesystem(env, tmp, opts.Fsck)
+			if err != nil {
+				_ = os.Remove(tmp)
+				return err
+			}
+			if !result.OK() {
+				_ = os.Remove(tmp)
+				return fmt.Errorf("userdata filesystem of %s has errors (e2fsck exit %d); refusing to export golden:\n%s",
+					name, result.ExitCode, result.Output)
+			}
+			result.Image = e.img
+			e.fsck = &result
 		}
 		if err := os.Rename(tmp, dstFile); err != nil {
-			return "", 0, err
+			return err
 		}
 		if st, err := os.Stat(dstFile); err == nil {
-			totalSize += st.Size()
+			e.size, e.modTime = st.Size(), st.ModTime().UnixNano()
+		}
+		sum, err := fileSHA256(dstFile)
+		if err != nil {
+			return fmt.Errorf("hash %s: %w", e.img, err)
+		}
+		e.sha256 = sum
+		return nil
+	})
+	if err != nil {
+		return "", 0, err
+	}
+	for _, e := range exports {
+		totalSize += e.size
+		manifest.Images = append(manifest.Images, GoldenImage{Name: e.img, SizeBytes: e.size, ModTime: e.modTime, SHA256: e.sha256})
+		if e.fsck != nil {
+			manifest.Fsck = e.fsck
+		}
+	}
+	if custom, err := readCustomizations(avdPath); err != nil {
+		return "", 0, err
+	} else if !custom.Empty() {
+		manifest.Customizations = &custom
+	}
+	if opts.SelfContained {
+		sysdir, err := exportSelfContained(env, avdPath, exportDir)
+		if err != nil {
+			return "", 0, err
+		}
+		manifest.SelfContained = true
+		manifest.SystemImageDir = sysdir
+	}
+	if err := writeGoldenManifest(exportDir, manifest); err != nil {
+		return "", 0, err
+	}
+	if opts.Validate {
+		if _, err := ValidateGolden(env, name, exportDir, opts.ValidateTimeout); err != nil {
+			return "", 0, fmt.Errorf("golden not saved to %s: %w", goldenDir, err)
+		}
+	}
+	if err := publishGoldenDir(exportDir, goldenDir); err != nil {
+		return "", 0, fmt.Errorf("save golden to %s: %w", goldenDir, err)
+	}
+	if inGoldenRegistry(env, goldenDir) {
+		if _, err := Promote(env, goldenDir, ChannelLatest); err != nil {
+			logEvent(env, "golden channel update failed", "channel", ChannelLatest, "golden", goldenDir, "error", err)
 		}
 	}
 
 	return goldenDir, totalSize, nil
 }
 
+// newPartialGoldenDir creates the hidden sibling of goldenDir that SaveGoldenWithOptions exports
+// into. Being hidden, it is never taken for a golden of Env.GoldenDir.
+func newPartialGoldenDir(goldenDir string) (string, error) {
+	if err := os.MkdirAll(filepath.Dir(goldenDir), 0o755); err != nil {
+		return "", err
+	}
+	dir, err := os.MkdirTemp(filepath.Dir(goldenDir), "."+filepath.Base(goldenDir)+".partial-")
+	if err != nil {
+		return "", err
+	}
+	return dir, os.Chmod(dir, 0o755)
+}
+
+// publishGoldenDir moves the complete golden exportDir to goldenDir, replacing a golden already
+// there.
+func publishGoldenDir(exportDir, goldenDir string) error {
+	if !fileExists(goldenDir) {
+		return os.Rename(exportDir, goldenDir)
+	}
+	replaced := filepath.Join(filepath.Dir(goldenDir), fmt.Sprintf(".%s.replaced-%d", filepath.Base(goldenDir), time.Now().UnixNano()))
+	if err := os.Rename(goldenDir, replaced); err != nil {
+		return err
+	}
+	if err := os.Rename(exportDir, goldenDir); err != nil {
+		_ = os.Rename(replaced, goldenDir)
+		return err
+	}
+	return os.RemoveAll(replaced)
+}
+
 // CloneFromGolden creates a new AVD directory by copying raw IMG files from golden directory.
 // Uses full file copy (not QCOW2 overlays) to preserve all customizations independently.
 // It symlinks the base AVD's read-only files (system images, ROMs) and copies writable images.
 // Cloning takes time proportional to golden image size but ensures full isolation.
 func CloneFromGolden(env Env, base, name, golden string) (Info, error) {
+	return CloneFromGoldenWithOptions(env, base, name, golden, CloneOptions{})
+}
+
+// CloneFromGoldenWithOptions is CloneFromGolden with template variables for AVDCTL_CONFIG_TEMPLATE.
+// name must satisfy Env.Naming (see ValidateName).
+func CloneFromGoldenWithOptions(env Env, base, name, golden string, opts CloneOptions) (Info, error) {
+	if err := ValidateName(env, name); err != nil {
+		return Info{}, err
+	}
+	return cloneFromGolden(env, base, name, golden, opts)
+}
+
+// cloneFromGolden clones without checking the naming policy, for clones avdctl names itself
+// or re-creates under an existing name.
+func cloneFromGolden(env Env, base, name, golden string, opts CloneOptions) (Info, error) {
 	_, span := startSpan(
 		env,
 		"avd.CloneFromGolden",
@@ -185,6 +384,11 @@ func CloneFromGolden(env Env, base, name, golden string) (Info, error) {
 		attribute.String("clone", name),
 	)
 	defer span.End()
+	golden, err := ResolveGolden(env, golden)
+	if err != nil {
+		recordSpanError(span, err)
+		return Info{}, err
+	}
 	logEvent(
 		env,
 		"avd clone start",
@@ -198,9 +402,19 @@ func CloneFromGolden(env Env, base, name, golden string) (Info, error) {
 	baseDir := filepath.Join(env.AVDHome, base+".avd")
 	cloneDir := filepath.Join(env.AVDHome, name+".avd")
 
-	if _, err := os.Stat(baseDir); err != nil {
+	_, baseErr := os.Stat(baseDir)
+	if _, err := ParseLinkMode(string(env.CloneLinkMode)); err != nil {
 		recordSpanError(span, err)
-		return Info{}, fmt.Errorf("base AVD not found: %w", err)
+		return Info{}, fmt.Errorf("AVDCTL_CLONE_LINK: %w", err)
+	}
+	if env.Offline {
+		local, err := localGolden(env, golden)
+		if err != nil {
+			err = &MissingArtifactsError{Missing: []Artifact{{Kind: "golden", Ref: golden, Reason: err.Error()}}}
+			recordSpanError(span, err)
+			return Info{}, err
+		}
+		golden = local
 	}
 
 	// Resolve golden path (can be directory or legacy .qcow2 file)
@@ -213,6 +427,17 @@ func CloneFromGolden(env Env, base, name, golden string) (Info, error) {
 		recordSpanError(span, err)
 		return Info{}, fmt.Errorf("resolve golden path: %w", err)
 	}
+	// Without the base AVD, fall back to the copy embedded in a self-contained golden.
+	selfContained := false
+	if baseErr != nil {
+		embedded, ok := embeddedBase(absGoldenDir)
+		if !ok {
+			recordSpanError(span, baseErr)
+			return Info{}, fmt.Errorf("base AVD not found: %w", baseErr)
+		}
+		baseDir, selfContained = embedded, true
+		span.SetAttributes(attribute.Bool("self_contained", true))
+	}
 	fingerprint, err := goldenFingerprint(absGoldenDir)
 	if err != nil {
 		recordSpanError(span, err)
@@ -235,40 +460,35 @@ func CloneFromGolden(env Env, base, name, golden string) (Info, error) {
 	// ---------------------------------------------------------------------
 	// 1. Copy or template the config.ini and disable qcow2
 	// ---------------------------------------------------------------------
-	tpl := os.Getenv("AVDCTL_CONFIG_TEMPLATE")
 	dstCfg := filepath.Join(cloneDir, "config.ini")
-	var cfgBytes []byte
-
-	switch {
-	case tpl != "":
-		cfgBytes, err = os.ReadFile(tpl)
-		if err != nil {
-			recordSpanError(span, err)
-			return Info{}, fmt.Errorf("read template: %w", err)
-		}
-	default:
-		cfgBytes, err = os.ReadFile(filepath.Join(baseDir, "config.ini"))
-		if err != nil {
-			recordSpanError(span, err)
-			return Info{}, fmt.Errorf("read base config: %w", err)
-		}
+	cfgBytes, err := cloneConfig(baseDir, base, name, golden, opts)
+	if err != nil {
+		recordSpanError(span, err)
+		return Info{}, err
 	}
-
-	cfgBytes = sanitizeConfigINI(cfgBytes)
-	// Disable QCOW2 overlays (use raw IMG full copies)
-	cfgStr := string(cfgBytes)
-	cfgStr = strings.ReplaceAll(cfgStr, "userdata.useQcow2=yes", "userdata.useQcow2=no")
-	if !strings.Contains(cfgStr, "userdata.useQcow2") {
-		cfgStr += "\nuserdata.useQcow2=no\n"
+	if selfContained {
+		cfgBytes = pointSysdirAtGolden(cfgBytes, absGoldenDir)
 	}
-	if err := os.WriteFile(dstCfg, []byte(cfgStr), 0o644); err != nil {
+	if err := os.WriteFile(dstCfg, cfgBytes, 0o644); err != nil {
 		recordSpanError(span, err)
 		return Info{}, fmt.Errorf("write clone config: %w", err)
 	}
 
 	// ---------------------------------------------------------------------
-	// 2. Symlink read-only artifacts from base to clone
+	// 2. Symlink read-only artifacts from base to clone, or hardlink/copy them per opts.Links
+	//    (copied instead of symlinked on shared storage, where absolute links break across mounts)
 	// ---------------------------------------------------------------------
+	storage, err := detectStorageFn(env.AVDHome)
+	if err != nil {
+		recordSpanError(span, err)
+		return Info{}, fmt.Errorf("detect storage: %w", err)
+	}
+	layout := goldenLayout(absGoldenDir)
+	layoutImage := map[string]bool{}
+	for _, img := range layout.Images {
+		layoutImage[filepath.Clean(img.Name)] = true
+	}
+	span.SetAttributes(attribute.String("fs_type", storage.FSType), attribute.Bool("shared_storage", storage.Shared))
 	err = filepath.WalkDir(baseDir, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
@@ -278,12 +498,17 @@ func CloneFromGolden(env Env, base, name, golden string) (Info, error) {
 		}
 		rel, _ := filepath.Rel(baseDir, path)
 
-		// Skip: snapshots, cache*, userdata*, encryptionkey*, config.ini, locks
+		// Skip: snapshots, cache*, userdata*, encryptionkey*, config.ini, hardware-qemu.ini (written
+		// below), avdctl's sidecars of the base, locks, and the golden's layout images, which are
+		// copied below (a symlink there would let the copy write into the base)
 		if strings.HasPrefix(rel, "snapshots") ||
 			strings.HasPrefix(rel, "cache") ||
 			strings.HasPrefix(rel, "userdata") ||
 			strings.HasPrefix(rel, "encryptionkey") ||
+			strings.HasPrefix(rel, sidecarPrefix) ||
 			rel == "config.ini" ||
+			rel == hardwareConfigFilename ||
+			layoutImage[rel] || layoutImage[strings.TrimSuffix(rel, ".qcow2")] ||
 			strings.HasSuffix(rel, ".lock") {
 			return nil
 		}
@@ -292,54 +517,51 @@ func CloneFromGolden(env Env, base, name, golden string) (Info, error) {
 		if d.IsDir() {
 			return os.MkdirAll(dst, 0o755)
 		}
-		// Symlink
-		if err := os.Symlink(path, dst); err != nil && !os.IsExist(err) {
-			return err
-		}
-		return nil
+		_, err = linkArtifact(path, dst, opts.Links.modeFor(env, rel), storage)
+		return err
 	})
 	if err != nil {
 		recordSpanError(span, err)
 		return Info{}, err
 	}
+	if err := writeCloneHardwareConfig(env, baseDir, cloneDir); err != nil {
+		recordSpanError(span, err)
+		return Info{}, err
+	}
 
 	// ---------------------------------------------------------------------
 	// 3. Copy raw IMG files from golden directory (full copy, no overlays)
 	// ---------------------------------------------------------------------
-	images := []string{"userdata-qemu.img", "encryptionkey.img", "cache.img", "sdcard.img"}
-	for _, img := range images {
+	// Images are copied concurrently, at most Env.IOParallelism at a time.
+	err = runIOPool(env, len(layout.Images), func(i int) error {
+		img := layout.Images[i].Name
 		goldenFile := filepath.Join(absGoldenDir, img)
 		if _, err := os.Stat(goldenFile); err != nil {
 			// If sdcard.img is missing, create it from config.ini sdcard.size
 			if img == "sdcard.img" {
 				if err := createSDCard(env, cloneDir, dstCfg); err != nil {
-					recordSpanError(span, err)
-					return Info{}, fmt.Errorf("create sdcard: %w", err)
+					return fmt.Errorf("create sdcard: %w", err)
 				}
 			}
-			continue // Skip if golden image doesn't exist
+			return nil // Skip if golden image doesn't exist
 		}
 
-		dstFile := filepath.Join(cloneDir, img)
-		// Stream copy to avoid loading entire file into memory
-		src, err := os.Open(goldenFile)
-		if err != nil {
-			recordSpanError(span, err)
-			return Info{}, fmt.Errorf("open golden %s: %w", img, err)
+		var progress func(copied, total int64)
+		if opts.Progress != nil {
+			progress = func(copied, total int64) { opts.Progress(img, copied, total) }
 		}
-		dst, err := os.OpenFile(dstFile, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
-		if err != nil {
-			src.Close()
-			recordSpanError(span, err)
-			return Info{}, fmt.Errorf("create clone %s: %w", img, err)
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(cloneDir, img)), 0o755); err != nil {
+			return err
 		}
-		_, err = io.Copy(dst, src)
-		src.Close()
-		dst.Close()
-		if err != nil {
-			recordSpanError(span, err)
-			return Info{}, fmt.Errorf("copy %s: %w", img, err)
+		// Reflinked or streamed by default, holes preserved; never read into memory.
+		if err := materializeImage(env, absGoldenDir, cloneDir, img, progress); err != nil {
+			return fmt.Errorf("copy %s: %w", img, err)
 		}
+		return nil
+	})
+	if err != nil {
+		recordSpanError(span, err)
+		return Info{}, err
 	}
 
 	// ---------------------------------------------------------------------
@@ -364,6 +586,34 @@ func CloneFromGolden(env Env, base, name, golden string) (Info, error) {
 	if err := os.WriteFile(ini, []byte(body), 0o644); err != nil {
 		return Info{}, err
 	}
+	identity, err := newCloneIdentity(env)
+	if err != nil {
+		recordSpanError(span, err)
+		return Info{}, fmt.Errorf("generate clone identity: %w", err)
+	}
+	if err := writeCloneIdentity(cloneDir, identity); err != nil {
+		recordSpanError(span, err)
+		return Info{}, err
+	}
+	// The clone's userdata carries the golden's customizations; keep them on record.
+	if manifest, err := ReadGoldenManifest(absGoldenDir); err == nil && manifest.Customizations != nil {
+		if err := recordCustomizations(cloneDir, *manifest.Customizations); err != nil {
+			recordSpanError(span, err)
+			return Info{}, err
+		}
+	}
+	if err := recordImageHashes(cloneDir, manifestImageHashes(absGoldenDir)); err != nil {
+		recordSpanError(span, err)
+		return Info{}, err
+	}
+	if err := writeCloneLayout(cloneDir, layout); err != nil {
+		recordSpanError(span, err)
+		return Info{}, err
+	}
+	if err := writeCloneOptions(cloneDir, opts); err != nil {
+		recordSpanError(span, err)
+		return Info{}, err
+	}
 	if err := writeCloneFingerprint(cloneDir, fingerprint); err != nil {
 		return Info{}, err
 	}
@@ -400,9 +650,84 @@ func CloneFromGolden(env Env, base, name, golden string) (Info, error) {
 		"size_bytes",
 		fi.Size(),
 	)
+	recordUsage(env, usageDisk, name, "")
 	return info, nil
 }
 
+// CloneMany clones golden into each of names with the same options, at most
+// Env.IOParallelism clones at a time (each copying its images one by one). It returns the
+// clones that were created, in names order, and the errors of the others.
+func CloneMany(env Env, base, golden string, names []string, opts CloneOptions) ([]Info, error) {
+	_, span := startSpan(env, "avd.CloneMany", attribute.String("base", base), attribute.Int("clones", len(names)))
+	defer span.End()
+	golden, err := ResolveGolden(env, golden)
+	if err != nil {
+		recordSpanError(span, err)
+		return nil, err
+	}
+	for _, name := range names {
+		if err := ValidateName(env, name); err != nil {
+			recordSpanError(span, err)
+			return nil, err
+		}
+	}
+	inner := env
+	inner.IOParallelism = 1
+	infos := make([]Info, len(names))
+	err = runIOPool(env, len(names), func(i int) error {
+		info, err := CloneFromGoldenWithOptions(inner, base, names[i], golden, opts)
+		if err != nil {
+			return fmt.Errorf("clone %s: %w", names[i], err)
+		}
+		infos[i] = info
+		return nil
+	})
+	var created []Info
+	for _, info := range infos {
+		if info.Name != "" {
+			created = append(created, info)
+		}
+	}
+	recordSpanError(span, err)
+	return created, err
+}
+
+// cloneConfig produces a clone's config.ini: AVDCTL_CONFIG_TEMPLATE rendered with the clone's
+// variables when set, the base config otherwise, sanitized and with QCOW2 overlays disabled.
+func cloneConfig(baseDir, base, name, golden string, opts CloneOptions) ([]byte, error) {
+	var cfgBytes []byte
+	var err error
+	switch tpl := os.Getenv("AVDCTL_CONFIG_TEMPLATE"); {
+	case tpl != "":
+		cfgBytes, err = os.ReadFile(tpl)
+		if err != nil {
+			return nil, fmt.Errorf("read template: %w", err)
+		}
+		data, err := configTemplateData(base, name, golden, opts)
+		if err != nil {
+			return nil, err
+		}
+		cfgBytes, err = renderConfigTemplate(cfgBytes, data)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		cfgBytes, err = os.ReadFile(filepath.Join(baseDir, "config.ini"))
+		if err != nil {
+			return nil, fmt.Errorf("read base config: %w", err)
+		}
+	}
+
+	cfgBytes = sanitizeConfigINI(cfgBytes)
+	// Disable QCOW2 overlays (use raw IMG full copies)
+	cfgStr := string(cfgBytes)
+	cfgStr = strings.ReplaceAll(cfgStr, "userdata.useQcow2=yes", "userdata.useQcow2=no")
+	if !strings.Contains(cfgStr, "userdata.useQcow2") {
+		cfgStr += "\nuserdata.useQcow2=no\n"
+	}
+	return []byte(cfgStr), nil
+}
+
 func sanitizeConfigINI(b []byte) []byte {
 	lines := strings.Split(string(b), "\n")
 	out := make([]string, 0, len(lines))
@@ -440,7 +765,7 @@ func goldenFingerprint(path string) (string, error) {
 			return entries[i].Name() < entries[j].Name()
 		})
 		for _, entry := range entries {
-			if entry.IsDir() {
+			if entry.IsDir() || strings.HasPrefix(entry.Name(), goldenManifestFilename) {
 				continue
 			}
 			st, err := entry.Info()
@@ -486,42 +811,26 @@ func isCloneDir(path string) bool {
 	return err == nil
 }
 
-func StartEmulator(env Env, name string, extraArgs ...string) (*exec.Cmd, error) {
-	_, span := startSpan(
-		env,
-		"avd.StartEmulator",
-		attribute.String("name", name),
-	)
-	defer span.End()
-	logEvent(env, "emulator start requested", "name", name)
-	args := []string{
-		"-avd", name,
-		"-no-window",
-		"-no-boot-anim",
-		"-no-snapshot",
-		"-no-snapshot-load",
-		"-no-snapshot-save",
-		"-skip-adb-auth",
-		"-no-metrics",
-		"-no-location-ui",
-		"-no-audio",
-		"-read-only",
-		"-gpu", "swiftshader_indirect",
-		"-logcat", "*:S",
-	}
-
-	args = append(args, extraArgs...)
-	cmd := commandWithEnv([]string{"QEMU_FILE_LOCKING=off", "ADB_VENDOR_KEYS=/dev/null"}, env.Emulator, args...)
-	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
-	cmd.Stderr = newLineLogWriterWithMessage(env, "emulator stderr", "name", name, "stream", "stderr")
-	if err := cmd.Start(); err != nil {
-		recordSpanError(span, err)
-		logEvent(env, "emulator start failed", "name", name, "error", err)
-		return nil, fmt.Errorf("emulator start: %w", err)
+// StartResult describes an instance started by StartEmulator, StartEmulatorOnPort,
+// StartEphemeralOnPort or RunAVD.
+type StartResult struct {
+	Name    string    `json:"name"`
+	Serial  string    `json:"serial"`
+	Port    int       `json:"port"`
+	LogPath string    `json:"log_path"`
+	PID     int       `json:"pid"`
+	Mode    StartMode `json:"mode"`
+	Cmd     *exec.Cmd `json:"-"` // the started process; killing it stops the instance
+}
+
+// StartEmulator starts name headless on its sticky console port (see SetStickyPort), or the
+// first free one, like StartEmulatorOnPort.
+func StartEmulator(env Env, name string, extraArgs ...string) (StartResult, error) {
+	port, err := stickyPort(env, name)
+	if err != nil {
+		return StartResult{}, err
 	}
-	span.SetAttributes(attribute.Int("pid", cmd.Process.Pid))
-	logEvent(env, "emulator started", "name", name, "pid", cmd.Process.Pid)
-	return cmd, nil
+	return StartEmulatorOnPort(env, name, port, extraArgs...)
 }
 
 func GuessEmulatorSerial(env Env) (string, error) {
@@ -545,6 +854,11 @@ func WaitForBootWithProgress(
 	timeout time.Duration,
 	progress BootProgressFunc,
 ) error {
+	return waitForBoot(env, serial, timeout, progress, nil)
+}
+
+// waitForBoot is WaitForBootWithProgress with per-phase timeouts.
+func waitForBoot(env Env, serial string, timeout time.Duration, progress BootProgressFunc, phases PhaseTimeouts) (err error) {
 	_, span := startSpan(
 		env,
 		"avd.WaitForBoot",
@@ -552,6 +866,14 @@ func WaitForBootWithProgress(
 		attribute.String("timeout", timeout.String()),
 	)
 	defer span.End()
+	defer func() {
+		if err == nil {
+			recordRunBooted(env, serial)
+		} else if bootTimedOut(err) {
+			detail, _, _ := strings.Cut(err.Error(), "\n")
+			recordRunFailure(env, serial, FailureBootTimeout, detail)
+		}
+	}()
 
 	ctx := env.Context
 	if ctx == nil {
@@ -569,11 +891,14 @@ func WaitForBootWithProgress(
 		progress(status, time.Since(start))
 	}
 
-	reportProgress("waiting_adb")
+	reportProgress(string(BootPhaseWaitingADB))
 	waitErrCh := make(chan error, 1)
 	waitForDeviceTimeout := timeout
 	minWaitForDeviceTimeout := 2 * time.Minute
-	if waitForDeviceTimeout <= 0 {
+	adbDeadline, adbLimited := phases.budget(BootPhaseWaitingADB, deadline)
+	if adbLimited {
+		waitForDeviceTimeout = phases[BootPhaseWaitingADB]
+	} else if waitForDeviceTimeout <= 0 {
 		waitForDeviceTimeout = minWaitForDeviceTimeout
 	} else if waitForDeviceTimeout < minWaitForDeviceTimeout {
 		waitForDeviceTimeout = minWaitForDeviceTimeout
@@ -584,11 +909,15 @@ func WaitForBootWithProgress(
 		waitErrCh <- runWithContext(waitCtx, env, env.ADB, "wait-for-device")
 	}()
 
+	adbPhaseTimeout := &BootPhaseTimeoutError{Serial: serial, Phase: BootPhaseWaitingADB, Timeout: phases[BootPhaseWaitingADB], Detail: "adb never saw the device"}
 	nextProgress := time.Now().Add(5 * time.Second)
 	for {
 		select {
 		case err := <-waitErrCh:
 			if err != nil {
+				if adbLimited && !time.Now().Before(adbDeadline) {
+					err = adbPhaseTimeout
+				}
 				recordSpanError(span, err)
 				return err
 			}
@@ -600,22 +929,28 @@ func WaitForBootWithProgress(
 			recordSpanError(span, ctx.Err())
 			return ctx.Err()
 		}
-		if time.Now().After(deadline) {
+		if time.Now().After(adbDeadline) {
+			if adbLimited {
+				<-waitErrCh // stopped at the same deadline
+				recordSpanError(span, adbPhaseTimeout)
+				return adbPhaseTimeout
+			}
 			break
 		}
 		if time.Now().After(nextProgress) {
-			reportProgress("waiting_adb")
+			reportProgress(string(BootPhaseWaitingADB))
 			nextProgress = time.Now().Add(5 * time.Second)
 		}
 		time.Sleep(500 * time.Millisecond)
 	}
 
 checkBoot:
-	reportProgress("checking_bootanim")
+	reportProgress(string(BootPhaseCheckingBootanim))
 	nextProgress = time.Now().Add(5 * time.Second)
+	bootDeadline, bootLimited := phases.budget(BootPhaseCheckingBootanim, deadline)
 
 	lastError := ""
-	for time.Now().Before(deadline) {
+	for time.Now().Before(bootDeadline) {
 		if ctx.Err() != nil {
 			recordSpanError(span, ctx.Err())
 			return ctx.Err()
@@ -636,7 +971,7 @@ checkBoot:
 		if bootCompleted == "1" {
 			time.Sleep(2 * time.Second)
 			span.SetAttributes(attribute.Bool("boot_completed", true))
-			reportProgress("boot_complete")
+			reportProgress(string(BootPhaseBootComplete))
 			logEvent(
 				env,
 				"emulator boot completed",
@@ -645,6 +980,11 @@ checkBoot:
 				"duration",
 				time.Since(start).String(),
 			)
+			applyCloneIdentityOnBoot(env, serial)
+			if err := waitPostBoot(ctx, env, serial, phases, deadline, reportProgress); err != nil {
+				recordSpanError(span, err)
+				return err
+			}
 			return nil
 		}
 
@@ -656,12 +996,20 @@ checkBoot:
 		}
 
 		if time.Now().After(nextProgress) {
-			reportProgress("checking_bootanim")
+			reportProgress(string(BootPhaseCheckingBootanim))
 			nextProgress = time.Now().Add(5 * time.Second)
 		}
 
 		time.Sleep(500 * time.Millisecond)
 	}
+	if bootLimited {
+		err := &BootPhaseTimeoutError{Serial: serial, Phase: BootPhaseCheckingBootanim, Timeout: phases[BootPhaseCheckingBootanim], Detail: "sys.boot_completed not set"}
+		if lastError != "" {
+			err.Detail += "; last adb error: " + strings.TrimSpace(lastError)
+		}
+		recordSpanError(span, err)
+		return err
+	}
 
 	errMsg := fmt.Sprintf("boot timeout after %s (adb could not confirm boot completion)", timeout)
 	if lastError != "" {
@@ -690,6 +1038,86 @@ func KillEmulator(env Env, serial string) {
 }
 
 func PrewarmGolden(env Env, name, dest string, extra time.Duration, bootTimeout time.Duration) (string, int64, error) {
+	return PrewarmGoldenWithOptions(env, name, dest, extra, bootTimeout, PrewarmOptions{})
+}
+
+// PrewarmOptions customizes PrewarmGoldenWithOptions.
+type PrewarmOptions struct {
+	// ADBKeys are adbkey.pub files written to /data/misc/adb/adb_keys before export, so clones
+	// accept those hosts without the RSA authorization dialog. Defaults to Env.ADBKeyFiles.
+	ADBKeys []string
+	// SelfContained is passed to SaveGoldenWithOptions.
+	SelfContained bool
+	// Settings are adb shell commands run after boot, before export, and recorded as
+	// customizations of the AVD (see Customizations).
+	Settings []string
+	// AccessibilityServices are components ("package/.Class") enabled after Settings and
+	// checked active before export. The apps must already be installed in the AVD.
+	AccessibilityServices []string
+	// WarmApps are packages launched once until their first frame, then stopped, so the ART
+	// profiles of a first run are in the golden.
+	WarmApps []string
+	// CompileFilter, when set, compiles all packages with `cmd package compile -m <filter> -a`
+	// after WarmApps; "speed-profile" uses the recorded profiles. See CompilerFilters.
+	CompileFilter string
+	// FreezeUpdates turns off automatic system updates and disables the Play Store, which
+	// delivers app updates and Google Play system updates, after the warm-up. Clones then stay
+	// identical to the golden. The commands are recorded as customizations.
+	FreezeUpdates bool
+}
+
+// completeSetup skips the setup wizard and disables the lockscreen of a booted emulator.
+func completeSetup(env Env, serial string) {
+	_ = run(env, env.ADB, "-s", serial, "shell", "settings", "put", "global", "device_provisioned", "1")
+	_ = run(env, env.ADB, "-s", serial, "shell", "settings", "put", "secure", "user_setup_complete", "1")
+	_ = run(env, env.ADB, "-s", serial, "shell", "locksettings", "set-disabled", "true")
+	_ = run(env, env.ADB, "-s", serial, "shell", "wm", "dismiss-keyguard")
+	_ = run(env, env.ADB, "-s", serial, "shell", "input", "keyevent", "82") // MENU key to wake/unlock
+}
+
+// applySettings runs each command with adb shell, stopping at the first failure.
+func applySettings(env Env, serial string, settings []string) error {
+	for _, setting := range settings {
+		if err := applySetting(env, serial, setting); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func applySetting(env Env, serial, setting string) error {
+	out, err := runCommandCombinedOutputWithEnv(env.Context, nil, nil, env.ADB, "-s", serial, "shell", setting)
+	if err != nil {
+		return fmt.Errorf("setting %q: %w: %s", setting, err, bytes.TrimSpace(out))
+	}
+	return nil
+}
+
+// PrewarmGoldenWithOptions is PrewarmGolden with optional ADB key preseeding, settings and app
+// warm-up. When keys, accessibility services, a warm-up or an update freeze are requested, the
+// golden is only exported after a full boot, since they are applied over adb.
+func PrewarmGoldenWithOptions(env Env, name, dest string, extra time.Duration, bootTimeout time.Duration, opts PrewarmOptions) (string, int64, error) {
+	report, err := PrewarmGoldenWithReport(env, name, dest, extra, bootTimeout, opts)
+	return report.Path, report.SizeBytes, err
+}
+
+// PrewarmGoldenWithReport is PrewarmGoldenWithOptions returning the boot and resource metrics of
+// the run along with the golden. BootDuration stays 0 when the golden is saved after a boot
+// timeout.
+func PrewarmGoldenWithReport(env Env, name, dest string, extra time.Duration, bootTimeout time.Duration, opts PrewarmOptions) (BuildReport, error) {
+	keyFiles := opts.ADBKeys
+	if len(keyFiles) == 0 {
+		keyFiles = env.ADBKeyFiles
+	}
+	keys, err := ReadADBPublicKeys(keyFiles)
+	if err != nil {
+		return BuildReport{}, err
+	}
+	if err := validateWarmUp(opts); err != nil {
+		return BuildReport{}, err
+	}
+	saveOpts := SaveGoldenOptions{SelfContained: opts.SelfContained}
+
 	// Restart ADB server to clear stale state
 	_ = run(env, env.ADB, "kill-server")
 	time.Sleep(1 * time.Second)
@@ -698,177 +1126,338 @@ func PrewarmGolden(env Env, name, dest string, extra time.Duration, bootTimeout
 	// Find a free port dynamically to avoid conflicts
 	port, err := FindFreeEvenPortWithEnv(env, 5580, 5800)
 	if err != nil {
-		return "", 0, fmt.Errorf("no free port available for prewarming: %w", err)
+		return BuildReport{}, fmt.Errorf("no free port available for prewarming: %w", err)
 	}
-	cmd, serial, logPath, err := StartEmulatorOnPort(env, name, port)
+	avdPath := filepath.Join(env.AVDHome, name+".avd")
+	report := BuildReport{ImageBytesBefore: imageBytes(avdPath)}
+	started := time.Now()
+	res, err := StartEmulatorOnPort(env, name, port)
 	if err != nil {
-		return "", 0, err
+		return BuildReport{}, err
 	}
-	defer func() {
-		if cmd != nil && cmd.Process != nil {
-			_ = cmd.Process.Kill()
-		}
-	}()
+	serial, logPath := res.Serial, res.LogPath
+	defer func() { _ = res.Cmd.Process.Kill() }()
 
 	// Wait until adb sees that specific emulator serial
 	if err := waitForEmulatorSerial(env, serial, 60*time.Second); err != nil {
-		return "", 0, fmt.Errorf("ADB failed to detect emulator serial %s: %w\nEmulator log: %s\nNote: The emulator may still be starting. Check the log file for details.", serial, err, logPath)
+		return BuildReport{}, fmt.Errorf("ADB failed to detect emulator serial %s: %w\nEmulator log: %s\nNote: The emulator may still be starting. Check the log file for details.", serial, err, logPath)
 	}
 
 	// Now wait for Android to finish booting
 	if err := WaitForBoot(env, serial, bootTimeout); err != nil {
+		if len(keys) > 0 || len(opts.AccessibilityServices) > 0 || len(opts.WarmApps) > 0 || opts.CompileFilter != "" || opts.FreezeUpdates {
+			return BuildReport{}, fmt.Errorf("%w\nEmulator log: %s", err, logPath)
+		}
 		// Check if userdata was created (indicates boot likely succeeded)
-		avdPath := filepath.Join(env.AVDHome, name+".avd")
-		userdata1 := filepath.Join(avdPath, "userdata-qemu.img.qcow2")
-		userdata2 := filepath.Join(avdPath, "userdata-qemu.img")
-		if st, statErr := os.Stat(userdata1); statErr == nil && st.Size() > 1024*1024 {
+		if _, size := userdataImage(avdPath); size > 1024*1024 {
+			report.PeakRSSBytes = peakRSS(res.Cmd.Process.Pid)
 			KillEmulator(env, serial)
-			return SaveGolden(env, name, dest)
+			report.ImageBytesAfter = imageBytes(avdPath)
+			report.Path, report.SizeBytes, err = SaveGoldenWithOptions(env, name, dest, saveOpts)
+			if err != nil {
+				return BuildReport{}, err
+			}
+			return report, nil
 		}
-		if st, statErr := os.Stat(userdata2); statErr == nil && st.Size() > 1024*1024 {
-			KillEmulator(env, serial)
-			return SaveGolden(env, name, dest)
+		return BuildReport{}, fmt.Errorf("%w\nEmulator log: %s", err, logPath)
+	}
+
+	report.BootDuration = time.Since(started)
+	booted := time.Now()
+
+	completeSetup(env, serial)
+	if len(opts.Settings) > 0 {
+		if err := applySettings(env, serial, opts.Settings); err != nil {
+			return BuildReport{}, err
+		}
+		if err := recordCustomizations(avdPath, Customizations{Settings: opts.Settings}); err != nil {
+			return BuildReport{}, err
+		}
+	}
+	for _, component := range opts.AccessibilityServices {
+		settings, err := enableAccessibilityService(env, serial, component)
+		if err != nil {
+			return BuildReport{}, err
+		}
+		if err := recordCustomizations(avdPath, Customizations{Settings: settings}); err != nil {
+			return BuildReport{}, err
+		}
+	}
+	if err := warmUp(env, serial, opts.WarmApps, opts.CompileFilter); err != nil {
+		return BuildReport{}, err
+	}
+	if opts.FreezeUpdates {
+		settings, err := freezeUpdates(env, serial)
+		if err != nil {
+			return BuildReport{}, err
+		}
+		if err := recordCustomizations(avdPath, Customizations{Settings: settings}); err != nil {
+			return BuildReport{}, err
 		}
-		return "", 0, fmt.Errorf("%w\nEmulator log: %s", err, logPath)
 	}
 
-	// Disable lockscreen and complete setup
-	_ = run(env, env.ADB, "-s", serial, "shell", "settings", "put", "global", "device_provisioned", "1")
-	_ = run(env, env.ADB, "-s", serial, "shell", "settings", "put", "secure", "user_setup_complete", "1")
-	_ = run(env, env.ADB, "-s", serial, "shell", "locksettings", "set-disabled", "true")
-	_ = run(env, env.ADB, "-s", serial, "shell", "wm", "dismiss-keyguard")
-	_ = run(env, env.ADB, "-s", serial, "shell", "input", "keyevent", "82") // MENU key to wake/unlock
+	if len(keys) > 0 {
+		if err := PreseedADBKeys(env, serial, keys); err != nil {
+			return BuildReport{}, err
+		}
+	}
 
 	if extra > 0 {
 		time.Sleep(extra)
 	}
 
+	// Record the integrity posture so clones can be checked against it later.
+	integrity, err := CheckIntegrity(env, serial)
+	if err != nil {
+		logEvent(env, "golden integrity check failed", "name", name, "error", err)
+	}
+
+	report.SettleDuration = time.Since(booted)
+	report.Packages = installedPackages(env, serial)
+	report.PeakRSSBytes = peakRSS(res.Cmd.Process.Pid)
 	KillEmulator(env, serial)
-	return SaveGolden(env, name, dest)
+	report.ImageBytesAfter = imageBytes(avdPath)
+	goldenDir, size, err := SaveGoldenWithOptions(env, name, dest, saveOpts)
+	if err != nil {
+		return BuildReport{}, err
+	}
+	manifest, err := ReadGoldenManifest(goldenDir)
+	if err != nil {
+		return BuildReport{}, err
+	}
+	manifest.ADBKeys = keys
+	if integrity.Verdict != "" {
+		integrity.Serial = ""
+		manifest.Integrity = &integrity
+	}
+	if err := writeGoldenManifest(goldenDir, manifest); err != nil {
+		return BuildReport{}, err
+	}
+	report.Path, report.SizeBytes = goldenDir, size
+	logBuildReport(env, name, report)
+	return report, nil
+}
+
+// RunAVD starts name like StartEmulator and waits until adb sees its serial.
+func RunAVD(env Env, name string, extraArgs ...string) (StartResult, error) {
+	return runAVD(env, name, false, extraArgs...)
 }
 
-func RunAVD(env Env, name string, extraArgs ...string) (string, error) {
+func runAVD(env Env, name string, ephemeral bool, extraArgs ...string) (StartResult, error) {
 	_, span := startSpan(
 		env,
 		"avd.RunAVD",
 		attribute.String("name", name),
+		attribute.Bool("ephemeral", ephemeral),
 	)
 	defer span.End()
 	ensureADB(env)
-	port, err := FindFreeEvenPortWithEnv(env, 5580, 5800)
+	port, err := stickyPort(env, name)
 	if err != nil {
 		recordSpanError(span, err)
-		return "", err
+		return StartResult{}, err
+	}
+	start := StartEmulatorOnPort
+	if ephemeral {
+		start = StartEphemeralOnPort
 	}
-	_, serial, logPath, err := StartEmulatorOnPort(env, name, port, extraArgs...)
+	res, err := start(env, name, port, extraArgs...)
 	if err != nil {
 		recordSpanError(span, err)
-		return "", err
+		return StartResult{}, err
 	}
 
 	// wait up to 60s for adb to see this exact serial
-	if err := waitForEmulatorSerial(env, serial, 60*time.Second); err != nil {
+	if err := waitForEmulatorSerial(env, res.Serial, 60*time.Second); err != nil {
 		recordSpanError(span, err)
-		return "", fmt.Errorf("%w\nemulator log: %s", err, logPath)
+		return res, fmt.Errorf("%w\nemulator log: %s", err, res.LogPath)
 	}
-	span.SetAttributes(attribute.String("serial", serial))
-	fmt.Printf("Started %s on %s (log: %s)\n", name, serial, logPath)
-	return serial, nil
+	span.SetAttributes(attribute.String("serial", res.Serial))
+	return res, nil
 }
 
 func BakeAPK(env Env, base, name, golden string, apks []string, timeout time.Duration) (string, int64, error) {
-	if _, err := CloneFromGolden(env, base, name, golden); err != nil {
-		return "", 0, err
+	return BakeAPKWithOptions(env, base, name, golden, apks, timeout, BakeOptions{})
+}
+
+// BakeOptions are the optional steps of BakeAPKWithOptions.
+type BakeOptions struct {
+	// FridaServer is a frida-server binary for the guest ABI installed in the clone after the
+	// APKs, so every clone of the exported golden can be instrumented (see StartFrida).
+	// Needs an image whose adbd runs as root (not Play Store images).
+	FridaServer string
+	// OBBs are expansion files (main|patch.<versionCode>.<package>.obb) pushed to
+	// /sdcard/Android/obb/<package>/ after the APKs.
+	OBBs []string
+	// AppData are directories copied into the data directories of installed apps after the
+	// OBBs (see PushAppData): as root on root-capable images, else through run-as.
+	AppData []AppData
+}
+
+// BakeAPKWithOptions is BakeAPK with optional provisioning steps: expansion files, app data
+// and dynamic-analysis prerequisites. Everything is checked before the clone is created.
+func BakeAPKWithOptions(env Env, base, name, golden string, apks []string, timeout time.Duration, opts BakeOptions) (string, int64, error) {
+	report, err := BakeAPKWithReport(env, base, name, golden, apks, timeout, opts)
+	return report.Path, report.SizeBytes, err
+}
+
+// BakeAPKWithReport is BakeAPKWithOptions returning the boot and resource metrics of the run
+// along with the data image of the clone. ImageBytesBefore is measured on the fresh clone.
+func BakeAPKWithReport(env Env, base, name, golden string, apks []string, timeout time.Duration, opts BakeOptions) (BuildReport, error) {
+	if opts.FridaServer != "" {
+		if _, err := os.Stat(opts.FridaServer); err != nil {
+			return BuildReport{}, fmt.Errorf("frida-server: %w", err)
+		}
+	}
+	obbs := make([]string, 0, len(opts.OBBs))
+	for _, obb := range opts.OBBs {
+		if _, err := obbPackage(obb); err != nil {
+			return BuildReport{}, err
+		}
+		if _, err := os.Stat(obb); err != nil {
+			return BuildReport{}, fmt.Errorf("obb: %w", err)
+		}
+		if abs, err := filepath.Abs(obb); err == nil {
+			obb = abs
+		}
+		obbs = append(obbs, obb)
+	}
+	appData := make([]AppData, 0, len(opts.AppData))
+	for _, data := range opts.AppData {
+		if err := data.Validate(); err != nil {
+			return BuildReport{}, err
+		}
+		if abs, err := filepath.Abs(data.Dir); err == nil {
+			data.Dir = abs
+		}
+		appData = append(appData, data)
 	}
-	cmd, err := StartEmulator(env, name)
+	sets, packages, err := prepareBakeAPKs(env, base, apks)
 	if err != nil {
-		return "", 0, err
+		return BuildReport{}, err
 	}
 	defer func() {
-		if cmd != nil && cmd.Process != nil {
-			_ = cmd.Process.Kill()
+		for _, set := range sets {
+			set.cleanup()
 		}
 	}()
-
-	serial, err := GuessEmulatorSerial(env)
+	if _, err := CloneFromGolden(env, base, name, golden); err != nil {
+		return BuildReport{}, err
+	}
+	cloneDir := filepath.Join(env.AVDHome, name+".avd")
+	report := BuildReport{ImageBytesBefore: imageBytes(cloneDir)}
+	started := time.Now()
+	res, err := StartEmulator(env, name)
 	if err != nil {
-		return "", 0, err
+		return BuildReport{}, err
 	}
+	defer func() { _ = res.Cmd.Process.Kill() }()
+
+	serial := res.Serial
 	if err := WaitForBoot(env, serial, timeout); err != nil {
-		return "", 0, err
+		return BuildReport{}, err
 	}
-	for _, apk := range apks {
-		if err := run(env, env.ADB, "-s", serial, "install", "-r", apk); err != nil {
-			return "", 0, fmt.Errorf("install %s: %w", apk, err)
+	report.BootDuration = time.Since(started)
+	booted := time.Now()
+	installed := make([]string, 0, len(sets))
+	for _, set := range sets {
+		if err := set.install(env, serial); err != nil {
+			return BuildReport{}, err
+		}
+		installed = append(installed, set.Source)
+	}
+	for _, obb := range obbs {
+		if err := PushOBB(env, serial, obb); err != nil {
+			return BuildReport{}, err
 		}
 	}
+	for _, data := range appData {
+		if err := PushAppData(env, serial, data); err != nil {
+			return BuildReport{}, err
+		}
+	}
+	if opts.FridaServer != "" {
+		if err := InstallFridaServer(env, serial, opts.FridaServer); err != nil {
+			return BuildReport{}, err
+		}
+	}
+	report.SettleDuration = time.Since(booted)
+	report.Packages = installedPackages(env, serial)
+	report.PeakRSSBytes = peakRSS(res.Cmd.Process.Pid)
 	KillEmulator(env, serial)
+	report.ImageBytesAfter = imageBytes(cloneDir)
 
 	// Return overlay path and size
-	cloneDir := filepath.Join(env.AVDHome, name+".avd")
-	ud := filepath.Join(cloneDir, "userdata-qemu.img.qcow2")
-	if _, err := os.Stat(ud); err != nil {
-		ud = filepath.Join(cloneDir, "userdata-qemu.img")
+	if err := recordCustomizations(cloneDir, Customizations{APKs: installed, Packages: packages, OBBs: obbs, AppData: appData}); err != nil {
+		return BuildReport{}, err
 	}
-	st, _ := os.Stat(ud)
-	return ud, st.Size(), nil
+	report.Path, report.SizeBytes = userdataImage(cloneDir)
+	logBuildReport(env, name, report)
+	return report, nil
 }
 
-func Delete(env Env, name string) error {
-	if name == "" {
-		return errors.New("empty name")
-	}
-	avdDir := filepath.Join(env.AVDHome, name+".avd")
-	ini := filepath.Join(env.AVDHome, name+".ini")
-
-	if _, err := os.Stat(avdDir); err != nil {
-		if os.IsNotExist(err) {
-			if _, iniErr := os.Stat(ini); os.IsNotExist(iniErr) {
-				return nil
-			}
-		} else {
-			return err
+// prepareBakeAPKs resolves each APK, split set or bundle for base and reads its manifest. The
+// bake is refused before anything is cloned when one cannot be installed on the API level of
+// base. The caller cleans the returned sets up.
+func prepareBakeAPKs(env Env, base string, apks []string) ([]apkSet, []APKMetadata, error) {
+	info := Info{Name: base, Path: filepath.Join(env.AVDHome, base+".avd")}
+	describeAVD(&info, nil)
+	spec := func() (DeviceSpec, error) { return DeviceSpecForAVD(env, base) }
+	var errs []error
+	sets := make([]apkSet, 0, len(apks))
+	packages := make([]APKMetadata, 0, len(apks))
+	for _, apk := range apks {
+		if abs, err := filepath.Abs(apk); err == nil {
+			apk = abs
 		}
+		set, err := prepareAPKSet(env, apk, spec)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		sets = append(sets, set)
+		meta, err := set.metadata()
+		if err == nil {
+			err = meta.CompatibleWith(info.APILevel)
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		logEvent(env, "apk inspected", "apk", apk, "package", meta.Package, "version", meta.VersionName,
+			"min_sdk", meta.MinSDK, "target_sdk", meta.TargetSDK, "splits", len(set.APKs))
+		packages = append(packages, meta)
 	}
-
-	procs, err := ListRunning(env)
-	if err != nil {
-		return err
-	}
-	for _, proc := range procs {
-		if proc.Name == name {
-			return fmt.Errorf("cannot delete running AVD %s; stop it first", name)
+	if err := errors.Join(errs...); err != nil {
+		for _, set := range sets {
+			set.cleanup()
 		}
+		return nil, nil, fmt.Errorf("cannot bake into %s: %w", base, err)
 	}
-
-	_ = os.RemoveAll(avdDir)
-	_ = os.Remove(ini)
-	return nil
+	return sets, packages, nil
 }
 
 func infoOf(env Env, name string) (Info, error) {
 	dir := filepath.Join(env.AVDHome, name+".avd")
-	ud := filepath.Join(dir, "userdata-qemu.img.qcow2")
-	if _, err := os.Stat(ud); err != nil {
-		alt := filepath.Join(dir, "userdata-qemu.img")
-		if _, err2 := os.Stat(alt); err2 == nil {
-			ud = alt
-		} else {
-			ud = filepath.Join(dir, "userdata.img")
-		}
-	}
-	var sz int64
-	if st, err := os.Stat(ud); err == nil {
-		sz = st.Size()
-	}
+	ud, sz := userdataImage(dir)
 	return Info{Name: name, Path: dir, Userdata: ud, SizeBytes: sz}, nil
 }
 
 // ensureADB starts adb server (idempotent).
 func ensureADB(env Env) { _ = run(env, env.ADB, "start-server") }
 
-// StartEmulatorOnPort starts emulator with a fixed port and returns (*exec.Cmd, serial, logPath).
-func StartEmulatorOnPort(env Env, name string, port int, extraArgs ...string) (*exec.Cmd, string, string, error) {
+// StartEmulatorOnPort starts name headless on console port (even, 5554-5800), with its
+// output in a log file under the temp dir. extraArgs are folded into HeadlessArgs, so they
+// override its defaults (see StartEmulatorWithArgs).
+func StartEmulatorOnPort(env Env, name string, port int, extraArgs ...string) (StartResult, error) {
+	return StartEmulatorWithArgs(env, HeadlessArgs(name, port).withExtra(extraArgs...))
+}
+
+// StartEmulatorWithArgs starts the emulator described by a, which must have a console port,
+// detached from avdctl and with its output in a log file under the temp dir. The clone's
+// identity MAC and the environment's default flags are added unless a sets them.
+func StartEmulatorWithArgs(env Env, a EmulatorArgs) (StartResult, error) {
+	name, port := a.Name, a.Port
 	_, span := startSpan(
 		env,
 		"avd.StartEmulatorOnPort",
@@ -877,16 +1466,47 @@ func StartEmulatorOnPort(env Env, name string, port int, extraArgs ...string) (*
 	)
 	defer span.End()
 	logEvent(env, "emulator start requested", "name", name, "port", port)
-	// emulator uses a pair: <port> and <port+1>; must be even
-	if port%2 != 0 {
-		err := fmt.Errorf("port %d is odd; emulator requires even port numbers (uses port and port+1)", port)
+	if port == 0 {
+		err := errors.New("emulator start needs a console port")
+		recordSpanError(span, err)
+		return StartResult{}, err
+	}
+	if a.WifiMAC == "" {
+		if identity, err := ReadCloneIdentity(env, name); err == nil {
+			a.WifiMAC = identity.WifiMAC
+		}
+	}
+	defaults, err := defaultEmulatorArgs(env)
+	if err != nil {
+		recordSpanError(span, err)
+		return StartResult{}, err
+	}
+	a = a.withDefaults(defaults)
+	if env.SharedAVD {
+		a.ReadOnly = true
+	} else if inUse := waitAVDFree(env, name); inUse != nil {
+		recordSpanError(span, inUse)
+		return StartResult{}, inUse
+	}
+	if err := admitStart(env, name); err != nil {
 		recordSpanError(span, err)
-		return nil, "", "", err
+		return StartResult{}, err
 	}
-	if port < 5554 || port > 5800 {
-		err := fmt.Errorf("port %d out of valid range (5554-5800)", port)
+	if err := a.Validate(); err != nil {
 		recordSpanError(span, err)
-		return nil, "", "", err
+		return StartResult{}, err
+	}
+	extraArgs := a.Extra
+	a.Extra = nil
+	runAs, err := resolveRunAs(env, name)
+	if err != nil {
+		recordSpanError(span, err)
+		return StartResult{}, err
+	}
+	if env.CloneNetns && env.Sandbox.IsolateNetwork {
+		err := errors.New("per-clone network namespaces and sandbox network isolation are exclusive")
+		recordSpanError(span, err)
+		return StartResult{}, err
 	}
 
 	// Check if port is already in use (with retry for TIME_WAIT sockets)
@@ -905,44 +1525,89 @@ func StartEmulatorOnPort(env Env, name string, port int, extraArgs ...string) (*
 				maxRetries,
 			)
 			recordSpanError(span, err)
-			return nil, "", "", err
+			return StartResult{}, err
+		}
+	}
+
+	var scratchEnv []string
+	if env.SharedAVD {
+		if scratchEnv, err = prepareSharedScratch(env, port); err != nil {
+			recordSpanError(span, err)
+			return StartResult{}, err
+		}
+	}
+	if runAs != nil {
+		if err := runAs.handOver(filepath.Join(env.AVDHome, name+".avd"), ephemeralRunDir(env, port)); err != nil {
+			recordSpanError(span, err)
+			return StartResult{}, err
 		}
 	}
 
+	mode := classifyStart(filepath.Join(env.AVDHome, name+".avd"), a)
 	logPath := filepath.Join(os.TempDir(), fmt.Sprintf("emulator-%s-%d.log", name, port))
 	logFile, err := os.Create(logPath)
 	if err != nil {
 		recordSpanError(span, err)
-		return nil, "", "", fmt.Errorf("open log: %w", err)
-	}
-
-	args := []string{
-		"-avd", name,
-		"-port", fmt.Sprint(port),
-		"-no-window",
-		"-no-boot-anim",
-		"-no-snapshot",
-		"-no-snapshot-load",
-		"-no-snapshot-save",
-		"-skip-adb-auth",
-		"-no-metrics",
-		"-no-location-ui",
-		"-no-audio",
-		"-read-only",
-		"-gpu", "swiftshader_indirect",
-		"-logcat", "*:S",
-	}
-
-	args = append(args, extraArgs...)
-	cmd := commandWithEnv([]string{"QEMU_FILE_LOCKING=off", "ADB_VENDOR_KEYS=/dev/null"}, env.Emulator, args...)
+		return StartResult{}, fmt.Errorf("open log: %w", err)
+	}
+
+	args, err := emulatorStartArgs(env, a.flags(), extraArgs)
+	if err != nil {
+		_ = logFile.Close()
+		recordSpanError(span, err)
+		return StartResult{}, err
+	}
+	program := env.Emulator
+	if env.Sandbox.Enabled() {
+		argv, err := env.Sandbox.wrap(port, []string{filepath.Join(env.AVDHome, name+".avd"), ephemeralRunDir(env, port)}, program, args)
+		if err != nil {
+			_ = logFile.Close()
+			recordSpanError(span, err)
+			return StartResult{}, err
+		}
+		program, args = argv[0], argv[1:]
+	}
+	if env.CloneNetns {
+		netns, err := setupCloneNetwork(env, port)
+		if err != nil {
+			_ = logFile.Close()
+			recordSpanError(span, err)
+			return StartResult{}, err
+		}
+		prefix := netns.execPrefix()
+		if runAs != nil {
+			// ip netns exec needs root, so the switch to the user happens inside it.
+			prefix = append(prefix, runAs.setprivPrefix()...)
+		}
+		program, args = prefix[0], append(append(prefix[1:], program), args...)
+	}
+	procEnv := append([]string{"QEMU_FILE_LOCKING=off", "ADB_VENDOR_KEYS=/dev/null"}, sdkProcessEnv(env)...)
+	if runAs != nil {
+		procEnv = append(procEnv, runAs.processEnv(env)...)
+	}
+	procEnv = append(procEnv, scratchEnv...)
+	cmd := commandWithEnv(procEnv, program, args...)
 	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if runAs != nil {
+		if !env.CloneNetns {
+			cmd.SysProcAttr.Credential = runAs.credential()
+		}
+		logEvent(env, "emulator runs as user", "name", name, "user", runAs.Name, "uid", runAs.UID)
+	}
 	// For detached emulators, write directly to a file descriptor instead of parent-owned
 	// pipes (e.g. io.MultiWriter), otherwise the child can die when avdctl exits.
 	cmd.Stdout = logFile
 	cmd.Stderr = logFile
 
+	logEvent(env, "emulator command", "name", name, "cmd", shellJoin(append([]string{program}, args...)))
 	if err := cmd.Start(); err != nil {
 		_ = logFile.Close()
+		if env.CloneNetns {
+			teardownCloneNetwork(env, port)
+		}
+		if env.SharedAVD {
+			_ = os.RemoveAll(sharedScratchDir(env, port))
+		}
 		recordSpanError(span, err)
 		logEvent(
 			env,
@@ -956,14 +1621,30 @@ func StartEmulatorOnPort(env Env, name string, port int, extraArgs ...string) (*
 			"log_path",
 			logPath,
 		)
-		return nil, "", "", fmt.Errorf("emulator start: %w", err)
+		return StartResult{}, fmt.Errorf("emulator start: %w", err)
 	}
 	_ = logFile.Close()
+	recordLastBoot(filepath.Join(env.AVDHome, name+".avd"))
+	rec := instanceRecord{
+		Serial:    fmt.Sprintf("emulator-%d", port),
+		PID:       cmd.Process.Pid,
+		LogPath:   logPath,
+		StartedAt: time.Now().UTC(),
+		Program:   cmd.Path,
+		Args:      args,
+		Emulator:  env.Emulator,
+	}
+	recordInstance(filepath.Join(env.AVDHome, name+".avd"), rec)
+	recordRun(env, filepath.Join(env.AVDHome, name+".avd"), rec)
+	recordUsage(env, usageBoot, name, rec.Serial)
+	clearPauseState(filepath.Join(env.AVDHome, name+".avd"))
+	clearHibernation(filepath.Join(env.AVDHome, name+".avd"))
 	serial := fmt.Sprintf("emulator-%d", port)
 	span.SetAttributes(
 		attribute.String("serial", serial),
 		attribute.Int("pid", cmd.Process.Pid),
 		attribute.String("log_path", logPath),
+		attribute.String("start_mode", string(mode)),
 	)
 	logEvent(
 		env,
@@ -978,8 +1659,10 @@ func StartEmulatorOnPort(env Env, name string, port int, extraArgs ...string) (*
 		cmd.Process.Pid,
 		"log_path",
 		logPath,
+		"mode",
+		mode,
 	)
-	return cmd, serial, logPath, nil
+	return StartResult{Name: name, Serial: serial, Port: port, LogPath: logPath, PID: cmd.Process.Pid, Mode: mode, Cmd: cmd}, nil
 }
 
 // waitForEmulatorSerial polls adb devices for a specific serial.
@@ -1067,11 +1750,32 @@ type ProcInfo struct {
 	Port   int    `json:"port"`
 	PID    int    `json:"pid"`
 	Booted bool   `json:"booted"`
+	// Paused is set for emulators frozen by Pause; adb is not queried for them, so Booted is false.
+	Paused bool `json:"paused,omitempty"`
+	// ADBState is ADBStateOnline, ADBStateOffline or ADBStateUnauthorized; Booted is only checked
+	// when online. It is empty for an emulator adb does not list yet (still starting).
+	ADBState string `json:"adb_state,omitempty"`
+	// LogPath is the emulator log of an instance avdctl started; StartedAt, GPU (the -gpu mode)
+	// and Args (the emulator command line) come from /proc, or from avdctl's start record.
+	LogPath   string     `json:"log_path,omitempty"`
+	StartedAt *time.Time `json:"started_at,omitempty"`
+	GPU       string     `json:"gpu,omitempty"`
+	Args      []string   `json:"args,omitempty"`
+	// Adopted is set for an emulator started outside avdctl and registered with Adopt.
+	Adopted bool `json:"adopted,omitempty"`
+	// EmulatorVersion is the version of the emulator binary the instance was started with
+	// (Env.Emulator for an instance avdctl did not start), when it could be detected.
+	EmulatorVersion string `json:"emulator_version,omitempty"`
+	// Forwards are the adb TCP tunnels of the instance; StopBySerial removes them.
+	Forwards []PortForward `json:"forwards,omitempty"`
 }
 
 type CleanupReport struct {
 	OrphanedProcesses []ProcInfo `json:"orphaned_processes"`
 	OrphanedAVDs      []Info     `json:"orphaned_avds"`
+	// PrunedHistory is what Env.Retention removed (or, without force, would remove) from the
+	// run history of the remaining AVDs.
+	PrunedHistory []PrunedItem `json:"pruned_history,omitempty"`
 }
 
 func ListRunning(env Env) ([]ProcInfo, error) {
@@ -1084,10 +1788,12 @@ func ListRunning(env Env) ([]ProcInfo, error) {
 
 	// Strategy 1: Get emulators from adb devices (may not show all if just started)
 	out, _, _ := runCommandOutputWithEnv(env.Context, nil, nil, env.ADB, "devices")
+	out = recoverOfflineDevices(env, out)
 	for _, line := range strings.Split(out, "\n") {
 		f := parseADBDeviceLine(line)
 		if len(f) >= 2 && strings.HasPrefix(f[0], "emulator-") {
 			serial := f[0]
+			state := adbState(f[1])
 			port := 0
 			if n, err := strconv.Atoi(strings.TrimPrefix(serial, "emulator-")); err == nil {
 				port = n
@@ -1097,15 +1803,33 @@ func ListRunning(env Env) ([]ProcInfo, error) {
 			}
 			seen[port] = true
 
-			// Try to get name from adb, fallback to process cmdline
-			name, _ := GetAVDNameFromSerial(env, serial)
 			pid := findEmulatorPID(port)
 			if pid > 0 && isZombieProcess(pid) {
 				continue
 			}
+			forwards, _ := ListForwards(env, serial)
+			// A paused emulator does not answer adb (nor, when stopped by a signal, the console).
+			if pid > 0 && isStoppedProcess(pid) {
+				procs = append(procs, ProcInfo{Serial: serial, Name: findEmulatorNameFromPID(pid), Port: port, PID: pid, Paused: true, ADBState: state, Forwards: forwards})
+				continue
+			}
+			// Try to get name from adb, fallback to process cmdline
+			name := ""
+			if state == ADBStateOnline {
+				name, _ = GetAVDNameFromSerial(env, serial)
+			}
 			if name == "" && pid > 0 {
 				name = findEmulatorNameFromPID(pid)
 			}
+			if name != "" && consolePaused(filepath.Join(env.AVDHome, name+".avd"), serial) {
+				procs = append(procs, ProcInfo{Serial: serial, Name: name, Port: port, PID: pid, Paused: true, ADBState: state, Forwards: forwards})
+				continue
+			}
+			if state != ADBStateOnline {
+				// adb cannot run shell commands on it; ADBState tells the caller why.
+				procs = append(procs, ProcInfo{Serial: serial, Name: name, Port: port, PID: pid, ADBState: state, Forwards: forwards})
+				continue
+			}
 
 			boot := false
 			// quick boot check using explicit serial
@@ -1123,7 +1847,7 @@ func ListRunning(env Env) ([]ProcInfo, error) {
 			if strings.TrimSpace(bootOut) == "1" {
 				boot = true
 			}
-			procs = append(procs, ProcInfo{Serial: serial, Name: name, Port: port, PID: pid, Booted: boot})
+			procs = append(procs, ProcInfo{Serial: serial, Name: name, Port: port, PID: pid, Booted: boot, ADBState: state, Forwards: forwards})
 		}
 	}
 
@@ -1141,6 +1865,10 @@ func ListRunning(env Env) ([]ProcInfo, error) {
 			}
 			// Found a running emulator on this port
 			serial := fmt.Sprintf("emulator-%d", port)
+			if isStoppedProcess(pid) {
+				procs = append(procs, ProcInfo{Serial: serial, Name: findEmulatorNameFromPID(pid), Port: port, PID: pid, Paused: true})
+				continue
+			}
 			// Try to get name from adb, fallback to process cmdline
 			name, _ := GetAVDNameFromSerial(env, serial)
 			if name == "" {
@@ -1168,6 +1896,26 @@ func ListRunning(env Env) ([]ProcInfo, error) {
 		}
 	}
 
+	adopted := liveAdopted(env)
+	for i := range procs {
+		describeProcess(env, &procs[i])
+		if inst, ok := adopted[procs[i].Serial]; ok && inst.PID == procs[i].PID {
+			procs[i].Adopted = true
+			if procs[i].Name == "" {
+				procs[i].Name = inst.Name
+			}
+			if procs[i].LogPath == "" {
+				procs[i].LogPath = inst.LogPath
+			}
+		}
+	}
+	for i := range procs {
+		instEnv := env
+		instEnv.Emulator = instanceEmulator(env, procs[i])
+		if version, err := DetectEmulatorVersion(instEnv); err == nil {
+			procs[i].EmulatorVersion = version.String()
+		}
+	}
 	return procs, nil
 }
 
@@ -1203,6 +1951,9 @@ func CleanupOrphans(env Env, force bool) (CleanupReport, error) {
 	}
 
 	for _, proc := range procs {
+		if proc.Adopted {
+			continue
+		}
 		if proc.Name == "" {
 			report.OrphanedProcesses = append(report.OrphanedProcesses, proc)
 			continue
@@ -1224,17 +1975,23 @@ func CleanupOrphans(env Env, force bool) (CleanupReport, error) {
 
 	if force {
 		for _, proc := range report.OrphanedProcesses {
-			if err := StopBySerial(env, proc.Serial); err != nil {
+			if err := StopBySerialWithOptions(env, proc.Serial, StopOptions{Outcome: FailureKilledByGC}); err != nil {
 				logEvent(env, "orphan process stop failed", "serial", proc.Serial, "error", err)
 			}
 		}
+		// Orphans are freed at once: moving them to the trash would keep their disk in use.
 		for _, info := range report.OrphanedAVDs {
-			if err := Delete(env, info.Name); err != nil {
+			if err := DeleteWithOptions(env, info.Name, DeleteOptions{SkipTrash: true}); err != nil {
 				logEvent(env, "orphan avd delete failed", "name", info.Name, "error", err)
 			}
 		}
 	}
 
+	report.PrunedHistory, err = PruneHistory(env, "", !force)
+	if err != nil {
+		logEvent(env, "history prune failed", "error", err)
+	}
+
 	if len(report.OrphanedProcesses) > 0 || len(report.OrphanedAVDs) > 0 {
 		logEvent(
 			env,
@@ -1537,12 +2294,20 @@ func createSDCard(env Env, avdDir, configPath string) error {
 }
 
 // CustomizeStart prepares AVD for manual customization and starts GUI emulator without snapshots.
-// Returns path to emulator log file.
+// Returns path to emulator log file. If a customize session for name is still running it is
+// resumed: nothing is started and its log path is returned.
 func CustomizeStart(env Env, name string) (string, error) {
 	if name == "" {
 		return "", errors.New("empty name")
 	}
 	avdDir := filepath.Join(env.AVDHome, name+".avd")
+	if session, ok, err := readCustomizeSession(avdDir); err == nil && ok && customizeProcessAlive(session.PID, name) {
+		logEvent(env, "customize session resumed", "name", name, "pid", session.PID)
+		return session.LogPath, nil
+	}
+	if inUse := waitAVDFree(env, name); inUse != nil {
+		return "", inUse
+	}
 	cfg := filepath.Join(avdDir, "config.ini")
 	b, err := os.ReadFile(cfg)
 	if err != nil {
@@ -1558,8 +2323,17 @@ func CustomizeStart(env Env, name string) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("open log: %w", err)
 	}
-	args := []string{"-avd", name, "-no-snapshot-load", "-no-snapshot-save"}
-	cmd := commandWithEnv([]string{"QEMU_FILE_LOCKING=off"}, env.Emulator, args...)
+	defaults, err := defaultEmulatorArgs(env)
+	if err != nil {
+		_ = lf.Close()
+		return "", err
+	}
+	args, err := EmulatorArgs{Name: name, NoSnapshotLoad: true, NoSnapshotSave: true}.withDefaults(defaults).Args()
+	if err != nil {
+		_ = lf.Close()
+		return "", err
+	}
+	cmd := commandWithEnv(append([]string{"QEMU_FILE_LOCKING=off"}, sdkProcessEnv(env)...), env.Emulator, args...)
 	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
 	// Keep the child independent from parent lifecycle: file-only stdio for detached launch.
 	cmd.Stdout = lf
@@ -1569,22 +2343,23 @@ func CustomizeStart(env Env, name string) (string, error) {
 		return "", fmt.Errorf("emulator start: %w", err)
 	}
 	_ = lf.Close()
+	session := CustomizeSession{Name: name, PID: cmd.Process.Pid, StartedAt: time.Now().UTC(), LogPath: logPath}
+	if err := writeCustomizeSession(avdDir, session); err != nil {
+		return logPath, err
+	}
+	// Reap the emulator if avdctl outlives it, so it does not linger as a zombie.
+	go func() { _ = cmd.Wait() }()
 	return logPath, nil
 }
 
-// CustomizeFinish stops the emulator (if running) and exports userdata to a golden qcow2.
+// CustomizeFinish stops the emulator (if running), waits for it to exit and exports userdata
+// to a golden qcow2.
 func CustomizeFinish(env Env, name, dest string) (string, int64, error) {
 	if name == "" {
 		return "", 0, errors.New("empty name")
 	}
-	if procs, err := ListRunning(env); err == nil {
-		for _, p := range procs {
-			if p.Name == name {
-				KillEmulator(env, p.Serial)
-				time.Sleep(1 * time.Second)
-				break
-			}
-		}
+	if err := finishCustomizeSession(env, name); err != nil {
+		return "", 0, err
 	}
 	if dest == "" {
 		dir := env.GoldenDir
@@ -1594,26 +2369,154 @@ func CustomizeFinish(env Env, name, dest string) (string, int64, error) {
 	return SaveGolden(env, name, dest)
 }
 
+// StopMode selects how StopBySerialWithOptions shuts an emulator down.
+type StopMode string
+
+const (
+	// StopConsoleKill asks the emulator console to exit (adb emu kill), then falls back to SIGTERM.
+	StopConsoleKill StopMode = "console-kill"
+	// StopGuestShutdown powers Android off from inside the guest (adb shell reboot -p) and waits
+	// for the emulator to exit, so userdata is unmounted cleanly. Falls back to StopConsoleKill
+	// if the guest does not shut down within the timeout.
+	StopGuestShutdown StopMode = "guest-shutdown"
+	// StopForce kills the emulator process with SIGKILL.
+	StopForce StopMode = "force"
+)
+
+// ParseStopMode parses a --mode value; empty means StopConsoleKill.
+func ParseStopMode(s string) (StopMode, error) {
+	switch mode := StopMode(strings.TrimSpace(s)); mode {
+	case "":
+		return StopConsoleKill, nil
+	case StopConsoleKill, StopGuestShutdown, StopForce:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid stop mode %q (expected console-kill, guest-shutdown or force)", s)
+	}
+}
+
+// StopOptions customizes StopBySerialWithOptions.
+type StopOptions struct {
+	Mode    StopMode      // default StopConsoleKill
+	Timeout time.Duration // guest shutdown wait; default 60s
+	// Outcome is recorded in the run history as why the run ended (default RunStopped), e.g.
+	// FailureLeaseExpired. An outcome recorded earlier, such as a boot timeout, is kept.
+	Outcome string
+}
+
 // Stop by serial (clean). Falls back to SIGTERM if adb fails.
 func StopBySerial(env Env, serial string) error {
+	return StopBySerialWithOptions(env, serial, StopOptions{})
+}
+
+// StopBySerialWithOptions stops an emulator using the given StopMode.
+func StopBySerialWithOptions(env Env, serial string, opts StopOptions) (err error) {
 	if !strings.HasPrefix(serial, "emulator-") {
 		return fmt.Errorf("invalid serial format: %s (expected emulator-XXXX)", serial)
 	}
+	if opts.Mode == "" {
+		opts.Mode = StopConsoleKill
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 60 * time.Second
+	}
+	outcome, err := ParseRunOutcome(opts.Outcome)
+	if err != nil {
+		return err
+	}
 
 	// Extract port from serial
 	port := 0
 	if n, err := strconv.Atoi(strings.TrimPrefix(serial, "emulator-")); err == nil {
 		port = n
 	}
+	var (
+		runDir string
+		runPID int
+		inRun  bool
+	)
+	defer func() {
+		if err == nil {
+			if inRun {
+				endRun(env, runDir, runPID, serial, outcome)
+			}
+			clearPortForwards(env, serial)
+			discardEphemeral(env, port)
+			teardownCloneNetwork(env, port)
+			_ = os.Remove(captureStatePath(serial))
+		}
+	}()
 	_, span := startSpan(
 		env,
 		"avd.StopBySerial",
 		attribute.String("serial", serial),
 		attribute.Int("port", port),
+		attribute.String("mode", string(opts.Mode)),
 	)
 	defer span.End()
-	logEvent(env, "emulator stop requested", "serial", serial, "port", port)
+	logEvent(env, "emulator stop requested", "serial", serial, "port", port, "mode", opts.Mode)
+	// A process stopped by Pause would never handle the console kill or guest shutdown.
+	if pid := findEmulatorPID(port); pid > 0 && isStoppedProcess(pid) {
+		_ = syscall.Kill(pid, syscall.SIGCONT)
+	}
+	runDir, runPID, inRun = recordRunUsage(env, serial)
+
+	switch opts.Mode {
+	case StopConsoleKill:
+	case StopGuestShutdown:
+		if stopGuestShutdown(env, serial, port, opts.Timeout) {
+			span.SetAttributes(attribute.Bool("stopped", true))
+			logEvent(env, "emulator stopped", "serial", serial, "port", port, "mode", opts.Mode)
+			return nil
+		}
+		logEvent(env, "guest shutdown timed out, falling back to console kill", "serial", serial, "port", port)
+	case StopForce:
+		if pid := findEmulatorPID(port); pid != 0 {
+			if proc, err := os.FindProcess(pid); err == nil && proc.Kill() == nil {
+				waitForPIDExit(port, 10*time.Second)
+				span.SetAttributes(attribute.Bool("stopped", true))
+				logEvent(env, "emulator stopped", "serial", serial, "port", port, "pid", pid, "mode", opts.Mode)
+				return nil
+			}
+		}
+	default:
+		err := fmt.Errorf("invalid stop mode %q", opts.Mode)
+		recordSpanError(span, err)
+		return err
+	}
+
+	return stopConsoleKill(env, span, serial, port)
+}
+
+// stopGuestShutdown requests a guest power-off and reports whether the emulator exited in time.
+func stopGuestShutdown(env Env, serial string, port int, timeout time.Duration) bool {
+	if _, _, err := runCommandOutputWithEnv(env.Context, nil, nil, env.ADB, "-s", serial, "shell", "reboot", "-p"); err != nil {
+		logEvent(env, "guest shutdown request failed", "serial", serial, "error", err)
+		return false
+	}
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		visible, err := isSerialVisible(env, serial)
+		if err == nil && !visible && findEmulatorPID(port) == 0 {
+			return true
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+	return false
+}
+
+func waitForPIDExit(port int, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if findEmulatorPID(port) == 0 {
+			return true
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+	return false
+}
 
+func stopConsoleKill(env Env, span trace.Span, serial string, port int) error {
 	// Try graceful shutdown via adb first
 	_, errOut, adbErr := runCommandOutputWithEnv(env.Context, nil, nil, env.ADB, "-s", serial, "emu", "kill")
 	adbOutput := strings.TrimSpace(errOut)
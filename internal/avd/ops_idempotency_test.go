@@ -132,6 +132,9 @@ func TestCleanupOrphans(t *testing.T) {
 	if _, err := os.Stat(orphanClone); !os.IsNotExist(err) {
 		t.Fatalf("expected orphan clone removed")
 	}
+	if entries, _ := ListTrash(env); len(entries) != 0 {
+		t.Fatalf("orphan clone moved to the trash: %#v", entries)
+	}
 
 	deadline := time.Now().Add(5 * time.Second)
 	for time.Now().Before(deadline) {
@@ -0,0 +1,79 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Orientation is a fixed screen rotation.
+type Orientation string
+
+const (
+	OrientationPortrait         Orientation = "portrait"
+	OrientationLandscape        Orientation = "landscape"
+	OrientationReversePortrait  Orientation = "reverse-portrait"
+	OrientationReverseLandscape Orientation = "reverse-landscape"
+)
+
+// userRotations maps orientations to the values of Settings.System.USER_ROTATION.
+var userRotations = map[Orientation]int{
+	OrientationPortrait: 0, OrientationLandscape: 1, OrientationReversePortrait: 2, OrientationReverseLandscape: 3,
+}
+
+// OrientationLockSettings returns the adb shell commands that turn off auto-rotation and pin
+// the screen to o. Passed as PrewarmOptions.Settings they are baked into the golden, so every
+// clone boots in that orientation.
+func OrientationLockSettings(o Orientation) ([]string, error) {
+	rotation, ok := userRotations[o]
+	if !ok {
+		return nil, fmt.Errorf("unknown orientation %q (want portrait, landscape, reverse-portrait or reverse-landscape)", o)
+	}
+	return []string{
+		"settings put system accelerometer_rotation 0",
+		fmt.Sprintf("settings put system user_rotation %d", rotation),
+	}, nil
+}
+
+// Rotate turns the screen of serial to o and keeps it there: auto-rotation is disabled, so
+// sensor noise cannot flip it back (unlike the console "rotate" command, which is relative).
+func Rotate(env Env, serial string, o Orientation) error {
+	_, span := startSpan(env, "avd.Rotate", attribute.String("serial", serial), attribute.String("orientation", string(o)))
+	defer span.End()
+	settings, err := OrientationLockSettings(o)
+	if err == nil {
+		err = applySettings(env, serial, settings)
+	}
+	recordSpanError(span, err)
+	return err
+}
+
+// Posture is a foldable posture, named as in the console's "posture" command.
+type Posture string
+
+const (
+	PostureClosed   Posture = "closed"
+	PostureHalfOpen Posture = "half-open"
+	PostureOpen     Posture = "open"
+	PostureFlipped  Posture = "flipped"
+	PostureTent     Posture = "tent"
+)
+
+// SetPosture folds the emulated device behind serial. The AVD must use a foldable hardware
+// profile; other devices reply KO.
+func SetPosture(env Env, serial string, p Posture) error {
+	_, span := startSpan(env, "avd.SetPosture", attribute.String("serial", serial), attribute.String("posture", string(p)))
+	defer span.End()
+	var err error
+	switch p {
+	case PostureClosed, PostureHalfOpen, PostureOpen, PostureFlipped, PostureTent:
+		_, err = Console(env, serial, "posture "+string(p))
+	default:
+		err = fmt.Errorf("unknown posture %q (want closed, half-open, open, flipped or tent)", p)
+	}
+	recordSpanError(span, err)
+	return err
+}
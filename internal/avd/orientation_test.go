@@ -0,0 +1,45 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRotateLocksOrientation(t *testing.T) {
+	env := newTestEnv(t)
+	logPath := filepath.Join(t.TempDir(), "adb.log")
+	if err := os.WriteFile(env.ADB, []byte("#!/bin/sh\necho \"$@\" >> "+logPath+"\n"), 0o755); err != nil {
+		t.Fatalf("write adb stub: %v", err)
+	}
+	if err := Rotate(env, "emulator-5580", OrientationLandscape); err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	log, _ := os.ReadFile(logPath)
+	want := "-s emulator-5580 shell settings put system accelerometer_rotation 0\n" +
+		"-s emulator-5580 shell settings put system user_rotation 1\n"
+	if string(log) != want {
+		t.Fatalf("adb calls = %q, want %q", log, want)
+	}
+	if err := Rotate(env, "emulator-5580", Orientation("sideways")); err == nil || !strings.Contains(err.Error(), "unknown orientation") {
+		t.Fatalf("expected unknown orientation error, got %v", err)
+	}
+}
+
+func TestSetPostureSendsConsoleCommand(t *testing.T) {
+	serial, received := startFakeConsole(t, writeConsoleToken(t, "s3cret"), map[string]string{"posture half-open": ""})
+	if err := SetPosture(newTestEnv(t), serial, PostureHalfOpen); err != nil {
+		t.Fatalf("SetPosture: %v", err)
+	}
+	<-received // auth
+	if got := <-received; got != "posture half-open" {
+		t.Fatalf("unexpected command %q", got)
+	}
+	if err := SetPosture(newTestEnv(t), serial, Posture("origami")); err == nil || !strings.Contains(err.Error(), "unknown posture") {
+		t.Fatalf("expected unknown posture error, got %v", err)
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// pausedFilename marks, in the AVD directory, a paused emulator and how it was paused.
+const pausedFilename = "avdctl-paused.json"
+
+// Pause methods.
+const (
+	PauseSignal  = "signal"  // SIGSTOP of the emulator process: no CPU use at all
+	PauseConsole = "console" // console "avd stop": the guest stops, the emulator process idles
+)
+
+type pauseState struct {
+	Serial string    `json:"serial"`
+	Method string    `json:"method"`
+	Since  time.Time `json:"since"`
+}
+
+// Pause freezes the emulator on serial so an idle clone stops using CPU without paying a full
+// boot on Resume. The process is stopped with SIGSTOP when its pid is known (Linux), else the
+// guest is stopped through the console. A paused emulator answers neither adb nor, with
+// SIGSTOP, the console; ListRunning reports it as Paused without querying it.
+func Pause(env Env, serial string) error {
+	_, span := startSpan(env, "avd.Pause", attribute.String("serial", serial))
+	defer span.End()
+	port, err := consolePort(serial)
+	if err != nil {
+		recordSpanError(span, err)
+		return err
+	}
+	pid := findEmulatorPID(port)
+	name := ""
+	if pid > 0 {
+		name = findEmulatorNameFromPID(pid)
+	}
+	if name == "" {
+		name, _ = GetAVDNameFromSerial(env, serial)
+	}
+	state := pauseState{Serial: serial, Method: PauseConsole, Since: time.Now().UTC()}
+	if pid > 0 {
+		state.Method = PauseSignal
+		err = syscall.Kill(pid, syscall.SIGSTOP)
+	} else {
+		_, err = Console(env, serial, "avd stop")
+	}
+	if err != nil {
+		err = fmt.Errorf("pause %s: %w", serial, err)
+		recordSpanError(span, err)
+		return err
+	}
+	if name != "" {
+		if err := writePauseState(filepath.Join(env.AVDHome, name+".avd"), state); err != nil {
+			logEvent(env, "pause state not recorded", "serial", serial, "error", err)
+		}
+	}
+	span.SetAttributes(attribute.String("method", state.Method))
+	logEvent(env, "emulator paused", "serial", serial, "name", name, "method", state.Method)
+	return nil
+}
+
+// Resume continues an emulator frozen by Pause.
+func Resume(env Env, serial string) error {
+	_, span := startSpan(env, "avd.Resume", attribute.String("serial", serial))
+	defer span.End()
+	port, err := consolePort(serial)
+	if err != nil {
+		recordSpanError(span, err)
+		return err
+	}
+	pid := findEmulatorPID(port)
+	name := ""
+	if pid > 0 {
+		name = findEmulatorNameFromPID(pid)
+	}
+	if pid > 0 && isStoppedProcess(pid) {
+		err = syscall.Kill(pid, syscall.SIGCONT)
+	} else {
+		_, err = Console(env, serial, "avd start")
+	}
+	if err != nil {
+		err = fmt.Errorf("resume %s: %w", serial, err)
+		recordSpanError(span, err)
+		return err
+	}
+	if name == "" {
+		name, _ = GetAVDNameFromSerial(env, serial)
+	}
+	if name != "" {
+		clearPauseState(filepath.Join(env.AVDHome, name+".avd"))
+	}
+	logEvent(env, "emulator resumed", "serial", serial, "name", name)
+	return nil
+}
+
+// isStoppedProcess reports whether pid is stopped by a signal (state T in /proc).
+func isStoppedProcess(pid int) bool {
+	state, _, err := readProcessState(pid)
+	return err == nil && strings.HasPrefix(state, "T")
+}
+
+// consolePaused reports whether the AVD in avdDir was paused through the console on serial.
+func consolePaused(avdDir, serial string) bool {
+	state, ok := readPauseState(avdDir)
+	return ok && state.Method == PauseConsole && state.Serial == serial
+}
+
+func writePauseState(avdDir string, state pauseState) error {
+	b, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(avdDir, pausedFilename), append(b, '\n'), 0o644)
+}
+
+func readPauseState(avdDir string) (pauseState, bool) {
+	b, err := os.ReadFile(filepath.Join(avdDir, pausedFilename))
+	if err != nil {
+		return pauseState{}, false
+	}
+	var state pauseState
+	if err := json.Unmarshal(b, &state); err != nil {
+		return pauseState{}, false
+	}
+	return state, true
+}
+
+// clearPauseState forgets a pause, on Resume and on every start (a paused emulator that was
+// killed leaves the marker behind).
+func clearPauseState(avdDir string) {
+	_ = os.Remove(filepath.Join(avdDir, pausedFilename))
+}
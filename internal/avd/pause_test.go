@@ -0,0 +1,77 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestPauseAndResumeStopTheProcess(t *testing.T) {
+	if _, err := os.Stat("/proc/self/stat"); err != nil {
+		t.Skip("needs /proc")
+	}
+	// A stand-in whose command line looks like an emulator on port 5798.
+	cmd := exec.Command("sh", "-c", "sleep 30; :", "qemu-system-test", "-port", "5798")
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = cmd.Process.Kill(); _ = cmd.Wait() })
+	env := newTestEnv(t)
+	serial := "emulator-5798"
+
+	waitState := func(stopped bool) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for isStoppedProcess(cmd.Process.Pid) != stopped {
+			if time.Now().After(deadline) {
+				t.Fatalf("process stopped = %v, want %v", !stopped, stopped)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	if err := Pause(env, serial); err != nil {
+		t.Fatalf("Pause: %v", err)
+	}
+	waitState(true)
+	procs, err := ListRunning(env)
+	if err != nil {
+		t.Fatalf("ListRunning: %v", err)
+	}
+	found := false
+	for _, p := range procs {
+		if p.Serial == serial {
+			found = p.Paused && p.PID == cmd.Process.Pid
+		}
+	}
+	if !found {
+		t.Fatalf("ListRunning did not report %s as paused: %+v", serial, procs)
+	}
+	if err := Resume(env, serial); err != nil {
+		t.Fatalf("Resume: %v", err)
+	}
+	waitState(false)
+}
+
+func TestPauseStateMarker(t *testing.T) {
+	dir := t.TempDir()
+	if consolePaused(dir, "emulator-5580") {
+		t.Fatal("paused without a marker")
+	}
+	if err := writePauseState(dir, pauseState{Serial: "emulator-5580", Method: PauseConsole}); err != nil {
+		t.Fatal(err)
+	}
+	if !consolePaused(dir, "emulator-5580") {
+		t.Fatal("console pause not detected")
+	}
+	if consolePaused(dir, "emulator-5582") {
+		t.Fatal("marker for another serial matched")
+	}
+	clearPauseState(dir)
+	if consolePaused(dir, "emulator-5580") {
+		t.Fatal("marker survived clearPauseState")
+	}
+}
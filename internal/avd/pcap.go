@@ -0,0 +1,92 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// captureStatePath records where the running network capture of serial writes, so
+// StopNetworkCapture needs nothing but the serial.
+func captureStatePath(serial string) string {
+	return filepath.Join(os.TempDir(), "avdctl-pcap-"+serial)
+}
+
+// StartNetworkCapture makes the emulator behind serial write the guest's network traffic to
+// dest (a file, or a directory that gets <serial>-<timestamp>.pcap), through the console's
+// "network capture" command. It returns the absolute path. Only one capture runs per emulator.
+func StartNetworkCapture(env Env, serial, dest string) (string, error) {
+	_, span := startSpan(env, "avd.StartNetworkCapture", attribute.String("serial", serial))
+	defer span.End()
+	fail := func(err error) (string, error) {
+		recordSpanError(span, err)
+		return "", err
+	}
+	if b, err := os.ReadFile(captureStatePath(serial)); err == nil && !consoleGone(env, serial) {
+		return fail(fmt.Errorf("a network capture is already running on %s (%s); stop it first", serial, strings.TrimSpace(string(b))))
+	}
+	if dest == "" {
+		dest = "."
+	}
+	if st, err := os.Stat(dest); err == nil && st.IsDir() {
+		dest = filepath.Join(dest, fmt.Sprintf("%s-%s.pcap", serial, time.Now().UTC().Format("20060102T150405Z")))
+	}
+	// The emulator opens the file itself, from its own working directory.
+	dest, err := filepath.Abs(dest)
+	if err != nil {
+		return fail(err)
+	}
+	if strings.ContainsAny(dest, " \t\n") {
+		return fail(fmt.Errorf("capture path %q: the console cannot take paths with spaces", dest))
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return fail(err)
+	}
+	if _, err := Console(env, serial, "network capture start "+dest); err != nil {
+		return fail(fmt.Errorf("start network capture on %s: %w", serial, err))
+	}
+	if err := os.WriteFile(captureStatePath(serial), []byte(dest+"\n"), 0o644); err != nil {
+		_, _ = Console(env, serial, "network capture stop")
+		return fail(err)
+	}
+	logEvent(env, "network capture started", "serial", serial, "path", dest)
+	return dest, nil
+}
+
+// StopNetworkCapture ends the capture started by StartNetworkCapture and returns the pcap path.
+// An emulator that already exited has closed the file; its path is still returned.
+func StopNetworkCapture(env Env, serial string) (string, error) {
+	_, span := startSpan(env, "avd.StopNetworkCapture", attribute.String("serial", serial))
+	defer span.End()
+	b, err := os.ReadFile(captureStatePath(serial))
+	if err != nil {
+		err = fmt.Errorf("no network capture is running on %s", serial)
+		recordSpanError(span, err)
+		return "", err
+	}
+	dest := strings.TrimSpace(string(b))
+	_, err = Console(env, serial, "network capture stop")
+	var ko *ConsoleError
+	if err != nil && !errors.As(err, &ko) && !consoleGone(env, serial) {
+		err = fmt.Errorf("stop network capture on %s: %w", serial, err)
+		recordSpanError(span, err)
+		return "", err
+	}
+	_ = os.Remove(captureStatePath(serial))
+	logEvent(env, "network capture stopped", "serial", serial, "path", dest)
+	return dest, nil
+}
+
+// consoleGone reports whether the console port of serial is closed, i.e. the emulator exited.
+func consoleGone(env Env, serial string) bool {
+	port, err := consolePort(serial)
+	return err == nil && isPortPairFree(env, port)
+}
@@ -0,0 +1,57 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestStartNetworkCapture(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	dir := t.TempDir()
+	want := filepath.Join(dir, "w-1.pcap")
+	serial, received := startFakeConsole(t, writeConsoleToken(t, "s3cret"), map[string]string{"network capture start " + want: ""})
+	got, err := StartNetworkCapture(newTestEnv(t), serial, want)
+	if err != nil {
+		t.Fatalf("StartNetworkCapture: %v", err)
+	}
+	if got != want {
+		t.Fatalf("path = %q, want %q", got, want)
+	}
+	<-received // auth
+	if cmd := <-received; cmd != "network capture start "+want {
+		t.Fatalf("console command = %q", cmd)
+	}
+	if _, err := StartNetworkCapture(newTestEnv(t), serial, dir); err == nil || !strings.Contains(err.Error(), "already running") {
+		t.Fatalf("expected a second capture to be refused, got %v", err)
+	}
+}
+
+func TestStopNetworkCaptureAfterEmulatorExit(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial := "emulator-" + strconv.Itoa(ln.Addr().(*net.TCPAddr).Port)
+	ln.Close()
+	if _, err := StopNetworkCapture(newTestEnv(t), serial); err == nil {
+		t.Fatal("expected an error without a running capture")
+	}
+	if err := os.WriteFile(captureStatePath(serial), []byte("/srv/pcap/w-1.pcap\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := StopNetworkCapture(newTestEnv(t), serial)
+	if err != nil || got != "/srv/pcap/w-1.pcap" {
+		t.Fatalf("StopNetworkCapture = %q, %v", got, err)
+	}
+	if fileExists(captureStatePath(serial)) {
+		t.Fatal("capture state left behind")
+	}
+}
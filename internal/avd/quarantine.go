@@ -0,0 +1,233 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// quarantineFilename marks, in the AVD directory, a clone that starts refuse; it holds the
+// QuarantineRecord.
+const quarantineFilename = "avdctl-quarantine.json"
+
+// QuarantineRecord is why a clone is quarantined (see Quarantine).
+type QuarantineRecord struct {
+	Name   string    `json:"name"`
+	Since  time.Time `json:"since"`
+	Reason string    `json:"reason,omitempty"`
+	// Failures is the run of boot failures that quarantined the clone; 0 when done by hand.
+	Failures int `json:"failures,omitempty"`
+}
+
+// QuarantinedError is returned when a quarantined clone is started.
+type QuarantinedError struct {
+	QuarantineRecord
+}
+
+func (e *QuarantinedError) Error() string {
+	msg := fmt.Sprintf("AVD %s is quarantined since %s", e.Name, e.Since.Local().Format("2006-01-02 15:04"))
+	if e.Reason != "" {
+		msg += " (" + e.Reason + ")"
+	}
+	return msg + "; release it, or release it with rematerialize to reset it from its golden"
+}
+
+// Quarantine stops name from being started until ReleaseQuarantine. Its run history and files
+// are kept as they are, whatever Env.Retention says, for a post-mortem.
+//
+// With Env.QuarantineAfter set, a clone is quarantined on its own when that many starts in a row
+// fail to boot while a sibling clone of the same golden boots: the fault is then in the clone's
+// overlay, not in the golden or the host.
+func Quarantine(env Env, name, reason string) error {
+	avdDir := filepath.Join(env.AVDHome, name+".avd")
+	if !fileExists(avdDir) {
+		return fmt.Errorf("AVD %s not found", name)
+	}
+	return writeQuarantine(env, avdDir, QuarantineRecord{Name: name, Since: time.Now().UTC(), Reason: strings.TrimSpace(reason)})
+}
+
+// ReleaseQuarantine lets a quarantined clone be started again. rematerialize first resets it
+// from the golden it was cloned from (see ResetClone), which must be in Env.GoldenDir.
+// Releasing an AVD that is not quarantined is a no-op, except for the reset.
+func ReleaseQuarantine(env Env, name string, rematerialize bool) error {
+	_, span := startSpan(env, "avd.ReleaseQuarantine", attribute.String("name", name), attribute.Bool("rematerialize", rematerialize))
+	defer span.End()
+	fail := func(err error) error {
+		recordSpanError(span, err)
+		return err
+	}
+	avdDir := filepath.Join(env.AVDHome, name+".avd")
+	if !fileExists(avdDir) {
+		return fail(fmt.Errorf("AVD %s not found", name))
+	}
+	if rematerialize {
+		fingerprint, err := os.ReadFile(filepath.Join(avdDir, cloneFingerprintFilename))
+		if err != nil {
+			return fail(fmt.Errorf("%s is not a clone", name))
+		}
+		golden, ok := registryFingerprints(env)[strings.TrimSpace(string(fingerprint))]
+		if !ok {
+			return fail(fmt.Errorf("rematerialize %s: its golden is not in the golden registry (AVDCTL_GOLDEN_DIR)", name))
+		}
+		res, err := ResetClone(env, name, golden)
+		if err != nil {
+			return fail(fmt.Errorf("rematerialize %s: %w", name, err))
+		}
+		logEvent(env, "quarantined avd rematerialized", "name", name, "golden", golden, "copied", len(res.Copied))
+	}
+	if err := os.Remove(filepath.Join(avdDir, quarantineFilename)); err != nil && !os.IsNotExist(err) {
+		return fail(fmt.Errorf("release %s: %w", name, err))
+	}
+	logEvent(env, "avd released from quarantine", "name", name)
+	return nil
+}
+
+// ListQuarantined returns the quarantined AVDs, by name.
+func ListQuarantined(env Env) ([]QuarantineRecord, error) {
+	_, span := startSpan(env, "avd.ListQuarantined")
+	defer span.End()
+	dirs, err := filepath.Glob(filepath.Join(env.AVDHome, "*.avd"))
+	if err != nil {
+		recordSpanError(span, err)
+		return nil, err
+	}
+	var out []QuarantineRecord
+	for _, avdDir := range dirs {
+		if rec, ok := quarantineOf(avdDir); ok {
+			out = append(out, rec)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+// writeQuarantine replaces the quarantine record of avdDir through a rename, so it never
+// writes through a link into another AVD.
+func writeQuarantine(env Env, avdDir string, rec QuarantineRecord) error {
+	b, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(avdDir, quarantineFilename)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(b, '\n'), 0o644); err != nil {
+		return fmt.Errorf("quarantine %s: %w", rec.Name, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("quarantine %s: %w", rec.Name, err)
+	}
+	logEvent(env, "avd quarantined", "name", rec.Name, "reason", rec.Reason, "failures", rec.Failures)
+	return nil
+}
+
+func quarantineOf(avdDir string) (QuarantineRecord, bool) {
+	b, err := os.ReadFile(filepath.Join(avdDir, quarantineFilename))
+	if err != nil {
+		return QuarantineRecord{}, false
+	}
+	rec := QuarantineRecord{Name: strings.TrimSuffix(filepath.Base(avdDir), ".avd")}
+	_ = json.Unmarshal(b, &rec)
+	return rec, true
+}
+
+// admitStart refuses to start a quarantined clone with *QuarantinedError, or with
+// Env.QuarantineRematerialize resets it from its golden and releases it first.
+func admitStart(env Env, name string) error {
+	rec, ok := quarantineOf(filepath.Join(env.AVDHome, name+".avd"))
+	if !ok {
+		return nil
+	}
+	if !env.QuarantineRematerialize {
+		return &QuarantinedError{rec}
+	}
+	return ReleaseQuarantine(env, name, true)
+}
+
+// recordRunBooted notes that the current run of the running instance serial booted.
+func recordRunBooted(env Env, serial string) {
+	avdDir, rec, ok := runningAVDDir(env, serial)
+	if !ok {
+		return
+	}
+	runs := readRunHistory(avdDir)
+	for i := len(runs) - 1; i >= 0; i-- {
+		if runs[i].PID == rec.PID {
+			if runs[i].BootedAt == nil {
+				now := time.Now().UTC()
+				runs[i].BootedAt = &now
+				writeRunHistory(avdDir, runs)
+			}
+			return
+		}
+	}
+}
+
+// checkFlakyStart quarantines the clone of avdDir when its last Env.QuarantineAfter runs timed
+// out booting while a sibling clone (one of the same golden) booted since the first of them.
+func checkFlakyStart(env Env, avdDir string) {
+	n := env.QuarantineAfter
+	if n <= 0 || !isCloneDir(avdDir) {
+		return
+	}
+	if _, ok := quarantineOf(avdDir); ok {
+		return
+	}
+	runs := readRunHistory(avdDir)
+	if len(runs) < n {
+		return
+	}
+	streak := runs[len(runs)-n:]
+	for _, run := range streak {
+		if run.Outcome != FailureBootTimeout {
+			return
+		}
+	}
+	sibling, ok := bootedSibling(env, avdDir, streak[0].StartedAt)
+	if !ok {
+		return
+	}
+	name := strings.TrimSuffix(filepath.Base(avdDir), ".avd")
+	rec := QuarantineRecord{
+		Name:     name,
+		Since:    time.Now().UTC(),
+		Reason:   fmt.Sprintf("%d boot failures in a row while %s booted", n, sibling),
+		Failures: n,
+	}
+	if err := writeQuarantine(env, avdDir, rec); err != nil {
+		logEvent(env, "avd not quarantined", "name", name, "error", err)
+	}
+}
+
+// bootedSibling returns a clone of the golden of the clone avdDir, other than it, with a run
+// that booted after since.
+func bootedSibling(env Env, avdDir string, since time.Time) (string, bool) {
+	fingerprint, err := os.ReadFile(filepath.Join(avdDir, cloneFingerprintFilename))
+	if err != nil {
+		return "", false
+	}
+	dirs, _ := filepath.Glob(filepath.Join(env.AVDHome, "*.avd"))
+	for _, dir := range dirs {
+		if dir == avdDir {
+			continue
+		}
+		other, err := os.ReadFile(filepath.Join(dir, cloneFingerprintFilename))
+		if err != nil || strings.TrimSpace(string(other)) != strings.TrimSpace(string(fingerprint)) {
+			continue
+		}
+		for _, run := range readRunHistory(dir) {
+			if run.BootedAt != nil && run.BootedAt.After(since) {
+				return strings.TrimSuffix(filepath.Base(dir), ".avd"), true
+			}
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,99 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFlakyStartQuarantine(t *testing.T) {
+	env := newTestEnv(t)
+	env.QuarantineAfter = 2
+	now := time.Now().UTC()
+	at := func(hoursAgo int) time.Time { return now.Add(-time.Duration(hoursAgo) * time.Hour) }
+	dirs := map[string]string{}
+	for _, name := range []string{"w-1", "w-2"} {
+		makeBaseAVD(t, env, name)
+		dirs[name] = filepath.Join(env.AVDHome, name+".avd")
+		if err := writeCloneFingerprint(dirs[name], "golden-fp"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeRunHistory(dirs["w-1"], []InstanceRun{
+		{StartedAt: at(5), PID: 1, Outcome: RunStopped},
+		{StartedAt: at(3), PID: 2, Outcome: FailureBootTimeout},
+		{StartedAt: at(1), PID: 3, Outcome: FailureBootTimeout},
+	})
+	booted := at(4)
+	writeRunHistory(dirs["w-2"], []InstanceRun{{StartedAt: at(4), PID: 4, BootedAt: &booted, Outcome: RunStopped}})
+
+	// The only sibling boot predates the streak: the golden may be at fault.
+	checkFlakyStart(env, dirs["w-1"])
+	if _, ok := quarantineOf(dirs["w-1"]); ok {
+		t.Fatal("quarantined without a sibling booting during the failures")
+	}
+	booted = at(2)
+	writeRunHistory(dirs["w-2"], []InstanceRun{{StartedAt: at(2), PID: 4, BootedAt: &booted, Outcome: RunStopped}})
+	checkFlakyStart(env, dirs["w-1"])
+	rec, ok := quarantineOf(dirs["w-1"])
+	if !ok || rec.Failures != 2 || !strings.Contains(rec.Reason, "w-2") {
+		t.Fatalf("quarantine = %+v, %v", rec, ok)
+	}
+
+	_, err := StartEmulatorOnPort(env, "w-1", 5590)
+	var quarantined *QuarantinedError
+	if !errors.As(err, &quarantined) || quarantined.Name != "w-1" {
+		t.Fatalf("expected a QuarantinedError, got %v", err)
+	}
+	infos, err := List(env)
+	if err != nil || len(infos) != 2 || !infos[0].Quarantined || infos[1].Quarantined {
+		t.Fatalf("List = %+v, %v", infos, err)
+	}
+	env.Retention = RetentionPolicy{Runs: RetentionRule{MaxCount: 1}}
+	if pruned, err := PruneHistory(env, "w-1", false); err != nil || len(pruned) != 0 || len(readRunHistory(dirs["w-1"])) != 3 {
+		t.Fatalf("history of a quarantined clone pruned: %+v, %v", pruned, err)
+	}
+
+	if err := ReleaseQuarantine(env, "w-1", true); err == nil {
+		t.Fatal("rematerialized a clone whose golden is not in the registry")
+	}
+	if err := ReleaseQuarantine(env, "w-1", false); err != nil {
+		t.Fatal(err)
+	}
+	if recs, err := ListQuarantined(env); err != nil || len(recs) != 0 {
+		t.Fatalf("ListQuarantined after release = %+v, %v", recs, err)
+	}
+}
+
+func TestCloneOfQuarantinedBase(t *testing.T) {
+	env := newTestEnv(t)
+	makeBaseAVD(t, env, "base")
+	if err := Quarantine(env, "base", "investigating"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CloneFromGolden(env, "base", "w-1", makeGoldenDir(t)); err != nil {
+		t.Fatalf("CloneFromGolden: %v", err)
+	}
+	cloneDir := filepath.Join(env.AVDHome, "w-1.avd")
+	if _, ok := quarantineOf(cloneDir); ok {
+		t.Fatal("clone inherited the base's quarantine")
+	}
+
+	// A link left by an older clone is replaced, not written through.
+	baseFile := filepath.Join(env.AVDHome, "base.avd", quarantineFilename)
+	if err := os.Symlink(baseFile, filepath.Join(cloneDir, quarantineFilename)); err != nil {
+		t.Fatal(err)
+	}
+	if err := Quarantine(env, "w-1", "corrupt overlay"); err != nil {
+		t.Fatal(err)
+	}
+	if rec, _ := quarantineOf(filepath.Join(env.AVDHome, "base.avd")); rec.Reason != "investigating" {
+		t.Fatalf("base quarantine = %+v", rec)
+	}
+}
@@ -0,0 +1,214 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// QuerySpec selects and orders AVDs (Query) or running emulators (QueryRunning).
+type QuerySpec struct {
+	// Filters must all match. See Filter for the syntax.
+	Filters []Filter
+	// Sort is a field to order by, e.g. "name", "size" or "uptime"; "-" in front reverses it.
+	// Empty keeps the listing order.
+	Sort string
+}
+
+// Filter matches one field. Value is compared as is, or:
+//   - "prefix:X", "suffix:X", "contains:X" match part of a text field;
+//   - a value with "*" or "?" is a glob, e.g. "w-*-eu";
+//   - ">N", "<N", ">=N", "<=N" compare a numeric field.
+type Filter struct {
+	Field string
+	Value string
+}
+
+// ParseFilter parses "field=value", e.g. "name=prefix:w-" or "booted=true".
+func ParseFilter(s string) (Filter, error) {
+	field, value, ok := strings.Cut(s, "=")
+	field = strings.TrimSpace(field)
+	if !ok || field == "" {
+		return Filter{}, fmt.Errorf("invalid filter %q (want field=value)", s)
+	}
+	return Filter{Field: strings.ToLower(field), Value: strings.TrimSpace(value)}, nil
+}
+
+// InfoQueryFields and ProcQueryFields are the fields Query and QueryRunning understand.
+var (
+	InfoQueryFields = []string{"name", "kind", "golden", "api", "abi", "device", "size", "running", "booted", "serial", "uptime", "quarantined"}
+	ProcQueryFields = []string{"name", "serial", "port", "pid", "booted", "paused", "adb_state", "uptime"}
+)
+
+// Query returns the AVDs of ListWide that match spec, in spec order. "uptime" is the time since
+// the last boot of running AVDs (0 for stopped ones) in seconds.
+func Query(env Env, spec QuerySpec) ([]Info, error) {
+	infos, err := ListWide(env)
+	if err != nil {
+		return nil, err
+	}
+	return QueryInfos(infos, spec)
+}
+
+// QueryInfos applies spec to entries of ListWide, as Query does.
+func QueryInfos(infos []Info, spec QuerySpec) ([]Info, error) {
+	rows := make([]queryRow, len(infos))
+	for i, info := range infos {
+		var uptime int64
+		if info.Running && info.LastBoot != nil {
+			uptime = int64(time.Since(*info.LastBoot).Seconds())
+		}
+		rows[i] = queryRow{
+			"name": info.Name, "kind": info.Kind, "golden": info.Golden, "api": strconv.Itoa(info.APILevel),
+			"abi": info.ABI, "device": info.Device, "size": strconv.FormatInt(info.SizeBytes, 10),
+			"running": strconv.FormatBool(info.Running), "booted": strconv.FormatBool(info.Booted),
+			"serial": info.Serial, "uptime": strconv.FormatInt(uptime, 10), "quarantined": strconv.FormatBool(info.Quarantined),
+		}
+	}
+	keep, err := runQuery(rows, spec, InfoQueryFields)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Info, len(keep))
+	for i, idx := range keep {
+		out[i] = infos[idx]
+	}
+	return out, nil
+}
+
+// QueryRunning returns the running emulators of ListRunning that match spec, in spec order.
+// "uptime" is the time since avdctl last started the AVD, in seconds.
+func QueryRunning(env Env, spec QuerySpec) ([]ProcInfo, error) {
+	procs, err := ListRunning(env)
+	if err != nil {
+		return nil, err
+	}
+	return QueryProcs(env, procs, spec)
+}
+
+// QueryProcs applies spec to procs, as QueryRunning does.
+func QueryProcs(env Env, procs []ProcInfo, spec QuerySpec) ([]ProcInfo, error) {
+	rows := make([]queryRow, len(procs))
+	for i, p := range procs {
+		var uptime int64
+		if boot, ok := lastBoot(filepath.Join(env.AVDHome, p.Name+".avd")); ok && p.Name != "" {
+			uptime = int64(time.Since(boot).Seconds())
+		}
+		rows[i] = queryRow{
+			"name": p.Name, "serial": p.Serial, "port": strconv.Itoa(p.Port), "pid": strconv.Itoa(p.PID),
+			"booted": strconv.FormatBool(p.Booted), "paused": strconv.FormatBool(p.Paused), "adb_state": p.ADBState, "uptime": strconv.FormatInt(uptime, 10),
+		}
+	}
+	keep, err := runQuery(rows, spec, ProcQueryFields)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]ProcInfo, len(keep))
+	for i, idx := range keep {
+		out[i] = procs[idx]
+	}
+	return out, nil
+}
+
+// queryRow holds the fields of one entry as text.
+type queryRow map[string]string
+
+// runQuery returns the indexes of the rows that match spec, sorted by spec.Sort.
+func runQuery(rows []queryRow, spec QuerySpec, fields []string) ([]int, error) {
+	known := func(field string) error {
+		for _, f := range fields {
+			if f == field {
+				return nil
+			}
+		}
+		return fmt.Errorf("unknown field %q (want one of %s)", field, strings.Join(fields, ", "))
+	}
+	for _, f := range spec.Filters {
+		if err := known(f.Field); err != nil {
+			return nil, err
+		}
+	}
+	var keep []int
+	for i, row := range rows {
+		ok := true
+		for _, f := range spec.Filters {
+			matched, err := matchFilter(row[f.Field], f.Value)
+			if err != nil {
+				return nil, fmt.Errorf("filter %s: %w", f.Field, err)
+			}
+			if !matched {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			keep = append(keep, i)
+		}
+	}
+	if spec.Sort == "" {
+		return keep, nil
+	}
+	field, desc := strings.CutPrefix(spec.Sort, "-")
+	if err := known(field); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(keep, func(a, b int) bool {
+		x, y := rows[keep[a]][field], rows[keep[b]][field]
+		if desc {
+			x, y = y, x
+		}
+		nx, errX := strconv.ParseInt(x, 10, 64)
+		ny, errY := strconv.ParseInt(y, 10, 64)
+		if errX == nil && errY == nil {
+			return nx < ny
+		}
+		return x < y
+	})
+	return keep, nil
+}
+
+func matchFilter(actual, want string) (bool, error) {
+	for _, op := range []string{">=", "<=", ">", "<"} {
+		limit, ok := strings.CutPrefix(want, op)
+		if !ok {
+			continue
+		}
+		n, err := strconv.ParseInt(strings.TrimSpace(limit), 10, 64)
+		if err != nil {
+			return false, fmt.Errorf("%q is not a number", limit)
+		}
+		v, err := strconv.ParseInt(actual, 10, 64)
+		if err != nil {
+			return false, fmt.Errorf("%s applies to numeric fields only", op)
+		}
+		switch op {
+		case ">=":
+			return v >= n, nil
+		case "<=":
+			return v <= n, nil
+		case ">":
+			return v > n, nil
+		}
+		return v < n, nil
+	}
+	if p, ok := strings.CutPrefix(want, "prefix:"); ok {
+		return strings.HasPrefix(actual, p), nil
+	}
+	if s, ok := strings.CutPrefix(want, "suffix:"); ok {
+		return strings.HasSuffix(actual, s), nil
+	}
+	if c, ok := strings.CutPrefix(want, "contains:"); ok {
+		return strings.Contains(actual, c), nil
+	}
+	if strings.ContainsAny(want, "*?") {
+		return path.Match(want, actual)
+	}
+	return actual == want, nil
+}
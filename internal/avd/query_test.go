@@ -0,0 +1,57 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestQueryInfosFiltersAndSorts(t *testing.T) {
+	infos := []Info{
+		{Name: "base-a35", Kind: KindBase, APILevel: 35, SizeBytes: 4096},
+		{Name: "w-acme", Kind: KindClone, APILevel: 35, SizeBytes: 300, Running: true, Booted: true},
+		{Name: "w-globex", Kind: KindClone, APILevel: 34, SizeBytes: 900, Running: true},
+		{Name: "w-initech", Kind: KindClone, APILevel: 35, SizeBytes: 100},
+	}
+	names := func(spec QuerySpec) string {
+		t.Helper()
+		got, err := QueryInfos(infos, spec)
+		if err != nil {
+			t.Fatalf("QueryInfos(%+v): %v", spec, err)
+		}
+		var out []string
+		for _, info := range got {
+			out = append(out, info.Name)
+		}
+		return strings.Join(out, ",")
+	}
+	for want, spec := range map[string]QuerySpec{
+		"w-initech,w-acme,w-globex": {Filters: []Filter{{"name", "prefix:w-"}}, Sort: "size"},
+		"w-globex,w-acme":           {Filters: []Filter{{"running", "true"}}, Sort: "-size"},
+		"w-acme":                    {Filters: []Filter{{"booted", "true"}}},
+		"base-a35,w-acme,w-initech": {Filters: []Filter{{"api", ">=35"}}},
+		"w-acme,w-globex":           {Filters: []Filter{{"name", "w-*e*"}, {"size", ">200"}}},
+	} {
+		if got := names(spec); got != want {
+			t.Fatalf("QueryInfos(%+v) = %s, want %s", spec, got, want)
+		}
+	}
+	if _, err := QueryInfos(infos, QuerySpec{Filters: []Filter{{"color", "red"}}}); err == nil {
+		t.Fatal("unknown filter field accepted")
+	}
+	if _, err := QueryInfos(infos, QuerySpec{Filters: []Filter{{"name", ">3"}}}); err == nil {
+		t.Fatal("numeric comparison on a text field accepted")
+	}
+}
+
+func TestParseFilter(t *testing.T) {
+	f, err := ParseFilter("Name=prefix:w-")
+	if err != nil || f != (Filter{Field: "name", Value: "prefix:w-"}) {
+		t.Fatalf("ParseFilter = %+v, %v", f, err)
+	}
+	if _, err := ParseFilter("booted"); err == nil {
+		t.Fatal("ParseFilter accepted a filter without =")
+	}
+}
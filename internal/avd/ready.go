@@ -0,0 +1,161 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// resultOK is Activity.RESULT_OK, what a receiver sets with setResultCode(RESULT_OK).
+const resultOK = -1
+
+// ReadyBroadcast is an app-level readiness probe: once the OS has booted, WaitForBootWithOptions
+// sends Action with `am broadcast` until a receiver in the app answers with ResultCode (and Data,
+// when set). The app decides what "up" means, e.g. its services are bound or its database is
+// migrated, and answers only then.
+type ReadyBroadcast struct {
+	Action string // intent action to broadcast; required
+	// Component is the explicit receiver, e.g. com.example/.ReadyReceiver. Android 8+ does not
+	// deliver implicit broadcasts to receivers declared in the manifest, so set Component or Package.
+	Component string
+	Package   string // restrict delivery to this package
+	// ResultCode is the code that means ready; 0 stands for Activity.RESULT_OK (-1), because 0 is
+	// what am reports when no receiver answered.
+	ResultCode int
+	Data       string        // if set, the result data must equal it
+	Interval   time.Duration // between probes; default 1s
+}
+
+// WaitOptions controls WaitForBootWithOptions.
+type WaitOptions struct {
+	Timeout  time.Duration // for the OS boot and the readiness probe together
+	Progress BootProgressFunc
+	// ReadyBroadcast, when set, also waits for an app to report itself ready; the progress
+	// status is "waiting_ready" meanwhile.
+	ReadyBroadcast *ReadyBroadcast
+	// PhaseTimeouts bounds single phases within Timeout, e.g. to fail fast when adb never sees
+	// the instance; an exhausted phase returns *BootPhaseTimeoutError.
+	PhaseTimeouts PhaseTimeouts
+}
+
+// WaitForBootWithOptions waits like WaitForBootWithProgress and then, with opts.ReadyBroadcast,
+// for the app-level readiness probe to succeed within the same timeout.
+func WaitForBootWithOptions(env Env, serial string, opts WaitOptions) error {
+	if err := opts.PhaseTimeouts.Validate(); err != nil {
+		return err
+	}
+	start := time.Now()
+	if err := waitForBoot(env, serial, opts.Timeout, opts.Progress, opts.PhaseTimeouts); err != nil {
+		return err
+	}
+	if opts.ReadyBroadcast == nil {
+		return nil
+	}
+	deadline := start.Add(opts.Timeout)
+	end, limited := opts.PhaseTimeouts.budget(BootPhaseWaitingReady, deadline)
+	err := waitForReadyBroadcast(env, serial, *opts.ReadyBroadcast, time.Until(end), func(status string) {
+		if opts.Progress != nil {
+			opts.Progress(status, time.Since(start))
+		}
+	})
+	if err != nil && limited && !time.Now().Before(end) {
+		return &BootPhaseTimeoutError{Serial: serial, Phase: BootPhaseWaitingReady, Timeout: opts.PhaseTimeouts[BootPhaseWaitingReady], Detail: err.Error()}
+	}
+	return err
+}
+
+func waitForReadyBroadcast(env Env, serial string, probe ReadyBroadcast, timeout time.Duration, progress func(string)) error {
+	_, span := startSpan(env, "avd.WaitForReadyBroadcast", attribute.String("serial", serial), attribute.String("action", probe.Action))
+	defer span.End()
+	if strings.TrimSpace(probe.Action) == "" {
+		err := errors.New("ready broadcast needs an action")
+		recordSpanError(span, err)
+		return err
+	}
+	want := probe.ResultCode
+	if want == 0 {
+		want = resultOK
+	}
+	interval := probe.Interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ctx := env.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	start := time.Now()
+	deadline := start.Add(timeout)
+	last := "no reply yet"
+	for {
+		progress(string(BootPhaseWaitingReady))
+		code, data, err := sendReadyBroadcast(ctx, env, serial, probe)
+		switch {
+		case err != nil:
+			last = err.Error()
+		case code == want && (probe.Data == "" || data == probe.Data):
+			span.SetAttributes(attribute.String("ready_after", time.Since(start).String()))
+			progress(string(BootPhaseAppReady))
+			logEvent(env, "app reported ready", "serial", serial, "action", probe.Action, "duration", time.Since(start).String())
+			return nil
+		default:
+			last = fmt.Sprintf("result=%d data=%q", code, data)
+		}
+		if !time.Now().Add(interval).Before(deadline) {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			recordSpanError(span, ctx.Err())
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+	err := fmt.Errorf("%s booted but %s did not report ready within %s (last: %s)", serial, probe.Action, timeout.Round(time.Second), last)
+	recordSpanError(span, err)
+	return err
+}
+
+// broadcastResultRe matches the last line of `am broadcast`, e.g.
+// Broadcast completed: result=-1, data="ready".
+var broadcastResultRe = regexp.MustCompile(`Broadcast completed: result=(-?\d+)(?:, data="((?:[^"\\]|\\.)*)")?`)
+
+func sendReadyBroadcast(ctx context.Context, env Env, serial string, probe ReadyBroadcast) (int, string, error) {
+	args := []string{"-s", serial, "shell", "am", "broadcast", "-a", probe.Action}
+	if probe.Component != "" {
+		args = append(args, "-n", probe.Component)
+	}
+	if probe.Package != "" {
+		args = append(args, "-p", probe.Package)
+	}
+	out, errOut, err := runCommandOutputWithEnv(ctx, nil, nil, env.ADB, args...)
+	if err != nil {
+		if msg := strings.TrimSpace(errOut); msg != "" {
+			return 0, "", fmt.Errorf("%w: %s", err, msg)
+		}
+		return 0, "", err
+	}
+	return parseBroadcastResult(out)
+}
+
+func parseBroadcastResult(out string) (int, string, error) {
+	m := broadcastResultRe.FindStringSubmatch(out)
+	if m == nil {
+		return 0, "", fmt.Errorf("unexpected am broadcast output: %q", strings.TrimSpace(out))
+	}
+	code, err := strconv.Atoi(m[1])
+	if err != nil {
+		return 0, "", err
+	}
+	data := strings.ReplaceAll(m[2], `\"`, `"`)
+	return code, data, nil
+}
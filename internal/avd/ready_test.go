@@ -0,0 +1,74 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseBroadcastResult(t *testing.T) {
+	out := "Broadcasting: Intent { act=com.example.READY flg=0x400000 }\n" +
+		"Broadcast completed: result=-1, data=\"db \\\"v3\\\" ready\"\n"
+	code, data, err := parseBroadcastResult(out)
+	if err != nil || code != -1 || data != `db "v3" ready` {
+		t.Fatalf("parseBroadcastResult = %d, %q, %v", code, data, err)
+	}
+	if code, data, err := parseBroadcastResult("Broadcast completed: result=0\n"); err != nil || code != 0 || data != "" {
+		t.Fatalf("parseBroadcastResult(no receiver) = %d, %q, %v", code, data, err)
+	}
+	if _, _, err := parseBroadcastResult("Error: unknown option"); err == nil {
+		t.Fatal("expected an error for unexpected output")
+	}
+}
+
+// newBroadcastEnv stubs adb so that the first unanswered probes report result=0 and later
+// ones the given reply; the stub logs its arguments to args.log.
+func newBroadcastEnv(t *testing.T, unanswered int, reply string) (Env, string) {
+	t.Helper()
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "args.log")
+	script := "#!/bin/sh\n" +
+		"echo \"$@\" >> " + logPath + "\n" +
+		"n=$(wc -l < " + logPath + ")\n" +
+		"if [ \"$n\" -le " + strconv.Itoa(unanswered) + " ]; then echo 'Broadcast completed: result=0'; else echo '" + reply + "'; fi\n"
+	adb := filepath.Join(dir, "adb")
+	if err := os.WriteFile(adb, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return Env{AVDHome: dir, ADB: adb}, logPath
+}
+
+func TestWaitForReadyBroadcast(t *testing.T) {
+	env, logPath := newBroadcastEnv(t, 2, `Broadcast completed: result=-1, data="ready"`)
+	probe := ReadyBroadcast{Action: "com.example.READY", Component: "com.example/.Ready", Data: "ready", Interval: time.Millisecond}
+	var statuses []string
+	if err := waitForReadyBroadcast(env, "emulator-5580", probe, 5*time.Second, func(s string) { statuses = append(statuses, s) }); err != nil {
+		t.Fatalf("waitForReadyBroadcast: %v", err)
+	}
+	b, _ := os.ReadFile(logPath)
+	calls := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if len(calls) != 3 || calls[0] != "-s emulator-5580 shell am broadcast -a com.example.READY -n com.example/.Ready" {
+		t.Fatalf("unexpected probes: %q", calls)
+	}
+	if statuses[len(statuses)-1] != "app_ready" {
+		t.Fatalf("statuses = %v", statuses)
+	}
+}
+
+func TestWaitForReadyBroadcastTimesOut(t *testing.T) {
+	env, _ := newBroadcastEnv(t, 0, `Broadcast completed: result=-1, data="migrating"`)
+	probe := ReadyBroadcast{Action: "com.example.READY", Package: "com.example", Data: "ready", Interval: 10 * time.Millisecond}
+	err := waitForReadyBroadcast(env, "emulator-5580", probe, 50*time.Millisecond, func(string) {})
+	if err == nil || !strings.Contains(err.Error(), `data="migrating"`) {
+		t.Fatalf("expected a timeout naming the last reply, got %v", err)
+	}
+	if err := waitForReadyBroadcast(env, "emulator-5580", ReadyBroadcast{}, time.Second, func(string) {}); err == nil {
+		t.Fatal("expected an error without an action")
+	}
+}
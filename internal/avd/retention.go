@@ -0,0 +1,415 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Kinds of files kept with a run (see RunArtifact and PrunedItem).
+const (
+	ArtifactLog        = "log" // the emulator log, InstanceRun.LogPath
+	ArtifactLogcat     = "logcat"
+	ArtifactScreenshot = "screenshot"
+	ArtifactBugreport  = "bugreport"
+)
+
+// RunArtifact is a file CaptureArtifacts collected during a run.
+type RunArtifact struct {
+	Kind       string    `json:"kind"`
+	Path       string    `json:"path"`
+	CapturedAt time.Time `json:"captured_at"`
+}
+
+// RetentionRule caps what is kept per AVD; zero fields do not limit.
+type RetentionRule struct {
+	MaxAge   time.Duration `json:"max_age,omitempty"`
+	MaxBytes int64         `json:"max_bytes,omitempty"`
+	MaxCount int           `json:"max_count,omitempty"`
+}
+
+// RetentionPolicy is how much run history, and which of its files, each AVD keeps. Logs covers
+// emulator logs and captured logcat dumps. Runs ignores MaxBytes and keeps maxRunHistory runs
+// when MaxCount is 0. A run record dropped by Runs takes its files with it.
+type RetentionPolicy struct {
+	Runs        RetentionRule `json:"runs"`
+	Logs        RetentionRule `json:"logs"`
+	Screenshots RetentionRule `json:"screenshots"`
+	Bugreports  RetentionRule `json:"bugreports"`
+}
+
+// PrunedItem is a run record or file removed by retention, or that a dry run would remove.
+type PrunedItem struct {
+	AVD       string    `json:"avd"`
+	Kind      string    `json:"kind"`           // "run" or an artifact kind
+	Path      string    `json:"path,omitempty"` // empty for run records
+	StartedAt time.Time `json:"started_at"`     // of the run
+	SizeBytes int64     `json:"size_bytes,omitempty"`
+	Reason    string    `json:"reason"` // age, count or size
+}
+
+// ParseRetentionPolicy parses comma-separated limits such as
+// "runs.age=30d,runs.count=50,logs.size=500M,screenshots.count=10,bugreports.age=14d". Kinds
+// are runs, logs, screenshots and bugreports; limits are age (as ParseRetention, "off" for
+// none), size (as ParseSize; not for runs) and count.
+func ParseRetentionPolicy(s string) (RetentionPolicy, error) {
+	var policy RetentionPolicy
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(entry, "=")
+		kind, limit, ok2 := strings.Cut(strings.TrimSpace(key), ".")
+		if !ok || !ok2 {
+			return RetentionPolicy{}, fmt.Errorf("invalid retention %q (want kind.limit=value, e.g. logs.age=7d)", entry)
+		}
+		var rule *RetentionRule
+		switch kind {
+		case "runs":
+			rule = &policy.Runs
+		case "logs":
+			rule = &policy.Logs
+		case "screenshots":
+			rule = &policy.Screenshots
+		case "bugreports":
+			rule = &policy.Bugreports
+		default:
+			return RetentionPolicy{}, fmt.Errorf("invalid retention %q: unknown kind %q (want runs, logs, screenshots or bugreports)", entry, kind)
+		}
+		value = strings.TrimSpace(value)
+		switch {
+		case limit == "age":
+			d, err := ParseRetention(value)
+			if err != nil {
+				return RetentionPolicy{}, err
+			}
+			rule.MaxAge = max(d, 0)
+		case limit == "size" && kind != "runs":
+			n, err := ParseSize(value)
+			if err != nil {
+				return RetentionPolicy{}, err
+			}
+			rule.MaxBytes = n
+		case limit == "count":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return RetentionPolicy{}, fmt.Errorf("invalid retention %q: count must be a non-negative integer", entry)
+			}
+			rule.MaxCount = n
+		default:
+			return RetentionPolicy{}, fmt.Errorf("invalid retention %q: unknown limit %q for %s", entry, limit, kind)
+		}
+	}
+	return policy, nil
+}
+
+// retentionClass is the RetentionPolicy rule an artifact kind counts against.
+func retentionClass(kind string) string {
+	if kind == ArtifactLog || kind == ArtifactLogcat {
+		return "logs"
+	}
+	return kind
+}
+
+func (p RetentionPolicy) rule(kind string) RetentionRule {
+	switch kind {
+	case ArtifactLog, ArtifactLogcat:
+		return p.Logs
+	case ArtifactScreenshot:
+		return p.Screenshots
+	case ArtifactBugreport:
+		return p.Bugreports
+	}
+	return RetentionRule{}
+}
+
+// RunHistory returns the runs of the AVD name that avdctl recorded, oldest first, with the
+// files kept for each and the outcome of the runs that ended.
+func RunHistory(env Env, name string) ([]InstanceRun, error) {
+	avdDir := filepath.Join(env.AVDHome, name+".avd")
+	if !fileExists(avdDir) {
+		return nil, fmt.Errorf("AVD %s not found", name)
+	}
+	runs, _ := classifyRuns(avdDir, readRunHistory(avdDir))
+	return runs, nil
+}
+
+// ExportHistory writes the run history of the AVD name to w as a gzipped tar: name/runs.json,
+// and the files of each run that still exist under name/<start time>/.
+func ExportHistory(env Env, name string, w io.Writer) error {
+	_, span := startSpan(env, "avd.ExportHistory", attribute.String("name", name))
+	defer span.End()
+	runs, err := RunHistory(env, name)
+	if err != nil {
+		recordSpanError(span, err)
+		return err
+	}
+	if err := exportHistory(name, runs, w); err != nil {
+		err = fmt.Errorf("export history of %s: %w", name, err)
+		recordSpanError(span, err)
+		return err
+	}
+	return nil
+}
+
+func exportHistory(name string, runs []InstanceRun, w io.Writer) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	b, err := json.MarshalIndent(runs, "", "  ")
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	if err := tw.WriteHeader(&tar.Header{Name: name + "/runs.json", Mode: 0o644, Size: int64(len(b)), ModTime: time.Now()}); err != nil {
+		return err
+	}
+	if _, err := tw.Write(b); err != nil {
+		return err
+	}
+	for _, run := range runs {
+		dir := name + "/" + run.StartedAt.UTC().Format("20060102T150405Z")
+		paths := []string{run.LogPath}
+		for _, a := range run.Artifacts {
+			paths = append(paths, a.Path)
+		}
+		for _, path := range paths {
+			if path == "" {
+				continue
+			}
+			if err := addTarFile(tw, dir+"/"+filepath.Base(path), path); err != nil {
+				return err
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// addTarFile adds the regular file path to tw as name; missing files are skipped.
+func addTarFile(tw *tar.Writer, name, path string) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil || !info.Mode().IsRegular() {
+		return err
+	}
+	if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: info.Size(), ModTime: info.ModTime()}); err != nil {
+		return err
+	}
+	_, err = io.CopyN(tw, f, info.Size())
+	return err
+}
+
+// PruneHistory applies Env.Retention to the run history of the AVD name, or of every AVD when
+// name is empty, and returns what it removed. With dryRun it only reports what would go. The
+// run of an instance still running and its emulator log are never pruned.
+func PruneHistory(env Env, name string, dryRun bool) ([]PrunedItem, error) {
+	_, span := startSpan(env, "avd.PruneHistory", attribute.String("name", name), attribute.Bool("dry_run", dryRun))
+	defer span.End()
+	dirs := []string{filepath.Join(env.AVDHome, name+".avd")}
+	if name == "" {
+		dirs, _ = filepath.Glob(filepath.Join(env.AVDHome, "*.avd"))
+	} else if !fileExists(dirs[0]) {
+		err := fmt.Errorf("AVD %s not found", name)
+		recordSpanError(span, err)
+		return nil, err
+	}
+	var pruned []PrunedItem
+	for _, avdDir := range dirs {
+		pruned = append(pruned, applyRetention(env, avdDir, dryRun)...)
+	}
+	span.SetAttributes(attribute.Int("pruned", len(pruned)))
+	return pruned, nil
+}
+
+// applyRetention prunes the run history of avdDir by Env.Retention, unless it is quarantined.
+func applyRetention(env Env, avdDir string, dryRun bool) []PrunedItem {
+	runs := readRunHistory(avdDir)
+	if len(runs) == 0 {
+		return nil
+	}
+	if _, quarantined := quarantineOf(avdDir); quarantined {
+		return nil
+	}
+	livePID := 0
+	if rec, ok := readInstance(avdDir); ok && rec.PID > 0 && syscall.Kill(rec.PID, 0) == nil && !isZombieProcess(rec.PID) {
+		livePID = rec.PID
+	}
+	name := strings.TrimSuffix(filepath.Base(avdDir), ".avd")
+	kept, pruned, remove := planRetention(name, runs, env.Retention, time.Now(), livePID)
+	if dryRun || len(pruned) == 0 {
+		return pruned
+	}
+	for _, path := range remove {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			logEvent(env, "history file not pruned", "avd", name, "path", path, "error", err)
+		}
+	}
+	writeRunHistory(avdDir, kept)
+	logEvent(env, "history pruned", "avd", name, "items", len(pruned), "files", len(remove))
+	return pruned
+}
+
+// planRetention decides, for runs in time order, which run records and files policy keeps at
+// now. It returns the runs to write back, what was pruned, and the files to remove: those no
+// kept run still refers to, as a port reused by a later run reuses its log path.
+func planRetention(name string, runs []InstanceRun, policy RetentionPolicy, now time.Time, livePID int) ([]InstanceRun, []PrunedItem, []string) {
+	var pruned []PrunedItem
+	prune := func(run InstanceRun, kind, path, reason string) {
+		item := PrunedItem{AVD: name, Kind: kind, Path: path, StartedAt: run.StartedAt, Reason: reason}
+		if path != "" {
+			item.SizeBytes = fileSize(path)
+		}
+		pruned = append(pruned, item)
+	}
+	old := func(rule RetentionRule, t time.Time) bool {
+		return rule.MaxAge > 0 && now.Sub(t) > rule.MaxAge
+	}
+	live := func(run InstanceRun) bool { return livePID > 0 && run.PID == livePID }
+
+	maxRuns := policy.Runs.MaxCount
+	if maxRuns == 0 {
+		maxRuns = maxRunHistory
+	}
+	var kept []InstanceRun
+	var dropped []InstanceRun
+	var droppedReasons []string
+	for i := len(runs) - 1; i >= 0; i-- {
+		run := runs[i]
+		switch {
+		case live(run):
+		case len(kept) >= maxRuns:
+			prune(run, "run", "", "count")
+			dropped, droppedReasons = append(dropped, run), append(droppedReasons, "count")
+			continue
+		case old(policy.Runs, run.StartedAt):
+			prune(run, "run", "", "age")
+			dropped, droppedReasons = append(dropped, run), append(droppedReasons, "age")
+			continue
+		}
+		kept = append(kept, run)
+	}
+	for i, j := 0, len(kept)-1; i < j; i, j = i+1, j-1 {
+		kept[i], kept[j] = kept[j], kept[i]
+	}
+
+	// Files of the kept runs, newest first, against the rule of their kind.
+	type file struct {
+		run, artifact int // artifact is -1 for the emulator log
+		kind, path    string
+		at            time.Time
+	}
+	var files []file
+	for i, run := range kept {
+		if run.LogPath != "" {
+			files = append(files, file{run: i, artifact: -1, kind: ArtifactLog, path: run.LogPath, at: run.StartedAt})
+		}
+		for j, a := range run.Artifacts {
+			files = append(files, file{run: i, artifact: j, kind: a.Kind, path: a.Path, at: a.CapturedAt})
+		}
+	}
+	sort.SliceStable(files, func(i, j int) bool { return files[i].at.After(files[j].at) })
+	type usage struct {
+		count int
+		bytes int64
+	}
+	used := map[string]*usage{}
+	seen := map[string]bool{}
+	drop := map[[2]int]bool{}
+	var remove []string
+	for _, f := range files {
+		if seen[f.path] {
+			// An older run's reference to a file a newer run reuses.
+			drop[[2]int{f.run, f.artifact}] = true
+			continue
+		}
+		seen[f.path] = true
+		rule := policy.rule(f.kind)
+		u := used[retentionClass(f.kind)]
+		if u == nil {
+			u = &usage{}
+			used[retentionClass(f.kind)] = u
+		}
+		size := fileSize(f.path)
+		reason := ""
+		switch {
+		case f.artifact == -1 && live(kept[f.run]):
+		case old(rule, f.at):
+			reason = "age"
+		case rule.MaxCount > 0 && u.count >= rule.MaxCount:
+			reason = "count"
+		case rule.MaxBytes > 0 && u.bytes+size > rule.MaxBytes:
+			reason = "size"
+		}
+		if reason == "" {
+			u.count++
+			u.bytes += size
+			continue
+		}
+		prune(kept[f.run], f.kind, f.path, reason)
+		drop[[2]int{f.run, f.artifact}] = true
+		remove = append(remove, f.path)
+	}
+	for i := range kept {
+		if drop[[2]int{i, -1}] {
+			kept[i].LogPath = ""
+		}
+		var artifacts []RunArtifact
+		for j, a := range kept[i].Artifacts {
+			if !drop[[2]int{i, j}] {
+				artifacts = append(artifacts, a)
+			}
+		}
+		kept[i].Artifacts = artifacts
+	}
+
+	// Files of dropped runs go with them, unless a kept run refers to them too.
+	for k, run := range dropped {
+		paths := []string{run.LogPath}
+		kinds := []string{ArtifactLog}
+		for _, a := range run.Artifacts {
+			paths = append(paths, a.Path)
+			kinds = append(kinds, a.Kind)
+		}
+		for i, path := range paths {
+			if path == "" || seen[path] {
+				continue
+			}
+			seen[path] = true
+			prune(run, kinds[i], path, droppedReasons[k])
+			remove = append(remove, path)
+		}
+	}
+	return kept, pruned, remove
+}
+
+func fileSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
@@ -0,0 +1,154 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseRetentionPolicy(t *testing.T) {
+	policy, err := ParseRetentionPolicy("runs.age=30d, runs.count=50,logs.size=1M,screenshots.count=2,bugreports.age=off")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := RetentionPolicy{
+		Runs:        RetentionRule{MaxAge: 30 * 24 * time.Hour, MaxCount: 50},
+		Logs:        RetentionRule{MaxBytes: 1 << 20},
+		Screenshots: RetentionRule{MaxCount: 2},
+	}
+	if policy != want {
+		t.Fatalf("policy = %+v, want %+v", policy, want)
+	}
+	for _, bad := range []string{"logs=7d", "traces.age=7d", "runs.size=1G", "logs.count=-1", "logs.age=soon"} {
+		if _, err := ParseRetentionPolicy(bad); err == nil {
+			t.Errorf("ParseRetentionPolicy(%q) succeeded", bad)
+		}
+	}
+}
+
+func writeSizedFile(t *testing.T, path string, size int) string {
+	t.Helper()
+	if err := os.WriteFile(path, bytes.Repeat([]byte("x"), size), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestPlanRetention(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	at := func(hoursAgo int) time.Time { return now.Add(-time.Duration(hoursAgo) * time.Hour) }
+	shared := writeSizedFile(t, filepath.Join(dir, "emulator-w-5580.log"), 10)
+	runs := []InstanceRun{
+		{StartedAt: at(100), PID: 1, LogPath: shared},
+		{StartedAt: at(50), PID: 2, LogPath: writeSizedFile(t, filepath.Join(dir, "old.log"), 10), Artifacts: []RunArtifact{
+			{Kind: ArtifactScreenshot, Path: writeSizedFile(t, filepath.Join(dir, "s1.png"), 10), CapturedAt: at(50)},
+			{Kind: ArtifactBugreport, Path: writeSizedFile(t, filepath.Join(dir, "b1.zip"), 100), CapturedAt: at(50)},
+		}},
+		{StartedAt: at(2), PID: 3, LogPath: shared, Artifacts: []RunArtifact{
+			{Kind: ArtifactScreenshot, Path: writeSizedFile(t, filepath.Join(dir, "s2.png"), 10), CapturedAt: at(1)},
+			{Kind: ArtifactLogcat, Path: writeSizedFile(t, filepath.Join(dir, "logcat.txt"), 15), CapturedAt: at(1)},
+		}},
+	}
+	policy := RetentionPolicy{
+		Runs:        RetentionRule{MaxAge: 72 * time.Hour},
+		Logs:        RetentionRule{MaxBytes: 20},
+		Screenshots: RetentionRule{MaxCount: 1},
+	}
+	kept, pruned, remove := planRetention("w", runs, policy, now, 3)
+
+	if len(kept) != 2 || kept[0].PID != 2 || kept[1].PID != 3 {
+		t.Fatalf("kept = %+v", kept)
+	}
+	// The newest logcat fits the byte cap, the live run's log is kept over it and the older log
+	// is not.
+	if kept[1].LogPath != shared || len(kept[1].Artifacts) != 2 {
+		t.Fatalf("live run = %+v", kept[1])
+	}
+	if kept[0].LogPath != "" || len(kept[0].Artifacts) != 1 || kept[0].Artifacts[0].Kind != ArtifactBugreport {
+		t.Fatalf("older run = %+v", kept[0])
+	}
+	reasons := map[string]string{}
+	for _, item := range pruned {
+		reasons[item.Kind+" "+filepath.Base(item.Path)] = item.Reason
+	}
+	want := map[string]string{
+		"run .":             "age",
+		"log old.log":       "size",
+		"screenshot s1.png": "count",
+	}
+	if len(reasons) != len(want) {
+		t.Fatalf("pruned = %+v", pruned)
+	}
+	for k, v := range want {
+		if reasons[k] != v {
+			t.Errorf("pruned %s: reason %q, want %q (%+v)", k, reasons[k], v, pruned)
+		}
+	}
+	// The dropped run's log is the live run's too.
+	if slices.Contains(remove, shared) || len(remove) != 2 {
+		t.Fatalf("remove = %v", remove)
+	}
+}
+
+func TestPruneAndExportHistory(t *testing.T) {
+	env := newTestEnv(t)
+	makeBaseAVD(t, env, "w")
+	avdDir := filepath.Join(env.AVDHome, "w.avd")
+	dir := t.TempDir()
+	var runs []InstanceRun
+	for i := range 3 {
+		log := writeSizedFile(t, filepath.Join(dir, "run"+string(rune('a'+i))+".log"), 4)
+		runs = append(runs, InstanceRun{StartedAt: time.Now().Add(time.Duration(i-3) * time.Hour), PID: 100000 + i, LogPath: log})
+	}
+	writeRunHistory(avdDir, runs)
+	env.Retention = RetentionPolicy{Runs: RetentionRule{MaxCount: 2}}
+
+	var buf bytes.Buffer
+	if err := ExportHistory(env, "w", &buf); err != nil {
+		t.Fatalf("ExportHistory: %v", err)
+	}
+	gz, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, hdr.Name)
+	}
+	if len(names) != 4 || names[0] != "w/runs.json" || filepath.Base(names[1]) != "runa.log" {
+		t.Fatalf("archive = %v", names)
+	}
+
+	dry, err := PruneHistory(env, "w", true)
+	if err != nil || len(dry) != 2 || !fileExists(runs[0].LogPath) {
+		t.Fatalf("dry run = %+v, %v", dry, err)
+	}
+	if _, err := PruneHistory(env, "", false); err != nil {
+		t.Fatal(err)
+	}
+	left, err := RunHistory(env, "w")
+	if err != nil || len(left) != 2 || left[0].PID != runs[1].PID || fileExists(runs[0].LogPath) {
+		t.Fatalf("history after prune = %+v, %v", left, err)
+	}
+	if _, err := PruneHistory(env, "missing", false); err == nil {
+		t.Fatal("PruneHistory of a missing AVD succeeded")
+	}
+}
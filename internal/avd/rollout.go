@@ -0,0 +1,348 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// rolloutPreviousSuffix is appended to a clone's .avd directory and .ini while its replacement
+// is verified, so a failed batch can be put back as it was.
+const rolloutPreviousSuffix = ".rollout-previous"
+
+// DefaultRolloutBatch is the number of clones Rollout replaces at a time.
+const DefaultRolloutBatch = 5
+
+// RolloutOptions configures Rollout.
+type RolloutOptions struct {
+	// Golden is the new golden: a directory, a name in Env.GoldenDir or "@<channel>".
+	Golden string
+	// Base is the base AVD of the clones (default: the new golden's source AVD).
+	Base string
+	// Clones are the clones to replace. Empty selects the clones made from From.
+	Clones []string
+	// From selects the clones to replace by the golden they were made from. With neither
+	// Clones nor From, every clone of Base not on Golden yet is replaced.
+	From string
+	// BatchSize is how many clones are replaced and verified together (default: DefaultRolloutBatch).
+	BatchSize int
+	// BootTimeout bounds the boot of each new clone (default: 3m).
+	BootTimeout time.Duration
+}
+
+// RolloutReport lists the actions of a rollout. Actions use the FleetAction kinds plus
+// "check" (health checks of a new clone) and "restore" (a clone put back after a failed batch).
+type RolloutReport struct {
+	FleetReport
+	Golden  string   `json:"golden"`
+	Updated []string `json:"updated"` // clones now running the new golden
+	Aborted bool     `json:"aborted"` // a batch failed; later batches were not started
+}
+
+// rolloutStartFn starts a clone on port and waits for it to boot; tests replace it.
+var rolloutStartFn = startRolloutClone
+
+// Rollout replaces clones with fresh clones of a new golden, opts.BatchSize at a time. For each
+// batch the clones are stopped, set aside, re-cloned (keeping their identity), booted on their
+// previous port and health-checked. Clones that were not running are stopped again once they
+// pass. If any clone of a batch fails, the whole batch is put back as it was and the rollout
+// stops; batches that already passed keep the new golden.
+func Rollout(env Env, opts RolloutOptions) (RolloutReport, error) {
+	_, span := startSpan(env, "avd.Rollout", attribute.String("golden", opts.Golden), attribute.Int("batch", opts.BatchSize))
+	defer span.End()
+	fail := func(err error) (RolloutReport, error) {
+		recordSpanError(span, err)
+		return RolloutReport{}, err
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultRolloutBatch
+	}
+	if opts.BootTimeout == 0 {
+		opts.BootTimeout = 3 * time.Minute
+	}
+	golden, err := ResolveGolden(env, opts.Golden)
+	if err != nil {
+		return fail(err)
+	}
+	if golden == "" {
+		return fail(errors.New("rollout needs a golden"))
+	}
+	if golden, err = filepath.Abs(golden); err != nil {
+		return fail(err)
+	}
+	fingerprint, err := goldenFingerprint(golden)
+	if err != nil {
+		return fail(fmt.Errorf("fingerprint golden: %w", err))
+	}
+	if opts.Base == "" {
+		manifest, err := ReadGoldenManifest(golden)
+		if err != nil || manifest.Source == "" {
+			return fail(fmt.Errorf("cannot tell the base AVD of %s; pass it explicitly", golden))
+		}
+		opts.Base = manifest.Source
+	}
+	clones, err := rolloutClones(env, opts)
+	if err != nil {
+		return fail(err)
+	}
+	procs, err := ListRunning(env)
+	if err != nil {
+		return fail(err)
+	}
+	running := map[string]ProcInfo{}
+	for _, p := range procs {
+		if p.Name != "" {
+			running[p.Name] = p
+		}
+	}
+
+	report := RolloutReport{Golden: golden}
+	var pending []string
+	for _, name := range clones {
+		if ok, _ := cloneMatchesFingerprint(filepath.Join(env.AVDHome, name+".avd"), fingerprint); ok {
+			report.add("clone", name, "unchanged", golden)
+			continue
+		}
+		pending = append(pending, name)
+	}
+	logEvent(env, "rollout start", "golden", golden, "clones", len(pending), "batch", opts.BatchSize)
+	for start := 0; start < len(pending); start += opts.BatchSize {
+		batch := pending[start:min(start+opts.BatchSize, len(pending))]
+		if !rolloutBatch(env, &report, opts, golden, batch, running) {
+			report.Aborted = true
+			logEvent(env, "rollout aborted", "golden", golden, "batch", batch, "updated", len(report.Updated))
+			break
+		}
+		report.Updated = append(report.Updated, batch...)
+		logEvent(env, "rollout batch done", "golden", golden, "batch", batch)
+	}
+	if report.Aborted {
+		recordSpanError(span, errors.New("rollout aborted"))
+	}
+	return report, nil
+}
+
+// rolloutBatch replaces batch and reports whether every new clone passed its health checks.
+// On failure the batch is restored before returning.
+func rolloutBatch(env Env, report *RolloutReport, opts RolloutOptions, golden string, batch []string, running map[string]ProcInfo) bool {
+	started := map[string]string{}
+	var replaced []string
+	ok := true
+	for _, name := range batch {
+		// Re-clone with the config variables and link policy the clone was made with.
+		cloneOpts, err := readCloneOptions(filepath.Join(env.AVDHome, name+".avd"))
+		if err != nil {
+			report.add("clone", name, "failed", fmt.Sprintf("%v; its clone options were not recorded (cloned by an older avdctl), re-create it before a rollout", err))
+			ok = false
+			break
+		}
+		if proc, isRunning := running[name]; isRunning {
+			if err := StopBySerial(env, proc.Serial); err != nil {
+				report.add("stop", name, "failed", err.Error())
+				ok = false
+				break
+			}
+			report.add("stop", name, "done", proc.Serial)
+		}
+		replaced = append(replaced, name)
+		if err := setAsidePrevious(env, name); err != nil {
+			report.add("clone", name, "failed", err.Error())
+			ok = false
+			break
+		}
+		if _, err := cloneFromGolden(env, opts.Base, name, golden, cloneOpts); err != nil {
+			report.add("clone", name, "failed", err.Error())
+			ok = false
+			break
+		}
+		keepPreviousIdentity(env, name)
+		report.add("clone", name, "done", golden)
+		port := running[name].Port
+		if port == 0 {
+			var err error
+			if port, err = stickyPort(env, name); err != nil {
+				report.add("start", name, "failed", err.Error())
+				ok = false
+				break
+			}
+		}
+		serial, err := rolloutStartFn(env, name, port, opts.BootTimeout)
+		if serial != "" {
+			started[name] = serial
+		}
+		if err != nil {
+			report.add("start", name, "failed", err.Error())
+			ok = false
+			break
+		}
+		report.add("start", name, "done", serial)
+	}
+	if ok {
+		for _, name := range batch {
+			var failed []string
+			for _, check := range runHealthChecks(env, started[name]) {
+				if !check.OK {
+					failed = append(failed, check.Name+": "+check.Detail)
+				}
+			}
+			if len(failed) > 0 {
+				report.add("check", name, "failed", strings.Join(failed, "; "))
+				ok = false
+				continue
+			}
+			report.add("check", name, "done", started[name])
+		}
+	}
+
+	if ok {
+		for _, name := range batch {
+			if _, wasRunning := running[name]; !wasRunning {
+				if err := StopBySerial(env, started[name]); err != nil {
+					report.add("stop", name, "failed", err.Error())
+				}
+			}
+			_ = os.RemoveAll(filepath.Join(env.AVDHome, name+".avd"+rolloutPreviousSuffix))
+			_ = os.Remove(filepath.Join(env.AVDHome, name+".ini"+rolloutPreviousSuffix))
+		}
+		return true
+	}
+	for _, name := range replaced {
+		if serial, isStarted := started[name]; isStarted {
+			_ = StopBySerial(env, serial)
+		}
+		if err := restorePrevious(env, name); err != nil {
+			report.add("restore", name, "failed", err.Error())
+			continue
+		}
+		detail := ""
+		if proc, wasRunning := running[name]; wasRunning {
+			serial, err := rolloutStartFn(env, name, proc.Port, opts.BootTimeout)
+			if err != nil {
+				report.add("restore", name, "failed", fmt.Sprintf("restored but not restarted: %v", err))
+				continue
+			}
+			detail = serial
+		}
+		report.add("restore", name, "done", detail)
+	}
+	return false
+}
+
+// rolloutClones returns the clones selected by opts, sorted.
+func rolloutClones(env Env, opts RolloutOptions) ([]string, error) {
+	if len(opts.Clones) > 0 {
+		clones := append([]string(nil), opts.Clones...)
+		for _, name := range clones {
+			if !isCloneDir(filepath.Join(env.AVDHome, name+".avd")) {
+				return nil, fmt.Errorf("%s is not a clone", name)
+			}
+		}
+		sort.Strings(clones)
+		return clones, nil
+	}
+	if opts.From == "" {
+		infos, err := clonesOfBase(env, opts.Base)
+		if err != nil {
+			return nil, err
+		}
+		if len(infos) == 0 {
+			return nil, fmt.Errorf("no clones of %s found", opts.Base)
+		}
+		clones := make([]string, len(infos))
+		for i, info := range infos {
+			clones[i] = info.Name
+		}
+		return clones, nil
+	}
+	from, err := ResolveGolden(env, opts.From)
+	if err != nil {
+		return nil, err
+	}
+	fingerprint, err := goldenFingerprint(from)
+	if err != nil {
+		return nil, fmt.Errorf("fingerprint golden %s: %w", from, err)
+	}
+	infos, err := List(env)
+	if err != nil {
+		return nil, err
+	}
+	var clones []string
+	for _, info := range infos {
+		if ok, _ := cloneMatchesFingerprint(info.Path, fingerprint); ok {
+			clones = append(clones, info.Name)
+		}
+	}
+	if len(clones) == 0 {
+		return nil, fmt.Errorf("no clones of %s found", from)
+	}
+	return clones, nil
+}
+
+// setAsidePrevious moves a clone's .avd directory and .ini out of the way of its replacement.
+func setAsidePrevious(env Env, name string) error {
+	for _, path := range []string{filepath.Join(env.AVDHome, name+".avd"), filepath.Join(env.AVDHome, name+".ini")} {
+		if err := os.Rename(path, path+rolloutPreviousSuffix); err != nil {
+			return fmt.Errorf("set aside %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// restorePrevious deletes a replacement clone and puts the set-aside one back. Paths that were
+// never set aside are left alone.
+func restorePrevious(env Env, name string) error {
+	for _, path := range []string{filepath.Join(env.AVDHome, name+".avd"), filepath.Join(env.AVDHome, name+".ini")} {
+		if !fileExists(path + rolloutPreviousSuffix) {
+			continue
+		}
+		if err := os.RemoveAll(path); err != nil {
+			return err
+		}
+		if err := os.Rename(path+rolloutPreviousSuffix, path); err != nil {
+			return fmt.Errorf("restore %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// keepPreviousIdentity gives a replacement clone the identity of the clone it replaces, so
+// backends keep seeing the same device. Its userdata comes from the new golden, so the Android
+// ID is applied again on first boot.
+func keepPreviousIdentity(env Env, name string) {
+	b, err := os.ReadFile(filepath.Join(env.AVDHome, name+".avd"+rolloutPreviousSuffix, cloneIdentityFilename))
+	if err != nil {
+		return
+	}
+	var identity CloneIdentity
+	if err := json.Unmarshal(b, &identity); err != nil {
+		return
+	}
+	identity.AndroidIDApplied = false
+	_ = writeCloneIdentity(filepath.Join(env.AVDHome, name+".avd"), identity)
+}
+
+func startRolloutClone(env Env, name string, port int, bootTimeout time.Duration) (string, error) {
+	ensureADB(env)
+	res, err := StartEmulatorOnPort(env, name, port)
+	if err != nil {
+		return "", err
+	}
+	serial, logPath := res.Serial, res.LogPath
+	if err := waitForEmulatorSerial(env, serial, 60*time.Second); err != nil {
+		return serial, fmt.Errorf("%w\nEmulator log: %s", err, logPath)
+	}
+	if err := WaitForBoot(env, serial, bootTimeout); err != nil {
+		return serial, fmt.Errorf("%w\nEmulator log: %s", err, logPath)
+	}
+	return serial, nil
+}
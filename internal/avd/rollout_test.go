@@ -0,0 +1,183 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setupRollout creates base-a35 and clones w-1..w-3 of golden v4, and stubs the emulator start.
+func setupRollout(t *testing.T, unhealthySerial string) (Env, string, string) {
+	t.Helper()
+	env := newTestEnv(t)
+	env.GoldenDir = t.TempDir()
+	makeBaseAVD(t, env, "base-a35")
+	v4 := newRegistryGolden(t, env, "v4")
+	v5 := newRegistryGolden(t, env, "v5")
+	for _, name := range []string{"w-1", "w-2", "w-3"} {
+		if _, err := CloneFromGolden(env, "base-a35", name, v4); err != nil {
+			t.Fatalf("clone %s: %v", name, err)
+		}
+	}
+	// Health checks pass on every serial but unhealthySerial.
+	script := `#!/bin/sh
+[ "$1" = "-s" ] || exit 0
+if [ "$2" = "` + unhealthySerial + `" ]; then echo "Error: device offline"; exit 1; fi
+case "$4" in
+  pm) echo "package:/system/framework/framework-res.apk" ;;
+  touch*) echo ok ;;
+  cmd) echo "com.android.launcher3/.Launcher" ;;
+esac
+`
+	if err := os.WriteFile(env.ADB, []byte(script), 0o755); err != nil {
+		t.Fatalf("write adb stub: %v", err)
+	}
+	orig := rolloutStartFn
+	rolloutStartFn = func(_ Env, name string, _ int, _ time.Duration) (string, error) {
+		return "emulator-55" + strings.TrimPrefix(name, "w-") + "0", nil
+	}
+	t.Cleanup(func() { rolloutStartFn = orig })
+	return env, v4, v5
+}
+
+func assertCloneOf(t *testing.T, env Env, name, golden string) {
+	t.Helper()
+	fingerprint, err := goldenFingerprint(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := cloneMatchesFingerprint(filepath.Join(env.AVDHome, name+".avd"), fingerprint); err != nil || !ok {
+		t.Fatalf("%s is not a clone of %s (%v)", name, golden, err)
+	}
+}
+
+func TestRolloutDefaultsToClonesOfBase(t *testing.T) {
+	env, _, v5 := setupRollout(t, "")
+	if _, err := CloneFromGolden(env, "base-a35", "w-4", v5); err != nil {
+		t.Fatalf("clone w-4: %v", err)
+	}
+	report, err := Rollout(env, RolloutOptions{Golden: "v5"})
+	if err != nil {
+		t.Fatalf("Rollout: %v", err)
+	}
+	if report.Aborted || strings.Join(report.Updated, ",") != "w-1,w-2,w-3" {
+		t.Fatalf("updated %v, want the clones of base-a35 not on v5 yet", report.Updated)
+	}
+	for _, a := range report.Actions {
+		if a.Name == "w-4" && a.Status != "unchanged" {
+			t.Fatalf("w-4 is already on v5, got %+v", a)
+		}
+	}
+}
+
+func TestRolloutReplacesClonesInBatches(t *testing.T) {
+	env, _, v5 := setupRollout(t, "")
+	identity, err := ReadCloneIdentity(env, "w-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// w-2 has booted: its Android ID is in its userdata, not in the golden's.
+	applied := identity
+	applied.AndroidIDApplied = true
+	if err := writeCloneIdentity(filepath.Join(env.AVDHome, "w-2.avd"), applied); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := Rollout(env, RolloutOptions{Golden: "v5", From: "v4", BatchSize: 2})
+	if err != nil {
+		t.Fatalf("Rollout: %v", err)
+	}
+	if report.Aborted || report.Failed() || strings.Join(report.Updated, ",") != "w-1,w-2,w-3" {
+		t.Fatalf("unexpected report %#v", report)
+	}
+	for _, name := range []string{"w-1", "w-2", "w-3"} {
+		assertCloneOf(t, env, name, v5)
+	}
+	if got, err := ReadCloneIdentity(env, "w-2"); err != nil || got != identity {
+		t.Fatalf("identity = %#v, %v; want %#v", got, err, identity)
+	}
+	if leftovers, _ := filepath.Glob(filepath.Join(env.AVDHome, "*"+rolloutPreviousSuffix)); len(leftovers) != 0 {
+		t.Fatalf("previous clones left behind: %v", leftovers)
+	}
+
+	report, err = Rollout(env, RolloutOptions{Golden: v5, Clones: []string{"w-1"}})
+	if err != nil || len(report.Updated) != 0 || report.Actions[0].Status != "unchanged" {
+		t.Fatalf("second Rollout = %#v, %v", report, err)
+	}
+}
+
+func TestRolloutRestoresFailedBatchAndAborts(t *testing.T) {
+	env, v4, v5 := setupRollout(t, "emulator-5520")
+
+	report, err := Rollout(env, RolloutOptions{Golden: v5, Clones: []string{"w-3", "w-2", "w-1"}, BatchSize: 1})
+	if err != nil {
+		t.Fatalf("Rollout: %v", err)
+	}
+	if !report.Aborted || strings.Join(report.Updated, ",") != "w-1" {
+		t.Fatalf("unexpected report %#v", report)
+	}
+	var restored bool
+	for _, action := range report.Actions {
+		restored = restored || (action.Kind == "restore" && action.Name == "w-2" && action.Status == "done")
+	}
+	if !restored {
+		t.Fatalf("w-2 not restored: %#v", report.Actions)
+	}
+	assertCloneOf(t, env, "w-1", v5)
+	assertCloneOf(t, env, "w-2", v4)
+	assertCloneOf(t, env, "w-3", v4)
+	if _, err := os.Stat(filepath.Join(env.AVDHome, "w-2.ini")); err != nil {
+		t.Fatalf("w-2.ini not restored: %v", err)
+	}
+}
+
+func TestRolloutKeepsCloneOptions(t *testing.T) {
+	env, v4, v5 := setupRollout(t, "")
+	tpl := filepath.Join(t.TempDir(), "config.ini.tpl")
+	if err := os.WriteFile(tpl, []byte("hw.ramSize={{default \"2048\" .RAM}}\nhw.cpu.ncore={{default \"2\" .Cores}}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AVDCTL_CONFIG_TEMPLATE", tpl)
+	values := filepath.Join(t.TempDir(), "w-9.values")
+	if err := os.WriteFile(values, []byte("Cores=6\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	links := LinkPolicy{Rules: []LinkRule{{Pattern: "*.ini", Mode: LinkCopy}}}
+	opts := CloneOptions{ConfigVars: map[string]string{"RAM": "8192"}, ConfigValuesFile: values, Links: links}
+	if _, err := CloneFromGoldenWithOptions(env, "base-a35", "w-9", v4, opts); err != nil {
+		t.Fatal(err)
+	}
+	// The values file is read at clone time only.
+	if err := os.Remove(values); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := Rollout(env, RolloutOptions{Golden: v5, Clones: []string{"w-9"}})
+	if err != nil || report.Failed() {
+		t.Fatalf("Rollout = %#v, %v", report, err)
+	}
+	assertCloneOf(t, env, "w-9", v5)
+	cfg, _ := os.ReadFile(filepath.Join(env.AVDHome, "w-9.avd", "config.ini"))
+	if !strings.Contains(string(cfg), "hw.ramSize=8192") || !strings.Contains(string(cfg), "hw.cpu.ncore=6") {
+		t.Fatalf("rolled-out config.ini:\n%s", cfg)
+	}
+	got, err := readCloneOptions(filepath.Join(env.AVDHome, "w-9.avd"))
+	if err != nil || got.Links.Rules[0] != links.Rules[0] || got.ConfigVars["Cores"] != "6" {
+		t.Fatalf("clone options = %+v, %v", got, err)
+	}
+
+	// A clone whose options were never recorded is left as it is.
+	if err := os.Remove(filepath.Join(env.AVDHome, "w-1.avd", cloneOptionsFilename)); err != nil {
+		t.Fatal(err)
+	}
+	report, err = Rollout(env, RolloutOptions{Golden: v5, Clones: []string{"w-1"}})
+	if err != nil || !report.Failed() || len(report.Updated) != 0 {
+		t.Fatalf("Rollout without clone options = %#v, %v", report, err)
+	}
+	assertCloneOf(t, env, "w-1", v4)
+}
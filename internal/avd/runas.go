@@ -0,0 +1,133 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// runAsUser is the account an instance runs as (see Env.RunAsUser).
+type runAsUser struct {
+	Name   string
+	Home   string
+	UID    uint32
+	GID    uint32
+	Groups []uint32
+}
+
+// resolveRunAs resolves Env.RunAsUser for the AVD name; nil when instances run as avdctl's
+// own user. Switching to another user needs avdctl to run as root.
+func resolveRunAs(env Env, name string) (*runAsUser, error) {
+	if env.RunAsUser == "" {
+		return nil, nil
+	}
+	spec := strings.ReplaceAll(env.RunAsUser, "{name}", name)
+	userSpec, groupSpec, _ := strings.Cut(spec, ":")
+	u, err := lookupUser(userSpec)
+	if err != nil {
+		return nil, fmt.Errorf("run as %s: %w", spec, err)
+	}
+	uid, err := strconv.ParseUint(u.Uid, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("run as %s: uid %q: %w", spec, u.Uid, err)
+	}
+	gid, err := strconv.ParseUint(u.Gid, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("run as %s: gid %q: %w", spec, u.Gid, err)
+	}
+	if groupSpec != "" {
+		g, err := lookupGroup(groupSpec)
+		if err != nil {
+			return nil, fmt.Errorf("run as %s: %w", spec, err)
+		}
+		if gid, err = strconv.ParseUint(g.Gid, 10, 32); err != nil {
+			return nil, fmt.Errorf("run as %s: gid %q: %w", spec, g.Gid, err)
+		}
+	}
+	if uid == 0 {
+		return nil, fmt.Errorf("run as %s: refusing to run instances as root", spec)
+	}
+	if euid := os.Geteuid(); euid != 0 && uint64(euid) != uid {
+		return nil, fmt.Errorf("run as %s: avdctl must run as root to switch users", spec)
+	}
+	run := &runAsUser{Name: u.Username, Home: u.HomeDir, UID: uint32(uid), GID: uint32(gid)}
+	// Only the primary group: supplementary groups of the account may open up shared
+	// directories the instance should not see.
+	run.Groups = []uint32{run.GID}
+	return run, nil
+}
+
+func lookupUser(spec string) (*user.User, error) {
+	if _, err := strconv.Atoi(spec); err == nil {
+		return user.LookupId(spec)
+	}
+	return user.Lookup(spec)
+}
+
+func lookupGroup(spec string) (*user.Group, error) {
+	if _, err := strconv.Atoi(spec); err == nil {
+		return user.LookupGroupId(spec)
+	}
+	return user.LookupGroup(spec)
+}
+
+// credential returns the process credential of the account.
+func (r *runAsUser) credential() *syscall.Credential {
+	return &syscall.Credential{Uid: r.UID, Gid: r.GID, Groups: r.Groups}
+}
+
+// setprivPrefix switches to the account from inside a wrapper that must start as root.
+func (r *runAsUser) setprivPrefix() []string {
+	return []string{
+		"setpriv",
+		"--reuid=" + strconv.FormatUint(uint64(r.UID), 10),
+		"--regid=" + strconv.FormatUint(uint64(r.GID), 10),
+		"--clear-groups",
+		"--",
+	}
+}
+
+// processEnv returns the environment entries that point the emulator at the account's home
+// while it still finds the AVDs of env.
+func (r *runAsUser) processEnv(env Env) []string {
+	return []string{
+		"HOME=" + r.Home,
+		"USER=" + r.Name,
+		"LOGNAME=" + r.Name,
+		"ANDROID_AVD_HOME=" + env.AVDHome,
+	}
+}
+
+// handOver makes the account the owner of dirs and everything below them, and closes the
+// dirs themselves to other users. Symlinks are re-owned, not followed, so shared base images
+// keep their owner.
+func (r *runAsUser) handOver(dirs ...string) error {
+	var errs []error
+	for _, dir := range dirs {
+		if !fileExists(dir) {
+			continue
+		}
+		err := filepath.WalkDir(dir, func(path string, _ fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			return os.Lchown(path, int(r.UID), int(r.GID))
+		})
+		if err == nil {
+			err = os.Chmod(dir, 0o700)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("hand %s over to %s: %w", dir, r.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
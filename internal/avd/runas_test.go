@@ -0,0 +1,64 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestResolveRunAs(t *testing.T) {
+	env := newTestEnv(t)
+	if run, err := resolveRunAs(env, "w-1"); err != nil || run != nil {
+		t.Fatalf("unset RunAsUser = %v, %v", run, err)
+	}
+
+	env.RunAsUser = "avdctl-missing-{name}"
+	if _, err := resolveRunAs(env, "w-1"); err == nil || !strings.Contains(err.Error(), "avdctl-missing-w-1") {
+		t.Fatalf("expected an error naming the expanded user, got %v", err)
+	}
+
+	env.RunAsUser = "0"
+	if _, err := resolveRunAs(env, "w-1"); err == nil {
+		t.Fatal("expected running instances as root to be refused")
+	}
+
+	me, err := user.Current()
+	if err != nil || me.Uid == "0" {
+		t.Skip("needs an unprivileged current user")
+	}
+	env.RunAsUser = me.Username
+	run, err := resolveRunAs(env, "w-1")
+	if err != nil {
+		t.Fatalf("resolveRunAs(self): %v", err)
+	}
+	if strconv.FormatUint(uint64(run.UID), 10) != me.Uid || run.Home != me.HomeDir {
+		t.Fatalf("run as = %+v, want %s", run, me.Uid)
+	}
+}
+
+func TestRunAsHandOverClosesCloneDir(t *testing.T) {
+	env := newTestEnv(t)
+	makeBaseAVD(t, env, "w-1")
+	dir := filepath.Join(env.AVDHome, "w-1.avd")
+	run := &runAsUser{Name: "self", UID: uint32(os.Getuid()), GID: uint32(os.Getgid())}
+	if err := run.handOver(dir, filepath.Join(env.AVDHome, "missing")); err != nil {
+		t.Fatalf("handOver: %v", err)
+	}
+	st, err := os.Stat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Mode().Perm() != 0o700 {
+		t.Fatalf("clone dir mode = %v, want 0700", st.Mode().Perm())
+	}
+	got := run.processEnv(env)
+	if got[len(got)-1] != "ANDROID_AVD_HOME="+env.AVDHome {
+		t.Fatalf("processEnv = %v", got)
+	}
+}
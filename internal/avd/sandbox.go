@@ -0,0 +1,110 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+)
+
+// Sandbox tools StartEmulatorWithArgs can wrap the emulator in.
+const (
+	SandboxBwrap  = "bwrap"  // bubblewrap
+	SandboxNsjail = "nsjail" // nsjail
+)
+
+// Sandbox confines an emulator launch: the host filesystem is mounted read-only (SDK included),
+// /tmp is private, and only the clone directory, its ephemeral run directory and Writable are
+// writable. The zero value runs the emulator unconfined.
+type Sandbox struct {
+	// Tool is SandboxBwrap or SandboxNsjail, looked up in PATH; empty disables the sandbox.
+	Tool string `json:"tool,omitempty"`
+	// IsolateNetwork runs the emulator in its own network namespace, set up by pasta (passt),
+	// into which only the console and adb ports of the instance are forwarded. The guest keeps
+	// outbound connectivity.
+	IsolateNetwork bool `json:"isolateNetwork,omitempty"`
+	// Writable are more host paths the emulator may write to, e.g. a shared capture directory.
+	Writable []string `json:"writable,omitempty"`
+}
+
+// Enabled reports whether s confines launches.
+func (s Sandbox) Enabled() bool { return s.Tool != "" }
+
+// Validate checks the tool name and that Writable paths are absolute.
+func (s Sandbox) Validate() error {
+	switch s.Tool {
+	case "", SandboxBwrap, SandboxNsjail:
+	default:
+		return fmt.Errorf("sandbox tool %q: must be %s or %s", s.Tool, SandboxBwrap, SandboxNsjail)
+	}
+	if !s.Enabled() && (s.IsolateNetwork || len(s.Writable) > 0) {
+		return fmt.Errorf("sandbox options need a sandbox tool")
+	}
+	for _, p := range s.Writable {
+		if !filepath.IsAbs(p) {
+			return fmt.Errorf("sandbox writable path %q must be absolute", p)
+		}
+	}
+	return nil
+}
+
+// wrap returns the argv that runs program with args inside the sandbox, for the instance on
+// console port whose writable state lives in dirs.
+func (s Sandbox) wrap(port int, dirs []string, program string, args []string) ([]string, error) {
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	tool, err := exec.LookPath(s.Tool)
+	if err != nil {
+		return nil, fmt.Errorf("sandbox: %w", err)
+	}
+	var writable []string
+	for _, dir := range append(dirs, s.Writable...) {
+		if fileExists(dir) {
+			writable = append(writable, dir)
+		}
+	}
+	var argv []string
+	if s.IsolateNetwork {
+		pasta, err := exec.LookPath("pasta")
+		if err != nil {
+			return nil, fmt.Errorf("sandbox network isolation: %w", err)
+		}
+		ports := strconv.Itoa(port) + "," + strconv.Itoa(port+1)
+		argv = append(argv, pasta, "--quiet", "-t", ports, "-u", "none", "-T", "none", "-U", "none", "--")
+	}
+	argv = append(argv, tool)
+	switch s.Tool {
+	case SandboxBwrap:
+		argv = append(argv,
+			"--ro-bind", "/", "/",
+			"--dev-bind", "/dev", "/dev",
+			"--proc", "/proc",
+			"--tmpfs", "/tmp",
+			"--unshare-pid", "--unshare-ipc", "--unshare-uts",
+		)
+		for _, dir := range writable {
+			argv = append(argv, "--bind", dir, dir)
+		}
+		argv = append(argv, "--")
+	case SandboxNsjail:
+		// nsjail kills its child after 600s and clamps rlimits unless told otherwise. Its own
+		// network namespace would be empty, so the one of pasta (or the host's) is kept, which
+		// adb must reach.
+		argv = append(argv,
+			"--mode", "o", "--quiet", "--time_limit", "0", "--disable_rlimits", "--keep_env",
+			"--disable_clone_newnet", "--chroot", "/", "--tmpfsmount", "/tmp",
+		)
+		if fileExists("/dev/kvm") {
+			argv = append(argv, "--bindmount", "/dev/kvm")
+		}
+		for _, dir := range writable {
+			argv = append(argv, "--bindmount", dir)
+		}
+		argv = append(argv, "--")
+	}
+	return append(append(argv, program), args...), nil
+}
@@ -0,0 +1,82 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func fakeSandboxTools(t *testing.T, names ...string) string {
+	t.Helper()
+	bin := t.TempDir()
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(bin, name), []byte("#!/bin/sh\nexit 0\n"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", bin)
+	return bin
+}
+
+func TestSandboxWrapBwrap(t *testing.T) {
+	bin := fakeSandboxTools(t, "bwrap", "pasta")
+	clone := t.TempDir()
+	sb := Sandbox{Tool: SandboxBwrap, IsolateNetwork: true, Writable: []string{bin}}
+	argv, err := sb.wrap(5580, []string{clone, filepath.Join(clone, "missing")}, "/sdk/qemu", []string{"-avd", "w-1"})
+	if err != nil {
+		t.Fatalf("wrap: %v", err)
+	}
+	got := strings.Join(argv, " ")
+	for _, want := range []string{
+		filepath.Join(bin, "pasta") + " --quiet -t 5580,5581 -u none -T none -U none -- " + filepath.Join(bin, "bwrap"),
+		"--ro-bind / /",
+		"--bind " + clone + " " + clone,
+		"--bind " + bin + " " + bin + " -- /sdk/qemu -avd w-1",
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("argv %q lacks %q", got, want)
+		}
+	}
+	if strings.Contains(got, "missing") {
+		t.Fatalf("argv binds a missing directory: %q", got)
+	}
+}
+
+func TestSandboxWrapNsjail(t *testing.T) {
+	bin := fakeSandboxTools(t, "nsjail")
+	argv, err := Sandbox{Tool: SandboxNsjail}.wrap(5554, []string{bin}, "/sdk/qemu", nil)
+	if err != nil {
+		t.Fatalf("wrap: %v", err)
+	}
+	if argv[0] != filepath.Join(bin, "nsjail") || argv[len(argv)-1] != "/sdk/qemu" {
+		t.Fatalf("argv = %v", argv)
+	}
+	for _, want := range []string{"--disable_clone_newnet", "--time_limit", bin} {
+		if !slices.Contains(argv, want) {
+			t.Fatalf("argv %v lacks %q", argv, want)
+		}
+	}
+	if _, err := (Sandbox{Tool: SandboxNsjail, IsolateNetwork: true}).wrap(5554, nil, "/sdk/qemu", nil); err == nil {
+		t.Fatal("expected an error without pasta in PATH")
+	}
+}
+
+func TestSandboxValidate(t *testing.T) {
+	for _, bad := range []Sandbox{
+		{Tool: "firejail"},
+		{IsolateNetwork: true},
+		{Tool: SandboxBwrap, Writable: []string{"relative/dir"}},
+	} {
+		if err := bad.Validate(); err == nil {
+			t.Fatalf("expected an error for %+v", bad)
+		}
+	}
+	if err := (Sandbox{}).Validate(); err != nil {
+		t.Fatalf("zero sandbox: %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// parseSDKList parses AVDCTL_SDKS ("stable=/opt/sdk-34,canary=/opt/sdk-35").
+func parseSDKList(s string) map[string]string {
+	sdks := map[string]string{}
+	for _, entry := range strings.Split(s, ",") {
+		name, root, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || strings.TrimSpace(name) == "" || strings.TrimSpace(root) == "" {
+			continue
+		}
+		sdks[strings.TrimSpace(name)] = expandHome(strings.TrimSpace(root))
+	}
+	return sdks
+}
+
+// SDKNames returns the configured SDK names, sorted.
+func (env Env) SDKNames() []string {
+	names := make([]string, 0, len(env.SDKs))
+	for name := range env.SDKs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// WithSDK returns a copy of env that runs tools from the named SDK root (see Env.SDKs).
+// An empty name returns env unchanged. The emulator must exist in the SDK; adb, avdmanager
+// and sdkmanager are switched only when the SDK ships them.
+func (env Env) WithSDK(name string) (Env, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return env, nil
+	}
+	root, ok := env.SDKs[name]
+	if !ok {
+		return env, fmt.Errorf("unknown SDK %q (configured: %s)", name, strings.Join(env.SDKNames(), ", "))
+	}
+	emulator := filepath.Join(root, "emulator", "emulator")
+	if _, err := os.Stat(emulator); err != nil {
+		return env, fmt.Errorf("SDK %s: emulator not found: %w", name, err)
+	}
+	env.SDKRoot = root
+	env.Emulator = emulator
+	if adb := filepath.Join(root, "platform-tools", "adb"); fileExists(adb) {
+		env.ADB = adb
+	}
+	if avdMgr := filepath.Join(root, "cmdline-tools", "latest", "bin", "avdmanager"); fileExists(avdMgr) {
+		env.AvdMgr = avdMgr
+	}
+	if sdkMgr := filepath.Join(root, "cmdline-tools", "latest", "bin", "sdkmanager"); fileExists(sdkMgr) {
+		env.SdkManager = sdkMgr
+	}
+	return env, nil
+}
+
+// sdkProcessEnv pins ANDROID_SDK_ROOT/ANDROID_HOME for child processes, so an emulator from
+// one SDK does not pick up system images or tools from another.
+func sdkProcessEnv(env Env) []string {
+	if env.SDKRoot == "" {
+		return nil
+	}
+	return []string{"ANDROID_SDK_ROOT=" + env.SDKRoot, "ANDROID_HOME=" + env.SDKRoot}
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,77 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func makeFakeSDK(t *testing.T, withADB bool) string {
+	t.Helper()
+	root := t.TempDir()
+	tools := []string{filepath.Join("emulator", "emulator")}
+	if withADB {
+		tools = append(tools, filepath.Join("platform-tools", "adb"))
+	}
+	for _, tool := range tools {
+		path := filepath.Join(root, tool)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("mkdir %s: %v", tool, err)
+		}
+		if err := os.WriteFile(path, []byte("#!/bin/sh\nexit 0\n"), 0o755); err != nil {
+			t.Fatalf("write %s: %v", tool, err)
+		}
+	}
+	return root
+}
+
+func TestParseSDKList(t *testing.T) {
+	sdks := parseSDKList(" stable=/opt/sdk-34 , canary=/opt/sdk-35,broken, =/x")
+	if len(sdks) != 2 || sdks["stable"] != "/opt/sdk-34" || sdks["canary"] != "/opt/sdk-35" {
+		t.Fatalf("unexpected SDKs: %#v", sdks)
+	}
+}
+
+func TestEnvWithSDK(t *testing.T) {
+	stable := makeFakeSDK(t, true)
+	canary := makeFakeSDK(t, false)
+	env := newTestEnv(t)
+	env.Emulator = "emulator"
+	env.SDKs = map[string]string{"stable": stable, "canary": canary}
+
+	same, err := env.WithSDK("")
+	if err != nil || same.Emulator != "emulator" {
+		t.Fatalf("empty SDK must keep env: %v %q", err, same.Emulator)
+	}
+
+	got, err := env.WithSDK("stable")
+	if err != nil {
+		t.Fatalf("WithSDK(stable): %v", err)
+	}
+	if got.SDKRoot != stable || got.Emulator != filepath.Join(stable, "emulator", "emulator") ||
+		got.ADB != filepath.Join(stable, "platform-tools", "adb") {
+		t.Fatalf("unexpected stable env: %+v", got)
+	}
+	if procEnv := strings.Join(sdkProcessEnv(got), " "); !strings.Contains(procEnv, "ANDROID_SDK_ROOT="+stable) {
+		t.Fatalf("process env missing SDK root: %s", procEnv)
+	}
+
+	got, err = env.WithSDK("canary")
+	if err != nil {
+		t.Fatalf("WithSDK(canary): %v", err)
+	}
+	if got.ADB != env.ADB {
+		t.Fatalf("adb should stay unchanged when the SDK lacks platform-tools: %q", got.ADB)
+	}
+	if env.Emulator != "emulator" {
+		t.Fatal("WithSDK must not modify the receiver")
+	}
+
+	if _, err := env.WithSDK("beta"); err == nil || !strings.Contains(err.Error(), "canary, stable") {
+		t.Fatalf("expected unknown SDK error listing names, got %v", err)
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// InstallProgressFunc reports sdkmanager download/install progress for a package (0-100).
+type InstallProgressFunc func(pkg string, percent int)
+
+// sdkInstallLockPath is shared by every avdctl process installing into the same SDK.
+func sdkInstallLockPath(env Env) string {
+	if env.SDKRoot != "" {
+		return filepath.Join(env.SDKRoot, ".avdctl-sdkmanager.lock")
+	}
+	return filepath.Join(env.AVDHome, ".avdctl", "sdkmanager.lock")
+}
+
+// sysImgInstalled probes the SDK for an installed system image package.
+func sysImgInstalled(env Env, pkg string) bool {
+	if env.SDKRoot == "" {
+		return false
+	}
+	parts := strings.Split(pkg, ";")
+	if len(parts) < 3 {
+		return false
+	}
+	abi := "x86_64"
+	if len(parts) >= 4 {
+		abi = parts[3]
+	}
+	_, err := os.Stat(filepath.Join(env.SDKRoot, "system-images", parts[1], parts[2], abi))
+	return err == nil
+}
+
+// installSysImg runs sdkmanager for pkg under the SDK install lock, so concurrent InitBase
+// calls on a fresh host run a single installer while the others wait and then reuse its result.
+func installSysImg(env Env, pkg string, progress InstallProgressFunc) error {
+	_, span := startSpan(env, "avd.installSysImg", attribute.String("package", pkg))
+	defer span.End()
+
+	lockPath := sdkInstallLockPath(env)
+	unlock, err := acquireFileLock(env.Context, lockPath, func() {
+		logEvent(env, "waiting for sdkmanager lock", "package", pkg, "lock", lockPath)
+	})
+	if err != nil {
+		recordSpanError(span, err)
+		return err
+	}
+	defer unlock()
+
+	// Another process may have installed it while we waited.
+	if sysImgInstalled(env, pkg) {
+		span.SetAttributes(attribute.Bool("installed_by_peer", true))
+		return nil
+	}
+
+	proxyArgs, extraEnv, err := sdkManagerProxy(env)
+	if err != nil {
+		recordSpanError(span, err)
+		return err
+	}
+	if env.SDKCacheDir != "" {
+		if err := os.MkdirAll(env.SDKCacheDir, 0o755); err != nil {
+			recordSpanError(span, err)
+			return err
+		}
+		// sdkmanager keeps its download cache under $ANDROID_USER_HOME/cache.
+		extraEnv = append(extraEnv, "ANDROID_USER_HOME="+env.SDKCacheDir)
+	}
+	logEvent(env, "sdkmanager install start", "package", pkg, "proxy", redactProxy(env.Proxy))
+	// accept licenses if needed
+	_ = runCommandWithEnv(env.Context, extraEnv, nil, nil, nil, env.SdkManager, append([]string{"--licenses"}, proxyArgs...)...)
+	var stderr bytes.Buffer
+	stdout := &sdkProgressWriter{pkg: pkg, progress: progress, env: env}
+	if err := runCommandWithEnv(env.Context, extraEnv, nil, stdout, &stderr, env.SdkManager, append([]string{pkg}, proxyArgs...)...); err != nil {
+		recordSpanError(span, err)
+		return fmt.Errorf("sdkmanager install %s: %w\n%s", pkg, err, strings.TrimSpace(stderr.String()))
+	}
+	if progress != nil && stdout.last < 100 {
+		progress(pkg, 100)
+	}
+	logEvent(env, "sdkmanager install finished", "package", pkg)
+	return nil
+}
+
+var sdkProgressRE = regexp.MustCompile(`(\d{1,3})%`)
+
+// sdkProgressWriter parses sdkmanager's carriage-return progress bar ("[===   ] 42% Downloading ...").
+type sdkProgressWriter struct {
+	mu       sync.Mutex
+	pkg      string
+	progress InstallProgressFunc
+	env      Env
+	buf      []byte
+	last     int
+}
+
+func (w *sdkProgressWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexAny(w.buf, "\r\n")
+		if i < 0 {
+			break
+		}
+		w.line(string(w.buf[:i]))
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+func (w *sdkProgressWriter) line(line string) {
+	m := sdkProgressRE.FindStringSubmatch(line)
+	if m == nil {
+		return
+	}
+	percent, _ := strconv.Atoi(m[1])
+	if percent <= w.last || percent > 100 {
+		return
+	}
+	// Log every 10% so a stuck download is visible without flooding the log.
+	if percent/10 > w.last/10 {
+		logEvent(w.env, "sdkmanager progress", "package", w.pkg, "percent", percent)
+	}
+	w.last = percent
+	if w.progress != nil {
+		w.progress(w.pkg, percent)
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAcquireFileLockWaitsForHolder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.lock")
+	unlock, err := acquireFileLock(context.Background(), path, nil)
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	waited := false
+	if _, err := acquireFileLock(ctx, path, func() { waited = true }); err == nil {
+		t.Fatal("expected timeout while lock is held")
+	}
+	if !waited {
+		t.Fatal("expected onWait callback")
+	}
+
+	unlock()
+	unlock2, err := acquireFileLock(context.Background(), path, nil)
+	if err != nil {
+		t.Fatalf("acquire after release: %v", err)
+	}
+	unlock2()
+}
+
+func TestAcquireFileLockUsesLockfileWithoutLocking(t *testing.T) {
+	orig := detectStorageFn
+	detectStorageFn = func(path string) (StorageInfo, error) {
+		return StorageInfo{Path: path, FSType: "nfs", Shared: true}, nil
+	}
+	t.Cleanup(func() { detectStorageFn = orig })
+
+	path := filepath.Join(t.TempDir(), "test.lock")
+	unlock, err := acquireFileLock(context.Background(), path, nil)
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	if !fileExists(path + ".pid") {
+		t.Fatal("expected lockfile while the lock is held")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	if _, err := acquireFileLock(ctx, path, nil); err == nil {
+		t.Fatal("expected timeout while lock is held")
+	}
+	unlock()
+	if fileExists(path + ".pid") {
+		t.Fatal("expected lockfile removed on release")
+	}
+
+	// A lockfile of an exited process of this host is stale; one of another host is not.
+	cmd := exec.Command("true")
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("run true: %v", err)
+	}
+	host, _ := os.Hostname()
+	if err := os.WriteFile(path+".pid", []byte(fmt.Sprintf("%s %d\n", host, cmd.Process.Pid)), 0o644); err != nil {
+		t.Fatalf("write stale lockfile: %v", err)
+	}
+	unlock, err = acquireFileLock(context.Background(), path, nil)
+	if err != nil {
+		t.Fatalf("acquire over stale lockfile: %v", err)
+	}
+	unlock()
+	if err := os.WriteFile(path+".pid", []byte(fmt.Sprintf("other-%s %d\n", host, cmd.Process.Pid)), 0o644); err != nil {
+		t.Fatalf("write foreign lockfile: %v", err)
+	}
+	ctx2, cancel2 := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel2()
+	if _, err := acquireFileLock(ctx2, path, nil); err == nil {
+		t.Fatal("expected another host's lockfile to be honored")
+	}
+}
+
+func TestEnsureSysImgRunsSingleInstaller(t *testing.T) {
+	env := newTestEnv(t)
+	env.SDKRoot = t.TempDir()
+	env.SDKCacheDir = filepath.Join(t.TempDir(), "cache")
+	calls := filepath.Join(t.TempDir(), "calls.log")
+	env.SdkManager = filepath.Join(t.TempDir(), "sdkmanager")
+	script := `#!/bin/sh
+[ "$1" = "--licenses" ] && exit 0
+echo "install $ANDROID_USER_HOME" >> ` + calls + `
+printf '[===       ] 30%% Downloading\r[==========] 100%% Unzipping\n'
+sleep 0.3
+mkdir -p "` + env.SDKRoot + `/system-images/android-35/google_apis/x86_64"
+`
+	if err := os.WriteFile(env.SdkManager, []byte(script), 0o755); err != nil {
+		t.Fatalf("write sdkmanager stub: %v", err)
+	}
+
+	pkg := "system-images;android-35;google_apis;x86_64"
+	var mu sync.Mutex
+	var seen []int
+	var wg sync.WaitGroup
+	errs := make(chan error, 3)
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- ensureSysImg(env, pkg, func(_ string, percent int) {
+				mu.Lock()
+				seen = append(seen, percent)
+				mu.Unlock()
+			})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("ensureSysImg: %v", err)
+		}
+	}
+
+	log, err := os.ReadFile(calls)
+	if err != nil {
+		t.Fatalf("read calls: %v", err)
+	}
+	if n := strings.Count(string(log), "install"); n != 1 {
+		t.Fatalf("expected one sdkmanager install, got %d:\n%s", n, log)
+	}
+	if !strings.Contains(string(log), env.SDKCacheDir) {
+		t.Fatalf("expected shared cache dir passed to sdkmanager:\n%s", log)
+	}
+	if len(seen) < 2 || seen[0] != 30 || seen[len(seen)-1] != 100 {
+		t.Fatalf("unexpected progress: %v", seen)
+	}
+}
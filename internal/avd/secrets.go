@@ -0,0 +1,200 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// secretsMarkerFilename is written in the AVD directory of a clone that received secrets at
+// runtime; SaveGolden refuses to export it.
+const secretsMarkerFilename = "avdctl-secrets.json"
+
+// ErrSecretsProvisioned is returned by SaveGolden for an AVD whose userdata received secrets
+// (see MarkSecretsProvisioned). Export from a clone that never held customer secrets instead.
+var ErrSecretsProvisioned = errors.New("AVD received secrets at runtime and cannot be exported as a golden")
+
+// SecretFile is a file written into a running instance. With Package, Path is relative to the
+// app's data directory (/data/data/<package>) and the file is given to the app; otherwise Path
+// is absolute on the guest.
+type SecretFile struct {
+	Path    string
+	Data    []byte
+	Mode    os.FileMode // default 0600
+	Package string
+}
+
+// SecretBroadcast hands values to an app in the extras of a string-extra broadcast.
+type SecretBroadcast struct {
+	Action    string
+	Component string // explicit receiver, e.g. com.example/.ConfigReceiver
+	Package   string
+	Extras    map[string]string
+}
+
+// MarkSecretsProvisioned records that AVD name holds secrets in its userdata.
+func MarkSecretsProvisioned(env Env, name, serial string) error {
+	b, err := json.MarshalIndent(struct {
+		Serial string    `json:"serial"`
+		Time   time.Time `json:"time"`
+	}{serial, time.Now().UTC()}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(env.AVDHome, name+".avd", secretsMarkerFilename), b, 0o644)
+}
+
+// SecretsProvisioned reports whether MarkSecretsProvisioned was called for AVD name.
+func SecretsProvisioned(env Env, name string) bool {
+	_, err := os.Stat(filepath.Join(env.AVDHome, name+".avd", secretsMarkerFilename))
+	return err == nil
+}
+
+// WriteSecretFile streams f into the instance on serial. The data goes from memory through adb's
+// stdin: it is never written to a host file or passed on a command line. App files are written
+// as root and handed to the app where adbd can run as root, else through run-as (debuggable apps).
+func WriteSecretFile(env Env, serial string, f SecretFile) error {
+	_, span := startSpan(env, "avd.WriteSecretFile", attribute.String("serial", serial), attribute.String("package", f.Package))
+	defer span.End()
+	fail := func(err error) error {
+		recordSpanError(span, err)
+		return err
+	}
+	clean := path.Clean("/" + f.Path)
+	switch {
+	case f.Path == "" || strings.HasSuffix(f.Path, "/"):
+		return fail(fmt.Errorf("secret file path %q is not a file", f.Path))
+	case f.Package == "" && !path.IsAbs(f.Path):
+		return fail(fmt.Errorf("secret file path %q must be absolute without a package", f.Path))
+	case f.Package != "" && (path.IsAbs(f.Path) || clean != "/"+f.Path):
+		return fail(fmt.Errorf("secret file path %q must stay inside the data directory of %s", f.Path, f.Package))
+	case f.Package != "" && !packageRe.MatchString(f.Package):
+		return fail(fmt.Errorf("invalid package %q", f.Package))
+	}
+	mode := f.Mode
+	if mode == 0 {
+		mode = 0o600
+	}
+	dir, name := path.Split(clean)
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	if err := tw.WriteHeader(&tar.Header{Name: name, Mode: int64(mode.Perm()), Size: int64(len(f.Data)), ModTime: time.Now()}); err != nil {
+		return fail(err)
+	}
+	if _, err := tw.Write(f.Data); err != nil {
+		return fail(err)
+	}
+	if err := tw.Close(); err != nil {
+		return fail(err)
+	}
+
+	var args []string
+	how := "shell"
+	switch {
+	case f.Package != "" && adbRoot(env, serial) == nil:
+		app := "/data/data/" + f.Package
+		target := path.Join(app, dir)
+		script := fmt.Sprintf("mkdir -p %[1]s && tar -xf - -C %[1]s && chown -R $(stat -c %%u:%%g %[2]s) %[1]s && (restorecon -R %[1]s || true) && sync", target, app)
+		args = []string{"-s", serial, "exec-in", "sh -c '" + script + "'"}
+		how = "root"
+	case f.Package != "":
+		target := path.Join("/data/data/"+f.Package, dir)
+		args = []string{"-s", serial, "exec-in", "run-as", f.Package, "sh", "-c", "'mkdir -p " + target + " && tar -xf - -C " + target + "'"}
+		how = "run-as"
+	default:
+		args = []string{"-s", serial, "exec-in", "sh -c 'mkdir -p " + dir + " && tar -xf - -C " + dir + " && sync'"}
+	}
+	out, errOut, err := runCommandOutputWithEnv(env.Context, nil, &buf, env.ADB, args...)
+	if err != nil {
+		return fail(fmt.Errorf("write secret file %s on %s: %w: %s", f.Path, serial, err, strings.TrimSpace(out+errOut)))
+	}
+	logEvent(env, "secret file written", "serial", serial, "package", f.Package, "path", f.Path, "bytes", len(f.Data), "mode", how)
+	return nil
+}
+
+// InsertSecretContent inserts a row of string values into the content provider at uri, as
+// `content insert --bind key:s:value`.
+func InsertSecretContent(env Env, serial, uri string, values map[string]string) error {
+	_, span := startSpan(env, "avd.InsertSecretContent", attribute.String("serial", serial), attribute.String("uri", uri))
+	defer span.End()
+	if !strings.HasPrefix(uri, "content://") || len(values) == 0 {
+		err := fmt.Errorf("content insert needs a content:// uri and values, got %q", uri)
+		recordSpanError(span, err)
+		return err
+	}
+	cmd := "content insert --uri " + shellQuote(uri)
+	for _, key := range slices.Sorted(maps.Keys(values)) {
+		cmd += " --bind " + shellQuote(key+":s:"+values[key])
+	}
+	if err := runSecretScript(env, serial, cmd); err != nil {
+		err = fmt.Errorf("insert into %s on %s: %w", uri, serial, err)
+		recordSpanError(span, err)
+		return err
+	}
+	logEvent(env, "secret content inserted", "serial", serial, "uri", uri, "keys", strings.Join(slices.Sorted(maps.Keys(values)), ","))
+	return nil
+}
+
+// SendSecretBroadcast sends b with its extras as --es string extras.
+func SendSecretBroadcast(env Env, serial string, b SecretBroadcast) error {
+	_, span := startSpan(env, "avd.SendSecretBroadcast", attribute.String("serial", serial), attribute.String("action", b.Action))
+	defer span.End()
+	if strings.TrimSpace(b.Action) == "" {
+		err := errors.New("secret broadcast needs an action")
+		recordSpanError(span, err)
+		return err
+	}
+	cmd := "am broadcast -a " + shellQuote(b.Action)
+	if b.Component != "" {
+		cmd += " -n " + shellQuote(b.Component)
+	}
+	if b.Package != "" {
+		cmd += " -p " + shellQuote(b.Package)
+	}
+	for _, key := range slices.Sorted(maps.Keys(b.Extras)) {
+		cmd += " --es " + shellQuote(key) + " " + shellQuote(b.Extras[key])
+	}
+	if err := runSecretScript(env, serial, cmd); err != nil {
+		err = fmt.Errorf("broadcast %s on %s: %w", b.Action, serial, err)
+		recordSpanError(span, err)
+		return err
+	}
+	logEvent(env, "secret broadcast sent", "serial", serial, "action", b.Action, "keys", strings.Join(slices.Sorted(maps.Keys(b.Extras)), ","))
+	return nil
+}
+
+// secretExitMarker precedes the exit status runSecretScript reads back: exec-in does not
+// report the status of the guest command.
+const secretExitMarker = "avdctl-exit="
+
+// runSecretScript feeds cmd to a guest shell on stdin, so the values in it stay off the host's
+// process list. A failing cmd is reported by exit status only: its output may echo the values.
+func runSecretScript(env Env, serial, cmd string) error {
+	script := cmd + "\necho " + secretExitMarker + "$?\n"
+	out, _, err := runCommandOutputWithEnv(env.Context, nil, strings.NewReader(script), env.ADB, "-s", serial, "exec-in", "sh")
+	if err != nil {
+		return err
+	}
+	i := strings.LastIndex(out, secretExitMarker)
+	if i < 0 {
+		return errors.New("guest shell reported no exit status")
+	}
+	if status := strings.TrimSpace(out[i+len(secretExitMarker):]); status != "0" {
+		return fmt.Errorf("exit status %s", status)
+	}
+	return nil
+}
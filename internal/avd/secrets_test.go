@@ -0,0 +1,131 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"archive/tar"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// secretsADB installs an adb stub that logs its arguments and saves what exec-in receives on
+// stdin, answering guest scripts with exit status status. adbd runs as root.
+func secretsADB(t *testing.T, env Env, status string) (logPath, stdinPath string) {
+	t.Helper()
+	dir := t.TempDir()
+	logPath, stdinPath = filepath.Join(dir, "adb.log"), filepath.Join(dir, "stdin")
+	script := `#!/bin/sh
+echo "$@" >> ` + logPath + `
+case "$3" in
+  shell) echo 0 ;;
+  exec-in) cat > ` + stdinPath + `; [ "$4" = sh ] && echo "avdctl-exit=` + status + `" ;;
+esac
+exit 0
+`
+	if err := os.WriteFile(env.ADB, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return logPath, stdinPath
+}
+
+func TestWriteSecretFile(t *testing.T) {
+	env := newTestEnv(t)
+	logPath, stdinPath := secretsADB(t, env, "0")
+	f := SecretFile{Package: "com.acme.wallet", Path: "shared_prefs/token.xml", Data: []byte("<token>s3cr3t</token>")}
+	if err := WriteSecretFile(env, "emulator-5594", f); err != nil {
+		t.Fatalf("WriteSecretFile: %v", err)
+	}
+	calls, _ := os.ReadFile(logPath)
+	if !strings.Contains(string(calls), "exec-in sh -c 'mkdir -p /data/data/com.acme.wallet/shared_prefs && tar -xf - -C /data/data/com.acme.wallet/shared_prefs && chown -R $(stat -c %u:%g /data/data/com.acme.wallet)") {
+		t.Fatalf("unexpected adb calls:\n%s", calls)
+	}
+	if strings.Contains(string(calls), "s3cr3t") {
+		t.Fatalf("secret leaked into adb arguments:\n%s", calls)
+	}
+	stdin, err := os.Open(stdinPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdin.Close()
+	tr := tar.NewReader(stdin)
+	hdr, err := tr.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(tr)
+	if hdr.Name != "token.xml" || hdr.Mode != 0o600 || string(data) != string(f.Data) {
+		t.Fatalf("tar entry %s (%o) = %q", hdr.Name, hdr.Mode, data)
+	}
+
+	for _, bad := range []SecretFile{
+		{Package: "com.acme.wallet", Path: "../com.evil/x"},
+		{Package: "com.acme.wallet", Path: "/data/local/tmp/x"},
+		{Path: "relative.txt"},
+		{Path: "/data/local/tmp/"},
+	} {
+		if err := WriteSecretFile(env, "emulator-5594", bad); err == nil {
+			t.Fatalf("expected %+v to be refused", bad)
+		}
+	}
+}
+
+func TestSecretContentAndBroadcast(t *testing.T) {
+	env := newTestEnv(t)
+	logPath, stdinPath := secretsADB(t, env, "0")
+	if err := InsertSecretContent(env, "emulator-5594", "content://com.acme.wallet.config/creds", map[string]string{"token": "s3cr3t value", "user": "ann"}); err != nil {
+		t.Fatalf("InsertSecretContent: %v", err)
+	}
+	script, _ := os.ReadFile(stdinPath)
+	if want := "content insert --uri content://com.acme.wallet.config/creds --bind 'token:s:s3cr3t value' --bind user:s:ann\n"; !strings.HasPrefix(string(script), want) {
+		t.Fatalf("script = %q, want prefix %q", script, want)
+	}
+	if err := SendSecretBroadcast(env, "emulator-5594", SecretBroadcast{Action: "com.acme.CONFIGURE", Package: "com.acme.wallet", Extras: map[string]string{"api_key": "k-1"}}); err != nil {
+		t.Fatalf("SendSecretBroadcast: %v", err)
+	}
+	script, _ = os.ReadFile(stdinPath)
+	if want := "am broadcast -a com.acme.CONFIGURE -p com.acme.wallet --es api_key k-1\n"; !strings.HasPrefix(string(script), want) {
+		t.Fatalf("script = %q, want prefix %q", script, want)
+	}
+	if calls, _ := os.ReadFile(logPath); strings.Contains(string(calls), "s3cr3t") || strings.Contains(string(calls), "k-1") {
+		t.Fatalf("secret leaked into adb arguments:\n%s", calls)
+	}
+
+	secretsADB(t, env, "1")
+	err := SendSecretBroadcast(env, "emulator-5594", SecretBroadcast{Action: "com.acme.CONFIGURE", Extras: map[string]string{"api_key": "k-1"}})
+	if err == nil || strings.Contains(err.Error(), "k-1") {
+		t.Fatalf("expected a failure that does not echo the secret, got %v", err)
+	}
+}
+
+func TestSaveGoldenRefusesProvisionedClone(t *testing.T) {
+	env := newTestEnv(t)
+	makeBaseAVD(t, env, "w-acme")
+	if SecretsProvisioned(env, "w-acme") {
+		t.Fatal("fresh AVD reported as provisioned")
+	}
+	if err := MarkSecretsProvisioned(env, "w-acme", "emulator-5594"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := SaveGolden(env, "w-acme", filepath.Join(t.TempDir(), "golden")); !errors.Is(err, ErrSecretsProvisioned) {
+		t.Fatalf("SaveGolden = %v, want ErrSecretsProvisioned", err)
+	}
+}
+
+func TestCloneOfProvisionedBaseHoldsNoSecrets(t *testing.T) {
+	env := newTestEnv(t)
+	makeBaseAVD(t, env, "base")
+	if err := MarkSecretsProvisioned(env, "base", "emulator-5594"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CloneFromGolden(env, "base", "w-1", makeGoldenDir(t)); err != nil {
+		t.Fatalf("CloneFromGolden: %v", err)
+	}
+	if SecretsProvisioned(env, "w-1") {
+		t.Fatal("clone inherited the base's secrets marker")
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Subdirectories of a self-contained golden: the base AVD's read-only files (with its
+// config.ini) and the system image it boots, so clones need neither on the target host.
+const (
+	goldenBaseDirname   = "base"
+	goldenSystemDirname = "system"
+)
+
+// isWritableAVDArtifact reports whether rel (relative to an .avd directory) is per-instance
+// state rather than a read-only artifact a clone can share with its base.
+func isWritableAVDArtifact(rel string) bool {
+	return strings.HasPrefix(rel, "snapshots") ||
+		strings.HasPrefix(rel, "cache") ||
+		strings.HasPrefix(rel, "userdata") ||
+		strings.HasPrefix(rel, "encryptionkey") ||
+		strings.HasPrefix(rel, "sdcard") ||
+		rel == cloneFingerprintFilename ||
+		rel == cloneIdentityFilename ||
+		rel == customizationsFilename ||
+		rel == customizeSessionFilename ||
+		rel == imageHashesFilename ||
+		rel == lastBootFilename ||
+		rel == pinFilename ||
+		rel == layoutFilename ||
+		rel == pausedFilename ||
+		rel == hibernatedFilename ||
+		rel == instanceFilename ||
+		rel == hardwareConfigFilename ||
+		strings.HasSuffix(rel, ".lock")
+}
+
+// exportSelfContained copies the read-only files of avdPath into goldenDir/base and the system
+// image named by its config.ini into goldenDir/system. It returns the original image.sysdir.1.
+func exportSelfContained(env Env, avdPath, goldenDir string) (string, error) {
+	baseDst := filepath.Join(goldenDir, goldenBaseDirname)
+	if err := os.RemoveAll(baseDst); err != nil {
+		return "", err
+	}
+	err := filepath.WalkDir(avdPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(avdPath, path)
+		if rel == "." {
+			return os.MkdirAll(baseDst, 0o755)
+		}
+		if isWritableAVDArtifact(rel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		dst := filepath.Join(baseDst, rel)
+		if d.IsDir() {
+			return os.MkdirAll(dst, 0o755)
+		}
+		return copyFile(path, dst, 0o644) // follows symlinks of clone-derived bases
+	})
+	if err != nil {
+		return "", fmt.Errorf("export base artifacts: %w", err)
+	}
+
+	cfg, err := os.ReadFile(filepath.Join(avdPath, "config.ini"))
+	if err != nil {
+		return "", fmt.Errorf("read config: %w", err)
+	}
+	sysdir := configValue(cfg, "image.sysdir.1")
+	if sysdir == "" {
+		return "", fmt.Errorf("config.ini of %s has no image.sysdir.1", avdPath)
+	}
+	src := sysdir
+	if !filepath.IsAbs(src) {
+		if env.SDKRoot == "" {
+			return "", fmt.Errorf("ANDROID_SDK_ROOT not set; cannot locate system image %s", sysdir)
+		}
+		src = filepath.Join(env.SDKRoot, sysdir)
+	}
+	systemDst := filepath.Join(goldenDir, goldenSystemDirname)
+	if err := os.RemoveAll(systemDst); err != nil {
+		return "", err
+	}
+	if _, err := copyGoldenDir(src, systemDst); err != nil {
+		return "", fmt.Errorf("export system image %s: %w", sysdir, err)
+	}
+	return sysdir, nil
+}
+
+// embeddedBase returns the base directory stored in a self-contained golden, if any.
+func embeddedBase(goldenDir string) (string, bool) {
+	dir := filepath.Join(goldenDir, goldenBaseDirname)
+	st, err := os.Stat(filepath.Join(dir, "config.ini"))
+	return dir, err == nil && !st.IsDir()
+}
+
+// pointSysdirAtGolden rewrites image.sysdir.1 to the system image embedded in goldenDir.
+func pointSysdirAtGolden(cfg []byte, goldenDir string) []byte {
+	system := filepath.Join(goldenDir, goldenSystemDirname)
+	if st, err := os.Stat(system); err != nil || !st.IsDir() {
+		return cfg
+	}
+	return setConfigValue(cfg, "image.sysdir.1", system+string(filepath.Separator))
+}
+
+func configValue(cfg []byte, key string) string {
+	scanner := bufio.NewScanner(bytes.NewReader(cfg))
+	for scanner.Scan() {
+		k, v, ok := strings.Cut(scanner.Text(), "=")
+		if ok && strings.TrimSpace(k) == key {
+			return strings.TrimSpace(v)
+		}
+	}
+	return ""
+}
+
+func setConfigValue(cfg []byte, key, value string) []byte {
+	lines := strings.Split(string(cfg), "\n")
+	for i, line := range lines {
+		if k, _, ok := strings.Cut(line, "="); ok && strings.TrimSpace(k) == key {
+			lines[i] = key + "=" + value
+			return []byte(strings.Join(lines, "\n"))
+		}
+	}
+	out := strings.TrimRight(string(cfg), "\n") + "\n" + key + "=" + value + "\n"
+	return []byte(out)
+}
@@ -0,0 +1,89 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSetConfigValue(t *testing.T) {
+	cfg := []byte("hw.device.name=pixel_6\nimage.sysdir.1 = system-images/android-35/\n")
+	got := setConfigValue(cfg, "image.sysdir.1", "/golden/system/")
+	if configValue(got, "image.sysdir.1") != "/golden/system/" || configValue(got, "hw.device.name") != "pixel_6" {
+		t.Fatalf("unexpected config after replace:\n%s", got)
+	}
+	got = setConfigValue([]byte("hw.device.name=pixel_6"), "hw.ramSize", "4096")
+	if string(got) != "hw.device.name=pixel_6\nhw.ramSize=4096\n" {
+		t.Fatalf("unexpected config after append: %q", got)
+	}
+}
+
+func TestSelfContainedGoldenClonesWithoutBase(t *testing.T) {
+	env := newFsckTestEnv(t, 0)
+	env.SDKRoot = t.TempDir()
+	sysdir := "system-images/android-35/google_apis/x86_64/"
+	if err := os.MkdirAll(filepath.Join(env.SDKRoot, sysdir), 0o755); err != nil {
+		t.Fatalf("mkdir sysdir: %v", err)
+	}
+	for _, name := range []string{"kernel-ranchu", "system.img"} {
+		if err := os.WriteFile(filepath.Join(env.SDKRoot, sysdir, name), []byte(name), 0o644); err != nil {
+			t.Fatalf("write system image file: %v", err)
+		}
+	}
+	baseDir := filepath.Join(env.AVDHome, "demo.avd")
+	cfg := []byte("hw.device.name=pixel_6\nimage.sysdir.1=" + sysdir + "\n")
+	if err := os.WriteFile(filepath.Join(baseDir, "config.ini"), cfg, 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	for _, name := range []string{"advancedFeatures.ini", "sdcard.img"} {
+		if err := os.WriteFile(filepath.Join(baseDir, name), []byte(name), 0o644); err != nil {
+			t.Fatalf("write base artifact: %v", err)
+		}
+	}
+
+	golden := filepath.Join(t.TempDir(), "golden")
+	if _, _, err := SaveGoldenWithOptions(env, "demo", golden, SaveGoldenOptions{SelfContained: true}); err != nil {
+		t.Fatalf("SaveGoldenWithOptions: %v", err)
+	}
+	manifest, err := ReadGoldenManifest(golden)
+	if err != nil {
+		t.Fatalf("ReadGoldenManifest: %v", err)
+	}
+	if !manifest.SelfContained || manifest.SystemImageDir != sysdir {
+		t.Fatalf("unexpected manifest: %#v", manifest)
+	}
+	if _, err := os.Stat(filepath.Join(golden, goldenBaseDirname, "sdcard.img")); !os.IsNotExist(err) {
+		t.Fatal("writable images must not be embedded in the base")
+	}
+
+	if err := os.RemoveAll(baseDir); err != nil {
+		t.Fatalf("remove base: %v", err)
+	}
+	if _, err := CloneFromGolden(env, "demo", "w-portable", golden); err != nil {
+		t.Fatalf("CloneFromGolden: %v", err)
+	}
+	cloneCfg, err := os.ReadFile(filepath.Join(env.AVDHome, "w-portable.avd", "config.ini"))
+	if err != nil {
+		t.Fatalf("read clone config: %v", err)
+	}
+	absGolden, _ := filepath.Abs(golden)
+	want := filepath.Join(absGolden, goldenSystemDirname) + string(filepath.Separator)
+	if got := configValue(cloneCfg, "image.sysdir.1"); got != want {
+		t.Fatalf("image.sysdir.1 = %q, want %q", got, want)
+	}
+	if _, err := os.Stat(filepath.Join(env.AVDHome, "w-portable.avd", "advancedFeatures.ini")); err != nil {
+		t.Fatalf("base artifact missing from clone: %v", err)
+	}
+}
+
+func TestCloneFromGoldenStillRequiresBaseWhenNotSelfContained(t *testing.T) {
+	env := newTestEnv(t)
+	_, err := CloneFromGolden(env, "missing", "w-none", makeGoldenDir(t))
+	if err == nil || !strings.Contains(err.Error(), "base AVD not found") {
+		t.Fatalf("expected missing base error, got %v", err)
+	}
+}
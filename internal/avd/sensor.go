@@ -0,0 +1,178 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Sensor is an emulator sensor, named as in the console's "sensor set" command.
+type Sensor string
+
+const (
+	SensorAcceleration  Sensor = "acceleration"   // m/s², x:y:z
+	SensorGyroscope     Sensor = "gyroscope"      // rad/s, x:y:z
+	SensorMagneticField Sensor = "magnetic-field" // µT, x:y:z
+	SensorOrientation   Sensor = "orientation"    // degrees, azimuth:pitch:roll
+	SensorPressure      Sensor = "pressure"       // hPa
+	SensorTemperature   Sensor = "temperature"    // °C
+	SensorHumidity      Sensor = "humidity"       // %
+	SensorLight         Sensor = "light"          // lux
+	SensorProximity     Sensor = "proximity"      // cm
+)
+
+// sensorValueCounts is the number of values each sensor takes.
+var sensorValueCounts = map[Sensor]int{
+	SensorAcceleration: 3, SensorGyroscope: 3, SensorMagneticField: 3, SensorOrientation: 3,
+	SensorPressure: 1, SensorTemperature: 1, SensorHumidity: 1, SensorLight: 1, SensorProximity: 1,
+}
+
+// SensorSample is one reading of a recorded sensor trace.
+type SensorSample struct {
+	Offset time.Duration // time since the start of the trace
+	Sensor Sensor
+	Values []float64
+}
+
+// sensorCommand validates values for sensor and returns the console command setting them.
+func sensorCommand(sensor Sensor, values []float64) (string, error) {
+	want, ok := sensorValueCounts[sensor]
+	if !ok {
+		return "", fmt.Errorf("unknown sensor %q", sensor)
+	}
+	if len(values) != want {
+		return "", fmt.Errorf("sensor %s takes %d value(s), got %d", sensor, want, len(values))
+	}
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	return fmt.Sprintf("sensor set %s %s", sensor, strings.Join(parts, ":")), nil
+}
+
+// SetSensor sets the current values of a sensor on the emulator behind serial.
+func SetSensor(env Env, serial string, sensor Sensor, values []float64) error {
+	_, span := startSpan(env, "avd.SetSensor", attribute.String("serial", serial), attribute.String("sensor", string(sensor)))
+	defer span.End()
+	command, err := sensorCommand(sensor, values)
+	if err == nil {
+		_, err = Console(env, serial, command)
+	}
+	recordSpanError(span, err)
+	return err
+}
+
+// PlaySensorTrace feeds samples to the emulator behind serial at their offsets, over one
+// console session. Samples must be ordered by Offset. It returns early if env.Context is done.
+func PlaySensorTrace(env Env, serial string, samples []SensorSample) error {
+	_, span := startSpan(env, "avd.PlaySensorTrace", attribute.String("serial", serial), attribute.Int("samples", len(samples)))
+	defer span.End()
+	fail := func(err error) error {
+		recordSpanError(span, err)
+		return err
+	}
+	commands := make([]string, len(samples))
+	for i, sample := range samples {
+		if i > 0 && sample.Offset < samples[i-1].Offset {
+			return fail(fmt.Errorf("sensor trace sample %d is out of order", i+1))
+		}
+		command, err := sensorCommand(sample.Sensor, sample.Values)
+		if err != nil {
+			return fail(fmt.Errorf("sensor trace sample %d: %w", i+1, err))
+		}
+		commands[i] = command
+	}
+	session, err := openConsole(env, serial)
+	if err != nil {
+		return fail(err)
+	}
+	defer session.Close()
+	ctx := spanContext(env)
+	start := time.Now()
+	for i, sample := range samples {
+		if wait := time.Until(start.Add(sample.Offset)); wait > 0 {
+			if err := sleepContext(ctx, wait); err != nil {
+				return fail(err)
+			}
+		}
+		if _, err := session.send(commands[i]); err != nil {
+			return fail(err)
+		}
+	}
+	logEvent(env, "sensor trace played", "serial", serial, "samples", len(samples), "duration", time.Since(start).String())
+	return nil
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// ParseSensorTrace reads a sensor trace: one sample per line as
+// "<offset ms>,<sensor>,<value>[,<value>...]", e.g. "250,acceleration,0.1,9.8,0.3".
+// Blank lines and lines starting with # are ignored.
+func ParseSensorTrace(r io.Reader) ([]SensorSample, error) {
+	var samples []SensorSample
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, ",")
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("sensor trace line %d: want offset,sensor,values", lineNo)
+		}
+		ms, err := strconv.ParseFloat(strings.TrimSpace(fields[0]), 64)
+		if err != nil || ms < 0 {
+			return nil, fmt.Errorf("sensor trace line %d: invalid offset %q", lineNo, fields[0])
+		}
+		sample := SensorSample{
+			Offset: time.Duration(ms * float64(time.Millisecond)),
+			Sensor: Sensor(strings.TrimSpace(fields[1])),
+		}
+		for _, field := range fields[2:] {
+			v, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+			if err != nil {
+				return nil, fmt.Errorf("sensor trace line %d: invalid value %q", lineNo, field)
+			}
+			sample.Values = append(sample.Values, v)
+		}
+		samples = append(samples, sample)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read sensor trace: %w", err)
+	}
+	return samples, nil
+}
+
+// FormatSensorTrace writes samples in the format read by ParseSensorTrace.
+func FormatSensorTrace(samples []SensorSample) string {
+	var b strings.Builder
+	for _, sample := range samples {
+		b.WriteString(strconv.FormatFloat(float64(sample.Offset)/float64(time.Millisecond), 'f', -1, 64))
+		b.WriteByte(',')
+		b.WriteString(string(sample.Sensor))
+		for _, v := range sample.Values {
+			b.WriteByte(',')
+			b.WriteString(strconv.FormatFloat(v, 'g', -1, 64))
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
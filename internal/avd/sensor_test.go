@@ -0,0 +1,76 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSetSensorSendsConsoleCommand(t *testing.T) {
+	serial, received := startFakeConsole(t, writeConsoleToken(t, "s3cret"), map[string]string{
+		"sensor set acceleration 0:9.8:0.25": "",
+	})
+	if err := SetSensor(newTestEnv(t), serial, SensorAcceleration, []float64{0, 9.8, 0.25}); err != nil {
+		t.Fatalf("SetSensor: %v", err)
+	}
+	<-received // auth
+	if got := <-received; got != "sensor set acceleration 0:9.8:0.25" {
+		t.Fatalf("unexpected command %q", got)
+	}
+}
+
+func TestSetSensorValidatesValues(t *testing.T) {
+	env := newTestEnv(t)
+	if err := SetSensor(env, "emulator-5580", SensorPressure, []float64{1013, 1}); err == nil || !strings.Contains(err.Error(), "takes 1 value") {
+		t.Fatalf("expected value count error, got %v", err)
+	}
+	if err := SetSensor(env, "emulator-5580", Sensor("barometer"), []float64{1013}); err == nil || !strings.Contains(err.Error(), "unknown sensor") {
+		t.Fatalf("expected unknown sensor error, got %v", err)
+	}
+}
+
+func TestPlaySensorTraceFeedsSamplesInOrder(t *testing.T) {
+	serial, received := startFakeConsole(t, writeConsoleToken(t, "s3cret"), map[string]string{
+		"sensor set acceleration 0:9.8:0": "",
+		"sensor set acceleration 1:9.8:0": "",
+		"sensor set pressure 1013.25":     "",
+	})
+	samples, err := ParseSensorTrace(strings.NewReader("# t_ms,sensor,values\n0,acceleration,0,9.8,0\n\n50,acceleration,1,9.8,0\n100,pressure,1013.25\n"))
+	if err != nil {
+		t.Fatalf("ParseSensorTrace: %v", err)
+	}
+	start := time.Now()
+	if err := PlaySensorTrace(newTestEnv(t), serial, samples); err != nil {
+		t.Fatalf("PlaySensorTrace: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Fatalf("trace played in %v, want at least its 100ms span", elapsed)
+	}
+	<-received // auth
+	for _, want := range []string{"sensor set acceleration 0:9.8:0", "sensor set acceleration 1:9.8:0", "sensor set pressure 1013.25"} {
+		if got := <-received; got != want {
+			t.Fatalf("got %q, want %q", got, want)
+		}
+	}
+}
+
+func TestSensorTraceRoundTrip(t *testing.T) {
+	samples := []SensorSample{
+		{Offset: 0, Sensor: SensorGyroscope, Values: []float64{0.1, -0.2, 0}},
+		{Offset: 1500 * time.Microsecond, Sensor: SensorLight, Values: []float64{340}},
+	}
+	parsed, err := ParseSensorTrace(strings.NewReader(FormatSensorTrace(samples)))
+	if err != nil {
+		t.Fatalf("ParseSensorTrace: %v", err)
+	}
+	if !reflect.DeepEqual(parsed, samples) {
+		t.Fatalf("round trip = %#v, want %#v", parsed, samples)
+	}
+	if _, err := ParseSensorTrace(strings.NewReader("x,light,1\n")); err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Fatalf("expected offset error, got %v", err)
+	}
+}
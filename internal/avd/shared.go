@@ -0,0 +1,29 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// sharedScratchDir is where a shared (-read-only) run on port keeps its temporary files: the
+// emulator writes the guest's changes to overlays in its temp directory instead of the AVD.
+// It lives in the run directory of the port, so it is handed over, sandboxed and discarded by
+// StopBySerial like the copies of an ephemeral run.
+func sharedScratchDir(env Env, port int) string {
+	return filepath.Join(ephemeralRunDir(env, port), "tmp")
+}
+
+// prepareSharedScratch creates an empty scratch directory for the shared run on port and
+// returns the process environment pointing the emulator's temp files into it.
+func prepareSharedScratch(env Env, port int) ([]string, error) {
+	dir := sharedScratchDir(env, port)
+	_ = os.RemoveAll(dir) // left over from an instance that exited on its own
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("shared run scratch directory: %w", err)
+	}
+	return []string{"ANDROID_TMP=" + dir, "TMPDIR=" + dir}, nil
+}
@@ -0,0 +1,35 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPrepareSharedScratch(t *testing.T) {
+	env := newTestEnv(t)
+	env.EphemeralDir = t.TempDir()
+	dir := sharedScratchDir(env, 5580)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "stale.qcow2"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	procEnv, err := prepareSharedScratch(env, 5580)
+	if err != nil {
+		t.Fatalf("prepareSharedScratch: %v", err)
+	}
+	if len(procEnv) != 2 || procEnv[0] != "ANDROID_TMP="+dir || procEnv[1] != "TMPDIR="+dir {
+		t.Fatalf("procEnv = %q, want the scratch dir %s", procEnv, dir)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Fatalf("scratch dir not emptied: %v", entries)
+	}
+	if sharedScratchDir(env, 5582) == dir {
+		t.Fatal("instances share a scratch dir")
+	}
+}
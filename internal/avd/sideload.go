@@ -0,0 +1,172 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// guestOBBDir is where Android looks for the expansion files of each package.
+const guestOBBDir = "/sdcard/Android/obb"
+
+var (
+	// Expansion files are named main|patch.<versionCode>.<package>.obb.
+	obbNameRe      = regexp.MustCompile(`^(main|patch)\.\d+\.([A-Za-z][\w]*(\.[A-Za-z][\w]*)+)\.obb$`)
+	packageRe      = regexp.MustCompile(`^[A-Za-z][\w]*(\.[A-Za-z][\w]*)+$`)
+	errNotOBB      = errors.New("OBB files must be named main.<versionCode>.<package>.obb or patch.<versionCode>.<package>.obb")
+	errAppDataForm = errors.New("app data is given as <package>=<directory>")
+)
+
+// AppData is a local directory whose contents are copied into the private data directory of an
+// installed app (/data/data/<package>), e.g. shared_prefs/ and databases/ of a logged-in state.
+type AppData struct {
+	Package string `json:"package"`
+	Dir     string `json:"dir"`
+}
+
+// ParseAppData parses the "<package>=<directory>" form of the CLI.
+func ParseAppData(s string) (AppData, error) {
+	pkg, dir, ok := strings.Cut(s, "=")
+	if !ok || dir == "" {
+		return AppData{}, fmt.Errorf("%q: %w", s, errAppDataForm)
+	}
+	data := AppData{Package: strings.TrimSpace(pkg), Dir: dir}
+	return data, data.Validate()
+}
+
+// Validate checks the package name and that Dir is a directory.
+func (d AppData) Validate() error {
+	if !packageRe.MatchString(d.Package) {
+		return fmt.Errorf("app data: %q is not a package name", d.Package)
+	}
+	st, err := os.Stat(d.Dir)
+	if err != nil {
+		return fmt.Errorf("app data of %s: %w", d.Package, err)
+	}
+	if !st.IsDir() {
+		return fmt.Errorf("app data of %s: %s is not a directory", d.Package, d.Dir)
+	}
+	return nil
+}
+
+// obbPackage returns the package an expansion file belongs to, from its name.
+func obbPackage(obb string) (string, error) {
+	m := obbNameRe.FindStringSubmatch(filepath.Base(obb))
+	if m == nil {
+		return "", fmt.Errorf("%s: %w", obb, errNotOBB)
+	}
+	return m[2], nil
+}
+
+// PushOBB copies an expansion file to /sdcard/Android/obb/<package>/ on serial. The package is
+// taken from the file name, which Android also requires.
+func PushOBB(env Env, serial, obb string) error {
+	_, span := startSpan(env, "avd.PushOBB", attribute.String("serial", serial), attribute.String("obb", obb))
+	defer span.End()
+	fail := func(err error) error {
+		recordSpanError(span, err)
+		return err
+	}
+	pkg, err := obbPackage(obb)
+	if err != nil {
+		return fail(err)
+	}
+	dir := path.Join(guestOBBDir, pkg)
+	if out, err := runCommandCombinedOutputWithEnv(env.Context, nil, nil, env.ADB, "-s", serial, "shell", "mkdir", "-p", dir); err != nil {
+		return fail(fmt.Errorf("create %s on %s: %w: %s", dir, serial, err, strings.TrimSpace(string(out))))
+	}
+	if out, err := runCommandCombinedOutputWithEnv(env.Context, nil, nil, env.ADB, "-s", serial, "push", obb, path.Join(dir, filepath.Base(obb))); err != nil {
+		return fail(fmt.Errorf("push %s to %s: %w: %s", obb, serial, err, strings.TrimSpace(string(out))))
+	}
+	logEvent(env, "obb pushed", "serial", serial, "package", pkg, "obb", obb)
+	return nil
+}
+
+// PushAppData copies data.Dir into the data directory of the installed app data.Package. With
+// root-capable adbd the files are extracted as root and given to the app's uid; otherwise the
+// app must be debuggable and they are extracted through run-as. The app should not be running.
+func PushAppData(env Env, serial string, data AppData) error {
+	_, span := startSpan(env, "avd.PushAppData", attribute.String("serial", serial), attribute.String("package", data.Package))
+	defer span.End()
+	fail := func(err error) error {
+		recordSpanError(span, err)
+		return err
+	}
+	if err := data.Validate(); err != nil {
+		return fail(err)
+	}
+	target := "/data/data/" + data.Package
+	args := []string{"-s", serial, "exec-in", "run-as", data.Package, "tar", "-xf", "-", "-C", target}
+	mode := "run-as"
+	if adbRoot(env, serial) == nil {
+		// sync so the files reach userdata before a bake kills the instance for export.
+		script := fmt.Sprintf("tar -xf - -C %[1]s && chown -R $(stat -c %%u:%%g %[1]s) %[1]s && (restorecon -R %[1]s || true) && sync", target)
+		// adb joins the arguments into one command line for the guest shell.
+		args = []string{"-s", serial, "exec-in", "sh -c '" + script + "'"}
+		mode = "root"
+	}
+	pr, pw := io.Pipe()
+	go func() { pw.CloseWithError(writeTar(pw, data.Dir)) }()
+	out, errOut, err := runCommandOutputWithEnv(env.Context, nil, pr, env.ADB, args...)
+	_ = pr.Close()
+	if err != nil {
+		detail := strings.TrimSpace(out + errOut)
+		if mode == "run-as" {
+			detail += " (without root, the app must be debuggable)"
+		}
+		return fail(fmt.Errorf("seed app data of %s on %s: %w: %s", data.Package, serial, err, detail))
+	}
+	logEvent(env, "app data seeded", "serial", serial, "package", data.Package, "dir", data.Dir, "mode", mode)
+	return nil
+}
+
+// writeTar writes the contents of dir, relative to it, as a tar stream.
+func writeTar(w io.Writer, dir string) error {
+	tw := tar.NewWriter(w)
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil || p == dir {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() && !info.IsDir() {
+			return nil // sockets, links: not app state worth carrying
+		}
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		if info.IsDir() {
+			hdr.Name += "/"
+		}
+		hdr.Uid, hdr.Gid, hdr.Uname, hdr.Gname = 0, 0, "", ""
+		if err := tw.WriteHeader(hdr); err != nil || info.IsDir() {
+			return err
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
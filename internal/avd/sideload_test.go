@@ -0,0 +1,114 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"archive/tar"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestOBBAndAppDataNames(t *testing.T) {
+	if pkg, err := obbPackage("/srv/obb/main.4210.com.acme.game.obb"); err != nil || pkg != "com.acme.game" {
+		t.Fatalf("obbPackage = %q, %v", pkg, err)
+	}
+	for _, bad := range []string{"game.obb", "main.x.com.acme.game.obb", "extra.1.com.acme.game.obb"} {
+		if _, err := obbPackage(bad); !errors.Is(err, errNotOBB) {
+			t.Fatalf("obbPackage(%q) = %v, want errNotOBB", bad, err)
+		}
+	}
+	dir := t.TempDir()
+	if data, err := ParseAppData("com.acme.game=" + dir); err != nil || data.Package != "com.acme.game" || data.Dir != dir {
+		t.Fatalf("ParseAppData = %+v, %v", data, err)
+	}
+	for _, bad := range []string{dir, "acme=" + dir, "com.acme.game=" + filepath.Join(dir, "missing")} {
+		if _, err := ParseAppData(bad); err == nil {
+			t.Fatalf("expected an error for %q", bad)
+		}
+	}
+}
+
+func TestPushOBB(t *testing.T) {
+	env := newTestEnv(t)
+	logPath := filepath.Join(t.TempDir(), "adb.log")
+	if err := os.WriteFile(env.ADB, []byte("#!/bin/sh\necho \"$@\" >> "+logPath+"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := PushOBB(env, "emulator-5592", "/srv/obb/main.4210.com.acme.game.obb"); err != nil {
+		t.Fatalf("PushOBB: %v", err)
+	}
+	calls, _ := os.ReadFile(logPath)
+	want := "-s emulator-5592 shell mkdir -p /sdcard/Android/obb/com.acme.game\n" +
+		"-s emulator-5592 push /srv/obb/main.4210.com.acme.game.obb /sdcard/Android/obb/com.acme.game/main.4210.com.acme.game.obb\n"
+	if string(calls) != want {
+		t.Fatalf("adb calls:\n%s\nwant\n%s", calls, want)
+	}
+}
+
+func TestPushAppData(t *testing.T) {
+	data := AppData{Package: "com.acme.game", Dir: t.TempDir()}
+	if err := os.MkdirAll(filepath.Join(data.Dir, "shared_prefs"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(data.Dir, "shared_prefs", "session.xml"), []byte("<map/>"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for _, root := range []bool{true, false} {
+		env := newTestEnv(t)
+		dir := t.TempDir()
+		logPath, stream := filepath.Join(dir, "adb.log"), filepath.Join(dir, "stream.tar")
+		refuse := ""
+		if !root {
+			refuse = "echo 'adbd cannot run as root in production builds'"
+		}
+		script := `#!/bin/sh
+echo "$@" >> ` + logPath + `
+case "$3" in
+  root) ` + refuse + ` ;;
+  shell) echo 0 ;;
+  exec-in) cat > ` + stream + ` ;;
+esac
+exit 0
+`
+		if err := os.WriteFile(env.ADB, []byte(script), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := PushAppData(env, "emulator-5592", data); err != nil {
+			t.Fatalf("PushAppData(root=%v): %v", root, err)
+		}
+		calls, _ := os.ReadFile(logPath)
+		want := "exec-in run-as com.acme.game tar -xf - -C /data/data/com.acme.game"
+		if root {
+			want = "exec-in sh -c 'tar -xf - -C /data/data/com.acme.game && chown -R $(stat -c %u:%g /data/data/com.acme.game)"
+		}
+		if !strings.Contains(string(calls), want) {
+			t.Fatalf("adb calls (root=%v) missing %q:\n%s", root, want, calls)
+		}
+		f, err := os.Open(stream)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		tr := tar.NewReader(f)
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("read stream: %v", err)
+			}
+			names = append(names, hdr.Name)
+		}
+		_ = f.Close()
+		if !slices.Equal(names, []string{"shared_prefs/", "shared_prefs/session.xml"}) {
+			t.Fatalf("tar entries = %v", names)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// DefaultStallTimeout is how long a starting emulator may write nothing to its log, before adb
+// sees it, until the start counts as stalled. Cold KVM starts that hang do so silently.
+const DefaultStallTimeout = 30 * time.Second
+
+// startupWatchLimit bounds the stall watch of a start that keeps logging without adb seeing
+// it; the boot wait decides about such starts.
+const startupWatchLimit = 3 * time.Minute
+
+// stallPatterns are emulator log lines that, repeated, mean a start will never boot.
+var stallPatterns = []struct {
+	text    string
+	repeats int
+}{
+	{"Failed to open vhost", 3},
+}
+
+// StallError is returned when every attempt of a start stalled (see StartWithStallRetry).
+type StallError struct {
+	Name     string
+	Serial   string
+	LogPath  string // log of the last attempt
+	Reason   string
+	Attempts int
+}
+
+func (e *StallError) Error() string {
+	return fmt.Sprintf("emulator %s on %s stalled during startup after %d attempt(s): %s (log: %s)", e.Name, e.Serial, e.Attempts, e.Reason, e.LogPath)
+}
+
+// StartWithStallRetry runs start and watches the emulator it launched until adb sees it. A
+// start whose log goes quiet for quiet (DefaultStallTimeout when 0) or repeats a stall pattern
+// is killed and launched again, up to retries more times, before a *StallError is returned.
+func StartWithStallRetry(env Env, retries int, quiet time.Duration, start func() (StartResult, error)) (StartResult, error) {
+	if quiet <= 0 {
+		quiet = DefaultStallTimeout
+	}
+	for attempt := 1; ; attempt++ {
+		res, err := start()
+		if err != nil {
+			return res, err
+		}
+		reason, err := watchStartup(env, res, quiet)
+		if err != nil || reason == "" {
+			return res, err
+		}
+		logEvent(env, "emulator start stalled", "name", res.Name, "serial", res.Serial, "attempt", attempt, "reason", reason, "log_path", res.LogPath)
+		avdDir := filepath.Join(env.AVDHome, res.Name+".avd")
+		markRun(avdDir, res.PID, FailureBootTimeout, "stalled during startup: "+reason, true)
+		checkFlakyStart(env, avdDir)
+		abandonStart(env, res)
+		if attempt > retries {
+			return StartResult{}, &StallError{Name: res.Name, Serial: res.Serial, LogPath: res.LogPath, Reason: reason, Attempts: attempt}
+		}
+	}
+}
+
+// watchStartup returns why the launched emulator res stalled, or "" once adb sees it (or after
+// startupWatchLimit). An emulator that exits is an error, not a stall: relaunching it would
+// fail the same way.
+func watchStartup(env Env, res StartResult, quiet time.Duration) (string, error) {
+	started := time.Now()
+	lastOutput := started
+	var size int64 = -1
+	for time.Since(started) < startupWatchLimit {
+		if visible, _ := isSerialVisible(env, res.Serial); visible {
+			return "", nil
+		}
+		if res.PID > 0 && (syscall.Kill(res.PID, 0) != nil || isZombieProcess(res.PID)) {
+			return "", fmt.Errorf("emulator %s exited during startup (log: %s)", res.Name, res.LogPath)
+		}
+		if st, err := os.Stat(res.LogPath); err == nil && st.Size() != size {
+			size, lastOutput = st.Size(), time.Now()
+			if reason := stallPattern(res.LogPath); reason != "" {
+				return reason, nil
+			}
+		}
+		if idle := time.Since(lastOutput); idle >= quiet {
+			return fmt.Sprintf("no emulator output for %s", idle.Round(time.Second)), nil
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+	return "", nil
+}
+
+// stallPattern returns the stall pattern the log at path repeats, if any.
+func stallPattern(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	for _, p := range stallPatterns {
+		if n := strings.Count(string(data), p.text); n >= p.repeats {
+			return fmt.Sprintf("%q logged %d times", p.text, n)
+		}
+	}
+	return ""
+}
+
+// abandonStart kills a stalled emulator and releases what its launch set up, so the port is
+// free for the next attempt.
+func abandonStart(env Env, res StartResult) {
+	if res.PID > 0 {
+		// The launch runs in its own session: take qemu down with the emulator launcher.
+		_ = syscall.Kill(-res.PID, syscall.SIGKILL)
+	}
+	if res.Cmd != nil && res.Cmd.Process != nil {
+		_ = res.Cmd.Process.Kill()
+		_ = res.Cmd.Wait()
+	}
+	discardEphemeral(env, res.Port)
+	teardownCloneNetwork(env, res.Port)
+}
@@ -0,0 +1,69 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// fakeStart returns a start function launching a process that writes script's output to its
+// log, as an emulator would, and the launched processes.
+func fakeStart(t *testing.T, script string) (func() (StartResult, error), *[]*exec.Cmd) {
+	t.Helper()
+	var cmds []*exec.Cmd
+	t.Cleanup(func() {
+		for _, cmd := range cmds {
+			_ = cmd.Process.Kill()
+			_ = cmd.Wait()
+		}
+	})
+	return func() (StartResult, error) {
+		logPath := filepath.Join(t.TempDir(), "emulator.log")
+		cmd := exec.Command("sh", "-c", script+" >> "+logPath+"; sleep 30")
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+		if err := cmd.Start(); err != nil {
+			return StartResult{}, err
+		}
+		cmds = append(cmds, cmd)
+		return StartResult{Name: "w-1", Serial: "emulator-5590", Port: 5590, LogPath: logPath, PID: cmd.Process.Pid, Cmd: cmd}, nil
+	}, &cmds
+}
+
+func TestStartWithStallRetry(t *testing.T) {
+	env := newTestEnv(t)
+	env.EphemeralDir = t.TempDir()
+
+	start, cmds := fakeStart(t, "true")
+	_, err := StartWithStallRetry(env, 1, time.Second, start)
+	var stall *StallError
+	if !errors.As(err, &stall) || stall.Attempts != 2 || !strings.Contains(stall.Reason, "no emulator output") {
+		t.Fatalf("expected a silent stall after 2 attempts, got %v", err)
+	}
+	for _, cmd := range *cmds {
+		if cmd.ProcessState == nil {
+			t.Fatalf("stalled attempt %d left running", cmd.Process.Pid)
+		}
+	}
+
+	start, _ = fakeStart(t, "for i in 1 2 3; do echo 'qemu: Failed to open vhost device'; done")
+	if _, err := StartWithStallRetry(env, 0, time.Minute, start); !errors.As(err, &stall) || !strings.Contains(stall.Reason, "Failed to open vhost") {
+		t.Fatalf("expected a vhost stall, got %v", err)
+	}
+
+	if err := os.WriteFile(env.ADB, []byte("#!/bin/sh\nprintf 'List of devices attached\\nemulator-5590\\toffline\\n'\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	start, cmds = fakeStart(t, "true")
+	res, err := StartWithStallRetry(env, 2, time.Minute, start)
+	if err != nil || res.Serial != "emulator-5590" || len(*cmds) != 1 {
+		t.Fatalf("StartWithStallRetry() = %+v, %v after %d launches", res, err, len(*cmds))
+	}
+}
@@ -0,0 +1,41 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// StartMode tells how an emulator start brings the guest up, since boot durations are only
+// comparable between starts of the same mode.
+type StartMode string
+
+const (
+	// StartCold boots the kernel from the raw images, with no snapshot and no leftover overlay.
+	StartCold StartMode = "cold"
+	// StartQuickboot resumes a snapshot: the one named by -snapshot, or the AVD's default_boot
+	// quickboot snapshot when snapshot loading is not disabled.
+	StartQuickboot StartMode = "quickboot"
+	// StartOverlayReuse boots without a snapshot on top of qcow2 overlays a previous run of the
+	// AVD left over its writable images.
+	StartOverlayReuse StartMode = "overlay-reuse"
+)
+
+// classifyStart returns the mode a launch of a on the AVD in avdDir starts in. It must run
+// before the launch, which can create the overlays and snapshots it looks for.
+func classifyStart(avdDir string, a EmulatorArgs) StartMode {
+	if a.Snapshot != "" {
+		return StartQuickboot
+	}
+	if !a.NoSnapshot && !a.NoSnapshotLoad && fileExists(filepath.Join(avdDir, "snapshots", "default_boot")) {
+		return StartQuickboot
+	}
+	for _, img := range cloneLayout(avdDir).Images {
+		if st, err := os.Stat(filepath.Join(avdDir, img.Name+".qcow2")); err == nil && st.Mode().IsRegular() {
+			return StartOverlayReuse
+		}
+	}
+	return StartCold
+}
@@ -0,0 +1,40 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestClassifyStart(t *testing.T) {
+	avdDir := t.TempDir()
+	cold := HeadlessArgs("w-1", 5580)
+	if mode := classifyStart(avdDir, cold); mode != StartCold {
+		t.Fatalf("fresh clone = %q, want cold", mode)
+	}
+	if err := os.WriteFile(filepath.Join(avdDir, "userdata-qemu.img.qcow2"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if mode := classifyStart(avdDir, cold); mode != StartOverlayReuse {
+		t.Fatalf("leftover overlay = %q, want overlay-reuse", mode)
+	}
+
+	if err := os.MkdirAll(filepath.Join(avdDir, "snapshots", "default_boot"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if mode := classifyStart(avdDir, cold); mode != StartOverlayReuse {
+		t.Fatalf("-no-snapshot-load with a quickboot snapshot = %q, want overlay-reuse", mode)
+	}
+	quick := cold
+	quick.NoSnapshot, quick.NoSnapshotLoad = false, false
+	if mode := classifyStart(avdDir, quick); mode != StartQuickboot {
+		t.Fatalf("default_boot load = %q, want quickboot", mode)
+	}
+	named := cold.withExtra("-snapshot", "hibernated")
+	if mode := classifyStart(t.TempDir(), named); mode != StartQuickboot {
+		t.Fatalf("-snapshot = %q, want quickboot", mode)
+	}
+}
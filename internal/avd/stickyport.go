@@ -0,0 +1,137 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// stickyPortsFilename, in Env.AVDHome, maps AVD names to their console port, so an AVD gets
+// the same serial on every start.
+const stickyPortsFilename = "avdctl-ports.json"
+
+// Console ports handed out to AVDs started without an explicit port.
+const (
+	stickyPortStart = 5580
+	stickyPortEnd   = maxConsolePort
+)
+
+// StickyPorts returns the console port assigned to each AVD.
+func StickyPorts(env Env) map[string]int {
+	ports := map[string]int{}
+	b, err := os.ReadFile(filepath.Join(env.AVDHome, stickyPortsFilename))
+	if err == nil {
+		_ = json.Unmarshal(b, &ports)
+	}
+	return ports
+}
+
+// SetStickyPort assigns port to name, so starts without an explicit port use emulator-<port>.
+// Port 0 drops the assignment; the next start picks and records a new one.
+func SetStickyPort(env Env, name string, port int) error {
+	_, span := startSpan(env, "avd.SetStickyPort", attribute.String("name", name), attribute.Int("port", port))
+	defer span.End()
+	if port != 0 {
+		if err := checkConsolePort(port); err != nil {
+			err = fmt.Errorf("invalid console port: %w", err)
+			recordSpanError(span, err)
+			return err
+		}
+	}
+	err := updateStickyPorts(env, func(ports map[string]int) error {
+		if port == 0 {
+			delete(ports, name)
+			return nil
+		}
+		for other, p := range ports {
+			if p == port && other != name {
+				return fmt.Errorf("port %d is already assigned to %s", port, other)
+			}
+		}
+		ports[name] = port
+		return nil
+	})
+	if err != nil {
+		recordSpanError(span, err)
+		return err
+	}
+	logEvent(env, "sticky port set", "name", name, "port", port)
+	return nil
+}
+
+// stickyPort returns the port name starts on when none is given: its assigned port when that
+// is free, else the first free port assigned to no other AVD, which is then recorded. A free
+// port is still used when all are assigned or the map cannot be written, without being
+// recorded.
+func stickyPort(env Env, name string) (int, error) {
+	var port int
+	err := updateStickyPorts(env, func(ports map[string]int) error {
+		if p, ok := ports[name]; ok && isPortPairFree(env, p) {
+			port = p
+			return nil
+		}
+		taken := map[int]bool{}
+		for other, p := range ports {
+			if other != name {
+				taken[p] = true
+			}
+		}
+		for p := stickyPortStart; p < stickyPortEnd; p += 2 {
+			if !taken[p] && isPortPairFree(env, p) {
+				if old, ok := ports[name]; ok {
+					logEvent(env, "sticky port busy, reassigned", "name", name, "old", old, "port", p)
+				}
+				port, ports[name] = p, p
+				return nil
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		logEvent(env, "sticky port not recorded", "name", name, "error", err)
+	}
+	if port != 0 {
+		return port, nil
+	}
+	return FindFreeEvenPortWithEnv(env, stickyPortStart, stickyPortEnd)
+}
+
+// releaseStickyPort drops the port of an AVD that no longer exists.
+func releaseStickyPort(env Env, name string) {
+	if _, ok := StickyPorts(env)[name]; ok {
+		_ = updateStickyPorts(env, func(ports map[string]int) error {
+			delete(ports, name)
+			return nil
+		})
+	}
+}
+
+// updateStickyPorts applies change to the port map under its lock; nothing is written when
+// change fails.
+func updateStickyPorts(env Env, change func(map[string]int) error) error {
+	path := filepath.Join(env.AVDHome, stickyPortsFilename)
+	unlock, err := acquireFileLock(env.Context, path+".lock", nil)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	ports := StickyPorts(env)
+	if err := change(ports); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(ports, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(b, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
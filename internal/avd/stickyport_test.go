@@ -0,0 +1,48 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"net"
+	"testing"
+)
+
+func TestStickyPortIsKeptAcrossStarts(t *testing.T) {
+	env := newTestEnv(t)
+	if err := SetStickyPort(env, "w-acme", 5720); err != nil {
+		t.Fatalf("SetStickyPort: %v", err)
+	}
+	if err := SetStickyPort(env, "w-other", 5720); err == nil {
+		t.Fatal("expected an error for a port assigned to another AVD")
+	}
+	for _, bad := range []int{5721, 5552, 5802} {
+		if err := SetStickyPort(env, "w-other", bad); err == nil {
+			t.Fatalf("expected port %d to be refused", bad)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if port, err := stickyPort(env, "w-acme"); err != nil || port != 5720 {
+			t.Fatalf("stickyPort(w-acme) = %d, %v; want 5720", port, err)
+		}
+	}
+	port, err := stickyPort(env, "w-new")
+	if err != nil || port == 5720 || StickyPorts(env)["w-new"] != port {
+		t.Fatalf("stickyPort(w-new) = %d, %v; ports %v", port, err, StickyPorts(env))
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:5720")
+	if err != nil {
+		t.Skipf("cannot bind 5720: %v", err)
+	}
+	moved, err := stickyPort(env, "w-acme")
+	_ = l.Close()
+	if err != nil || moved == 5720 || moved == port || StickyPorts(env)["w-acme"] != moved {
+		t.Fatalf("busy sticky port not reassigned: %d, %v; ports %v", moved, err, StickyPorts(env))
+	}
+
+	releaseStickyPort(env, "w-new")
+	if _, ok := StickyPorts(env)["w-new"]; ok {
+		t.Fatal("released port still assigned")
+	}
+}
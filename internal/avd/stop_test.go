@@ -0,0 +1,89 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseStopMode(t *testing.T) {
+	for in, want := range map[string]StopMode{
+		"":               StopConsoleKill,
+		"console-kill":   StopConsoleKill,
+		"guest-shutdown": StopGuestShutdown,
+		"force":          StopForce,
+	} {
+		got, err := ParseStopMode(in)
+		if err != nil || got != want {
+			t.Fatalf("ParseStopMode(%q) = %q, %v", in, got, err)
+		}
+	}
+	if _, err := ParseStopMode("halt"); err == nil {
+		t.Fatal("expected error for unknown mode")
+	}
+}
+
+func TestStopGuestShutdownPowersOffGuest(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("requires /proc")
+	}
+	env := newTestEnv(t)
+	logPath := filepath.Join(t.TempDir(), "adb.log")
+	script := "#!/bin/sh\necho \"$@\" >> " + logPath + "\nexit 0\n"
+	if err := os.WriteFile(env.ADB, []byte(script), 0o755); err != nil {
+		t.Fatalf("write adb stub: %v", err)
+	}
+
+	err := StopBySerialWithOptions(env, "emulator-5590", StopOptions{Mode: StopGuestShutdown, Timeout: 2 * time.Second})
+	if err != nil {
+		t.Fatalf("StopBySerialWithOptions: %v", err)
+	}
+	calls, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("read adb log: %v", err)
+	}
+	if !strings.Contains(string(calls), "-s emulator-5590 shell reboot -p") {
+		t.Fatalf("expected guest power-off, adb calls:\n%s", calls)
+	}
+	if strings.Contains(string(calls), "emu kill") {
+		t.Fatalf("guest shutdown should not fall back to console kill, adb calls:\n%s", calls)
+	}
+}
+
+func TestFailedStopKeepsRunOpen(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("requires /proc")
+	}
+	env := newTestEnv(t)
+	if err := os.WriteFile(env.ADB, []byte("#!/bin/sh\nexit 1\n"), 0o755); err != nil {
+		t.Fatalf("write adb stub: %v", err)
+	}
+	makeBaseAVD(t, env, "w-1")
+	avdDir := filepath.Join(env.AVDHome, "w-1.avd")
+	rec := instanceRecord{Serial: "emulator-5590", PID: os.Getpid(), StartedAt: time.Now()}
+	recordInstance(avdDir, rec)
+	recordRun(env, avdDir, rec)
+
+	if err := StopBySerial(env, "emulator-5590"); err == nil {
+		t.Fatal("expected the stop to fail with adb failing")
+	}
+	runs := readRunHistory(avdDir)
+	if len(runs) != 1 || runs[0].EndedAt != nil || runs[0].Outcome != "" {
+		t.Fatalf("runs = %+v, want the run still open", runs)
+	}
+	events, err := readUsageLedger(env)
+	if err != nil {
+		t.Fatalf("readUsageLedger: %v", err)
+	}
+	for _, ev := range events {
+		if ev.Kind == usageStop {
+			t.Fatalf("usage ledger has a stop for a failed stop: %+v", events)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// StorageInfo describes the filesystem backing an avdctl directory and what it can safely do.
+type StorageInfo struct {
+	Path     string `json:"path"`
+	FSType   string `json:"fs_type"`
+	Shared   bool   `json:"shared"`   // network or shared filesystem (NFS, SMB, CephFS, 9p, FUSE)
+	Symlinks bool   `json:"symlinks"` // absolute symlinks resolve the same way on every host
+	Locking  bool   `json:"locking"`  // advisory file locks are reliable; otherwise lockfiles are used
+}
+
+// Filesystem magic numbers as reported by statfs(2) on Linux.
+var fsTypeNames = map[int64]string{
+	0x6969:     "nfs",
+	0xFF534D42: "cifs",
+	0xFE534D42: "smb2",
+	0x517B:     "smb",
+	0x00C36400: "ceph",
+	0x01021997: "9p",
+	0x65735546: "fuse",
+	0xEF53:     "ext4",
+	0x58465342: "xfs",
+	0x9123683E: "btrfs",
+	0x01021994: "tmpfs",
+	0x794C7630: "overlay",
+	0x2FC12FC1: "zfs",
+	0x5346544E: "ntfs",
+	0x4D44:     "vfat",
+}
+
+var sharedFSTypes = map[string]bool{
+	"nfs":  true,
+	"cifs": true,
+	"smb2": true,
+	"smb":  true,
+	"ceph": true,
+	"9p":   true,
+	"fuse": true,
+}
+
+// detectStorageFn is swapped in tests to simulate shared storage.
+var detectStorageFn = DetectStorage
+
+// DetectStorage reports the filesystem type and capabilities of path.
+// If path does not exist yet, the nearest existing parent directory is inspected.
+func DetectStorage(path string) (StorageInfo, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return StorageInfo{}, fmt.Errorf("resolve %s: %w", path, err)
+	}
+	probe := abs
+	for {
+		if _, err := os.Stat(probe); err == nil {
+			break
+		}
+		parent := filepath.Dir(probe)
+		if parent == probe {
+			break
+		}
+		probe = parent
+	}
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(probe, &st); err != nil {
+		return StorageInfo{}, fmt.Errorf("statfs %s: %w", probe, err)
+	}
+	fsType := fsTypeName(int64(st.Type))
+	shared := sharedFSTypes[fsType]
+	return StorageInfo{
+		Path:     abs,
+		FSType:   fsType,
+		Shared:   shared,
+		Symlinks: !shared,
+		Locking:  !shared,
+	}, nil
+}
+
+func fsTypeName(magic int64) string {
+	if name, ok := fsTypeNames[magic]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%x", magic)
+}
+
+// StorageReport returns storage information for AVDHome and GoldenDir.
+func StorageReport(env Env) ([]StorageInfo, error) {
+	var out []StorageInfo
+	for _, dir := range []string{env.AVDHome, env.GoldenDir} {
+		if dir == "" {
+			continue
+		}
+		info, err := DetectStorage(dir)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, info)
+	}
+	return out, nil
+}
+
+// copyFile copies src to dst (reflinked or streamed, holes preserved; see copyFileContents),
+// creating dst with perm.
+func copyFile(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if err := copyFileContents(out, in, imageCopy{}); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,65 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestDetectStorageLocalTempDir(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("filesystem magic numbers are Linux-specific")
+	}
+	dir := t.TempDir()
+	info, err := DetectStorage(filepath.Join(dir, "not", "created", "yet"))
+	if err != nil {
+		t.Fatalf("DetectStorage: %v", err)
+	}
+	if info.FSType == "" {
+		t.Fatal("expected filesystem type")
+	}
+	if info.Path != filepath.Join(dir, "not", "created", "yet") {
+		t.Fatalf("unexpected path %q", info.Path)
+	}
+}
+
+func TestFSTypeName(t *testing.T) {
+	if got := fsTypeName(0x6969); got != "nfs" {
+		t.Fatalf("fsTypeName(nfs) = %q", got)
+	}
+	if got := fsTypeName(0x1234); got != "0x1234" {
+		t.Fatalf("fsTypeName(unknown) = %q", got)
+	}
+	if !sharedFSTypes["nfs"] || sharedFSTypes["ext4"] {
+		t.Fatal("unexpected shared filesystem classification")
+	}
+}
+
+func TestCloneFromGoldenCopiesOnSharedStorage(t *testing.T) {
+	orig := detectStorageFn
+	detectStorageFn = func(path string) (StorageInfo, error) {
+		return StorageInfo{Path: path, FSType: "nfs", Shared: true}, nil
+	}
+	t.Cleanup(func() { detectStorageFn = orig })
+
+	env := newTestEnv(t)
+	makeBaseAVD(t, env, "base-a35")
+	kernel := filepath.Join(env.AVDHome, "base-a35.avd", "kernel-ranchu")
+	if err := os.WriteFile(kernel, []byte("kernel"), 0o644); err != nil {
+		t.Fatalf("write kernel: %v", err)
+	}
+	if _, err := CloneFromGolden(env, "base-a35", "w-shared", makeGoldenDir(t)); err != nil {
+		t.Fatalf("CloneFromGolden: %v", err)
+	}
+	st, err := os.Lstat(filepath.Join(env.AVDHome, "w-shared.avd", "kernel-ranchu"))
+	if err != nil {
+		t.Fatalf("lstat clone kernel: %v", err)
+	}
+	if st.Mode()&os.ModeSymlink != 0 {
+		t.Fatal("expected kernel to be copied, not symlinked, on shared storage")
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// cloneOptionsFilename keeps, in the clone directory, the CloneOptions the clone was made with,
+// so a rollout re-clones it the same way. ConfigValuesFile is folded into ConfigVars.
+const cloneOptionsFilename = "avdctl-clone-options.json"
+
+type savedCloneOptions struct {
+	ConfigVars map[string]string `json:"config_vars,omitempty"`
+	Links      LinkPolicy        `json:"links"`
+}
+
+// CloneOptions customizes CloneFromGoldenWithOptions.
+type CloneOptions struct {
+	// ConfigVars are exposed to a Go-template AVDCTL_CONFIG_TEMPLATE (e.g. {{.RAM}}).
+	// They override values read from ConfigValuesFile.
+	ConfigVars map[string]string
+	// ConfigValuesFile is an optional per-clone KEY=VALUE file merged into the template variables.
+	ConfigValuesFile string
+	// Links selects symlink, hardlink or copy per base artifact (default: symlink everything).
+	Links LinkPolicy
+	// Progress, if set, reports the copy of each golden image.
+	Progress CopyProgressFunc
+}
+
+// configTemplateData builds template variables for a clone. Name, Base and Golden are always set;
+// RAM, Cores, Density and Port are conventional keys supplied by the caller (--var, --values).
+// The shipped config.ini.tpl uses e.g. {{default "4" .Cores}}. Actions are rendered in
+// "#" comments too, so template comments must not contain any.
+func configTemplateData(base, name, golden string, opts CloneOptions) (map[string]string, error) {
+	opts, err := effectiveCloneOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	data := opts.ConfigVars
+	data["Name"] = name
+	data["Base"] = base
+	data["Golden"] = golden
+	return data, nil
+}
+
+// renderConfigTemplate renders tpl as a Go template. Missing variables render as empty strings;
+// use {{default "4096" .RAM}} to provide fallbacks.
+func renderConfigTemplate(tpl []byte, data map[string]string) ([]byte, error) {
+	t, err := template.New("config.ini").
+		Option("missingkey=zero").
+		Funcs(template.FuncMap{
+			"default": func(def, value string) string {
+				if strings.TrimSpace(value) == "" {
+					return def
+				}
+				return value
+			},
+		}).
+		Parse(string(tpl))
+	if err != nil {
+		return nil, fmt.Errorf("parse config template: %w", err)
+	}
+	var out bytes.Buffer
+	if err := t.Execute(&out, data); err != nil {
+		return nil, fmt.Errorf("render config template: %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+// effectiveCloneOptions returns opts with ConfigValuesFile read into ConfigVars, under the
+// values ConfigVars sets itself: the variables the template sees and the clone records.
+func effectiveCloneOptions(opts CloneOptions) (CloneOptions, error) {
+	vars := map[string]string{}
+	if opts.ConfigValuesFile != "" {
+		values, err := readValuesFile(opts.ConfigValuesFile)
+		if err != nil {
+			return CloneOptions{}, err
+		}
+		for k, v := range values {
+			vars[k] = v
+		}
+	}
+	for k, v := range opts.ConfigVars {
+		vars[k] = v
+	}
+	opts.ConfigVars, opts.ConfigValuesFile = vars, ""
+	return opts, nil
+}
+
+func writeCloneOptions(cloneDir string, opts CloneOptions) error {
+	opts, err := effectiveCloneOptions(opts)
+	if err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(savedCloneOptions{ConfigVars: opts.ConfigVars, Links: opts.Links}, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(cloneDir, cloneOptionsFilename)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(b, '\n'), 0o644); err != nil {
+		return fmt.Errorf("write clone options: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// readCloneOptions returns the options the clone in cloneDir was made with.
+func readCloneOptions(cloneDir string) (CloneOptions, error) {
+	b, err := os.ReadFile(filepath.Join(cloneDir, cloneOptionsFilename))
+	if err != nil {
+		return CloneOptions{}, fmt.Errorf("read clone options: %w", err)
+	}
+	var saved savedCloneOptions
+	if err := json.Unmarshal(b, &saved); err != nil {
+		return CloneOptions{}, fmt.Errorf("parse clone options: %w", err)
+	}
+	return effectiveCloneOptions(CloneOptions{ConfigVars: saved.ConfigVars, Links: saved.Links})
+}
+
+// readValuesFile parses KEY=VALUE lines; blank lines and lines starting with # are ignored.
+func readValuesFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("read values file: %w", err)
+	}
+	defer f.Close()
+	values := map[string]string{}
+	scanner := bufio.NewScanner(f)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("values file %s:%d: expected KEY=VALUE", path, lineNo)
+		}
+		values[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read values file: %w", err)
+	}
+	return values, nil
+}
@@ -0,0 +1,95 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRenderConfigTemplateWithDefaults(t *testing.T) {
+	tpl := []byte("avd.name={{.Name}}\nhw.ramSize={{default \"1536M\" .RAM}}\nhw.cpu.ncore={{default \"4\" .Cores}}\n")
+	out, err := renderConfigTemplate(tpl, map[string]string{"Name": "w-acme", "RAM": "4096M"})
+	if err != nil {
+		t.Fatalf("renderConfigTemplate: %v", err)
+	}
+	want := "avd.name=w-acme\nhw.ramSize=4096M\nhw.cpu.ncore=4\n"
+	if string(out) != want {
+		t.Fatalf("rendered = %q, want %q", out, want)
+	}
+}
+
+func TestShippedConfigTemplateRendersOnlySettings(t *testing.T) {
+	tpl, err := os.ReadFile(filepath.Join("..", "..", "config.ini.tpl"))
+	if err != nil {
+		t.Fatalf("read config.ini.tpl: %v", err)
+	}
+	out, err := renderConfigTemplate(tpl, map[string]string{"Name": "w-acme", "Base": "base-a35", "Golden": "/golden"})
+	if err != nil {
+		t.Fatalf("renderConfigTemplate: %v", err)
+	}
+	if strings.Contains(string(out), "w-acme") || strings.Contains(string(out), "base-a35") {
+		t.Fatalf("template comments rendered clone variables:\n%s", out)
+	}
+	if !strings.Contains(string(out), "hw.cpu.ncore=4\n") {
+		t.Fatal("expected default core count in rendered config")
+	}
+}
+
+func TestConfigTemplateDataMergesValuesFile(t *testing.T) {
+	values := filepath.Join(t.TempDir(), "w-acme.values")
+	body := "# per-clone overrides\nRAM=2048M\nDensity = 320\n"
+	if err := os.WriteFile(values, []byte(body), 0o644); err != nil {
+		t.Fatalf("write values: %v", err)
+	}
+	data, err := configTemplateData("base-a35", "w-acme", "/golden", CloneOptions{
+		ConfigValuesFile: values,
+		ConfigVars:       map[string]string{"RAM": "3072M", "Name": "ignored"},
+	})
+	if err != nil {
+		t.Fatalf("configTemplateData: %v", err)
+	}
+	if data["RAM"] != "3072M" || data["Density"] != "320" {
+		t.Fatalf("unexpected merged vars: %#v", data)
+	}
+	if data["Name"] != "w-acme" || data["Base"] != "base-a35" {
+		t.Fatalf("built-in vars must win: %#v", data)
+	}
+}
+
+func TestReadValuesFileRejectsMalformedLine(t *testing.T) {
+	values := filepath.Join(t.TempDir(), "bad.values")
+	if err := os.WriteFile(values, []byte("RAM\n"), 0o644); err != nil {
+		t.Fatalf("write values: %v", err)
+	}
+	if _, err := readValuesFile(values); err == nil || !strings.Contains(err.Error(), "expected KEY=VALUE") {
+		t.Fatalf("expected malformed line error, got %v", err)
+	}
+}
+
+func TestCloneFromGoldenRendersTemplate(t *testing.T) {
+	env := newTestEnv(t)
+	makeBaseAVD(t, env, "base-a35")
+	tpl := filepath.Join(t.TempDir(), "config.ini.tpl")
+	if err := os.WriteFile(tpl, []byte("hw.ramSize={{default \"1536M\" .RAM}}\nhw.lcd.density={{.Density}}\n"), 0o644); err != nil {
+		t.Fatalf("write template: %v", err)
+	}
+	t.Setenv("AVDCTL_CONFIG_TEMPLATE", tpl)
+
+	opts := CloneOptions{ConfigVars: map[string]string{"Density": "320"}}
+	if _, err := CloneFromGoldenWithOptions(env, "base-a35", "w-tpl", makeGoldenDir(t), opts); err != nil {
+		t.Fatalf("CloneFromGoldenWithOptions: %v", err)
+	}
+	cfg, err := os.ReadFile(filepath.Join(env.AVDHome, "w-tpl.avd", "config.ini"))
+	if err != nil {
+		t.Fatalf("read clone config: %v", err)
+	}
+	for _, needle := range []string{"hw.ramSize=1536M", "hw.lcd.density=320"} {
+		if !strings.Contains(string(cfg), needle) {
+			t.Fatalf("clone config missing %q:\n%s", needle, cfg)
+		}
+	}
+}
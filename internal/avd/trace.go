@@ -0,0 +1,167 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// guestTracePath is where the avdctl perfetto session writes in the guest. There is at most
+// one avdctl trace per emulator, so StopTrace needs nothing but the serial.
+const guestTracePath = "/data/misc/perfetto-traces/avdctl.perfetto-trace"
+
+// DefaultTraceDuration bounds a trace that is never stopped.
+const DefaultTraceDuration = 10 * time.Minute
+
+// defaultTraceConfig records scheduling, CPU frequency/idle, process stats and the framework
+// atrace categories that matter for slow boots and janky clones.
+const defaultTraceConfig = `buffers: { size_kb: 65536 fill_policy: RING_BUFFER }
+buffers: { size_kb: 4096 fill_policy: RING_BUFFER }
+data_sources: {
+  config {
+    name: "linux.ftrace"
+    target_buffer: 0
+    ftrace_config {
+      ftrace_events: "sched/sched_switch"
+      ftrace_events: "sched/sched_wakeup"
+      ftrace_events: "power/cpu_frequency"
+      ftrace_events: "power/cpu_idle"
+      atrace_categories: "am"
+      atrace_categories: "wm"
+      atrace_categories: "gfx"
+      atrace_categories: "view"
+      atrace_categories: "dalvik"
+      atrace_categories: "pm"
+      atrace_categories: "ss"
+    }
+  }
+}
+data_sources: {
+  config {
+    name: "linux.process_stats"
+    target_buffer: 1
+    process_stats_config { scan_all_processes_on_start: true }
+  }
+}
+`
+
+// TraceConfig selects what a perfetto trace records.
+type TraceConfig struct {
+	// Config is a perfetto text-format TraceConfig; empty uses a default covering scheduling,
+	// CPU frequency and the am/wm/gfx/view/dalvik/pm/ss atrace categories. Its duration_ms,
+	// if any, is replaced by Duration.
+	Config string
+	// Duration stops the trace on its own if StopTrace is not called (default: DefaultTraceDuration).
+	Duration time.Duration
+}
+
+func (c TraceConfig) text() string {
+	text := c.Config
+	if strings.TrimSpace(text) == "" {
+		text = defaultTraceConfig
+	}
+	duration := c.Duration
+	if duration <= 0 {
+		duration = DefaultTraceDuration
+	}
+	var b strings.Builder
+	for _, line := range strings.Split(text, "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), "duration_ms:") {
+			b.WriteString(line)
+			b.WriteByte('\n')
+		}
+	}
+	fmt.Fprintf(&b, "duration_ms: %d\n", duration.Milliseconds())
+	return b.String()
+}
+
+// StartTrace starts a background perfetto session on serial. It waits for adb to see the
+// emulator and for traced to accept sessions, so it can be called right after start to cover
+// most of the boot. Only one avdctl trace can run per emulator.
+func StartTrace(env Env, serial string, cfg TraceConfig) error {
+	_, span := startSpan(env, "avd.StartTrace", attribute.String("serial", serial))
+	defer span.End()
+	if err := waitForEmulatorSerial(env, serial, 2*time.Minute); err != nil {
+		recordSpanError(span, err)
+		return err
+	}
+	if pid, _ := tracePID(env, serial); pid != 0 {
+		err := fmt.Errorf("a trace is already running on %s (perfetto pid %d); stop it first", serial, pid)
+		recordSpanError(span, err)
+		return err
+	}
+	config := cfg.text()
+	var lastErr error
+	// adbd may still be offline and traced not yet up early in boot; retry until the session starts.
+	for deadline := time.Now().Add(90 * time.Second); ; {
+		out, errOut, err := runCommandOutputWithEnv(env.Context, nil, strings.NewReader(config), env.ADB,
+			"-s", serial, "shell", "perfetto", "--background", "--txt", "-c", "-", "-o", guestTracePath)
+		if err == nil {
+			logEvent(env, "trace started", "serial", serial, "pid", strings.TrimSpace(out), "path", guestTracePath)
+			return nil
+		}
+		lastErr = fmt.Errorf("start perfetto on %s: %w: %s", serial, err, strings.TrimSpace(errOut+out))
+		if time.Now().After(deadline) || (env.Context != nil && env.Context.Err() != nil) {
+			break
+		}
+		time.Sleep(time.Second)
+	}
+	recordSpanError(span, lastErr)
+	return lastErr
+}
+
+// StopTrace ends the avdctl trace on serial and pulls it to dest (a file, or a directory that
+// gets <serial>-<timestamp>.perfetto-trace). It returns the local path. A trace that already
+// stopped on its own (Duration elapsed) is still retrieved.
+func StopTrace(env Env, serial, dest string) (string, error) {
+	_, span := startSpan(env, "avd.StopTrace", attribute.String("serial", serial))
+	defer span.End()
+	if pid, _ := tracePID(env, serial); pid != 0 {
+		_, _ = runCommandCombinedOutputWithEnv(env.Context, nil, nil, env.ADB, "-s", serial, "shell", "kill", "-TERM", strconv.Itoa(pid))
+		// perfetto flushes its buffers into the output file before exiting.
+		for deadline := time.Now().Add(30 * time.Second); time.Now().Before(deadline); time.Sleep(500 * time.Millisecond) {
+			if pid, _ := tracePID(env, serial); pid == 0 {
+				break
+			}
+		}
+	}
+	if st, err := os.Stat(dest); err == nil && st.IsDir() {
+		dest = filepath.Join(dest, fmt.Sprintf("%s-%s.perfetto-trace", serial, time.Now().UTC().Format("20060102T150405Z")))
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		recordSpanError(span, err)
+		return "", err
+	}
+	if out, err := runCommandCombinedOutputWithEnv(env.Context, nil, nil, env.ADB, "-s", serial, "pull", guestTracePath, dest); err != nil {
+		err = fmt.Errorf("pull trace from %s: %w: %s", serial, err, strings.TrimSpace(string(out)))
+		recordSpanError(span, err)
+		return "", err
+	}
+	_, _ = runCommandCombinedOutputWithEnv(env.Context, nil, nil, env.ADB, "-s", serial, "shell", "rm", "-f", guestTracePath)
+	logEvent(env, "trace retrieved", "serial", serial, "path", dest)
+	return dest, nil
+}
+
+// tracePID returns the pid of the perfetto process writing guestTracePath, or 0.
+func tracePID(env Env, serial string) (int, error) {
+	// The bracket keeps the pattern from matching the shell that runs pgrep.
+	out, _, err := runCommandOutputWithEnv(env.Context, nil, nil, env.ADB, "-s", serial, "shell", "pgrep", "-f",
+		"'perfetto-traces/[a]vdctl.perfetto-trace'")
+	if err != nil {
+		return 0, err // pgrep exits 1 when nothing matches
+	}
+	for _, field := range strings.Fields(out) {
+		if pid, err := strconv.Atoi(field); err == nil {
+			return pid, nil
+		}
+	}
+	return 0, nil
+}
@@ -0,0 +1,86 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeTraceADBStub fakes adb for a running perfetto session while the "running" file exists.
+func writeTraceADBStub(t *testing.T, env Env) (dir string) {
+	t.Helper()
+	dir = t.TempDir()
+	script := `#!/bin/sh
+echo "$@" >> ` + dir + `/adb.log
+case "$*" in
+  devices) printf 'List of devices attached\nemulator-5580\tdevice\n' ;;
+  *pgrep*) [ -f ` + dir + `/running ] || exit 1; echo 4242 ;;
+  *kill*) rm -f ` + dir + `/running ;;
+  *"perfetto --background"*) cat > ` + dir + `/config.pbtxt; echo 4242 ;;
+  *" pull "*) echo trace > "$5" ;;
+esac
+`
+	if err := os.WriteFile(env.ADB, []byte(script), 0o755); err != nil {
+		t.Fatalf("write adb stub: %v", err)
+	}
+	return dir
+}
+
+func TestTraceConfigReplacesDuration(t *testing.T) {
+	text := TraceConfig{Config: "buffers: { size_kb: 1024 }\nduration_ms: 5000\n", Duration: 90 * time.Second}.text()
+	if strings.Contains(text, "duration_ms: 5000") || !strings.HasSuffix(text, "duration_ms: 90000\n") {
+		t.Fatalf("unexpected config:\n%s", text)
+	}
+	if !strings.Contains(TraceConfig{}.text(), `name: "linux.ftrace"`) {
+		t.Fatal("empty config should use the default data sources")
+	}
+}
+
+func TestStartTracePipesConfig(t *testing.T) {
+	env := newTestEnv(t)
+	dir := writeTraceADBStub(t, env)
+	if err := StartTrace(env, "emulator-5580", TraceConfig{Duration: time.Minute}); err != nil {
+		t.Fatalf("StartTrace: %v", err)
+	}
+	cfg, err := os.ReadFile(filepath.Join(dir, "config.pbtxt"))
+	if err != nil || !strings.Contains(string(cfg), "duration_ms: 60000") {
+		t.Fatalf("perfetto config = %q, %v", cfg, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "running"), nil, 0o644); err != nil {
+		t.Fatalf("mark running: %v", err)
+	}
+	if err := StartTrace(env, "emulator-5580", TraceConfig{}); err == nil || !strings.Contains(err.Error(), "already running") {
+		t.Fatalf("expected already running error, got %v", err)
+	}
+}
+
+func TestStopTraceStopsAndPulls(t *testing.T) {
+	env := newTestEnv(t)
+	dir := writeTraceADBStub(t, env)
+	if err := os.WriteFile(filepath.Join(dir, "running"), nil, 0o644); err != nil {
+		t.Fatalf("mark running: %v", err)
+	}
+	out := t.TempDir()
+	path, err := StopTrace(env, "emulator-5580", out)
+	if err != nil {
+		t.Fatalf("StopTrace: %v", err)
+	}
+	if filepath.Dir(path) != out || !strings.HasPrefix(filepath.Base(path), "emulator-5580-") {
+		t.Fatalf("unexpected trace path %q", path)
+	}
+	if b, err := os.ReadFile(path); err != nil || string(b) != "trace\n" {
+		t.Fatalf("trace = %q, %v", b, err)
+	}
+	calls, _ := os.ReadFile(filepath.Join(dir, "adb.log"))
+	for _, needle := range []string{"shell kill -TERM 4242", "shell rm -f " + guestTracePath} {
+		if !strings.Contains(string(calls), needle) {
+			t.Fatalf("adb calls missing %q:\n%s", needle, calls)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// DefaultPushDir is where PushFiles puts files when no destination is given; the Files app
+// shows it as Downloads.
+const DefaultPushDir = "/sdcard/Download"
+
+// PushFiles copies host files into dir on serial (default DefaultPushDir) and asks the media
+// scanner to index them, so they show up in pickers without a reboot. It returns the guest paths.
+func PushFiles(env Env, serial string, files []string, dir string) ([]string, error) {
+	_, span := startSpan(env, "avd.PushFiles", attribute.String("serial", serial), attribute.Int("files", len(files)))
+	defer span.End()
+	if len(files) == 0 {
+		err := errors.New("no files to push")
+		recordSpanError(span, err)
+		return nil, err
+	}
+	if dir == "" {
+		dir = DefaultPushDir
+	}
+	var pushed []string
+	for _, file := range files {
+		if !fileExists(file) {
+			err := fmt.Errorf("file not found: %s", file)
+			recordSpanError(span, err)
+			return pushed, err
+		}
+		guestPath := path.Join(dir, filepath.Base(file))
+		if out, err := runCommandCombinedOutputWithEnv(env.Context, nil, nil, env.ADB, "-s", serial, "push", file, guestPath); err != nil {
+			err = fmt.Errorf("push %s to %s: %w: %s", file, serial, err, strings.TrimSpace(string(out)))
+			recordSpanError(span, err)
+			return pushed, err
+		}
+		// Best effort: deprecated on recent releases, which rescan on their own.
+		_, _ = runCommandCombinedOutputWithEnv(env.Context, nil, nil, env.ADB, "-s", serial, "shell",
+			"am", "broadcast", "-a", "android.intent.action.MEDIA_SCANNER_SCAN_FILE", "-d", "file://"+guestPath)
+		logEvent(env, "file pushed", "serial", serial, "file", file, "guest_path", guestPath)
+		pushed = append(pushed, guestPath)
+	}
+	return pushed, nil
+}
+
+// TypeText types text into the focused field of serial, e.g. a URL or token copied on the
+// host. Spaces and shell metacharacters are escaped; non-ASCII text is not supported by
+// input text.
+func TypeText(env Env, serial, text string) error {
+	_, span := startSpan(env, "avd.TypeText", attribute.String("serial", serial))
+	defer span.End()
+	if text == "" {
+		err := errors.New("no text to type")
+		recordSpanError(span, err)
+		return err
+	}
+	// input text reads %s as a space; the single quotes protect the rest from the guest shell.
+	arg := "'" + strings.ReplaceAll(strings.ReplaceAll(text, " ", "%s"), "'", `'\''`) + "'"
+	out, err := runCommandCombinedOutputWithEnv(env.Context, nil, nil, env.ADB, "-s", serial, "shell", "input", "text", arg)
+	if err != nil {
+		err = fmt.Errorf("type text on %s: %w: %s", serial, err, strings.TrimSpace(string(out)))
+	}
+	recordSpanError(span, err)
+	return err
+}
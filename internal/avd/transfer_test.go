@@ -0,0 +1,43 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPushFilesAndTypeText(t *testing.T) {
+	env := newTestEnv(t)
+	logPath := filepath.Join(t.TempDir(), "adb.log")
+	if err := os.WriteFile(env.ADB, []byte("#!/bin/sh\necho \"$@\" >> "+logPath+"\n"), 0o755); err != nil {
+		t.Fatalf("write adb stub: %v", err)
+	}
+	pdf := filepath.Join(t.TempDir(), "statement.pdf")
+	if err := os.WriteFile(pdf, []byte("%PDF"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	pushed, err := PushFiles(env, "emulator-5580", []string{pdf}, "")
+	if err != nil || len(pushed) != 1 || pushed[0] != "/sdcard/Download/statement.pdf" {
+		t.Fatalf("PushFiles = %v, %v", pushed, err)
+	}
+	if err := TypeText(env, "emulator-5580", "it's a token"); err != nil {
+		t.Fatalf("TypeText: %v", err)
+	}
+	log, _ := os.ReadFile(logPath)
+	for _, want := range []string{
+		"-s emulator-5580 push " + pdf + " /sdcard/Download/statement.pdf\n",
+		"MEDIA_SCANNER_SCAN_FILE -d file:///sdcard/Download/statement.pdf\n",
+		`-s emulator-5580 shell input text 'it'\''s%sa%stoken'` + "\n",
+	} {
+		if !strings.Contains(string(log), want) {
+			t.Fatalf("adb calls %q missing %q", log, want)
+		}
+	}
+	if _, err := PushFiles(env, "emulator-5580", []string{filepath.Join(t.TempDir(), "missing.pdf")}, ""); err == nil {
+		t.Fatal("expected missing file error")
+	}
+}
@@ -0,0 +1,271 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// DefaultTrashRetention is how long deleted AVDs and goldens are kept when
+// Env.TrashRetention is not set.
+const DefaultTrashRetention = 7 * 24 * time.Hour
+
+// trashDirname is the trash directory created in Env.AVDHome (for AVDs) and Env.GoldenDir
+// (for goldens), so moving an item there is a rename on the same filesystem.
+const trashDirname = ".avdctl-trash"
+
+const trashEntryFilename = "avdctl-trash.json"
+
+// Trash entry kinds.
+const (
+	TrashAVD    = "avd"
+	TrashGolden = "golden"
+)
+
+// TrashEntry is a deleted AVD or golden that Restore can bring back until it expires.
+type TrashEntry struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Kind      string    `json:"kind"` // avd or golden
+	Path      string    `json:"path"` // where Restore puts it back
+	DeletedAt time.Time `json:"deleted_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	SizeBytes int64     `json:"size_bytes"`
+	dir       string
+}
+
+// ParseRetention parses a trash retention such as "72h", "7d" or "off" (also "0"), which
+// disables the trash. "off" yields a negative duration.
+func ParseRetention(s string) (time.Duration, error) {
+	v := strings.ToLower(strings.TrimSpace(s))
+	switch v {
+	case "off", "0":
+		return -1, nil
+	}
+	if days, ok := strings.CutSuffix(v, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid retention %q (want e.g. 7d, 72h or off)", s)
+		}
+		if n == 0 {
+			return -1, nil
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid retention %q (want e.g. 7d, 72h or off)", s)
+	}
+	if d == 0 {
+		return -1, nil
+	}
+	return d, nil
+}
+
+func (env Env) trashRetention() time.Duration {
+	if env.TrashRetention == 0 {
+		return DefaultTrashRetention
+	}
+	return env.TrashRetention
+}
+
+func (env Env) trashEnabled() bool { return env.trashRetention() > 0 }
+
+// trashDirs returns the trash directories for AVDs and for goldens; they are one directory
+// when Env.TrashDir is set.
+func (env Env) trashDirs() []string {
+	if env.TrashDir != "" {
+		return []string{env.TrashDir}
+	}
+	dirs := []string{filepath.Join(env.AVDHome, trashDirname)}
+	if env.GoldenDir != "" {
+		dirs = append(dirs, filepath.Join(env.GoldenDir, trashDirname))
+	}
+	return dirs
+}
+
+// moveToTrash moves paths (the first is the item, the rest travel with it, e.g. the .ini of an
+// AVD) into a new trash entry. Expired entries are purged first.
+func moveToTrash(env Env, kind, name string, paths ...string) (TrashEntry, error) {
+	if _, err := PurgeTrash(env, false); err != nil {
+		logEvent(env, "trash purge failed", "error", err)
+	}
+	root := env.trashDirs()[0]
+	if kind == TrashGolden && env.TrashDir == "" && env.GoldenDir != "" {
+		root = filepath.Join(env.GoldenDir, trashDirname)
+	}
+	now := time.Now().UTC()
+	entry := TrashEntry{
+		ID:        name + "-" + now.Format("20060102T150405.000000000"),
+		Name:      name,
+		Kind:      kind,
+		Path:      paths[0],
+		DeletedAt: now,
+		ExpiresAt: now.Add(env.trashRetention()),
+		SizeBytes: dirSize(paths[0]),
+	}
+	entry.dir = filepath.Join(root, entry.ID)
+	if err := os.MkdirAll(entry.dir, 0o755); err != nil {
+		return TrashEntry{}, fmt.Errorf("create trash entry: %w", err)
+	}
+	if err := writeTrashEntry(entry); err != nil {
+		_ = os.RemoveAll(entry.dir)
+		return TrashEntry{}, err
+	}
+	for _, path := range paths {
+		if !fileExists(path) {
+			continue
+		}
+		if err := os.Rename(path, filepath.Join(entry.dir, filepath.Base(path))); err != nil {
+			if errors.Is(err, syscall.EXDEV) {
+				err = fmt.Errorf("%w; set AVDCTL_TRASH_DIR to a directory on the same filesystem", err)
+			}
+			return TrashEntry{}, fmt.Errorf("move %s to trash: %w", path, err)
+		}
+	}
+	logEvent(env, "moved to trash", "kind", kind, "name", name, "id", entry.ID, "expires_at", entry.ExpiresAt)
+	return entry, nil
+}
+
+func writeTrashEntry(entry TrashEntry) error {
+	b, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(entry.dir, trashEntryFilename), append(b, '\n'), 0o644); err != nil {
+		return fmt.Errorf("write trash entry: %w", err)
+	}
+	return nil
+}
+
+// ListTrash returns the entries of the trash, most recently deleted first.
+func ListTrash(env Env) ([]TrashEntry, error) {
+	var entries []TrashEntry
+	seen := map[string]bool{}
+	for _, root := range env.trashDirs() {
+		if seen[root] {
+			continue
+		}
+		seen[root] = true
+		dirs, err := os.ReadDir(root)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read trash: %w", err)
+		}
+		for _, d := range dirs {
+			dir := filepath.Join(root, d.Name())
+			b, err := os.ReadFile(filepath.Join(dir, trashEntryFilename))
+			if err != nil {
+				continue
+			}
+			var entry TrashEntry
+			if err := json.Unmarshal(b, &entry); err != nil {
+				continue
+			}
+			entry.dir = dir
+			entries = append(entries, entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].DeletedAt.After(entries[j].DeletedAt) })
+	return entries, nil
+}
+
+// Restore moves the most recently deleted AVD or golden called ref (or the entry with ID ref)
+// back to where it was. It fails when something with that name exists again.
+func Restore(env Env, ref string) (TrashEntry, error) {
+	_, span := startSpan(env, "avd.Restore", attribute.String("ref", ref))
+	defer span.End()
+	fail := func(err error) (TrashEntry, error) {
+		recordSpanError(span, err)
+		return TrashEntry{}, err
+	}
+	entries, err := ListTrash(env)
+	if err != nil {
+		return fail(err)
+	}
+	var entry *TrashEntry
+	for i := range entries {
+		if entries[i].ID == ref || entries[i].Name == ref {
+			entry = &entries[i]
+			break
+		}
+	}
+	if entry == nil {
+		return fail(fmt.Errorf("%s is not in the trash", ref))
+	}
+	item := filepath.Join(entry.dir, filepath.Base(entry.Path))
+	var moves [][2]string
+	moves = append(moves, [2]string{item, entry.Path})
+	if entry.Kind == TrashAVD {
+		ini := strings.TrimSuffix(entry.Path, ".avd") + ".ini"
+		moves = append(moves, [2]string{filepath.Join(entry.dir, filepath.Base(ini)), ini})
+	}
+	for _, m := range moves {
+		if fileExists(m[0]) && fileExists(m[1]) {
+			return fail(fmt.Errorf("cannot restore %s: %s exists", entry.Name, m[1]))
+		}
+	}
+	for _, m := range moves {
+		if !fileExists(m[0]) {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(m[1]), 0o755); err != nil {
+			return fail(err)
+		}
+		if err := os.Rename(m[0], m[1]); err != nil {
+			return fail(fmt.Errorf("restore %s: %w", entry.Name, err))
+		}
+	}
+	_ = os.RemoveAll(entry.dir)
+	logEvent(env, "restored from trash", "kind", entry.Kind, "name", entry.Name, "path", entry.Path)
+	return *entry, nil
+}
+
+// PurgeTrash permanently removes expired trash entries, or all of them, and returns them.
+func PurgeTrash(env Env, all bool) ([]TrashEntry, error) {
+	entries, err := ListTrash(env)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	var purged []TrashEntry
+	for _, entry := range entries {
+		if !all && now.Before(entry.ExpiresAt) {
+			continue
+		}
+		if err := os.RemoveAll(entry.dir); err != nil {
+			return purged, fmt.Errorf("purge %s: %w", entry.ID, err)
+		}
+		purged = append(purged, entry)
+	}
+	if len(purged) > 0 {
+		logEvent(env, "trash purged", "entries", len(purged), "all", all)
+	}
+	return purged, nil
+}
+
+// dirSize is the apparent size of the files under path.
+func dirSize(path string) int64 {
+	var size int64
+	_ = filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err == nil && info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
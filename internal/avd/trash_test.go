@@ -0,0 +1,99 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDeleteMovesToTrashAndRestore(t *testing.T) {
+	env := newTestEnv(t)
+	makeBaseAVD(t, env, "base")
+	ini := filepath.Join(env.AVDHome, "base.ini")
+	if err := os.WriteFile(ini, []byte("path=base.avd\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetStickyPort(env, "base", 5720); err != nil {
+		t.Fatal(err)
+	}
+	if err := Delete(env, "base"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if fileExists(filepath.Join(env.AVDHome, "base.avd")) || fileExists(ini) {
+		t.Fatal("Delete left the AVD in place")
+	}
+	if _, ok := StickyPorts(env)["base"]; ok {
+		t.Fatal("trashed AVD kept its sticky port")
+	}
+	entries, err := ListTrash(env)
+	if err != nil || len(entries) != 1 || entries[0].Name != "base" || entries[0].Kind != TrashAVD {
+		t.Fatalf("ListTrash = %#v, %v", entries, err)
+	}
+	if got := entries[0].ExpiresAt.Sub(entries[0].DeletedAt); got != DefaultTrashRetention {
+		t.Fatalf("retention = %s", got)
+	}
+
+	entry, err := Restore(env, "base")
+	if err != nil || entry.Name != "base" {
+		t.Fatalf("Restore = %#v, %v", entry, err)
+	}
+	if !fileExists(filepath.Join(env.AVDHome, "base.avd", "config.ini")) || !fileExists(ini) {
+		t.Fatal("Restore did not bring back the .avd and .ini")
+	}
+	if entries, _ := ListTrash(env); len(entries) != 0 {
+		t.Fatalf("trash after restore = %#v", entries)
+	}
+	if _, err := Restore(env, "base"); err == nil {
+		t.Fatal("Restore of an empty trash succeeded")
+	}
+}
+
+func TestRestoreRefusesExistingName(t *testing.T) {
+	env := newTestEnv(t)
+	makeBaseAVD(t, env, "base")
+	if err := Delete(env, "base"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	makeBaseAVD(t, env, "base")
+	if _, err := Restore(env, "base"); err == nil {
+		t.Fatal("Restore over an existing AVD succeeded")
+	}
+}
+
+func TestPurgeTrashAndDisabledTrash(t *testing.T) {
+	env := newTestEnv(t)
+	env.TrashRetention = time.Nanosecond
+	makeBaseAVD(t, env, "old")
+	if err := Delete(env, "old"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	time.Sleep(time.Millisecond)
+	purged, err := PurgeTrash(env, false)
+	if err != nil || len(purged) != 1 || purged[0].Name != "old" {
+		t.Fatalf("PurgeTrash = %#v, %v", purged, err)
+	}
+
+	env.TrashRetention = -1
+	makeBaseAVD(t, env, "gone")
+	if err := Delete(env, "gone"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if entries, _ := ListTrash(env); len(entries) != 0 {
+		t.Fatalf("disabled trash kept %#v", entries)
+	}
+}
+
+func TestParseRetention(t *testing.T) {
+	for in, want := range map[string]time.Duration{"7d": 7 * 24 * time.Hour, "36h": 36 * time.Hour, "off": -1, "0": -1} {
+		if got, err := ParseRetention(in); err != nil || got != want {
+			t.Fatalf("ParseRetention(%q) = %s, %v; want %s", in, got, err, want)
+		}
+	}
+	if _, err := ParseRetention("soon"); err == nil {
+		t.Fatal("ParseRetention accepted garbage")
+	}
+}
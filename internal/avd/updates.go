@@ -0,0 +1,47 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// updaterPackages deliver updates to a booted image: the Play Store installs app updates and
+// Google Play system updates (mainline modules).
+var updaterPackages = []string{"com.android.vending"}
+
+// freezeUpdates turns off automatic system updates and disables the updater packages present
+// on serial, so clones stay identical to the golden instead of updating themselves. It returns
+// the commands it ran, to be recorded as customizations.
+func freezeUpdates(env Env, serial string) ([]string, error) {
+	settings := []string{"settings put global ota_disable_automatic_update 1"}
+	var disabled []string
+	for _, pkg := range updaterPackages {
+		if packageInstalled(env, serial, pkg) {
+			settings = append(settings, "pm disable-user --user 0 "+pkg)
+			disabled = append(disabled, pkg)
+		}
+	}
+	if err := applySettings(env, serial, settings); err != nil {
+		return nil, err
+	}
+	out, _, err := runCommandOutputWithEnv(env.Context, nil, nil, env.ADB, "-s", serial, "shell", "settings", "get", "global", "ota_disable_automatic_update")
+	if err != nil || strings.TrimSpace(out) != "1" {
+		return nil, fmt.Errorf("automatic system updates still enabled on %s", serial)
+	}
+	if len(disabled) > 0 {
+		out, _, err := runCommandOutputWithEnv(env.Context, nil, nil, env.ADB, "-s", serial, "shell", "pm", "list", "packages", "-d")
+		if err != nil {
+			return nil, fmt.Errorf("list disabled packages on %s: %w", serial, err)
+		}
+		for _, pkg := range disabled {
+			if !strings.Contains(out+"\n", "package:"+pkg+"\n") {
+				return nil, fmt.Errorf("%s is still enabled on %s", pkg, serial)
+			}
+		}
+	}
+	logEvent(env, "updates frozen", "serial", serial, "disabled", strings.Join(disabled, ","))
+	return settings, nil
+}
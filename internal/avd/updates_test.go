@@ -0,0 +1,56 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// updatesADB installs an adb stub for an image with the Play Store that records its calls and
+// reports the Play Store disabled once pm disable-user ran.
+func updatesADB(t *testing.T, env Env, disableWorks bool) string {
+	t.Helper()
+	logPath := filepath.Join(t.TempDir(), "adb.log")
+	disabled := ""
+	if disableWorks {
+		disabled = "package:com.android.vending"
+	}
+	script := `#!/bin/sh
+echo "$@" >> ` + logPath + `
+case "$*" in
+  *"pm path com.android.vending"*) echo "package:/product/priv-app/Phonesky/Phonesky.apk" ;;
+  *"settings get global ota_disable_automatic_update"*) echo 1 ;;
+  *"pm list packages -d"*) echo "` + disabled + `" ;;
+esac
+`
+	if err := os.WriteFile(env.ADB, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return logPath
+}
+
+func TestFreezeUpdates(t *testing.T) {
+	env := newTestEnv(t)
+	logPath := updatesADB(t, env, true)
+	settings, err := freezeUpdates(env, "emulator-5580")
+	if err != nil {
+		t.Fatalf("freezeUpdates: %v", err)
+	}
+	want := []string{"settings put global ota_disable_automatic_update 1", "pm disable-user --user 0 com.android.vending"}
+	if strings.Join(settings, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("settings = %q, want %q", settings, want)
+	}
+	calls, _ := os.ReadFile(logPath)
+	if !strings.Contains(string(calls), "shell pm disable-user --user 0 com.android.vending") {
+		t.Fatalf("Play Store not disabled:\n%s", calls)
+	}
+
+	updatesADB(t, env, false)
+	if _, err := freezeUpdates(env, "emulator-5580"); err == nil || !strings.Contains(err.Error(), "still enabled") {
+		t.Fatalf("expected a still-enabled error, got %v", err)
+	}
+}
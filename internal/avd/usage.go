@@ -0,0 +1,259 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// usageLedgerFilename is the append-only ledger of boots, stops, disk samples and deletions,
+// under AVDHome/.avdctl, that Usage accounts from.
+const usageLedgerFilename = "usage.jsonl"
+
+// Kinds of usage ledger events.
+const (
+	usageBoot   = "boot"
+	usageStop   = "stop"
+	usageDisk   = "disk" // a clone was created or reset; only its disk is sampled
+	usageDelete = "delete"
+)
+
+type usageEvent struct {
+	Time      time.Time `json:"time"`
+	Kind      string    `json:"kind"`
+	AVD       string    `json:"avd"`
+	Serial    string    `json:"serial,omitempty"`
+	DiskBytes int64     `json:"disk_bytes"` // disk used by the AVD at the time; 0 once deleted
+}
+
+// AVDUsage is what one AVD used over an accounting window (see Usage).
+type AVDUsage struct {
+	AVD           string  `json:"avd"`
+	EmulatorHours float64 `json:"emulator_hours"`
+	DiskGBDays    float64 `json:"disk_gb_days"` // GB (10^9 bytes) allocated, times days
+	Boots         int     `json:"boots"`
+}
+
+// UsageGroup sums the usage of the AVDs sharing a label value (see GroupUsage).
+type UsageGroup struct {
+	Group         string   `json:"group"` // label value; empty for AVDs without the label
+	EmulatorHours float64  `json:"emulator_hours"`
+	DiskGBDays    float64  `json:"disk_gb_days"`
+	Boots         int      `json:"boots"`
+	AVDs          []string `json:"avds"`
+}
+
+var usageMu sync.Mutex
+
+func usageLedgerPath(env Env) string {
+	return filepath.Join(env.AVDHome, ".avdctl", usageLedgerFilename)
+}
+
+// recordUsage appends an event for the AVD name to the usage ledger, with the disk it uses now.
+// Accounting is best effort: a ledger that cannot be written is logged and skipped.
+func recordUsage(env Env, kind, name, serial string) {
+	ev := usageEvent{Time: time.Now().UTC(), Kind: kind, AVD: name, Serial: serial}
+	if kind != usageDelete {
+		ev.DiskBytes = diskUsage(filepath.Join(env.AVDHome, name+".avd"))
+	}
+	b, err := json.Marshal(ev)
+	if err != nil {
+		return
+	}
+	usageMu.Lock()
+	defer usageMu.Unlock()
+	path := usageLedgerPath(env)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		logEvent(env, "usage ledger not written", "path", path, "error", err)
+		return
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		logEvent(env, "usage ledger not written", "path", path, "error", err)
+		return
+	}
+	_, _ = f.Write(append(b, '\n'))
+	_ = f.Close()
+}
+
+func readUsageLedger(env Env) ([]usageEvent, error) {
+	f, err := os.Open(usageLedgerPath(env))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("usage ledger: %w", err)
+	}
+	defer f.Close()
+	var events []usageEvent
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var ev usageEvent
+		if json.Unmarshal(scanner.Bytes(), &ev) == nil && ev.AVD != "" {
+			events = append(events, ev)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("usage ledger: %w", err)
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Time.Before(events[j].Time) })
+	return events, nil
+}
+
+// Usage accounts, per AVD, the emulator-hours, disk GB-days and boots between since and until
+// (now when zero), from the ledger avdctl appends to on every clone, reset, start, stop and
+// delete. An instance that exits without Stop counts until its run ended in the run history (see
+// classifyRuns), or until now while it still runs. Disk is sampled at each of those events and
+// holds until the next sample.
+func Usage(env Env, since, until time.Time) ([]AVDUsage, error) {
+	_, span := startSpan(env, "avd.Usage")
+	defer span.End()
+	if until.IsZero() {
+		until = time.Now().UTC()
+	}
+	events, err := readUsageLedger(env)
+	if err != nil {
+		recordSpanError(span, err)
+		return nil, err
+	}
+	byAVD := map[string][]usageEvent{}
+	for _, ev := range events {
+		byAVD[ev.AVD] = append(byAVD[ev.AVD], ev)
+	}
+	out := make([]AVDUsage, 0, len(byAVD))
+	for name, evs := range byAVD {
+		u := accountAVD(evs, since, until, isAVDRunning(env, name), endedRuns(filepath.Join(env.AVDHome, name+".avd")))
+		u.AVD = name
+		if u.Boots > 0 || u.EmulatorHours > 0 || u.DiskGBDays > 0 {
+			out = append(out, u)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].AVD < out[j].AVD })
+	span.SetAttributes(attribute.Int("avds", len(out)), attribute.Int("events", len(events)))
+	return out, nil
+}
+
+// endedRuns returns the run history of avdDir with the runs that exited without Stop
+// classified and given an end, which is kept so later reports agree.
+func endedRuns(avdDir string) []InstanceRun {
+	runs, changed := classifyRuns(avdDir, readRunHistory(avdDir))
+	if changed {
+		writeRunHistory(avdDir, runs)
+	}
+	return runs
+}
+
+// runEnd returns when the run started last at or before boot on serial ended, per runs.
+func runEnd(runs []InstanceRun, boot time.Time, serial string) (time.Time, bool) {
+	for i := len(runs) - 1; i >= 0; i-- {
+		run := runs[i]
+		if run.StartedAt.After(boot) || (serial != "" && run.Serial != "" && run.Serial != serial) {
+			continue
+		}
+		if run.EndedAt == nil {
+			return time.Time{}, false
+		}
+		return *run.EndedAt, true
+	}
+	return time.Time{}, false
+}
+
+// accountAVD sums the usage of one AVD's events, in time order, over [since, until]. runs is
+// its run history, for when the instances that exited without Stop ended.
+func accountAVD(evs []usageEvent, since, until time.Time, running bool, runs []InstanceRun) AVDUsage {
+	var u AVDUsage
+	overlap := func(start, end time.Time) float64 {
+		if start.Before(since) {
+			start = since
+		}
+		if end.After(until) {
+			end = until
+		}
+		if !end.After(start) {
+			return 0
+		}
+		return end.Sub(start).Seconds()
+	}
+	var open *time.Time
+	var openSerial string
+	var diskFrom time.Time
+	var disk int64
+	for _, ev := range evs {
+		if disk > 0 {
+			u.DiskGBDays += float64(disk) / 1e9 * overlap(diskFrom, ev.Time) / 86400
+		}
+		disk, diskFrom = ev.DiskBytes, ev.Time
+		if ev.Kind == usageDisk {
+			continue
+		}
+		// A stop or delete ends the open session; so does a start, of an instance that exited
+		// without Stop, unless its run history says it ended earlier.
+		if open != nil {
+			end := ev.Time
+			if ended, ok := runEnd(runs, *open, openSerial); ok && ended.Before(end) {
+				end = ended
+			}
+			u.EmulatorHours += overlap(*open, end) / 3600
+			open = nil
+		}
+		if ev.Kind == usageBoot {
+			t := ev.Time
+			open, openSerial = &t, ev.Serial
+			if !t.Before(since) && !t.After(until) {
+				u.Boots++
+			}
+		}
+	}
+	if disk > 0 {
+		u.DiskGBDays += float64(disk) / 1e9 * overlap(diskFrom, until) / 86400
+	}
+	if open != nil {
+		if running {
+			u.EmulatorHours += overlap(*open, until) / 3600
+		} else if ended, ok := runEnd(runs, *open, openSerial); ok {
+			u.EmulatorHours += overlap(*open, ended) / 3600
+		}
+	}
+	return u
+}
+
+// isAVDRunning reports whether the emulator avdctl last started for name is still alive.
+func isAVDRunning(env Env, name string) bool {
+	rec, ok := readInstance(filepath.Join(env.AVDHome, name+".avd"))
+	return ok && rec.PID > 0 && syscall.Kill(rec.PID, 0) == nil && !isZombieProcess(rec.PID)
+}
+
+// GroupUsage sums usage by the value of label key in labels (AVD name to labels, e.g. a fleet
+// file's Labels), sorted by group. AVDs without the label form the "" group.
+func GroupUsage(usage []AVDUsage, labels map[string]map[string]string, key string) []UsageGroup {
+	groups := map[string]*UsageGroup{}
+	for _, u := range usage {
+		value := labels[u.AVD][key]
+		g, ok := groups[value]
+		if !ok {
+			g = &UsageGroup{Group: value}
+			groups[value] = g
+		}
+		g.EmulatorHours += u.EmulatorHours
+		g.DiskGBDays += u.DiskGBDays
+		g.Boots += u.Boots
+		g.AVDs = append(g.AVDs, u.AVD)
+	}
+	out := make([]UsageGroup, 0, len(groups))
+	for _, g := range groups {
+		out = append(out, *g)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Group < out[j].Group })
+	return out
+}
@@ -0,0 +1,158 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAccountAVD(t *testing.T) {
+	day := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	at := func(h int) time.Time { return day.Add(time.Duration(h) * time.Hour) }
+	evs := []usageEvent{
+		{Time: at(-2), Kind: usageBoot, DiskBytes: 1e9}, // started before the window
+		{Time: at(2), Kind: usageStop, DiskBytes: 2e9},
+		{Time: at(10), Kind: usageBoot, DiskBytes: 2e9}, // exited without stop
+		{Time: at(13), Kind: usageBoot, DiskBytes: 2e9},
+		{Time: at(14), Kind: usageStop, DiskBytes: 2e9},
+		{Time: at(20), Kind: usageDelete},
+	}
+	u := accountAVD(evs, day, at(24), false, nil)
+	if u.Boots != 2 || u.EmulatorHours != 2+3+1 {
+		t.Fatalf("usage = %+v, want 2 boots in the window and 6 emulator-hours", u)
+	}
+	// 1 GB for 2h, then 2 GB until the delete at 20h.
+	if want := (1*2 + 2*18) / 24.0; math.Abs(u.DiskGBDays-want) > 1e-9 {
+		t.Fatalf("disk = %f GB-days, want %f", u.DiskGBDays, want)
+	}
+
+	open := accountAVD([]usageEvent{{Time: at(20), Kind: usageBoot, DiskBytes: 1e9}}, day, at(24), true, nil)
+	gone := accountAVD([]usageEvent{{Time: at(20), Kind: usageBoot, DiskBytes: 1e9}}, day, at(24), false, nil)
+	if open.EmulatorHours != 4 || gone.EmulatorHours != 0 {
+		t.Fatalf("open session = %v h, exited one = %v h", open.EmulatorHours, gone.EmulatorHours)
+	}
+
+	// A crashed run counts until its end in the run history, also before a later start.
+	crashedAt := at(21)
+	runs := []InstanceRun{{StartedAt: at(20), Serial: "emulator-5580", EndedAt: &crashedAt, Outcome: FailureCrash}}
+	boot := []usageEvent{{Time: at(20), Kind: usageBoot, Serial: "emulator-5580", DiskBytes: 1e9}}
+	if crashed := accountAVD(boot, day, at(24), false, runs); crashed.EmulatorHours != 1 {
+		t.Fatalf("crashed session = %v h, want 1", crashed.EmulatorHours)
+	}
+	restarted := append(boot, usageEvent{Time: at(23), Kind: usageBoot, Serial: "emulator-5580", DiskBytes: 1e9})
+	if u := accountAVD(restarted, day, at(24), false, runs); u.EmulatorHours != 1 {
+		t.Fatalf("crashed then restarted = %v h, want 1", u.EmulatorHours)
+	}
+
+	// A disk sample charges disk without ending a session.
+	sampled := accountAVD([]usageEvent{
+		{Time: at(12), Kind: usageDisk, DiskBytes: 1e9},
+		{Time: at(18), Kind: usageBoot, DiskBytes: 1e9},
+		{Time: at(20), Kind: usageDisk, DiskBytes: 1e9},
+	}, day, at(24), true, nil)
+	if sampled.EmulatorHours != 6 || sampled.Boots != 1 || math.Abs(sampled.DiskGBDays-0.5) > 1e-9 {
+		t.Fatalf("usage = %+v, want 6 emulator-hours, 1 boot and 0.5 GB-days", sampled)
+	}
+}
+
+func TestUsageChargesNeverStartedClone(t *testing.T) {
+	env := newTestEnv(t)
+	since := time.Now().Add(-time.Minute)
+	makeBaseAVD(t, env, "base-a35")
+	golden := makeGoldenDir(t)
+	if _, err := CloneFromGolden(env, "base-a35", "w-idle", golden); err != nil {
+		t.Fatalf("CloneFromGolden: %v", err)
+	}
+	if _, err := ResetClone(env, "w-idle", golden); err != nil {
+		t.Fatalf("ResetClone: %v", err)
+	}
+	events, err := readUsageLedger(env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0].Kind != usageDisk || events[1].Kind != usageDisk || events[0].DiskBytes == 0 {
+		t.Fatalf("ledger = %+v, want a disk sample at clone and at reset", events)
+	}
+	usage, err := Usage(env, since, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(usage) != 1 || usage[0].AVD != "w-idle" || usage[0].DiskGBDays == 0 || usage[0].Boots != 0 {
+		t.Fatalf("usage = %+v, want disk charged to the never-started clone", usage)
+	}
+}
+
+func TestUsageEndsCrashedRun(t *testing.T) {
+	env := newTestEnv(t)
+	makeBaseAVD(t, env, "w-1")
+	avdDir := filepath.Join(env.AVDHome, "w-1.avd")
+	started := time.Now().Add(-2 * time.Hour).UTC()
+	logPath := filepath.Join(t.TempDir(), "emulator.log")
+	if err := os.WriteFile(logPath, []byte("fatal: device lost\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	crashed := started.Add(30 * time.Minute)
+	if err := os.Chtimes(logPath, crashed, crashed); err != nil {
+		t.Fatal(err)
+	}
+	// The instance is gone: PID 0 never names a live process.
+	writeRunHistory(avdDir, []InstanceRun{{StartedAt: started, Serial: "emulator-5580", LogPath: logPath}})
+	writeLedger(t, env, usageEvent{Time: started.Add(time.Second), Kind: usageBoot, AVD: "w-1", Serial: "emulator-5580", DiskBytes: 1e9})
+
+	usage, err := Usage(env, started.Add(-time.Hour), time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(usage) != 1 || math.Abs(usage[0].EmulatorHours-0.5) > 0.01 {
+		t.Fatalf("usage = %+v, want the crashed run's half hour", usage)
+	}
+	if runs := readRunHistory(avdDir); len(runs) != 1 || runs[0].EndedAt == nil || !runs[0].EndedAt.Equal(crashed.UTC()) {
+		t.Fatalf("runs = %+v, want the crash time kept as the run's end", runs)
+	}
+}
+
+func writeLedger(t *testing.T, env Env, evs ...usageEvent) {
+	t.Helper()
+	path := usageLedgerPath(env)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	var b []byte
+	for _, ev := range evs {
+		line, err := json.Marshal(ev)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b = append(append(b, line...), '\n')
+	}
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUsageLedgerAndGroups(t *testing.T) {
+	env := newTestEnv(t)
+	since := time.Now().Add(-time.Minute)
+	for _, name := range []string{"w-acme-1", "w-acme-2", "w-gino"} {
+		makeBaseAVD(t, env, name)
+		recordUsage(env, usageBoot, name, "emulator-5580")
+		recordUsage(env, usageStop, name, "emulator-5580")
+	}
+	usage, err := Usage(env, since, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(usage) != 3 || usage[0].AVD != "w-acme-1" || usage[0].Boots != 1 || usage[0].DiskGBDays == 0 {
+		t.Fatalf("usage = %+v", usage)
+	}
+	groups := GroupUsage(usage, map[string]map[string]string{"w-acme-1": {"customer": "acme"}, "w-acme-2": {"customer": "acme"}}, "customer")
+	if len(groups) != 2 || groups[0].Group != "" || groups[1].Group != "acme" || groups[1].Boots != 2 || len(groups[1].AVDs) != 2 {
+		t.Fatalf("groups = %+v", groups)
+	}
+}
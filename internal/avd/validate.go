@@ -0,0 +1,141 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// GoldenValidation records a successful boot test of a golden (see ValidateGolden).
+type GoldenValidation struct {
+	ValidatedAt time.Time     `json:"validated_at"`
+	Base        string        `json:"base"`    // base AVD the test clone was made from
+	BootMillis  int64         `json:"boot_ms"` // time from emulator start to boot completed
+	Checks      []HealthCheck `json:"checks"`
+}
+
+// HealthCheck is one post-boot probe of a validation run.
+type HealthCheck struct {
+	Name   string `json:"name"`
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// ValidateGolden clones goldenDir from base (default: the manifest's source AVD) into a
+// temporary AVD, boots it headless, runs the health checks and records the result as
+// manifest.Validation. The temporary clone is deleted either way; on failure the manifest is
+// left without a validation and the error lists the failed checks.
+func ValidateGolden(env Env, base, goldenDir string, bootTimeout time.Duration) (GoldenValidation, error) {
+	_, span := startSpan(env, "avd.ValidateGolden", attribute.String("golden", goldenDir))
+	defer span.End()
+	fail := func(err error) (GoldenValidation, error) {
+		recordSpanError(span, err)
+		return GoldenValidation{}, err
+	}
+	manifest, err := ReadGoldenManifest(goldenDir)
+	if err != nil {
+		return fail(err)
+	}
+	if base == "" {
+		base = manifest.Source
+	}
+	if bootTimeout == 0 {
+		bootTimeout = 3 * time.Minute
+	}
+	name := fmt.Sprintf("avdctl-validate-%d", time.Now().UnixNano())
+	logEvent(env, "golden validation start", "golden", goldenDir, "base", base, "clone", name)
+	if _, err := cloneFromGolden(env, base, name, goldenDir, CloneOptions{}); err != nil {
+		return fail(fmt.Errorf("validation clone: %w", err))
+	}
+	defer func() { _ = DeleteWithOptions(env, name, DeleteOptions{Force: true, SkipTrash: true}) }()
+
+	ensureADB(env)
+	port, err := FindFreeEvenPortWithEnv(env, 5580, 5800)
+	if err != nil {
+		return fail(fmt.Errorf("no free port available for validation: %w", err))
+	}
+	started := time.Now()
+	res, err := StartEmulatorOnPort(env, name, port)
+	if err != nil {
+		return fail(err)
+	}
+	serial, logPath := res.Serial, res.LogPath
+	defer func() {
+		KillEmulator(env, serial)
+		_ = res.Cmd.Process.Kill()
+	}()
+	if err := waitForEmulatorSerial(env, serial, 60*time.Second); err != nil {
+		return fail(fmt.Errorf("validation clone not seen by adb: %w\nEmulator log: %s", err, logPath))
+	}
+	if err := WaitForBoot(env, serial, bootTimeout); err != nil {
+		return fail(fmt.Errorf("validation clone did not boot: %w\nEmulator log: %s", err, logPath))
+	}
+	validation := GoldenValidation{
+		Base:       base,
+		BootMillis: time.Since(started).Milliseconds(),
+		Checks:     runHealthChecks(env, serial),
+	}
+	var failed []string
+	for _, check := range validation.Checks {
+		if !check.OK {
+			failed = append(failed, fmt.Sprintf("%s: %s", check.Name, check.Detail))
+		}
+	}
+	if len(failed) > 0 {
+		return fail(fmt.Errorf("golden %s failed validation:\n  %s", goldenDir, strings.Join(failed, "\n  ")))
+	}
+	validation.ValidatedAt = time.Now().UTC()
+	manifest.Validation = &validation
+	if err := writeGoldenManifest(goldenDir, manifest); err != nil {
+		return fail(err)
+	}
+	logEvent(env, "golden validated", "golden", goldenDir, "boot_ms", validation.BootMillis)
+	return validation, nil
+}
+
+// HealthChecks runs the post-boot probes of ValidateGolden against a booted emulator.
+func HealthChecks(env Env, serial string) []HealthCheck {
+	_, span := startSpan(env, "avd.HealthChecks", attribute.String("serial", serial))
+	defer span.End()
+	checks := runHealthChecks(env, serial)
+	failed := 0
+	for _, check := range checks {
+		if !check.OK {
+			failed++
+		}
+	}
+	span.SetAttributes(attribute.Int("failed", failed))
+	return checks
+}
+
+// runHealthChecks probes a booted emulator: the package manager answers, userdata is
+// writable and a home activity resolves.
+func runHealthChecks(env Env, serial string) []HealthCheck {
+	probe := func(name string, args []string, ok func(string) bool) HealthCheck {
+		out, errOut, err := runCommandOutputWithEnv(env.Context, nil, nil, env.ADB, append([]string{"-s", serial, "shell"}, args...)...)
+		out = strings.TrimSpace(out)
+		if err != nil {
+			return HealthCheck{Name: name, Detail: strings.TrimSpace(fmt.Sprintf("%v: %s %s", err, errOut, out))}
+		}
+		if !ok(out) {
+			return HealthCheck{Name: name, Detail: fmt.Sprintf("unexpected output %q", out)}
+		}
+		return HealthCheck{Name: name, OK: true}
+	}
+	return []HealthCheck{
+		probe("package-manager", []string{"pm", "path", "android"}, func(out string) bool {
+			return strings.HasPrefix(out, "package:")
+		}),
+		probe("userdata-writable", []string{"touch /data/local/tmp/.avdctl-validate && rm /data/local/tmp/.avdctl-validate && echo ok"}, func(out string) bool {
+			return out == "ok"
+		}),
+		probe("home-activity", []string{"cmd", "package", "resolve-activity", "--brief", "-c", "android.intent.category.HOME", "-a", "android.intent.action.MAIN"}, func(out string) bool {
+			return strings.Contains(out, "/")
+		}),
+	}
+}
@@ -0,0 +1,36 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"os"
+	"testing"
+)
+
+func TestRunHealthChecks(t *testing.T) {
+	env := newTestEnv(t)
+	// adb -s SERIAL shell <args>
+	script := `#!/bin/sh
+case "$4" in
+  pm) echo "package:/system/framework/framework-res.apk" ;;
+  touch*) echo ok ;;
+  cmd) echo "$HOME_ACTIVITY" ;;
+esac
+`
+	if err := os.WriteFile(env.ADB, []byte(script), 0o755); err != nil {
+		t.Fatalf("write adb stub: %v", err)
+	}
+	t.Setenv("HOME_ACTIVITY", "com.android.launcher3/.uioverrides.QuickstepLauncher")
+	for _, check := range runHealthChecks(env, "emulator-5580") {
+		if !check.OK {
+			t.Fatalf("check %s failed: %s", check.Name, check.Detail)
+		}
+	}
+
+	t.Setenv("HOME_ACTIVITY", "No activity found")
+	checks := runHealthChecks(env, "emulator-5580")
+	if last := checks[len(checks)-1]; last.Name != "home-activity" || last.OK {
+		t.Fatalf("expected home-activity to fail, got %#v", last)
+	}
+}
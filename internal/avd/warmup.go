@@ -0,0 +1,66 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// CompilerFilters are the ART compiler filters accepted by PrewarmOptions.CompileFilter, from
+// the least to the most compiled.
+var CompilerFilters = []string{"verify", "space-profile", "space", "speed-profile", "speed", "everything-profile", "everything"}
+
+// warmAppTimeout bounds the first launch of each PrewarmOptions.WarmApps package.
+const warmAppTimeout = 2 * time.Minute
+
+// validateWarmUp checks the warm-up options of a prewarm before the emulator starts.
+func validateWarmUp(opts PrewarmOptions) error {
+	if opts.CompileFilter != "" && !slices.Contains(CompilerFilters, opts.CompileFilter) {
+		return fmt.Errorf("unknown compiler filter %q (use one of %s)", opts.CompileFilter, strings.Join(CompilerFilters, ", "))
+	}
+	for _, pkg := range opts.WarmApps {
+		if !packageRe.MatchString(pkg) {
+			return fmt.Errorf("invalid warm app package %q", pkg)
+		}
+	}
+	return nil
+}
+
+// warmUp launches each of apps once, until its first frame, and stops it again, so the
+// profiles ART records on that run go into the golden; then it compiles every package with
+// filter. Clones of the golden then skip the JIT and dexopt work of their first run.
+func warmUp(env Env, serial string, apps []string, filter string) error {
+	for _, pkg := range apps {
+		ready, err := WaitForApp(env, serial, pkg, warmAppTimeout)
+		if err != nil {
+			return fmt.Errorf("warm up %s: %w", pkg, err)
+		}
+		// Let the app write its profile before stopping it.
+		time.Sleep(2 * time.Second)
+		_ = run(env, env.ADB, "-s", serial, "shell", "am", "force-stop", pkg)
+		logEvent(env, "app warmed up", "serial", serial, "package", pkg, "first_frame", ready.Duration.String())
+	}
+	if filter == "" {
+		return nil
+	}
+	start := time.Now()
+	out, err := runCommandCombinedOutputWithEnv(env.Context, nil, nil, env.ADB, "-s", serial, "shell", "cmd", "package", "compile", "-m", filter, "-a")
+	if err != nil {
+		return fmt.Errorf("compile packages with %s on %s: %w: %s", filter, serial, err, firstLine(strings.TrimSpace(string(out))))
+	}
+	// cmd package compile exits 0 and prints "Failure: ..." for packages it could not compile;
+	// those keep running interpreted and JIT-compiled, so they do not fail the prewarm.
+	var failed []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if line = strings.TrimSpace(line); strings.HasPrefix(line, "Failure") {
+			failed = append(failed, line)
+		}
+	}
+	logEvent(env, "packages compiled", "serial", serial, "filter", filter, "duration", time.Since(start).Round(time.Second).String(),
+		"failures", strings.Join(failed, "; "))
+	return nil
+}
@@ -0,0 +1,41 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avd
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestValidateWarmUp(t *testing.T) {
+	if err := validateWarmUp(PrewarmOptions{WarmApps: []string{"com.acme.wallet"}, CompileFilter: "speed-profile"}); err != nil {
+		t.Fatalf("validateWarmUp: %v", err)
+	}
+	if err := validateWarmUp(PrewarmOptions{CompileFilter: "fast"}); err == nil || !strings.Contains(err.Error(), "speed-profile") {
+		t.Fatalf("expected an unknown filter error, got %v", err)
+	}
+	if err := validateWarmUp(PrewarmOptions{WarmApps: []string{"com.acme.wallet; reboot"}}); err == nil {
+		t.Fatal("expected an invalid package error")
+	}
+}
+
+func TestWarmUpLaunchesAppsBeforeCompiling(t *testing.T) {
+	env := newTestEnv(t)
+	logPath := appADB(t, env, "HAS_DRAWN")
+	if err := warmUp(env, "emulator-5596", []string{"com.acme.wallet"}, "speed-profile"); err != nil {
+		t.Fatalf("warmUp: %v", err)
+	}
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := string(data)
+	launch := strings.Index(calls, "shell am start -n com.acme.wallet/.MainActivity")
+	stop := strings.Index(calls, "shell am force-stop com.acme.wallet")
+	compile := strings.Index(calls, "shell cmd package compile -m speed-profile -a")
+	if launch < 0 || stop < launch || compile < stop {
+		t.Fatalf("expected launch, force-stop, then compile:\n%s", calls)
+	}
+}
@@ -0,0 +1,28 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avdmanager
+
+import (
+	"github.com/forkbombeu/avdctl/internal/avd"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// EnableAccessibilityService enables an accessibility service ("package/class" or
+// "package/.Class") on a running emulator, keeping those already enabled, and returns once
+// Android reports it active. To bake it into a golden, use PrewarmOptions.AccessibilityServices.
+func (m *Manager) EnableAccessibilityService(serial, component string) error {
+	if err := m.checkWritable("EnableAccessibilityService"); err != nil {
+		return err
+	}
+	ctx, span := m.startSpan("avdmanager.EnableAccessibilityService", attribute.String("serial", serial), attribute.String("component", component))
+	defer span.End()
+	if m.usesRemote() {
+		_, err := m.runRemote("accessibility", "--serial", serial, component)
+		recordSpanError(span, err)
+		return err
+	}
+	err := avd.EnableAccessibilityService(m.withContext(ctx), serial, component)
+	recordSpanError(span, err)
+	return err
+}
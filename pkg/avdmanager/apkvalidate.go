@@ -0,0 +1,27 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avdmanager
+
+import "github.com/forkbombeu/avdctl/internal/avd"
+
+// APKValidator vets an app before InstallAPK, BakeAPK or Migrate put it on an AVD
+// (Environment.APKValidators). Returning an error refuses the install; each verdict is
+// written to Environment.AuditLog.
+type APKValidator = avd.APKValidator
+
+// APKFile is the file handed to an APKValidator, with its SHA-256 and manifest metadata.
+type APKFile = avd.APKFile
+
+// SignatureValidator accepts APKs signed by one of the given certificate digests (apksigner).
+type SignatureValidator = avd.SignatureValidator
+
+// HashListValidator refuses (or, with Allow, only accepts) files whose SHA-256 is listed.
+type HashListValidator = avd.HashListValidator
+
+// VirusTotalValidator refuses files VirusTotal engines flag as malicious. Only hashes are
+// looked up; nothing is uploaded.
+type VirusTotalValidator = avd.VirusTotalValidator
+
+// AuditEvent is one JSON line of Environment.AuditLog.
+type AuditEvent = avd.AuditEvent
@@ -0,0 +1,42 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avdmanager
+
+import (
+	"time"
+
+	"github.com/forkbombeu/avdctl/internal/avd"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// AppReady reports the launched activity, PID and time to first frame of WaitForApp.
+type AppReady = avd.AppReady
+
+// WaitForApp launches pkg on a booted instance and waits up to timeout (default 1m) until its
+// process is up and its first window is drawn, for orchestration that reports "app ready"
+// rather than "device booted". The app needs no instrumentation; see ReadyBroadcast for a
+// readiness the app decides itself.
+func (m *Manager) WaitForApp(serial, pkg string, timeout time.Duration) (AppReady, error) {
+	if err := m.checkWritable("WaitForApp"); err != nil {
+		return AppReady{}, err
+	}
+	ctx, span := m.startSpan("avdmanager.WaitForApp",
+		attribute.String("serial", serial),
+		attribute.String("package", pkg),
+	)
+	defer span.End()
+	if m.usesRemote() {
+		args := []string{"wait-app", "--serial", serial, "--json"}
+		if timeout > 0 {
+			args = append(args, "--timeout", timeout.String())
+		}
+		var ready AppReady
+		err := m.runRemoteJSON(&ready, append(args, pkg)...)
+		recordSpanError(span, err)
+		return ready, err
+	}
+	ready, err := avd.WaitForApp(m.withContext(ctx), serial, pkg, timeout)
+	recordSpanError(span, err)
+	return ready, err
+}
@@ -0,0 +1,47 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avdmanager
+
+import (
+	"github.com/forkbombeu/avdctl/internal/avd"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// DeviceSpec is the bundletool device specification of an AVD (ABIs, density, locales, SDK).
+type DeviceSpec = avd.DeviceSpec
+
+// DeviceSpec derives the bundletool device spec of AVD name from its config.ini. It is what
+// InstallAPK and BakeAPK pick the splits of .apks and .aab bundles with.
+func (m *Manager) DeviceSpec(name string) (DeviceSpec, error) {
+	_, span := m.startSpan("avdmanager.DeviceSpec", attribute.String("name", name))
+	defer span.End()
+	if m.usesRemote() {
+		var spec DeviceSpec
+		err := m.runRemoteJSON(&spec, "device-spec", "--name", name)
+		recordSpanError(span, err)
+		return spec, err
+	}
+	spec, err := avd.DeviceSpecForAVD(m.env, name)
+	recordSpanError(span, err)
+	return spec, err
+}
+
+// InstallAPK installs an app on a running emulator, replacing an installed version. path is an
+// APK, a directory of split APKs (adb install-multiple), or an .apks/.aab bundle split for the
+// AVD with Environment.BundletoolBin. Over SSH, path is on the remote host.
+func (m *Manager) InstallAPK(serial, path string) error {
+	if err := m.checkWritable("InstallAPK"); err != nil {
+		return err
+	}
+	ctx, span := m.startSpan("avdmanager.InstallAPK", attribute.String("serial", serial), attribute.String("path", path))
+	defer span.End()
+	if m.usesRemote() {
+		_, err := m.runRemote("install", "--serial", serial, path)
+		recordSpanError(span, err)
+		return err
+	}
+	err := avd.InstallAPK(m.withContext(ctx), serial, path)
+	recordSpanError(span, err)
+	return err
+}
@@ -0,0 +1,111 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avdmanager
+
+import (
+	"strconv"
+
+	"github.com/forkbombeu/avdctl/internal/avd"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type (
+	// Channel names a pointer to a golden (ChannelLatest, ChannelStaging, ChannelProduction).
+	// CloneOptions.GoldenPath accepts "@<channel>" to clone whatever the channel points at.
+	Channel = avd.Channel
+	// ChannelPointer is the golden a channel points at, and its earlier goldens.
+	ChannelPointer = avd.ChannelPointer
+	// ChannelEntry is an earlier target of a channel.
+	ChannelEntry = avd.ChannelEntry
+	// ChannelHealthOptions configures CheckChannelHealth.
+	ChannelHealthOptions = avd.ChannelHealthOptions
+	// ChannelHealth reports the health of the running clones of a channel's golden.
+	ChannelHealth = avd.ChannelHealth
+)
+
+const (
+	ChannelLatest     = avd.ChannelLatest
+	ChannelStaging    = avd.ChannelStaging
+	ChannelProduction = avd.ChannelProduction
+)
+
+// ListChannels returns the channel pointers of the golden registry (Env.GoldenDir).
+func (m *Manager) ListChannels() (map[Channel]ChannelPointer, error) {
+	ctx, span := m.startSpan("avdmanager.ListChannels")
+	defer span.End()
+	if m.usesRemote() {
+		channels := map[Channel]ChannelPointer{}
+		err := m.runRemoteJSON(&channels, "channel", "list", "--json")
+		recordSpanError(span, err)
+		return channels, err
+	}
+	channels, err := avd.ListChannels(m.withContext(ctx))
+	recordSpanError(span, err)
+	return channels, err
+}
+
+// Promote points channel at golden (a directory or "@<channel>"), keeping the previous
+// golden for Rollback.
+func (m *Manager) Promote(golden string, channel Channel) (ChannelPointer, error) {
+	if err := m.checkWritable("Promote"); err != nil {
+		return ChannelPointer{}, err
+	}
+	ctx, span := m.startSpan("avdmanager.Promote", attribute.String("golden", golden), attribute.String("channel", string(channel)))
+	defer span.End()
+	if m.usesRemote() {
+		var pointer ChannelPointer
+		err := m.runRemoteJSON(&pointer, "channel", "promote", "--golden", golden, "--channel", string(channel), "--json")
+		recordSpanError(span, err)
+		return pointer, err
+	}
+	pointer, err := avd.Promote(m.withContext(ctx), golden, channel)
+	recordSpanError(span, err)
+	return pointer, err
+}
+
+// Rollback points channel back at the golden it pointed at before its last promotion.
+func (m *Manager) Rollback(channel Channel) (ChannelPointer, error) {
+	if err := m.checkWritable("Rollback"); err != nil {
+		return ChannelPointer{}, err
+	}
+	ctx, span := m.startSpan("avdmanager.Rollback", attribute.String("channel", string(channel)))
+	defer span.End()
+	if m.usesRemote() {
+		var pointer ChannelPointer
+		err := m.runRemoteJSON(&pointer, "channel", "rollback", "--channel", string(channel), "--json")
+		recordSpanError(span, err)
+		return pointer, err
+	}
+	pointer, err := avd.Rollback(m.withContext(ctx), channel)
+	recordSpanError(span, err)
+	return pointer, err
+}
+
+// CheckChannelHealth health-checks the booted clones of the golden channel points at and,
+// with opts.Rollback, rolls the channel back once opts.MaxFailures clones fail.
+func (m *Manager) CheckChannelHealth(channel Channel, opts ChannelHealthOptions) (ChannelHealth, error) {
+	if opts.Rollback {
+		if err := m.checkWritable("CheckChannelHealth"); err != nil {
+			return ChannelHealth{}, err
+		}
+	}
+	ctx, span := m.startSpan("avdmanager.CheckChannelHealth", attribute.String("channel", string(channel)))
+	defer span.End()
+	if m.usesRemote() {
+		args := []string{"channel", "check", "--channel", string(channel), "--json"}
+		if opts.MaxFailures > 0 {
+			args = append(args, "--max-failures", strconv.Itoa(opts.MaxFailures))
+		}
+		if opts.Rollback {
+			args = append(args, "--rollback")
+		}
+		var health ChannelHealth
+		err := m.runRemoteJSON(&health, args...)
+		recordSpanError(span, err)
+		return health, err
+	}
+	health, err := avd.CheckChannelHealth(m.withContext(ctx), channel, opts)
+	recordSpanError(span, err)
+	return health, err
+}
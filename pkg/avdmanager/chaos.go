@@ -0,0 +1,38 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avdmanager
+
+import (
+	"errors"
+
+	"github.com/forkbombeu/avdctl/internal/avd"
+)
+
+// ChaosPolicy selects which running instances a ChaosController disrupts and how; every
+// action is reported as a ChaosEvent.
+type (
+	ChaosPolicy     = avd.ChaosPolicy
+	ChaosEvent      = avd.ChaosEvent
+	ChaosController = avd.ChaosController
+)
+
+// Chaos actions for ChaosPolicy.Actions.
+const (
+	ChaosKill           = avd.ChaosKill
+	ChaosPause          = avd.ChaosPause
+	ChaosDegradeNetwork = avd.ChaosDegradeNetwork
+)
+
+// Chaos returns a controller that randomly kills, pauses or degrades the network of running
+// instances per policy. Run it against a test farm to check that the orchestration layer
+// recovers; Run stops with the manager's context and reverts pauses and degradations.
+func (m *Manager) Chaos(policy ChaosPolicy) (*ChaosController, error) {
+	if err := m.checkWritable("Chaos"); err != nil {
+		return nil, err
+	}
+	if m.usesRemote() {
+		return nil, errors.New("chaos testing is not supported over SSH; run `avdctl chaos` on the target host")
+	}
+	return avd.NewChaosController(m.env, policy)
+}
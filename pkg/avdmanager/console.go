@@ -0,0 +1,33 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avdmanager
+
+import (
+	"strings"
+
+	"github.com/forkbombeu/avdctl/internal/avd"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ConsoleError is a KO reply from the emulator console.
+type ConsoleError = avd.ConsoleError
+
+// Console sends a command to the emulator console of a running emulator (e.g. "sensor set
+// acceleration 0:9.8:0", "geo fix 4.89 52.37", "power capacity 15") and returns its reply.
+// Locally a KO reply is returned as *ConsoleError; over SSH it is part of the remote error.
+func (m *Manager) Console(serial, command string) (string, error) {
+	if err := m.checkWritable("Console"); err != nil {
+		return "", err
+	}
+	ctx, span := m.startSpan("avdmanager.Console", attribute.String("serial", serial), attribute.String("command", command))
+	defer span.End()
+	if m.usesRemote() {
+		out, err := m.runRemote(append([]string{"console", "--serial", serial, "--"}, strings.Fields(command)...)...)
+		recordSpanError(span, err)
+		return strings.TrimSpace(out), err
+	}
+	out, err := avd.Console(m.withContext(ctx), serial, command)
+	recordSpanError(span, err)
+	return out, err
+}
@@ -0,0 +1,518 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avdmanager
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Agent is one avdctl host of a Coordinator: a Manager, usually in SSH mode, and the number of
+// instances it may run (0 = no limit).
+type Agent struct {
+	Name     string
+	Manager  *Manager
+	Capacity int
+}
+
+// agentsFile is the agents file read by LoadAgents.
+type agentsFile struct {
+	Agents []struct {
+		Name     string   `yaml:"name"`
+		SSH      string   `yaml:"ssh"`      // SSH target; empty for the local host
+		SSHArgs  []string `yaml:"ssh_args"` // extra ssh arguments, e.g. ["-p", "2222"]
+		Capacity int      `yaml:"capacity"`
+	} `yaml:"agents"`
+}
+
+// LoadAgents reads an agents file (YAML):
+//
+//	agents:
+//	  - name: rack-1
+//	    ssh: ci@rack-1.internal
+//	    capacity: 8
+//	  - name: local
+//
+// Agents without ssh run on the local host.
+func LoadAgents(path string) ([]Agent, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read agents file: %w", err)
+	}
+	var file agentsFile
+	if err := yaml.Unmarshal(b, &file); err != nil {
+		return nil, fmt.Errorf("parse agents file %s: %w", path, err)
+	}
+	agents := make([]Agent, 0, len(file.Agents))
+	for _, a := range file.Agents {
+		m := New()
+		if strings.TrimSpace(a.SSH) != "" {
+			m = NewWithEnv(Environment{SSHTarget: a.SSH, SSHArgs: a.SSHArgs})
+		}
+		agents = append(agents, Agent{Name: a.Name, Manager: m, Capacity: a.Capacity})
+	}
+	if len(agents) == 0 {
+		return nil, fmt.Errorf("agents file %s lists no agents", path)
+	}
+	return agents, nil
+}
+
+// AgentLoad is what a PlacementPolicy knows about an agent. Error is set, and the other counts
+// empty, when the agent could not be reached.
+type AgentLoad struct {
+	Agent       string   `json:"agent"`
+	Capacity    int      `json:"capacity,omitempty"` // 0 = no limit
+	Running     int      `json:"running"`
+	AVDs        []string `json:"avds,omitempty"`         // AVDs on the host
+	RunningAVDs []string `json:"running_avds,omitempty"` // AVDs with a running instance
+	Quarantined []string `json:"quarantined,omitempty"`  // AVDs the host refuses to start
+	Error       string   `json:"error,omitempty"`
+}
+
+// Full reports whether the agent runs as many instances as its capacity allows.
+func (l AgentLoad) Full() bool {
+	return l.Capacity > 0 && l.Running >= l.Capacity
+}
+
+// PlacementOp is the kind of request a PlacementPolicy places.
+type PlacementOp string
+
+// Placement operations.
+const (
+	PlaceClone PlacementOp = "clone" // Name is the clone to create from Base
+	PlaceRun   PlacementOp = "run"   // Name is the AVD to start
+)
+
+// PlacementRequest is a clone or run a Coordinator needs an agent for.
+type PlacementRequest struct {
+	Op   PlacementOp
+	Name string
+	Base string // base AVD of a clone
+}
+
+// PlacementPolicy picks the agent that serves a request, given the current load of every
+// agent. It returns an agent name, or an error when no agent fits.
+type PlacementPolicy interface {
+	Place(req PlacementRequest, loads []AgentLoad) (string, error)
+}
+
+// ErrNoAgent is returned, wrapped, when no agent can serve a placement request.
+var ErrNoAgent = errors.New("no agent available")
+
+// LeastLoaded is the default PlacementPolicy. It places a clone on an agent that has the base
+// but not the clone yet and a run on an agent that has the AVD but neither runs nor quarantines
+// it, skipping unreachable and full agents, and picks the one with the lowest running/capacity ratio (agents
+// without capacity count their running instances). Ties go to the first agent by name.
+type LeastLoaded struct{}
+
+// Place implements PlacementPolicy.
+func (LeastLoaded) Place(req PlacementRequest, loads []AgentLoad) (string, error) {
+	need := req.Name
+	if req.Op == PlaceClone {
+		need = req.Base
+	}
+	var candidates []AgentLoad
+	for _, load := range loads {
+		if load.Error != "" || load.Full() || !slices.Contains(load.AVDs, need) {
+			continue
+		}
+		if req.Op == PlaceRun && (slices.Contains(load.RunningAVDs, req.Name) || slices.Contains(load.Quarantined, req.Name)) ||
+			req.Op == PlaceClone && slices.Contains(load.AVDs, req.Name) {
+			continue
+		}
+		candidates = append(candidates, load)
+	}
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("%w to %s %s: none reachable with free capacity has %s", ErrNoAgent, req.Op, req.Name, need)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if li, lj := loadRatio(candidates[i]), loadRatio(candidates[j]); li != lj {
+			return li < lj
+		}
+		return candidates[i].Agent < candidates[j].Agent
+	})
+	return candidates[0].Agent, nil
+}
+
+// Affinity is a PlacementPolicy that applies label rules before handing the remaining agents to
+// Next (LeastLoaded when nil). Labels holds the labels of each AVD by name. A clone is kept off
+// agents holding an AVD with the same value of an AntiAffinity label, and pinned to the agents
+// holding AVDs with the same value of an Affinity label, if any. Runs are matched against the
+// running instances of each agent instead.
+type Affinity struct {
+	Labels       map[string]map[string]string
+	Affinity     []string
+	AntiAffinity []string
+	Next         PlacementPolicy
+}
+
+// AffinityFromFleet returns the Affinity policy of a fleet file's clone labels and placement
+// rules, placing with next among the agents they allow.
+func AffinityFromFleet(fleet Fleet, next PlacementPolicy) *Affinity {
+	return &Affinity{
+		Labels:       fleet.Labels(),
+		Affinity:     fleet.Placement.Affinity,
+		AntiAffinity: fleet.Placement.AntiAffinity,
+		Next:         next,
+	}
+}
+
+// Place implements PlacementPolicy.
+func (a *Affinity) Place(req PlacementRequest, loads []AgentLoad) (string, error) {
+	next := a.Next
+	if next == nil {
+		next = LeastLoaded{}
+	}
+	labels := a.Labels[req.Name]
+	if len(labels) == 0 {
+		return next.Place(req, loads)
+	}
+	all := loads
+	var rules []string
+	for _, key := range a.AntiAffinity {
+		value, ok := labels[key]
+		if !ok {
+			continue
+		}
+		kept := loads[:0:0]
+		for _, load := range loads {
+			if !a.hostsPeer(req, load, key, value) {
+				kept = append(kept, load)
+			}
+		}
+		loads = kept
+		rules = append(rules, fmt.Sprintf("anti-affinity %s=%s", key, value))
+	}
+	for _, key := range a.Affinity {
+		value, ok := labels[key]
+		if !ok {
+			continue
+		}
+		if !slices.ContainsFunc(all, func(load AgentLoad) bool { return a.hostsPeer(req, load, key, value) }) {
+			// No peer placed yet: any agent may start the group.
+			continue
+		}
+		var pinned []AgentLoad
+		for _, load := range loads {
+			if a.hostsPeer(req, load, key, value) {
+				pinned = append(pinned, load)
+			}
+		}
+		loads = pinned
+		rules = append(rules, fmt.Sprintf("affinity %s=%s", key, value))
+	}
+	agent, err := next.Place(req, loads)
+	if err != nil && len(rules) > 0 {
+		return "", fmt.Errorf("%w (after %s)", err, strings.Join(rules, ", "))
+	}
+	return agent, err
+}
+
+// hostsPeer reports whether load holds an AVD other than req.Name with label key set to value:
+// among the agent's AVDs for a clone, among its running instances for a run.
+func (a *Affinity) hostsPeer(req PlacementRequest, load AgentLoad, key, value string) bool {
+	names := load.AVDs
+	if req.Op == PlaceRun {
+		names = load.RunningAVDs
+	}
+	for _, name := range names {
+		if name != req.Name && a.Labels[name][key] == value {
+			return true
+		}
+	}
+	return false
+}
+
+func loadRatio(l AgentLoad) float64 {
+	if l.Capacity <= 0 {
+		return float64(l.Running)
+	}
+	return float64(l.Running) / float64(l.Capacity)
+}
+
+// Coordinator aggregates several avdctl agents: it places clones and starts on them with a
+// PlacementPolicy and lists their AVDs and instances as one fleet.
+type Coordinator struct {
+	agents []Agent
+	policy PlacementPolicy
+	// mu serializes placements, so concurrent requests see each other's load.
+	mu sync.Mutex
+}
+
+// NewCoordinator returns a Coordinator over agents, placing with policy (LeastLoaded when
+// nil). Agent names must be set and unique.
+func NewCoordinator(agents []Agent, policy PlacementPolicy) (*Coordinator, error) {
+	if len(agents) == 0 {
+		return nil, errors.New("coordinator needs at least one agent")
+	}
+	seen := map[string]bool{}
+	for _, a := range agents {
+		if a.Name == "" || a.Manager == nil {
+			return nil, errors.New("coordinator agents need a name and a manager")
+		}
+		if seen[a.Name] {
+			return nil, fmt.Errorf("duplicate agent %s", a.Name)
+		}
+		seen[a.Name] = true
+	}
+	if policy == nil {
+		policy = LeastLoaded{}
+	}
+	return &Coordinator{agents: agents, policy: policy}, nil
+}
+
+// Agents returns the coordinator's agents.
+func (c *Coordinator) Agents() []Agent {
+	return slices.Clone(c.agents)
+}
+
+// HostedAVD is an AVD and the agent it lives on.
+type HostedAVD struct {
+	Agent string `json:"agent"`
+	AVDInfo
+}
+
+// HostedProcess is a running instance and the agent it runs on.
+type HostedProcess struct {
+	Agent string `json:"agent"`
+	ProcessInfo
+}
+
+// HostedStart is a Start placed on an agent.
+type HostedStart struct {
+	Agent string `json:"agent"`
+	StartResult
+}
+
+// Loads queries every agent, concurrently, for its AVDs and running instances.
+func (c *Coordinator) Loads() []AgentLoad {
+	loads := make([]AgentLoad, len(c.agents))
+	c.each(func(i int, a Agent) error {
+		loads[i] = agentLoad(a)
+		return nil
+	})
+	return loads
+}
+
+func agentLoad(a Agent) AgentLoad {
+	load := AgentLoad{Agent: a.Name, Capacity: a.Capacity}
+	infos, err := a.Manager.List()
+	if err == nil {
+		var procs []ProcessInfo
+		if procs, err = a.Manager.ListRunning(); err == nil {
+			for _, info := range infos {
+				load.AVDs = append(load.AVDs, info.Name)
+				if info.Quarantined {
+					load.Quarantined = append(load.Quarantined, info.Name)
+				}
+			}
+			for _, proc := range procs {
+				load.RunningAVDs = append(load.RunningAVDs, proc.Name)
+			}
+			load.Running = len(procs)
+			return load
+		}
+	}
+	load.Error = err.Error()
+	return load
+}
+
+// Clone creates the clone opts.CloneName on the agent the policy picks for it. GoldenPath is
+// resolved on that agent.
+func (c *Coordinator) Clone(opts CloneOptions) (HostedAVD, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	a, err := c.place(PlacementRequest{Op: PlaceClone, Name: opts.CloneName, Base: opts.BaseName})
+	if err != nil {
+		return HostedAVD{}, err
+	}
+	info, err := a.Manager.Clone(opts)
+	if err != nil {
+		return HostedAVD{}, fmt.Errorf("clone %s on %s: %w", opts.CloneName, a.Name, err)
+	}
+	return HostedAVD{Agent: a.Name, AVDInfo: info}, nil
+}
+
+// Start starts opts.Name on the agent the policy picks for it.
+func (c *Coordinator) Start(opts RunOptions) (HostedStart, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	a, err := c.place(PlacementRequest{Op: PlaceRun, Name: opts.Name})
+	if err != nil {
+		return HostedStart{}, err
+	}
+	res, err := a.Manager.Start(opts)
+	if err != nil {
+		return HostedStart{}, fmt.Errorf("start %s on %s: %w", opts.Name, a.Name, err)
+	}
+	return HostedStart{Agent: a.Name, StartResult: res}, nil
+}
+
+func (c *Coordinator) place(req PlacementRequest) (Agent, error) {
+	name, err := c.policy.Place(req, c.Loads())
+	if err != nil {
+		return Agent{}, err
+	}
+	return c.agent(name)
+}
+
+// List returns the AVDs of every agent. Agents that cannot be reached are left out and
+// reported in the joined error, alongside the AVDs of the others.
+func (c *Coordinator) List() ([]HostedAVD, error) {
+	lists := make([][]HostedAVD, len(c.agents))
+	err := c.each(func(i int, a Agent) error {
+		infos, err := a.Manager.List()
+		for _, info := range infos {
+			lists[i] = append(lists[i], HostedAVD{Agent: a.Name, AVDInfo: info})
+		}
+		return err
+	})
+	return slices.Concat(lists...), err
+}
+
+// ListRunning returns the running instances of every agent, with the partial results and
+// joined error of List.
+func (c *Coordinator) ListRunning() ([]HostedProcess, error) {
+	lists := make([][]HostedProcess, len(c.agents))
+	err := c.each(func(i int, a Agent) error {
+		procs, err := a.Manager.ListRunning()
+		for _, proc := range procs {
+			lists[i] = append(lists[i], HostedProcess{Agent: a.Name, ProcessInfo: proc})
+		}
+		return err
+	})
+	return slices.Concat(lists...), err
+}
+
+// Stop stops the instance serial on agent.
+func (c *Coordinator) Stop(agent, serial string) error {
+	a, err := c.agent(agent)
+	if err != nil {
+		return err
+	}
+	return a.Manager.Stop(serial)
+}
+
+// EmulatorLog returns the last lines of the emulator log of the instance serial on agent (see
+// Manager.EmulatorLog).
+func (c *Coordinator) EmulatorLog(agent, serial string, lines int) (string, error) {
+	a, err := c.agent(agent)
+	if err != nil {
+		return "", err
+	}
+	return a.Manager.EmulatorLog(serial, lines)
+}
+
+// AgentCapacity is the capacity of one agent for a golden. Additional is also capped by the
+// agent's Capacity; Error is set when the agent could not estimate it.
+type AgentCapacity struct {
+	Agent string `json:"agent"`
+	CapacityReport
+	Error string `json:"error,omitempty"`
+}
+
+// Capacity estimates, on every agent, how many more clones of golden it can run (see
+// Manager.Capacity), and returns the total with the per-agent reports. golden is resolved on
+// each agent.
+func (c *Coordinator) Capacity(golden string) (int, []AgentCapacity) {
+	reports := make([]AgentCapacity, len(c.agents))
+	c.each(func(i int, a Agent) error {
+		reports[i] = AgentCapacity{Agent: a.Name}
+		report, err := a.Manager.Capacity(golden)
+		if err != nil {
+			reports[i].Error = err.Error()
+			return nil
+		}
+		if a.Capacity > 0 && a.Capacity-report.Instances < report.Additional {
+			report.Additional = max(0, a.Capacity-report.Instances)
+			report.Bottleneck = "agent capacity"
+		}
+		reports[i].CapacityReport = report
+		return nil
+	})
+	total := 0
+	for _, r := range reports {
+		total += r.Additional
+	}
+	return total, reports
+}
+
+// FailureStats counts the failed runs since since across every agent (see
+// Manager.FailureStats); AVDs are named "agent/avd". Unreachable agents are left out and
+// reported in the error.
+func (c *Coordinator) FailureStats(since time.Time) (FailureReport, error) {
+	reports := make([]FailureReport, len(c.agents))
+	err := c.each(func(i int, a Agent) error {
+		report, err := a.Manager.FailureStats(since)
+		for j := range report.Classes {
+			for k, name := range report.Classes[j].AVDs {
+				report.Classes[j].AVDs[k] = a.Name + "/" + name
+			}
+		}
+		reports[i] = report
+		return err
+	})
+	total := FailureReport{Since: since}
+	classes := map[string]*FailureCount{}
+	for _, r := range reports {
+		total.Runs += r.Runs
+		total.Failed += r.Failed
+		for _, fc := range r.Classes {
+			c, ok := classes[fc.Class]
+			if !ok {
+				c = &FailureCount{Class: fc.Class}
+				classes[fc.Class] = c
+			}
+			c.Runs += fc.Runs
+			c.AVDs = append(c.AVDs, fc.AVDs...)
+			if fc.Last.After(c.Last) {
+				c.Last = fc.Last
+			}
+		}
+	}
+	for _, c := range classes {
+		total.Classes = append(total.Classes, *c)
+	}
+	sort.Slice(total.Classes, func(i, j int) bool {
+		if total.Classes[i].Runs != total.Classes[j].Runs {
+			return total.Classes[i].Runs > total.Classes[j].Runs
+		}
+		return total.Classes[i].Class < total.Classes[j].Class
+	})
+	return total, err
+}
+
+func (c *Coordinator) agent(name string) (Agent, error) {
+	for _, a := range c.agents {
+		if a.Name == name {
+			return a, nil
+		}
+	}
+	return Agent{}, fmt.Errorf("unknown agent %s", name)
+}
+
+// each runs fn for every agent concurrently and joins the errors, prefixed with the agent.
+func (c *Coordinator) each(fn func(i int, a Agent) error) error {
+	errs := make([]error, len(c.agents))
+	var wg sync.WaitGroup
+	for i, a := range c.agents {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := fn(i, a); err != nil {
+				errs[i] = fmt.Errorf("agent %s: %w", a.Name, err)
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
@@ -0,0 +1,218 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avdmanager
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLeastLoadedPlace(t *testing.T) {
+	loads := []AgentLoad{
+		{Agent: "a", Capacity: 4, Running: 2, AVDs: []string{"base", "w-1"}, RunningAVDs: []string{"w-1", "x", "y"}},
+		{Agent: "b", Capacity: 8, Running: 2, AVDs: []string{"base", "w-1", "w-2"}},
+		{Agent: "c", Capacity: 2, Running: 2, AVDs: []string{"base", "w-2"}},
+		{Agent: "d", Error: "ssh: connection refused"},
+	}
+	for _, tc := range []struct {
+		req  PlacementRequest
+		want string
+	}{
+		{PlacementRequest{Op: PlaceClone, Name: "w-3", Base: "base"}, "b"},
+		{PlacementRequest{Op: PlaceRun, Name: "w-1"}, "b"},
+		{PlacementRequest{Op: PlaceRun, Name: "w-2"}, "b"},
+	} {
+		if got, err := (LeastLoaded{}).Place(tc.req, loads); err != nil || got != tc.want {
+			t.Fatalf("Place(%+v) = %q, %v; want %q", tc.req, got, err, tc.want)
+		}
+	}
+	loads[1].Quarantined = []string{"w-2"}
+	if got, err := (LeastLoaded{}).Place(PlacementRequest{Op: PlaceRun, Name: "w-2"}, loads); !errors.Is(err, ErrNoAgent) {
+		t.Fatalf("run of w-2, quarantined on b, placed on %q, %v", got, err)
+	}
+	loads[1].Running = 8
+	for _, req := range []PlacementRequest{
+		{Op: PlaceRun, Name: "w-1"},                  // only running on a, b is full
+		{Op: PlaceClone, Name: "w-1", Base: "base"},  // exists on a
+		{Op: PlaceClone, Name: "w-9", Base: "other"}, // no agent has the base
+	} {
+		if got, err := (LeastLoaded{}).Place(req, loads); !errors.Is(err, ErrNoAgent) {
+			t.Fatalf("Place(%+v) = %q, %v; want ErrNoAgent", req, got, err)
+		}
+	}
+}
+
+func TestCoordinatorRemote(t *testing.T) {
+	var calls []string
+	withRemoteRunner(t, func(target string, _ []string, avdArgs []string) (string, string, error) {
+		call := target + " " + strings.Join(avdArgs, " ")
+		calls = append(calls, call)
+		switch {
+		case target == "rack-2":
+			return "", "ssh: connect to host rack-2: Connection refused", errors.New("exit status 255")
+		case avdArgs[0] == "list":
+			return `[{"Name":"base"},{"Name":"w-1"}]`, "", nil
+		case avdArgs[0] == "ps" && target == "rack-1":
+			return `[{"Serial":"emulator-5554","Name":"x"}]`, "", nil
+		case avdArgs[0] == "ps":
+			return `[]`, "", nil
+		case avdArgs[0] == "run":
+			return "Started w-1 on emulator-5580 (log: /tmp/e.log)\n", "", nil
+		case avdArgs[0] == "logs":
+			return "boot completed\n", "", nil
+		}
+		return "", "", nil
+	})
+	var agents []Agent
+	for _, target := range []string{"rack-1", "rack-2", "rack-3"} {
+		agents = append(agents, Agent{Name: target, Manager: NewWithEnv(Environment{SSHTarget: target}), Capacity: 4})
+	}
+	c, err := NewCoordinator(agents, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := c.Start(RunOptions{Name: "w-1"})
+	if err != nil || res.Agent != "rack-3" || res.Serial != "emulator-5580" {
+		t.Fatalf("Start() = %+v, %v; want w-1 on the idle rack-3", res, err)
+	}
+	procs, err := c.ListRunning()
+	if len(procs) != 1 || procs[0].Agent != "rack-1" || err == nil || !strings.Contains(err.Error(), "agent rack-2") {
+		t.Fatalf("ListRunning() = %+v, %v; want rack-1's instance and rack-2's error", procs, err)
+	}
+	if out, err := c.EmulatorLog("rack-3", "emulator-5580", 20); err != nil || out != "boot completed\n" {
+		t.Fatalf("EmulatorLog() = %q, %v", out, err)
+	}
+	if calls[len(calls)-1] != "rack-3 logs --serial emulator-5580 --lines 20" {
+		t.Fatalf("last call %q", calls[len(calls)-1])
+	}
+	if err := c.Stop("rack-9", "emulator-5580"); err == nil {
+		t.Fatal("expected Stop on an unknown agent to fail")
+	}
+	if _, err := NewCoordinator([]Agent{agents[0], agents[0]}, nil); err == nil {
+		t.Fatal("expected duplicate agents to be rejected")
+	}
+}
+
+func TestLoadAgents(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agents.yaml")
+	data := "agents:\n  - name: rack-1\n    ssh: ci@rack-1\n    ssh_args: [\"-p\", \"2222\"]\n    capacity: 8\n  - name: local\n"
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	agents, err := LoadAgents(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(agents) != 2 || agents[0].Capacity != 8 || !agents[0].Manager.usesRemote() || agents[1].Manager.usesRemote() {
+		t.Fatalf("LoadAgents() = %+v", agents)
+	}
+	if got := agents[0].Manager.env.SSHArgs; len(got) != 2 || got[1] != "2222" {
+		t.Fatalf("ssh args = %v", got)
+	}
+}
+
+func TestAffinityPlace(t *testing.T) {
+	policy := &Affinity{
+		Labels: map[string]map[string]string{
+			"w-acme-1": {"customer": "acme"},
+			"w-acme-2": {"customer": "acme"},
+			"w-gino-1": {"customer": "gino", "isolate": "bank"},
+			"w-bank-1": {"customer": "bank", "isolate": "bank"},
+		},
+		Affinity:     []string{"customer"},
+		AntiAffinity: []string{"isolate"},
+	}
+	loads := []AgentLoad{
+		{Agent: "a", Capacity: 4, Running: 3, AVDs: []string{"base", "w-acme-1"}},
+		{Agent: "b", Capacity: 4, AVDs: []string{"base", "w-gino-1"}},
+		{Agent: "c", Capacity: 4, Running: 1, AVDs: []string{"base"}},
+	}
+	for _, tc := range []struct {
+		name string
+		want string
+	}{
+		{"w-acme-2", "a"}, // pinned to its customer despite the load
+		{"w-bank-1", "c"}, // kept off b, which holds another isolate=bank clone
+		{"w-other", "b"},  // no labels: least loaded
+	} {
+		got, err := policy.Place(PlacementRequest{Op: PlaceClone, Name: tc.name, Base: "base"}, loads)
+		if err != nil || got != tc.want {
+			t.Fatalf("Place(%s) = %q, %v; want %q", tc.name, got, err, tc.want)
+		}
+	}
+	loads[0].Running = 4
+	_, err := policy.Place(PlacementRequest{Op: PlaceClone, Name: "w-acme-2", Base: "base"}, loads)
+	if !errors.Is(err, ErrNoAgent) || !strings.Contains(err.Error(), "affinity customer=acme") {
+		t.Fatalf("expected the full affinity host to block placement, got %v", err)
+	}
+}
+
+func TestCoordinatorCapacity(t *testing.T) {
+	var last string
+	withRemoteRunner(t, func(target string, _ []string, avdArgs []string) (string, string, error) {
+		last = strings.Join(avdArgs, " ")
+		if target == "rack-2" {
+			return "", "no clones of golden a35 on this host", errors.New("exit status 1")
+		}
+		return `{"golden":"/g/a35","clones":3,"samples":5,"instances":3,"limits":{"memory":6,"cpu":9},"additional":6,"bottleneck":"memory"}`, "", nil
+	})
+	c, err := NewCoordinator([]Agent{
+		{Name: "rack-1", Manager: NewWithEnv(Environment{SSHTarget: "rack-1"})},
+		{Name: "rack-2", Manager: NewWithEnv(Environment{SSHTarget: "rack-2"})},
+		{Name: "rack-3", Manager: NewWithEnv(Environment{SSHTarget: "rack-3"}), Capacity: 5},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	total, reports := c.Capacity("a35")
+	if last != "capacity --golden a35 --json" {
+		t.Fatalf("remote call %q", last)
+	}
+	if total != 8 || reports[0].Additional != 6 || reports[1].Error == "" || reports[2].Additional != 2 || reports[2].Bottleneck != "agent capacity" {
+		t.Fatalf("Capacity() = %d, %+v", total, reports)
+	}
+}
+
+func TestCoordinatorFailureStats(t *testing.T) {
+	var last string
+	withRemoteRunner(t, func(target string, _ []string, avdArgs []string) (string, string, error) {
+		last = strings.Join(avdArgs, " ")
+		switch target {
+		case "rack-1":
+			return `{"runs":10,"failed":3,"classes":[{"class":"crash","runs":2,"avds":["w-1"]},{"class":"boot-timeout","runs":1,"avds":["w-2"]}]}`, "", nil
+		case "rack-2":
+			return `{"runs":5,"failed":2,"classes":[{"class":"boot-timeout","runs":2,"avds":["w-9"]}]}`, "", nil
+		}
+		return "", "ssh: connect to host rack-3", errors.New("exit status 255")
+	})
+	c, err := NewCoordinator([]Agent{
+		{Name: "rack-1", Manager: NewWithEnv(Environment{SSHTarget: "rack-1"})},
+		{Name: "rack-2", Manager: NewWithEnv(Environment{SSHTarget: "rack-2"})},
+		{Name: "rack-3", Manager: NewWithEnv(Environment{SSHTarget: "rack-3"})},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	since := time.Date(2026, 10, 7, 0, 0, 0, 0, time.UTC)
+	report, err := c.FailureStats(since)
+	if err == nil || !strings.Contains(err.Error(), "agent rack-3") {
+		t.Fatalf("FailureStats error = %v, want rack-3 unreachable", err)
+	}
+	if last != "stats failures --since 2026-10-07T00:00:00Z --json" {
+		t.Fatalf("remote call %q", last)
+	}
+	if report.Runs != 15 || report.Failed != 5 || len(report.Classes) != 2 {
+		t.Fatalf("FailureStats() = %+v", report)
+	}
+	top := report.Classes[0]
+	if top.Class != FailureBootTimeout || top.Runs != 3 || !slices.Equal(top.AVDs, []string{"rack-1/w-2", "rack-2/w-9"}) {
+		t.Fatalf("top class = %+v", top)
+	}
+}
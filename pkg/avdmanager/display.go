@@ -0,0 +1,60 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avdmanager
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/forkbombeu/avdctl/internal/avd"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// SetDisplaySize overrides the resolution and density of a running emulator, so one golden
+// can be tested as a phone, a tablet or a small device. Pass 0 for width and height, or for
+// density, to leave it unchanged. Undo with ResetDisplaySize.
+func (m *Manager) SetDisplaySize(serial string, width, height, density int) error {
+	if err := m.checkWritable("SetDisplaySize"); err != nil {
+		return err
+	}
+	ctx, span := m.startSpan("avdmanager.SetDisplaySize",
+		attribute.String("serial", serial),
+		attribute.Int("width", width),
+		attribute.Int("height", height),
+		attribute.Int("density", density),
+	)
+	defer span.End()
+	if m.usesRemote() {
+		args := []string{"display", "--serial", serial}
+		if width != 0 || height != 0 {
+			args = append(args, "--size", fmt.Sprintf("%dx%d", width, height))
+		}
+		if density != 0 {
+			args = append(args, "--density", strconv.Itoa(density))
+		}
+		_, err := m.runRemote(args...)
+		recordSpanError(span, err)
+		return err
+	}
+	err := avd.SetDisplaySize(m.withContext(ctx), serial, width, height, density)
+	recordSpanError(span, err)
+	return err
+}
+
+// ResetDisplaySize restores the resolution and density of the device profile.
+func (m *Manager) ResetDisplaySize(serial string) error {
+	if err := m.checkWritable("ResetDisplaySize"); err != nil {
+		return err
+	}
+	ctx, span := m.startSpan("avdmanager.ResetDisplaySize", attribute.String("serial", serial))
+	defer span.End()
+	if m.usesRemote() {
+		_, err := m.runRemote("display", "--serial", serial, "--reset")
+		recordSpanError(span, err)
+		return err
+	}
+	err := avd.ResetDisplaySize(m.withContext(ctx), serial)
+	recordSpanError(span, err)
+	return err
+}
@@ -0,0 +1,103 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avdmanager
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/forkbombeu/avdctl/internal/avd"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// StandbyBucket is an app standby bucket (StandbyActive, StandbyRare, ...).
+type StandbyBucket = avd.StandbyBucket
+
+const (
+	StandbyActive     = avd.StandbyActive
+	StandbyWorkingSet = avd.StandbyWorkingSet
+	StandbyFrequent   = avd.StandbyFrequent
+	StandbyRare       = avd.StandbyRare
+	StandbyRestricted = avd.StandbyRestricted // Android 12+
+)
+
+// DisableDoze turns off light and deep doze on a running emulator until it reboots.
+// To do it on every start, use RunOptions.DisableDoze.
+func (m *Manager) DisableDoze(serial string) error {
+	if err := m.checkWritable("DisableDoze"); err != nil {
+		return err
+	}
+	ctx, span := m.startSpan("avdmanager.DisableDoze", attribute.String("serial", serial))
+	defer span.End()
+	if m.usesRemote() {
+		_, err := m.runRemote("doze", "--serial", serial, "--disable")
+		recordSpanError(span, err)
+		return err
+	}
+	err := avd.DisableDoze(m.withContext(ctx), serial)
+	recordSpanError(span, err)
+	return err
+}
+
+// EnableDoze restores doze after DisableDoze.
+func (m *Manager) EnableDoze(serial string) error {
+	if err := m.checkWritable("EnableDoze"); err != nil {
+		return err
+	}
+	ctx, span := m.startSpan("avdmanager.EnableDoze", attribute.String("serial", serial))
+	defer span.End()
+	if m.usesRemote() {
+		_, err := m.runRemote("doze", "--serial", serial, "--enable")
+		recordSpanError(span, err)
+		return err
+	}
+	err := avd.EnableDoze(m.withContext(ctx), serial)
+	recordSpanError(span, err)
+	return err
+}
+
+// SetAppStandbyBucket moves an installed package into a standby bucket on a running
+// emulator (am set-standby-bucket).
+func (m *Manager) SetAppStandbyBucket(serial, pkg string, bucket StandbyBucket) error {
+	if err := m.checkWritable("SetAppStandbyBucket"); err != nil {
+		return err
+	}
+	ctx, span := m.startSpan("avdmanager.SetAppStandbyBucket",
+		attribute.String("serial", serial),
+		attribute.String("package", pkg),
+		attribute.String("bucket", string(bucket)),
+	)
+	defer span.End()
+	if m.usesRemote() {
+		_, err := m.runRemote("standby-bucket", "--serial", serial, pkg, string(bucket))
+		recordSpanError(span, err)
+		return err
+	}
+	err := avd.SetAppStandbyBucket(m.withContext(ctx), serial, pkg, bucket)
+	recordSpanError(span, err)
+	return err
+}
+
+// afterStart applies the post-start options of RunOptions to a started emulator.
+func (m *Manager) afterStart(serial string, opts RunOptions) error {
+	if err := m.startBootTrace(serial, opts); err != nil {
+		return err
+	}
+	if !opts.DisableDoze && opts.SecretsProvisioner == nil {
+		return nil
+	}
+	timeout := opts.BootTimeout
+	if timeout == 0 {
+		timeout = 3 * time.Minute
+	}
+	if err := m.WaitForBoot(serial, timeout); err != nil {
+		return err
+	}
+	if opts.DisableDoze {
+		if err := m.DisableDoze(serial); err != nil {
+			return fmt.Errorf("disable doze on %s: %w", serial, err)
+		}
+	}
+	return m.provisionSecrets(serial, opts)
+}
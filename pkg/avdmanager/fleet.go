@@ -0,0 +1,117 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avdmanager
+
+import (
+	"errors"
+
+	"github.com/forkbombeu/avdctl/internal/avd"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Fleet is the declarative description of bases, goldens and clones (see LoadFleet).
+type Fleet = avd.Fleet
+
+// FleetBase, FleetGolden and FleetClone are the entries of a Fleet; FleetPlacement holds its
+// placement rules for a Coordinator (see AffinityFromFleet).
+type (
+	FleetBase      = avd.FleetBase
+	FleetGolden    = avd.FleetGolden
+	FleetClone     = avd.FleetClone
+	FleetPlacement = avd.FleetPlacement
+)
+
+// FleetAction is one step of an Up or Down run; FleetReport lists them.
+type (
+	FleetAction = avd.FleetAction
+	FleetReport = avd.FleetReport
+)
+
+// FleetDrift is one difference found by Diff; FleetDiffReport lists them.
+type (
+	FleetDrift      = avd.FleetDrift
+	FleetDiffReport = avd.FleetDiffReport
+)
+
+var errFleetRemote = errors.New("fleet operations are not supported over SSH; run `avdctl up` on the target host")
+
+// LoadFleet reads and validates a fleet file (YAML).
+func LoadFleet(path string) (Fleet, error) {
+	return avd.LoadFleet(path)
+}
+
+// Up converges the host to the fleet: creates missing bases and prewarm goldens,
+// materializes clones and starts clones marked run.
+func (m *Manager) Up(fleet Fleet) (FleetReport, error) {
+	if err := m.checkWritable("Up"); err != nil {
+		return FleetReport{}, err
+	}
+	ctx, span := m.startSpan("avdmanager.Up", attribute.Int("clones", len(fleet.Clones)))
+	defer span.End()
+	if m.usesRemote() {
+		recordSpanError(span, errFleetRemote)
+		return FleetReport{}, errFleetRemote
+	}
+	report, err := avd.FleetUp(m.withContext(ctx), fleet)
+	recordSpanError(span, err)
+	return report, err
+}
+
+// Down stops the fleet's running clones and, if remove is set, deletes them.
+// Bases and goldens are kept.
+func (m *Manager) Down(fleet Fleet, remove bool) (FleetReport, error) {
+	if err := m.checkWritable("Down"); err != nil {
+		return FleetReport{}, err
+	}
+	ctx, span := m.startSpan("avdmanager.Down", attribute.Bool("remove", remove))
+	defer span.End()
+	if m.usesRemote() {
+		recordSpanError(span, errFleetRemote)
+		return FleetReport{}, errFleetRemote
+	}
+	report, err := avd.FleetDown(m.withContext(ctx), fleet, remove)
+	recordSpanError(span, err)
+	return report, err
+}
+
+// Diff compares the fleet with the host's AVDs, running instances and clone config values
+// without changing anything.
+func (m *Manager) Diff(fleet Fleet) (FleetDiffReport, error) {
+	ctx, span := m.startSpan("avdmanager.Diff", attribute.Int("clones", len(fleet.Clones)))
+	defer span.End()
+	if m.usesRemote() {
+		recordSpanError(span, errFleetRemote)
+		return FleetDiffReport{}, errFleetRemote
+	}
+	report, err := avd.FleetDiff(m.withContext(ctx), fleet)
+	recordSpanError(span, err)
+	return report, err
+}
+
+// RolloutOptions configures Rollout; RolloutReport lists its actions.
+type (
+	RolloutOptions = avd.RolloutOptions
+	RolloutReport  = avd.RolloutReport
+)
+
+// DefaultRolloutBatch is the number of clones Rollout replaces at a time.
+const DefaultRolloutBatch = avd.DefaultRolloutBatch
+
+// Rollout replaces clones with clones of a new golden in batches, each booted and
+// health-checked before the next starts. A failing batch is restored and ends the rollout
+// (RolloutReport.Aborted); batches that passed keep the new golden.
+func (m *Manager) Rollout(opts RolloutOptions) (RolloutReport, error) {
+	if err := m.checkWritable("Rollout"); err != nil {
+		return RolloutReport{}, err
+	}
+	ctx, span := m.startSpan("avdmanager.Rollout", attribute.String("golden", opts.Golden), attribute.Int("batch", opts.BatchSize))
+	defer span.End()
+	if m.usesRemote() {
+		recordSpanError(span, errFleetRemote)
+		return RolloutReport{}, errFleetRemote
+	}
+	report, err := avd.Rollout(m.withContext(ctx), opts)
+	recordSpanError(span, err)
+	return report, err
+}
@@ -0,0 +1,66 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avdmanager
+
+import (
+	"strconv"
+
+	"github.com/forkbombeu/avdctl/internal/avd"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Forward makes localPort on the host connect to remotePort on the emulator. Pass 0 as
+// localPort to let adb choose a free port. Forwards are listed in ProcessInfo.Forwards and
+// removed when the instance is stopped.
+func (m *Manager) Forward(serial string, localPort, remotePort int) (PortForward, error) {
+	if err := m.checkWritable("Forward"); err != nil {
+		return PortForward{}, err
+	}
+	ctx, span := m.startSpan("avdmanager.Forward", attribute.String("serial", serial))
+	defer span.End()
+	if m.usesRemote() {
+		var fwd PortForward
+		err := m.runRemoteJSON(&fwd, "forward", "--serial", serial,
+			"--host", strconv.Itoa(localPort), "--device", strconv.Itoa(remotePort), "--json")
+		recordSpanError(span, err)
+		return fwd, err
+	}
+	fwd, err := avd.Forward(m.withContext(ctx), serial, localPort, remotePort)
+	recordSpanError(span, err)
+	return fwd, err
+}
+
+// Reverse makes devicePort on the emulator connect to hostPort on the host, e.g. so an app on a
+// clone reaches a mock server on the CI host. It is removed when the instance is stopped.
+func (m *Manager) Reverse(serial string, devicePort, hostPort int) (PortForward, error) {
+	if err := m.checkWritable("Reverse"); err != nil {
+		return PortForward{}, err
+	}
+	ctx, span := m.startSpan("avdmanager.Reverse", attribute.String("serial", serial))
+	defer span.End()
+	if m.usesRemote() {
+		var fwd PortForward
+		err := m.runRemoteJSON(&fwd, "reverse", "--serial", serial,
+			"--device", strconv.Itoa(devicePort), "--host", strconv.Itoa(hostPort), "--json")
+		recordSpanError(span, err)
+		return fwd, err
+	}
+	fwd, err := avd.Reverse(m.withContext(ctx), serial, devicePort, hostPort)
+	recordSpanError(span, err)
+	return fwd, err
+}
+
+// RemoveForward removes a tunnel returned by Forward or Reverse.
+func (m *Manager) RemoveForward(serial string, fwd PortForward) error {
+	if err := m.checkWritable("RemoveForward"); err != nil {
+		return err
+	}
+	if m.usesRemote() {
+		args := []string{fwd.Direction, "--serial", serial, "--remove",
+			"--host", strconv.Itoa(fwd.HostPort), "--device", strconv.Itoa(fwd.DevicePort)}
+		_, err := m.runRemote(args...)
+		return err
+	}
+	return avd.RemoveForward(m.env, serial, fwd)
+}
@@ -0,0 +1,67 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avdmanager
+
+import (
+	"strconv"
+
+	"github.com/forkbombeu/avdctl/internal/avd"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// FridaSession is a frida-server running in a guest and the host port forwarded to it.
+type FridaSession = avd.FridaSession
+
+// DefaultFridaPort is the port frida-server listens on, and the default forwarded host port.
+const DefaultFridaPort = avd.DefaultFridaPort
+
+// StartFrida starts frida-server as root on a running emulator and forwards DefaultFridaPort on
+// the host to it, so frida and objection can attach with -H 127.0.0.1:27042 (over SSH the port
+// is on the remote host). The server comes from a golden baked with BakeAPKOptions.FridaServer,
+// or is installed from Environment.FridaServer. A server that is already running is reused.
+func (m *Manager) StartFrida(serial string) (FridaSession, error) {
+	return m.StartFridaOnPort(serial, 0)
+}
+
+// StartFridaOnPort is StartFrida forwarding hostPort instead (0 = DefaultFridaPort), for
+// instrumenting several emulators from one host.
+func (m *Manager) StartFridaOnPort(serial string, hostPort int) (FridaSession, error) {
+	if err := m.checkWritable("StartFrida"); err != nil {
+		return FridaSession{}, err
+	}
+	ctx, span := m.startSpan("avdmanager.StartFrida", attribute.String("serial", serial), attribute.Int("host_port", hostPort))
+	defer span.End()
+	if m.usesRemote() {
+		args := []string{"frida", "start", "--serial", serial, "--json"}
+		if hostPort != 0 {
+			args = append(args, "--port", strconv.Itoa(hostPort))
+		}
+		var session FridaSession
+		if err := m.runRemoteJSON(&session, args...); err != nil {
+			recordSpanError(span, err)
+			return FridaSession{}, err
+		}
+		return session, nil
+	}
+	session, err := avd.StartFrida(m.withContext(ctx), serial, hostPort)
+	recordSpanError(span, err)
+	return session, err
+}
+
+// StopFrida kills frida-server on a running emulator and removes its port forwards.
+func (m *Manager) StopFrida(serial string) error {
+	if err := m.checkWritable("StopFrida"); err != nil {
+		return err
+	}
+	ctx, span := m.startSpan("avdmanager.StopFrida", attribute.String("serial", serial))
+	defer span.End()
+	if m.usesRemote() {
+		_, err := m.runRemote("frida", "stop", "--serial", serial)
+		recordSpanError(span, err)
+		return err
+	}
+	err := avd.StopFrida(m.withContext(ctx), serial)
+	recordSpanError(span, err)
+	return err
+}
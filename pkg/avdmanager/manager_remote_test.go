@@ -1,8 +1,10 @@
 package avdmanager
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -54,6 +56,15 @@ func TestParseStartedLineAndPathSize(t *testing.T) {
 		t.Fatal("expected parseStartedLine failure for malformed output")
 	}
 
+	res, err := parseStartResult("Started w-1 on emulator-5584 (log: /tmp/w-1.log) pid 4242")
+	if err != nil || res != (StartResult{Name: "w-1", Serial: "emulator-5584", Port: 5584, LogPath: "/tmp/w-1.log", PID: 4242}) {
+		t.Fatalf("parseStartResult() = %+v, %v", res, err)
+	}
+	res, err = parseStartResult("Started w-1 on emulator-5584 (log: /tmp/w-1.log) pid 4242 mode quickboot")
+	if err != nil || res.PID != 4242 || res.Mode != StartQuickboot {
+		t.Fatalf("parseStartResult(mode) = %+v, %v", res, err)
+	}
+
 	path, size, err := parsePathAndSize("Golden saved: /tmp/golden/demo (123 bytes)", "Golden saved")
 	if err != nil {
 		t.Fatalf("parsePathAndSize() error: %v", err)
@@ -244,6 +255,8 @@ func TestRemoteSavePrewarmBakeAndFindFreePort(t *testing.T) {
 			return "Prewarmed golden saved: /tmp/pre (200 bytes)\n", "", nil
 		case remoteKey([]string{"bake-apk", "--base", "base", "--name", "clone", "--golden", "/tmp/g", "--apk", "/tmp/a.apk", "--dest", "/tmp/b"}):
 			return "Baked clone at /tmp/b (300 bytes)\n", "", nil
+		case remoteKey([]string{"bake-apk", "--base", "base", "--name", "clone", "--golden", "/tmp/g", "--apk", "/tmp/a.apk", "--dest", "/tmp/b", "--json"}):
+			return `{"path":"/tmp/b","size_bytes":300,"boot_duration":45000000000,"peak_rss_bytes":2147483648,"packages":3,"golden":"/tmp/b","golden_size_bytes":400}`, "", nil
 		case remoteKey([]string{"ps", "--json"}):
 			return `[{"serial":"emulator-5580","name":"demo","port":5580,"pid":10,"booted":true}]`, "", nil
 		default:
@@ -275,6 +288,17 @@ func TestRemoteSavePrewarmBakeAndFindFreePort(t *testing.T) {
 	if err != nil || p != "/tmp/b" || sz != 300 {
 		t.Fatalf("BakeAPK(remote) mismatch: path=%q size=%d err=%v", p, sz, err)
 	}
+	report, err := m.BakeAPKWithReport(BakeAPKOptions{
+		BaseName:    "base",
+		CloneName:   "clone",
+		GoldenPath:  "/tmp/g",
+		APKPaths:    []string{"/tmp/a.apk"},
+		Destination: "/tmp/b",
+	})
+	if err != nil || report.Path != "/tmp/b" || report.SizeBytes != 300 || report.BootDuration != 45*time.Second ||
+		report.PeakRSSBytes != 2<<30 || report.Packages != 3 {
+		t.Fatalf("BakeAPKWithReport(remote) mismatch: %+v err=%v", report, err)
+	}
 
 	port, err := m.FindFreePort(5580, 5590)
 	if err != nil {
@@ -382,7 +406,7 @@ func TestRemoteAdditionalBranchesForCoverage(t *testing.T) {
 		}
 	})
 
-	t.Run("RunOnPort port zero delegates to Run", func(t *testing.T) {
+	t.Run("RunOnPort port zero reports the log too", func(t *testing.T) {
 		m := newRemoteManager(t)
 		withRemoteRunner(t, func(_ string, _ []string, avdArgs []string) (string, string, error) {
 			switch remoteKey(avdArgs) {
@@ -398,7 +422,7 @@ func TestRemoteAdditionalBranchesForCoverage(t *testing.T) {
 		if err != nil {
 			t.Fatalf("RunOnPort(port=0 remote) error: %v", err)
 		}
-		if serial != "emulator-5590" || logPath != "" {
+		if serial != "emulator-5590" || logPath != "/tmp/e-5590.log" {
 			t.Fatalf("RunOnPort(port=0 remote) mismatch: serial=%q log=%q", serial, logPath)
 		}
 	})
@@ -481,3 +505,1191 @@ func TestRemoteAdditionalBranchesForCoverage(t *testing.T) {
 		}
 	})
 }
+
+func TestRemoteRunSession(t *testing.T) {
+	m := newRemoteManager(t)
+	started := false
+	var calls []string
+	withRemoteRunner(t, func(_ string, _ []string, avdArgs []string) (string, string, error) {
+		calls = append(calls, strings.Join(avdArgs, " "))
+		switch avdArgs[0] {
+		case "ps":
+			if !started {
+				return `[]`, "", nil
+			}
+			return `[{"serial":"emulator-5580","name":"w-session","port":5580,"pid":42,"booted":true}]`, "", nil
+		case "run":
+			started = true
+			return "Started w-session on emulator-5580 (log: /tmp/e.log)\n", "", nil
+		case "capture":
+			return `{"dir":"/tmp/s","screenshot":"/tmp/s/screenshot.png","logcat":"/tmp/s/logcat.txt","errors":["bugreport: exit status 1"]}`, "", nil
+		}
+		return "", "", nil
+	})
+
+	var ranOn string
+	report, err := m.RunSession("w-session", time.Minute, SessionOptions{
+		OutputDir: "/tmp/s",
+		Run: func(_ context.Context, serial string) error {
+			ranOn = serial
+			return errors.New("assertion failed")
+		},
+	})
+	if err != nil {
+		t.Fatalf("RunSession(remote) error: %v", err)
+	}
+	if ranOn != "emulator-5580" || report.Serial != "emulator-5580" || report.BootedAt.IsZero() {
+		t.Fatalf("unexpected session: ran on %q, report %#v", ranOn, report)
+	}
+	if report.Reason != SessionCompleted || report.RunError != "assertion failed" {
+		t.Fatalf("unexpected end of session: %q %q", report.Reason, report.RunError)
+	}
+	if report.Capture.Screenshot != "/tmp/s/screenshot.png" || len(report.Capture.Errors) != 1 {
+		t.Fatalf("unexpected capture: %#v", report.Capture)
+	}
+	if last := calls[len(calls)-1]; last != "stop --serial emulator-5580" {
+		t.Fatalf("session must end with stop, got %q", last)
+	}
+
+}
+
+func TestRemoteRunSessionTimesOutWithoutRun(t *testing.T) {
+	m := newRemoteManager(t)
+	started := false
+	withRemoteRunner(t, func(_ string, _ []string, avdArgs []string) (string, string, error) {
+		switch avdArgs[0] {
+		case "ps":
+			if !started {
+				return `[]`, "", nil
+			}
+			return `[{"serial":"emulator-5580","name":"w-soak","port":5580,"pid":42,"booted":true}]`, "", nil
+		case "run":
+			started = true
+			return "Started w-soak on emulator-5580 (log: /tmp/e.log)\n", "", nil
+		case "capture":
+			return `{"dir":"/tmp/s"}`, "", nil
+		}
+		return "", "", nil
+	})
+	begin := time.Now()
+	report, err := m.RunSession("w-soak", 50*time.Millisecond, SessionOptions{OutputDir: "/tmp/s"})
+	if err != nil {
+		t.Fatalf("RunSession error: %v", err)
+	}
+	if report.Reason != SessionTimedOut || time.Since(begin) < 50*time.Millisecond {
+		t.Fatalf("expected timeout after the session duration, got %q", report.Reason)
+	}
+}
+
+func TestRemoteTraceOnBootAndStopTrace(t *testing.T) {
+	m := newRemoteManager(t)
+	var calls []string
+	withRemoteRunner(t, func(_ string, _ []string, avdArgs []string) (string, string, error) {
+		calls = append(calls, strings.Join(avdArgs, " "))
+		switch avdArgs[0] {
+		case "ps":
+			return `[]`, "", nil
+		case "run":
+			return "Started w-slow on emulator-5580 (log: /tmp/e.log)\n", "", nil
+		case "trace":
+			if avdArgs[1] == "stop" {
+				return "Trace saved: /tmp/traces/emulator-5580-1.perfetto-trace\n", "", nil
+			}
+		}
+		return "", "", nil
+	})
+
+	serial, err := m.Run(RunOptions{Name: "w-slow", TraceOnBoot: true, TraceConfig: TraceConfig{Duration: 5 * time.Minute}})
+	if err != nil || serial != "emulator-5580" {
+		t.Fatalf("Run(remote) = %q, %v", serial, err)
+	}
+	if last := calls[len(calls)-1]; last != "trace start --serial emulator-5580 --duration 5m0s" {
+		t.Fatalf("expected boot trace start, got %q", last)
+	}
+	path, err := m.StopTrace(serial, "/tmp/traces")
+	if err != nil || path != "/tmp/traces/emulator-5580-1.perfetto-trace" {
+		t.Fatalf("StopTrace(remote) = %q, %v", path, err)
+	}
+}
+
+func TestRemoteConsole(t *testing.T) {
+	m := newRemoteManager(t)
+	var got string
+	withRemoteRunner(t, func(_ string, _ []string, avdArgs []string) (string, string, error) {
+		got = strings.Join(avdArgs, " ")
+		return "acceleration = 0:9.8:0\n", "", nil
+	})
+	out, err := m.Console("emulator-5580", "sensor get acceleration")
+	if err != nil || out != "acceleration = 0:9.8:0" {
+		t.Fatalf("Console(remote) = %q, %v", out, err)
+	}
+	if got != "console --serial emulator-5580 -- sensor get acceleration" {
+		t.Fatalf("unexpected remote args %q", got)
+	}
+}
+
+func TestRemoteSensor(t *testing.T) {
+	m := newRemoteManager(t)
+	var calls [][]string
+	withRemoteRunner(t, func(_ string, _ []string, avdArgs []string) (string, string, error) {
+		calls = append(calls, avdArgs)
+		return "", "", nil
+	})
+	if err := m.SetSensor("emulator-5580", SensorAcceleration, []float64{0, 9.8, 0}); err != nil {
+		t.Fatalf("SetSensor(remote): %v", err)
+	}
+	if got := strings.Join(calls[0], " "); got != "sensor set --serial emulator-5580 acceleration 0:9.8:0" {
+		t.Fatalf("unexpected remote args %q", got)
+	}
+	samples := []SensorSample{{Offset: 250 * time.Millisecond, Sensor: SensorPressure, Values: []float64{1013.25}}}
+	if err := m.PlaySensorTrace("emulator-5580", samples); err != nil {
+		t.Fatalf("PlaySensorTrace(remote): %v", err)
+	}
+	if got := calls[1]; got[len(got)-2] != "--trace-text" || got[len(got)-1] != "250,pressure,1013.25\n" {
+		t.Fatalf("unexpected remote args %q", got)
+	}
+}
+
+func TestRemoteRotateAndPrewarmLockOrientation(t *testing.T) {
+	m := newRemoteManager(t)
+	var calls []string
+	withRemoteRunner(t, func(_ string, _ []string, avdArgs []string) (string, string, error) {
+		calls = append(calls, strings.Join(avdArgs, " "))
+		if avdArgs[0] == "prewarm" {
+			return "Prewarmed golden saved: /tmp/g (10 bytes)\n", "", nil
+		}
+		return "", "", nil
+	})
+	if err := m.Rotate("emulator-5580", OrientationLandscape); err != nil {
+		t.Fatalf("Rotate(remote): %v", err)
+	}
+	if calls[0] != "rotate --serial emulator-5580 landscape" {
+		t.Fatalf("unexpected remote args %q", calls[0])
+	}
+	if _, _, err := m.Prewarm(PrewarmOptions{Name: "base-a35", LockOrientation: OrientationPortrait}); err != nil {
+		t.Fatalf("Prewarm(remote): %v", err)
+	}
+	if !strings.HasSuffix(calls[1], "--setting settings put system accelerometer_rotation 0 --setting settings put system user_rotation 0") {
+		t.Fatalf("expected orientation lock settings, got %q", calls[1])
+	}
+	if _, _, err := m.Prewarm(PrewarmOptions{Name: "base-a35", LockOrientation: "sideways"}); err == nil {
+		t.Fatal("expected unknown orientation error")
+	}
+}
+
+func TestRemoteDisplaySize(t *testing.T) {
+	m := newRemoteManager(t)
+	var calls []string
+	withRemoteRunner(t, func(_ string, _ []string, avdArgs []string) (string, string, error) {
+		calls = append(calls, strings.Join(avdArgs, " "))
+		return "", "", nil
+	})
+	if err := m.SetDisplaySize("emulator-5580", 1200, 1920, 240); err != nil {
+		t.Fatalf("SetDisplaySize(remote): %v", err)
+	}
+	if err := m.ResetDisplaySize("emulator-5580"); err != nil {
+		t.Fatalf("ResetDisplaySize(remote): %v", err)
+	}
+	want := []string{
+		"display --serial emulator-5580 --size 1200x1920 --density 240",
+		"display --serial emulator-5580 --reset",
+	}
+	if strings.Join(calls, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("remote calls = %q, want %q", calls, want)
+	}
+}
+
+func TestRemoteAccessibilityService(t *testing.T) {
+	m := newRemoteManager(t)
+	var calls []string
+	withRemoteRunner(t, func(_ string, _ []string, avdArgs []string) (string, string, error) {
+		calls = append(calls, strings.Join(avdArgs, " "))
+		if avdArgs[0] == "prewarm" {
+			return "Prewarmed golden saved: /tmp/g (10 bytes)\n", "", nil
+		}
+		return "", "", nil
+	})
+	if err := m.EnableAccessibilityService("emulator-5580", "com.example.driver/.A11yService"); err != nil {
+		t.Fatalf("EnableAccessibilityService(remote): %v", err)
+	}
+	if calls[0] != "accessibility --serial emulator-5580 com.example.driver/.A11yService" {
+		t.Fatalf("unexpected remote args %q", calls[0])
+	}
+	if _, _, err := m.Prewarm(PrewarmOptions{Name: "base-a35", AccessibilityServices: []string{"com.example.driver/.A11yService"}}); err != nil {
+		t.Fatalf("Prewarm(remote): %v", err)
+	}
+	if !strings.HasSuffix(calls[1], "--accessibility-service com.example.driver/.A11yService") {
+		t.Fatalf("expected accessibility service flag, got %q", calls[1])
+	}
+}
+
+func TestRemoteRunDisablesDozeAfterBoot(t *testing.T) {
+	m := newRemoteManager(t)
+	var calls []string
+	psCalls := 0
+	withRemoteRunner(t, func(_ string, _ []string, avdArgs []string) (string, string, error) {
+		calls = append(calls, strings.Join(avdArgs, " "))
+		switch avdArgs[0] {
+		case "ps":
+			psCalls++
+			if psCalls == 1 {
+				return `[]`, "", nil
+			}
+			return `[{"serial":"emulator-5580","name":"w-long","port":5580,"pid":42,"booted":true}]`, "", nil
+		case "run":
+			return "Started w-long on emulator-5580 (log: /tmp/e.log)\n", "", nil
+		}
+		return "", "", nil
+	})
+	if _, err := m.Run(RunOptions{Name: "w-long", DisableDoze: true, BootTimeout: 2 * time.Second}); err != nil {
+		t.Fatalf("Run(remote): %v", err)
+	}
+	if last := calls[len(calls)-1]; last != "doze --serial emulator-5580 --disable" {
+		t.Fatalf("expected doze disabled after boot, got %q", last)
+	}
+	if err := m.SetAppStandbyBucket("emulator-5580", "com.example.app", StandbyActive); err != nil {
+		t.Fatalf("SetAppStandbyBucket(remote): %v", err)
+	}
+	if last := calls[len(calls)-1]; last != "standby-bucket --serial emulator-5580 com.example.app active" {
+		t.Fatalf("unexpected remote args %q", last)
+	}
+}
+
+func TestRemoteSaveGoldenValidate(t *testing.T) {
+	m := newRemoteManager(t)
+	var got string
+	withRemoteRunner(t, func(_ string, _ []string, avdArgs []string) (string, string, error) {
+		got = strings.Join(avdArgs, " ")
+		return "Golden saved: /tmp/out (10 bytes)\nGolden validated: a headless clone booted and passed the health checks\n", "", nil
+	})
+	p, _, err := m.SaveGolden(SaveGoldenOptions{Name: "demo", Destination: "/tmp/out", Validate: true, ValidateTimeout: 5 * time.Minute})
+	if err != nil || p != "/tmp/out" {
+		t.Fatalf("SaveGolden(remote) = %q, %v", p, err)
+	}
+	if got != "save-golden --name demo --dest /tmp/out --validate --validate-timeout 5m0s" {
+		t.Fatalf("unexpected remote args %q", got)
+	}
+}
+
+func TestRemoteSaveGoldenLayout(t *testing.T) {
+	m := newRemoteManager(t)
+	var got []string
+	withRemoteRunner(t, func(_ string, _ []string, avdArgs []string) (string, string, error) {
+		got = avdArgs
+		return "Golden saved: /tmp/out (10 bytes)\n", "", nil
+	})
+	layout := GoldenLayout{Images: []LayoutImage{{Name: "userdata-qemu.img", Fsck: true}, {Name: "vendor-qemu.img"}}}
+	if _, _, err := m.SaveGolden(SaveGoldenOptions{Name: "demo", Destination: "/tmp/out", Layout: &layout}); err != nil {
+		t.Fatalf("SaveGolden(remote): %v", err)
+	}
+	if len(got) != 7 || got[5] != "--layout" {
+		t.Fatalf("unexpected remote args %q", got)
+	}
+	remote, err := LoadGoldenLayout(got[6])
+	if err != nil || len(remote.Images) != 2 || remote.Images[1].Name != "vendor-qemu.img" {
+		t.Fatalf("remote layout = %#v, %v", remote, err)
+	}
+}
+
+func TestRemoteChannels(t *testing.T) {
+	m := newRemoteManager(t)
+	var calls []string
+	withRemoteRunner(t, func(_ string, _ []string, avdArgs []string) (string, string, error) {
+		calls = append(calls, strings.Join(avdArgs, " "))
+		switch avdArgs[1] {
+		case "promote", "rollback":
+			return `{"golden":"/goldens/v5","promoted_at":"2025-01-01T00:00:00Z"}`, "", nil
+		case "check":
+			return `{"channel":"production","golden":"/goldens/v5","healthy":["emulator-5580"],"unhealthy":{},"not_booted":null}`, "", nil
+		}
+		return `{}`, "", nil
+	})
+	pointer, err := m.Promote("@staging", ChannelProduction)
+	if err != nil || pointer.Golden != "/goldens/v5" {
+		t.Fatalf("Promote(remote) = %#v, %v", pointer, err)
+	}
+	if _, err := m.Rollback(ChannelProduction); err != nil {
+		t.Fatalf("Rollback(remote): %v", err)
+	}
+	health, err := m.CheckChannelHealth(ChannelProduction, ChannelHealthOptions{MaxFailures: 2, Rollback: true})
+	if err != nil || len(health.Healthy) != 1 {
+		t.Fatalf("CheckChannelHealth(remote) = %#v, %v", health, err)
+	}
+	want := []string{
+		"channel promote --golden @staging --channel production --json",
+		"channel rollback --channel production --json",
+		"channel check --channel production --json --max-failures 2 --rollback",
+	}
+	if strings.Join(calls, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected remote calls:\n%s", strings.Join(calls, "\n"))
+	}
+}
+
+func TestRemoteCloneMany(t *testing.T) {
+	m := newRemoteManager(t)
+	var calls []string
+	withRemoteRunner(t, func(_ string, _ []string, avdArgs []string) (string, string, error) {
+		calls = append(calls, strings.Join(avdArgs, " "))
+		if avdArgs[0] == "list" {
+			return `[{"Name":"w-1","Path":"/avd/w-1.avd"},{"Name":"w-2","Path":"/avd/w-2.avd"}]`, "", nil
+		}
+		return "", "", nil
+	})
+	infos, err := m.CloneMany(CloneOptions{BaseName: "base-a35", GoldenPath: "@production"}, []string{"w-1", "w-2"})
+	if err != nil || len(infos) != 2 {
+		t.Fatalf("CloneMany(remote) = %#v, %v", infos, err)
+	}
+	if calls[0] != "clone --base base-a35 --name w-1 --name w-2 --golden @production" {
+		t.Fatalf("unexpected remote args %q", calls[0])
+	}
+}
+
+func TestRemoteReset(t *testing.T) {
+	m := newRemoteManager(t)
+	var calls []string
+	withRemoteRunner(t, func(_ string, _ []string, avdArgs []string) (string, string, error) {
+		calls = append(calls, strings.Join(avdArgs, " "))
+		return `{"name":"w-1","copied":["userdata-qemu.img"],"skipped":["cache.img"]}`, "", nil
+	})
+	result, err := m.Reset("w-1", "@production")
+	if err != nil || result.Name != "w-1" || len(result.Copied) != 1 || len(result.Skipped) != 1 {
+		t.Fatalf("Reset(remote) = %#v, %v", result, err)
+	}
+	if got := strings.Join(calls, "\n"); got != "reset --name w-1 --golden @production --json" {
+		t.Fatalf("unexpected remote calls:\n%s", got)
+	}
+}
+
+func TestRemoteForceDeleteAndPin(t *testing.T) {
+	m := newRemoteManager(t)
+	var calls []string
+	withRemoteRunner(t, func(_ string, _ []string, avdArgs []string) (string, string, error) {
+		calls = append(calls, strings.Join(avdArgs, " "))
+		return "", "", nil
+	})
+	if err := m.Pin("base-a35", "hand-configured"); err != nil {
+		t.Fatalf("Pin(remote): %v", err)
+	}
+	if err := m.Unpin("base-a35"); err != nil {
+		t.Fatalf("Unpin(remote): %v", err)
+	}
+	if err := m.DeleteWithOptions("base-a35", DeleteOptions{Force: true}); err != nil {
+		t.Fatalf("DeleteWithOptions(remote): %v", err)
+	}
+	want := "pin base-a35 --reason hand-configured\nunpin base-a35\ndelete base-a35 --force"
+	if got := strings.Join(calls, "\n"); got != want {
+		t.Fatalf("unexpected remote calls:\n%s", got)
+	}
+}
+
+func TestRemoteDeleteTree(t *testing.T) {
+	m := newRemoteManager(t)
+	var calls []string
+	withRemoteRunner(t, func(_ string, _ []string, avdArgs []string) (string, string, error) {
+		calls = append(calls, strings.Join(avdArgs, " "))
+		return `{"base":"base-a35","items":[{"kind":"clone","name":"w-1","path":"/avd/w-1.avd","status":"planned"},{"kind":"base","name":"base-a35","path":"/avd/base-a35.avd","status":"planned"}]}`, "", nil
+	})
+	report, err := m.DeleteTree("base-a35", DeleteTreeOptions{DryRun: true})
+	if err != nil || report.Base != "base-a35" || len(report.Items) != 2 || report.Items[0].Kind != "clone" {
+		t.Fatalf("DeleteTree(remote) = %#v, %v", report, err)
+	}
+	if got := strings.Join(calls, "\n"); got != "delete android base-a35 --tree --json --dry-run" {
+		t.Fatalf("unexpected remote calls:\n%s", got)
+	}
+}
+
+func TestRemoteTrash(t *testing.T) {
+	m := newRemoteManager(t)
+	var calls []string
+	withRemoteRunner(t, func(_ string, _ []string, avdArgs []string) (string, string, error) {
+		calls = append(calls, strings.Join(avdArgs, " "))
+		if avdArgs[0] == "restore" {
+			return `{"id":"base-1","name":"base","kind":"avd","path":"/avd/base.avd"}`, "", nil
+		}
+		return `[{"id":"base-1","name":"base","kind":"avd","path":"/avd/base.avd"}]`, "", nil
+	})
+	if entries, err := m.ListTrash(); err != nil || len(entries) != 1 || entries[0].Name != "base" {
+		t.Fatalf("ListTrash(remote) = %#v, %v", entries, err)
+	}
+	if entry, err := m.Restore("base"); err != nil || entry.Path != "/avd/base.avd" {
+		t.Fatalf("Restore(remote) = %#v, %v", entry, err)
+	}
+	if _, err := m.PurgeTrash(true); err != nil {
+		t.Fatalf("PurgeTrash(remote): %v", err)
+	}
+	if err := m.DeleteWithOptions("w-1", DeleteOptions{SkipTrash: true}); err != nil {
+		t.Fatalf("DeleteWithOptions(remote): %v", err)
+	}
+	want := "trash list --json\nrestore base --json\ntrash purge --json --all\ndelete w-1 --no-trash"
+	if got := strings.Join(calls, "\n"); got != want {
+		t.Fatalf("unexpected remote calls:\n%s", got)
+	}
+}
+
+func TestRemoteInspectHardwareConfig(t *testing.T) {
+	m := newRemoteManager(t)
+	var calls []string
+	withRemoteRunner(t, func(_ string, _ []string, avdArgs []string) (string, string, error) {
+		calls = append(calls, strings.Join(avdArgs, " "))
+		return `{"name":"w-1","path":"/avd/w-1.avd/hardware-qemu.ini","entries":[{"key":"hw.ramSize","value":"2048"}],` +
+			`"other_avd_paths":[{"key":"disk.dataPartition.path","value":"/avd/base.avd/userdata-qemu.img"}]}`, "", nil
+	})
+	out, err := m.InspectHardwareConfig("w-1")
+	if err != nil || len(out.Entries) != 1 || len(out.OtherAVDPaths) != 1 {
+		t.Fatalf("InspectHardwareConfig(remote) = %#v, %v", out, err)
+	}
+	if got := strings.Join(calls, "\n"); got != "hwconfig w-1 --json" {
+		t.Fatalf("unexpected remote calls:\n%s", got)
+	}
+}
+
+func TestRemoteGuestStorageAndResize(t *testing.T) {
+	m := newRemoteManager(t)
+	var calls []string
+	withRemoteRunner(t, func(_ string, _ []string, avdArgs []string) (string, string, error) {
+		calls = append(calls, strings.Join(avdArgs, " "))
+		if avdArgs[0] == "guest-storage" {
+			return `{"serial":"emulator-5580","size_bytes":100,"used_bytes":95,"avail_bytes":5}`, "", nil
+		}
+		return `{"name":"w-1","image":"/avd/w-1.avd/userdata-qemu.img","old_size_bytes":1,"new_size_bytes":8589934592}`, "", nil
+	})
+	storage, err := m.GuestStorage("emulator-5580")
+	if err != nil || storage.UsedFraction() < 0.9 {
+		t.Fatalf("GuestStorage(remote) = %#v, %v", storage, err)
+	}
+	if result, err := m.ResizeUserdata("w-1", 8<<30); err != nil || result.NewSizeBytes != 8<<30 {
+		t.Fatalf("ResizeUserdata(remote) = %#v, %v", result, err)
+	}
+	want := "guest-storage --serial emulator-5580 --json\nresize-userdata --name w-1 --size 8589934592 --json"
+	if got := strings.Join(calls, "\n"); got != want {
+		t.Fatalf("unexpected remote calls:\n%s", got)
+	}
+}
+
+func TestRemotePauseResume(t *testing.T) {
+	m := newRemoteManager(t)
+	var calls []string
+	withRemoteRunner(t, func(_ string, _ []string, avdArgs []string) (string, string, error) {
+		calls = append(calls, strings.Join(avdArgs, " "))
+		return "", "", nil
+	})
+	if err := m.Pause("emulator-5580"); err != nil {
+		t.Fatalf("Pause(remote): %v", err)
+	}
+	if err := m.Resume("emulator-5580"); err != nil {
+		t.Fatalf("Resume(remote): %v", err)
+	}
+	want := "pause --serial emulator-5580\nresume --serial emulator-5580"
+	if got := strings.Join(calls, "\n"); got != want {
+		t.Fatalf("unexpected remote calls:\n%s", got)
+	}
+}
+
+func TestRemoteHibernateAndWake(t *testing.T) {
+	m := newRemoteManager(t)
+	var calls []string
+	withRemoteRunner(t, func(_ string, _ []string, avdArgs []string) (string, string, error) {
+		calls = append(calls, strings.Join(avdArgs, " "))
+		switch avdArgs[0] {
+		case "hibernate":
+			if avdArgs[1] == "--list" {
+				return `[{"name":"w-1","snapshot":"avdctl-hibernate","port":5580}]`, "", nil
+			}
+			return `{"name":"w-1","snapshot":"avdctl-hibernate","port":5580,"size_bytes":2048}`, "", nil
+		case "wake":
+			return "Started w-1 on emulator-5580 (log: /tmp/w-1.log)\n", "", nil
+		}
+		return "", "", nil
+	})
+	h, err := m.Hibernate("w-1")
+	if err != nil {
+		t.Fatalf("Hibernate(remote): %v", err)
+	}
+	if h.SizeBytes != 2048 || h.Port != 5580 {
+		t.Fatalf("unexpected hibernation: %+v", h)
+	}
+	list, err := m.ListHibernated()
+	if err != nil || len(list) != 1 || list[0].Name != "w-1" {
+		t.Fatalf("ListHibernated(remote) = %+v, %v", list, err)
+	}
+	serial, logPath, err := m.Wake("w-1")
+	if err != nil {
+		t.Fatalf("Wake(remote): %v", err)
+	}
+	if serial != "emulator-5580" || logPath != "/tmp/w-1.log" {
+		t.Fatalf("Wake(remote) = %q, %q", serial, logPath)
+	}
+	want := "hibernate w-1 --json\nhibernate --list --json\nwake w-1"
+	if got := strings.Join(calls, "\n"); got != want {
+		t.Fatalf("unexpected remote calls:\n%s", got)
+	}
+}
+
+func TestRemoteWaitForBootWithReadyBroadcast(t *testing.T) {
+	m := newRemoteManager(t)
+	var calls []string
+	withRemoteRunner(t, func(_ string, _ []string, avdArgs []string) (string, string, error) {
+		calls = append(calls, strings.Join(avdArgs, " "))
+		if avdArgs[0] == "ps" {
+			return `[{"serial":"emulator-5580","name":"w-1","port":5580,"booted":true}]`, "", nil
+		}
+		return "emulator-5580 ready\n", "", nil
+	})
+	var statuses []string
+	err := m.WaitForBootWithOptions("emulator-5580", WaitOptions{
+		Timeout:        time.Minute,
+		Progress:       func(status string, _ time.Duration) { statuses = append(statuses, status) },
+		ReadyBroadcast: &ReadyBroadcast{Action: "com.example.READY", Package: "com.example", Data: "ok"},
+	})
+	if err != nil {
+		t.Fatalf("WaitForBootWithOptions(remote): %v", err)
+	}
+	last := calls[len(calls)-1]
+	if !strings.HasPrefix(last, "wait --serial emulator-5580 --timeout ") ||
+		!strings.HasSuffix(last, " --ready-action com.example.READY --ready-package com.example --ready-data ok") {
+		t.Fatalf("unexpected remote wait: %q", last)
+	}
+	if statuses[len(statuses)-1] != "app_ready" {
+		t.Fatalf("statuses = %v", statuses)
+	}
+}
+
+func TestRemoteAdopt(t *testing.T) {
+	m := newRemoteManager(t)
+	var got []string
+	withRemoteRunner(t, func(_ string, _ []string, avdArgs []string) (string, string, error) {
+		got = avdArgs
+		return `{"serial":"emulator-5554","name":"Pixel_8_API_35","pid":4242}`, "", nil
+	})
+	inst, err := m.Adopt("emulator-5554")
+	if err != nil {
+		t.Fatalf("Adopt(remote): %v", err)
+	}
+	if inst.Name != "Pixel_8_API_35" || inst.PID != 4242 {
+		t.Fatalf("unexpected instance: %+v", inst)
+	}
+	if strings.Join(got, " ") != "adopt --serial emulator-5554 --json" {
+		t.Fatalf("unexpected remote args: %v", got)
+	}
+}
+
+func TestRemoteMigrateHome(t *testing.T) {
+	m := newRemoteManager(t)
+	var got []string
+	withRemoteRunner(t, func(_ string, _ []string, avdArgs []string) (string, string, error) {
+		got = avdArgs
+		return `{"from":"/home/ci/.android/avd","to":"/data/avd","avds":[{"name":"w-1","status":"planned"}]}`, "", nil
+	})
+	report, err := m.MigrateHome(HomeMigrationOptions{From: "/home/ci/.android/avd", To: "/data/avd", DryRun: true})
+	if err != nil {
+		t.Fatalf("MigrateHome(remote): %v", err)
+	}
+	if len(report.AVDs) != 1 || report.AVDs[0].Status != "planned" {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if want := "migrate --json --from /home/ci/.android/avd --to /data/avd --dry-run"; strings.Join(got, " ") != want {
+		t.Fatalf("unexpected remote args: %v", got)
+	}
+}
+
+func TestRemoteRunAndWait(t *testing.T) {
+	m := newRemoteManager(t)
+	started := false
+	var calls []string
+	withRemoteRunner(t, func(_ string, _ []string, avdArgs []string) (string, string, error) {
+		calls = append(calls, strings.Join(avdArgs, " "))
+		switch avdArgs[0] {
+		case "ps":
+			if !started {
+				return `[]`, "", nil
+			}
+			return `[{"serial":"emulator-5580","name":"w-1","port":5580,"pid":41,"booted":true,"log_path":"/tmp/w-1.log"}]`, "", nil
+		case "run":
+			started = true
+			return "Started w-1 on emulator-5580\n", "", nil
+		case "health":
+			return `[{"name":"package-manager","ok":true},{"name":"home-activity","ok":false,"detail":"no launcher"}]`, "", nil
+		}
+		return "", "", nil
+	})
+	var stages []string
+	result, err := m.RunAndWait(RunAndWaitOptions{
+		RunOptions: RunOptions{Name: "w-1", BootTimeout: 5 * time.Second},
+		Progress:   func(status string, _ time.Duration) { stages = append(stages, status) },
+	})
+	if err == nil || !strings.Contains(err.Error(), "home-activity: no launcher") {
+		t.Fatalf("RunAndWait(remote) error = %v, want a failed health check", err)
+	}
+	if result.Serial != "emulator-5580" || result.LogPath != "/tmp/w-1.log" || result.BootDuration <= 0 || len(result.Checks) != 2 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	if len(stages) == 0 || stages[len(stages)-1] != "boot_complete" {
+		t.Fatalf("unexpected progress: %v", stages)
+	}
+	if last := calls[len(calls)-1]; last != "health --serial emulator-5580 --json" {
+		t.Fatalf("expected a health check last, got %q", last)
+	}
+}
+
+func TestRemoteStickyPorts(t *testing.T) {
+	m := newRemoteManager(t)
+	var calls []string
+	withRemoteRunner(t, func(_ string, _ []string, avdArgs []string) (string, string, error) {
+		calls = append(calls, strings.Join(avdArgs, " "))
+		return `{"w-acme":5580}`, "", nil
+	})
+	ports, err := m.StickyPorts()
+	if err != nil || ports["w-acme"] != 5580 {
+		t.Fatalf("StickyPorts(remote) = %v, %v", ports, err)
+	}
+	if err := m.SetStickyPort("w-acme", 5600); err != nil {
+		t.Fatalf("SetStickyPort(remote): %v", err)
+	}
+	if err := m.SetStickyPort("w-acme", 0); err != nil {
+		t.Fatalf("SetStickyPort(remote, 0): %v", err)
+	}
+	want := []string{"port --json", "port --name w-acme --set 5600", "port --name w-acme --clear"}
+	if strings.Join(calls, "|") != strings.Join(want, "|") {
+		t.Fatalf("unexpected remote calls: %v", calls)
+	}
+}
+
+func TestRemoteInspect(t *testing.T) {
+	m := newRemoteManager(t)
+	withRemoteRunner(t, func(_ string, _ []string, avdArgs []string) (string, string, error) {
+		if remoteKey(avdArgs) != remoteKey([]string{"inspect", "--name", "w-acme", "--json"}) {
+			t.Fatalf("unexpected remote args: %v", avdArgs)
+		}
+		return `{"name":"w-acme","running":true,"pid":41,"command":["/sdk/bin","-avd","w-acme"],"qemu_pid":42}`, "", nil
+	})
+	ins, err := m.Inspect("w-acme")
+	if err != nil {
+		t.Fatalf("Inspect(remote) error: %v", err)
+	}
+	if !ins.Running || ins.QemuPID != 42 || len(ins.Command) != 3 {
+		t.Fatalf("Inspect(remote) = %+v", ins)
+	}
+}
+
+func TestRemoteRunWithFeatures(t *testing.T) {
+	m := newRemoteManager(t)
+	var runArgs []string
+	withRemoteRunner(t, func(_ string, _ []string, avdArgs []string) (string, string, error) {
+		switch avdArgs[0] {
+		case "ps":
+			return "[]", "", nil
+		case "run":
+			runArgs = avdArgs
+			return "Started w-1 on emulator-5580 (log: /tmp/w-1.log) pid 7\n", "", nil
+		}
+		return "", "", nil
+	})
+	if _, err := m.Start(RunOptions{Name: "w-1", Features: map[string]bool{"Vulkan": true, "VirtioWifi": false}}); err != nil {
+		t.Fatalf("Start(remote) error: %v", err)
+	}
+	want := []string{"run", "--name", "w-1", "--feature", "VirtioWifi=off", "--feature", "Vulkan=on"}
+	if remoteKey(runArgs) != remoteKey(want) {
+		t.Fatalf("remote run args = %v, want %v", runArgs, want)
+	}
+
+	opts := RunOptions{Name: "w-1", Features: map[string]bool{"BluetoothEmulation": true}, DisableBluetoothEmulation: true}
+	if _, err := m.Start(opts); err != nil {
+		t.Fatalf("Start(remote) error: %v", err)
+	}
+	want = []string{"run", "--name", "w-1", "--feature", "BluetoothEmulation=off"}
+	if remoteKey(runArgs) != remoteKey(want) {
+		t.Fatalf("remote run args = %v, want %v", runArgs, want)
+	}
+	if !opts.Features["BluetoothEmulation"] {
+		t.Fatalf("Start must not modify RunOptions.Features")
+	}
+}
+
+func TestRemoteRunSandboxed(t *testing.T) {
+	m := newRemoteManager(t)
+	var runArgs []string
+	withRemoteRunner(t, func(_ string, _ []string, avdArgs []string) (string, string, error) {
+		switch avdArgs[0] {
+		case "ps":
+			return "[]", "", nil
+		case "run":
+			runArgs = avdArgs
+			return "Started w-1 on emulator-5580 (log: /tmp/w-1.log) pid 7\n", "", nil
+		}
+		return "", "", nil
+	})
+	sb := &Sandbox{Tool: SandboxBwrap, IsolateNetwork: true, Writable: []string{"/srv/captures"}}
+	if _, err := m.Start(RunOptions{Name: "w-1", Sandbox: sb}); err != nil {
+		t.Fatalf("Start(remote) error: %v", err)
+	}
+	want := []string{"run", "--name", "w-1", "--sandbox", "bwrap", "--sandbox-isolate-net", "--sandbox-writable", "/srv/captures"}
+	if remoteKey(runArgs) != remoteKey(want) {
+		t.Fatalf("remote run args = %v, want %v", runArgs, want)
+	}
+	runArgs = nil
+	if _, err := m.Start(RunOptions{Name: "w-1", Sandbox: &Sandbox{Tool: "firejail"}}); err == nil || runArgs != nil {
+		t.Fatalf("expected an invalid sandbox to fail before the remote run, got %v", err)
+	}
+	if _, err := m.Start(RunOptions{Name: "w-1", NetworkNamespace: true}); err != nil {
+		t.Fatalf("Start(remote) error: %v", err)
+	}
+	if want := []string{"run", "--name", "w-1", "--netns"}; remoteKey(runArgs) != remoteKey(want) {
+		t.Fatalf("remote run args = %v, want %v", runArgs, want)
+	}
+	policy := &NetworkPolicy{Allow: []string{"api.staging.example.com"}, Deny: []string{"10.9.0.0/16"}}
+	if _, err := m.Start(RunOptions{Name: "w-1", NetworkPolicy: policy}); err != nil {
+		t.Fatalf("Start(remote) error: %v", err)
+	}
+	want = []string{"run", "--name", "w-1", "--netns", "--netns-allow", "api.staging.example.com", "--netns-deny", "10.9.0.0/16"}
+	if remoteKey(runArgs) != remoteKey(want) {
+		t.Fatalf("remote run args = %v, want %v", runArgs, want)
+	}
+}
+
+func TestRemoteNetworkCapture(t *testing.T) {
+	m := newRemoteManager(t)
+	var calls []string
+	withRemoteRunner(t, func(_ string, _ []string, avdArgs []string) (string, string, error) {
+		calls = append(calls, strings.Join(avdArgs, " "))
+		if avdArgs[1] == "start" {
+			return "Capturing to: /srv/pcap/emulator-5580-20250101T000000Z.pcap\n", "", nil
+		}
+		return "Capture saved: /srv/pcap/emulator-5580-20250101T000000Z.pcap\n", "", nil
+	})
+	path, err := m.StartCapture("emulator-5580", "/srv/pcap")
+	if err != nil || path != "/srv/pcap/emulator-5580-20250101T000000Z.pcap" {
+		t.Fatalf("StartCapture = %q, %v", path, err)
+	}
+	if path, err = m.StopCapture("emulator-5580"); err != nil || path != "/srv/pcap/emulator-5580-20250101T000000Z.pcap" {
+		t.Fatalf("StopCapture = %q, %v", path, err)
+	}
+	want := []string{"pcap start --serial emulator-5580 --out /srv/pcap", "pcap stop --serial emulator-5580"}
+	if strings.Join(calls, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("remote calls = %v, want %v", calls, want)
+	}
+}
+
+func TestRemoteFrida(t *testing.T) {
+	m := newRemoteManager(t)
+	var calls []string
+	withRemoteRunner(t, func(_ string, _ []string, avdArgs []string) (string, string, error) {
+		calls = append(calls, strings.Join(avdArgs, " "))
+		if avdArgs[1] == "start" {
+			return `{"serial":"emulator-5580","pid":4242,"host_port":27050,"device_port":27042}`, "", nil
+		}
+		return "frida-server stopped on emulator-5580\n", "", nil
+	})
+	session, err := m.StartFridaOnPort("emulator-5580", 27050)
+	if err != nil || session.PID != 4242 || session.HostPort != 27050 || session.DevicePort != DefaultFridaPort {
+		t.Fatalf("StartFridaOnPort = %+v, %v", session, err)
+	}
+	if _, err := m.StartFrida("emulator-5580"); err != nil {
+		t.Fatalf("StartFrida: %v", err)
+	}
+	if err := m.StopFrida("emulator-5580"); err != nil {
+		t.Fatalf("StopFrida: %v", err)
+	}
+	want := []string{
+		"frida start --serial emulator-5580 --json --port 27050",
+		"frida start --serial emulator-5580 --json",
+		"frida stop --serial emulator-5580",
+	}
+	if strings.Join(calls, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("remote calls = %v, want %v", calls, want)
+	}
+}
+
+func TestRemoteInstallAPKAndDeviceSpec(t *testing.T) {
+	m := newRemoteManager(t)
+	var calls []string
+	withRemoteRunner(t, func(_ string, _ []string, avdArgs []string) (string, string, error) {
+		calls = append(calls, strings.Join(avdArgs, " "))
+		if avdArgs[0] == "device-spec" {
+			return `{"supportedAbis":["arm64-v8a"],"supportedLocales":["en-US"],"screenDensity":420,"sdkVersion":34}`, "", nil
+		}
+		return "Installed /srv/apps/wallet.aab on emulator-5580\n", "", nil
+	})
+	if err := m.InstallAPK("emulator-5580", "/srv/apps/wallet.aab"); err != nil {
+		t.Fatalf("InstallAPK: %v", err)
+	}
+	spec, err := m.DeviceSpec("w-1")
+	if err != nil || spec.SDKVersion != 34 || len(spec.SupportedABIs) != 1 || spec.SupportedABIs[0] != "arm64-v8a" {
+		t.Fatalf("DeviceSpec = %+v, %v", spec, err)
+	}
+	want := []string{"install --serial emulator-5580 /srv/apps/wallet.aab", "device-spec --name w-1"}
+	if strings.Join(calls, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("remote calls = %v, want %v", calls, want)
+	}
+}
+
+func TestRemoteBakeAPKSideloads(t *testing.T) {
+	m := newRemoteManager(t)
+	var got []string
+	withRemoteRunner(t, func(_ string, _ []string, avdArgs []string) (string, string, error) {
+		got = avdArgs
+		return "Baked clone at /srv/avd/w-game.avd/userdata-qemu.img (300 bytes)\n", "", nil
+	})
+	if _, _, err := m.BakeAPK(BakeAPKOptions{
+		BaseName:   "base",
+		CloneName:  "w-game",
+		GoldenPath: "/srv/g",
+		APKPaths:   []string{"/srv/game.apk"},
+		OBBPaths:   []string{"/srv/main.7.com.acme.game.obb"},
+		AppData:    []AppData{{Package: "com.acme.game", Dir: "/srv/game-data"}},
+	}); err != nil {
+		t.Fatalf("BakeAPK(remote): %v", err)
+	}
+	want := []string{"bake-apk", "--base", "base", "--name", "w-game", "--golden", "/srv/g", "--apk", "/srv/game.apk",
+		"--obb", "/srv/main.7.com.acme.game.obb", "--app-data", "com.acme.game=/srv/game-data"}
+	if remoteKey(got) != remoteKey(want) {
+		t.Fatalf("remote args = %v, want %v", got, want)
+	}
+}
+
+func TestRemoteStartRefusesSecretsProvisioner(t *testing.T) {
+	m := newRemoteManager(t)
+	called := false
+	withRemoteRunner(t, func(_ string, _ []string, avdArgs []string) (string, string, error) {
+		called = true
+		return "", "", nil
+	})
+	_, err := m.Start(RunOptions{Name: "w-acme", SecretsProvisioner: func(*SecretsSession) error { return nil }})
+	if err == nil || !strings.Contains(err.Error(), "SSH") {
+		t.Fatalf("Start(remote, SecretsProvisioner) = %v, want an SSH error", err)
+	}
+	if called {
+		t.Fatal("remote avdctl was invoked")
+	}
+}
+
+func TestRemoteWaitForApp(t *testing.T) {
+	m := newRemoteManager(t)
+	var got []string
+	withRemoteRunner(t, func(_ string, _ []string, avdArgs []string) (string, string, error) {
+		got = avdArgs
+		return `{"package":"com.acme.wallet","activity":"com.acme.wallet/.MainActivity","pid":4242,"duration":850000000}`, "", nil
+	})
+	ready, err := m.WaitForApp("emulator-5580", "com.acme.wallet", 90*time.Second)
+	if err != nil {
+		t.Fatalf("WaitForApp(remote): %v", err)
+	}
+	want := []string{"wait-app", "--serial", "emulator-5580", "--json", "--timeout", "1m30s", "com.acme.wallet"}
+	if remoteKey(got) != remoteKey(want) {
+		t.Fatalf("remote args = %v, want %v", got, want)
+	}
+	if ready.PID != 4242 || ready.Duration != 850*time.Millisecond {
+		t.Fatalf("ready = %+v", ready)
+	}
+}
+
+func TestRemoteWaitForBootPhaseTimeouts(t *testing.T) {
+	m := newRemoteManager(t)
+	var got []string
+	withRemoteRunner(t, func(_ string, _ []string, avdArgs []string) (string, string, error) {
+		got = avdArgs
+		return "emulator-5580 ready\n", "", nil
+	})
+	err := m.WaitForBootWithOptions("emulator-5580", WaitOptions{
+		Timeout:       5 * time.Minute,
+		PhaseTimeouts: PhaseTimeouts{BootPhaseWaitingADB: 30 * time.Second, BootPhaseCheckingBootanim: 4 * time.Minute},
+	})
+	if err != nil {
+		t.Fatalf("WaitForBootWithOptions(remote): %v", err)
+	}
+	want := []string{"wait", "--serial", "emulator-5580", "--timeout", "5m0s",
+		"--phase-timeout", "waiting_adb=30s", "--phase-timeout", "checking_bootanim=4m0s"}
+	if remoteKey(got) != remoteKey(want) {
+		t.Fatalf("remote args = %v, want %v", got, want)
+	}
+	if err := m.WaitForBootWithOptions("emulator-5580", WaitOptions{PhaseTimeouts: PhaseTimeouts{BootPhaseAppReady: time.Second}}); err == nil {
+		t.Fatal("expected app_ready to be refused as a phase timeout")
+	}
+}
+
+func TestRemotePrewarmWarmUpAndFreeze(t *testing.T) {
+	m := newRemoteManager(t)
+	var calls []string
+	withRemoteRunner(t, func(_ string, _ []string, avdArgs []string) (string, string, error) {
+		calls = append(calls, strings.Join(avdArgs, " "))
+		return "Prewarmed golden saved: /tmp/g (10 bytes)\n", "", nil
+	})
+	opts := PrewarmOptions{Name: "base-play", WarmApps: []string{"com.android.vending", "com.google.android.gms"}, CompileFilter: "speed-profile"}
+	if _, _, err := m.Prewarm(opts); err != nil {
+		t.Fatalf("Prewarm(remote): %v", err)
+	}
+	if !strings.HasSuffix(calls[0], "--warm-app com.android.vending --warm-app com.google.android.gms --compile speed-profile") {
+		t.Fatalf("expected warm-up flags, got %q", calls[0])
+	}
+	if _, _, err := m.Prewarm(PrewarmOptions{Name: "base-play", FreezeUpdates: true}); err != nil {
+		t.Fatalf("Prewarm(remote): %v", err)
+	}
+	if !strings.HasSuffix(calls[1], "--freeze-updates") {
+		t.Fatalf("expected --freeze-updates, got %q", calls[1])
+	}
+}
+
+func TestRemoteRunSharedAVD(t *testing.T) {
+	m := newRemoteManager(t)
+	var calls []string
+	withRemoteRunner(t, func(_ string, _ []string, avdArgs []string) (string, string, error) {
+		calls = append(calls, strings.Join(avdArgs, " "))
+		switch avdArgs[0] {
+		case "ps":
+			return `[{"serial":"emulator-5580","name":"w-tmp","port":5580,"pid":10,"booted":true}]`, "", nil
+		case "run":
+			return "Started w-tmp on emulator-5582 (log: /tmp/e.log)\n", "", nil
+		}
+		return "", "", nil
+	})
+	_, err := m.Run(RunOptions{Name: "w-tmp"})
+	var inUse *AVDInUseError
+	if !errors.As(err, &inUse) || inUse.Serial != "emulator-5580" {
+		t.Fatalf("expected an AVDInUseError for emulator-5580, got %v", err)
+	}
+	if _, err := m.Run(RunOptions{Name: "w-tmp", Shared: true}); err != nil {
+		t.Fatalf("Run(remote, shared): %v", err)
+	}
+	if last := calls[len(calls)-1]; last != "run --name w-tmp --shared" {
+		t.Fatalf("expected a shared run, got %q", last)
+	}
+}
+
+func TestRemoteStartShared(t *testing.T) {
+	m := newRemoteManager(t)
+	var calls []string
+	failAt := 0
+	withRemoteRunner(t, func(_ string, _ []string, avdArgs []string) (string, string, error) {
+		calls = append(calls, strings.Join(avdArgs, " "))
+		switch avdArgs[0] {
+		case "ps":
+			return `[{"serial":"emulator-5580","name":"w-tmp","port":5580,"pid":10,"booted":true}]`, "", nil
+		case "run":
+			port := avdArgs[len(avdArgs)-2]
+			if failAt--; failAt == 0 {
+				return "", "boom", errors.New("exit status 1")
+			}
+			return "Started w-tmp on emulator-" + port + " (log: /tmp/e.log)\n", "", nil
+		}
+		return "", "", nil
+	})
+	res, err := m.StartShared(RunOptions{Name: "w-tmp", Port: 5578}, 2)
+	if err != nil {
+		t.Fatalf("StartShared(remote): %v", err)
+	}
+	if len(res) != 2 || res[0].Serial != "emulator-5578" || res[1].Serial != "emulator-5582" {
+		t.Fatalf("unexpected instances %+v", res)
+	}
+	var runs []string
+	for _, call := range calls {
+		if strings.HasPrefix(call, "run ") {
+			runs = append(runs, call)
+		}
+	}
+	if want := "run --name w-tmp --port 5582 --shared"; len(runs) != 2 || runs[1] != want {
+		t.Fatalf("runs = %q, want the second on %q", runs, want)
+	}
+
+	calls, failAt = nil, 2
+	if _, err := m.StartShared(RunOptions{Name: "w-tmp", Port: 5578}, 3); err == nil {
+		t.Fatal("expected an error when an instance fails to start")
+	}
+	if last := calls[len(calls)-1]; last != "stop --serial emulator-5578" {
+		t.Fatalf("started instance not stopped, last call %q", last)
+	}
+}
+
+func TestRemoteRunStartupRetries(t *testing.T) {
+	m := newRemoteManager(t)
+	var last string
+	withRemoteRunner(t, func(_ string, _ []string, avdArgs []string) (string, string, error) {
+		switch avdArgs[0] {
+		case "ps":
+			return `[]`, "", nil
+		case "run":
+			last = strings.Join(avdArgs, " ")
+			return "Started w-tmp on emulator-5580 (log: /tmp/e.log)\n", "", nil
+		}
+		return "", "", nil
+	})
+	if _, err := m.Run(RunOptions{Name: "w-tmp", StartupRetries: 2, StallTimeout: 20 * time.Second}); err != nil {
+		t.Fatalf("Run(remote): %v", err)
+	}
+	if want := "run --name w-tmp --startup-retries 2 --stall-timeout 20s"; last != want {
+		t.Fatalf("run args = %q, want %q", last, want)
+	}
+}
+
+func TestRemoteCheckAfterResume(t *testing.T) {
+	m := newRemoteManager(t)
+	var last string
+	withRemoteRunner(t, func(_ string, _ []string, avdArgs []string) (string, string, error) {
+		last = strings.Join(avdArgs, " ")
+		return `[{"serial":"emulator-5580","name":"w-1","survived":false,"clock_synced":false,"problems":["adb offline"]}]`, "", nil
+	})
+	checks, err := m.CheckAfterResume()
+	if err != nil {
+		t.Fatalf("CheckAfterResume(remote): %v", err)
+	}
+	if last != "host-resume --json" || len(checks) != 1 || checks[0].Survived || checks[0].Problems[0] != "adb offline" {
+		t.Fatalf("checks = %+v after %q", checks, last)
+	}
+	if err := m.WatchHostResume(0, nil); err == nil {
+		t.Fatal("expected WatchHostResume to refuse remote mode")
+	}
+}
+
+func TestRemoteUsage(t *testing.T) {
+	m := newRemoteManager(t)
+	var last string
+	withRemoteRunner(t, func(_ string, _ []string, avdArgs []string) (string, string, error) {
+		last = strings.Join(avdArgs, " ")
+		return `[{"avd":"w-acme-1","emulator_hours":5.5,"disk_gb_days":12,"boots":3},{"avd":"w-gino","emulator_hours":1,"boots":1}]`, "", nil
+	})
+	since := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	usage, err := m.Usage(since, since.AddDate(0, 1, 0))
+	if err != nil {
+		t.Fatalf("Usage(remote): %v", err)
+	}
+	if last != "usage --since 2026-09-01T00:00:00Z --until 2026-10-01T00:00:00Z --format json" || len(usage) != 2 {
+		t.Fatalf("usage = %+v after %q", usage, last)
+	}
+	groups := GroupUsage(usage, map[string]map[string]string{"w-acme-1": {"customer": "acme"}}, "customer")
+	if len(groups) != 2 || groups[1].Group != "acme" || groups[1].EmulatorHours != 5.5 {
+		t.Fatalf("groups = %+v", groups)
+	}
+}
+
+func TestRemoteHistory(t *testing.T) {
+	m := newRemoteManager(t)
+	var calls []string
+	withRemoteRunner(t, func(_ string, _ []string, avdArgs []string) (string, string, error) {
+		calls = append(calls, strings.Join(avdArgs, " "))
+		switch avdArgs[1] {
+		case "list":
+			return `[{"started_at":"2026-10-01T08:00:00Z","pid":42,"log_path":"/tmp/e.log","artifacts":[{"kind":"screenshot","path":"/srv/s.png","captured_at":"2026-10-01T08:05:00Z"}]}]`, "", nil
+		case "export":
+			return "\x1f\x8barchive", "", nil
+		}
+		return `[{"avd":"w-1","kind":"log","path":"/tmp/e.log","started_at":"2026-10-01T08:00:00Z","reason":"age"}]`, "", nil
+	})
+	runs, err := m.RunHistory("w-1")
+	if err != nil || len(runs) != 1 || runs[0].Artifacts[0].Kind != ArtifactScreenshot {
+		t.Fatalf("RunHistory(remote) = %+v, %v", runs, err)
+	}
+	var buf bytes.Buffer
+	if err := m.ExportHistory("w-1", &buf); err != nil || buf.String() != "\x1f\x8barchive" {
+		t.Fatalf("ExportHistory(remote) = %q, %v", buf.String(), err)
+	}
+	pruned, err := m.PruneHistory("", true)
+	if err != nil || len(pruned) != 1 || pruned[0].Reason != "age" {
+		t.Fatalf("PruneHistory(remote) = %+v, %v", pruned, err)
+	}
+	want := []string{"history list --name w-1 --json", "history export --name w-1", "history prune --json --dry-run"}
+	if !slices.Equal(calls, want) {
+		t.Fatalf("calls = %q, want %q", calls, want)
+	}
+}
+
+func TestRemoteQuarantine(t *testing.T) {
+	m := newRemoteManager(t)
+	var calls []string
+	withRemoteRunner(t, func(_ string, _ []string, avdArgs []string) (string, string, error) {
+		calls = append(calls, strings.Join(avdArgs, " "))
+		if avdArgs[1] == "list" {
+			return `[{"name":"w-1","since":"2026-10-01T08:00:00Z","reason":"3 boot failures in a row while w-2 booted","failures":3}]`, "", nil
+		}
+		return "", "", nil
+	})
+	if err := m.Quarantine("w-1", "corrupt overlay"); err != nil {
+		t.Fatal(err)
+	}
+	recs, err := m.ListQuarantined()
+	if err != nil || len(recs) != 1 || recs[0].Failures != 3 {
+		t.Fatalf("ListQuarantined(remote) = %+v, %v", recs, err)
+	}
+	if err := m.ReleaseQuarantine("w-1", true); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"quarantine add w-1 --reason corrupt overlay", "quarantine list --json", "quarantine release w-1 --rematerialize"}
+	if !slices.Equal(calls, want) {
+		t.Fatalf("calls = %q, want %q", calls, want)
+	}
+}
+
+func TestRemoteRunEphemeral(t *testing.T) {
+	m := newRemoteManager(t)
+	var calls []string
+	withRemoteRunner(t, func(_ string, _ []string, avdArgs []string) (string, string, error) {
+		calls = append(calls, strings.Join(avdArgs, " "))
+		switch avdArgs[0] {
+		case "ps":
+			return `[]`, "", nil
+		case "run":
+			return "Started w-tmp on emulator-5580 (log: /tmp/e.log)\n", "", nil
+		}
+		return "", "", nil
+	})
+	if _, err := m.Run(RunOptions{Name: "w-tmp", EphemeralTmpfs: true}); err != nil {
+		t.Fatalf("Run(remote): %v", err)
+	}
+	if last := calls[len(calls)-1]; last != "run --name w-tmp --ephemeral" {
+		t.Fatalf("expected an ephemeral run, got %q", last)
+	}
+}
+
+func TestRemoteSlugName(t *testing.T) {
+	m := newRemoteManager(t)
+	var got string
+	withRemoteRunner(t, func(_ string, _ []string, avdArgs []string) (string, string, error) {
+		got = strings.Join(avdArgs, " ")
+		return "w-acme-corp\n", "", nil
+	})
+	name, err := m.SlugName("ACME Corp")
+	if err != nil || name != "w-acme-corp" || got != "slug ACME Corp" {
+		t.Fatalf("SlugName(remote) = %q, %v (call %q)", name, err, got)
+	}
+}
+
+func TestRemoteListWide(t *testing.T) {
+	m := newRemoteManager(t)
+	var got string
+	withRemoteRunner(t, func(_ string, _ []string, avdArgs []string) (string, string, error) {
+		got = strings.Join(avdArgs, " ")
+		return `[{"name":"w-1","path":"/avd/w-1.avd","api_level":35,"kind":"clone","golden":"/goldens/v5","last_boot":"2025-01-01T00:00:00Z","running":true,"serial":"emulator-5580"}]`, "", nil
+	})
+	infos, err := m.ListWide()
+	if err != nil || got != "list --wide --json" {
+		t.Fatalf("ListWide(remote) = %v (call %q)", err, got)
+	}
+	if len(infos) != 1 || infos[0].APILevel != 35 || infos[0].Kind != KindClone || infos[0].LastBoot == nil || infos[0].Serial != "emulator-5580" {
+		t.Fatalf("ListWide(remote) = %#v", infos)
+	}
+}
+
+func TestRemoteQuery(t *testing.T) {
+	m := newRemoteManager(t)
+	var calls []string
+	withRemoteRunner(t, func(_ string, _ []string, avdArgs []string) (string, string, error) {
+		calls = append(calls, strings.Join(avdArgs, " "))
+		if avdArgs[0] == "ps" {
+			return `[{"serial":"emulator-5580","name":"w-1","port":5580,"pid":42,"booted":true}]`, "", nil
+		}
+		return `[{"name":"w-1","kind":"clone","running":true,"booted":true}]`, "", nil
+	})
+	spec := QuerySpec{Filters: []Filter{{Field: "name", Value: "prefix:w-"}, {Field: "booted", Value: "true"}}, Sort: "-uptime"}
+	infos, err := m.Query(spec)
+	if err != nil || len(infos) != 1 || !infos[0].Booted {
+		t.Fatalf("Query(remote) = %#v, %v", infos, err)
+	}
+	procs, err := m.QueryRunning(spec)
+	if err != nil || len(procs) != 1 || procs[0].Serial != "emulator-5580" {
+		t.Fatalf("QueryRunning(remote) = %#v, %v", procs, err)
+	}
+	want := []string{
+		"list --wide --json --filter name=prefix:w- --filter booted=true --sort -uptime",
+		"ps --json --filter name=prefix:w- --filter booted=true --sort -uptime",
+	}
+	if strings.Join(calls, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected remote calls:\n%s", strings.Join(calls, "\n"))
+	}
+}
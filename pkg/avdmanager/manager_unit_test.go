@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -99,6 +100,33 @@ func TestListAndDeleteRoundTrip(t *testing.T) {
 	}
 }
 
+func TestReadOnlyManagerRefusesMutations(t *testing.T) {
+	tmp := t.TempDir()
+	avdHome := filepath.Join(tmp, "avd")
+	if err := os.MkdirAll(filepath.Join(avdHome, "demo.avd"), 0o755); err != nil {
+		t.Fatalf("mkdir avd: %v", err)
+	}
+
+	m := NewWithEnv(Environment{AVDHome: avdHome, Context: context.Background(), ReadOnly: true})
+	if !m.ReadOnly() {
+		t.Fatal("ReadOnly() = false")
+	}
+	if list, err := m.List(); err != nil || len(list) != 1 {
+		t.Fatalf("List() = %#v, %v", list, err)
+	}
+	err := m.Delete("demo")
+	var roErr *ReadOnlyError
+	if !errors.As(err, &roErr) || roErr.Op != "DeleteWithOptions" || !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("Delete() error = %v, want *ReadOnlyError", err)
+	}
+	if _, err := m.Clone(CloneOptions{BaseName: "demo", CloneName: "w-1"}); !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("Clone() error = %v, want ErrReadOnly", err)
+	}
+	if _, err := os.Stat(filepath.Join(avdHome, "demo.avd")); err != nil {
+		t.Fatalf("read-only manager touched the AVD: %v", err)
+	}
+}
+
 func TestListRunningStopAndStopByName(t *testing.T) {
 	tmp := t.TempDir()
 	adb := writeExecScript(t, tmp, "adb", `
@@ -277,6 +305,37 @@ exit 0
 	}
 }
 
+func TestWaitForBootAllReportsPerSerial(t *testing.T) {
+	tmp := t.TempDir()
+	adb := writeExecScript(t, tmp, "adb", `
+if [ "$1" = "-s" ] && [ "$3" = "shell" ] && [ "$5" = "sys.boot_completed" ]; then
+  if [ "$2" = "emulator-5580" ]; then echo "1"; else echo "0"; fi
+fi
+exit 0
+`)
+	m := newManagerWithBinaries(t, adb, filepath.Join(tmp, "missing-qemu"))
+
+	var mu sync.Mutex
+	stages := map[string][]string{}
+	results, err := m.WaitForBootAll([]string{"emulator-5580", "emulator-5582", "emulator-5580"}, time.Second, func(serial, status string, _ time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		stages[serial] = append(stages[serial], status)
+	})
+	if err == nil || !strings.Contains(err.Error(), "emulator-5582: boot timeout") {
+		t.Fatalf("WaitForBootAll() error = %v", err)
+	}
+	if len(results) != 2 || results["emulator-5580"].Err != nil || results["emulator-5582"].Err == nil {
+		t.Fatalf("unexpected results: %#v", results)
+	}
+	if got := strings.Join(stages["emulator-5580"], ","); got != "waiting_adb,checking_bootanim,boot_complete" {
+		t.Fatalf("emulator-5580 stages = %s", got)
+	}
+	if got := strings.Join(stages["emulator-5582"], ","); got != "waiting_adb,checking_bootanim" {
+		t.Fatalf("emulator-5582 stages = %s", got)
+	}
+}
+
 func TestInitBaseAndCloneSuccessPaths(t *testing.T) {
 	tmp := t.TempDir()
 	avdHome := filepath.Join(tmp, "avd")
@@ -0,0 +1,19 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avdmanager
+
+import "github.com/forkbombeu/avdctl/internal/avd"
+
+// CloneMaterializer creates the writable images of new and reset clones from a golden
+// (Environment.CloneMaterializer), for storage with its own cloning such as Ceph RBD or
+// overlayfs. Materialize is called once per golden image, concurrently up to IOParallelism,
+// and must leave a raw image (or a symlink to one) at CloneImage.Dest. It applies to local
+// managers only; over SSH the remote avdctl copies.
+type CloneMaterializer = avd.CloneMaterializer
+
+// CloneImage is the image handed to a CloneMaterializer: its golden source and clone destination.
+type CloneImage = avd.CloneImage
+
+// CopyMaterializer is the default CloneMaterializer: a reflink where supported, else a sparse copy.
+type CopyMaterializer = avd.CopyMaterializer
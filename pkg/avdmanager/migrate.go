@@ -0,0 +1,101 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avdmanager
+
+import (
+	"github.com/forkbombeu/avdctl/internal/avd"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Customizations are the APKs and post-boot settings recorded for an AVD or golden.
+type Customizations = avd.Customizations
+
+// APKMetadata is the package name, version, SDK levels and permissions declared by an APK;
+// BakeAPK records it in Customizations.Packages.
+type APKMetadata = avd.APKMetadata
+
+// ReadAPKMetadata parses the AndroidManifest.xml of a local APK.
+func ReadAPKMetadata(path string) (APKMetadata, error) {
+	return avd.ReadAPKMetadata(path)
+}
+
+// MigrateOptions selects the base to migrate and the system image of the new API level.
+type MigrateOptions = avd.MigrateOptions
+
+// MigrationStep is one customization carried over (or not); MigrationReport lists them.
+type (
+	MigrationStep   = avd.MigrationStep
+	MigrationReport = avd.MigrationReport
+)
+
+// MigrateBase creates a base on a new system image and re-applies the config values, APKs
+// and settings recorded for the old base and its golden. Steps that could not be carried
+// over are listed in the report (see MigrationReport.NotCarried).
+func (m *Manager) MigrateBase(opts MigrateOptions) (MigrationReport, error) {
+	if err := m.checkWritable("MigrateBase"); err != nil {
+		return MigrationReport{}, err
+	}
+	ctx, span := m.startSpan("avdmanager.MigrateBase",
+		attribute.String("from", opts.From),
+		attribute.String("name", opts.Name),
+	)
+	defer span.End()
+	if m.usesRemote() {
+		var report MigrationReport
+		args := []string{"migrate", "--json", "--from", opts.From, "--name", opts.Name, "--image", opts.SystemImage}
+		if opts.Golden != "" {
+			args = append(args, "--golden", opts.Golden)
+		}
+		if opts.Device != "" {
+			args = append(args, "--device", opts.Device)
+		}
+		if opts.Dest != "" {
+			args = append(args, "--dest", opts.Dest)
+		}
+		if opts.BootTimeout > 0 {
+			args = append(args, "--timeout", opts.BootTimeout.String())
+		}
+		err := m.runRemoteJSON(&report, args...)
+		recordSpanError(span, err)
+		return report, err
+	}
+	report, err := avd.MigrateBase(m.withContext(ctx), opts)
+	recordSpanError(span, err)
+	return report, err
+}
+
+// HomeMigrationOptions selects the AVD home MigrateHome moves; HomeMigrationReport lists the
+// AVDs it moved, with the references it rewrote and those that do not resolve.
+type (
+	HomeMigrationOptions = avd.HomeMigrationOptions
+	HomeMigrationItem    = avd.HomeMigrationItem
+	HomeMigrationReport  = avd.HomeMigrationReport
+)
+
+// MigrateHome moves every AVD of opts.From to opts.To, rewriting the paths that pointed into
+// the old home, and verifies that clones still resolve. Configure the Manager with the new
+// AVDHome afterwards.
+func (m *Manager) MigrateHome(opts HomeMigrationOptions) (HomeMigrationReport, error) {
+	if err := m.checkWritable("MigrateHome"); err != nil {
+		return HomeMigrationReport{}, err
+	}
+	ctx, span := m.startSpan("avdmanager.MigrateHome",
+		attribute.String("from", opts.From),
+		attribute.String("to", opts.To),
+	)
+	defer span.End()
+	if m.usesRemote() {
+		var report HomeMigrationReport
+		args := []string{"migrate", "--json", "--from", opts.From, "--to", opts.To}
+		if opts.DryRun {
+			args = append(args, "--dry-run")
+		}
+		err := m.runRemoteJSON(&report, args...)
+		recordSpanError(span, err)
+		return report, err
+	}
+	report, err := avd.MigrateHome(m.withContext(ctx), opts)
+	recordSpanError(span, err)
+	return report, err
+}
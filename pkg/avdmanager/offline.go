@@ -0,0 +1,67 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avdmanager
+
+import (
+	"errors"
+
+	"github.com/forkbombeu/avdctl/internal/avd"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ArtifactRequirements lists system images and goldens needed by a workflow.
+type ArtifactRequirements = avd.ArtifactRequirements
+
+// Artifact is a required system image or golden; MissingArtifactsError lists the ones
+// unavailable in offline mode.
+type (
+	Artifact              = avd.Artifact
+	MissingArtifactsError = avd.MissingArtifactsError
+)
+
+// PrefetchReport lists what Prefetch installed or copied.
+type PrefetchReport = avd.PrefetchReport
+
+// FleetRequirements returns the system images and goldens referenced by a fleet.
+func FleetRequirements(fleet Fleet) ArtifactRequirements {
+	return avd.FleetRequirements(fleet)
+}
+
+// CheckArtifacts reports required artifacts that are not available locally. It makes no
+// network calls and is safe to run in offline mode.
+func (m *Manager) CheckArtifacts(req ArtifactRequirements) ([]Artifact, error) {
+	if m.usesRemote() {
+		return nil, errors.New("artifact checks are not supported over SSH; run `avdctl prefetch --check` on the target host")
+	}
+	return avd.CheckArtifacts(m.env, req), nil
+}
+
+// Prefetch installs missing system images and copies goldens into GoldenDir so later
+// runs can use offline mode.
+func (m *Manager) Prefetch(req ArtifactRequirements) (PrefetchReport, error) {
+	if err := m.checkWritable("Prefetch"); err != nil {
+		return PrefetchReport{}, err
+	}
+	ctx, span := m.startSpan("avdmanager.Prefetch",
+		attribute.Int("system_images", len(req.SystemImages)),
+		attribute.Int("goldens", len(req.Goldens)),
+	)
+	defer span.End()
+	if m.usesRemote() {
+		var report PrefetchReport
+		args := []string{"prefetch", "--json"}
+		for _, image := range req.SystemImages {
+			args = append(args, "--image", image)
+		}
+		for _, golden := range req.Goldens {
+			args = append(args, "--golden", golden)
+		}
+		err := m.runRemoteJSON(&report, args...)
+		recordSpanError(span, err)
+		return report, err
+	}
+	report, err := avd.Prefetch(m.withContext(ctx), req)
+	recordSpanError(span, err)
+	return report, err
+}
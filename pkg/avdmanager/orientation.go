@@ -0,0 +1,67 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avdmanager
+
+import (
+	"github.com/forkbombeu/avdctl/internal/avd"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type (
+	// Orientation is a fixed screen rotation (OrientationPortrait, OrientationLandscape, ...).
+	Orientation = avd.Orientation
+	// Posture is a foldable posture (PostureClosed, PostureHalfOpen, ...).
+	Posture = avd.Posture
+)
+
+const (
+	OrientationPortrait         = avd.OrientationPortrait
+	OrientationLandscape        = avd.OrientationLandscape
+	OrientationReversePortrait  = avd.OrientationReversePortrait
+	OrientationReverseLandscape = avd.OrientationReverseLandscape
+)
+
+const (
+	PostureClosed   = avd.PostureClosed
+	PostureHalfOpen = avd.PostureHalfOpen
+	PostureOpen     = avd.PostureOpen
+	PostureFlipped  = avd.PostureFlipped
+	PostureTent     = avd.PostureTent
+)
+
+// Rotate pins the screen of a running emulator to orientation and disables auto-rotation,
+// so screenshots are taken in a known orientation. To boot clones locked, use
+// PrewarmOptions.LockOrientation.
+func (m *Manager) Rotate(serial string, orientation Orientation) error {
+	if err := m.checkWritable("Rotate"); err != nil {
+		return err
+	}
+	ctx, span := m.startSpan("avdmanager.Rotate", attribute.String("serial", serial), attribute.String("orientation", string(orientation)))
+	defer span.End()
+	if m.usesRemote() {
+		_, err := m.runRemote("rotate", "--serial", serial, string(orientation))
+		recordSpanError(span, err)
+		return err
+	}
+	err := avd.Rotate(m.withContext(ctx), serial, orientation)
+	recordSpanError(span, err)
+	return err
+}
+
+// SetPosture folds a running foldable emulator (the AVD must use a foldable device profile).
+func (m *Manager) SetPosture(serial string, posture Posture) error {
+	if err := m.checkWritable("SetPosture"); err != nil {
+		return err
+	}
+	ctx, span := m.startSpan("avdmanager.SetPosture", attribute.String("serial", serial), attribute.String("posture", string(posture)))
+	defer span.End()
+	if m.usesRemote() {
+		_, err := m.runRemote("posture", "--serial", serial, string(posture))
+		recordSpanError(span, err)
+		return err
+	}
+	err := avd.SetPosture(m.withContext(ctx), serial, posture)
+	recordSpanError(span, err)
+	return err
+}
@@ -0,0 +1,71 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avdmanager
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/forkbombeu/avdctl/internal/avd"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// StartCapture makes a running emulator record the guest's network traffic to dest (a .pcap
+// file, or a directory that gets <serial>-<timestamp>.pcap) and returns the capture path; over
+// SSH it is on the remote host. Only one capture runs per emulator.
+func (m *Manager) StartCapture(serial, dest string) (string, error) {
+	if err := m.checkWritable("StartCapture"); err != nil {
+		return "", err
+	}
+	ctx, span := m.startSpan("avdmanager.StartCapture", attribute.String("serial", serial))
+	defer span.End()
+	if m.usesRemote() {
+		out, err := m.runRemote("pcap", "start", "--serial", serial, "--out", dest)
+		if err == nil {
+			var path string
+			if path, err = parseCaptureOutput(out, "Capturing to: "); err == nil {
+				return path, nil
+			}
+		}
+		recordSpanError(span, err)
+		return "", err
+	}
+	path, err := avd.StartNetworkCapture(m.withContext(ctx), serial, dest)
+	recordSpanError(span, err)
+	return path, err
+}
+
+// StopCapture ends the capture started by StartCapture and returns the pcap path. Stopping the
+// emulator also ends it.
+func (m *Manager) StopCapture(serial string) (string, error) {
+	if err := m.checkWritable("StopCapture"); err != nil {
+		return "", err
+	}
+	ctx, span := m.startSpan("avdmanager.StopCapture", attribute.String("serial", serial))
+	defer span.End()
+	if m.usesRemote() {
+		out, err := m.runRemote("pcap", "stop", "--serial", serial)
+		if err == nil {
+			var path string
+			if path, err = parseCaptureOutput(out, "Capture saved: "); err == nil {
+				return path, nil
+			}
+		}
+		recordSpanError(span, err)
+		return "", err
+	}
+	path, err := avd.StopNetworkCapture(m.withContext(ctx), serial)
+	recordSpanError(span, err)
+	return path, err
+}
+
+// parseCaptureOutput returns the path the remote pcap command printed after prefix.
+func parseCaptureOutput(out, prefix string) (string, error) {
+	for _, line := range strings.Split(out, "\n") {
+		if path, ok := strings.CutPrefix(strings.TrimSpace(line), prefix); ok {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("unexpected pcap output: %q", strings.TrimSpace(out))
+}
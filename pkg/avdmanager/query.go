@@ -0,0 +1,70 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avdmanager
+
+import (
+	"github.com/forkbombeu/avdctl/internal/avd"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type (
+	// QuerySpec selects and orders AVDs (Query) or running emulators (QueryRunning).
+	QuerySpec = avd.QuerySpec
+	// Filter matches one field: exact, "prefix:", "suffix:", "contains:", a glob, or ">N"/"<N".
+	Filter = avd.Filter
+)
+
+// ParseFilter parses "field=value", e.g. "name=prefix:w-" or "booted=true".
+func ParseFilter(s string) (Filter, error) {
+	return avd.ParseFilter(s)
+}
+
+// Query returns the AVDs of ListWide that match spec. Fields: name, kind, golden, api, abi,
+// device, size, running, booted, serial and uptime (seconds since boot of running AVDs).
+func (m *Manager) Query(spec QuerySpec) ([]AVDInfo, error) {
+	ctx, span := m.startSpan("avdmanager.Query", attribute.Int("filters", len(spec.Filters)), attribute.String("sort", spec.Sort))
+	defer span.End()
+	if m.usesRemote() {
+		var infos []AVDInfo
+		err := m.runRemoteJSON(&infos, append([]string{"list", "--wide", "--json"}, queryRemoteArgs(spec)...)...)
+		recordSpanError(span, err)
+		return infos, err
+	}
+	infos, err := avd.Query(m.withContext(ctx), spec)
+	recordSpanError(span, err)
+	if err != nil {
+		return nil, err
+	}
+	return avdInfos(infos), nil
+}
+
+// QueryRunning returns the running emulators that match spec. Fields: name, serial, port,
+// pid, booted and uptime (seconds since the AVD was started).
+func (m *Manager) QueryRunning(spec QuerySpec) ([]ProcessInfo, error) {
+	ctx, span := m.startSpan("avdmanager.QueryRunning", attribute.Int("filters", len(spec.Filters)), attribute.String("sort", spec.Sort))
+	defer span.End()
+	if m.usesRemote() {
+		var procs []ProcessInfo
+		err := m.runRemoteJSON(&procs, append([]string{"ps", "--json"}, queryRemoteArgs(spec)...)...)
+		recordSpanError(span, err)
+		return procs, err
+	}
+	procs, err := avd.QueryRunning(m.withContext(ctx), spec)
+	recordSpanError(span, err)
+	if err != nil {
+		return nil, err
+	}
+	return processInfos(procs), nil
+}
+
+func queryRemoteArgs(spec QuerySpec) []string {
+	var args []string
+	for _, f := range spec.Filters {
+		args = append(args, "--filter", f.Field+"="+f.Value)
+	}
+	if spec.Sort != "" {
+		args = append(args, "--sort", spec.Sort)
+	}
+	return args
+}
@@ -0,0 +1,40 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avdmanager
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrReadOnly matches, with errors.Is, every *ReadOnlyError.
+var ErrReadOnly = errors.New("manager is read-only")
+
+// ReadOnlyError is returned by the mutating methods of a Manager created with
+// Environment.ReadOnly. Op is the refused method, e.g. "Clone".
+type ReadOnlyError struct {
+	Op string
+}
+
+func (e *ReadOnlyError) Error() string {
+	return fmt.Sprintf("%s refused: %s", e.Op, ErrReadOnly)
+}
+
+// Is makes errors.Is(err, ErrReadOnly) true.
+func (e *ReadOnlyError) Is(target error) bool {
+	return target == ErrReadOnly
+}
+
+// ReadOnly reports whether the manager refuses mutating calls (see Environment.ReadOnly).
+func (m *Manager) ReadOnly() bool {
+	return m.readOnly
+}
+
+// checkWritable returns a *ReadOnlyError for op when the manager is read-only.
+func (m *Manager) checkWritable(op string) error {
+	if !m.readOnly {
+		return nil
+	}
+	return &ReadOnlyError{Op: op}
+}
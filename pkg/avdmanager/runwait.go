@@ -0,0 +1,127 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avdmanager
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/forkbombeu/avdctl/internal/avd"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// RunAndWaitOptions controls RunAndWait.
+type RunAndWaitOptions struct {
+	RunOptions
+	Progress       BootProgressFunc // boot progress, as for WaitForBootWithProgress (optional)
+	ReadyBroadcast *ReadyBroadcast  // also wait for an app to report ready (optional)
+	PhaseTimeouts  PhaseTimeouts    // per-phase limits within BootTimeout (optional)
+	// SkipHealthChecks leaves out the post-boot probes, for images without a launcher.
+	SkipHealthChecks bool
+}
+
+// RunResult is the outcome of RunAndWait.
+type RunResult struct {
+	Serial       string
+	LogPath      string        // emulator stdout/stderr log, if known
+	BootDuration time.Duration // from the start call to boot (and readiness) completed
+	Checks       []HealthCheck // post-boot probes; empty with SkipHealthChecks
+}
+
+// HealthChecks runs the post-boot probes of golden validation on a booted emulator: the
+// package manager answers, userdata is writable and a home activity resolves.
+func (m *Manager) HealthChecks(serial string) ([]HealthCheck, error) {
+	ctx, span := m.startSpan("avdmanager.HealthChecks", attribute.String("serial", serial))
+	defer span.End()
+	if m.usesRemote() {
+		var checks []HealthCheck
+		err := m.runRemoteJSON(&checks, "health", "--serial", serial, "--json")
+		recordSpanError(span, err)
+		return checks, err
+	}
+	return avd.HealthChecks(m.withContext(ctx), serial), nil
+}
+
+// RunAndWait starts an AVD, waits for it to boot (and for opts.ReadyBroadcast), applies the
+// post-boot options of RunOptions and runs the health checks: the Run, WaitForBoot,
+// DisableDoze and probe calls every consumer otherwise writes. opts.BootTimeout bounds the
+// wait (default 3m). On an error after the start the instance is left running and the
+// result still carries its serial, so the caller can inspect or stop it.
+func (m *Manager) RunAndWait(opts RunAndWaitOptions) (RunResult, error) {
+	if err := m.checkWritable("RunAndWait"); err != nil {
+		return RunResult{}, err
+	}
+	_, span := m.startSpan("avdmanager.RunAndWait", attribute.String("avd_name", opts.Name))
+	defer span.End()
+	fail := func(result RunResult, err error) (RunResult, error) {
+		recordSpanError(span, err)
+		return result, err
+	}
+	timeout := opts.BootTimeout
+	if timeout == 0 {
+		timeout = 3 * time.Minute
+	}
+	// Doze is turned off below, once the wait here has seen the boot.
+	runOpts := opts.RunOptions
+	runOpts.DisableDoze = false
+
+	started := time.Now()
+	var result RunResult
+	var err error
+	result.Serial, result.LogPath, err = m.RunOnPort(runOpts)
+	if err != nil {
+		return fail(result, err)
+	}
+	span.SetAttributes(attribute.String("serial", result.Serial))
+	if result.LogPath == "" {
+		result.LogPath = m.instanceLogPath(result.Serial)
+	}
+	err = m.WaitForBootWithOptions(result.Serial, WaitOptions{
+		Timeout:        timeout,
+		Progress:       opts.Progress,
+		ReadyBroadcast: opts.ReadyBroadcast,
+		PhaseTimeouts:  opts.PhaseTimeouts,
+	})
+	if err != nil {
+		return fail(result, fmt.Errorf("%s did not boot: %w", result.Serial, err))
+	}
+	result.BootDuration = time.Since(started)
+	if opts.DisableDoze {
+		if err := m.DisableDoze(result.Serial); err != nil {
+			return fail(result, fmt.Errorf("disable doze on %s: %w", result.Serial, err))
+		}
+	}
+	if opts.SkipHealthChecks {
+		return result, nil
+	}
+	if result.Checks, err = m.HealthChecks(result.Serial); err != nil {
+		return fail(result, err)
+	}
+	var failed []string
+	for _, check := range result.Checks {
+		if !check.OK {
+			failed = append(failed, fmt.Sprintf("%s: %s", check.Name, check.Detail))
+		}
+	}
+	if len(failed) > 0 {
+		return fail(result, fmt.Errorf("%s failed health checks:\n  %s", result.Serial, strings.Join(failed, "\n  ")))
+	}
+	span.SetAttributes(attribute.Int64("boot_ms", result.BootDuration.Milliseconds()))
+	return result, nil
+}
+
+// instanceLogPath returns the log ListRunning reports for serial, if any.
+func (m *Manager) instanceLogPath(serial string) string {
+	procs, err := m.ListRunning()
+	if err != nil {
+		return ""
+	}
+	for _, p := range procs {
+		if p.Serial == serial {
+			return p.LogPath
+		}
+	}
+	return ""
+}
@@ -0,0 +1,78 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avdmanager
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/forkbombeu/avdctl/internal/avd"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// SecretFile is a file a SecretsProvisioner writes into the instance, either at an absolute
+// guest path or, with Package, inside the app's data directory.
+type SecretFile = avd.SecretFile
+
+// SecretBroadcast hands string extras to an app receiver.
+type SecretBroadcast = avd.SecretBroadcast
+
+// ErrSecretsProvisioned is returned by SaveGolden for a clone that was given secrets by a
+// SecretsProvisioner.
+var ErrSecretsProvisioned = avd.ErrSecretsProvisioned
+
+// SecretsProvisioner pushes per-customer credentials and configuration into an instance once it
+// has booted (RunOptions.SecretsProvisioner), so they never have to be baked into a golden.
+// The clone is marked as holding secrets and SaveGolden refuses to export it; EphemeralTmpfs
+// runs are not marked, since their userdata is discarded on Stop.
+type SecretsProvisioner func(s *SecretsSession) error
+
+// SecretsSession writes secrets into one booted instance. Values are streamed to adb on stdin
+// and are neither stored on the host nor logged.
+type SecretsSession struct {
+	Name   string // AVD name
+	Serial string
+
+	m   *Manager
+	ctx context.Context
+}
+
+// WriteFile writes f on the instance.
+func (s *SecretsSession) WriteFile(f SecretFile) error {
+	return avd.WriteSecretFile(s.m.withContext(s.ctx), s.Serial, f)
+}
+
+// InsertContent inserts a row of string values into the content provider at uri.
+func (s *SecretsSession) InsertContent(uri string, values map[string]string) error {
+	return avd.InsertSecretContent(s.m.withContext(s.ctx), s.Serial, uri, values)
+}
+
+// Broadcast sends b to the apps on the instance.
+func (s *SecretsSession) Broadcast(b SecretBroadcast) error {
+	return avd.SendSecretBroadcast(s.m.withContext(s.ctx), s.Serial, b)
+}
+
+// provisionSecrets runs opts.SecretsProvisioner on a booted instance.
+func (m *Manager) provisionSecrets(serial string, opts RunOptions) error {
+	if opts.SecretsProvisioner == nil {
+		return nil
+	}
+	ctx, span := m.startSpan("avdmanager.ProvisionSecrets",
+		attribute.String("avd_name", opts.Name),
+		attribute.String("serial", serial),
+	)
+	defer span.End()
+	if !opts.EphemeralTmpfs {
+		if err := avd.MarkSecretsProvisioned(m.env, opts.Name, serial); err != nil {
+			recordSpanError(span, err)
+			return err
+		}
+	}
+	err := opts.SecretsProvisioner(&SecretsSession{Name: opts.Name, Serial: serial, m: m, ctx: ctx})
+	if err != nil {
+		err = fmt.Errorf("provision secrets on %s: %w", serial, err)
+	}
+	recordSpanError(span, err)
+	return err
+}
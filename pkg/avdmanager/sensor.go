@@ -0,0 +1,78 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avdmanager
+
+import (
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/forkbombeu/avdctl/internal/avd"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type (
+	// Sensor is an emulator sensor (SensorAcceleration, SensorPressure, ...).
+	Sensor = avd.Sensor
+	// SensorSample is one reading of a recorded sensor trace.
+	SensorSample = avd.SensorSample
+)
+
+const (
+	SensorAcceleration  = avd.SensorAcceleration
+	SensorGyroscope     = avd.SensorGyroscope
+	SensorMagneticField = avd.SensorMagneticField
+	SensorOrientation   = avd.SensorOrientation
+	SensorPressure      = avd.SensorPressure
+	SensorTemperature   = avd.SensorTemperature
+	SensorHumidity      = avd.SensorHumidity
+	SensorLight         = avd.SensorLight
+	SensorProximity     = avd.SensorProximity
+)
+
+// ParseSensorTrace reads a sensor trace of "<offset ms>,<sensor>,<value>[,<value>...]" lines.
+func ParseSensorTrace(r io.Reader) ([]SensorSample, error) {
+	return avd.ParseSensorTrace(r)
+}
+
+// SetSensor sets the current values of a sensor on a running emulator, e.g.
+// SetSensor(serial, SensorAcceleration, []float64{0, 9.8, 0}).
+func (m *Manager) SetSensor(serial string, sensor Sensor, values []float64) error {
+	if err := m.checkWritable("SetSensor"); err != nil {
+		return err
+	}
+	ctx, span := m.startSpan("avdmanager.SetSensor", attribute.String("serial", serial), attribute.String("sensor", string(sensor)))
+	defer span.End()
+	if m.usesRemote() {
+		parts := make([]string, len(values))
+		for i, v := range values {
+			parts[i] = strconv.FormatFloat(v, 'g', -1, 64)
+		}
+		_, err := m.runRemote("sensor", "set", "--serial", serial, string(sensor), strings.Join(parts, ":"))
+		recordSpanError(span, err)
+		return err
+	}
+	err := avd.SetSensor(m.withContext(ctx), serial, sensor, values)
+	recordSpanError(span, err)
+	return err
+}
+
+// PlaySensorTrace feeds samples to a running emulator at their offsets and returns once the
+// last one is sent (or the manager context is canceled). Over SSH the trace is sent in full
+// and timed on the remote host.
+func (m *Manager) PlaySensorTrace(serial string, samples []SensorSample) error {
+	if err := m.checkWritable("PlaySensorTrace"); err != nil {
+		return err
+	}
+	ctx, span := m.startSpan("avdmanager.PlaySensorTrace", attribute.String("serial", serial), attribute.Int("samples", len(samples)))
+	defer span.End()
+	if m.usesRemote() {
+		_, err := m.runRemote("sensor", "play", "--serial", serial, "--trace-text", avd.FormatSensorTrace(samples))
+		recordSpanError(span, err)
+		return err
+	}
+	err := avd.PlaySensorTrace(m.withContext(ctx), serial, samples)
+	recordSpanError(span, err)
+	return err
+}
@@ -0,0 +1,168 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avdmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/forkbombeu/avdctl/internal/avd"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Capture lists the screenshot, logcat and bugreport files collected from an emulator.
+type Capture = avd.Capture
+
+// CaptureArtifacts saves a screenshot, the logcat buffers and a bugreport of a running
+// emulator into dir. Over SSH the files are written on the remote host.
+func (m *Manager) CaptureArtifacts(serial, dir string) (Capture, error) {
+	ctx, span := m.startSpan("avdmanager.CaptureArtifacts", attribute.String("serial", serial))
+	defer span.End()
+	if m.usesRemote() {
+		var capture Capture
+		err := m.runRemoteJSON(&capture, "capture", "--serial", serial, "--dir", dir, "--json")
+		recordSpanError(span, err)
+		return capture, err
+	}
+	capture, err := avd.CaptureArtifacts(m.withContext(ctx), serial, dir)
+	recordSpanError(span, err)
+	return capture, err
+}
+
+// Reasons a session ended (SessionReport.Reason).
+const (
+	SessionCompleted  = "completed"   // SessionOptions.Run returned
+	SessionTimedOut   = "timeout"     // the session duration elapsed
+	SessionCanceled   = "canceled"    // the manager context was canceled
+	SessionBootFailed = "boot-failed" // the clone did not boot within BootTimeout
+)
+
+// SessionOptions contains options for RunSession.
+type SessionOptions struct {
+	Port        int           // Console port (0 = auto-assign)
+	SDK         string        // Named SDK root from Environment.SDKs (optional)
+	BootTimeout time.Duration // Boot timeout (default: 3m)
+	OutputDir   string        // Artifact directory (default: <tmp>/avdctl-sessions/<name>-<timestamp>)
+	Stop        StopOptions   // How the clone is stopped at the end (default: StopConsoleKill)
+
+	// Run, if set, is called once the clone has booted, with a context that expires when the
+	// session duration elapses. The session ends when Run returns; without Run it lasts the
+	// full duration. Run must return promptly once its context is done.
+	Run func(ctx context.Context, serial string) error
+}
+
+// SessionReport reports the results of RunSession.
+type SessionReport struct {
+	Name      string    // Clone name
+	Serial    string    // Emulator serial
+	StartedAt time.Time // When the clone was started
+	BootedAt  time.Time // When boot completed (zero if it did not)
+	EndedAt   time.Time // When the clone was stopped
+	Reason    string    // Why the session ended (SessionCompleted, SessionTimedOut, ...)
+	RunError  string    // Error returned by SessionOptions.Run, if any
+	Capture   Capture   // Artifacts captured before stopping
+	StopError string    // Error stopping the clone, if any
+}
+
+// RunSession starts a clone, waits for boot, lets it run for at most d, then captures a
+// screenshot, logcat and bugreport and stops it. Artifacts are captured and the clone is
+// stopped even if boot fails or the manager context is canceled; the returned error is
+// for failures to start or boot the clone.
+func (m *Manager) RunSession(name string, d time.Duration, opts SessionOptions) (SessionReport, error) {
+	if err := m.checkWritable("RunSession"); err != nil {
+		return SessionReport{}, err
+	}
+	_, span := m.startSpan("avdmanager.RunSession",
+		attribute.String("avd_name", name),
+		attribute.String("duration", d.String()),
+	)
+	defer span.End()
+	report := SessionReport{Name: name, StartedAt: time.Now()}
+	if d <= 0 {
+		err := errors.New("session duration must be positive")
+		recordSpanError(span, err)
+		return report, err
+	}
+	if opts.BootTimeout == 0 {
+		opts.BootTimeout = 3 * time.Minute
+	}
+	if opts.OutputDir == "" {
+		opts.OutputDir = filepath.Join(os.TempDir(), "avdctl-sessions",
+			fmt.Sprintf("%s-%s", name, report.StartedAt.UTC().Format("20060102T150405Z")))
+	}
+
+	serial, _, err := m.RunOnPort(RunOptions{Name: name, Port: opts.Port, SDK: opts.SDK})
+	if err != nil {
+		recordSpanError(span, err)
+		return report, err
+	}
+	report.Serial = serial
+	span.SetAttributes(attribute.String("serial", serial))
+
+	bootErr := m.WaitForBoot(serial, opts.BootTimeout)
+	if bootErr != nil {
+		report.Reason = SessionBootFailed
+	} else {
+		report.BootedAt = time.Now()
+		report.Reason, report.RunError = m.holdSession(serial, d, opts.Run)
+	}
+
+	// Capture and stop even when the caller's context is already canceled.
+	cleanup := &Manager{env: m.env}
+	cleanup.env.Context = context.WithoutCancel(m.spanContext())
+	capture, err := cleanup.CaptureArtifacts(serial, opts.OutputDir)
+	if err != nil {
+		capture.Errors = append(capture.Errors, err.Error())
+	}
+	report.Capture = capture
+	// Run was cut short by the end of the session: record it as a lease that expired.
+	if report.Reason == SessionTimedOut && opts.Run != nil && opts.Stop.Outcome == "" {
+		opts.Stop.Outcome = FailureLeaseExpired
+	}
+	if err := cleanup.StopWithOptions(serial, opts.Stop); err != nil {
+		report.StopError = err.Error()
+	}
+	report.EndedAt = time.Now()
+	span.SetAttributes(attribute.String("reason", report.Reason))
+	recordSpanError(span, bootErr)
+	return report, bootErr
+}
+
+// holdSession waits until d elapses, run returns or the manager context is canceled.
+func (m *Manager) holdSession(serial string, d time.Duration, run func(context.Context, string) error) (string, string) {
+	ctx, cancel := context.WithTimeout(m.spanContext(), d)
+	defer cancel()
+	reason := func() string {
+		if m.spanContext().Err() != nil {
+			return SessionCanceled
+		}
+		return SessionTimedOut
+	}
+	if run == nil {
+		<-ctx.Done()
+		return reason(), ""
+	}
+	done := make(chan error, 1)
+	go func() { done <- run(ctx, serial) }()
+	select {
+	case err := <-done:
+		if ctx.Err() != nil {
+			return reason(), errorString(err)
+		}
+		return SessionCompleted, errorString(err)
+	case <-ctx.Done():
+		return reason(), ""
+	}
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -0,0 +1,83 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avdmanager
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/forkbombeu/avdctl/internal/avd"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// TraceConfig selects what a perfetto trace records (default: scheduling, CPU frequency and
+// framework atrace categories, stopped on its own after DefaultTraceDuration).
+type TraceConfig = avd.TraceConfig
+
+// DefaultTraceDuration bounds a trace that is never stopped.
+const DefaultTraceDuration = avd.DefaultTraceDuration
+
+// StartTrace starts a background perfetto session on a running emulator. It waits for the
+// emulator to appear in adb, so it can be called right after Run to cover most of the boot.
+func (m *Manager) StartTrace(serial string, config TraceConfig) error {
+	if err := m.checkWritable("StartTrace"); err != nil {
+		return err
+	}
+	ctx, span := m.startSpan("avdmanager.StartTrace", attribute.String("serial", serial))
+	defer span.End()
+	if m.usesRemote() {
+		args := []string{"trace", "start", "--serial", serial}
+		if strings.TrimSpace(config.Config) != "" {
+			args = append(args, "--config-text", config.Config)
+		}
+		if config.Duration > 0 {
+			args = append(args, "--duration", config.Duration.String())
+		}
+		_, err := m.runRemote(args...)
+		recordSpanError(span, err)
+		return err
+	}
+	err := avd.StartTrace(m.withContext(ctx), serial, config)
+	recordSpanError(span, err)
+	return err
+}
+
+// StopTrace stops the perfetto session started by StartTrace and pulls the trace to dest
+// (a file, or a directory). It returns the trace path; over SSH it is on the remote host.
+func (m *Manager) StopTrace(serial, dest string) (string, error) {
+	if err := m.checkWritable("StopTrace"); err != nil {
+		return "", err
+	}
+	ctx, span := m.startSpan("avdmanager.StopTrace", attribute.String("serial", serial))
+	defer span.End()
+	if m.usesRemote() {
+		out, err := m.runRemote("trace", "stop", "--serial", serial, "--out", dest)
+		recordSpanError(span, err)
+		if err != nil {
+			return "", err
+		}
+		for _, line := range strings.Split(out, "\n") {
+			if path, ok := strings.CutPrefix(strings.TrimSpace(line), "Trace saved: "); ok {
+				return path, nil
+			}
+		}
+		err = fmt.Errorf("unexpected trace stop output: %q", strings.TrimSpace(out))
+		recordSpanError(span, err)
+		return "", err
+	}
+	path, err := avd.StopTrace(m.withContext(ctx), serial, dest)
+	recordSpanError(span, err)
+	return path, err
+}
+
+// startBootTrace starts the trace requested by RunOptions.TraceOnBoot.
+func (m *Manager) startBootTrace(serial string, opts RunOptions) error {
+	if !opts.TraceOnBoot {
+		return nil
+	}
+	if err := m.StartTrace(serial, opts.TraceConfig); err != nil {
+		return fmt.Errorf("start boot trace on %s: %w", serial, err)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+// Copyright (C) 2025 Forkbomb B.V.
+// License: AGPL-3.0-only
+
+package avdmanager
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// BootResult is the outcome of waiting for one serial in WaitForBootAll.
+type BootResult struct {
+	Elapsed time.Duration // until the instance booted or the wait failed
+	Err     error         // nil once the instance booted
+}
+
+// MultiBootProgressFunc reports a stage transition of one of the instances WaitForBootAll
+// waits for. Stages are those of BootProgressFunc ("waiting_adb", "checking_bootanim",
+// "boot_complete").
+type MultiBootProgressFunc func(serial, status string, elapsed time.Duration)
+
+// WaitForBootAll waits for the emulators on serials to boot, all at once, each within timeout.
+// progress, when set, is called once per stage transition of an instance and never
+// concurrently, so it can render a single status table. The result has an entry per serial;
+// the error joins the failures and is nil when every instance booted.
+func (m *Manager) WaitForBootAll(serials []string, timeout time.Duration, progress MultiBootProgressFunc) (map[string]BootResult, error) {
+	if err := m.checkWritable("WaitForBootAll"); err != nil {
+		return nil, err
+	}
+	_, span := m.startSpan("avdmanager.WaitForBootAll", attribute.Int("instances", len(serials)))
+	defer span.End()
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results = make(map[string]BootResult, len(serials))
+		stages  = make(map[string]string, len(serials))
+	)
+	for _, serial := range serials {
+		if _, dup := results[serial]; dup {
+			continue
+		}
+		results[serial] = BootResult{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			err := m.WaitForBootWithProgress(serial, timeout, func(status string, elapsed time.Duration) {
+				mu.Lock()
+				defer mu.Unlock()
+				if stages[serial] == status {
+					return
+				}
+				stages[serial] = status
+				if progress != nil {
+					progress(serial, status, elapsed)
+				}
+			})
+			mu.Lock()
+			results[serial] = BootResult{Elapsed: time.Since(start), Err: err}
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	var errs []error
+	for i, serial := range serials {
+		if res := results[serial]; res.Err != nil && !slices.Contains(serials[:i], serial) {
+			errs = append(errs, fmt.Errorf("%s: %w", serial, res.Err))
+		}
+	}
+	err := errors.Join(errs...)
+	span.SetAttributes(attribute.Int("failed", len(errs)))
+	recordSpanError(span, err)
+	return results, err
+}